@@ -8,12 +8,14 @@ import (
 	"log"
 	"myredis/resp"
 	"os"
+	"strings"
 )
 
 // 本文件负责 AOF 的加载与重放（replay）：
 // - 启动时读取 AOF 文件
 // - 解析为 RESP MultiBulk（命令数组）
 // - 逐条交给上层 executor 执行（通常是 db.Exec 的内部通道版本）
+// - MULTI ... EXEC 之间的命令先缓存，读到 EXEC 才整体执行；文件末尾不完整的事务（崩溃时只写了一半）直接丢弃
 
 // LoadAof 启动时加载 AOF 文件并重放命令
 func (handler *AofHandler) LoadAof(executor func(cmd [][]byte) resp.Reply) error {
@@ -32,13 +34,21 @@ func (handler *AofHandler) LoadAof(executor func(cmd [][]byte) resp.Reply) error
 
 	// Replay commands
 	// ParseStream creates a channel, we iterate it.
-	payloads := resp.ParseStream(file)
+	// AOF 由本进程写入，不受客户端协议限制（运行期调低限制后仍能加载之前写入的大 value）
+	payloads := resp.ParseStreamWithLimits(file, resp.Limits{})
+
+	// tx 为当前未闭合事务中的命令；inTx 表示已读到 MULTI 但还没读到 EXEC
+	var tx [][][]byte
+	inTx := false
 
 	for payload := range payloads {
 		if payload.Err != nil {
 			if payload.Err == io.EOF {
 				break
 			}
+			if inTx {
+				log.Printf("AOF: discard incomplete transaction (%d commands)", len(tx))
+			}
 			log.Printf("AOF parse error: %v", payload.Err)
 			return payload.Err
 		}
@@ -54,8 +64,32 @@ func (handler *AofHandler) LoadAof(executor func(cmd [][]byte) resp.Reply) error
 			continue
 		}
 
+		args := multiBulk.Args
+		if len(args) == 1 {
+			switch strings.ToLower(string(args[0])) {
+			case "multi":
+				inTx = true
+				tx = tx[:0]
+				continue
+			case "exec":
+				for _, cmd := range tx {
+					executor(cmd)
+				}
+				inTx = false
+				tx = tx[:0]
+				continue
+			}
+		}
+		if inTx {
+			tx = append(tx, args)
+			continue
+		}
+
 		// Exec command using provided callback
-		executor(multiBulk.Args)
+		executor(args)
+	}
+	if inTx {
+		log.Printf("AOF: discard incomplete transaction (%d commands)", len(tx))
 	}
 
 	log.Println("AOF load finished")
@@ -14,6 +14,7 @@ import (
 // 本文件实现对等节点（peer）的客户端：
 // - 复用 TCP 连接（简单连接池），降低转发开销
 // - 采用 RESP request/reply：发送 MultiBulk 命令，读取一个 Reply 返回
+// - 新连接先发送 PEERHANDSHAKE <secret>，接收方校验共享密钥后才会在该连接上执行 LOCALSCAN/LOCALEXEC 等内部命令
 
 type peerConn struct {
 	conn   net.Conn
@@ -25,6 +26,7 @@ type PeerClient struct {
 	addr        string
 	dialTimeout time.Duration
 	rwTimeout   time.Duration
+	secret      string
 
 	pool      chan *peerConn
 	closing   chan struct{}
@@ -32,7 +34,7 @@ type PeerClient struct {
 	mu        sync.Mutex
 }
 
-func NewPeerClient(addr string, poolSize int) *PeerClient {
+func NewPeerClient(addr string, poolSize int, secret string) *PeerClient {
 	if poolSize <= 0 {
 		poolSize = 4
 	}
@@ -40,6 +42,7 @@ func NewPeerClient(addr string, poolSize int) *PeerClient {
 		addr:        addr,
 		dialTimeout: 2 * time.Second,
 		rwTimeout:   5 * time.Second,
+		secret:      secret,
 		pool:        make(chan *peerConn, poolSize),
 		closing:     make(chan struct{}),
 	}
@@ -63,6 +66,12 @@ func (c *PeerClient) Close() {
 }
 
 func (c *PeerClient) Do(cmd [][]byte) (resp.Reply, error) {
+	return c.DoTimeout(cmd, c.rwTimeout, nil)
+}
+
+// DoTimeout 与 Do 相同，但使用指定的读写超时；timeout <= 0 表示不设超时（用于 BLPOP 0 等永久阻塞命令）。
+// done 关闭时放弃请求并关闭连接，目标节点读到连接断开后撤销阻塞命令的等待者；done 为 nil 表示不取消。
+func (c *PeerClient) DoTimeout(cmd [][]byte, timeout time.Duration, done <-chan struct{}) (resp.Reply, error) {
 	select {
 	case <-c.closing:
 		return nil, errors.New("peer client closed")
@@ -75,17 +84,25 @@ func (c *PeerClient) Do(cmd [][]byte) (resp.Reply, error) {
 	}
 
 	// 超时保护：避免 peer 卡住导致当前连接 goroutine 无限制阻塞
-	_ = pc.conn.SetDeadline(time.Now().Add(c.rwTimeout))
+	if timeout > 0 {
+		_ = pc.conn.SetDeadline(time.Now().Add(timeout))
+	}
 
-	// 发送请求
-	_, err = pc.conn.Write(resp.MakeMultiBulkReply(cmd).ToBytes())
-	if err != nil {
-		_ = pc.conn.Close()
-		return nil, err
+	var stop func() bool
+	if done != nil {
+		stop = watchCancel(pc, done)
 	}
 
-	// 读取单个 RESP reply
-	reply, err := pc.parser.ReadReply()
+	// 发送请求，读取单个 RESP reply
+	var reply resp.Reply
+	_, err = pc.conn.Write(resp.MakeMultiBulkReply(cmd).ToBytes())
+	if err == nil {
+		reply, err = pc.parser.ReadReply()
+	}
+	if stop != nil && stop() {
+		// 连接已由 watchCancel 关闭
+		return nil, errors.New("peer request canceled")
+	}
 	if err != nil {
 		_ = pc.conn.Close()
 		return nil, err
@@ -97,6 +114,25 @@ func (c *PeerClient) Do(cmd [][]byte) (resp.Reply, error) {
 	return reply, nil
 }
 
+// watchCancel 在 done 关闭时关闭 pc 的连接（进行中的读写随即返回错误）；返回的 stop 结束观察并报告连接是否已因取消而关闭。
+func watchCancel(pc *peerConn, done <-chan struct{}) (stop func() bool) {
+	stopCh := make(chan struct{})
+	canceled := make(chan bool, 1)
+	go func() {
+		select {
+		case <-done:
+			_ = pc.conn.Close()
+			canceled <- true
+		case <-stopCh:
+			canceled <- false
+		}
+	}()
+	return func() bool {
+		close(stopCh)
+		return <-canceled
+	}
+}
+
 func (c *PeerClient) acquire() (*peerConn, error) {
 	select {
 	case <-c.closing:
@@ -112,11 +148,34 @@ func (c *PeerClient) acquire() (*peerConn, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &peerConn{
+		pc := &peerConn{
 			conn:   conn,
 			parser: resp.NewStreamParser(conn),
-		}, nil
+		}
+		if err := c.handshake(pc); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return pc, nil
+	}
+}
+
+// handshake 发送共享密钥，把新连接标记为节点间连接。
+func (c *PeerClient) handshake(pc *peerConn) error {
+	_ = pc.conn.SetDeadline(time.Now().Add(c.rwTimeout))
+	defer pc.conn.SetDeadline(time.Time{})
+	cmd := [][]byte{[]byte(PeerHandshakeCommand), []byte(c.secret)}
+	if _, err := pc.conn.Write(resp.MakeMultiBulkReply(cmd).ToBytes()); err != nil {
+		return err
+	}
+	reply, err := pc.parser.ReadReply()
+	if err != nil {
+		return err
+	}
+	if er, ok := reply.(*resp.ErrorReply); ok {
+		return errors.New("peer handshake failed: " + er.Status)
 	}
+	return nil
 }
 
 func (c *PeerClient) release(pc *peerConn) {
@@ -44,6 +44,13 @@ func NewRing(nodes []string, vnodes int) *Ring {
 	return r
 }
 
+// Nodes 返回按地址排序的节点列表（副本）。各节点构造 Ring 时传入的节点集合相同，因此顺序在全集群一致。
+func (r *Ring) Nodes() []string {
+	nodes := append([]string(nil), r.nodes...)
+	sort.Strings(nodes)
+	return nodes
+}
+
 // NodeForKey 返回 key 应该落在哪个节点上。
 func (r *Ring) NodeForKey(key string) string {
 	if len(r.sortedHashes) == 0 {
@@ -1,13 +1,19 @@
 // Cluster Router：分布式路由器（对外实现 db.DB）。
-// 关键点：按 key 分片路由，本地执行或转发到目标节点；对 DEL 做跨节点分组与结果聚合。
+// 关键点：按 key 分片路由，本地执行或转发到目标节点；对 DEL/MGET/MSET 做跨节点分组与结果聚合。
 // 限制：当前不支持动态拓扑变更，也不支持 Redis Cluster 协议（MOVED/ASK 等）。
 package cluster
 
 import (
+	"crypto/subtle"
 	"myredis/db"
+	"myredis/module"
+	"myredis/pkg/hll"
+	"myredis/pubsub"
 	"myredis/resp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // 本文件实现分布式路由器（Router）：
@@ -16,29 +22,81 @@ import (
 //
 // 当前支持的路由规则：
 // - 单 key 命令：默认 key 在 args[1]
-// - 多 key 命令：DEL，会按 key 分组并聚合返回值
+// - 多 key 命令：DEL/EXISTS/TOUCH，会按 key 分组并聚合返回值
+// - 多 key 命令：MGET/MSET，按节点分组并行执行（scatter-gather），MGET 按请求顺序拼装结果
+// - 多 key 命令：MSETNX/RENAME/RENAMENX/COPY/LMOVE/RPOPLPUSH 需要原子性，只允许所有 key 落在同一节点，否则返回 CROSSSLOT 错误
+// - 无 key 命令（DBSIZE/RANDOMKEY/FLUSHALL 等）只作用于入口节点本地
+// - 阻塞命令 BLPOP/BRPOP/BLMOVE：key 必须同节点，转发时按阻塞超时放宽 peer 读写超时；客户端断开时（ExecBlocking）关闭 peer 连接撤销远端等待
+// - XREAD/XREADGROUP：key 为 STREAMS 之后的前一半参数，必须同节点；带 BLOCK 时同样放宽 peer 超时
+// - XGROUP：key 在 args[2]（子命令之后）
+// - 集合运算 SINTER/SUNION/SDIFF/SINTERCARD：key 跨节点时从各节点拉取 SMEMBERS，在入口节点计算
+// - 多 key PFCOUNT：key 跨节点时从各节点 GET 原始 HLL 字节，在入口节点合并估计
+// - 集合写命令 SINTERSTORE/SUNIONSTORE/SDIFFSTORE/SMOVE、BITOP、PFMERGE、GEOSEARCHSTORE：只允许所有 key 落在同一节点，否则返回 CROSSSLOT 错误
+// - EVAL/EVALSHA：key 为 numkeys 之后的参数，必须同节点（脚本在目标节点的 Actor 内原子执行）；没有 key 时在入口节点执行
+// - SCRIPT LOAD/FLUSH：广播到所有节点，保证 EVALSHA 转发到任意节点都能找到脚本；SCRIPT EXISTS 只查询入口节点
+// - 模块命令（myredis/module）：按注册时声明的 FirstKey/LastKey/KeyStep 取 key，多 key 必须同节点
+// - PUBLISH：在入口节点发布后并行转发到其它节点（订阅者可以连接任意节点），返回各节点接收者数量之和；PUBSUB 只统计入口节点
+// - SCAN：按节点顺序逐个遍历，游标 = 节点内游标 * 节点数 + 节点下标（无状态，可从任意入口节点继续）
+//
+// 节点间转发使用内部命令（LOCALSCAN、LOCALEXEC，见 IsPeerCommand）：PeerClient 建立连接后先发送
+// PEERHANDSHAKE <secret>，服务端只在共享密钥校验通过的连接上接受这些命令（见 VerifyPeerSecret）；
+// 普通客户端（包括自行发送 PEERHANDSHAKE 但不知道密钥的客户端）发送时返回 unknown command，不能绕过一致性哈希路由。
+
+// localCommands 为不带 key 的命令：只在入口节点本地执行（FLUSHALL ASYNC 等带参数的也不能按 args[1] 路由）。
+var localCommands = map[string]struct{}{
+	"ping": {}, "dbsize": {}, "randomkey": {}, "flushdb": {}, "flushall": {},
+	"save": {}, "bgsave": {}, "rewriteaof": {}, "bgrewriteaof": {},
+	"pubsub": {}, "config": {},
+}
+
+// localScanCommand 为节点间转发 SCAN 使用的内部命令名：接收方只扫描本地 keyspace。
+const localScanCommand = "localscan"
+
+// PeerHandshakeCommand 为 PeerClient 在新连接上发送的第一条命令（PEERHANDSHAKE secret），
+// 服务端用 VerifyPeerSecret 校验通过后才把连接标记为节点间连接。
+const PeerHandshakeCommand = "peerhandshake"
+
+// VerifyPeerSecret 校验 PEERHANDSHAKE 携带的密钥：secret 为空（未配置集群密钥）时拒绝所有握手；比较为常数时间。
+func VerifyPeerSecret(secret string, given []byte) bool {
+	if secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), given) == 1
+}
+
+// IsPeerCommand 判断 name（小写命令名）是否为只允许节点间连接执行的内部命令。
+func IsPeerCommand(name string) bool {
+	return name == localScanCommand || name == localExecCommand
+}
+
+// localExecCommand 为节点间广播使用的内部命令：LOCALEXEC cmd [arg ...] 让接收方只在本地执行 cmd，不再路由或广播。
+const localExecCommand = "localexec"
 
 type Router struct {
 	localAddr string
 	localDB   db.DB
 	ring      *Ring
+	// secret 为节点间共享密钥，PeerClient 握手时发送
+	secret string
 
 	peersMu sync.RWMutex
 	peers   map[string]*PeerClient // addr -> client
 }
 
-func NewRouter(localAddr string, localDB db.DB, nodes []string, vnodes int) *Router {
+// NewRouter 创建路由器；secret 为集群共享密钥，各节点必须一致（服务端以同一密钥校验 PEERHANDSHAKE）。
+func NewRouter(localAddr string, localDB db.DB, nodes []string, vnodes int, secret string) *Router {
 	r := &Router{
 		localAddr: localAddr,
 		localDB:   localDB,
 		ring:      NewRing(nodes, vnodes),
+		secret:    secret,
 		peers:     make(map[string]*PeerClient),
 	}
 	for _, n := range nodes {
 		if n == "" || n == localAddr {
 			continue
 		}
-		r.peers[n] = NewPeerClient(n, 4)
+		r.peers[n] = NewPeerClient(n, 4, r.secret)
 	}
 	return r
 }
@@ -65,35 +123,113 @@ func (r *Router) Exec(cmd [][]byte) resp.Reply {
 	name := strings.ToLower(string(cmd[0]))
 
 	// 无 key 的命令直接本地执行
-	if name == "ping" {
+	if _, ok := localCommands[name]; ok {
 		return r.localDB.Exec(cmd)
 	}
 
-	// 多 key：DEL 需要分组到各节点并聚合删除数量
-	if name == "del" {
-		return r.execDel(cmd)
+	switch name {
+	case "scan":
+		return r.execScan(cmd)
+	case localScanCommand:
+		// 其它节点转发来的 SCAN 分片：只遍历本地，不能再按集群游标拆分
+		sub := append([][]byte{[]byte("SCAN")}, cmd[1:]...)
+		return r.localDB.Exec(sub)
+	case localExecCommand:
+		if len(cmd) < 2 {
+			return resp.MakeErrReply("ERR wrong number of arguments for '" + localExecCommand + "' command")
+		}
+		return r.localDB.Exec(cmd[1:])
 	}
 
-	// 单 key 默认在 args[1]
-	if len(cmd) < 2 {
-		return r.localDB.Exec(cmd)
+	// 多 key：DEL/EXISTS/TOUCH 需要分组到各节点并聚合计数
+	switch name {
+	case "del", "exists", "touch":
+		return r.execKeyCount(cmd)
 	}
-	key := string(cmd[1])
-	target := r.ring.NodeForKey(key)
-	if target == "" || target == r.localAddr {
-		return r.localDB.Exec(cmd)
+	if reply, ok := r.execBlockingCommand(name, cmd, nil); ok {
+		return reply
 	}
 
-	reply, err := r.peerDo(target, cmd)
-	if err != nil {
-		return resp.MakeErrReply("ERR cluster forward failed: " + err.Error())
+	// 多 key：MGET/MSET 分组执行；MSETNX/RENAME/COPY/LMOVE 要求同节点
+	switch name {
+	case "mget":
+		return r.execMGet(cmd)
+	case "mset":
+		return r.execMSet(cmd)
+	case "msetnx":
+		return r.execMSetNX(cmd)
+	case "eval", "evalsha":
+		return r.execEval(cmd)
+	case "script":
+		return r.execScript(cmd)
+	case "publish":
+		return r.execPublish(cmd)
+	case "xgroup":
+		if len(cmd) < 3 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execOn(r.nodeFor(cmd[2]), cmd)
+	case "sinter", "sunion", "sdiff":
+		if len(cmd) < 2 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSetAlgebra(cmd, cmd[1:])
+	case "sintercard":
+		return r.execSInterCard(cmd)
+	case "pfcount":
+		if len(cmd) < 3 {
+			break
+		}
+		return r.execPFCount(cmd)
+	case "pfmerge":
+		if len(cmd) < 2 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, cmd[1:])
+	case "bitop":
+		// BITOP op destkey key [key ...]：key 从 args[2] 开始
+		if len(cmd) < 4 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, cmd[2:])
+	case "sinterstore", "sunionstore", "sdiffstore":
+		if len(cmd) < 3 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, cmd[1:])
+	case "smove", "geosearchstore":
+		if len(cmd) < 3 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, cmd[1:3])
+	case "rename", "renamenx", "copy", "lmove", "rpoplpush":
+		if len(cmd) < 3 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, cmd[1:3])
 	}
-	return reply
+
+	// 模块命令：按注册时声明的 key 位置路由（多 key 必须同节点；没有 key 时在入口节点执行）
+	if mc, ok := module.Lookup(name); ok {
+		keys := mc.Keys(cmd)
+		if len(keys) == 0 {
+			return r.localDB.Exec(cmd)
+		}
+		return r.execSameNode(cmd, keys)
+	}
+
+	// 单 key 默认在 args[1]
+	if len(cmd) < 2 {
+		return r.localDB.Exec(cmd)
+	}
+	return r.execOn(r.nodeFor(cmd[1]), cmd)
 }
 
-func (r *Router) execDel(cmd [][]byte) resp.Reply {
+// execKeyCount 处理 “多 key + 返回整数计数” 的命令（DEL/EXISTS/TOUCH）：按节点分组执行并求和。
+func (r *Router) execKeyCount(cmd [][]byte) resp.Reply {
+	name := strings.ToLower(string(cmd[0]))
 	if len(cmd) < 2 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'del' command")
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
 	}
 
 	// node -> keys
@@ -107,13 +243,13 @@ func (r *Router) execDel(cmd [][]byte) resp.Reply {
 		groups[node] = append(groups[node], kb)
 	}
 
-	type delResult struct {
+	type countResult struct {
 		count int64
 		err   resp.Reply
 	}
 
 	var wg sync.WaitGroup
-	results := make(chan delResult, len(groups))
+	results := make(chan countResult, len(groups))
 
 	for node, keys := range groups {
 		node := node
@@ -122,7 +258,7 @@ func (r *Router) execDel(cmd [][]byte) resp.Reply {
 		go func() {
 			defer wg.Done()
 			subCmd := make([][]byte, 0, len(keys)+1)
-			subCmd = append(subCmd, []byte("DEL"))
+			subCmd = append(subCmd, cmd[0])
 			subCmd = append(subCmd, keys...)
 
 			var reply resp.Reply
@@ -131,22 +267,22 @@ func (r *Router) execDel(cmd [][]byte) resp.Reply {
 			} else {
 				rep, err := r.peerDo(node, subCmd)
 				if err != nil {
-					results <- delResult{err: resp.MakeErrReply("ERR cluster forward failed: " + err.Error())}
+					results <- countResult{err: resp.MakeErrReply("ERR cluster forward failed: " + err.Error())}
 					return
 				}
 				reply = rep
 			}
 
 			if er, ok := reply.(*resp.ErrorReply); ok {
-				results <- delResult{err: er}
+				results <- countResult{err: er}
 				return
 			}
 			intReply, ok := reply.(*resp.IntReply)
 			if !ok {
-				results <- delResult{err: resp.MakeErrReply("ERR cluster: DEL unexpected reply")}
+				results <- countResult{err: resp.MakeErrReply("ERR cluster: " + strings.ToUpper(name) + " unexpected reply")}
 				return
 			}
-			results <- delResult{count: intReply.Code}
+			results <- countResult{count: intReply.Code}
 		}()
 	}
 
@@ -163,7 +299,574 @@ func (r *Router) execDel(cmd [][]byte) resp.Reply {
 	return resp.MakeIntReply(total)
 }
 
+func (r *Router) execMGet(cmd [][]byte) resp.Reply {
+	if len(cmd) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'mget' command")
+	}
+	keys := cmd[1:]
+
+	// node -> keys 在原请求中的下标（用于按原顺序回填结果）
+	groups := make(map[string][]int)
+	for i, kb := range keys {
+		node := r.nodeFor(kb)
+		groups[node] = append(groups[node], i)
+	}
+
+	values := make([][]byte, len(keys))
+	var (
+		wg     sync.WaitGroup
+		errMu  sync.Mutex
+		errRep resp.Reply
+	)
+	for node, idxs := range groups {
+		node := node
+		idxs := idxs
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			subCmd := make([][]byte, 0, len(idxs)+1)
+			subCmd = append(subCmd, []byte("MGET"))
+			for _, i := range idxs {
+				subCmd = append(subCmd, keys[i])
+			}
+
+			reply := r.execOn(node, subCmd)
+			mb, ok := reply.(*resp.MultiBulkReply)
+			if !ok || mb == nil || len(mb.Args) != len(idxs) {
+				errMu.Lock()
+				if er, isErr := reply.(*resp.ErrorReply); isErr {
+					errRep = er
+				} else {
+					errRep = resp.MakeErrReply("ERR cluster: MGET unexpected reply")
+				}
+				errMu.Unlock()
+				return
+			}
+			// 各分组的下标互不重叠，可以直接并发写入 values
+			for j, i := range idxs {
+				values[i] = mb.Args[j]
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errRep != nil {
+		return errRep
+	}
+	return resp.MakeMultiBulkReply(values)
+}
+
+// execMSet 按节点分组执行 MSET。
+// 注意：跨节点的 MSET 不是原子的（某个节点失败时，其它节点可能已经写入）。
+func (r *Router) execMSet(cmd [][]byte) resp.Reply {
+	if len(cmd) < 3 || len(cmd)%2 != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'mset' command")
+	}
+
+	// node -> [k1 v1 k2 v2 ...]
+	groups := make(map[string][][]byte)
+	for i := 1; i < len(cmd); i += 2 {
+		node := r.nodeFor(cmd[i])
+		groups[node] = append(groups[node], cmd[i], cmd[i+1])
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan resp.Reply, len(groups))
+	for node, pairs := range groups {
+		node := node
+		pairs := pairs
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			subCmd := make([][]byte, 0, len(pairs)+1)
+			subCmd = append(subCmd, []byte("MSET"))
+			subCmd = append(subCmd, pairs...)
+			results <- r.execOn(node, subCmd)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for reply := range results {
+		if er, ok := reply.(*resp.ErrorReply); ok {
+			return er
+		}
+	}
+	return resp.OkReply
+}
+
+// execMSetNX 只在所有 key 落在同一节点时执行（保证“全部写入或全部不写”的原子语义）。
+func (r *Router) execMSetNX(cmd [][]byte) resp.Reply {
+	if len(cmd) < 3 || len(cmd)%2 != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'msetnx' command")
+	}
+	keys := make([][]byte, 0, len(cmd)/2)
+	for i := 1; i < len(cmd); i += 2 {
+		keys = append(keys, cmd[i])
+	}
+	return r.execSameNode(cmd, keys)
+}
+
+// execSameNode 要求 keys 全部落在同一节点，然后把完整命令交给该节点执行；否则返回 CROSSSLOT 错误。
+func (r *Router) execSameNode(cmd [][]byte, keys [][]byte) resp.Reply {
+	if !r.sameNode(keys) {
+		return resp.MakeErrReply("CROSSSLOT Keys in request don't hash to the same slot")
+	}
+	return r.execOn(r.nodeFor(keys[0]), cmd)
+}
+
+// execSetAlgebra 执行 SINTER/SUNION/SDIFF：key 同节点时整体转发，否则并行拉取各 key 的成员后在本地计算。
+func (r *Router) execSetAlgebra(cmd [][]byte, keys [][]byte) resp.Reply {
+	if r.sameNode(keys) {
+		return r.execOn(r.nodeFor(keys[0]), cmd)
+	}
+	var op db.SetOp
+	switch strings.ToLower(string(cmd[0])) {
+	case "sunion":
+		op = db.SetUnion
+	case "sdiff":
+		op = db.SetDiff
+	default:
+		op = db.SetInter
+	}
+	sets, errReply := r.fetchSets(keys)
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeSetBulkReply(db.CombineSets(op, sets).Members())
+}
+
+// execSInterCard 执行 SINTERCARD numkeys key [key ...] [LIMIT limit]。
+// 参数不合法时交给本地节点返回与单机一致的错误。
+func (r *Router) execSInterCard(cmd [][]byte) resp.Reply {
+	if len(cmd) < 3 {
+		return r.localDB.Exec(cmd)
+	}
+	numKeys, err := strconv.Atoi(string(cmd[1]))
+	if err != nil || numKeys <= 0 || numKeys > len(cmd)-2 {
+		return r.localDB.Exec(cmd)
+	}
+	keys := cmd[2 : 2+numKeys]
+	if r.sameNode(keys) {
+		return r.execOn(r.nodeFor(keys[0]), cmd)
+	}
+	limit := int64(0)
+	if rest := cmd[2+numKeys:]; len(rest) > 0 {
+		if len(rest) != 2 || !strings.EqualFold(string(rest[0]), "limit") {
+			return r.localDB.Exec(cmd)
+		}
+		limit, err = strconv.ParseInt(string(rest[1]), 10, 64)
+		if err != nil || limit < 0 {
+			return r.localDB.Exec(cmd)
+		}
+	}
+	sets, errReply := r.fetchSets(keys)
+	if errReply != nil {
+		return errReply
+	}
+	n := int64(db.CombineSets(db.SetInter, sets).Card())
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	return resp.MakeIntReply(n)
+}
+
+// fetchSets 并行读取各 key 的集合成员（SMEMBERS），按 keys 顺序返回；任一 key 出错（如 WRONGTYPE）则返回该错误。
+func (r *Router) fetchSets(keys [][]byte) ([]db.SetData, resp.Reply) {
+	sets := make([]db.SetData, len(keys))
+	errs := make([]resp.Reply, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		i, k := i, k
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := r.execOn(r.nodeFor(k), [][]byte{[]byte("SMEMBERS"), k})
+			mb, ok := reply.(*resp.MultiBulkReply)
+			if !ok {
+				if er, isErr := reply.(*resp.ErrorReply); isErr {
+					errs[i] = er
+				} else {
+					errs[i] = resp.MakeErrReply("ERR cluster: SMEMBERS unexpected reply")
+				}
+				return
+			}
+			sets[i] = db.NewSetData(mb.Args)
+		}()
+	}
+	wg.Wait()
+	// 按 key 顺序返回第一个错误，与单机执行时的报错一致
+	for _, e := range errs {
+		if e != nil {
+			return nil, e
+		}
+	}
+	return sets, nil
+}
+
+// execPFCount 执行多 key PFCOUNT：key 同节点时整体转发，否则拉取各 key 的 HLL 字节在本地合并估计。
+func (r *Router) execPFCount(cmd [][]byte) resp.Reply {
+	keys := cmd[1:]
+	if r.sameNode(keys) {
+		return r.execOn(r.nodeFor(keys[0]), cmd)
+	}
+	vals := make([]resp.Reply, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		i, k := i, k
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vals[i] = r.execOn(r.nodeFor(k), [][]byte{[]byte("GET"), k})
+		}()
+	}
+	wg.Wait()
+
+	merged := new(hll.Regs)
+	for _, v := range vals {
+		switch v := v.(type) {
+		case *resp.ErrorReply:
+			return v
+		case *resp.BulkReply:
+			if v.Arg == nil {
+				continue
+			}
+			regs, err := hll.Decode(v.Arg)
+			if err == hll.ErrCorrupted {
+				return resp.MakeErrReply("INVALIDOBJ Corrupted HLL object detected")
+			}
+			if err != nil {
+				return resp.MakeErrReply("WRONGTYPE Key is not a valid HyperLogLog string value.")
+			}
+			merged.Merge(regs)
+		default:
+			return resp.MakeErrReply("ERR cluster: GET unexpected reply")
+		}
+	}
+	return resp.MakeIntReply(int64(merged.Count()))
+}
+
+// sameNode 判断 keys 是否全部落在同一节点。
+func (r *Router) sameNode(keys [][]byte) bool {
+	node := r.nodeFor(keys[0])
+	for _, k := range keys[1:] {
+		if r.nodeFor(k) != node {
+			return false
+		}
+	}
+	return true
+}
+
+// execScan 实现集群范围的 SCAN：游标低位（对节点数取模）编码当前节点下标，其余部分为该节点的本地游标。
+// 某个节点返回游标 0 后切换到下一个节点；最后一个节点遍历完毕时返回 0。
+func (r *Router) execScan(cmd [][]byte) resp.Reply {
+	nodes := r.ring.Nodes()
+	if len(cmd) < 2 || len(nodes) == 0 {
+		return r.localDB.Exec(cmd)
+	}
+	cursor, err := strconv.ParseUint(string(cmd[1]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR invalid cursor")
+	}
+	n := uint64(len(nodes))
+	idx, local := cursor%n, cursor/n
+
+	subCmd := make([][]byte, len(cmd))
+	copy(subCmd, cmd)
+	subCmd[1] = []byte(strconv.FormatUint(local, 10))
+	var reply resp.Reply
+	if nodes[idx] == r.localAddr {
+		reply = r.localDB.Exec(subCmd)
+	} else {
+		subCmd[0] = []byte(localScanCommand)
+		reply = r.execOn(nodes[idx], subCmd)
+	}
+	if _, ok := reply.(*resp.ErrorReply); ok {
+		return reply
+	}
+	raw, ok := reply.(*resp.MultiRawReply)
+	if !ok || len(raw.Replies) != 2 {
+		return resp.MakeErrReply("ERR cluster: SCAN unexpected reply")
+	}
+	cb, ok1 := raw.Replies[0].(*resp.BulkReply)
+	keys, ok2 := raw.Replies[1].(*resp.MultiBulkReply)
+	if !ok1 || !ok2 {
+		return resp.MakeErrReply("ERR cluster: SCAN unexpected reply")
+	}
+	next, err := strconv.ParseUint(string(cb.Arg), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR cluster: SCAN unexpected reply")
+	}
+
+	var out uint64
+	switch {
+	case next != 0:
+		out = next*n + idx
+	case idx+1 < n:
+		out = idx + 1 // 下一个节点，本地游标从 0 开始
+	default:
+		out = 0
+	}
+	elems := keys.Args
+	if elems == nil {
+		elems = [][]byte{}
+	}
+	return resp.MakeMultiRawReply([]resp.Reply{
+		resp.MakeBulkReply([]byte(strconv.FormatUint(out, 10))),
+		resp.MakeMultiBulkReply(elems),
+	})
+}
+
+// ExecBlocking 与 Exec 相同；阻塞命令在 done 关闭（客户端断开）时撤销，见 db.BlockingDB：
+// 本地执行交给 localDB 撤销等待者，转发时关闭 peer 连接，目标节点随之撤销等待者。
+func (r *Router) ExecBlocking(cmd [][]byte, done <-chan struct{}) resp.Reply {
+	if len(cmd) > 0 {
+		if reply, ok := r.execBlockingCommand(strings.ToLower(string(cmd[0])), cmd, done); ok {
+			return reply
+		}
+	}
+	return r.Exec(cmd)
+}
+
+// execBlockingCommand 路由可能阻塞的命令（BLPOP/BRPOP/BLMOVE/XREAD/XREADGROUP）；不是这些命令时返回 false。
+func (r *Router) execBlockingCommand(name string, cmd [][]byte, done <-chan struct{}) (resp.Reply, bool) {
+	switch name {
+	case "blpop", "brpop":
+		if len(cmd) < 3 {
+			return r.localDB.Exec(cmd), true
+		}
+		return r.execBlocking(cmd, cmd[1:len(cmd)-1], cmd[len(cmd)-1], done), true
+	case "blmove":
+		if len(cmd) != 6 {
+			return r.localDB.Exec(cmd), true
+		}
+		return r.execBlocking(cmd, cmd[1:3], cmd[5], done), true
+	case "xread", "xreadgroup":
+		return r.execXRead(cmd, done), true
+	}
+	return nil, false
+}
+
+// execBlocking 处理阻塞命令（BLPOP/BRPOP/BLMOVE）：timeoutArg 为秒数，见 execBlockingTimeout。
+func (r *Router) execBlocking(cmd [][]byte, keys [][]byte, timeoutArg []byte, done <-chan struct{}) resp.Reply {
+	sec, err := strconv.ParseFloat(string(timeoutArg), 64)
+	if err != nil || sec < 0 {
+		return r.execBlockingTimeout(cmd, keys, -1, done)
+	}
+	return r.execBlockingTimeout(cmd, keys, time.Duration(sec*float64(time.Second)), done)
+}
+
+// execBlockingTimeout 转发阻塞命令：key 必须落在同一节点；
+// 转发时读写超时放宽为“阻塞超时 + 默认超时”（timeout 为 0 时不设超时），避免 peer 连接提前超时导致弹出的元素丢失。
+// timeout < 0 表示超时参数非法，直接交给目标节点返回标准错误。
+func (r *Router) execBlockingTimeout(cmd [][]byte, keys [][]byte, timeout time.Duration, done <-chan struct{}) resp.Reply {
+	node := r.nodeFor(keys[0])
+	for _, k := range keys[1:] {
+		if r.nodeFor(k) != node {
+			return resp.MakeErrReply("CROSSSLOT Keys in request don't hash to the same slot")
+		}
+	}
+	if node == r.localAddr {
+		if bdb, ok := r.localDB.(db.BlockingDB); ok {
+			return bdb.ExecBlocking(cmd, done)
+		}
+		return r.localDB.Exec(cmd)
+	}
+	if timeout < 0 {
+		return r.execOn(node, cmd)
+	}
+
+	c := r.peer(node)
+	if timeout > 0 {
+		timeout += c.rwTimeout
+	}
+	reply, err := c.DoTimeout(cmd, timeout, done)
+	if err != nil {
+		return resp.MakeErrReply("ERR cluster forward failed: " + err.Error())
+	}
+	return reply
+}
+
+// execXRead 路由 XREAD/XREADGROUP：key 为 STREAMS 之后的前一半参数；BLOCK 以毫秒为单位。
+func (r *Router) execXRead(cmd [][]byte, done <-chan struct{}) resp.Reply {
+	streamsIdx := 0
+	blocking := false
+	var timeout time.Duration
+loop:
+	for i := 1; i < len(cmd); i++ {
+		switch strings.ToLower(string(cmd[i])) {
+		case "count":
+			i++
+		case "group":
+			i += 2
+		case "block":
+			blocking, timeout = true, -1
+			if i+1 < len(cmd) {
+				if ms, err := strconv.ParseInt(string(cmd[i+1]), 10, 64); err == nil && ms >= 0 {
+					timeout = time.Duration(ms) * time.Millisecond
+				}
+			}
+			i++
+		case "streams":
+			streamsIdx = i + 1
+			break loop
+		}
+	}
+	rest := len(cmd) - streamsIdx
+	if streamsIdx == 0 || rest == 0 || rest%2 != 0 {
+		// 语法错误交给本地返回标准错误
+		return r.localDB.Exec(cmd)
+	}
+	keys := cmd[streamsIdx : streamsIdx+rest/2]
+	if !blocking {
+		return r.execSameNode(cmd, keys)
+	}
+	return r.execBlockingTimeout(cmd, keys, timeout, done)
+}
+
+// execEval 路由 EVAL/EVALSHA：按 numkeys 取出 key，要求全部落在同一节点。
+func (r *Router) execEval(cmd [][]byte) resp.Reply {
+	if len(cmd) < 3 {
+		return r.localDB.Exec(cmd)
+	}
+	numKeys, err := strconv.Atoi(string(cmd[2]))
+	if err != nil || numKeys <= 0 || numKeys > len(cmd)-3 {
+		// 参数错误或没有 key：交给入口节点（由它返回对应错误）
+		return r.localDB.Exec(cmd)
+	}
+	return r.execSameNode(cmd, cmd[3:3+numKeys])
+}
+
+// execScript 路由 SCRIPT：LOAD/FLUSH 广播到所有节点并返回入口节点的结果，其它子命令只在入口节点执行。
+func (r *Router) execScript(cmd [][]byte) resp.Reply {
+	if len(cmd) < 2 {
+		return r.localDB.Exec(cmd)
+	}
+	sub := strings.ToLower(string(cmd[1]))
+	if sub != "load" && sub != "flush" {
+		return r.localDB.Exec(cmd)
+	}
+	reply := r.localDB.Exec(cmd)
+	if _, ok := reply.(*resp.ErrorReply); ok {
+		return reply
+	}
+	// 以 LOCALEXEC 转发，避免接收方再次广播
+	forward := append([][]byte{[]byte(localExecCommand)}, cmd...)
+	for _, node := range r.ring.Nodes() {
+		if node == r.localAddr {
+			continue
+		}
+		res := r.execOn(node, forward)
+		if _, ok := res.(*resp.ErrorReply); ok {
+			return res
+		}
+	}
+	return reply
+}
+
+// execPublish 在本地发布后并行转发到其它节点，返回接收者总数（不可达的节点不计入，也不影响发布结果）。
+func (r *Router) execPublish(cmd [][]byte) resp.Reply {
+	reply := r.localDB.Exec(cmd)
+	local, ok := reply.(*resp.IntReply)
+	if !ok {
+		return reply
+	}
+
+	forward := append([][]byte{[]byte(localExecCommand)}, cmd...)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total = local.Code
+	)
+	for _, node := range r.ring.Nodes() {
+		if node == r.localAddr {
+			continue
+		}
+		node := node
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.peerDo(node, forward)
+			if err != nil {
+				return
+			}
+			if n, ok := res.(*resp.IntReply); ok {
+				mu.Lock()
+				total += n.Code
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return resp.MakeIntReply(total)
+}
+
+// PubSub 返回本地节点的发布订阅中心：SUBSCRIBE 只在客户端连接的节点上登记，PUBLISH 负责跨节点转发。
+func (r *Router) PubSub() *pubsub.Hub {
+	if ps, ok := r.localDB.(db.PubSubDB); ok {
+		return ps.PubSub()
+	}
+	return nil
+}
+
+// nodeFor 返回 key 所属节点；环为空时退化为本地节点。
+func (r *Router) nodeFor(key []byte) string {
+	node := r.ring.NodeForKey(string(key))
+	if node == "" {
+		return r.localAddr
+	}
+	return node
+}
+
+// execOn 在指定节点执行命令（本地直接执行，远端通过 PeerClient 转发）。
+func (r *Router) execOn(node string, cmd [][]byte) resp.Reply {
+	if node == r.localAddr {
+		return r.localDB.Exec(cmd)
+	}
+	reply, err := r.peerDo(node, cmd)
+	if err != nil {
+		return resp.MakeErrReply("ERR cluster forward failed: " + err.Error())
+	}
+	if mark, ok := replyShapes[strings.ToLower(string(cmd[0]))]; ok {
+		mark(reply)
+	}
+	return reply
+}
+
+// replyShapes 记录单机实现中带 RESP3 形态标记（MultiBulkReply.Kind / BulkReply.Double）的命令。
+// 节点间连接固定使用 RESP2，转发回来的回复丢失了这些标记，由入口节点按命令名重新标记，
+// 这样 HELLO 3 的客户端访问远端 key 时与本地 key 看到相同的 map/set/double。
+var replyShapes = map[string]func(resp.Reply){
+	"hgetall":  markKind(resp.KindMap),
+	"smembers": markKind(resp.KindSet),
+	"sinter":   markKind(resp.KindSet),
+	"sunion":   markKind(resp.KindSet),
+	"sdiff":    markKind(resp.KindSet),
+	"zscore":   markDouble,
+	"zincrby":  markDouble,
+	"zadd":     markDouble, // 只有 INCR 选项返回 bulk
+}
+
+func markKind(kind resp.AggregateKind) func(resp.Reply) {
+	return func(reply resp.Reply) {
+		if mb, ok := reply.(*resp.MultiBulkReply); ok {
+			mb.Kind = kind
+		}
+	}
+}
+
+func markDouble(reply resp.Reply) {
+	if b, ok := reply.(*resp.BulkReply); ok && b.Arg != nil {
+		b.Double = true
+	}
+}
+
 func (r *Router) peerDo(addr string, cmd [][]byte) (resp.Reply, error) {
+	return r.peer(addr).Do(cmd)
+}
+
+// peer 返回 addr 对应的 PeerClient（不存在时创建）。
+func (r *Router) peer(addr string) *PeerClient {
 	r.peersMu.RLock()
 	c := r.peers[addr]
 	r.peersMu.RUnlock()
@@ -172,10 +875,10 @@ func (r *Router) peerDo(addr string, cmd [][]byte) (resp.Reply, error) {
 		// double check
 		c = r.peers[addr]
 		if c == nil {
-			c = NewPeerClient(addr, 4)
+			c = NewPeerClient(addr, 4, r.secret)
 			r.peers[addr] = c
 		}
 		r.peersMu.Unlock()
 	}
-	return c.Do(cmd)
+	return c
 }
@@ -9,7 +9,9 @@ import (
 	"log"
 	"myredis/cluster"
 	"myredis/db"
+	"myredis/resp"
 	"myredis/server"
+	"os"
 	"os/signal"
 	"strings"
 	"syscall"
@@ -29,17 +31,29 @@ func main() {
 	eviction := flag.String("eviction", "lru", "eviction policy: lru|lfu")
 	maxBytes := flag.Int64("max-bytes", db.DefaultMaxBytes, "max memory in bytes for eviction")
 	vnodes := flag.Int("vnodes", 160, "virtual nodes for consistent hashing")
+	clusterSecret := flag.String("cluster-secret", os.Getenv("MYREDIS_CLUSTER_SECRET"), "shared secret authenticating inter-node connections, required with --nodes (default $MYREDIS_CLUSTER_SECRET)")
+	notifyEvents := flag.String("notify-keyspace-events", "", "keyspace notification flags as in redis.conf, e.g. Ex (empty to disable)")
+	maxBulkLen := flag.Int64("proto-max-bulk-len", resp.DefaultLimits.MaxBulkLen, "max length in bytes of a single bulk string in requests")
+	maxMultiBulkLen := flag.Int64("max-multibulk-len", resp.DefaultLimits.MaxMultiBulkLen, "max number of elements in a request array")
+	queryBufferLimit := flag.Int64("client-query-buffer-limit", resp.DefaultLimits.MaxQueryBuffer, "max size in bytes of a single client request")
 	flag.Parse()
 
 	if strings.ToLower(strings.TrimSpace(*appendfsync)) != "everysec" {
 		log.Fatal("only --appendfsync=everysec is supported")
 	}
+	if !db.ValidNotifyKeyspaceEvents(*notifyEvents) {
+		log.Fatal("invalid --notify-keyspace-events, use characters from 'Ag$lshzxeKEtmdn'")
+	}
+	if *maxBulkLen < 1 || *maxMultiBulkLen < 1 || *queryBufferLimit < 1 {
+		log.Fatal("--proto-max-bulk-len, --max-multibulk-len and --client-query-buffer-limit must be positive")
+	}
 
 	localDB := db.NewStandaloneDBWithConfig(db.StandaloneDBConfig{
-		AofFilename: *aofFile,
-		RdbFilename: *rdbFile,
-		MaxBytes:    *maxBytes,
-		Eviction:    *eviction,
+		AofFilename:          *aofFile,
+		RdbFilename:          *rdbFile,
+		MaxBytes:             *maxBytes,
+		Eviction:             *eviction,
+		NotifyKeyspaceEvents: *notifyEvents,
 	})
 
 	var database db.DB = localDB
@@ -48,7 +62,10 @@ func main() {
 		if !containsNode(nodeList, *addr) {
 			log.Fatal("--addr must be included in --nodes when cluster mode enabled")
 		}
-		database = cluster.NewRouter(*addr, localDB, nodeList, *vnodes)
+		if *clusterSecret == "" {
+			log.Fatal("--cluster-secret (or MYREDIS_CLUSTER_SECRET) is required when cluster mode enabled")
+		}
+		database = cluster.NewRouter(*addr, localDB, nodeList, *vnodes, *clusterSecret)
 	}
 
 	// Load AOF (Persistence)
@@ -56,6 +73,12 @@ func main() {
 
 	// Initialize Server
 	s := server.NewServer(*addr, database)
+	s.Limits = resp.Limits{
+		MaxBulkLen:      *maxBulkLen,
+		MaxMultiBulkLen: *maxMultiBulkLen,
+		MaxQueryBuffer:  *queryBufferLimit,
+	}
+	s.ClusterSecret = *clusterSecret
 
 	// Ctrl+C / SIGTERM 优雅关闭
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
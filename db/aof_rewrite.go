@@ -15,6 +15,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"myredis/module"
 	"myredis/rdb"
 	"myredis/resp"
 	"os"
@@ -206,6 +207,28 @@ func snapshotEntryToCommands(e rdb.Entry) ([][][]byte, error) {
 			cmd = append(cmd, pairs[i:end]...)
 			out = append(out, cmd)
 		}
+		// 字段级 TTL 以绝对时间（HPEXPIREAT）重建，相同过期时间的字段合并为一条命令
+		byDeadline := make(map[int64][]string)
+		for _, f := range fields {
+			if at, ok := e.HashExpire[f]; ok {
+				byDeadline[at] = append(byDeadline[at], f)
+			}
+		}
+		deadlines := make([]int64, 0, len(byDeadline))
+		for at := range byDeadline {
+			deadlines = append(deadlines, at)
+		}
+		sort.Slice(deadlines, func(i, j int) bool { return deadlines[i] < deadlines[j] })
+		for _, at := range deadlines {
+			fs := byDeadline[at]
+			cmd := make([][]byte, 0, 5+len(fs))
+			cmd = append(cmd, []byte("HPEXPIREAT"), key, []byte(strconv.FormatInt(at, 10)),
+				[]byte("FIELDS"), []byte(strconv.Itoa(len(fs))))
+			for _, f := range fs {
+				cmd = append(cmd, []byte(f))
+			}
+			out = append(out, cmd)
+		}
 	case rdb.TypeSet:
 		members := append([]string(nil), e.Set...)
 		sort.Strings(members)
@@ -221,6 +244,27 @@ func snapshotEntryToCommands(e rdb.Entry) ([][][]byte, error) {
 			}
 			out = append(out, cmd)
 		}
+	case rdb.TypeZSet:
+		for i := 0; i < len(e.ZSet); i += batch {
+			end := i + batch
+			if end > len(e.ZSet) {
+				end = len(e.ZSet)
+			}
+			cmd := make([][]byte, 0, 2+2*(end-i))
+			cmd = append(cmd, []byte("ZADD"), key)
+			for _, zm := range e.ZSet[i:end] {
+				cmd = append(cmd, []byte(formatScore(zm.Score)), []byte(zm.Member))
+			}
+			out = append(out, cmd)
+		}
+	case rdb.TypeStream:
+		out = append(out, streamToCommands(key, e.Stream)...)
+	case rdb.TypeModule:
+		cmds, err := moduleToCommands(key, e)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cmds...)
 	default:
 		return nil, errors.New("unknown snapshot entry type")
 	}
@@ -234,3 +278,65 @@ func snapshotEntryToCommands(e rdb.Entry) ([][][]byte, error) {
 	}
 	return out, nil
 }
+
+// streamToCommands 用 XADD 重建条目，XSETID 恢复 last-id 等元数据，XGROUP/XCLAIM 恢复消费者组与 PEL。
+// 空 Stream 先用 XADD MAXLEN 0 创建再删空（last-id 随后由 XSETID 覆盖）。
+func streamToCommands(key []byte, s *rdb.Stream) [][][]byte {
+	if s == nil {
+		return nil
+	}
+	formatID := func(id rdb.StreamID) []byte {
+		return []byte(strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10))
+	}
+	var out [][][]byte
+	for _, e := range s.Entries {
+		cmd := make([][]byte, 0, 3+len(e.Fields))
+		cmd = append(cmd, []byte("XADD"), key, formatID(e.ID))
+		cmd = append(cmd, e.Fields...)
+		out = append(out, cmd)
+	}
+	if len(s.Entries) == 0 {
+		id := s.LastID
+		if id.Ms == 0 && id.Seq == 0 {
+			id.Seq = 1
+		}
+		out = append(out, [][]byte{[]byte("XADD"), key, []byte("MAXLEN"), []byte("0"), formatID(id), []byte("x"), []byte("y")})
+	}
+	out = append(out, [][]byte{
+		[]byte("XSETID"), key, formatID(s.LastID),
+		[]byte("ENTRIESADDED"), []byte(strconv.FormatUint(s.EntriesAdded, 10)),
+		[]byte("MAXDELETEDID"), formatID(s.MaxDeletedID),
+	})
+	for _, g := range s.Groups {
+		name := []byte(g.Name)
+		out = append(out, [][]byte{[]byte("XGROUP"), []byte("CREATE"), key, name, formatID(g.LastID)})
+		for _, c := range g.Consumers {
+			out = append(out, [][]byte{[]byte("XGROUP"), []byte("CREATECONSUMER"), key, name, []byte(c.Name)})
+		}
+		for _, p := range g.Pending {
+			out = append(out, [][]byte{
+				[]byte("XCLAIM"), key, name, []byte(p.Consumer), []byte("0"), formatID(p.ID),
+				[]byte("TIME"), []byte(strconv.FormatInt(p.DeliveryTime, 10)),
+				[]byte("RETRYCOUNT"), []byte(strconv.FormatInt(p.DeliveryCount, 10)),
+				[]byte("FORCE"), []byte("JUSTID"),
+			})
+		}
+	}
+	return out
+}
+
+// moduleToCommands 解码快照中的模块值，由模块的 AofRewrite 钩子生成重建命令。
+func moduleToCommands(key []byte, e rdb.Entry) ([][][]byte, error) {
+	t, ok := module.LookupType(e.ModuleType)
+	if !ok {
+		return nil, errors.New("module type '" + e.ModuleType + "' is not registered")
+	}
+	if t.AofRewrite == nil {
+		return nil, errors.New("module type '" + e.ModuleType + "' does not support AOF rewrite")
+	}
+	v, err := t.Decode(e.Module)
+	if err != nil {
+		return nil, err
+	}
+	return t.AofRewrite(key, v), nil
+}
@@ -4,30 +4,147 @@
 package db
 
 import (
+	"math"
 	"myredis/resp"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// 本文件实现 String 相关命令：SET / GET / DEL
+// 本文件实现 String 相关命令：
+// - SET / GET / DEL / SETNX / GETSET / GETDEL / GETEX
+// - INCR / DECR / INCRBY / DECRBY / INCRBYFLOAT
+// - APPEND / STRLEN / GETRANGE / SETRANGE / MGET / MSET / MSETNX
 // 说明：
 // - 数据存储在可插拔 cache（LRU/LFU）中
 // - TTL 由 db.ttlMap 管理（惰性删除 + 定期删除）
 
-// SET key value
+// setOptions 为 SET 命令解析后的选项。
+type setOptions struct {
+	nx      bool
+	xx      bool
+	get     bool
+	keepTTL bool
+	// expireAt 为 EX/PX/EXAT/PXAT 换算后的绝对过期时间；零值表示未指定过期选项
+	expireAt time.Time
+}
+
+// parseSetArgs 解析 SET key value [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|KEEPTTL]
+func parseSetArgs(args [][]byte) (setOptions, resp.Reply) {
+	var opts setOptions
+	if len(args) < 3 {
+		return opts, resp.MakeErrReply("ERR wrong number of arguments for 'set' command")
+	}
+
+	hasExpire := false
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(string(args[i])); opt {
+		case "NX":
+			if opts.xx {
+				return opts, resp.MakeErrReply("ERR syntax error")
+			}
+			opts.nx = true
+		case "XX":
+			if opts.nx {
+				return opts, resp.MakeErrReply("ERR syntax error")
+			}
+			opts.xx = true
+		case "GET":
+			opts.get = true
+		case "KEEPTTL":
+			if hasExpire {
+				return opts, resp.MakeErrReply("ERR syntax error")
+			}
+			opts.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if hasExpire || opts.keepTTL || i+1 >= len(args) {
+				return opts, resp.MakeErrReply("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return opts, resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			// 换算为毫秒时间戳时需要防止溢出
+			unit, absolute := expireOption(opt)
+			ms, ok := expireAtMillis(n, unit, absolute)
+			if n <= 0 || !ok {
+				return opts, resp.MakeErrReply("ERR invalid expire time in 'set' command")
+			}
+			opts.expireAt = time.UnixMilli(ms)
+			hasExpire = true
+			i++
+		default:
+			return opts, resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	return opts, nil
+}
+
+// setApplied 根据 SET 的选项与回包判断本次写入是否真正生效（用于 AOF 改写）。
+// - 无 NX/XX：总是生效
+// - 无 GET：成功回 +OK，条件不满足回 nil
+// - 带 GET：回包为旧值；NX 仅在旧值为 nil 时生效，XX 仅在旧值非 nil 时生效
+func setApplied(opts setOptions, res resp.Reply) bool {
+	if !opts.nx && !opts.xx {
+		return true
+	}
+	br, isBulk := res.(*resp.BulkReply)
+	isNull := isBulk && br.Arg == nil
+	if !opts.get {
+		return !isNull
+	}
+	if opts.nx {
+		return isNull
+	}
+	return !isNull
+}
+
+// SET key value [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|KEEPTTL]
 func (db *StandaloneDB) set(args [][]byte) resp.Reply {
-	if len(args) != 3 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'set' command")
+	opts, errReply := parseSetArgs(args)
+	if errReply != nil {
+		return errReply
 	}
 	key := string(args[1])
 	val := args[2]
 
+	entity, exists := db.getEntity(key)
+
+	// GET：返回旧值；旧值不是字符串时按 Redis 语义报 WRONGTYPE（且不写入）
+	var oldReply resp.Reply = resp.NullBulkReply
+	if opts.get && exists {
+		str, ok := entity.(StringData)
+		if !ok {
+			return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+		}
+		oldReply = resp.MakeBulkReply(append([]byte(nil), str...))
+	}
+
+	if (opts.nx && exists) || (opts.xx && !exists) {
+		if opts.get {
+			return oldReply
+		}
+		return resp.NullBulkReply
+	}
+
 	// Store as StringData (implements Len())
 	db.cache.Add(key, StringData(val), 0)
 
-	// DEL ttl if exists (SET removes expire)
-	// Redis behavior: SET key val invokes "Remove expire".
-	delete(db.ttlMap, key)
+	// Redis behavior: SET 默认清除旧 TTL；KEEPTTL 保留；EX/PX/EXAT/PXAT 覆盖为新的绝对过期时间
+	switch {
+	case !opts.expireAt.IsZero():
+		// 写入可能因 maxBytes 触发自身被淘汰，此时不应残留 ttlMap 条目
+		if _, ok := db.cache.Peek(key); ok {
+			db.ttlMap[key] = opts.expireAt
+		}
+	case opts.keepTTL:
+	default:
+		delete(db.ttlMap, key)
+	}
 
+	if opts.get {
+		return oldReply
+	}
 	return resp.OkReply
 }
 
@@ -94,3 +211,387 @@ func (db *StandaloneDB) del(args [][]byte) resp.Reply {
 
 	return resp.MakeIntReply(int64(deleted))
 }
+
+// getString 读取字符串值（含惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+func (db *StandaloneDB) getString(key string) (StringData, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return nil, false, nil
+	}
+	str, ok := entity.(StringData)
+	if !ok {
+		return nil, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	return str, true, nil
+}
+
+// INCR key
+func (db *StandaloneDB) incr(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'incr' command")
+	}
+	return db.incrBy(string(args[1]), 1)
+}
+
+// DECR key
+func (db *StandaloneDB) decr(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'decr' command")
+	}
+	return db.incrBy(string(args[1]), -1)
+}
+
+// INCRBY key increment
+func (db *StandaloneDB) incrby(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'incrby' command")
+	}
+	delta, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	return db.incrBy(string(args[1]), delta)
+}
+
+// DECRBY key decrement
+func (db *StandaloneDB) decrby(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'decrby' command")
+	}
+	delta, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	// -MinInt64 无法表示
+	if delta == math.MinInt64 {
+		return resp.MakeErrReply("ERR decrement would overflow")
+	}
+	return db.incrBy(string(args[1]), -delta)
+}
+
+// incrBy 为整数自增的公共实现：key 不存在视为 0；保留原有 TTL（与 Redis 一致）。
+func (db *StandaloneDB) incrBy(key string, delta int64) resp.Reply {
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var cur int64
+	if exists {
+		n, err := strconv.ParseInt(string(str), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		cur = n
+	}
+
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return resp.MakeErrReply("ERR increment or decrement would overflow")
+	}
+	cur += delta
+
+	db.cache.Add(key, StringData(strconv.FormatInt(cur, 10)), 0)
+	return resp.MakeIntReply(cur)
+}
+
+// INCRBYFLOAT key increment
+func (db *StandaloneDB) incrbyfloat(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'incrbyfloat' command")
+	}
+	key := string(args[1])
+	delta, err := strconv.ParseFloat(string(args[2]), 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return resp.MakeErrReply("ERR value is not a valid float")
+	}
+
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var cur float64
+	if exists {
+		f, err := strconv.ParseFloat(string(str), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return resp.MakeErrReply("ERR value is not a valid float")
+		}
+		cur = f
+	}
+
+	cur += delta
+	if math.IsNaN(cur) || math.IsInf(cur, 0) {
+		return resp.MakeErrReply("ERR increment would produce NaN or Infinity")
+	}
+
+	val := []byte(strconv.FormatFloat(cur, 'f', -1, 64))
+	db.cache.Add(key, StringData(val), 0)
+	return resp.MakeBulkReply(val)
+}
+
+// maxStringLength 对齐 Redis proto-max-bulk-len 默认值（512MB），防止 SETRANGE 造成超大分配。
+const maxStringLength = 512 * 1024 * 1024
+
+// APPEND key value
+func (db *StandaloneDB) appendCmd(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'append' command")
+	}
+	key := string(args[1])
+	str, _, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+	if len(str)+len(args[2]) > maxStringLength {
+		return resp.MakeErrReply("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+
+	// 总是分配新 slice：旧值可能仍被尚未写出的回包引用，不能原地修改
+	val := make([]byte, 0, len(str)+len(args[2]))
+	val = append(val, str...)
+	val = append(val, args[2]...)
+	db.cache.Add(key, StringData(val), 0)
+	return resp.MakeIntReply(int64(len(val)))
+}
+
+// STRLEN key
+func (db *StandaloneDB) strlen(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'strlen' command")
+	}
+	str, _, errReply := db.getString(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeIntReply(int64(len(str)))
+}
+
+// GETRANGE key start end
+func (db *StandaloneDB) getrange(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'getrange' command")
+	}
+	start, err1 := strconv.ParseInt(string(args[2]), 10, 64)
+	end, err2 := strconv.ParseInt(string(args[3]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	str, _, errReply := db.getString(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+
+	size := int64(len(str))
+	if start < 0 && end < 0 && start > end {
+		return resp.MakeBulkReply([]byte{})
+	}
+	if start < 0 {
+		start = size + start
+	}
+	if end < 0 {
+		end = size + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= size {
+		end = size - 1
+	}
+	if size == 0 || start > end {
+		return resp.MakeBulkReply([]byte{})
+	}
+	return resp.MakeBulkReply(str[start : end+1])
+}
+
+// SETRANGE key offset value
+func (db *StandaloneDB) setrange(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'setrange' command")
+	}
+	key := string(args[1])
+	offset, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	if offset < 0 {
+		return resp.MakeErrReply("ERR offset is out of range")
+	}
+	value := args[3]
+
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+	// 空 value 不创建/修改 key，直接返回当前长度
+	if len(value) == 0 {
+		return resp.MakeIntReply(int64(len(str)))
+	}
+	if offset+int64(len(value)) > maxStringLength {
+		return resp.MakeErrReply("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+
+	size := int64(len(str))
+	if need := offset + int64(len(value)); need > size {
+		size = need
+	}
+	// 不足部分以 0 字节填充；同样分配新 slice，避免修改被回包引用的旧值
+	val := make([]byte, size)
+	if exists {
+		copy(val, str)
+	}
+	copy(val[offset:], value)
+	db.cache.Add(key, StringData(val), 0)
+	return resp.MakeIntReply(int64(len(val)))
+}
+
+// MGET key [key ...]
+func (db *StandaloneDB) mget(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'mget' command")
+	}
+	res := make([][]byte, 0, len(args)-1)
+	for _, k := range args[1:] {
+		// 与 Redis 一致：不存在或类型不符的 key 返回 nil，而不是报错
+		str, exists, errReply := db.getString(string(k))
+		if !exists || errReply != nil {
+			res = append(res, nil)
+			continue
+		}
+		res = append(res, str)
+	}
+	return resp.MakeMultiBulkReply(res)
+}
+
+// MSET key value [key value ...]
+func (db *StandaloneDB) mset(args [][]byte) resp.Reply {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'mset' command")
+	}
+	for i := 1; i < len(args); i += 2 {
+		key := string(args[i])
+		db.cache.Add(key, StringData(args[i+1]), 0)
+		delete(db.ttlMap, key)
+	}
+	return resp.OkReply
+}
+
+// MSETNX key value [key value ...]：任一 key 已存在则全部不写入
+func (db *StandaloneDB) msetnx(args [][]byte) resp.Reply {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'msetnx' command")
+	}
+	for i := 1; i < len(args); i += 2 {
+		if _, exists := db.getEntity(string(args[i])); exists {
+			return resp.MakeIntReply(0)
+		}
+	}
+	for i := 1; i < len(args); i += 2 {
+		db.cache.Add(string(args[i]), StringData(args[i+1]), 0)
+	}
+	return resp.MakeIntReply(1)
+}
+
+// SETNX key value
+func (db *StandaloneDB) setnx(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'setnx' command")
+	}
+	key := string(args[1])
+	if _, exists := db.getEntity(key); exists {
+		return resp.MakeIntReply(0)
+	}
+	db.cache.Add(key, StringData(args[2]), 0)
+	return resp.MakeIntReply(1)
+}
+
+// GETSET key value：返回旧值并覆盖（清除 TTL）
+func (db *StandaloneDB) getset(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'getset' command")
+	}
+	key := string(args[1])
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+	db.cache.Add(key, StringData(args[2]), 0)
+	delete(db.ttlMap, key)
+	if !exists {
+		return resp.NullBulkReply
+	}
+	return resp.MakeBulkReply(str)
+}
+
+// GETDEL key
+func (db *StandaloneDB) getdel(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'getdel' command")
+	}
+	key := string(args[1])
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	db.cache.Remove(key)
+	return resp.MakeBulkReply(str)
+}
+
+// GETEX key [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|PERSIST]
+func (db *StandaloneDB) getex(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'getex' command")
+	}
+	key := string(args[1])
+
+	var expireAt time.Time
+	persist := false
+	if len(args) > 2 {
+		opt := strings.ToUpper(string(args[2]))
+		switch {
+		case opt == "PERSIST" && len(args) == 3:
+			persist = true
+		case (opt == "EX" || opt == "PX" || opt == "EXAT" || opt == "PXAT") && len(args) == 4:
+			n, err := strconv.ParseInt(string(args[3]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			unit, absolute := expireOption(opt)
+			ms, ok := expireAtMillis(n, unit, absolute)
+			if n <= 0 || !ok {
+				return resp.MakeErrReply("ERR invalid expire time in 'getex' command")
+			}
+			expireAt = time.UnixMilli(ms)
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	str, exists, errReply := db.getString(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+
+	switch {
+	case persist:
+		delete(db.ttlMap, key)
+		db.touchWatched(key)
+	case !expireAt.IsZero():
+		if !expireAt.After(time.Now()) {
+			// 绝对时间已过：立即删除（AOF 中记为 DEL）
+			db.cache.Remove(key)
+		} else {
+			db.ttlMap[key] = expireAt
+			db.touchWatched(key)
+		}
+	}
+	return resp.MakeBulkReply(str)
+}
@@ -0,0 +1,355 @@
+// String 命令测试：覆盖原子计数器（INCR/DECR/INCRBY/DECRBY/INCRBYFLOAT）与 SET 完整选项语义。
+// 目标：计数器/锁在服务端原子完成，错误文案与 Redis 对齐，并能经 AOF 重放恢复。
+// 覆盖：整数溢出、非整数值、WRONGTYPE、TTL 保留、SET NX/XX/GET/EX/PX/KEEPTTL、过期参数边界值、AOF PXAT 改写。
+package db
+
+import (
+	"bytes"
+	"math"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestString_IncrFamily(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if n := replyInt(t, execArgs(d, "INCR", "c")); n != 1 {
+		t.Fatalf("INCR = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "INCRBY", "c", "10")); n != 11 {
+		t.Fatalf("INCRBY = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "DECRBY", "c", "5")); n != 6 {
+		t.Fatalf("DECRBY = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "DECR", "c")); n != 5 {
+		t.Fatalf("DECR = %d", n)
+	}
+
+	// 非整数
+	execArgs(d, "SET", "s", "abc")
+	if er, ok := execArgs(d, "INCR", "s").(*resp.ErrorReply); !ok || er.Status != "ERR value is not an integer or out of range" {
+		t.Fatalf("expected not-integer error, got %+v", er)
+	}
+
+	// 溢出
+	execArgs(d, "SET", "max", strconv.FormatInt(math.MaxInt64, 10))
+	if er, ok := execArgs(d, "INCR", "max").(*resp.ErrorReply); !ok || er.Status != "ERR increment or decrement would overflow" {
+		t.Fatalf("expected overflow error, got %+v", er)
+	}
+	if _, ok := execArgs(d, "DECRBY", "c", strconv.FormatInt(math.MinInt64, 10)).(*resp.ErrorReply); !ok {
+		t.Fatalf("expected overflow error for DECRBY MinInt64")
+	}
+
+	// WRONGTYPE
+	execArgs(d, "LPUSH", "l", "a")
+	if _, ok := execArgs(d, "INCR", "l").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected WRONGTYPE")
+	}
+
+	// INCRBYFLOAT
+	execArgs(d, "SET", "f", "10.5")
+	if br := execArgs(d, "INCRBYFLOAT", "f", "0.1").(*resp.BulkReply); string(br.Arg) != "10.6" {
+		t.Fatalf("INCRBYFLOAT = %q", br.Arg)
+	}
+	if br := execArgs(d, "INCRBYFLOAT", "f", "-5.6").(*resp.BulkReply); string(br.Arg) != "5" {
+		t.Fatalf("INCRBYFLOAT = %q", br.Arg)
+	}
+	if _, ok := execArgs(d, "INCRBYFLOAT", "f", "inf").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected invalid float error")
+	}
+
+	// INCR 不应清除 TTL
+	execArgs(d, "EXPIRE", "c", "100")
+	execArgs(d, "INCR", "c")
+	if ttl := replyInt(t, execArgs(d, "TTL", "c")); ttl <= 0 {
+		t.Fatalf("TTL after INCR = %d", ttl)
+	}
+}
+
+func TestString_IncrSurvivesRestart(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+
+	db1 := NewStandaloneDB(filename)
+	for i := 0; i < 5; i++ {
+		execArgs(db1, "INCR", "counter")
+	}
+	execArgs(db1, "INCRBYFLOAT", "ratio", "1.25")
+	// 失败的命令不应写入 AOF
+	execArgs(db1, "INCRBY", "counter", "notanumber")
+	db1.Close()
+
+	db2 := NewStandaloneDB(filename)
+	db2.Load()
+	defer db2.Close()
+
+	if br := execArgs(db2, "GET", "counter").(*resp.BulkReply); string(br.Arg) != "5" {
+		t.Fatalf("GET counter = %q", br.Arg)
+	}
+	if br := execArgs(db2, "GET", "ratio").(*resp.BulkReply); string(br.Arg) != "1.25" {
+		t.Fatalf("GET ratio = %q", br.Arg)
+	}
+}
+
+func TestString_SetOptions(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	// NX：分布式锁语义
+	if _, ok := execArgs(d, "SET", "lock", "owner1", "NX", "PX", "30000").(*resp.StatusReply); !ok {
+		t.Fatalf("SET NX on missing key should succeed")
+	}
+	if br, ok := execArgs(d, "SET", "lock", "owner2", "NX", "PX", "30000").(*resp.BulkReply); !ok || br.Arg != nil {
+		t.Fatalf("SET NX on existing key should return nil, got %+v", br)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "lock")); ttl < 29 || ttl > 30 {
+		t.Fatalf("TTL lock = %d", ttl)
+	}
+
+	// XX：key 不存在时不写入
+	if br, ok := execArgs(d, "SET", "missing", "v", "XX").(*resp.BulkReply); !ok || br.Arg != nil {
+		t.Fatalf("SET XX on missing key should return nil")
+	}
+
+	// GET：返回旧值
+	if br := execArgs(d, "SET", "lock", "owner3", "GET").(*resp.BulkReply); string(br.Arg) != "owner1" {
+		t.Fatalf("SET GET old = %q", br.Arg)
+	}
+	// 普通 SET 清除 TTL
+	if ttl := replyInt(t, execArgs(d, "TTL", "lock")); ttl != -1 {
+		t.Fatalf("TTL after plain SET = %d", ttl)
+	}
+
+	// KEEPTTL 保留 TTL
+	execArgs(d, "SET", "k", "v1", "EX", "100")
+	execArgs(d, "SET", "k", "v2", "KEEPTTL")
+	if ttl := replyInt(t, execArgs(d, "TTL", "k")); ttl <= 0 {
+		t.Fatalf("TTL after KEEPTTL = %d", ttl)
+	}
+
+	// 非法组合
+	for _, args := range [][]string{
+		{"SET", "k", "v", "NX", "XX"},
+		{"SET", "k", "v", "EX", "10", "PX", "100"},
+		{"SET", "k", "v", "EX", "10", "KEEPTTL"},
+		{"SET", "k", "v", "EX", "0"},
+		{"SET", "k", "v", "EX"},
+		{"SET", "k", "v", "BOGUS"},
+	} {
+		if _, ok := execArgs(d, args...).(*resp.ErrorReply); !ok {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+
+	// GET 遇到非字符串旧值报 WRONGTYPE，且不覆盖
+	execArgs(d, "LPUSH", "l", "a")
+	if _, ok := execArgs(d, "SET", "l", "v", "GET").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected WRONGTYPE for SET GET on list")
+	}
+	if n := replyInt(t, execArgs(d, "LLEN", "l")); n != 1 {
+		t.Fatalf("list should be untouched, LLEN = %d", n)
+	}
+}
+
+// TestString_SetExpireBoundaries：EX/PX/EXAT/PXAT 按毫秒时间戳换算，超过约 292 年（time.Duration 上限）
+// 仍能正确设置；换算溢出 int64 时回复 invalid expire time，且不写入。
+func TestString_SetExpireBoundaries(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	maxSec := strconv.FormatInt(math.MaxInt64/1000, 10)
+	maxSecPlus := strconv.FormatInt(math.MaxInt64/1000+1, 10)
+	maxMs := strconv.FormatInt(math.MaxInt64, 10)
+	for _, c := range []struct {
+		opt, n string
+		want   int64 // 期望的 PEXPIRETIME；0 表示按当前时间 + 相对值校验
+		rel    int64
+	}{
+		{"EX", "10000000000", 0, 10000000000 * 1000},
+		{"PX", strconv.FormatInt(math.MaxInt64/2, 10), 0, math.MaxInt64 / 2},
+		{"EXAT", maxSec, math.MaxInt64 / 1000 * 1000, 0},
+		{"PXAT", maxMs, math.MaxInt64, 0},
+	} {
+		now := time.Now().UnixMilli()
+		if r, ok := execArgs(d, "SET", "k", "v", c.opt, c.n).(*resp.StatusReply); !ok || r.Status != "OK" {
+			t.Fatalf("SET %s %s = %+v", c.opt, c.n, r)
+		}
+		got := replyInt(t, execArgs(d, "PEXPIRETIME", "k"))
+		if c.want != 0 && got != c.want {
+			t.Fatalf("SET %s %s: PEXPIRETIME = %d, want %d", c.opt, c.n, got, c.want)
+		}
+		if c.want == 0 && (got < now+c.rel || got > now+c.rel+5000) {
+			t.Fatalf("SET %s %s: PEXPIRETIME = %d, want about %d", c.opt, c.n, got, now+c.rel)
+		}
+		if n := replyInt(t, execArgs(d, "EXISTS", "k")); n != 1 {
+			t.Fatalf("SET %s %s: key should exist", c.opt, c.n)
+		}
+	}
+
+	execArgs(d, "SET", "k", "v")
+	for _, args := range [][]string{
+		{"EX", maxSec}, {"EX", maxSecPlus}, {"PX", maxMs}, {"EXAT", maxSecPlus},
+		{"EX", "-1"}, {"PXAT", "0"},
+	} {
+		r, ok := execArgs(d, append([]string{"SET", "k", "x"}, args...)...).(*resp.ErrorReply)
+		if !ok || r.Status != "ERR invalid expire time in 'set' command" {
+			t.Fatalf("SET %v = %+v", args, r)
+		}
+	}
+	if br := execArgs(d, "GET", "k").(*resp.BulkReply); string(br.Arg) != "v" {
+		t.Fatalf("rejected SET should not write, GET k = %q", br.Arg)
+	}
+	if ttl := replyInt(t, execArgs(d, "PTTL", "k")); ttl != -1 {
+		t.Fatalf("rejected SET should not set TTL, PTTL = %d", ttl)
+	}
+}
+
+// TestString_GetExExpireBoundaries：GETEX 与 SET 共用过期换算，大过期值设置 TTL 而不是删除 key。
+func TestString_GetExExpireBoundaries(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "k", "v")
+	now := time.Now().UnixMilli()
+	if br, ok := execArgs(d, "GETEX", "k", "EX", "10000000000").(*resp.BulkReply); !ok || string(br.Arg) != "v" {
+		t.Fatalf("GETEX EX 10000000000 = %+v", br)
+	}
+	if at := replyInt(t, execArgs(d, "PEXPIRETIME", "k")); at < now+1e13 || at > now+1e13+5000 {
+		t.Fatalf("GETEX EX 10000000000: PEXPIRETIME = %d", at)
+	}
+	execArgs(d, "GETEX", "k", "PXAT", strconv.FormatInt(math.MaxInt64, 10))
+	if at := replyInt(t, execArgs(d, "PEXPIRETIME", "k")); at != math.MaxInt64 {
+		t.Fatalf("GETEX PXAT max: PEXPIRETIME = %d", at)
+	}
+
+	execArgs(d, "PERSIST", "k")
+	for _, args := range [][]string{
+		{"EX", strconv.FormatInt(math.MaxInt64/1000, 10)},
+		{"PX", strconv.FormatInt(math.MaxInt64, 10)},
+		{"EXAT", strconv.FormatInt(math.MaxInt64/1000+1, 10)},
+		{"PX", "0"},
+	} {
+		r, ok := execArgs(d, append([]string{"GETEX", "k"}, args...)...).(*resp.ErrorReply)
+		if !ok || r.Status != "ERR invalid expire time in 'getex' command" {
+			t.Fatalf("GETEX %v = %+v", args, r)
+		}
+	}
+	if ttl := replyInt(t, execArgs(d, "PTTL", "k")); ttl != -1 {
+		t.Fatalf("rejected GETEX should keep the key without TTL, PTTL = %d", ttl)
+	}
+}
+
+func TestString_SetExpireRewrittenToPXAT(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+
+	db1 := NewStandaloneDB(filename)
+	execArgs(db1, "SET", "k", "v", "EX", "5")
+	execArgs(db1, "SET", "k", "other", "NX") // 未生效，不应写入 AOF
+	if err := db1.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	if !bytes.Contains(data, []byte("PXAT")) || bytes.Contains(data, []byte("$2\r\nEX\r\n")) {
+		t.Fatalf("expected SET rewritten with PXAT, got %q", data)
+	}
+	if bytes.Contains(data, []byte("other")) {
+		t.Fatalf("aborted SET NX should not be logged, got %q", data)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	db1.Close()
+
+	db2 := NewStandaloneDB(filename)
+	db2.Load()
+	defer db2.Close()
+	if br := execArgs(db2, "GET", "k").(*resp.BulkReply); string(br.Arg) != "v" {
+		t.Fatalf("GET k = %q", br.Arg)
+	}
+	if ttl := replyInt(t, execArgs(db2, "TTL", "k")); ttl < 0 || ttl > 4 {
+		t.Fatalf("TTL after restart = %d (expected 0..4)", ttl)
+	}
+}
+
+func TestString_RangeAndMultiKey(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if n := replyInt(t, execArgs(d, "APPEND", "log", "hello")); n != 5 {
+		t.Fatalf("APPEND = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "APPEND", "log", " world")); n != 11 {
+		t.Fatalf("APPEND = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "STRLEN", "log")); n != 11 {
+		t.Fatalf("STRLEN = %d", n)
+	}
+	if br := execArgs(d, "GETRANGE", "log", "-5", "-1").(*resp.BulkReply); string(br.Arg) != "world" {
+		t.Fatalf("GETRANGE = %q", br.Arg)
+	}
+	if br := execArgs(d, "GETRANGE", "log", "20", "30").(*resp.BulkReply); string(br.Arg) != "" {
+		t.Fatalf("GETRANGE out of range = %q", br.Arg)
+	}
+	if n := replyInt(t, execArgs(d, "SETRANGE", "log", "6", "WORLD")); n != 11 {
+		t.Fatalf("SETRANGE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SETRANGE", "pad", "3", "x")); n != 4 {
+		t.Fatalf("SETRANGE pad = %d", n)
+	}
+	if br := execArgs(d, "GET", "pad").(*resp.BulkReply); string(br.Arg) != "\x00\x00\x00x" {
+		t.Fatalf("GET pad = %q", br.Arg)
+	}
+
+	if _, ok := execArgs(d, "MSET", "a", "1", "b", "2").(*resp.StatusReply); !ok {
+		t.Fatalf("MSET failed")
+	}
+	execArgs(d, "LPUSH", "list", "x")
+	got := replyStrings(t, execArgs(d, "MGET", "a", "missing", "b", "list"))
+	if len(got) != 4 || got[0] != "1" || got[1] != "" || got[2] != "2" || got[3] != "" {
+		t.Fatalf("MGET = %q", got)
+	}
+	if n := replyInt(t, execArgs(d, "MSETNX", "c", "3", "a", "x")); n != 0 {
+		t.Fatalf("MSETNX with existing key = %d", n)
+	}
+	if br := execArgs(d, "GET", "c").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("MSETNX should not write any key")
+	}
+	if n := replyInt(t, execArgs(d, "MSETNX", "c", "3", "d", "4")); n != 1 {
+		t.Fatalf("MSETNX = %d", n)
+	}
+
+	if n := replyInt(t, execArgs(d, "SETNX", "a", "x")); n != 0 {
+		t.Fatalf("SETNX existing = %d", n)
+	}
+	execArgs(d, "EXPIRE", "a", "100")
+	if br := execArgs(d, "GETSET", "a", "new").(*resp.BulkReply); string(br.Arg) != "1" {
+		t.Fatalf("GETSET = %q", br.Arg)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "a")); ttl != -1 {
+		t.Fatalf("GETSET should clear TTL, got %d", ttl)
+	}
+	if br := execArgs(d, "GETDEL", "a").(*resp.BulkReply); string(br.Arg) != "new" {
+		t.Fatalf("GETDEL = %q", br.Arg)
+	}
+	if br := execArgs(d, "GET", "a").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("GETDEL should delete key")
+	}
+
+	if br := execArgs(d, "GETEX", "b", "EX", "100").(*resp.BulkReply); string(br.Arg) != "2" {
+		t.Fatalf("GETEX = %q", br.Arg)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "b")); ttl <= 0 {
+		t.Fatalf("GETEX EX should set TTL, got %d", ttl)
+	}
+	execArgs(d, "GETEX", "b", "PERSIST")
+	if ttl := replyInt(t, execArgs(d, "TTL", "b")); ttl != -1 {
+		t.Fatalf("GETEX PERSIST should clear TTL, got %d", ttl)
+	}
+}
@@ -0,0 +1,214 @@
+// Bitmap 命令测试：覆盖 SETBIT/GETBIT 自动补 0、BITCOUNT/BITPOS 的 BYTE/BIT 区间、BITOP 与 BITFIELD 溢出模式。
+// 目标：用 Redis 文档中的示例校验位序与边界语义（负数下标、全 1 时 BITPOS 0 的返回值等）。
+// 覆盖：SETBIT/BITOP/BITFIELD 写入 AOF，重放后位图一致；只含 GET 的 BITFIELD 不写 AOF。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBitmap_SetGetCountPos(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if n := replyInt(t, execArgs(d, "SETBIT", "bm", "7", "1")); n != 0 {
+		t.Fatalf("SETBIT = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SETBIT", "bm", "7", "0")); n != 1 {
+		t.Fatalf("SETBIT old = %d", n)
+	}
+	execArgs(d, "SETBIT", "bm", "7", "1")
+	if n := replyInt(t, execArgs(d, "GETBIT", "bm", "100")); n != 0 {
+		t.Fatalf("GETBIT beyond length = %d", n)
+	}
+	if br := execArgs(d, "GET", "bm").(*resp.BulkReply); !bytes.Equal(br.Arg, []byte{0x01}) {
+		t.Fatalf("GET bm = %q", br.Arg)
+	}
+	execArgs(d, "SETBIT", "bm", "23", "1")
+	if n := replyInt(t, execArgs(d, "STRLEN", "bm")); n != 3 {
+		t.Fatalf("SETBIT should zero-pad, STRLEN = %d", n)
+	}
+	if _, ok := execArgs(d, "SETBIT", "bm", "1", "2").(*resp.ErrorReply); !ok {
+		t.Fatalf("SETBIT value 2 should be rejected")
+	}
+	if _, ok := execArgs(d, "SETBIT", "bm", "4294967296", "1").(*resp.ErrorReply); !ok {
+		t.Fatalf("SETBIT offset beyond 512MB should be rejected")
+	}
+
+	execArgs(d, "SET", "s", "foobar")
+	for _, c := range []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"BITCOUNT", "s"}, 26},
+		{[]string{"BITCOUNT", "s", "0", "0"}, 4},
+		{[]string{"BITCOUNT", "s", "1", "1", "BYTE"}, 6},
+		{[]string{"BITCOUNT", "s", "5", "30", "BIT"}, 17},
+		{[]string{"BITCOUNT", "missing"}, 0},
+	} {
+		if n := replyInt(t, execArgs(d, c.args...)); n != c.want {
+			t.Fatalf("%v = %d, want %d", c.args, n, c.want)
+		}
+	}
+
+	execArgs(d, "SET", "p1", "\xff\xf0\x00")
+	execArgs(d, "SET", "p2", "\x00\xff\xf0")
+	execArgs(d, "SET", "ones", "\xff\xff\xff")
+	for _, c := range []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"BITPOS", "p1", "0"}, 12},
+		{[]string{"BITPOS", "p2", "1", "0"}, 8},
+		{[]string{"BITPOS", "p2", "1", "2"}, 16},
+		{[]string{"BITPOS", "p2", "1", "2", "-1", "BYTE"}, 16},
+		{[]string{"BITPOS", "p2", "1", "7", "15", "BIT"}, 8},
+		{[]string{"BITPOS", "p2", "1", "17", "-1", "BIT"}, 17},
+		{[]string{"BITPOS", "ones", "0"}, 24},
+		{[]string{"BITPOS", "ones", "0", "0", "-1"}, -1},
+		{[]string{"BITPOS", "missing", "0"}, 0},
+		{[]string{"BITPOS", "missing", "1"}, -1},
+	} {
+		if n := replyInt(t, execArgs(d, c.args...)); n != c.want {
+			t.Fatalf("%v = %d, want %d", c.args, n, c.want)
+		}
+	}
+}
+
+func TestBitmap_BitOp(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "k1", "foobar")
+	execArgs(d, "SET", "k2", "abcdef")
+	if n := replyInt(t, execArgs(d, "BITOP", "AND", "dest", "k1", "k2")); n != 6 {
+		t.Fatalf("BITOP AND = %d", n)
+	}
+	if br := execArgs(d, "GET", "dest").(*resp.BulkReply); string(br.Arg) != "`bc`ab" {
+		t.Fatalf("BITOP AND result = %q", br.Arg)
+	}
+	// 较短或缺失的源在右侧补 0
+	execArgs(d, "SET", "short", "\xff")
+	execArgs(d, "BITOP", "OR", "dest", "short", "missing", "k2")
+	if br := execArgs(d, "GET", "dest").(*resp.BulkReply); string(br.Arg) != "\xffbcdef" {
+		t.Fatalf("BITOP OR result = %q", br.Arg)
+	}
+	execArgs(d, "BITOP", "NOT", "dest", "short")
+	if br := execArgs(d, "GET", "dest").(*resp.BulkReply); !bytes.Equal(br.Arg, []byte{0x00}) {
+		t.Fatalf("BITOP NOT result = %q", br.Arg)
+	}
+	if n := replyInt(t, execArgs(d, "BITOP", "XOR", "dest", "missing")); n != 0 {
+		t.Fatalf("BITOP on missing keys = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "dest")); n != 0 {
+		t.Fatalf("empty BITOP result should delete dest")
+	}
+	if er, ok := execArgs(d, "BITOP", "NOT", "dest", "k1", "k2").(*resp.ErrorReply); !ok || er.Status != "ERR BITOP NOT must be called with a single source key." {
+		t.Fatalf("BITOP NOT with two keys = %+v", er)
+	}
+	execArgs(d, "LPUSH", "list", "x")
+	if _, ok := execArgs(d, "BITOP", "AND", "dest", "k1", "list").(*resp.ErrorReply); !ok {
+		t.Fatalf("BITOP with list source should be WRONGTYPE")
+	}
+}
+
+func TestBitmap_BitField(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	ints := func(args ...string) []int64 {
+		t.Helper()
+		raw, ok := execArgs(d, args...).(*resp.MultiRawReply)
+		if !ok {
+			t.Fatalf("%v: expected raw array", args)
+		}
+		out := make([]int64, 0, len(raw.Replies))
+		for _, r := range raw.Replies {
+			if ir, ok := r.(*resp.IntReply); ok {
+				out = append(out, ir.Code)
+			} else {
+				out = append(out, -999) // nil（FAIL 溢出）
+			}
+		}
+		return out
+	}
+
+	if got := ints("BITFIELD", "bf", "INCRBY", "i5", "100", "1", "GET", "u4", "0"); !intsEqual(got, 1, 0) {
+		t.Fatalf("BITFIELD INCRBY/GET = %v", got)
+	}
+	if got := ints("BITFIELD", "bf", "SET", "i8", "#1", "-100", "GET", "u8", "#1", "GET", "i8", "8"); !intsEqual(got, 0, 156, -100) {
+		t.Fatalf("BITFIELD SET/GET = %v", got)
+	}
+
+	// u2 的三种溢出模式
+	want := [][]int64{{1, 1, 1}, {2, 2, 2}, {3, 3, 3}, {0, 3, -999}}
+	for i, w := range want {
+		got := ints("BITFIELD", "ov", "INCRBY", "u2", "100", "1",
+			"OVERFLOW", "SAT", "INCRBY", "u2", "102", "1",
+			"OVERFLOW", "FAIL", "INCRBY", "u2", "104", "1")
+		if !intsEqual(got, w...) {
+			t.Fatalf("round %d: BITFIELD overflow = %v, want %v", i, got, w)
+		}
+	}
+	// 有符号：WRAP 回绕到最小值，SAT 停在最大值
+	if got := ints("BITFIELD", "sg", "SET", "i8", "0", "127", "INCRBY", "i8", "0", "1"); !intsEqual(got, 0, -128) {
+		t.Fatalf("BITFIELD i8 wrap = %v", got)
+	}
+	if got := ints("BITFIELD", "sg", "OVERFLOW", "SAT", "INCRBY", "i8", "0", "-1000", "SET", "i64", "64", "-1"); !intsEqual(got, -128, 0) {
+		t.Fatalf("BITFIELD i8 sat = %v", got)
+	}
+	if got := ints("BITFIELD_RO", "sg", "GET", "i64", "64"); !intsEqual(got, -1) {
+		t.Fatalf("BITFIELD_RO = %v", got)
+	}
+
+	if _, ok := execArgs(d, "BITFIELD", "bf", "GET", "u64", "0").(*resp.ErrorReply); !ok {
+		t.Fatalf("u64 should be rejected")
+	}
+	if _, ok := execArgs(d, "BITFIELD", "bf", "OVERFLOW", "BAD").(*resp.ErrorReply); !ok {
+		t.Fatalf("invalid OVERFLOW should be rejected")
+	}
+	if _, ok := execArgs(d, "BITFIELD_RO", "bf", "SET", "u8", "0", "1").(*resp.ErrorReply); !ok {
+		t.Fatalf("BITFIELD_RO SET should be rejected")
+	}
+}
+
+func TestBitmap_AOFReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	execArgs(d, "SETBIT", "dau:1", "5", "1")
+	execArgs(d, "SETBIT", "dau:1", "42", "1")
+	execArgs(d, "SETBIT", "dau:2", "42", "1")
+	execArgs(d, "BITOP", "AND", "both", "dau:1", "dau:2")
+	execArgs(d, "BITFIELD", "cnt", "INCRBY", "u16", "#0", "300")
+	execArgs(d, "BITFIELD", "cnt", "GET", "u16", "#0")
+	if err := d.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	if n := bytes.Count(data, []byte("BITFIELD")); n != 1 {
+		t.Fatalf("GET-only BITFIELD should not be logged, found %d BITFIELD records", n)
+	}
+	d.Close()
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if n := replyInt(t, execArgs(d2, "BITCOUNT", "dau:1")); n != 2 {
+		t.Fatalf("BITCOUNT after replay = %d", n)
+	}
+	if n := replyInt(t, execArgs(d2, "BITPOS", "both", "1")); n != 42 {
+		t.Fatalf("BITPOS both after replay = %d", n)
+	}
+	raw := execArgs(d2, "BITFIELD_RO", "cnt", "GET", "u16", "#0").(*resp.MultiRawReply)
+	if raw.Replies[0].(*resp.IntReply).Code != 300 {
+		t.Fatalf("BITFIELD after replay = %+v", raw.Replies)
+	}
+}
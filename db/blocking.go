@@ -0,0 +1,276 @@
+// 阻塞列表命令实现：BLPOP / BRPOP / BLMOVE。
+// 说明：阻塞不会卡住 Actor——拿不到元素的请求被挂到 per-key 等待队列，Actor 继续处理其它命令，稍后再回复该请求。
+// 关键点：push 类命令把 key 标记为 ready，命令结束后按 FIFO 唤醒等待者；AOF 记录实际生效的 LPOP/RPOP/LMOVE 而不是阻塞命令。
+package db
+
+import (
+	"container/list"
+	"math"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现阻塞列表命令：
+// - BLPOP key [key ...] timeout / BRPOP key [key ...] timeout：按 key 顺序找到第一个非空列表弹出，返回 [key, element]
+// - BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout：source 非空时等价于 LMOVE
+//
+// 流程：
+// 1) 命令能立即执行则直接返回（与非阻塞版本一致）
+// 2) 否则返回内部标记 blockReply，background 把请求登记到 blockedKeys[key]（FIFO），不回复客户端
+// 3) LPUSH/RPUSH/LMOVE 等写入列表后调用 signalKeyAsReady；命令处理完毕后 serveReadyKeys 重新执行队首等待者的命令
+// 4) 超时由 time.AfterFunc 投递一个内部任务到 Actor，移出队列并回复 nil
+//
+// XREAD/XREADGROUP 的 BLOCK 复用同一套等待队列（见 stream.go / stream_group.go），XADD 写入后同样调用 signalKeyAsReady。
+//
+// 连接断开：服务端经 BlockingDB.ExecBlocking 传入连接的 done 通道，断开时 cancelBlocked 在 Actor 中撤销等待者，
+// 之后写入的元素不会再弹给已断开的连接（否则元素被弹出后无人接收，造成静默丢失）。
+
+// blockReply 为 execInternal 返回的“需要阻塞”的内部标记，不会发送给客户端。
+type blockReply struct {
+	keys      []string
+	timeout   time.Duration // 0 表示永久等待
+	onTimeout resp.Reply
+	cmd       [][]byte // 非空时替换被挂起请求的命令（如 XREAD 把 $ 固定为阻塞时的 last-id），唤醒后重新执行它
+}
+
+func (r *blockReply) ToBytes() []byte {
+	return nil
+}
+
+// blockedClient 为一个被挂起的请求。
+type blockedClient struct {
+	req       *commandRequest
+	elems     map[string]*list.Element // key -> 在 blockedKeys[key] 中的位置
+	timer     *time.Timer
+	onTimeout resp.Reply
+	done      bool
+}
+
+// IsBlockingCommand 判断命令是否可能阻塞（Exec 对它们不能使用安全超时，否则会丢失稍后弹出的元素；
+// 服务端在执行前先 flush 已缓冲的回复）。
+func IsBlockingCommand(cmd [][]byte) bool {
+	if len(cmd) == 0 {
+		return false
+	}
+	switch strings.ToLower(string(cmd[0])) {
+	case "blpop", "brpop", "blmove":
+		return true
+	case "xread", "xreadgroup":
+		for _, arg := range cmd[1:] {
+			if strings.EqualFold(string(arg), "block") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BlockingDB 为可以撤销阻塞命令的 DB（StandaloneDB、cluster.Router 实现），服务端执行阻塞命令时使用。
+type BlockingDB interface {
+	// ExecBlocking 与 Exec 相同；done 关闭时撤销仍在等待的阻塞命令（客户端已断开），done 为 nil 表示不撤销。
+	ExecBlocking(cmd [][]byte, done <-chan struct{}) resp.Reply
+}
+
+// errClientClosed 为被撤销的阻塞命令的回复（连接已断开，不会发送给客户端）。
+var errClientClosed = resp.MakeErrReply("ERR client closed")
+
+// parseBlockTimeout 解析以秒为单位的超时（支持小数），0 表示永久等待。
+func parseBlockTimeout(arg []byte) (time.Duration, resp.Reply) {
+	sec, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(sec) || math.IsInf(sec, 0) {
+		return 0, resp.MakeErrReply("ERR timeout is not a float or out of range")
+	}
+	if sec < 0 {
+		return 0, resp.MakeErrReply("ERR timeout is negative")
+	}
+	if sec > float64(math.MaxInt64/int64(time.Second)) {
+		return 0, resp.MakeErrReply("ERR timeout is out of range")
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
+
+// BLPOP key [key ...] timeout / BRPOP key [key ...] timeout
+func (db *StandaloneDB) blockingPop(args [][]byte, left bool) resp.Reply {
+	name := "brpop"
+	if left {
+		name = "blpop"
+	}
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	timeout, errReply := parseBlockTimeout(args[len(args)-1])
+	if errReply != nil {
+		return errReply
+	}
+	keyArgs := args[1 : len(args)-1]
+	for _, k := range keyArgs {
+		key := string(k)
+		l, exists, errReply := db.getList(key)
+		if errReply != nil {
+			return errReply
+		}
+		if !exists {
+			continue
+		}
+		var e *list.Element
+		if left {
+			e = l.Front()
+		} else {
+			e = l.Back()
+		}
+		val := l.Remove(e).([]byte)
+		db.storeList(key, l)
+		return resp.MakeMultiBulkReply([][]byte{[]byte(key), val})
+	}
+
+	keys := make([]string, 0, len(keyArgs))
+	for _, k := range keyArgs {
+		keys = append(keys, string(k))
+	}
+	return &blockReply{keys: keys, timeout: timeout, onTimeout: resp.MakeMultiBulkReply(nil)}
+}
+
+// BLMOVE source destination LEFT|RIGHT LEFT|RIGHT timeout
+func (db *StandaloneDB) blmove(args [][]byte) resp.Reply {
+	if len(args) != 6 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'blmove' command")
+	}
+	fromLeft, ok1 := parseListEnd(args[3])
+	toLeft, ok2 := parseListEnd(args[4])
+	if !ok1 || !ok2 {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	timeout, errReply := parseBlockTimeout(args[5])
+	if errReply != nil {
+		return errReply
+	}
+	src := string(args[1])
+	if _, exists, errReply := db.getList(src); errReply != nil {
+		return errReply
+	} else if exists {
+		return db.listMove(src, string(args[2]), fromLeft, toLeft)
+	}
+	return &blockReply{keys: []string{src}, timeout: timeout, onTimeout: resp.NullBulkReply}
+}
+
+// signalKeyAsReady 标记 key 上有新元素（只有存在等待者时才记录）。
+func (db *StandaloneDB) signalKeyAsReady(key string) {
+	if _, ok := db.blockedKeys[key]; ok {
+		db.readyKeys = append(db.readyKeys, key)
+	}
+}
+
+// block 把请求挂到各个 key 的等待队列，并在需要时启动超时定时器。
+func (db *StandaloneDB) block(req *commandRequest, br *blockReply) {
+	if br.cmd != nil {
+		req.cmd = br.cmd
+	}
+	c := &blockedClient{req: req, elems: make(map[string]*list.Element, len(br.keys)), onTimeout: br.onTimeout}
+	req.blocked = c
+	for _, key := range br.keys {
+		if _, dup := c.elems[key]; dup {
+			continue
+		}
+		q, ok := db.blockedKeys[key]
+		if !ok {
+			q = list.New()
+			db.blockedKeys[key] = q
+		}
+		c.elems[key] = q.PushBack(c)
+	}
+	if br.timeout > 0 {
+		c.timer = time.AfterFunc(br.timeout, func() {
+			tr := &commandRequest{
+				fn: func() resp.Reply {
+					db.timeoutBlocked(c)
+					return nil
+				},
+				result: make(chan resp.Reply, 1),
+				noAof:  true,
+			}
+			select {
+			case db.ops <- tr:
+			case <-db.closing:
+			}
+		})
+	}
+}
+
+// unblock 把等待者从所有 key 的队列中移除。
+func (db *StandaloneDB) unblock(c *blockedClient) {
+	c.done = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	for key, e := range c.elems {
+		q := db.blockedKeys[key]
+		q.Remove(e)
+		if q.Len() == 0 {
+			delete(db.blockedKeys, key)
+		}
+	}
+}
+
+// cancelBlocked 撤销 req 的等待（客户端已断开）：撤销任务与 req 经同一 ops 队列按序进入 Actor，
+// 执行时 req 已被处理；仍在等待则移出队列，已被唤醒的请求照常返回结果。
+func (db *StandaloneDB) cancelBlocked(req *commandRequest) resp.Reply {
+	cr := &commandRequest{
+		fn: func() resp.Reply {
+			if c := req.blocked; c != nil && !c.done {
+				db.unblock(c)
+				req.result <- errClientClosed
+			}
+			return nil
+		},
+		result: make(chan resp.Reply, 1),
+		noAof:  true,
+	}
+	select {
+	case db.ops <- cr:
+	case <-db.closing:
+		return resp.MakeErrReply("ERR server closed")
+	}
+	select {
+	case <-cr.result:
+		return <-req.result
+	case <-db.closing:
+		return resp.MakeErrReply("ERR server closed")
+	}
+}
+
+func (db *StandaloneDB) timeoutBlocked(c *blockedClient) {
+	if c.done {
+		return
+	}
+	db.unblock(c)
+	c.req.result <- c.onTimeout
+}
+
+// serveReadyKeys 依次唤醒 ready key 上的等待者（FIFO）：重新执行其阻塞命令，成功则回复并写 AOF。
+// BLMOVE 的目标 key 可能因此变为 ready，所以循环直到没有新的 ready key。
+// 仍需阻塞的等待者留在原位并继续尝试后面的等待者（如 XREAD 等待的 ID 比新条目更大）。
+func (db *StandaloneDB) serveReadyKeys() {
+	for len(db.readyKeys) > 0 {
+		keys := db.readyKeys
+		db.readyKeys = nil
+		for _, key := range keys {
+			q, ok := db.blockedKeys[key]
+			if !ok {
+				continue
+			}
+			for e := q.Front(); e != nil; {
+				next := e.Next()
+				c := e.Value.(*blockedClient)
+				db.evictedKeys = db.evictedKeys[:0]
+				res := db.execInternal(c.req.cmd)
+				if _, stillBlocked := res.(*blockReply); !stillBlocked {
+					db.unblock(c)
+					db.finish(c.req, res)
+				}
+				e = next
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// 阻塞列表命令测试：覆盖立即返回、挂起后被 push 唤醒、FIFO 唤醒顺序、超时与 AOF 记录实际弹出。
+// 目标：保证阻塞不会卡住 Actor（其它命令照常执行），且重放 AOF 得到与内存一致的状态。
+// 覆盖：BLPOP/BRPOP/BLMOVE、多 key 顺序、timeout、连接断开撤销等待者、AOF 中只出现 LPOP/RPOP/LMOVE。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// execAsync 在独立 goroutine 中执行命令（模拟另一个被阻塞的客户端）。
+func execAsync(d *StandaloneDB, args ...string) <-chan resp.Reply {
+	ch := make(chan resp.Reply, 1)
+	go func() {
+		ch <- execArgs(d, args...)
+	}()
+	return ch
+}
+
+func waitReply(t *testing.T, ch <-chan resp.Reply) resp.Reply {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(3 * time.Second):
+		t.Fatalf("blocked command was not woken up")
+	}
+	return nil
+}
+
+// waitBlocked 等待指定 key 上出现 n 个等待者（通过 Actor 读取，避免数据竞争）。
+func waitBlocked(t *testing.T, d *StandaloneDB, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		req := &commandRequest{
+			fn: func() resp.Reply {
+				if q, ok := d.blockedKeys[key]; ok {
+					return resp.MakeIntReply(int64(q.Len()))
+				}
+				return resp.MakeIntReply(0)
+			},
+			result: make(chan resp.Reply, 1),
+			noAof:  true,
+		}
+		d.ops <- req
+		if (<-req.result).(*resp.IntReply).Code == int64(n) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d waiters on %s", n, key)
+}
+
+func TestBlocking_ImmediateAndTimeout(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "RPUSH", "b", "x", "y")
+	if got := strings.Join(replyStrings(t, execArgs(d, "BLPOP", "a", "b", "0")), ","); got != "b,x" {
+		t.Fatalf("BLPOP immediate = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "BRPOP", "b", "1")), ","); got != "b,y" {
+		t.Fatalf("BRPOP immediate = %s", got)
+	}
+
+	start := time.Now()
+	mb, ok := execArgs(d, "BLPOP", "empty", "0.2").(*resp.MultiBulkReply)
+	if !ok || mb.Args != nil {
+		t.Fatalf("BLPOP timeout should return null array, got %+v", mb)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("BLPOP returned too early: %v", elapsed)
+	}
+	if br, ok := execArgs(d, "BLMOVE", "empty", "dst", "LEFT", "LEFT", "0.05").(*resp.BulkReply); !ok || br.Arg != nil {
+		t.Fatalf("BLMOVE timeout should return null bulk")
+	}
+	if _, ok := execArgs(d, "BLPOP", "k", "-1").(*resp.ErrorReply); !ok {
+		t.Fatalf("negative timeout should be rejected")
+	}
+	if _, ok := execArgs(d, "BLPOP", "k", "abc").(*resp.ErrorReply); !ok {
+		t.Fatalf("invalid timeout should be rejected")
+	}
+}
+
+func TestBlocking_WakeFIFOAndAOF(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	first := execAsync(d, "BLPOP", "q", "0")
+	waitBlocked(t, d, "q", 1)
+	second := execAsync(d, "BRPOP", "other", "q", "0")
+	waitBlocked(t, d, "q", 2)
+
+	// 阻塞期间 Actor 仍能处理其它命令
+	execArgs(d, "SET", "k", "v")
+
+	if n := replyInt(t, execArgs(d, "RPUSH", "q", "a", "b", "c")); n != 3 {
+		t.Fatalf("RPUSH = %d", n)
+	}
+	if got := strings.Join(replyStrings(t, waitReply(t, first)), ","); got != "q,a" {
+		t.Fatalf("first waiter = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, waitReply(t, second)), ","); got != "q,c" {
+		t.Fatalf("second waiter = %s", got)
+	}
+
+	// BLMOVE 唤醒后推入 dst，dst 上的等待者被级联唤醒
+	chained := execAsync(d, "BLPOP", "dst", "0")
+	waitBlocked(t, d, "dst", 1)
+	mover := execAsync(d, "BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	waitBlocked(t, d, "src", 1)
+	execArgs(d, "LPUSH", "src", "job")
+	if br := waitReply(t, mover).(*resp.BulkReply); string(br.Arg) != "job" {
+		t.Fatalf("BLMOVE = %q", br.Arg)
+	}
+	if got := strings.Join(replyStrings(t, waitReply(t, chained)), ","); got != "dst,job" {
+		t.Fatalf("chained waiter = %s", got)
+	}
+
+	if err := d.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	if bytes.Contains(data, []byte("BLPOP")) || bytes.Contains(data, []byte("BRPOP")) || bytes.Contains(data, []byte("BLMOVE")) {
+		t.Fatalf("AOF should not contain blocking commands: %q", data)
+	}
+	d.Close()
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if got := strings.Join(replyStrings(t, execArgs(d2, "LRANGE", "q", "0", "-1")), ","); got != "b" {
+		t.Fatalf("q after replay = %s", got)
+	}
+	if n := replyInt(t, execArgs(d2, "EXISTS", "src", "dst")); n != 0 {
+		t.Fatalf("src/dst should be empty after replay, EXISTS = %d", n)
+	}
+}
+
+// TestBlocking_CancelOnDisconnect：连接断开（done 关闭）后撤销等待者，之后 push 的元素留在列表中或交给其它等待者。
+func TestBlocking_CancelOnDisconnect(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	done := make(chan struct{})
+	gone := make(chan resp.Reply, 1)
+	go func() {
+		gone <- d.ExecBlocking([][]byte{[]byte("BLPOP"), []byte("q"), []byte("0")}, done)
+	}()
+	waitBlocked(t, d, "q", 1)
+	close(done)
+	if r, ok := waitReply(t, gone).(*resp.ErrorReply); !ok || r != errClientClosed {
+		t.Fatalf("canceled BLPOP = %+v", r)
+	}
+	waitBlocked(t, d, "q", 0)
+	execArgs(d, "RPUSH", "q", "a")
+	if n := replyInt(t, execArgs(d, "LLEN", "q")); n != 1 {
+		t.Fatalf("element pushed after disconnect was lost, LLEN = %d", n)
+	}
+
+	// 撤销排在前面的等待者后，元素交给后面仍在等待的客户端
+	execArgs(d, "DEL", "q")
+	done = make(chan struct{})
+	go func() {
+		gone <- d.ExecBlocking([][]byte{[]byte("BLPOP"), []byte("q"), []byte("0")}, done)
+	}()
+	waitBlocked(t, d, "q", 1)
+	alive := execAsync(d, "BLPOP", "q", "0")
+	waitBlocked(t, d, "q", 2)
+	close(done)
+	waitReply(t, gone)
+	execArgs(d, "RPUSH", "q", "b")
+	if got := replyStrings(t, waitReply(t, alive)); len(got) != 2 || got[1] != "b" {
+		t.Fatalf("remaining waiter got %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+// 运行期配置：CONFIG GET/SET，目前只开放 notify-keyspace-events。
+// 说明：配置在 Actor 中读写，与命令执行串行，不需要额外加锁；配置不写 AOF（与 Redis 一致，重启后以启动参数为准）。
+// 关键点：CONFIG GET 支持 glob 模式与多个参数，未知参数返回空结果；CONFIG SET 校验全部参数后才生效。
+package db
+
+import (
+	"myredis/pkg/glob"
+	"myredis/resp"
+	"strings"
+)
+
+// 本文件实现：
+// - CONFIG GET parameter [parameter ...]：返回 [name, value, ...]
+// - CONFIG SET parameter value [parameter value ...]
+
+// configParam 为一个可在运行期读写的配置项。
+type configParam struct {
+	get func(db *StandaloneDB) string
+	// set 返回非空字符串表示取值非法（错误说明）
+	set func(db *StandaloneDB, value string) string
+}
+
+var configParams = map[string]configParam{
+	"notify-keyspace-events": {
+		get: func(db *StandaloneDB) string { return notifyFlagsString(db.notifyFlags) },
+		set: func(db *StandaloneDB, value string) string {
+			flags, ok := parseNotifyFlags(value)
+			if !ok {
+				return "Invalid event class character. Use 'Ag$lshzxeKEtmdn'."
+			}
+			db.notifyFlags = flags
+			return ""
+		},
+	},
+}
+
+// CONFIG GET|SET ...
+func (db *StandaloneDB) config(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'config' command")
+	}
+	sub := strings.ToLower(string(args[1]))
+	switch sub {
+	case "get":
+		if len(args) < 3 {
+			return resp.MakeErrReply("ERR wrong number of arguments for 'config|get' command")
+		}
+		var out [][]byte
+		for name, p := range configParams {
+			for _, pattern := range args[2:] {
+				if glob.Match(strings.ToLower(string(pattern)), name) {
+					out = append(out, []byte(name), []byte(p.get(db)))
+					break
+				}
+			}
+		}
+		return resp.MakeMapBulkReply(out)
+	case "set":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return resp.MakeErrReply("ERR wrong number of arguments for 'config|set' command")
+		}
+		// 先校验全部参数名，避免部分生效
+		for i := 2; i < len(args); i += 2 {
+			if _, ok := configParams[strings.ToLower(string(args[i]))]; !ok {
+				return resp.MakeErrReply("ERR Unknown option or number of arguments for CONFIG SET - '" + string(args[i]) + "'")
+			}
+		}
+		for i := 2; i < len(args); i += 2 {
+			name := strings.ToLower(string(args[i]))
+			if msg := configParams[name].set(db, string(args[i+1])); msg != "" {
+				return resp.MakeErrReply("ERR CONFIG SET failed (possibly related to argument '" + name + "') - " + msg)
+			}
+		}
+		return resp.OkReply
+	}
+	return resp.MakeErrReply("ERR unknown subcommand '" + string(args[1]) + "'. Try CONFIG HELP.")
+}
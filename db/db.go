@@ -4,8 +4,12 @@
 package db
 
 import (
+	"container/list"
 	"myredis/aof"
+	"myredis/module"
 	"myredis/pkg/lru"
+	"myredis/pkg/lua"
+	"myredis/pubsub"
 	"myredis/resp"
 	"strconv"
 	"strings"
@@ -36,6 +40,8 @@ type commandRequest struct {
 	fn     func() resp.Reply
 	result chan resp.Reply
 	noAof  bool
+	// blocked 为请求挂在等待队列上时对应的等待者（见 block），用于连接断开时撤销
+	blocked *blockedClient
 }
 
 // StandaloneDB 单机数据库 (Single-Threaded Actor Model)
@@ -43,6 +49,8 @@ type StandaloneDB struct {
 	// cache 为可插拔淘汰策略（LRU/LFU）。由 Actor 串行调用，因此不要求并发安全。
 	cache  lru.EvictionCache
 	ttlMap map[string]time.Time // key -> 绝对过期时间
+	// hashTTLKeys 记录含字段级 TTL 的 Hash key，供定期删除抽样（字段过期时间保存在 HashData.expires 中）。
+	hashTTLKeys map[string]struct{}
 
 	ops       chan *commandRequest
 	closing   chan struct{}
@@ -55,6 +63,26 @@ type StandaloneDB struct {
 
 	aofHandler *aof.AofHandler
 
+	// blockedKeys 为阻塞命令（BLPOP/BRPOP/BLMOVE）的 per-key 等待队列（元素为 *blockedClient，FIFO）。
+	// readyKeys 记录本次命令中被写入新元素、且有等待者的 key，命令结束后统一唤醒。仅在 background goroutine 中读写。
+	blockedKeys map[string]*list.List
+	readyKeys   []string
+
+	// watchedKeys 为被 WATCH 的 key 的版本号（只跟踪有监视者的 key），inMulti/multiAof 为事务执行期间的 AOF 缓冲，见 multi.go。
+	watchedKeys map[string]*watchState
+	inMulti     bool
+	multiAof    [][][]byte
+
+	// scripts 为 EVAL/SCRIPT LOAD 的脚本缓存（SHA1 -> 编译结果），见 script.go。
+	scripts map[string]*lua.Proto
+
+	// hub 为发布订阅中心（PUBLISH 在 Actor 中调用，SUBSCRIBE 由 server 层直接操作），见 pubsub.go。
+	hub *pubsub.Hub
+
+	// notifyFlags 为 notify-keyspace-events 标志，keyChanges 为当前命令修改过的 key，见 notify.go。仅在 background goroutine 中读写。
+	notifyFlags int
+	keyChanges  []keyChange
+
 	// rdbFilename 为可选快照文件路径（为空表示关闭 RDB）。
 	rdbFilename string
 	rdbMu       sync.Mutex
@@ -75,6 +103,10 @@ type StandaloneDBConfig struct {
 	RdbFilename string
 	MaxBytes    int64  // 内存上限（用于 LRU/LFU 淘汰）；0 表示使用默认值
 	Eviction    string // "lru" / "lfu"
+	// PubSubLimits 为订阅者的输出缓冲上限；零值表示使用 pubsub.DefaultLimits
+	PubSubLimits pubsub.Limits
+	// NotifyKeyspaceEvents 为键空间通知标志（与 Redis notify-keyspace-events 一致，例如 "Ex"）；空表示关闭，非法值按关闭处理
+	NotifyKeyspaceEvents string
 }
 
 func NewStandaloneDB(aofFilename string) *StandaloneDB {
@@ -91,36 +123,62 @@ func NewStandaloneDBWithConfig(cfg StandaloneDBConfig) *StandaloneDB {
 		cfg.MaxBytes = DefaultMaxBytes
 	}
 	eviction := strings.ToLower(strings.TrimSpace(cfg.Eviction))
+	if cfg.PubSubLimits == (pubsub.Limits{}) {
+		cfg.PubSubLimits = pubsub.DefaultLimits
+	}
 
 	db := &StandaloneDB{
-		ttlMap:  make(map[string]time.Time),
-		ops:     make(chan *commandRequest, 1000),
-		closing: make(chan struct{}),
+		ttlMap:      make(map[string]time.Time),
+		hashTTLKeys: make(map[string]struct{}),
+		ops:         make(chan *commandRequest, 1000),
+		closing:     make(chan struct{}),
+		blockedKeys: make(map[string]*list.List),
+		watchedKeys: make(map[string]*watchState),
+		scripts:     make(map[string]*lua.Proto),
+		hub:         pubsub.NewHub(cfg.PubSubLimits),
 		// 这里用一个有缓冲 channel，避免后台重写 goroutine 写入结果时被阻塞（Actor 会尽快消费）。
 		aofRewriteDone: make(chan aofRewriteResult, 1),
 		rdbFilename:    cfg.RdbFilename,
 	}
+	db.notifyFlags, _ = parseNotifyFlags(cfg.NotifyKeyspaceEvents)
 
 	// Initialize LRU Cache (Default strategy)
 	// OnEvicted callback:
 	// 1) 始终清理 ttlMap，避免过期表泄漏
 	// 2) 若是容量淘汰（Evicted），记录到 evictedKeys，稍后由 background 统一写入 AOF（DEL key）
+	// 3) 键空间通知：过期/淘汰立即发出 expired/evicted，显式删除记录下来由命令结束时统一发出（见 notify.go）
 	onEvicted := func(key string, value lru.Value, reason lru.RemoveReason) {
+		// 惰性删除与定期删除都通过 cache.Remove 完成，删除前 ttlMap 中的时间已经过去即为过期
+		expireAt, hasTTL := db.ttlMap[key]
+		expired := reason == lru.RemoveReasonExpired || (hasTTL && !time.Now().Before(expireAt))
 		// 任何删除都需要同步清理 ttlMap，避免内存泄漏
 		delete(db.ttlMap, key)
-		if reason == lru.RemoveReasonEvicted {
+		delete(db.hashTTLKeys, key)
+		db.touchWatched(key)
+		switch {
+		case reason == lru.RemoveReasonEvicted:
 			db.evictedKeys = append(db.evictedKeys, key)
+			db.trackKey(key, keyDropped)
+			db.notify(notifyEvicted, "evicted", key)
+		case expired:
+			db.trackKey(key, keyDropped)
+			db.notify(notifyExpired, "expired", key)
+		default:
+			db.trackKey(key, keyDeleted)
 		}
 	}
+	var cache lru.EvictionCache
 	switch eviction {
 	case "", "lru":
-		db.cache = lru.New(cfg.MaxBytes, onEvicted)
+		cache = lru.New(cfg.MaxBytes, onEvicted)
 	case "lfu":
-		db.cache = lru.NewLFU(cfg.MaxBytes, onEvicted)
+		cache = lru.NewLFU(cfg.MaxBytes, onEvicted)
 	default:
 		// 非法值降级为 LRU（并在文档/评估中明确只支持 lru/lfu）
-		db.cache = lru.New(cfg.MaxBytes, onEvicted)
+		cache = lru.New(cfg.MaxBytes, onEvicted)
 	}
+	// 写入时递增被 WATCH 的 key 的版本号（删除由 onEvicted 处理）
+	db.cache = watchedCache{EvictionCache: cache, db: db}
 
 	if cfg.AofFilename != "" {
 		handler, err := aof.NewAofHandler(cfg.AofFilename)
@@ -135,6 +193,11 @@ func NewStandaloneDBWithConfig(cfg StandaloneDBConfig) *StandaloneDB {
 }
 
 func (db *StandaloneDB) Exec(cmd [][]byte) resp.Reply {
+	return db.ExecBlocking(cmd, nil)
+}
+
+// ExecBlocking 与 Exec 相同；done 关闭（客户端断开）时撤销仍在等待的阻塞命令，见 BlockingDB。
+func (db *StandaloneDB) ExecBlocking(cmd [][]byte, done <-chan struct{}) resp.Reply {
 	// 关闭过程中直接返回，避免 goroutine 堆积
 	select {
 	case <-db.closing:
@@ -154,6 +217,17 @@ func (db *StandaloneDB) Exec(cmd [][]byte) resp.Reply {
 	}
 
 	// 2. Wait for result
+	// 阻塞命令可能长时间挂起：不能使用安全超时，否则 Actor 稍后弹出的元素会丢失
+	if IsBlockingCommand(cmd) {
+		select {
+		case res := <-req.result:
+			return res
+		case <-done:
+			return db.cancelBlocked(req)
+		case <-db.closing:
+			return resp.MakeErrReply("ERR server closed")
+		}
+	}
 	select {
 	case res := <-req.result:
 		return res
@@ -216,23 +290,7 @@ func (db *StandaloneDB) background() {
 	for {
 		select {
 		case req := <-db.ops:
-			db.evictedKeys = db.evictedKeys[:0]
-			var res resp.Reply
-			if req.fn != nil {
-				res = req.fn()
-			} else {
-				res = db.execInternal(req.cmd)
-			}
-
-			if !req.noAof && db.aofHandler != nil && !isError(res) {
-				db.appendAof(req.cmd, res)
-				// 将本次命令触发的“容量淘汰”写入 AOF，避免重启后被淘汰的数据复活
-				for _, key := range db.evictedKeys {
-					db.aofHandler.AddAof([][]byte{[]byte("DEL"), []byte(key)})
-				}
-			}
-
-			req.result <- res
+			db.handle(req)
 		case done := <-db.aofRewriteDone:
 			db.handleAofRewriteDone(done)
 		case <-ticker.C:
@@ -242,20 +300,7 @@ func (db *StandaloneDB) background() {
 			for {
 				select {
 				case req := <-db.ops:
-					db.evictedKeys = db.evictedKeys[:0]
-					var res resp.Reply
-					if req.fn != nil {
-						res = req.fn()
-					} else {
-						res = db.execInternal(req.cmd)
-					}
-					if !req.noAof && db.aofHandler != nil && !isError(res) {
-						db.appendAof(req.cmd, res)
-						for _, key := range db.evictedKeys {
-							db.aofHandler.AddAof([][]byte{[]byte("DEL"), []byte(key)})
-						}
-					}
-					req.result <- res
+					db.handle(req)
 				case done := <-db.aofRewriteDone:
 					db.handleAofRewriteDone(done)
 				default:
@@ -266,6 +311,56 @@ func (db *StandaloneDB) background() {
 	}
 }
 
+// handle 在 Actor 中执行一个请求：需要阻塞的命令挂起到等待队列，其余命令写 AOF 并回复；最后唤醒 ready key 上的等待者。
+func (db *StandaloneDB) handle(req *commandRequest) {
+	db.evictedKeys = db.evictedKeys[:0]
+	db.keyChanges = db.keyChanges[:0]
+	var res resp.Reply
+	if req.fn != nil {
+		res = req.fn()
+	} else {
+		res = db.execInternal(req.cmd)
+	}
+
+	if br, ok := res.(*blockReply); ok {
+		// AOF 重放不会出现阻塞命令（记录的是实际生效的 pop）；兜底按超时处理
+		if req.noAof {
+			req.result <- br.onTimeout
+		} else {
+			db.block(req, br)
+		}
+	} else {
+		db.finish(req, res)
+	}
+	db.serveReadyKeys()
+}
+
+// finish 追加 AOF（含本次命令触发的容量淘汰）并回复请求。
+func (db *StandaloneDB) finish(req *commandRequest, res resp.Reply) {
+	if !req.noAof && db.aofHandler != nil && !isError(res) {
+		db.logCommand(req.cmd, res)
+	}
+	req.result <- res
+}
+
+// logCommand 追加一条已成功执行的命令的 AOF 记录。
+func (db *StandaloneDB) logCommand(cmd [][]byte, res resp.Reply) {
+	db.appendAof(cmd, res)
+	// 将本次命令触发的“容量淘汰”写入 AOF，避免重启后被淘汰的数据复活
+	for _, key := range db.evictedKeys {
+		db.addAof([][]byte{[]byte("DEL"), []byte(key)})
+	}
+}
+
+// addAof 追加一条 AOF 记录；事务执行期间先缓存，EXEC 结束后以 MULTI/EXEC 包裹整体写入。
+func (db *StandaloneDB) addAof(record [][]byte) {
+	if db.inMulti {
+		db.multiAof = append(db.multiAof, record)
+		return
+	}
+	db.aofHandler.AddAof(record)
+}
+
 func (db *StandaloneDB) appendAof(cmd [][]byte, res resp.Reply) {
 	if len(cmd) == 0 {
 		return
@@ -273,8 +368,27 @@ func (db *StandaloneDB) appendAof(cmd [][]byte, res resp.Reply) {
 	name := strings.ToLower(string(cmd[0]))
 
 	switch name {
-	case "expire":
-		// EXPIRE 采用绝对过期时间写入 AOF（PEXPIREAT），避免重启后“续命”
+	case "set":
+		// SET 统一改写为“无条件 SET + 绝对过期时间（PXAT）”：
+		// - NX/XX/GET 的条件已在执行时判定，未生效的 SET 不记录
+		// - EX/PX/EXAT 统一转为 PXAT，避免重启后“续命”
+		opts, errReply := parseSetArgs(cmd)
+		if errReply != nil || !setApplied(opts, res) {
+			return
+		}
+		key := string(cmd[1])
+		record := [][]byte{[]byte("SET"), cmd[1], cmd[2]}
+		if !opts.expireAt.IsZero() {
+			if expireAt, ok := db.ttlMap[key]; ok {
+				record = append(record, []byte("PXAT"), []byte(strconv.FormatInt(expireAt.UnixMilli(), 10)))
+			}
+		} else if opts.keepTTL {
+			record = append(record, []byte("KEEPTTL"))
+		}
+		db.addAof(record)
+		return
+	case "expire", "pexpire", "expireat", "pexpireat":
+		// EXPIRE 系列统一采用绝对过期时间写入 AOF（PEXPIREAT，不带条件选项：条件已在执行时判定），避免重启后“续命”
 		intReply, ok := res.(*resp.IntReply)
 		if !ok || intReply.Code != 1 || len(cmd) < 2 {
 			return
@@ -282,7 +396,7 @@ func (db *StandaloneDB) appendAof(cmd [][]byte, res resp.Reply) {
 		key := string(cmd[1])
 		expireAt, ok := db.ttlMap[key]
 		if ok {
-			db.aofHandler.AddAof([][]byte{
+			db.addAof([][]byte{
 				[]byte("PEXPIREAT"),
 				[]byte(key),
 				[]byte(strconv.FormatInt(expireAt.UnixMilli(), 10)),
@@ -291,7 +405,31 @@ func (db *StandaloneDB) appendAof(cmd [][]byte, res resp.Reply) {
 		}
 
 		// seconds <= 0 会直接删除 key，此时 ttlMap 已被清理；AOF 用 DEL 保证重放一致性
-		db.aofHandler.AddAof([][]byte{[]byte("DEL"), []byte(key)})
+		db.addAof([][]byte{[]byte("DEL"), []byte(key)})
+		return
+	case "getex":
+		// GETEX 只有携带过期选项时才是写命令：过期统一记为 PEXPIREAT，PERSIST 原样记录
+		br, ok := res.(*resp.BulkReply)
+		if !ok || br.Arg == nil || len(cmd) < 3 {
+			return
+		}
+		key := string(cmd[1])
+		if strings.EqualFold(string(cmd[2]), "persist") {
+			db.addAof([][]byte{[]byte("PERSIST"), cmd[1]})
+			return
+		}
+		if expireAt, ok := db.ttlMap[key]; ok {
+			db.addAof([][]byte{
+				[]byte("PEXPIREAT"),
+				cmd[1],
+				[]byte(strconv.FormatInt(expireAt.UnixMilli(), 10)),
+			})
+			return
+		}
+		db.addAof([][]byte{[]byte("DEL"), cmd[1]})
+		return
+	case "hexpire", "hpexpire", "hexpireat", "hpexpireat":
+		db.appendHashExpireAof(cmd, res)
 		return
 	case "persist":
 		// PERSIST 只有成功删除 TTL（返回 1）才写入 AOF
@@ -299,12 +437,64 @@ func (db *StandaloneDB) appendAof(cmd [][]byte, res resp.Reply) {
 		if !ok || intReply.Code != 1 {
 			return
 		}
-		db.aofHandler.AddAof(cmd)
+		db.addAof(cmd)
+		return
+	case "blpop", "brpop":
+		// 记录实际生效的弹出：[key, element] -> LPOP/RPOP key
+		mb, ok := res.(*resp.MultiBulkReply)
+		if !ok || len(mb.Args) != 2 {
+			return
+		}
+		op := "RPOP"
+		if name == "blpop" {
+			op = "LPOP"
+		}
+		db.addAof([][]byte{[]byte(op), mb.Args[0]})
+		return
+	case "bitfield":
+		// 只包含 GET 的 BITFIELD 不修改数据，不需要记录
+		for _, a := range cmd[2:] {
+			if s := strings.ToLower(string(a)); s == "set" || s == "incrby" {
+				db.addAof(cmd)
+				return
+			}
+		}
+		return
+	case "spop":
+		// SPOP 的结果是随机的：记录实际弹出的成员（SREM），保证重放一致
+		var popped [][]byte
+		switch r := res.(type) {
+		case *resp.BulkReply:
+			if r.Arg != nil {
+				popped = [][]byte{r.Arg}
+			}
+		case *resp.MultiBulkReply:
+			popped = r.Args
+		}
+		if len(popped) == 0 {
+			return
+		}
+		db.addAof(append([][]byte{[]byte("SREM"), cmd[1]}, popped...))
+		return
+	case "blmove":
+		if br, ok := res.(*resp.BulkReply); !ok || br.Arg == nil {
+			return
+		}
+		db.addAof([][]byte{[]byte("LMOVE"), cmd[1], cmd[2], cmd[3], cmd[4]})
+		return
+	case "xadd":
+		db.appendXAddAof(cmd, res)
+		return
+	case "xgroup":
+		db.appendXGroupAof(cmd, res)
+		return
+	case "xreadgroup", "xclaim", "xautoclaim":
+		db.appendStreamClaimAof(name, cmd, res)
 		return
 	default:
 		// 其他写命令按原样追加
 		if isWriteCommand(cmd) {
-			db.aofHandler.AddAof(cmd)
+			db.addAof(cmd)
 		}
 	}
 }
@@ -334,6 +524,11 @@ func (db *StandaloneDB) activeExpire() {
 			break
 		}
 	}
+
+	db.activeExpireHashFields()
+	// 定期删除不属于任何命令：只为被删空的 Hash 发出 del（过期事件已在 onEvicted 中发出）
+	db.notifyCommand(nil, db.keyChanges)
+	db.keyChanges = db.keyChanges[:0]
 }
 
 // ... isError, writeCommands, isWriteCommand ...
@@ -348,11 +543,23 @@ func isError(reply resp.Reply) bool {
 
 var writeCommands = map[string]struct{}{
 	"set": {}, "del": {},
-	"lpush": {}, "rpush": {}, "lpop": {}, "rpop": {},
-	"hset": {}, "hdel": {},
-	"sadd": {}, "srem": {},
-	// expire/persist 在 appendAof 中做了“只在成功时记录 + 写 PEXPIREAT”特殊处理
-	"pexpireat": {},
+	"incr": {}, "decr": {}, "incrby": {}, "decrby": {}, "incrbyfloat": {},
+	"append": {}, "setrange": {}, "mset": {}, "msetnx": {}, "setnx": {}, "getset": {}, "getdel": {},
+	"setbit": {}, "bitop": {}, "bitfield": {},
+	"pfadd": {}, "pfmerge": {},
+	"lpush": {}, "rpush": {}, "lpop": {}, "rpop": {}, "lpushx": {}, "rpushx": {},
+	"lset": {}, "linsert": {}, "lrem": {}, "ltrim": {}, "lmove": {}, "rpoplpush": {},
+	"hset": {}, "hdel": {}, "hmset": {}, "hsetnx": {}, "hincrby": {}, "hincrbyfloat": {},
+	"hpersist": {},
+	"sadd":     {}, "srem": {}, "smove": {}, "sinterstore": {}, "sunionstore": {}, "sdiffstore": {},
+	"zadd": {}, "zrem": {}, "zincrby": {}, "zpopmin": {}, "zpopmax": {},
+	"zremrangebyscore": {}, "zremrangebyrank": {}, "zremrangebylex": {},
+	"geoadd": {}, "geosearchstore": {},
+	"xtrim": {}, "xsetid": {}, "xack": {},
+	"rename": {}, "renamenx": {}, "copy": {}, "flushdb": {}, "flushall": {},
+	// expire 系列/persist 在 appendAof 中做了“只在成功时记录 + 写 PEXPIREAT”特殊处理
+	// hexpire 系列同理，统一改写为 HPEXPIREAT（绝对时间）
+	// xadd/xgroup/xreadgroup/xclaim/xautoclaim 记录执行结果（实际 ID、投递时间），见 stream.go / stream_group.go
 }
 
 func isWriteCommand(cmd [][]byte) bool {
@@ -360,11 +567,16 @@ func isWriteCommand(cmd [][]byte) bool {
 		return false
 	}
 	name := strings.ToLower(string(cmd[0]))
-	_, ok := writeCommands[name]
-	return ok
+	if _, ok := writeCommands[name]; ok {
+		return true
+	}
+	// 模块命令通过 FlagWrite 声明是否为写命令
+	mc, ok := module.Lookup(name)
+	return ok && mc.IsWrite()
 }
 
-func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
+// execCommand 按命令名分发执行（由 execInternal 调用，见 notify.go）。
+func (db *StandaloneDB) execCommand(cmd [][]byte) resp.Reply {
 	if len(cmd) == 0 {
 		return nil
 	}
@@ -392,14 +604,72 @@ func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
 		return db.get(cmd)
 	case "del":
 		return db.del(cmd)
+	case "incr":
+		return db.incr(cmd)
+	case "decr":
+		return db.decr(cmd)
+	case "incrby":
+		return db.incrby(cmd)
+	case "decrby":
+		return db.decrby(cmd)
+	case "incrbyfloat":
+		return db.incrbyfloat(cmd)
+	case "append":
+		return db.appendCmd(cmd)
+	case "strlen":
+		return db.strlen(cmd)
+	case "getrange":
+		return db.getrange(cmd)
+	case "setrange":
+		return db.setrange(cmd)
+	case "mget":
+		return db.mget(cmd)
+	case "mset":
+		return db.mset(cmd)
+	case "msetnx":
+		return db.msetnx(cmd)
+	case "setnx":
+		return db.setnx(cmd)
+	case "getset":
+		return db.getset(cmd)
+	case "getdel":
+		return db.getdel(cmd)
+	case "getex":
+		return db.getex(cmd)
 	case "lpush":
 		return db.lpush(cmd)
 	case "rpush":
 		return db.rpush(cmd)
 	case "lpop":
-		return db.lpop(cmd)
+		return db.listPop(cmd, true)
 	case "rpop":
-		return db.rpop(cmd)
+		return db.listPop(cmd, false)
+	case "lpushx":
+		return db.pushx(cmd, true)
+	case "rpushx":
+		return db.pushx(cmd, false)
+	case "lindex":
+		return db.lindex(cmd)
+	case "lset":
+		return db.lset(cmd)
+	case "linsert":
+		return db.linsert(cmd)
+	case "lrem":
+		return db.lrem(cmd)
+	case "ltrim":
+		return db.ltrim(cmd)
+	case "lpos":
+		return db.lpos(cmd)
+	case "lmove":
+		return db.lmove(cmd)
+	case "rpoplpush":
+		return db.rpoplpush(cmd)
+	case "blpop":
+		return db.blockingPop(cmd, true)
+	case "brpop":
+		return db.blockingPop(cmd, false)
+	case "blmove":
+		return db.blmove(cmd)
 	case "lrange":
 		return db.lrange(cmd)
 	case "llen":
@@ -412,6 +682,66 @@ func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
 		return db.hgetall(cmd)
 	case "hdel":
 		return db.hdel(cmd)
+	case "hmset":
+		return db.hmset(cmd)
+	case "hmget":
+		return db.hmget(cmd)
+	case "hexists":
+		return db.hexists(cmd)
+	case "hlen":
+		return db.hlen(cmd)
+	case "hkeys":
+		return db.hkeysOrVals(cmd, true)
+	case "hvals":
+		return db.hkeysOrVals(cmd, false)
+	case "hsetnx":
+		return db.hsetnx(cmd)
+	case "hincrby":
+		return db.hincrby(cmd)
+	case "hincrbyfloat":
+		return db.hincrbyfloat(cmd)
+	case "hstrlen":
+		return db.hstrlen(cmd)
+	case "hrandfield":
+		return db.hrandfield(cmd)
+	case "hexpire":
+		return db.hexpireGeneric(cmd, "hexpire", time.Second, false)
+	case "hpexpire":
+		return db.hexpireGeneric(cmd, "hpexpire", time.Millisecond, false)
+	case "hexpireat":
+		return db.hexpireGeneric(cmd, "hexpireat", time.Second, true)
+	case "hpexpireat":
+		return db.hexpireGeneric(cmd, "hpexpireat", time.Millisecond, true)
+	case "httl":
+		return db.httlGeneric(cmd, "httl", time.Second, false)
+	case "hpttl":
+		return db.httlGeneric(cmd, "hpttl", time.Millisecond, false)
+	case "hexpiretime":
+		return db.httlGeneric(cmd, "hexpiretime", time.Second, true)
+	case "hpexpiretime":
+		return db.httlGeneric(cmd, "hpexpiretime", time.Millisecond, true)
+	case "hpersist":
+		return db.hpersist(cmd)
+	case "setbit":
+		return db.setbit(cmd)
+	case "getbit":
+		return db.getbit(cmd)
+	case "bitcount":
+		return db.bitcount(cmd)
+	case "bitpos":
+		return db.bitpos(cmd)
+	case "bitop":
+		return db.bitop(cmd)
+	case "bitfield":
+		return db.bitfield(cmd, false)
+	case "bitfield_ro":
+		return db.bitfield(cmd, true)
+	case "pfadd":
+		return db.pfadd(cmd)
+	case "pfcount":
+		return db.pfcount(cmd)
+	case "pfmerge":
+		return db.pfmerge(cmd)
 	case "sadd":
 		return db.sadd(cmd)
 	case "srem":
@@ -420,13 +750,162 @@ func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
 		return db.smembers(cmd)
 	case "scard":
 		return db.scard(cmd)
+	case "sismember":
+		return db.sismember(cmd)
+	case "smismember":
+		return db.smismember(cmd)
+	case "sinter":
+		return db.setAlgebra(cmd, SetInter)
+	case "sunion":
+		return db.setAlgebra(cmd, SetUnion)
+	case "sdiff":
+		return db.setAlgebra(cmd, SetDiff)
+	case "sinterstore":
+		return db.setAlgebraStore(cmd, SetInter)
+	case "sunionstore":
+		return db.setAlgebraStore(cmd, SetUnion)
+	case "sdiffstore":
+		return db.setAlgebraStore(cmd, SetDiff)
+	case "sintercard":
+		return db.sintercard(cmd)
+	case "spop":
+		return db.spop(cmd)
+	case "srandmember":
+		return db.srandmember(cmd)
+	case "smove":
+		return db.smove(cmd)
+	case "zadd":
+		return db.zadd(cmd)
+	case "zrem":
+		return db.zrem(cmd)
+	case "zscore":
+		return db.zscore(cmd)
+	case "zincrby":
+		return db.zincrby(cmd)
+	case "zcard":
+		return db.zcard(cmd)
+	case "zrank":
+		return db.zrank(cmd, false)
+	case "zrevrank":
+		return db.zrank(cmd, true)
+	case "zrange":
+		return db.zrange(cmd)
+	case "zrevrange":
+		return db.zrevrange(cmd)
+	case "zrangebyscore":
+		return db.zrangeByLegacy(cmd, commandName, false, false)
+	case "zrevrangebyscore":
+		return db.zrangeByLegacy(cmd, commandName, false, true)
+	case "zrangebylex":
+		return db.zrangeByLegacy(cmd, commandName, true, false)
+	case "zrevrangebylex":
+		return db.zrangeByLegacy(cmd, commandName, true, true)
+	case "zcount":
+		return db.zcount(cmd)
+	case "zpopmin":
+		return db.zpop(cmd, false)
+	case "zpopmax":
+		return db.zpop(cmd, true)
+	case "zremrangebyscore":
+		return db.zremrangebyscore(cmd)
+	case "zremrangebyrank":
+		return db.zremrangebyrank(cmd)
+	case "zremrangebylex":
+		return db.zremrangebylex(cmd)
+	// Geo
+	case "geoadd":
+		return db.geoadd(cmd)
+	case "geopos":
+		return db.geopos(cmd)
+	case "geodist":
+		return db.geodist(cmd)
+	case "geosearch":
+		return db.geosearch(cmd)
+	case "geosearchstore":
+		return db.geosearchstore(cmd)
+	// Stream
+	case "xadd":
+		return db.xadd(cmd)
+	case "xrange":
+		return db.xrange(cmd, false)
+	case "xrevrange":
+		return db.xrange(cmd, true)
+	case "xlen":
+		return db.xlen(cmd)
+	case "xtrim":
+		return db.xtrim(cmd)
+	case "xsetid":
+		return db.xsetid(cmd)
+	case "xread":
+		return db.xread(cmd)
+	case "xgroup":
+		return db.xgroup(cmd)
+	case "xreadgroup":
+		return db.xreadgroup(cmd)
+	case "xack":
+		return db.xack(cmd)
+	case "xpending":
+		return db.xpending(cmd)
+	case "xclaim":
+		return db.xclaim(cmd)
+	case "xautoclaim":
+		return db.xautoclaim(cmd)
+	// Scripting
+	case "eval":
+		return db.eval(cmd)
+	case "evalsha":
+		return db.evalsha(cmd)
+	case "script":
+		return db.script(cmd)
+	// Pub/Sub
+	case "publish":
+		return db.publish(cmd)
+	case "pubsub":
+		return db.pubsubCmd(cmd)
+	// Keyspace
+	case "exists":
+		return db.exists(cmd)
+	case "touch":
+		return db.touch(cmd)
+	case "type":
+		return db.typeCmd(cmd)
+	case "rename":
+		return db.rename(cmd)
+	case "renamenx":
+		return db.renamenx(cmd)
+	case "copy":
+		return db.copyCmd(cmd)
+	case "randomkey":
+		return db.randomkey(cmd)
+	case "dbsize":
+		return db.dbsize(cmd)
+	case "flushdb", "flushall":
+		return db.flush(cmd)
+	case "scan":
+		return db.scan(cmd)
+	case "hscan":
+		return db.hscan(cmd)
+	case "sscan":
+		return db.sscan(cmd)
+	case "zscan":
+		return db.zscan(cmd)
 	// New Commands
 	case "expire":
-		return db.expire(cmd)
+		return db.expireGeneric(cmd, "expire", time.Second, false)
+	case "pexpire":
+		return db.expireGeneric(cmd, "pexpire", time.Millisecond, false)
+	case "expireat":
+		return db.expireGeneric(cmd, "expireat", time.Second, true)
 	case "pexpireat":
-		return db.pexpireat(cmd)
+		return db.expireGeneric(cmd, "pexpireat", time.Millisecond, true)
 	case "ttl":
-		return db.ttl(cmd)
+		return db.ttlGeneric(cmd, "ttl", time.Second, false)
+	case "pttl":
+		return db.ttlGeneric(cmd, "pttl", time.Millisecond, false)
+	case "expiretime":
+		return db.ttlGeneric(cmd, "expiretime", time.Second, true)
+	case "pexpiretime":
+		return db.ttlGeneric(cmd, "pexpiretime", time.Millisecond, true)
 	case "persist":
 		return db.persist(cmd)
 	// Persistence / Admin
@@ -438,7 +917,10 @@ func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
 		return db.rewriteaof()
 	case "bgrewriteaof":
 		return db.bgrewriteaof()
+	case "config":
+		return db.config(cmd)
 	default:
-		return resp.MakeErrReply("ERR unknown command '" + commandName + "'")
+		// 内置命令之外查询模块注册表（见 module.go）
+		return db.execModule(commandName, cmd)
 	}
 }
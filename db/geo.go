@@ -0,0 +1,460 @@
+// GEO 命令实现：GEOADD / GEOPOS / GEODIST / GEOSEARCH / GEOSEARCHSTORE。
+// 说明：与 Redis 一样，GEO 数据就是一个 ZSET，member 的分值是 52 位 geohash（编解码见 pkg/geohash）；TYPE 返回 zset，ZRANGE/ZREM 等可直接操作。
+// 关键点：范围搜索先按半径估算 geohash 精度，把九宫格转换为若干分值区间在跳表上扫描，再用球面距离精确过滤。
+package db
+
+import (
+	"math"
+	"myredis/pkg/geohash"
+	"myredis/resp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 本文件实现 GEO 相关命令：
+// - GEOADD key [NX|XX] [CH] longitude latitude member [...]：转换为 ZADD 执行
+// - GEOPOS key [member ...]：返回 [longitude, latitude]，不存在的 member 返回 nil
+// - GEODIST key member1 member2 [M|KM|FT|MI]：两点球面距离（保留 4 位小数）
+// - GEOSEARCH key FROMMEMBER member|FROMLONLAT lon lat BYRADIUS r unit|BYBOX w h unit
+//   [ASC|DESC] [COUNT n [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+// - GEOSEARCHSTORE dst src ...（同 GEOSEARCH，不支持 WITH*）[STOREDIST]：结果写入 dst（覆盖），STOREDIST 时分值为距离
+//
+// 说明：GEOADD/GEOSEARCHSTORE 原样写 AOF（给定相同的数据，结果是确定的）；RDB 中就是普通的 ZSET。
+
+// parseGeoUnit 返回单位对应的米数。
+func parseGeoUnit(arg []byte) (float64, bool) {
+	switch strings.ToLower(string(arg)) {
+	case "m":
+		return 1, true
+	case "km":
+		return 1000, true
+	case "ft":
+		return 0.3048, true
+	case "mi":
+		return 1609.34, true
+	}
+	return 0, false
+}
+
+var errGeoUnit = resp.MakeErrReply("ERR unsupported unit provided. please use M, KM, FT, MI")
+
+// parseLonLat 解析并校验经纬度。
+func parseLonLat(lonArg, latArg []byte) (float64, float64, resp.Reply) {
+	lon, err1 := strconv.ParseFloat(string(lonArg), 64)
+	lat, err2 := strconv.ParseFloat(string(latArg), 64)
+	if err1 != nil || err2 != nil || math.IsNaN(lon) || math.IsNaN(lat) {
+		return 0, 0, resp.MakeErrReply("ERR value is not a valid float")
+	}
+	if !geohash.Valid(lon, lat) {
+		return 0, 0, resp.MakeErrReply("ERR invalid longitude,latitude pair " +
+			strconv.FormatFloat(lon, 'f', 6, 64) + "," + strconv.FormatFloat(lat, 'f', 6, 64))
+	}
+	return lon, lat, nil
+}
+
+// formatGeoCoord 按 Redis 的 human 格式输出坐标：17 位小数并去掉末尾的 0。
+func formatGeoCoord(f float64) []byte {
+	s := strconv.FormatFloat(f, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s)
+}
+
+func formatGeoDist(meters, unit float64) []byte {
+	return []byte(strconv.FormatFloat(meters/unit, 'f', 4, 64))
+}
+
+// GEOADD key [NX|XX] [CH] longitude latitude member [longitude latitude member ...]
+func (db *StandaloneDB) geoadd(args [][]byte) resp.Reply {
+	if len(args) < 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'geoadd' command")
+	}
+	var nx, xx bool
+	i := 2
+	for ; i < len(args); i++ {
+		opt := strings.ToLower(string(args[i]))
+		if opt == "nx" {
+			nx = true
+		} else if opt == "xx" {
+			xx = true
+		} else if opt != "ch" {
+			break
+		}
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 || (nx && xx) {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+
+	// 改写为 ZADD key [options] score member ...（与 Redis 的做法相同）
+	zaddArgs := make([][]byte, 0, i+len(triples)/3*2)
+	zaddArgs = append(zaddArgs, []byte("zadd"))
+	zaddArgs = append(zaddArgs, args[1:i]...)
+	for j := 0; j < len(triples); j += 3 {
+		lon, lat, errReply := parseLonLat(triples[j], triples[j+1])
+		if errReply != nil {
+			return errReply
+		}
+		bits := geohash.Encode(lon, lat, geohash.MaxStep).Bits
+		zaddArgs = append(zaddArgs, []byte(strconv.FormatUint(bits, 10)), triples[j+2])
+	}
+	return db.zadd(zaddArgs)
+}
+
+// geoMemberPos 返回 member 的坐标（由分值解码得到格子中心）。
+func geoMemberPos(zs ZSetData, member string) (lon, lat float64, ok bool) {
+	score, ok := zs.score(member)
+	if !ok {
+		return 0, 0, false
+	}
+	lon, lat = geohash.DecodeCenter(uint64(score))
+	return lon, lat, true
+}
+
+// GEOPOS key [member ...]
+func (db *StandaloneDB) geopos(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'geopos' command")
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	replies := make([]resp.Reply, 0, len(args)-2)
+	for _, m := range args[2:] {
+		if !exists {
+			replies = append(replies, resp.MakeMultiBulkReply(nil))
+			continue
+		}
+		lon, lat, ok := geoMemberPos(zs, string(m))
+		if !ok {
+			replies = append(replies, resp.MakeMultiBulkReply(nil))
+			continue
+		}
+		replies = append(replies, resp.MakeMultiBulkReply([][]byte{formatGeoCoord(lon), formatGeoCoord(lat)}))
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// GEODIST key member1 member2 [M|KM|FT|MI]
+func (db *StandaloneDB) geodist(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'geodist' command")
+	}
+	if len(args) > 5 {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	unit := 1.0
+	if len(args) == 5 {
+		var ok bool
+		if unit, ok = parseGeoUnit(args[4]); !ok {
+			return errGeoUnit
+		}
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	lon1, lat1, ok1 := geoMemberPos(zs, string(args[2]))
+	lon2, lat2, ok2 := geoMemberPos(zs, string(args[3]))
+	if !ok1 || !ok2 {
+		return resp.NullBulkReply
+	}
+	return resp.MakeBulkReply(formatGeoDist(geohash.Distance(lon1, lat1, lon2, lat2), unit))
+}
+
+// geoSearchSpec 为 GEOSEARCH / GEOSEARCHSTORE 解析后的参数。
+type geoSearchSpec struct {
+	fromMember    []byte
+	fromLonLat    bool
+	lon, lat      float64
+	byRadius      bool
+	byBox         bool
+	radius        float64 // 米
+	width, height float64 // 米
+	unit          float64 // 输出距离使用的单位（米数）
+	sort          int     // 0 不排序，1 ASC，-1 DESC
+	count         int64   // 0 表示不限制
+	any           bool
+	withCoord     bool
+	withDist      bool
+	withHash      bool
+	storeDist     bool
+}
+
+// geoPoint 为一次搜索命中的点。
+type geoPoint struct {
+	member   string
+	score    float64
+	dist     float64
+	lon, lat float64
+}
+
+// parseGeoSearch 解析 FROMMEMBER/FROMLONLAT 起的参数；store=true 时为 GEOSEARCHSTORE。
+func parseGeoSearch(name string, opts [][]byte, store bool) (*geoSearchSpec, resp.Reply) {
+	spec := &geoSearchSpec{}
+	syntaxErr := resp.MakeErrReply("ERR syntax error")
+	for i := 0; i < len(opts); i++ {
+		remaining := len(opts) - i - 1
+		switch strings.ToLower(string(opts[i])) {
+		case "frommember":
+			if remaining < 1 || spec.fromLonLat {
+				return nil, syntaxErr
+			}
+			spec.fromMember = opts[i+1]
+			i++
+		case "fromlonlat":
+			if remaining < 2 || spec.fromMember != nil {
+				return nil, syntaxErr
+			}
+			lon, lat, errReply := parseLonLat(opts[i+1], opts[i+2])
+			if errReply != nil {
+				return nil, errReply
+			}
+			spec.fromLonLat, spec.lon, spec.lat = true, lon, lat
+			i += 2
+		case "byradius":
+			if remaining < 2 || spec.byBox {
+				return nil, syntaxErr
+			}
+			r, err := strconv.ParseFloat(string(opts[i+1]), 64)
+			if err != nil || math.IsNaN(r) {
+				return nil, resp.MakeErrReply("ERR need numeric radius")
+			}
+			if r < 0 {
+				return nil, resp.MakeErrReply("ERR radius cannot be negative")
+			}
+			unit, ok := parseGeoUnit(opts[i+2])
+			if !ok {
+				return nil, errGeoUnit
+			}
+			spec.byRadius, spec.radius, spec.unit = true, r*unit, unit
+			i += 2
+		case "bybox":
+			if remaining < 3 || spec.byRadius {
+				return nil, syntaxErr
+			}
+			w, err := strconv.ParseFloat(string(opts[i+1]), 64)
+			if err != nil || math.IsNaN(w) {
+				return nil, resp.MakeErrReply("ERR need numeric width")
+			}
+			h, err := strconv.ParseFloat(string(opts[i+2]), 64)
+			if err != nil || math.IsNaN(h) {
+				return nil, resp.MakeErrReply("ERR need numeric height")
+			}
+			if w < 0 || h < 0 {
+				return nil, resp.MakeErrReply("ERR height or width cannot be negative")
+			}
+			unit, ok := parseGeoUnit(opts[i+3])
+			if !ok {
+				return nil, errGeoUnit
+			}
+			spec.byBox, spec.width, spec.height, spec.unit = true, w*unit, h*unit, unit
+			i += 3
+		case "asc":
+			spec.sort = 1
+		case "desc":
+			spec.sort = -1
+		case "count":
+			if remaining < 1 {
+				return nil, syntaxErr
+			}
+			n, err := strconv.ParseInt(string(opts[i+1]), 10, 64)
+			if err != nil {
+				return nil, resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n <= 0 {
+				return nil, resp.MakeErrReply("ERR COUNT must be > 0")
+			}
+			spec.count = n
+			i++
+		case "any":
+			spec.any = true
+		case "withcoord":
+			spec.withCoord = true
+		case "withdist":
+			spec.withDist = true
+		case "withhash":
+			spec.withHash = true
+		case "storedist":
+			if !store {
+				return nil, syntaxErr
+			}
+			spec.storeDist = true
+		default:
+			return nil, syntaxErr
+		}
+	}
+
+	if store && (spec.withCoord || spec.withDist || spec.withHash) {
+		return nil, resp.MakeErrReply("ERR " + name + " is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+	}
+	if spec.fromMember == nil && !spec.fromLonLat {
+		return nil, resp.MakeErrReply("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + name)
+	}
+	if !spec.byRadius && !spec.byBox {
+		return nil, resp.MakeErrReply("ERR exactly one of BYRADIUS and BYBOX can be specified for " + name)
+	}
+	if spec.any && spec.count == 0 {
+		return nil, resp.MakeErrReply("ERR the ANY argument requires COUNT argument")
+	}
+	// 指定 COUNT（非 ANY）但未指定顺序时按距离升序，保证返回最近的 count 个
+	if spec.count > 0 && !spec.any && spec.sort == 0 {
+		spec.sort = 1
+	}
+	return spec, nil
+}
+
+// geoSearch 在 zs 上执行搜索，返回命中的点（已按 spec 排序并截断）。
+func geoSearch(zs ZSetData, spec *geoSearchSpec) []geoPoint {
+	lon, lat := spec.lon, spec.lat
+	var areas []geohash.Hash
+	if spec.byRadius {
+		areas = geohash.SearchAreas(lon, lat, spec.radius, spec.radius, spec.radius)
+	} else {
+		halfW, halfH := spec.width/2, spec.height/2
+		areas = geohash.SearchAreas(lon, lat, halfW, halfH, math.Sqrt(halfW*halfW+halfH*halfH))
+	}
+
+	var points []geoPoint
+	for _, area := range areas {
+		min, max := area.Align52()
+		r := &scoreRange{min: float64(min), max: float64(max), maxEx: true}
+		for x := zs.zsl.firstInScoreRange(r); x != nil && r.belowMax(x.score); x = x.level[0].forward {
+			pLon, pLat := geohash.DecodeCenter(uint64(x.score))
+			var (
+				dist float64
+				in   bool
+			)
+			if spec.byRadius {
+				dist, in = geohash.InRadius(lon, lat, spec.radius, pLon, pLat)
+			} else {
+				dist, in = geohash.InBox(lon, lat, spec.width, spec.height, pLon, pLat)
+			}
+			if !in {
+				continue
+			}
+			points = append(points, geoPoint{member: x.member, score: x.score, dist: dist, lon: pLon, lat: pLat})
+			if spec.any && int64(len(points)) >= spec.count {
+				goto done
+			}
+		}
+	}
+
+done:
+	if spec.sort != 0 {
+		sort.Slice(points, func(i, j int) bool {
+			if spec.sort > 0 {
+				return points[i].dist < points[j].dist
+			}
+			return points[i].dist > points[j].dist
+		})
+	}
+	if spec.count > 0 && int64(len(points)) > spec.count {
+		points = points[:spec.count]
+	}
+	return points
+}
+
+// geoSearchPrepare 读取源 ZSET 并确定搜索中心。exists=false 表示源 key 不存在。
+func (db *StandaloneDB) geoSearchPrepare(key string, spec *geoSearchSpec) (ZSetData, bool, resp.Reply) {
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil || !exists {
+		return zs, exists, errReply
+	}
+	if spec.fromMember != nil {
+		lon, lat, ok := geoMemberPos(zs, string(spec.fromMember))
+		if !ok {
+			return zs, false, resp.MakeErrReply("ERR could not decode requested zset member")
+		}
+		spec.lon, spec.lat = lon, lat
+	}
+	return zs, true, nil
+}
+
+// GEOSEARCH key <FROMMEMBER member | FROMLONLAT lon lat> <BYRADIUS radius unit | BYBOX width height unit>
+// [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+func (db *StandaloneDB) geosearch(args [][]byte) resp.Reply {
+	if len(args) < 7 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'geosearch' command")
+	}
+	spec, errReply := parseGeoSearch(string(args[0]), args[2:], false)
+	if errReply != nil {
+		return errReply
+	}
+	zs, exists, errReply := db.geoSearchPrepare(string(args[1]), spec)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeMultiBulkReply([][]byte{})
+	}
+	points := geoSearch(zs, spec)
+
+	if !spec.withCoord && !spec.withDist && !spec.withHash {
+		members := make([][]byte, 0, len(points))
+		for _, p := range points {
+			members = append(members, []byte(p.member))
+		}
+		return resp.MakeMultiBulkReply(members)
+	}
+	// 每项为 [member, dist?, hash?, [lon, lat]?]
+	replies := make([]resp.Reply, 0, len(points))
+	for _, p := range points {
+		item := []resp.Reply{resp.MakeBulkReply([]byte(p.member))}
+		if spec.withDist {
+			item = append(item, resp.MakeBulkReply(formatGeoDist(p.dist, spec.unit)))
+		}
+		if spec.withHash {
+			item = append(item, resp.MakeIntReply(int64(p.score)))
+		}
+		if spec.withCoord {
+			item = append(item, resp.MakeMultiBulkReply([][]byte{formatGeoCoord(p.lon), formatGeoCoord(p.lat)}))
+		}
+		replies = append(replies, resp.MakeMultiRawReply(item))
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// GEOSEARCHSTORE destination source <FROMMEMBER ...|FROMLONLAT ...> <BYRADIUS ...|BYBOX ...>
+// [ASC|DESC] [COUNT count [ANY]] [STOREDIST]
+func (db *StandaloneDB) geosearchstore(args [][]byte) resp.Reply {
+	if len(args) < 8 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'geosearchstore' command")
+	}
+	spec, errReply := parseGeoSearch(string(args[0]), args[3:], true)
+	if errReply != nil {
+		return errReply
+	}
+	zs, exists, errReply := db.geoSearchPrepare(string(args[2]), spec)
+	if errReply != nil {
+		return errReply
+	}
+	var points []geoPoint
+	if exists {
+		points = geoSearch(zs, spec)
+	}
+
+	// 目标 key 被整体覆盖（包括类型与 TTL），结果为空时删除
+	dst := string(args[1])
+	if _, ok := db.cache.Peek(dst); ok {
+		db.cache.Remove(dst)
+	}
+	if len(points) == 0 {
+		return resp.MakeIntReply(0)
+	}
+	result := newZSetData()
+	for _, p := range points {
+		score := p.score
+		if spec.storeDist {
+			score = p.dist / spec.unit
+		}
+		result.add(p.member, score)
+	}
+	db.cache.Add(dst, result, 0)
+	return resp.MakeIntReply(int64(len(points)))
+}
@@ -0,0 +1,209 @@
+// GEO 命令测试：用 Redis 文档中的 Sicily 示例校验 GEOADD/GEOPOS/GEODIST/GEOSEARCH 的回包格式与数值。
+// 目标：坐标、距离的字符串格式与 Redis 完全一致；BYRADIUS/BYBOX、ASC/DESC、COUNT [ANY] 与 WITH* 组合正确。
+// 覆盖：GEOSEARCHSTORE（含 STOREDIST）覆盖目标 key；GEO 数据作为 ZSET 经 AOF 重放与 RDB 快照后保持一致。
+package db
+
+import (
+	"myredis/resp"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newSicily(t *testing.T, d *StandaloneDB) {
+	t.Helper()
+	if n := replyInt(t, execArgs(d, "GEOADD", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")); n != 2 {
+		t.Fatalf("GEOADD = %d", n)
+	}
+	execArgs(d, "GEOADD", "Sicily", "12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2")
+}
+
+// geoItems 把带 WITH* 的 GEOSEARCH 回包展开为 "member dist lon lat" 形式，便于比较。
+func geoItems(t *testing.T, r resp.Reply) []string {
+	t.Helper()
+	raw, ok := r.(*resp.MultiRawReply)
+	if !ok {
+		t.Fatalf("expected nested array, got %T %q", r, r.ToBytes())
+	}
+	var out []string
+	for _, item := range raw.Replies {
+		var parts []string
+		for _, f := range item.(*resp.MultiRawReply).Replies {
+			switch v := f.(type) {
+			case *resp.BulkReply:
+				parts = append(parts, string(v.Arg))
+			case *resp.IntReply:
+				parts = append(parts, string(v.ToBytes()[1:len(v.ToBytes())-2]))
+			case *resp.MultiBulkReply:
+				for _, a := range v.Args {
+					parts = append(parts, string(a))
+				}
+			}
+		}
+		out = append(out, strings.Join(parts, " "))
+	}
+	return out
+}
+
+func TestGeo_AddPosDist(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+	newSicily(t, d)
+
+	if br := execArgs(d, "GEODIST", "Sicily", "Palermo", "Catania").(*resp.BulkReply); string(br.Arg) != "166274.1516" {
+		t.Fatalf("GEODIST = %q", br.Arg)
+	}
+	if br := execArgs(d, "GEODIST", "Sicily", "Palermo", "Catania", "km").(*resp.BulkReply); string(br.Arg) != "166.2742" {
+		t.Fatalf("GEODIST km = %q", br.Arg)
+	}
+	if br := execArgs(d, "GEODIST", "Sicily", "Palermo", "Catania", "mi").(*resp.BulkReply); string(br.Arg) != "103.3182" {
+		t.Fatalf("GEODIST mi = %q", br.Arg)
+	}
+	if br := execArgs(d, "GEODIST", "Sicily", "Foo", "Bar").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("GEODIST missing member should be nil")
+	}
+
+	pos := execArgs(d, "GEOPOS", "Sicily", "Palermo", "NonExisting").(*resp.MultiRawReply)
+	if got := string(pos.ToBytes()); got != "*2\r\n*2\r\n$20\r\n13.36138933897018433\r\n$20\r\n38.11555639549629859\r\n*-1\r\n" {
+		t.Fatalf("GEOPOS = %q", got)
+	}
+
+	// GEO 就是 ZSET：分值为 52 位 geohash
+	if br := execArgs(d, "ZSCORE", "Sicily", "Palermo").(*resp.BulkReply); string(br.Arg) != "3479099956230698" {
+		t.Fatalf("ZSCORE Palermo = %q", br.Arg)
+	}
+	if n := replyInt(t, execArgs(d, "GEOADD", "Sicily", "XX", "CH", "13.361389", "38.115556", "Palermo", "1", "1", "New")); n != 0 {
+		t.Fatalf("GEOADD XX CH unchanged = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "GEOADD", "Sicily", "NX", "0", "0", "Palermo")); n != 0 {
+		t.Fatalf("GEOADD NX existing = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "ZCARD", "Sicily")); n != 4 {
+		t.Fatalf("ZCARD = %d", n)
+	}
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"GEOADD", "Sicily", "181", "0", "bad"}, "ERR invalid longitude,latitude pair 181.000000,0.000000"},
+		{[]string{"GEOADD", "Sicily", "0", "86", "bad"}, "ERR invalid longitude,latitude pair 0.000000,86.000000"},
+		{[]string{"GEOADD", "Sicily", "NX", "XX", "0", "0", "bad"}, "ERR syntax error"},
+		{[]string{"GEOADD", "Sicily", "0", "0", "bad", "1"}, "ERR syntax error"},
+		{[]string{"GEODIST", "Sicily", "Palermo", "Catania", "yd"}, "ERR unsupported unit provided. please use M, KM, FT, MI"},
+	} {
+		if er, ok := execArgs(d, c.args...).(*resp.ErrorReply); !ok || er.Status != c.want {
+			t.Fatalf("%v = %+v, want %q", c.args, er, c.want)
+		}
+	}
+	execArgs(d, "SET", "str", "x")
+	if _, ok := execArgs(d, "GEOPOS", "str", "a").(*resp.ErrorReply); !ok {
+		t.Fatalf("GEOPOS on string should be WRONGTYPE")
+	}
+}
+
+func TestGeo_Search(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+	newSicily(t, d)
+
+	if got := replyStrings(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "ASC")); strings.Join(got, ",") != "Catania,Palermo" {
+		t.Fatalf("GEOSEARCH BYRADIUS = %v", got)
+	}
+	got := geoItems(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "WITHCOORD", "WITHDIST"))
+	want := []string{
+		"Catania 56.4413 15.08726745843887329 37.50266842333162032",
+		"Palermo 190.4424 13.36138933897018433 38.11555639549629859",
+		"edge2 279.7403 17.24151045083999634 38.78813451624225195",
+		"edge1 279.7405 12.7584877610206604 38.78813451624225195",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("GEOSEARCH BYBOX WITHCOORD WITHDIST =\n%v\nwant\n%v", got, want)
+	}
+
+	got = geoItems(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "200", "km", "DESC", "WITHHASH"))
+	if strings.Join(got, "|") != "Catania 3479447370796909|edge1 3479273021651468|Palermo 3479099956230698" {
+		t.Fatalf("GEOSEARCH FROMMEMBER DESC WITHHASH = %v", got)
+	}
+	// COUNT 未指定顺序时返回最近的 count 个
+	if got := replyStrings(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "COUNT", "2")); strings.Join(got, ",") != "Catania,Palermo" {
+		t.Fatalf("GEOSEARCH COUNT = %v", got)
+	}
+	if got := replyStrings(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "COUNT", "1", "ANY")); len(got) != 1 {
+		t.Fatalf("GEOSEARCH COUNT ANY = %v", got)
+	}
+	if got := replyStrings(t, execArgs(d, "GEOSEARCH", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "10", "m")); len(got) != 0 {
+		t.Fatalf("GEOSEARCH far away = %v", got)
+	}
+	if got := execArgs(d, "GEOSEARCH", "missing", "FROMLONLAT", "0", "0", "BYRADIUS", "10", "m").ToBytes(); string(got) != "*0\r\n" {
+		t.Fatalf("GEOSEARCH on missing key = %q", got)
+	}
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"GEOSEARCH", "Sicily", "BYRADIUS", "1", "km", "ASC", "WITHDIST"}, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "ASC", "WITHDIST"}, "ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMMEMBER", "Palermo", "FROMLONLAT", "15", "37", "BYRADIUS", "1", "km"}, "ERR syntax error"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "-1", "km"}, "ERR radius cannot be negative"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "1", "km", "ANY"}, "ERR the ANY argument requires COUNT argument"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "1", "km", "COUNT", "0"}, "ERR COUNT must be > 0"},
+		{[]string{"GEOSEARCH", "Sicily", "FROMMEMBER", "Nope", "BYRADIUS", "1", "km"}, "ERR could not decode requested zset member"},
+		{[]string{"GEOSEARCHSTORE", "dst", "Sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "1", "km", "WITHDIST"}, "ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options"},
+	} {
+		if er, ok := execArgs(d, c.args...).(*resp.ErrorReply); !ok || er.Status != c.want {
+			t.Fatalf("%v = %+v, want %q", c.args, er, c.want)
+		}
+	}
+}
+
+func TestGeo_SearchStoreAndPersistence(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "node.aof")
+	rdbFile := filepath.Join(dir, "node.rdb")
+
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	newSicily(t, d)
+	execArgs(d, "SET", "near", "old value")
+	execArgs(d, "EXPIRE", "near", "100")
+	if n := replyInt(t, execArgs(d, "GEOSEARCHSTORE", "near", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "COUNT", "3")); n != 3 {
+		t.Fatalf("GEOSEARCHSTORE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "TTL", "near")); n != -1 {
+		t.Fatalf("GEOSEARCHSTORE should overwrite TTL, got %d", n)
+	}
+	if got := replyStrings(t, execArgs(d, "ZRANGE", "near", "0", "-1")); strings.Join(got, ",") != "Palermo,Catania,edge2" {
+		t.Fatalf("stored members by geohash = %v", got)
+	}
+	execArgs(d, "GEOSEARCHSTORE", "dists", "Sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "COUNT", "3", "STOREDIST")
+	scores := replyStrings(t, execArgs(d, "ZRANGE", "dists", "0", "-1", "WITHSCORES"))
+	if len(scores) != 6 || scores[0] != "Catania" || !strings.HasPrefix(scores[1], "56.4412") || scores[4] != "edge2" || !strings.HasPrefix(scores[5], "279.740") {
+		t.Fatalf("STOREDIST scores = %v", scores)
+	}
+	if n := replyInt(t, execArgs(d, "GEOSEARCHSTORE", "dists", "Sicily", "FROMLONLAT", "0", "0", "BYRADIUS", "1", "m")); n != 0 {
+		t.Fatalf("empty GEOSEARCHSTORE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "dists")); n != 0 {
+		t.Fatalf("empty GEOSEARCHSTORE should delete destination")
+	}
+	if _, ok := execArgs(d, "SAVE").(*resp.StatusReply); !ok {
+		t.Fatalf("SAVE failed")
+	}
+	d.Close()
+
+	for _, cfg := range []StandaloneDBConfig{
+		{AofFilename: aofFile, MaxBytes: DefaultMaxBytes},
+		{RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes},
+	} {
+		d2 := NewStandaloneDBWithConfig(cfg)
+		d2.Load()
+		if br := execArgs(d2, "GEODIST", "Sicily", "Palermo", "Catania").(*resp.BulkReply); string(br.Arg) != "166274.1516" {
+			t.Fatalf("GEODIST after reload (%+v) = %q", cfg, br.Arg)
+		}
+		if n := replyInt(t, execArgs(d2, "ZCARD", "near")); n != 3 {
+			t.Fatalf("GEOSEARCHSTORE result after reload (%+v) = %d", cfg, n)
+		}
+		d2.Close()
+	}
+}
@@ -1,171 +1,429 @@
-// Hash 命令实现：HSET/HGET/HGETALL/HDEL 等。
+// Hash 命令实现：HSET/HGET/HGETALL/HDEL/HMGET/HINCRBY/HRANDFIELD 等。
 // 说明：内部数据结构保持简单直接，重点在于命令语义正确 + AOF/TTL/淘汰的协同一致性。
-// 关键点：字段写入/删除要正确落 AOF，并在 key 为空时清理 TTL/缓存条目。
+// 关键点：字段写入/删除要正确落 AOF，并在 key 为空时清理 TTL/缓存条目；字段级 TTL 见 hash_ttl.go。
 package db
 
 import (
+	"math"
+	"math/rand"
 	"myredis/resp"
-	"time"
+	"strconv"
+	"strings"
 )
 
-// 本文件实现 Hash 相关命令：HSET / HGET / HGETALL / HDEL
+// 本文件实现 Hash 相关命令：
+// - 写：HSET / HMSET / HSETNX / HDEL / HINCRBY / HINCRBYFLOAT
+// - 读：HGET / HMGET / HGETALL / HEXISTS / HLEN / HKEYS / HVALS / HSTRLEN / HRANDFIELD
 // 说明：
-// - HashData 使用 map[string][]byte
-// - TTL 由 db.ttlMap 管理；过期只做内存删除，不额外写 AOF（AOF 用 PEXPIREAT 记录绝对时间）
+// - HashData 由 fields（map[string][]byte）+ expires（字段级绝对过期时间）组成
+// - key 级 TTL 由 db.ttlMap 管理；过期只做内存删除，不额外写 AOF（AOF 用 PEXPIREAT 记录绝对时间）
+// - HSET/HSETNX 覆盖字段时清除该字段的 TTL；HINCRBY/HINCRBYFLOAT 保留 TTL（与 Redis 一致）
 
-func (db *StandaloneDB) getHash(key string) (HashData, bool) {
-	val, ok := db.cache.Get(key)
+// --- HashData 基础操作 ---
+
+// set 写入字段并清除其 TTL，返回是否为新字段。
+func (d HashData) set(field string, val []byte) bool {
+	added := d.update(field, val)
+	delete(d.expires, field)
+	return added
+}
+
+// update 写入字段但保留其 TTL（HINCRBY/HINCRBYFLOAT），返回是否为新字段。
+func (d HashData) update(field string, val []byte) bool {
+	_, exists := d.fields[field]
+	d.fields[field] = val
+	if !exists {
+		d.scan.added(field)
+	}
+	return !exists
+}
+
+// remove 删除字段（连同其 TTL），返回字段是否存在。
+func (d HashData) remove(field string) bool {
+	if _, ok := d.fields[field]; !ok {
+		return false
+	}
+	delete(d.fields, field)
+	delete(d.expires, field)
+	d.scan.removed(field)
+	return true
+}
+
+// getHashData 读取 Hash（含 key 级与字段级惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+// 字段全部过期时删除 key，调用方看到的是“key 不存在”。
+func (db *StandaloneDB) getHashData(key string) (HashData, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return HashData{}, false, nil
+	}
+	h, ok := entity.(HashData)
 	if !ok {
-		return nil, false
+		return HashData{}, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
 	}
+	if !db.expireHashFields(key, h) {
+		return HashData{}, false, nil
+	}
+	return h, true, nil
+}
 
-	if expireTime, ok := db.ttlMap[key]; ok {
-		if time.Now().After(expireTime) {
+// storeHash 写回 Hash：为空时删除 key（同时清理 TTL），否则刷新缓存中的大小统计。
+func (db *StandaloneDB) storeHash(key string, h HashData) {
+	if len(h.fields) == 0 {
+		if _, ok := db.cache.Peek(key); ok {
 			db.cache.Remove(key)
-			return nil, false
 		}
+		return
 	}
-
-	h, ok := val.(HashData)
-	return h, ok
+	db.cache.Add(key, h, 0)
 }
 
-// HSET key field value
+// HSET key field value [field value ...]
 func (db *StandaloneDB) hset(args [][]byte) resp.Reply {
 	if len(args) < 4 || len(args)%2 != 0 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'hset' command")
 	}
 	key := string(args[1])
 
-	h, ok := db.getHash(key)
-	if !ok {
-		// Create new
-		h = make(HashData)
-		// Check if WRONGTYPE happened inside getHash?
-		// getHash returns (nil, false) if not found OR expired.
-		// It doesn't distinguish "not found" vs "wrong type".
-		// Wait, cache.Get returns interface{}.
-
-		// Let's refine getHash logic or do it inline.
-		// If key exists but wrong type, getHash returns (nil, false)?
-		// No, val.(HashData) fails -> return (nil, false).
-		// So HSET overwrites if wrong type? No, Redis returns WRONGTYPE.
-
-		// We need to check existence first.
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(HashData); !ok {
-				// Check expiry here too?
-				// If expired, we should treat as new.
-				if expireTime, ok := db.ttlMap[key]; ok {
-					if time.Now().After(expireTime) {
-						db.cache.Remove(key)
-						// Now it's deleted. Treat as new.
-						goto CreateNew
-					}
-				}
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
 	}
-
-CreateNew:
-	if h == nil {
-		h = make(HashData)
+	if !exists {
+		h = newHashData()
 	}
 
 	count := 0
 	for i := 2; i < len(args); i += 2 {
-		field := string(args[i])
-		val := args[i+1]
-		if _, exists := h[field]; !exists {
+		if h.set(string(args[i]), args[i+1]) {
 			count++
 		}
-		h[field] = val
 	}
 
 	db.cache.Add(key, h, 0)
 	return resp.MakeIntReply(int64(count))
 }
 
+// HGET key field
 func (db *StandaloneDB) hget(args [][]byte) resp.Reply {
 	if len(args) != 3 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'hget' command")
 	}
-	key := string(args[1])
-	field := string(args[2])
-
-	h, ok := db.getHash(key)
-	if !ok {
-		// Could be wrong type or not found.
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(HashData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
+	h, exists, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
 		return resp.NullBulkReply
 	}
 
-	val, exists := h[field]
-	if !exists {
+	val, ok := h.fields[string(args[2])]
+	if !ok {
 		return resp.NullBulkReply
 	}
 	return resp.MakeBulkReply(val)
 }
 
+// HGETALL key
 func (db *StandaloneDB) hgetall(args [][]byte) resp.Reply {
 	if len(args) != 2 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'hgetall' command")
 	}
-	key := string(args[1])
-
-	h, ok := db.getHash(key)
-	if !ok {
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(HashData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
-		return resp.MakeMultiBulkReply(nil)
+	h, exists, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeMapBulkReply(nil)
 	}
 
-	res := make([][]byte, 0, len(h)*2)
-	for k, v := range h {
+	// RESP3 下为 map，RESP2 下为 field/value 交替的数组
+	res := make([][]byte, 0, len(h.fields)*2)
+	for k, v := range h.fields {
 		res = append(res, []byte(k))
 		res = append(res, v)
 	}
-	return resp.MakeMultiBulkReply(res)
+	return resp.MakeMapBulkReply(res)
 }
 
+// HDEL key field [field ...]
 func (db *StandaloneDB) hdel(args [][]byte) resp.Reply {
 	if len(args) < 3 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'hdel' command")
 	}
 	key := string(args[1])
 
-	h, ok := db.getHash(key)
-	if !ok {
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(HashData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
 		return resp.MakeIntReply(0)
 	}
 
 	count := 0
 	for i := 2; i < len(args); i++ {
-		field := string(args[i])
-		if _, exists := h[field]; exists {
-			delete(h, field)
+		if h.remove(string(args[i])) {
 			count++
 		}
 	}
+	db.storeHash(key, h)
+	return resp.MakeIntReply(int64(count))
+}
 
-	if len(h) == 0 {
-		db.cache.Remove(key)
-	} else {
-		db.cache.Add(key, h, 0)
+// HMSET key field value [field value ...]（旧版本命令，语义同 HSET，返回 OK）
+func (db *StandaloneDB) hmset(args [][]byte) resp.Reply {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hmset' command")
+	}
+	if r := db.hset(args); isError(r) {
+		return r
 	}
+	return resp.OkReply
+}
 
-	return resp.MakeIntReply(int64(count))
+// HMGET key field [field ...]
+func (db *StandaloneDB) hmget(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hmget' command")
+	}
+	h, _, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	res := make([][]byte, 0, len(args)-2)
+	for _, f := range args[2:] {
+		// 不存在的 field（或 key）返回 nil
+		res = append(res, h.fields[string(f)])
+	}
+	return resp.MakeMultiBulkReply(res)
+}
+
+// HEXISTS key field
+func (db *StandaloneDB) hexists(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hexists' command")
+	}
+	h, _, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if _, ok := h.fields[string(args[2])]; ok {
+		return resp.MakeIntReply(1)
+	}
+	return resp.MakeIntReply(0)
+}
+
+// HLEN key
+func (db *StandaloneDB) hlen(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hlen' command")
+	}
+	h, _, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeIntReply(int64(len(h.fields)))
+}
+
+// HKEYS key / HVALS key
+func (db *StandaloneDB) hkeysOrVals(args [][]byte, keys bool) resp.Reply {
+	if len(args) != 2 {
+		name := "hvals"
+		if keys {
+			name = "hkeys"
+		}
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	h, _, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	res := make([][]byte, 0, len(h.fields))
+	for f, v := range h.fields {
+		if keys {
+			res = append(res, []byte(f))
+		} else {
+			res = append(res, v)
+		}
+	}
+	return resp.MakeMultiBulkReply(res)
+}
+
+// HSETNX key field value：只在 field 不存在时写入。
+func (db *StandaloneDB) hsetnx(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hsetnx' command")
+	}
+	key := string(args[1])
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		h = newHashData()
+	}
+	field := string(args[2])
+	if _, ok := h.fields[field]; ok {
+		return resp.MakeIntReply(0)
+	}
+	h.set(field, args[3])
+	db.cache.Add(key, h, 0)
+	return resp.MakeIntReply(1)
+}
+
+// HINCRBY key field increment
+func (db *StandaloneDB) hincrby(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hincrby' command")
+	}
+	key := string(args[1])
+	delta, err := strconv.ParseInt(string(args[3]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		h = newHashData()
+	}
+
+	field := string(args[2])
+	var cur int64
+	if v, ok := h.fields[field]; ok {
+		cur, err = strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR hash value is not an integer")
+		}
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return resp.MakeErrReply("ERR increment or decrement would overflow")
+	}
+	cur += delta
+	h.update(field, []byte(strconv.FormatInt(cur, 10))) // 保留字段 TTL
+	db.cache.Add(key, h, 0)
+	return resp.MakeIntReply(cur)
+}
+
+// HINCRBYFLOAT key field increment
+func (db *StandaloneDB) hincrbyfloat(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hincrbyfloat' command")
+	}
+	key := string(args[1])
+	delta, err := strconv.ParseFloat(string(args[3]), 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return resp.MakeErrReply("ERR value is not a valid float")
+	}
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		h = newHashData()
+	}
+
+	field := string(args[2])
+	var cur float64
+	if v, ok := h.fields[field]; ok {
+		cur, err = strconv.ParseFloat(string(v), 64)
+		if err != nil || math.IsNaN(cur) || math.IsInf(cur, 0) {
+			return resp.MakeErrReply("ERR hash value is not a float")
+		}
+	}
+	cur += delta
+	if math.IsNaN(cur) || math.IsInf(cur, 0) {
+		return resp.MakeErrReply("ERR increment would produce NaN or Infinity")
+	}
+	val := []byte(strconv.FormatFloat(cur, 'f', -1, 64))
+	h.update(field, val) // 保留字段 TTL
+	db.cache.Add(key, h, 0)
+	return resp.MakeBulkReply(val)
+}
+
+// HSTRLEN key field
+func (db *StandaloneDB) hstrlen(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hstrlen' command")
+	}
+	h, _, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeIntReply(int64(len(h.fields[string(args[2])])))
+}
+
+// HRANDFIELD key [count [WITHVALUES]]
+// - 不带 count：返回一个随机 field（key 不存在返回 nil）
+// - count > 0：返回最多 count 个互不相同的 field
+// - count < 0：返回 |count| 个 field，允许重复
+func (db *StandaloneDB) hrandfield(args [][]byte) resp.Reply {
+	if len(args) < 2 || len(args) > 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hrandfield' command")
+	}
+	var (
+		count      int64
+		withCount  = len(args) >= 3
+		withValues bool
+	)
+	if withCount {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		count = n
+		if len(args) == 4 {
+			if !strings.EqualFold(string(args[3]), "withvalues") {
+				return resp.MakeErrReply("ERR syntax error")
+			}
+			withValues = true
+		}
+		// 与 Redis 一致：避免 WITHVALUES 下 count*2 溢出
+		if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+			return resp.MakeErrReply("ERR value is out of range")
+		}
+	}
+
+	h, exists, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !withCount {
+		if !exists {
+			return resp.NullBulkReply
+		}
+		for f := range h.fields {
+			return resp.MakeBulkReply([]byte(f))
+		}
+	}
+	res := make([][]byte, 0)
+	if !exists || count == 0 {
+		return resp.MakeMultiBulkReply(res)
+	}
+
+	fields := make([]string, 0, len(h.fields))
+	for f := range h.fields {
+		fields = append(fields, f)
+	}
+	emit := func(f string) {
+		res = append(res, []byte(f))
+		if withValues {
+			res = append(res, h.fields[f])
+		}
+	}
+	if count < 0 {
+		for i := int64(0); i < -count; i++ {
+			emit(fields[rand.Intn(len(fields))])
+		}
+		return resp.MakeMultiBulkReply(res)
+	}
+	rand.Shuffle(len(fields), func(i, j int) { fields[i], fields[j] = fields[j], fields[i] })
+	if count > int64(len(fields)) {
+		count = int64(len(fields))
+	}
+	for _, f := range fields[:count] {
+		emit(f)
+	}
+	return resp.MakeMultiBulkReply(res)
 }
@@ -0,0 +1,103 @@
+// Hash 扩展命令测试：覆盖 HMGET/HEXISTS/HLEN/HKEYS/HVALS/HSETNX/HSTRLEN 的回包形态与 HINCRBY/HINCRBYFLOAT 计数路径。
+// 目标：保证与 Redis 一致的错误信息（非整数/非浮点/溢出/WRONGTYPE），以及 HRANDFIELD 的 count 正负语义。
+// 覆盖：缺失 key/field 的 nil 回包、计数器 AOF 重放。
+package db
+
+import (
+	"myredis/resp"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHash_ReadCommands(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "HSET", "user:1", "name", "alice", "city", "paris")
+	mb := execArgs(d, "HMGET", "user:1", "name", "missing", "city").(*resp.MultiBulkReply)
+	if len(mb.Args) != 3 || string(mb.Args[0]) != "alice" || mb.Args[1] != nil || string(mb.Args[2]) != "paris" {
+		t.Fatalf("HMGET = %q", mb.Args)
+	}
+	if mb := execArgs(d, "HMGET", "nokey", "a").(*resp.MultiBulkReply); len(mb.Args) != 1 || mb.Args[0] != nil {
+		t.Fatalf("HMGET missing key = %q", mb.Args)
+	}
+	if n := replyInt(t, execArgs(d, "HEXISTS", "user:1", "name")); n != 1 {
+		t.Fatalf("HEXISTS = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "HLEN", "user:1")); n != 2 {
+		t.Fatalf("HLEN = %d", n)
+	}
+	keys := replyStrings(t, execArgs(d, "HKEYS", "user:1"))
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "city,name" {
+		t.Fatalf("HKEYS = %v", keys)
+	}
+	if vals := replyStrings(t, execArgs(d, "HVALS", "nokey")); len(vals) != 0 {
+		t.Fatalf("HVALS missing key = %v", vals)
+	}
+	if n := replyInt(t, execArgs(d, "HSTRLEN", "user:1", "name")); n != 5 {
+		t.Fatalf("HSTRLEN = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "HSETNX", "user:1", "name", "bob")); n != 0 {
+		t.Fatalf("HSETNX existing = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "HSETNX", "user:1", "age", "30")); n != 1 {
+		t.Fatalf("HSETNX new = %d", n)
+	}
+
+	// HRANDFIELD：正数不重复且不超过字段数；负数允许重复
+	if got := replyStrings(t, execArgs(d, "HRANDFIELD", "user:1", "10")); len(got) != 3 {
+		t.Fatalf("HRANDFIELD 10 = %v", got)
+	}
+	if got := replyStrings(t, execArgs(d, "HRANDFIELD", "user:1", "-5", "WITHVALUES")); len(got) != 10 {
+		t.Fatalf("HRANDFIELD -5 WITHVALUES = %v", got)
+	}
+	if br := execArgs(d, "HRANDFIELD", "nokey").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("HRANDFIELD missing key = %q", br.Arg)
+	}
+
+	execArgs(d, "SET", "str", "v")
+	if _, ok := execArgs(d, "HLEN", "str").(*resp.ErrorReply); !ok {
+		t.Fatalf("HLEN on string should be WRONGTYPE")
+	}
+}
+
+func TestHash_IncrAndReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	if n := replyInt(t, execArgs(d, "HINCRBY", "stats", "visits", "5")); n != 5 {
+		t.Fatalf("HINCRBY = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "HINCRBY", "stats", "visits", "-2")); n != 3 {
+		t.Fatalf("HINCRBY negative = %d", n)
+	}
+	if br := execArgs(d, "HINCRBYFLOAT", "stats", "score", "10.5").(*resp.BulkReply); string(br.Arg) != "10.5" {
+		t.Fatalf("HINCRBYFLOAT = %q", br.Arg)
+	}
+	if br := execArgs(d, "HINCRBYFLOAT", "stats", "score", "-0.5").(*resp.BulkReply); string(br.Arg) != "10" {
+		t.Fatalf("HINCRBYFLOAT = %q", br.Arg)
+	}
+
+	execArgs(d, "HSET", "stats", "name", "x", "big", "9223372036854775807")
+	if er, ok := execArgs(d, "HINCRBY", "stats", "name", "1").(*resp.ErrorReply); !ok || er.Status != "ERR hash value is not an integer" {
+		t.Fatalf("HINCRBY non-integer = %+v", er)
+	}
+	if er, ok := execArgs(d, "HINCRBY", "stats", "big", "1").(*resp.ErrorReply); !ok || er.Status != "ERR increment or decrement would overflow" {
+		t.Fatalf("HINCRBY overflow = %+v", er)
+	}
+	if er, ok := execArgs(d, "HINCRBYFLOAT", "stats", "name", "1").(*resp.ErrorReply); !ok || er.Status != "ERR hash value is not a float" {
+		t.Fatalf("HINCRBYFLOAT non-float = %+v", er)
+	}
+	d.Close()
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	mb := execArgs(d2, "HMGET", "stats", "visits", "score").(*resp.MultiBulkReply)
+	if string(mb.Args[0]) != "3" || string(mb.Args[1]) != "10" {
+		t.Fatalf("after replay = %q", mb.Args)
+	}
+}
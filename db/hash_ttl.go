@@ -0,0 +1,351 @@
+// Hash 字段级 TTL：实现 HEXPIRE/HPEXPIRE/HEXPIREAT/HPEXPIREAT、HTTL/HPTTL/HEXPIRETIME/HPEXPIRETIME、HPERSIST。
+// 说明：字段过期时间以绝对毫秒保存在 HashData.expires 中，读取时惰性清理，activeExpire 定期抽样清理。
+// 关键点：AOF 统一记录 HPEXPIREAT（绝对时间），立即过期的字段记录为 HDEL，保证重启不会“续命”。
+package db
+
+import (
+	"math"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现 Hash 字段级 TTL（语法对齐 Redis 7.4）：
+// - HEXPIRE key seconds [NX|XX|GT|LT] FIELDS numfields field [field ...]（及 HPEXPIRE/HEXPIREAT/HPEXPIREAT）
+//   每个字段返回：-2 字段不存在；0 条件不满足；1 设置成功；2 时间已过去（或为 0），字段被删除
+// - HTTL key FIELDS numfields field [field ...]（及 HPTTL/HEXPIRETIME/HPEXPIRETIME）
+//   每个字段返回：-2 字段不存在；-1 没有 TTL；否则为剩余时间/绝对时间
+// - HPERSIST key FIELDS numfields field [field ...]：-2 字段不存在；-1 没有 TTL；1 已移除 TTL
+//
+// 说明：
+// - HSET/HSETNX 覆盖字段会清除其 TTL，HINCRBY/HINCRBYFLOAT 保留 TTL
+// - 字段全部过期时删除整个 key
+// - hashTTLKeys 只是定期删除的候选集合，可能包含已不存在或已无字段 TTL 的 key，抽样时顺带清理
+
+// activeExpireHashSample 为每轮定期删除抽样检查的 Hash key 数。
+const activeExpireHashSample = 20
+
+// expireHashFields 删除 h 中已过期的字段并写回缓存。返回 false 表示字段全部过期、key 已被删除。
+func (db *StandaloneDB) expireHashFields(key string, h HashData) bool {
+	if len(h.expires) == 0 {
+		return true
+	}
+	nowMs := time.Now().UnixMilli()
+	changed := false
+	for field, at := range h.expires {
+		if at <= nowMs {
+			h.remove(field)
+			changed = true
+		}
+	}
+	if len(h.expires) == 0 {
+		delete(db.hashTTLKeys, key)
+	}
+	if !changed {
+		return true
+	}
+	db.notify(notifyHash, "hexpired", key)
+	db.storeHash(key, h)
+	return len(h.fields) > 0
+}
+
+// trackHashTTL 在 key 为带字段 TTL 的 Hash 时登记到 hashTTLKeys（RENAME/COPY/加载快照后调用）。
+func (db *StandaloneDB) trackHashTTL(key string) {
+	v, ok := db.cache.Peek(key)
+	if !ok {
+		return
+	}
+	if h, ok := v.(HashData); ok && len(h.expires) > 0 {
+		db.hashTTLKeys[key] = struct{}{}
+	}
+}
+
+// activeExpireHashFields 抽样检查带字段 TTL 的 Hash，清理已过期字段（只清理内存，AOF 中已有绝对时间）。
+func (db *StandaloneDB) activeExpireHashFields() {
+	sampleSize := activeExpireHashSample
+	for key := range db.hashTTLKeys {
+		v, ok := db.cache.Peek(key)
+		h, isHash := v.(HashData)
+		if !ok || !isHash {
+			delete(db.hashTTLKeys, key)
+		} else {
+			db.expireHashFields(key, h)
+		}
+		sampleSize--
+		if sampleSize <= 0 {
+			break
+		}
+	}
+}
+
+// parseHashFields 解析 "FIELDS numfields field [field ...]"，要求恰好占满剩余参数。
+func parseHashFields(args [][]byte) ([]string, resp.Reply) {
+	if len(args) < 2 || !strings.EqualFold(string(args[0]), "fields") {
+		return nil, resp.MakeErrReply("ERR Mandatory argument FIELDS is missing or not at the right position")
+	}
+	n, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || n <= 0 {
+		return nil, resp.MakeErrReply("ERR Parameter `numFields` should be greater than 0")
+	}
+	if n != int64(len(args)-2) {
+		return nil, resp.MakeErrReply("ERR The `numfields` parameter must match the number of arguments")
+	}
+	fields := make([]string, 0, n)
+	for _, f := range args[2:] {
+		fields = append(fields, string(f))
+	}
+	return fields, nil
+}
+
+// hexpireCondition 为 HEXPIRE 系列的 NX/XX/GT/LT 条件。
+type hexpireCondition int
+
+const (
+	hexpireAlways hexpireCondition = iota
+	hexpireNX
+	hexpireXX
+	hexpireGT
+	hexpireLT
+)
+
+// parseHexpireArgs 解析 "key time [NX|XX|GT|LT] FIELDS numfields field ..."，返回绝对过期时间（毫秒）。
+func parseHexpireArgs(args [][]byte, name string, unit time.Duration, absolute bool) (int64, hexpireCondition, []string, resp.Reply) {
+	if len(args) < 6 {
+		return 0, 0, nil, resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	t, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return 0, 0, nil, resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	if t < 0 {
+		return 0, 0, nil, resp.MakeErrReply("ERR invalid expire time, must be >= 0")
+	}
+
+	cond := hexpireAlways
+	rest := args[3:]
+	switch strings.ToLower(string(rest[0])) {
+	case "nx":
+		cond = hexpireNX
+	case "xx":
+		cond = hexpireXX
+	case "gt":
+		cond = hexpireGT
+	case "lt":
+		cond = hexpireLT
+	}
+	if cond != hexpireAlways {
+		rest = rest[1:]
+	}
+	fields, errReply := parseHashFields(rest)
+	if errReply != nil {
+		return 0, 0, nil, errReply
+	}
+
+	unitMs := int64(unit / time.Millisecond)
+	base := int64(0)
+	if !absolute {
+		base = time.Now().UnixMilli()
+	}
+	if t > (math.MaxInt64-base)/unitMs {
+		return 0, 0, nil, resp.MakeErrReply("ERR invalid expire time in '" + name + "' command")
+	}
+	return base + t*unitMs, cond, fields, nil
+}
+
+// HEXPIRE / HPEXPIRE / HEXPIREAT / HPEXPIREAT key time [NX|XX|GT|LT] FIELDS numfields field [field ...]
+func (db *StandaloneDB) hexpireGeneric(args [][]byte, name string, unit time.Duration, absolute bool) resp.Reply {
+	at, cond, fields, errReply := parseHexpireArgs(args, name, unit, absolute)
+	if errReply != nil {
+		return errReply
+	}
+	key := string(args[1])
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+	replies := make([]resp.Reply, 0, len(fields))
+	if !exists {
+		for range fields {
+			replies = append(replies, resp.MakeIntReply(-2))
+		}
+		return resp.MakeMultiRawReply(replies)
+	}
+
+	nowMs := time.Now().UnixMilli()
+	changed := false
+	for _, f := range fields {
+		if _, ok := h.fields[f]; !ok {
+			replies = append(replies, resp.MakeIntReply(-2))
+			continue
+		}
+		cur, hasTTL := h.expires[f]
+		skip := false
+		switch cond {
+		case hexpireNX:
+			skip = hasTTL
+		case hexpireXX:
+			skip = !hasTTL
+		case hexpireGT:
+			// 没有 TTL 视为无限长，任何时间都不会“更大”
+			skip = !hasTTL || at <= cur
+		case hexpireLT:
+			skip = hasTTL && at >= cur
+		}
+		if skip {
+			replies = append(replies, resp.MakeIntReply(0))
+			continue
+		}
+		changed = true
+		if at <= nowMs {
+			h.remove(f)
+			replies = append(replies, resp.MakeIntReply(2))
+			continue
+		}
+		h.expires[f] = at
+		replies = append(replies, resp.MakeIntReply(1))
+	}
+	if changed {
+		db.storeHash(key, h)
+		if len(h.expires) > 0 {
+			db.hashTTLKeys[key] = struct{}{}
+		}
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// appendHashExpireAof 把 HEXPIRE 系列改写为 HPEXPIREAT（绝对时间），被立即删除的字段记为 HDEL。
+func (db *StandaloneDB) appendHashExpireAof(cmd [][]byte, res resp.Reply) {
+	raw, ok := res.(*resp.MultiRawReply)
+	if !ok || len(cmd) < 6 {
+		return
+	}
+	rest := cmd[3:]
+	if !strings.EqualFold(string(rest[0]), "fields") {
+		rest = rest[1:]
+	}
+	fields, errReply := parseHashFields(rest)
+	if errReply != nil || len(fields) != len(raw.Replies) {
+		return
+	}
+
+	var set, deleted [][]byte
+	for i, r := range raw.Replies {
+		ir, ok := r.(*resp.IntReply)
+		if !ok {
+			continue
+		}
+		switch ir.Code {
+		case 1:
+			set = append(set, []byte(fields[i]))
+		case 2:
+			deleted = append(deleted, []byte(fields[i]))
+		}
+	}
+	if len(deleted) > 0 {
+		db.addAof(append([][]byte{[]byte("HDEL"), cmd[1]}, deleted...))
+	}
+	if len(set) == 0 {
+		return
+	}
+	v, ok := db.cache.Peek(string(cmd[1]))
+	if !ok {
+		return
+	}
+	h, ok := v.(HashData)
+	if !ok {
+		return
+	}
+	at, ok := h.expires[string(set[0])]
+	if !ok {
+		return
+	}
+	record := [][]byte{
+		[]byte("HPEXPIREAT"), cmd[1], []byte(strconv.FormatInt(at, 10)),
+		[]byte("FIELDS"), []byte(strconv.Itoa(len(set))),
+	}
+	db.addAof(append(record, set...))
+}
+
+// HTTL / HPTTL / HEXPIRETIME / HPEXPIRETIME key FIELDS numfields field [field ...]
+func (db *StandaloneDB) httlGeneric(args [][]byte, name string, unit time.Duration, absolute bool) resp.Reply {
+	if len(args) < 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	fields, errReply := parseHashFields(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	h, exists, errReply := db.getHashData(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+
+	unitMs := int64(unit / time.Millisecond)
+	nowMs := time.Now().UnixMilli()
+	replies := make([]resp.Reply, 0, len(fields))
+	for _, f := range fields {
+		if !exists {
+			replies = append(replies, resp.MakeIntReply(-2))
+			continue
+		}
+		if _, ok := h.fields[f]; !ok {
+			replies = append(replies, resp.MakeIntReply(-2))
+			continue
+		}
+		at, hasTTL := h.expires[f]
+		switch {
+		case !hasTTL:
+			replies = append(replies, resp.MakeIntReply(-1))
+		case absolute:
+			replies = append(replies, resp.MakeIntReply(at/unitMs))
+		default:
+			// 剩余秒数向上取整，避免还有几百毫秒时返回 0
+			replies = append(replies, resp.MakeIntReply((at-nowMs+unitMs-1)/unitMs))
+		}
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// HPERSIST key FIELDS numfields field [field ...]
+func (db *StandaloneDB) hpersist(args [][]byte) resp.Reply {
+	if len(args) < 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'hpersist' command")
+	}
+	fields, errReply := parseHashFields(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	key := string(args[1])
+	h, exists, errReply := db.getHashData(key)
+	if errReply != nil {
+		return errReply
+	}
+
+	replies := make([]resp.Reply, 0, len(fields))
+	changed := false
+	for _, f := range fields {
+		if !exists {
+			replies = append(replies, resp.MakeIntReply(-2))
+			continue
+		}
+		if _, ok := h.fields[f]; !ok {
+			replies = append(replies, resp.MakeIntReply(-2))
+			continue
+		}
+		if _, ok := h.expires[f]; !ok {
+			replies = append(replies, resp.MakeIntReply(-1))
+			continue
+		}
+		delete(h.expires, f)
+		changed = true
+		replies = append(replies, resp.MakeIntReply(1))
+	}
+	if changed {
+		db.storeHash(key, h)
+		if len(h.expires) == 0 {
+			delete(db.hashTTLKeys, key)
+		}
+	}
+	return resp.MakeMultiRawReply(replies)
+}
@@ -0,0 +1,193 @@
+// Hash 字段级 TTL 测试：覆盖 HEXPIRE 的条件语义与回包、HTTL/HPERSIST、惰性过期删除 key。
+// 目标：保证 AOF 记录绝对时间（HPEXPIREAT），重放/快照/重写后字段的过期时间不变，不会“续命”。
+// 覆盖：NX/XX/GT/LT、立即过期（返回 2）、HSET 清除字段 TTL、SAVE/Load 与 REWRITEAOF 往返。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// replyInts 把 MultiRawReply 中的整数回包展开为切片。
+func replyInts(t *testing.T, r resp.Reply) []int64 {
+	t.Helper()
+	raw, ok := r.(*resp.MultiRawReply)
+	if !ok {
+		t.Fatalf("expected raw array, got %T %+v", r, r)
+	}
+	out := make([]int64, 0, len(raw.Replies))
+	for _, x := range raw.Replies {
+		out = append(out, replyInt(t, x))
+	}
+	return out
+}
+
+func intsEqual(a []int64, b ...int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHashTTL_ConditionsAndQueries(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "HSET", "h", "a", "1", "b", "2", "c", "3")
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "100", "FIELDS", "2", "a", "nope")); !intsEqual(got, 1, -2) {
+		t.Fatalf("HEXPIRE = %v", got)
+	}
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "200", "NX", "FIELDS", "2", "a", "b")); !intsEqual(got, 0, 1) {
+		t.Fatalf("HEXPIRE NX = %v", got)
+	}
+	// GT：没有 TTL 的字段视为无限长
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "150", "GT", "FIELDS", "3", "a", "b", "c")); !intsEqual(got, 1, 0, 0) {
+		t.Fatalf("HEXPIRE GT = %v", got)
+	}
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "50", "LT", "FIELDS", "2", "b", "c")); !intsEqual(got, 1, 1) {
+		t.Fatalf("HEXPIRE LT = %v", got)
+	}
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "10", "XX", "FIELDS", "1", "c")); !intsEqual(got, 1) {
+		t.Fatalf("HEXPIRE XX = %v", got)
+	}
+
+	if got := replyInts(t, execArgs(d, "HTTL", "h", "FIELDS", "3", "a", "c", "nope")); !intsEqual(got, 150, 10, -2) {
+		t.Fatalf("HTTL = %v", got)
+	}
+	if got := replyInts(t, execArgs(d, "HPERSIST", "h", "FIELDS", "2", "a", "a")); !intsEqual(got, 1, -1) {
+		t.Fatalf("HPERSIST = %v", got)
+	}
+	// HSET 覆盖字段会清除其 TTL
+	execArgs(d, "HSET", "h", "b", "x")
+	if got := replyInts(t, execArgs(d, "HPTTL", "h", "FIELDS", "2", "a", "b")); !intsEqual(got, -1, -1) {
+		t.Fatalf("HPTTL = %v", got)
+	}
+	if got := replyInts(t, execArgs(d, "HTTL", "missing", "FIELDS", "1", "a")); !intsEqual(got, -2) {
+		t.Fatalf("HTTL missing key = %v", got)
+	}
+
+	// 0 秒等价于立即过期：字段被删除，返回 2
+	if got := replyInts(t, execArgs(d, "HEXPIRE", "h", "0", "FIELDS", "1", "c")); !intsEqual(got, 2) {
+		t.Fatalf("HEXPIRE 0 = %v", got)
+	}
+	if n := replyInt(t, execArgs(d, "HLEN", "h")); n != 2 {
+		t.Fatalf("HLEN after immediate expire = %d", n)
+	}
+
+	if er, ok := execArgs(d, "HEXPIRE", "h", "10", "FIELDS", "2", "a").(*resp.ErrorReply); !ok || er.Status != "ERR The `numfields` parameter must match the number of arguments" {
+		t.Fatalf("numfields mismatch = %+v", er)
+	}
+	if er, ok := execArgs(d, "HEXPIRE", "h", "10", "FIELDS", "0", "a").(*resp.ErrorReply); !ok || er.Status != "ERR Parameter `numFields` should be greater than 0" {
+		t.Fatalf("numfields 0 = %+v", er)
+	}
+	if _, ok := execArgs(d, "HEXPIRE", "h", "9223372036854775807", "FIELDS", "1", "a").(*resp.ErrorReply); !ok {
+		t.Fatalf("overflowing expire time should be rejected")
+	}
+}
+
+func TestHashTTL_LazyAndActiveExpire(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "HSET", "h", "a", "1", "b", "2")
+	execArgs(d, "HSET", "all", "x", "1")
+	execArgs(d, "HPEXPIRE", "h", "50", "FIELDS", "1", "a")
+	execArgs(d, "HPEXPIRE", "all", "50", "FIELDS", "1", "x")
+	time.Sleep(100 * time.Millisecond)
+
+	if br := execArgs(d, "HGET", "h", "a").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("expired field still readable: %q", br.Arg)
+	}
+	if n := replyInt(t, execArgs(d, "HLEN", "h")); n != 1 {
+		t.Fatalf("HLEN = %d", n)
+	}
+	// 字段全部过期后 key 被删除（由定期删除或惰性检查完成）
+	deadline := time.Now().Add(2 * time.Second)
+	for replyInt(t, execArgs(d, "EXISTS", "all")) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("hash with all fields expired should be deleted")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHashTTL_AOFUsesAbsoluteTime(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	execArgs(d, "HSET", "h", "short", "1", "long", "2", "gone", "3")
+	execArgs(d, "HPEXPIRE", "h", "300", "FIELDS", "1", "short")
+	execArgs(d, "HEXPIRE", "h", "100", "FIELDS", "1", "long")
+	execArgs(d, "HEXPIRE", "h", "0", "FIELDS", "1", "gone")
+	if err := d.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	if bytes.Contains(data, []byte("HEXPIRE\r")) || !bytes.Contains(data, []byte("HPEXPIREAT")) || !bytes.Contains(data, []byte("HDEL")) {
+		t.Fatalf("AOF should record HPEXPIREAT/HDEL: %q", data)
+	}
+	d.Close()
+
+	// 重启前等待 short 过期：重放绝对时间后它不应复活
+	time.Sleep(400 * time.Millisecond)
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if got := replyStrings(t, execArgs(d2, "HKEYS", "h")); len(got) != 1 || got[0] != "long" {
+		t.Fatalf("HKEYS after replay = %v", got)
+	}
+	if got := replyInts(t, execArgs(d2, "HTTL", "h", "FIELDS", "1", "long")); got[0] <= 0 || got[0] > 100 {
+		t.Fatalf("HTTL after replay = %v", got)
+	}
+}
+
+func TestHashTTL_SnapshotAndRewrite(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "node.aof")
+	rdbFile := filepath.Join(dir, "node.rdb")
+	cfg := StandaloneDBConfig{AofFilename: aofFile, RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes, Eviction: "lru"}
+
+	d := NewStandaloneDBWithConfig(cfg)
+	execArgs(d, "HSET", "h", "a", "1", "b", "2", "c", "3")
+	at := time.Now().Add(time.Hour).UnixMilli()
+	execArgs(d, "HPEXPIREAT", "h", strconv.FormatInt(at, 10), "FIELDS", "2", "a", "b")
+	if _, ok := execArgs(d, "SAVE").(*resp.StatusReply); !ok {
+		t.Fatalf("SAVE failed")
+	}
+	if _, ok := execArgs(d, "REWRITEAOF").(*resp.StatusReply); !ok {
+		t.Fatalf("REWRITEAOF failed")
+	}
+	d.Close()
+
+	check := func(name string, d *StandaloneDB) {
+		t.Helper()
+		if got := replyInts(t, execArgs(d, "HPEXPIRETIME", "h", "FIELDS", "3", "a", "b", "c")); !intsEqual(got, at, at, -1) {
+			t.Fatalf("%s: HPEXPIRETIME = %v", name, got)
+		}
+	}
+
+	// 只从 RDB 加载
+	fromRDB := NewStandaloneDBWithConfig(StandaloneDBConfig{RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	fromRDB.Load()
+	check("rdb", fromRDB)
+	fromRDB.Close()
+
+	// 只从重写后的 AOF 加载
+	fromAOF := NewStandaloneDB(aofFile)
+	fromAOF.Load()
+	check("aof", fromAOF)
+	fromAOF.Close()
+}
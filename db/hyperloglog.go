@@ -0,0 +1,141 @@
+// HyperLogLog 命令实现：PFADD / PFCOUNT / PFMERGE。
+// 说明：与 Redis 一样，HLL 就是一个带 "HYLL" 头的字符串（StringData），编解码见 pkg/hll；TYPE 返回 string，RDB/AOF 重写原样携带字节。
+// 关键点：所有修改都生成新的字节串再写回缓存（不原地修改，避免影响已返回的回包）；PFCOUNT 只更新头部基数缓存，不写 AOF。
+package db
+
+import (
+	"myredis/pkg/hll"
+	"myredis/resp"
+)
+
+// 本文件实现 HyperLogLog 相关命令：
+// - PFADD key [element ...]：有寄存器变化（或新建 key）返回 1，否则返回 0
+// - PFCOUNT key [key ...]：单 key 时使用/刷新头部基数缓存；多 key 时合并后估计（不缓存）
+// - PFMERGE destkey [sourcekey ...]：合并结果写入 destkey（destkey 已存在时也参与合并），结果为 dense 编码
+//
+// 说明：key 是字符串但不是合法 HLL 时返回 WRONGTYPE；sparse 内容损坏时返回 INVALIDOBJ。
+
+func hllErrReply(err error) resp.Reply {
+	if err == hll.ErrCorrupted {
+		return resp.MakeErrReply("INVALIDOBJ Corrupted HLL object detected")
+	}
+	return resp.MakeErrReply("WRONGTYPE Key is not a valid HyperLogLog string value.")
+}
+
+// getHLL 读取 HLL 字节串（含惰性过期与格式校验）。key 不存在返回 exists=false。
+func (db *StandaloneDB) getHLL(key string) ([]byte, bool, resp.Reply) {
+	str, exists, errReply := db.getString(key)
+	if errReply != nil || !exists {
+		return nil, exists, errReply
+	}
+	if !hll.IsValid(str) {
+		return nil, false, hllErrReply(hll.ErrInvalid)
+	}
+	return str, true, nil
+}
+
+// PFADD key [element ...]
+func (db *StandaloneDB) pfadd(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'pfadd' command")
+	}
+	key := string(args[1])
+	str, exists, errReply := db.getHLL(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		str = hll.New()
+	}
+	regs, err := hll.Decode(str)
+	if err != nil {
+		return hllErrReply(err)
+	}
+
+	changed := !exists
+	updated := false
+	for _, elem := range args[2:] {
+		if regs.Add(elem) {
+			updated = true
+		}
+	}
+	if updated {
+		str = hll.Encode(regs, hll.IsDense(str))
+		changed = true
+	}
+	if changed {
+		db.cache.Add(key, StringData(str), 0)
+		return resp.MakeIntReply(1)
+	}
+	return resp.MakeIntReply(0)
+}
+
+// PFCOUNT key [key ...]
+func (db *StandaloneDB) pfcount(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'pfcount' command")
+	}
+	if len(args) == 2 {
+		key := string(args[1])
+		str, exists, errReply := db.getHLL(key)
+		if errReply != nil {
+			return errReply
+		}
+		if !exists {
+			return resp.MakeIntReply(0)
+		}
+		if card, ok := hll.CachedCount(str); ok {
+			return resp.MakeIntReply(int64(card))
+		}
+		regs, err := hll.Decode(str)
+		if err != nil {
+			return hllErrReply(err)
+		}
+		card := regs.Count()
+		// 刷新基数缓存：写回新副本（缓存只是加速，不影响语义，因此不写 AOF）
+		updated := append([]byte(nil), str...)
+		hll.SetCachedCount(updated, card)
+		db.cache.Add(key, StringData(updated), 0)
+		return resp.MakeIntReply(int64(card))
+	}
+
+	merged, errReply := db.mergeHLLs(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeIntReply(int64(merged.Count()))
+}
+
+// mergeHLLs 合并多个 key 的寄存器；不存在的 key 视为空。
+func (db *StandaloneDB) mergeHLLs(keys [][]byte) (*hll.Regs, resp.Reply) {
+	merged := new(hll.Regs)
+	for _, k := range keys {
+		str, exists, errReply := db.getHLL(string(k))
+		if errReply != nil {
+			return nil, errReply
+		}
+		if !exists {
+			continue
+		}
+		regs, err := hll.Decode(str)
+		if err != nil {
+			return nil, hllErrReply(err)
+		}
+		merged.Merge(regs)
+	}
+	return merged, nil
+}
+
+// PFMERGE destkey [sourcekey ...]
+func (db *StandaloneDB) pfmerge(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'pfmerge' command")
+	}
+	// destkey 本身也参与合并（与 Redis 一致）
+	merged, errReply := db.mergeHLLs(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+	db.cache.Add(string(args[1]), StringData(hll.EncodeDense(merged)), 0)
+	return resp.OkReply
+}
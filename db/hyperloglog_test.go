@@ -0,0 +1,111 @@
+// HyperLogLog 命令测试：覆盖 PFADD 返回值、PFCOUNT 单/多 key、PFMERGE 合并与类型校验。
+// 目标：保证 HLL 以字符串形式保存（TYPE=string），非法字符串返回 WRONGTYPE，且内存占用远小于等价的 Set。
+// 覆盖：PFADD/PFMERGE 写入 AOF 并可重放；RDB 快照原样携带 HLL 字节。
+package db
+
+import (
+	"math"
+	"myredis/resp"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestHLL_AddCountMerge(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if n := replyInt(t, execArgs(d, "PFADD", "hll", "a", "b", "c", "d", "e", "f", "g")); n != 1 {
+		t.Fatalf("PFADD = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "PFADD", "hll", "a", "b")); n != 0 {
+		t.Fatalf("PFADD existing elements = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "PFCOUNT", "hll")); n != 7 {
+		t.Fatalf("PFCOUNT = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "PFADD", "empty")); n != 1 {
+		t.Fatalf("PFADD without elements creates key, got %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "PFCOUNT", "empty", "missing")); n != 0 {
+		t.Fatalf("PFCOUNT empty = %d", n)
+	}
+	if br := execArgs(d, "TYPE", "hll").(*resp.StatusReply); br.Status != "string" {
+		t.Fatalf("TYPE hll = %s", br.Status)
+	}
+
+	execArgs(d, "PFADD", "other", "f", "g", "h", "i")
+	if n := replyInt(t, execArgs(d, "PFCOUNT", "hll", "other")); n != 9 {
+		t.Fatalf("PFCOUNT union = %d", n)
+	}
+	if _, ok := execArgs(d, "PFMERGE", "dst", "hll", "other").(*resp.StatusReply); !ok {
+		t.Fatalf("PFMERGE should reply OK")
+	}
+	if n := replyInt(t, execArgs(d, "PFCOUNT", "dst")); n != 9 {
+		t.Fatalf("PFCOUNT after PFMERGE = %d", n)
+	}
+
+	execArgs(d, "SET", "str", "not a hll")
+	if er, ok := execArgs(d, "PFADD", "str", "x").(*resp.ErrorReply); !ok || er.Status != "WRONGTYPE Key is not a valid HyperLogLog string value." {
+		t.Fatalf("PFADD on plain string = %+v", er)
+	}
+	execArgs(d, "SADD", "set", "x")
+	if _, ok := execArgs(d, "PFCOUNT", "set").(*resp.ErrorReply); !ok {
+		t.Fatalf("PFCOUNT on set should be WRONGTYPE")
+	}
+}
+
+func TestHLL_LargeCardinalityIsCompact(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	const n = 50000
+	args := []string{"PFADD", "visitors"}
+	for i := 0; i < n; i++ {
+		args = append(args, "user:"+strconv.Itoa(i))
+		if len(args) == 1002 {
+			execArgs(d, args...)
+			args = args[:2]
+		}
+	}
+	got := float64(replyInt(t, execArgs(d, "PFCOUNT", "visitors")))
+	if math.Abs(got-n)/n > 0.03 {
+		t.Fatalf("PFCOUNT = %v, want about %d", got, n)
+	}
+	// dense 编码固定 12KB + 16 字节头
+	if size := replyInt(t, execArgs(d, "STRLEN", "visitors")); size != 12304 {
+		t.Fatalf("dense HLL size = %d", size)
+	}
+}
+
+func TestHLL_PersistenceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "node.aof")
+	rdbFile := filepath.Join(dir, "node.rdb")
+
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	for i := 0; i < 300; i++ {
+		execArgs(d, "PFADD", "a", "e"+strconv.Itoa(i))
+	}
+	execArgs(d, "PFADD", "b", "x", "y", "z")
+	execArgs(d, "PFMERGE", "c", "a", "b")
+	want := replyInt(t, execArgs(d, "PFCOUNT", "c"))
+	if _, ok := execArgs(d, "SAVE").(*resp.StatusReply); !ok {
+		t.Fatalf("SAVE failed")
+	}
+	d.Close()
+
+	fromAOF := NewStandaloneDB(aofFile)
+	fromAOF.Load()
+	if got := replyInt(t, execArgs(fromAOF, "PFCOUNT", "c")); got != want {
+		t.Fatalf("PFCOUNT after AOF replay = %d, want %d", got, want)
+	}
+	fromAOF.Close()
+
+	fromRDB := NewStandaloneDBWithConfig(StandaloneDBConfig{RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	fromRDB.Load()
+	defer fromRDB.Close()
+	if got := replyInt(t, execArgs(fromRDB, "PFCOUNT", "c")); got != want {
+		t.Fatalf("PFCOUNT after RDB load = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,314 @@
+// 键空间命令实现：EXISTS/TYPE/RENAME/RENAMENX/RANDOMKEY/DBSIZE/FLUSHDB/FLUSHALL/TOUCH/COPY。
+// 说明：只通过 EvictionCache 接口（Get/Peek/ForEach/Len/Remove）与 ttlMap 操作数据，不依赖具体淘汰策略。
+// 关键点：RENAME/COPY 需要携带 TTL；FLUSHALL/FLUSHDB 在 AOF 中只记录一条命令，而不是逐 key DEL。
+package db
+
+import (
+	"container/list"
+	"myredis/pkg/lru"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现键空间（keyspace）相关命令：
+// - 只读：EXISTS / TYPE / RANDOMKEY / DBSIZE（使用 Peek，不污染 LRU/LFU 统计）
+// - 访问：TOUCH（使用 Get，刷新 LRU/LFU 统计）
+// - 写：RENAME / RENAMENX / COPY / FLUSHDB / FLUSHALL
+
+// peekEntity 与 getEntity 类似，但使用 Peek：不更新 LRU/LFU 统计，仍执行惰性过期。
+func (db *StandaloneDB) peekEntity(key string) (DataEntity, bool) {
+	val, ok := db.cache.Peek(key)
+	if !ok {
+		return nil, false
+	}
+	if expireTime, ok := db.ttlMap[key]; ok && time.Now().After(expireTime) {
+		db.cache.Remove(key)
+		return nil, false
+	}
+	entity, ok := val.(DataEntity)
+	return entity, ok
+}
+
+// typeName 返回 TYPE 命令使用的类型名称。
+func typeName(entity DataEntity) string {
+	switch v := entity.(type) {
+	case StringData:
+		return "string"
+	case ListData:
+		return "list"
+	case HashData:
+		return "hash"
+	case SetData:
+		return "set"
+	case ZSetData:
+		return "zset"
+	case *StreamData:
+		return "stream"
+	case ModuleData:
+		return v.typ.Name
+	default:
+		return "none"
+	}
+}
+
+// copyEntity 深拷贝一个值（COPY 使用）：HashData/SetData/ListData/ZSetData/StreamData 都是引用语义，必须复制底层结构。
+func copyEntity(entity DataEntity) DataEntity {
+	switch v := entity.(type) {
+	case StringData:
+		return StringData(append([]byte(nil), v...))
+	case ListData:
+		l := list.New()
+		if v.L != nil {
+			for e := v.L.Front(); e != nil; e = e.Next() {
+				l.PushBack(append([]byte(nil), e.Value.([]byte)...))
+			}
+		}
+		return ListData{L: l}
+	case HashData:
+		h := newHashData()
+		for f, val := range v.fields {
+			h.fields[f] = append([]byte(nil), val...)
+		}
+		for f, at := range v.expires {
+			h.expires[f] = at
+		}
+		return h
+	case SetData:
+		s := newSetData(v.Card())
+		for m := range v.members {
+			s.add(m)
+		}
+		return s
+	case ZSetData:
+		zs := newZSetData()
+		for x := v.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+			zs.add(x.member, x.score)
+		}
+		return zs
+	case *StreamData:
+		return v.clone()
+	case ModuleData:
+		return copyModuleValue(v)
+	default:
+		return nil
+	}
+}
+
+// EXISTS key [key ...]：重复的 key 会被重复计数（与 Redis 一致）
+func (db *StandaloneDB) exists(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'exists' command")
+	}
+	count := 0
+	for _, k := range args[1:] {
+		if _, ok := db.peekEntity(string(k)); ok {
+			count++
+		}
+	}
+	return resp.MakeIntReply(int64(count))
+}
+
+// TOUCH key [key ...]：刷新访问统计，返回存在的 key 数量
+func (db *StandaloneDB) touch(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'touch' command")
+	}
+	count := 0
+	for _, k := range args[1:] {
+		if _, ok := db.getEntity(string(k)); ok {
+			count++
+		}
+	}
+	return resp.MakeIntReply(int64(count))
+}
+
+// TYPE key
+func (db *StandaloneDB) typeCmd(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'type' command")
+	}
+	entity, ok := db.peekEntity(string(args[1]))
+	if !ok {
+		return resp.MakeStatusReply("none")
+	}
+	return resp.MakeStatusReply(typeName(entity))
+}
+
+// RENAME key newkey
+func (db *StandaloneDB) rename(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'rename' command")
+	}
+	src, dst := string(args[1]), string(args[2])
+	entity, ok := db.peekEntity(src)
+	if !ok {
+		return resp.MakeErrReply("ERR no such key")
+	}
+	if src == dst {
+		return resp.OkReply
+	}
+	db.moveKey(src, dst, entity)
+	return resp.OkReply
+}
+
+// RENAMENX key newkey
+func (db *StandaloneDB) renamenx(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'renamenx' command")
+	}
+	src, dst := string(args[1]), string(args[2])
+	entity, ok := db.peekEntity(src)
+	if !ok {
+		return resp.MakeErrReply("ERR no such key")
+	}
+	if src == dst {
+		return resp.MakeIntReply(0)
+	}
+	if _, exists := db.peekEntity(dst); exists {
+		return resp.MakeIntReply(0)
+	}
+	db.moveKey(src, dst, entity)
+	return resp.MakeIntReply(1)
+}
+
+// moveKey 将 src 的值与 TTL 移动到 dst（覆盖 dst 原有的值与 TTL）。
+func (db *StandaloneDB) moveKey(src, dst string, entity DataEntity) {
+	expireAt, hasTTL := db.ttlMap[src]
+
+	// 先删除 dst（OnEvicted 会清理 dst 的 TTL），再删除 src（同样清理 src 的 TTL）
+	if _, ok := db.cache.Peek(dst); ok {
+		db.cache.Remove(dst)
+	}
+	db.cache.Remove(src)
+
+	db.cache.Add(dst, entity, 0)
+	if _, ok := db.cache.Peek(dst); ok && hasTTL {
+		db.ttlMap[dst] = expireAt
+	}
+	db.trackHashTTL(dst)
+	// RENAME 一个列表到有阻塞等待者的 key 上，等同于向该 key 写入了元素
+	db.signalKeyAsReady(dst)
+}
+
+// COPY source destination [DB destination-db] [REPLACE]
+func (db *StandaloneDB) copyCmd(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'copy' command")
+	}
+	src, dst := string(args[1]), string(args[2])
+
+	replace := false
+	for i := 3; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "replace":
+			replace = true
+		case "db":
+			// 本项目只有一个逻辑库（0）
+			if i+1 >= len(args) {
+				return resp.MakeErrReply("ERR syntax error")
+			}
+			n, err := strconv.Atoi(string(args[i+1]))
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n != 0 {
+				return resp.MakeErrReply("ERR DB index is out of range")
+			}
+			i++
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	if src == dst {
+		return resp.MakeErrReply("ERR source and destination objects are the same")
+	}
+	entity, ok := db.peekEntity(src)
+	if !ok {
+		return resp.MakeIntReply(0)
+	}
+	_, exists := db.peekEntity(dst)
+	if exists && !replace {
+		return resp.MakeIntReply(0)
+	}
+	// 模块类型的深拷贝可能失败（Encode/Decode 出错），需要在删除目标 key 之前完成
+	copied := copyEntity(entity)
+	if copied == nil {
+		return resp.MakeErrReply("ERR failed to copy the value of key '" + src + "'")
+	}
+	if exists {
+		db.cache.Remove(dst)
+	}
+
+	expireAt, hasTTL := db.ttlMap[src]
+	db.cache.Add(dst, copied, 0)
+	if _, ok := db.cache.Peek(dst); ok && hasTTL {
+		db.ttlMap[dst] = expireAt
+	}
+	db.trackHashTTL(dst)
+	db.signalKeyAsReady(dst)
+	return resp.MakeIntReply(1)
+}
+
+// RANDOMKEY：利用 ForEach（底层 map 遍历顺序随机）取第一个未过期的 key
+func (db *StandaloneDB) randomkey(args [][]byte) resp.Reply {
+	if len(args) != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'randomkey' command")
+	}
+	now := time.Now()
+	var found string
+	ok := false
+	db.cache.ForEach(func(key string, _ lru.Value) bool {
+		if expireAt, has := db.ttlMap[key]; has && now.After(expireAt) {
+			return true
+		}
+		found, ok = key, true
+		return false
+	})
+	if !ok {
+		return resp.NullBulkReply
+	}
+	return resp.MakeBulkReply([]byte(found))
+}
+
+// DBSIZE
+func (db *StandaloneDB) dbsize(args [][]byte) resp.Reply {
+	if len(args) != 1 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'dbsize' command")
+	}
+	return resp.MakeIntReply(int64(db.cache.Len()))
+}
+
+// FLUSHDB [ASYNC|SYNC] / FLUSHALL [ASYNC|SYNC]
+// 本项目只有一个逻辑库，两者语义相同；ASYNC 也按同步方式执行。
+func (db *StandaloneDB) flush(args [][]byte) resp.Reply {
+	if len(args) > 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + strings.ToLower(string(args[0])) + "' command")
+	}
+	if len(args) == 2 {
+		mode := strings.ToLower(string(args[1]))
+		if mode != "async" && mode != "sync" {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	db.flushAll()
+	return resp.OkReply
+}
+
+// flushAll 清空所有 key 与 TTL。
+// 注意：删除原因是显式删除（RemoveReasonDeleted），不会写入 evictedKeys，因此 AOF 只记录一条 FLUSHALL/FLUSHDB。
+func (db *StandaloneDB) flushAll() {
+	keys := make([]string, 0, db.cache.Len())
+	db.cache.ForEach(func(key string, _ lru.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	// 先清空 ttlMap：已过期但尚未删除的 key 不应在 onEvicted 中被当作过期（不发出 expired 事件）
+	db.ttlMap = make(map[string]time.Time)
+	db.hashTTLKeys = make(map[string]struct{})
+	for _, k := range keys {
+		db.cache.Remove(k)
+	}
+}
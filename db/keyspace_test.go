@@ -0,0 +1,121 @@
+// 键空间命令测试：覆盖 EXISTS/TYPE/RENAME/RENAMENX/COPY/RANDOMKEY/DBSIZE/FLUSHALL/TOUCH。
+// 目标：RENAME/COPY 携带 TTL、COPY 为深拷贝；FLUSHALL 在 AOF 中只产生一条记录。
+// 覆盖：类型名称、错误文案、TTL 迁移、AOF 紧凑记录 + 重启回放。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyspace_Commands(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "s", "v")
+	execArgs(d, "RPUSH", "l", "a", "b")
+	execArgs(d, "HSET", "h", "f", "v")
+	execArgs(d, "SADD", "set", "m")
+	execArgs(d, "ZADD", "z", "1", "m")
+
+	for key, want := range map[string]string{"s": "string", "l": "list", "h": "hash", "set": "set", "z": "zset", "nope": "none"} {
+		if st := execArgs(d, "TYPE", key).(*resp.StatusReply); st.Status != want {
+			t.Fatalf("TYPE %s = %s, want %s", key, st.Status, want)
+		}
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "s", "l", "nope", "s")); n != 3 {
+		t.Fatalf("EXISTS = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "TOUCH", "s", "nope")); n != 1 {
+		t.Fatalf("TOUCH = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "DBSIZE")); n != 5 {
+		t.Fatalf("DBSIZE = %d", n)
+	}
+	if br := execArgs(d, "RANDOMKEY").(*resp.BulkReply); br.Arg == nil {
+		t.Fatalf("RANDOMKEY returned nil on non-empty db")
+	}
+
+	// RENAME 携带 TTL
+	execArgs(d, "EXPIRE", "s", "100")
+	if _, ok := execArgs(d, "RENAME", "s", "s2").(*resp.StatusReply); !ok {
+		t.Fatalf("RENAME failed")
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "s2")); ttl <= 0 {
+		t.Fatalf("RENAME should carry TTL, got %d", ttl)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "s")); ttl != -2 {
+		t.Fatalf("source key should be gone, TTL = %d", ttl)
+	}
+	if er, ok := execArgs(d, "RENAME", "nope", "x").(*resp.ErrorReply); !ok || er.Status != "ERR no such key" {
+		t.Fatalf("RENAME missing key should fail, got %+v", er)
+	}
+	if n := replyInt(t, execArgs(d, "RENAMENX", "s2", "l")); n != 0 {
+		t.Fatalf("RENAMENX onto existing key = %d", n)
+	}
+
+	// COPY 为深拷贝，并携带 TTL
+	execArgs(d, "EXPIRE", "h", "100")
+	if n := replyInt(t, execArgs(d, "COPY", "h", "h2")); n != 1 {
+		t.Fatalf("COPY = %d", n)
+	}
+	execArgs(d, "HSET", "h2", "f", "changed")
+	if br := execArgs(d, "HGET", "h", "f").(*resp.BulkReply); string(br.Arg) != "v" {
+		t.Fatalf("COPY should deep copy, source HGET = %q", br.Arg)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "h2")); ttl <= 0 {
+		t.Fatalf("COPY should carry TTL, got %d", ttl)
+	}
+	if n := replyInt(t, execArgs(d, "COPY", "l", "h2")); n != 0 {
+		t.Fatalf("COPY without REPLACE onto existing = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "COPY", "l", "h2", "REPLACE")); n != 1 {
+		t.Fatalf("COPY REPLACE = %d", n)
+	}
+	if st := execArgs(d, "TYPE", "h2").(*resp.StatusReply); st.Status != "list" {
+		t.Fatalf("TYPE h2 after COPY REPLACE = %s", st.Status)
+	}
+
+	if _, ok := execArgs(d, "FLUSHALL").(*resp.StatusReply); !ok {
+		t.Fatalf("FLUSHALL failed")
+	}
+	if n := replyInt(t, execArgs(d, "DBSIZE")); n != 0 {
+		t.Fatalf("DBSIZE after FLUSHALL = %d", n)
+	}
+	if br := execArgs(d, "RANDOMKEY").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("RANDOMKEY on empty db = %q", br.Arg)
+	}
+}
+
+func TestKeyspace_FlushAllCompactAOF(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+
+	db1 := NewStandaloneDB(filename)
+	execArgs(db1, "MSET", "a", "1", "b", "2", "c", "3")
+	execArgs(db1, "FLUSHALL")
+	execArgs(db1, "SET", "after", "1")
+	if err := db1.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	if bytes.Contains(data, []byte("\r\nDEL\r\n")) {
+		t.Fatalf("FLUSHALL should not produce per-key DEL, got %q", data)
+	}
+	if bytes.Count(data, []byte("FLUSHALL")) != 1 {
+		t.Fatalf("expected one FLUSHALL record, got %q", data)
+	}
+	db1.Close()
+
+	db2 := NewStandaloneDB(filename)
+	db2.Load()
+	defer db2.Close()
+	if n := replyInt(t, execArgs(db2, "DBSIZE")); n != 1 {
+		t.Fatalf("DBSIZE after replay = %d", n)
+	}
+}
@@ -1,16 +1,21 @@
-// List 命令实现：LPUSH/RPUSH/LPOP/RPOP/LRANGE/LLEN 等。
+// List 命令实现：LPUSH/RPUSH/LPOP/RPOP/LRANGE/LLEN/LINDEX/LSET/LINSERT/LREM/LTRIM/LPOS/LMOVE 等。
 // 说明：在淘汰/删除 key 时需要触发缓存删除回调，确保 TTL 与 AOF 状态一致。
 // 关键点：当列表为空导致 key 被移除时，需要当作“显式删除”处理以保持一致性。
 package db
 
 import (
+	"bytes"
 	"container/list"
+	"math"
 	"myredis/resp"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// 本文件实现 List 相关命令：LPUSH / RPUSH / LPOP / RPOP / LLEN / LRANGE
+// 本文件实现 List 相关命令：
+// - 写：LPUSH / RPUSH / LPUSHX / RPUSHX / LPOP / RPOP（可带 count）/ LSET / LINSERT / LREM / LTRIM / LMOVE / RPOPLPUSH
+// - 读：LLEN / LRANGE / LINDEX / LPOS
 // 说明：
 // - List 的具体值存为 ListData{*list.List}
 // - TTL 由 db.ttlMap 管理；过期时只做内存删除，不额外写入 AOF（AOF 使用 PEXPIREAT 语义保证一致性）
@@ -63,6 +68,7 @@ func (db *StandaloneDB) lpush(args [][]byte) resp.Reply {
 	// Update Cache
 	db.cache.Add(key, ListData{L: l}, 0)
 	// LPUSH does NOT reset TTL in Redis. Only SET does.
+	db.signalKeyAsReady(key)
 
 	return resp.MakeIntReply(int64(l.Len()))
 }
@@ -83,92 +89,62 @@ func (db *StandaloneDB) rpush(args [][]byte) resp.Reply {
 		l.PushBack(v)
 	}
 	db.cache.Add(key, ListData{L: l}, 0)
+	db.signalKeyAsReady(key)
 	return resp.MakeIntReply(int64(l.Len()))
 }
 
-// LPOP key
-func (db *StandaloneDB) lpop(args [][]byte) resp.Reply {
-	if len(args) != 2 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'lpop' command")
+// LPOP key [count] / RPOP key [count]
+// 不带 count 返回单个 bulk；带 count 返回数组（key 不存在时为 null 数组）。
+func (db *StandaloneDB) listPop(args [][]byte, left bool) resp.Reply {
+	name := "rpop"
+	if left {
+		name = "lpop"
 	}
-	key := string(args[1])
-
-	val, ok := db.cache.Get(key)
-	if !ok {
-		return resp.NullBulkReply
+	if len(args) != 2 && len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
 	}
-
-	// Check TTL
-	if expireTime, ok := db.ttlMap[key]; ok {
-		if time.Now().After(expireTime) {
-			db.cache.Remove(key)
-			return resp.NullBulkReply
+	key := string(args[1])
+	withCount := len(args) == 3
+	count := int64(1)
+	if withCount {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is out of range, must be positive")
 		}
+		if n < 0 {
+			return resp.MakeErrReply("ERR value is out of range, must be positive")
+		}
+		count = n
 	}
 
-	listData, ok := val.(ListData)
-	if !ok {
-		return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-	}
-	l := listData.L
-
-	if l.Len() == 0 {
-		return resp.NullBulkReply
-	}
-
-	front := l.Front()
-	l.Remove(front)
-
-	// Update or Remove if empty
-	if l.Len() == 0 {
-		db.cache.Remove(key) // Removing key also removes TTL
-	} else {
-		// Technically value changed (size changed), so update LFU weight?
-		// cache.Add updates value and recalculates weight.
-		db.cache.Add(key, ListData{L: l}, 0)
-	}
-
-	return resp.MakeBulkReply(front.Value.([]byte))
-}
-
-func (db *StandaloneDB) rpop(args [][]byte) resp.Reply {
-	if len(args) != 2 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'rpop' command")
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
 	}
-	key := string(args[1])
-
-	val, ok := db.cache.Get(key)
-	if !ok {
+	if !exists {
+		if withCount {
+			return resp.MakeMultiBulkReply(nil)
+		}
 		return resp.NullBulkReply
 	}
 
-	if expireTime, ok := db.ttlMap[key]; ok {
-		if time.Now().After(expireTime) {
-			db.cache.Remove(key)
-			return resp.NullBulkReply
+	popped := make([][]byte, 0)
+	for i := int64(0); i < count && l.Len() > 0; i++ {
+		var e *list.Element
+		if left {
+			e = l.Front()
+		} else {
+			e = l.Back()
 		}
+		l.Remove(e)
+		popped = append(popped, e.Value.([]byte))
 	}
+	db.storeList(key, l)
 
-	listData, ok := val.(ListData)
-	if !ok {
-		return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-	}
-	l := listData.L
-
-	if l.Len() == 0 {
-		return resp.NullBulkReply
-	}
-
-	back := l.Back()
-	l.Remove(back)
-
-	if l.Len() == 0 {
-		db.cache.Remove(key)
-	} else {
-		db.cache.Add(key, ListData{L: l}, 0)
+	if withCount {
+		return resp.MakeMultiBulkReply(popped)
 	}
-
-	return resp.MakeBulkReply(back.Value.([]byte))
+	return resp.MakeBulkReply(popped[0])
 }
 
 func (db *StandaloneDB) llen(args [][]byte) resp.Reply {
@@ -256,3 +232,418 @@ func (db *StandaloneDB) lrange(args [][]byte) resp.Reply {
 	}
 	return resp.MakeMultiBulkReply(slice)
 }
+
+// getList 读取 List（含惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+func (db *StandaloneDB) getList(key string) (*list.List, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return nil, false, nil
+	}
+	listData, ok := entity.(ListData)
+	if !ok || listData.L == nil {
+		return nil, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	return listData.L, true, nil
+}
+
+// storeList 写回 List：为空时删除 key（同时清理 TTL），否则刷新缓存中的大小统计。
+func (db *StandaloneDB) storeList(key string, l *list.List) {
+	if l.Len() == 0 {
+		if _, ok := db.cache.Peek(key); ok {
+			db.cache.Remove(key)
+		}
+		return
+	}
+	db.cache.Add(key, ListData{L: l}, 0)
+}
+
+// listIndex 把可能为负数的下标转换为 0-based 下标；越界返回 ok=false。
+func listIndex(index int64, size int) (int, bool) {
+	if index < 0 {
+		index += int64(size)
+	}
+	if index < 0 || index >= int64(size) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// listElementAt 返回第 index 个元素（index 已规范化），从离得更近的一端开始走。
+func listElementAt(l *list.List, index int) *list.Element {
+	if index < l.Len()/2 {
+		e := l.Front()
+		for i := 0; i < index; i++ {
+			e = e.Next()
+		}
+		return e
+	}
+	e := l.Back()
+	for i := l.Len() - 1; i > index; i-- {
+		e = e.Prev()
+	}
+	return e
+}
+
+// LPUSHX key element [element ...] / RPUSHX key element [element ...]
+// 只在 key 已存在时写入，返回写入后的长度（key 不存在返回 0）。
+func (db *StandaloneDB) pushx(args [][]byte, left bool) resp.Reply {
+	if len(args) < 3 {
+		name := "rpushx"
+		if left {
+			name = "lpushx"
+		}
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	key := string(args[1])
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+	for _, v := range args[2:] {
+		if left {
+			l.PushFront(v)
+		} else {
+			l.PushBack(v)
+		}
+	}
+	db.storeList(key, l)
+	db.signalKeyAsReady(key)
+	return resp.MakeIntReply(int64(l.Len()))
+}
+
+// LINDEX key index
+func (db *StandaloneDB) lindex(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'lindex' command")
+	}
+	index, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	l, exists, errReply := db.getList(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	i, ok := listIndex(index, l.Len())
+	if !ok {
+		return resp.NullBulkReply
+	}
+	return resp.MakeBulkReply(listElementAt(l, i).Value.([]byte))
+}
+
+// LSET key index element
+func (db *StandaloneDB) lset(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'lset' command")
+	}
+	key := string(args[1])
+	index, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeErrReply("ERR no such key")
+	}
+	i, ok := listIndex(index, l.Len())
+	if !ok {
+		return resp.MakeErrReply("ERR index out of range")
+	}
+	listElementAt(l, i).Value = args[3]
+	db.storeList(key, l)
+	return resp.OkReply
+}
+
+// LINSERT key BEFORE|AFTER pivot element
+// 返回插入后的长度；pivot 不存在返回 -1；key 不存在返回 0。
+func (db *StandaloneDB) linsert(args [][]byte) resp.Reply {
+	if len(args) != 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'linsert' command")
+	}
+	key := string(args[1])
+	var before bool
+	switch strings.ToLower(string(args[2])) {
+	case "before":
+		before = true
+	case "after":
+	default:
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+	for e := l.Front(); e != nil; e = e.Next() {
+		if !bytes.Equal(e.Value.([]byte), args[3]) {
+			continue
+		}
+		if before {
+			l.InsertBefore(args[4], e)
+		} else {
+			l.InsertAfter(args[4], e)
+		}
+		db.storeList(key, l)
+		return resp.MakeIntReply(int64(l.Len()))
+	}
+	return resp.MakeIntReply(-1)
+}
+
+// LREM key count element
+// count > 0 从头部开始删除 count 个；count < 0 从尾部开始删除 |count| 个；count = 0 删除全部。
+func (db *StandaloneDB) lrem(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'lrem' command")
+	}
+	key := string(args[1])
+	count, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+
+	removed := int64(0)
+	fromTail := count < 0
+	if fromTail {
+		count = -count
+	}
+	e := l.Front()
+	if fromTail {
+		e = l.Back()
+	}
+	for e != nil && (count == 0 || removed < count) {
+		next := e.Next()
+		if fromTail {
+			next = e.Prev()
+		}
+		if bytes.Equal(e.Value.([]byte), args[3]) {
+			l.Remove(e)
+			removed++
+		}
+		e = next
+	}
+	if removed > 0 {
+		db.storeList(key, l)
+	}
+	return resp.MakeIntReply(removed)
+}
+
+// LTRIM key start stop
+// 只保留 [start, stop] 区间（支持负数下标）；区间为空时删除 key。
+func (db *StandaloneDB) ltrim(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'ltrim' command")
+	}
+	key := string(args[1])
+	start, err1 := strconv.ParseInt(string(args[2]), 10, 64)
+	stop, err2 := strconv.ParseInt(string(args[3]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	l, exists, errReply := db.getList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.OkReply
+	}
+
+	start, stop, ok := normalizeRankRange(start, stop, int64(l.Len()))
+	if !ok {
+		l.Init()
+	} else {
+		for n := start; n > 0; n-- {
+			l.Remove(l.Front())
+		}
+		for n := int64(l.Len()) - (stop - start + 1); n > 0; n-- {
+			l.Remove(l.Back())
+		}
+	}
+	db.storeList(key, l)
+	return resp.OkReply
+}
+
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+// 不带 COUNT 返回第一个匹配的下标（或 nil）；带 COUNT 返回下标数组（COUNT 0 表示全部）。
+func (db *StandaloneDB) lpos(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'lpos' command")
+	}
+	rank, count, maxLen := int64(1), int64(1), int64(0)
+	withCount := false
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		switch strings.ToLower(string(args[i])) {
+		case "rank":
+			if n == 0 {
+				return resp.MakeErrReply("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			if n == math.MinInt64 {
+				return resp.MakeErrReply("ERR value is out of range")
+			}
+			rank = n
+		case "count":
+			if n < 0 {
+				return resp.MakeErrReply("ERR COUNT can't be negative")
+			}
+			count, withCount = n, true
+		case "maxlen":
+			if n < 0 {
+				return resp.MakeErrReply("ERR MAXLEN can't be negative")
+			}
+			maxLen = n
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	l, exists, errReply := db.getList(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		if withCount {
+			return resp.MakeMultiRawReply([]resp.Reply{})
+		}
+		return resp.NullBulkReply
+	}
+
+	fromTail := rank < 0
+	skip := rank - 1
+	if fromTail {
+		skip = -rank - 1
+	}
+	size := l.Len()
+	var positions []int
+	e, idx := l.Front(), 0
+	if fromTail {
+		e, idx = l.Back(), size-1
+	}
+	for scanned := int64(0); e != nil && (maxLen == 0 || scanned < maxLen); scanned++ {
+		if bytes.Equal(e.Value.([]byte), args[2]) {
+			if skip > 0 {
+				skip--
+			} else {
+				positions = append(positions, idx)
+				if count != 0 && int64(len(positions)) >= count {
+					break
+				}
+			}
+		}
+		if fromTail {
+			e, idx = e.Prev(), idx-1
+		} else {
+			e, idx = e.Next(), idx+1
+		}
+	}
+
+	if withCount {
+		res := make([]resp.Reply, 0, len(positions))
+		for _, p := range positions {
+			res = append(res, resp.MakeIntReply(int64(p)))
+		}
+		return resp.MakeMultiRawReply(res)
+	}
+	if len(positions) == 0 {
+		return resp.NullBulkReply
+	}
+	return resp.MakeIntReply(int64(positions[0]))
+}
+
+// parseListEnd 解析 LEFT|RIGHT。
+func parseListEnd(arg []byte) (left bool, ok bool) {
+	switch strings.ToLower(string(arg)) {
+	case "left":
+		return true, true
+	case "right":
+		return false, true
+	}
+	return false, false
+}
+
+// LMOVE source destination LEFT|RIGHT LEFT|RIGHT
+func (db *StandaloneDB) lmove(args [][]byte) resp.Reply {
+	if len(args) != 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'lmove' command")
+	}
+	fromLeft, ok1 := parseListEnd(args[3])
+	toLeft, ok2 := parseListEnd(args[4])
+	if !ok1 || !ok2 {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	return db.listMove(string(args[1]), string(args[2]), fromLeft, toLeft)
+}
+
+// RPOPLPUSH source destination（等价于 LMOVE source destination RIGHT LEFT）
+func (db *StandaloneDB) rpoplpush(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'rpoplpush' command")
+	}
+	return db.listMove(string(args[1]), string(args[2]), false, true)
+}
+
+// listMove 从 src 的一端弹出元素并推入 dst 的一端（src == dst 时为旋转）。
+// 先校验 dst 类型再修改 src，保证 WRONGTYPE 时不产生部分写入。
+func (db *StandaloneDB) listMove(src, dst string, fromLeft, toLeft bool) resp.Reply {
+	srcList, exists, errReply := db.getList(src)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	dstList := srcList
+	if dst != src {
+		var dstExists bool
+		dstList, dstExists, errReply = db.getList(dst)
+		if errReply != nil {
+			return errReply
+		}
+		if !dstExists {
+			dstList = list.New()
+		}
+	}
+
+	var e *list.Element
+	if fromLeft {
+		e = srcList.Front()
+	} else {
+		e = srcList.Back()
+	}
+	val := srcList.Remove(e).([]byte)
+	if toLeft {
+		dstList.PushFront(val)
+	} else {
+		dstList.PushBack(val)
+	}
+
+	db.storeList(src, srcList)
+	if dst != src {
+		db.storeList(dst, dstList)
+	}
+	db.signalKeyAsReady(dst)
+	return resp.MakeBulkReply(val)
+}
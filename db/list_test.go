@@ -0,0 +1,123 @@
+// List 扩展命令测试：覆盖负数下标语义、LREM/LTRIM/LPOS 的方向与计数、LMOVE 队列语义与 AOF 重放。
+// 目标：保证 capped log（LTRIM）与可靠队列（LMOVE）能在服务端完成，且重启后状态一致。
+// 覆盖：LINDEX/LSET/LINSERT/LREM/LTRIM/LPOS/LMOVE/RPOPLPUSH/LPUSHX/RPUSHX、带 count 的 LPOP/RPOP。
+package db
+
+import (
+	"myredis/resp"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestList_IndexInsertRemoveTrim(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "RPUSH", "l", "a", "b", "c", "b", "d", "b")
+	if br := execArgs(d, "LINDEX", "l", "-1").(*resp.BulkReply); string(br.Arg) != "b" {
+		t.Fatalf("LINDEX -1 = %q", br.Arg)
+	}
+	if br := execArgs(d, "LINDEX", "l", "10").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("LINDEX out of range = %q", br.Arg)
+	}
+	execArgs(d, "LSET", "l", "-2", "D")
+	if er, ok := execArgs(d, "LSET", "l", "6", "x").(*resp.ErrorReply); !ok || er.Status != "ERR index out of range" {
+		t.Fatalf("LSET out of range = %+v", er)
+	}
+	if er, ok := execArgs(d, "LSET", "missing", "0", "x").(*resp.ErrorReply); !ok || er.Status != "ERR no such key" {
+		t.Fatalf("LSET missing key = %+v", er)
+	}
+
+	if n := replyInt(t, execArgs(d, "LINSERT", "l", "BEFORE", "c", "x")); n != 7 {
+		t.Fatalf("LINSERT = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "LINSERT", "l", "AFTER", "nope", "x")); n != -1 {
+		t.Fatalf("LINSERT missing pivot = %d", n)
+	}
+	// a b x c b D b -> LREM -2 b 从尾部删两个
+	if n := replyInt(t, execArgs(d, "LREM", "l", "-2", "b")); n != 2 {
+		t.Fatalf("LREM -2 = %d", n)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "LRANGE", "l", "0", "-1")), ","); got != "a,b,x,c,D" {
+		t.Fatalf("after LREM = %s", got)
+	}
+
+	execArgs(d, "LTRIM", "l", "1", "-2")
+	if got := strings.Join(replyStrings(t, execArgs(d, "LRANGE", "l", "0", "-1")), ","); got != "b,x,c" {
+		t.Fatalf("after LTRIM = %s", got)
+	}
+	// 区间为空时删除 key
+	execArgs(d, "LTRIM", "l", "5", "10")
+	if n := replyInt(t, execArgs(d, "EXISTS", "l")); n != 0 {
+		t.Fatalf("LTRIM empty range should delete key")
+	}
+
+	if n := replyInt(t, execArgs(d, "LPUSHX", "l", "a")); n != 0 {
+		t.Fatalf("LPUSHX on missing key = %d", n)
+	}
+	execArgs(d, "RPUSH", "l", "a")
+	if n := replyInt(t, execArgs(d, "RPUSHX", "l", "b", "c")); n != 3 {
+		t.Fatalf("RPUSHX = %d", n)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "LPOP", "l", "2")), ","); got != "a,b" {
+		t.Fatalf("LPOP count = %s", got)
+	}
+	if mb := execArgs(d, "RPOP", "missing", "2").(*resp.MultiBulkReply); mb.Args != nil {
+		t.Fatalf("RPOP count on missing key should be null array")
+	}
+}
+
+func TestList_LPos(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "RPUSH", "l", "a", "b", "c", "1", "2", "3", "c", "c")
+	if n := replyInt(t, execArgs(d, "LPOS", "l", "c")); n != 2 {
+		t.Fatalf("LPOS = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "LPOS", "l", "c", "RANK", "-1")); n != 7 {
+		t.Fatalf("LPOS RANK -1 = %d", n)
+	}
+	raw := execArgs(d, "LPOS", "l", "c", "RANK", "2", "COUNT", "0").(*resp.MultiRawReply)
+	if len(raw.Replies) != 2 || raw.Replies[0].(*resp.IntReply).Code != 6 || raw.Replies[1].(*resp.IntReply).Code != 7 {
+		t.Fatalf("LPOS RANK 2 COUNT 0 = %+v", raw.Replies)
+	}
+	if br, ok := execArgs(d, "LPOS", "l", "c", "MAXLEN", "2").(*resp.BulkReply); !ok || br.Arg != nil {
+		t.Fatalf("LPOS MAXLEN 2 should not find c")
+	}
+	if _, ok := execArgs(d, "LPOS", "l", "c", "RANK", "0").(*resp.ErrorReply); !ok {
+		t.Fatalf("LPOS RANK 0 should be rejected")
+	}
+}
+
+func TestList_MoveAndReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	db1 := NewStandaloneDB(filename)
+
+	execArgs(db1, "RPUSH", "jobs", "j1", "j2", "j3")
+	if br := execArgs(db1, "LMOVE", "jobs", "processing", "LEFT", "RIGHT").(*resp.BulkReply); string(br.Arg) != "j1" {
+		t.Fatalf("LMOVE = %q", br.Arg)
+	}
+	if br := execArgs(db1, "RPOPLPUSH", "jobs", "processing").(*resp.BulkReply); string(br.Arg) != "j3" {
+		t.Fatalf("RPOPLPUSH = %q", br.Arg)
+	}
+	// 同一个 key：旋转
+	execArgs(db1, "LMOVE", "processing", "processing", "LEFT", "RIGHT")
+	execArgs(db1, "SET", "str", "v")
+	if _, ok := execArgs(db1, "LMOVE", "jobs", "str", "LEFT", "LEFT").(*resp.ErrorReply); !ok {
+		t.Fatalf("LMOVE to string should be WRONGTYPE")
+	}
+	execArgs(db1, "LTRIM", "jobs", "0", "0")
+	db1.Close()
+
+	db2 := NewStandaloneDB(filename)
+	db2.Load()
+	defer db2.Close()
+	if got := strings.Join(replyStrings(t, execArgs(db2, "LRANGE", "processing", "0", "-1")), ","); got != "j1,j3" {
+		t.Fatalf("processing after replay = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(db2, "LRANGE", "jobs", "0", "-1")), ","); got != "j2" {
+		t.Fatalf("jobs after replay = %s", got)
+	}
+}
@@ -0,0 +1,123 @@
+// 模块命令与自定义类型：execInternal 在内置命令之外查询 myredis/module 的注册表。
+// 说明：模块 Handler 在 Actor 线程内执行，通过 moduleKeyspace 访问数据；自定义类型的值以 ModuleData 包装后存入缓存。
+// 关键点：所有写入都经过 cache.Add/Remove，因此 WATCH 版本号、容量淘汰、TTL 清理与内置命令完全一致。
+package db
+
+import (
+	"myredis/module"
+	"myredis/resp"
+	"time"
+)
+
+// 本文件实现模块支持：
+// - execModule：参数个数检查 + 调用 Handler（未注册的命令返回 unknown command）
+// - moduleKeyspace：module.Keyspace 的实现
+// - ModuleData：自定义类型的值（TYPE 返回类型名；COPY 使用 Type.Copy 或 Encode/Decode 深拷贝；RDB 使用 Encode/Decode）
+//
+// AOF：带 module.FlagWrite 的命令成功后原样记录（见 isWriteCommand），重放时同样经过 execModule。
+
+// ModuleData 为模块自定义类型的值。
+type ModuleData struct {
+	typ *module.Type
+	v   module.Value
+}
+
+func (d ModuleData) Len() int {
+	return d.v.Len()
+}
+
+// execModule 执行模块命令；commandName 为小写命令名。
+func (db *StandaloneDB) execModule(commandName string, cmd [][]byte) resp.Reply {
+	mc, ok := module.Lookup(commandName)
+	if !ok {
+		return resp.MakeErrReply("ERR unknown command '" + commandName + "'")
+	}
+	if !mc.CheckArity(len(cmd)) {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + commandName + "' command")
+	}
+	res := mc.Handler(moduleKeyspace{db: db}, cmd)
+	if res == nil {
+		return resp.NullBulkReply
+	}
+	return res
+}
+
+// copyModuleValue 深拷贝自定义类型的值；编码失败时返回 nil。
+func copyModuleValue(d ModuleData) DataEntity {
+	if d.typ.Copy != nil {
+		return ModuleData{typ: d.typ, v: d.typ.Copy(d.v)}
+	}
+	data, err := d.typ.Encode(d.v)
+	if err != nil {
+		return nil
+	}
+	v, err := d.typ.Decode(data)
+	if err != nil {
+		return nil
+	}
+	return ModuleData{typ: d.typ, v: v}
+}
+
+// moduleKeyspace 为模块 Handler 提供的数据访问接口（只在 Actor 线程内使用）。
+type moduleKeyspace struct {
+	db *StandaloneDB
+}
+
+func (ks moduleKeyspace) GetString(key string) ([]byte, bool, error) {
+	str, ok, errReply := ks.db.getString(key)
+	if errReply != nil {
+		return nil, false, module.ErrWrongType
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), str...), true, nil
+}
+
+func (ks moduleKeyspace) SetString(key string, val []byte) {
+	ks.db.cache.Add(key, StringData(append([]byte(nil), val...)), 0)
+	delete(ks.db.ttlMap, key)
+}
+
+func (ks moduleKeyspace) Get(key string, t *module.Type) (module.Value, bool, error) {
+	entity, ok := ks.db.getEntity(key)
+	if !ok {
+		return nil, false, nil
+	}
+	d, ok := entity.(ModuleData)
+	if !ok || d.typ != t {
+		return nil, false, module.ErrWrongType
+	}
+	return d.v, true, nil
+}
+
+func (ks moduleKeyspace) Put(key string, t *module.Type, v module.Value) {
+	// 先做惰性过期，避免已过期 key 的 TTL 被新值继承；
+	// v 可能是原地修改后的同一个指针，缓存按上次记录的大小计算差值（见 lru.Cache.Add）
+	ks.db.getEntity(key)
+	ks.db.cache.Add(key, ModuleData{typ: t, v: v}, 0)
+}
+
+func (ks moduleKeyspace) Delete(key string) bool {
+	if _, ok := ks.db.peekEntity(key); !ok {
+		return false
+	}
+	ks.db.cache.Remove(key)
+	return true
+}
+
+func (ks moduleKeyspace) Exists(key string) bool {
+	_, ok := ks.db.peekEntity(key)
+	return ok
+}
+
+func (ks moduleKeyspace) TTL(key string) (time.Duration, bool) {
+	if _, ok := ks.db.peekEntity(key); !ok {
+		return 0, false
+	}
+	expireAt, ok := ks.db.ttlMap[key]
+	if !ok {
+		return 0, false
+	}
+	return time.Until(expireAt), true
+}
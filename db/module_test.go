@@ -0,0 +1,266 @@
+// 模块测试：用一个自定义计数器类型验证模块命令的分发、类型检查与持久化钩子。
+// 覆盖：参数个数检查、TYPE/COPY/WRONGTYPE、WATCH 版本号、禁止脚本调用、原地修改的内存统计、内置命令名表、AOF 只记录写命令、RDB 与 AOF 重写的往返。
+package db
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"myredis/module"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testCounter struct {
+	n int64
+}
+
+func (c *testCounter) Len() int { return 8 }
+
+var testCounterType = &module.Type{
+	Name: "testcounter",
+	Encode: func(v module.Value) ([]byte, error) {
+		return []byte(strconv.FormatInt(v.(*testCounter).n, 10)), nil
+	},
+	Decode: func(data []byte) (module.Value, error) {
+		n, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &testCounter{n: n}, nil
+	},
+	AofRewrite: func(key []byte, v module.Value) [][][]byte {
+		return [][][]byte{{[]byte("CNT.INCRBY"), key, []byte(strconv.FormatInt(v.(*testCounter).n, 10))}}
+	},
+}
+
+// testBlob 为原地追加的自定义类型，用于验证内存统计。
+type testBlob struct {
+	data []byte
+}
+
+func (b *testBlob) Len() int { return len(b.data) }
+
+var testBlobType = &module.Type{
+	Name:   "testblob",
+	Encode: func(v module.Value) ([]byte, error) { return append([]byte(nil), v.(*testBlob).data...), nil },
+	Decode: func(data []byte) (module.Value, error) { return &testBlob{data: data}, nil },
+}
+
+func init() {
+	mustRegister := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+	mustRegister(module.RegisterType(testCounterType))
+	mustRegister(module.RegisterType(testBlobType))
+	// BLOB.APPEND key data：原地追加后以同一个指针再次 Put
+	mustRegister(module.Register(&module.Command{
+		Name: "blob.append", Arity: 3, Flags: module.FlagWrite, FirstKey: 1, LastKey: 1,
+		Handler: func(ks module.Keyspace, args [][]byte) resp.Reply {
+			v, ok, err := ks.Get(string(args[1]), testBlobType)
+			if err != nil {
+				return resp.MakeErrReply(err.Error())
+			}
+			b := &testBlob{}
+			if ok {
+				b = v.(*testBlob)
+			}
+			b.data = append(b.data, args[2]...)
+			ks.Put(string(args[1]), testBlobType, b)
+			return resp.MakeIntReply(int64(len(b.data)))
+		},
+	}))
+	// CNT.INCRBY key n
+	mustRegister(module.Register(&module.Command{
+		Name: "cnt.incrby", Arity: 3, Flags: module.FlagWrite | module.FlagDenyScript, FirstKey: 1, LastKey: 1,
+		Handler: func(ks module.Keyspace, args [][]byte) resp.Reply {
+			delta, err := strconv.ParseInt(string(args[2]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			v, ok, err := ks.Get(string(args[1]), testCounterType)
+			if err != nil {
+				return resp.MakeErrReply(err.Error())
+			}
+			c := &testCounter{}
+			if ok {
+				c = v.(*testCounter)
+			}
+			c.n += delta
+			ks.Put(string(args[1]), testCounterType, c)
+			return resp.MakeIntReply(c.n)
+		},
+	}))
+	// CNT.GET key
+	mustRegister(module.Register(&module.Command{
+		Name: "cnt.get", Arity: 2, FirstKey: 1, LastKey: 1,
+		Handler: func(ks module.Keyspace, args [][]byte) resp.Reply {
+			v, ok, err := ks.Get(string(args[1]), testCounterType)
+			if err != nil {
+				return resp.MakeErrReply(err.Error())
+			}
+			if !ok {
+				return resp.MakeIntReply(0)
+			}
+			return resp.MakeIntReply(v.(*testCounter).n)
+		},
+	}))
+}
+
+func TestModule_Commands(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"CNT.INCRBY", "c", "5"}, ":5\r\n"},
+		{[]string{"cnt.incrby", "c", "2"}, ":7\r\n"},
+		{[]string{"CNT.GET", "c"}, ":7\r\n"},
+		{[]string{"CNT.GET", "c", "extra"}, "-ERR wrong number of arguments for 'cnt.get' command\r\n"},
+		{[]string{"CNT.NOPE"}, "-ERR unknown command 'cnt.nope'\r\n"},
+		{[]string{"TYPE", "c"}, "+testcounter\r\n"},
+		{[]string{"GET", "c"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"SET", "s", "x"}, "+OK\r\n"},
+		{[]string{"CNT.INCRBY", "s", "1"}, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{[]string{"COPY", "c", "c2"}, ":1\r\n"},
+		{[]string{"CNT.INCRBY", "c2", "1"}, ":8\r\n"},
+		{[]string{"CNT.GET", "c"}, ":7\r\n"},
+		{[]string{"SCAN", "0", "TYPE", "testcounter", "COUNT", "100"}, ""},
+		{[]string{"EVAL", "return redis.call('CNT.GET', 'c')", "0"}, ":7\r\n"},
+		{[]string{"EVAL", "return redis.call('CNT.INCRBY', 'c', 1)", "0"}, "-ERR This Redis command is not allowed from script\r\n"},
+	} {
+		got := string(execArgs(d, c.args...).ToBytes())
+		if c.want == "" {
+			if !bytes.Contains([]byte(got), []byte("$1\r\nc\r\n")) || !bytes.Contains([]byte(got), []byte("$2\r\nc2\r\n")) {
+				t.Fatalf("%v = %q", c.args, got)
+			}
+			continue
+		}
+		if got != c.want {
+			t.Fatalf("%v = %q, want %q", c.args, got, c.want)
+		}
+	}
+
+	// 模块写入同样会让 WATCH 失效
+	versions := d.Watch([]string{"c"})
+	execArgs(d, "CNT.INCRBY", "c", "1")
+	r := d.ExecMulti(txCmds([]string{"CNT.GET", "c"}), map[string]uint64{"c": versions[0]})
+	if mb, ok := r.(*resp.MultiBulkReply); !ok || mb.Args != nil {
+		t.Fatalf("EXEC after module write = %q, want nil array", r.ToBytes())
+	}
+	d.Unwatch([]string{"c"})
+}
+
+func TestModule_SizeAccounting(t *testing.T) {
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{MaxBytes: 2000, Eviction: "lru"})
+	defer d.Close()
+
+	// 原地追加的模块值同样计入 max-bytes：b 增长到 1900 字节后，最久未访问的 other 被淘汰
+	execArgs(d, "SET", "other", strings.Repeat("v", 100))
+	for i := 0; i < 19; i++ {
+		execArgs(d, "BLOB.APPEND", "b", strings.Repeat("x", 100))
+	}
+	if got := replyInt(t, execArgs(d, "EXISTS", "other")); got != 0 {
+		t.Fatalf("other should be evicted after the module value grew past max-bytes")
+	}
+	if got := replyInt(t, execArgs(d, "EXISTS", "b")); got != 1 {
+		t.Fatalf("b should still exist")
+	}
+}
+
+// TestModule_BuiltinNames 解析 execCommand 的 case 列表，保证 module 包的内置命令名表（builtin.go）没有遗漏。
+func TestModule_BuiltinNames(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "db.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != "execCommand" {
+			continue
+		}
+		for _, stmt := range fd.Body.List {
+			sw, ok := stmt.(*ast.SwitchStmt)
+			if !ok {
+				continue
+			}
+			for _, clause := range sw.Body.List {
+				for _, e := range clause.(*ast.CaseClause).List {
+					lit, ok := e.(*ast.BasicLit)
+					if !ok {
+						continue
+					}
+					name, _ := strconv.Unquote(lit.Value)
+					if !module.IsBuiltin(name) {
+						t.Errorf("built-in command %q is missing from module/builtin.go", name)
+					}
+					n++
+				}
+			}
+		}
+	}
+	if n < 100 {
+		t.Fatalf("found only %d commands in execCommand; did the dispatch move?", n)
+	}
+}
+
+func TestModule_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "module.aof")
+	rdbFile := filepath.Join(dir, "module.rdb")
+
+	d1 := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, RdbFilename: rdbFile})
+	execArgs(d1, "CNT.INCRBY", "c", "3")
+	execArgs(d1, "CNT.INCRBY", "c", "4")
+	execArgs(d1, "CNT.GET", "c")
+	execArgs(d1, "PEXPIRE", "c", "100000")
+	if r := execArgs(d1, "SAVE"); string(r.ToBytes()) != "+OK\r\n" {
+		t.Fatalf("SAVE = %q", r.ToBytes())
+	}
+	if err := d1.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	data, err := os.ReadFile(aofFile)
+	if err != nil {
+		t.Fatalf("read aof error: %v", err)
+	}
+	if bytes.Count(data, []byte("CNT.INCRBY")) != 2 || bytes.Contains(data, []byte("CNT.GET")) {
+		t.Fatalf("aof should contain only the module writes, got %q", data)
+	}
+	if r := execArgs(d1, "REWRITEAOF"); string(r.ToBytes()) != "+OK\r\n" {
+		t.Fatalf("REWRITEAOF = %q", r.ToBytes())
+	}
+	d1.Close()
+
+	// 只加载 RDB
+	d2 := NewStandaloneDBWithConfig(StandaloneDBConfig{RdbFilename: rdbFile})
+	d2.Load()
+	if got := string(execArgs(d2, "CNT.GET", "c").ToBytes()); got != ":7\r\n" {
+		t.Fatalf("rdb loaded c = %q", got)
+	}
+	if ttl := execArgs(d2, "PTTL", "c").(*resp.IntReply).Code; ttl <= 0 {
+		t.Fatalf("rdb loaded PTTL = %d", ttl)
+	}
+	d2.Close()
+
+	// 只加载重写后的 AOF
+	d3 := NewStandaloneDB(aofFile)
+	d3.Load()
+	defer d3.Close()
+	if got := string(execArgs(d3, "CNT.GET", "c").ToBytes()); got != ":7\r\n" {
+		t.Fatalf("rewritten aof loaded c = %q", got)
+	}
+	if ttl := execArgs(d3, "PTTL", "c").(*resp.IntReply).Code; ttl <= 0 {
+		t.Fatalf("rewritten aof loaded PTTL = %d", ttl)
+	}
+}
@@ -0,0 +1,183 @@
+// 事务支持：MULTI/EXEC 的批量执行与 WATCH 乐观锁的 key 版本号。
+// 说明：连接级的事务状态（命令队列、已 WATCH 的 key）由 server 层维护，这里只提供 Actor 内的原子执行与版本校验。
+// 关键点：整批命令作为一个 commandRequest 执行，期间不会穿插其它客户端的命令；AOF 以 MULTI/EXEC 包裹整批记录。
+package db
+
+import (
+	"myredis/pkg/lru"
+	"myredis/resp"
+	"strings"
+	"time"
+)
+
+// 本文件实现事务执行：
+// - Watch(keys)：登记监视并返回各 key 当前版本号；Unwatch(keys) 释放监视
+// - ExecMulti(cmds, watched)：先校验版本号（任一 key 被修改则返回 nil 数组，表示事务放弃），再依次执行整批命令
+//
+// 版本号：
+// - 只为有监视者的 key 维护（watchedKeys），无人 WATCH 时写命令没有额外开销
+// - 所有写入都经过 cache.Add（watchedCache 装饰）或 cache.Remove（onEvicted 回调），两处统一递增版本号；
+//   只修改 TTL 的命令（EXPIRE/PERSIST/GETEX）单独调用 touchWatched
+// - 被 WATCH 的 key 在 EXEC 前已过期，同样视为被修改
+//
+// 限制：
+// - 没有命令表，未知命令/参数错误无法在入队时发现，只会在 EXEC 的结果中体现为对应位置的错误（其余命令照常执行，与 Redis 运行期错误一致）
+// - 阻塞命令在事务中按“立即超时”处理（与 Redis 一致）
+
+// TxDB 为支持事务的 DB（cluster.Router 不支持跨节点事务，因此不实现该接口）。
+type TxDB interface {
+	DB
+	// Watch 登记对 keys 的监视，返回与 keys 一一对应的当前版本号。
+	Watch(keys []string) []uint64
+	// Unwatch 释放对 keys 的监视（每个 key 与一次 Watch 对应）。
+	Unwatch(keys []string)
+	// ExecMulti 原子执行整批命令；watched 中任一 key 的版本号变化时不执行，返回 nil 数组。
+	ExecMulti(cmds [][][]byte, watched map[string]uint64) resp.Reply
+}
+
+// watchState 为一个被监视 key 的版本号与监视者引用计数。
+type watchState struct {
+	version uint64
+	refs    int
+}
+
+// watchedCache 装饰缓存：写入时递增被 WATCH 的 key 的版本号。
+type watchedCache struct {
+	lru.EvictionCache
+	db *StandaloneDB
+}
+
+func (c watchedCache) Add(key string, value lru.Value, ttl int64) {
+	if c.db.notifyEnabled() {
+		if _, exists := c.EvictionCache.Peek(key); !exists {
+			c.db.trackKey(key, keyCreated)
+		}
+	}
+	c.db.touchWatched(key)
+	c.EvictionCache.Add(key, value, ttl)
+}
+
+// touchWatched 标记 key 被修改：递增版本号（仅当有监视者时），并记录到键空间通知（见 notify.go）。
+func (db *StandaloneDB) touchWatched(key string) {
+	if ws, ok := db.watchedKeys[key]; ok {
+		ws.version++
+	}
+	db.trackKey(key, keyModified)
+}
+
+// runTask 在 Actor 中执行 fn 并等待结果（不写 AOF）。
+func (db *StandaloneDB) runTask(fn func() resp.Reply) resp.Reply {
+	req := &commandRequest{
+		fn:     fn,
+		result: make(chan resp.Reply, 1),
+		noAof:  true,
+	}
+	select {
+	case <-db.closing:
+		return resp.MakeErrReply("ERR server closed")
+	case db.ops <- req:
+	}
+	select {
+	case res := <-req.result:
+		return res
+	case <-db.closing:
+		return resp.MakeErrReply("ERR server closed")
+	}
+}
+
+func (db *StandaloneDB) Watch(keys []string) []uint64 {
+	versions := make([]uint64, len(keys))
+	db.runTask(func() resp.Reply {
+		for i, key := range keys {
+			ws, ok := db.watchedKeys[key]
+			if !ok {
+				ws = &watchState{}
+				db.watchedKeys[key] = ws
+			}
+			ws.refs++
+			versions[i] = ws.version
+		}
+		return resp.OkReply
+	})
+	return versions
+}
+
+func (db *StandaloneDB) Unwatch(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	db.runTask(func() resp.Reply {
+		for _, key := range keys {
+			ws, ok := db.watchedKeys[key]
+			if !ok {
+				continue
+			}
+			if ws.refs--; ws.refs <= 0 {
+				delete(db.watchedKeys, key)
+			}
+		}
+		return resp.OkReply
+	})
+}
+
+func (db *StandaloneDB) ExecMulti(cmds [][][]byte, watched map[string]uint64) resp.Reply {
+	return db.runTask(func() resp.Reply {
+		return db.execMulti(cmds, watched)
+	})
+}
+
+// execMulti 在 Actor 中执行事务：校验 WATCH 版本号后逐条执行，并把整批 AOF 记录以 MULTI/EXEC 包裹写入。
+func (db *StandaloneDB) execMulti(cmds [][][]byte, watched map[string]uint64) resp.Reply {
+	now := time.Now()
+	for key := range watched {
+		// 已过期但尚未被删除的 key：先惰性删除（会递增版本号）
+		if expireAt, ok := db.ttlMap[key]; ok && now.After(expireAt) {
+			db.cache.Remove(key)
+		}
+	}
+	for key, version := range watched {
+		if ws, ok := db.watchedKeys[key]; !ok || ws.version != version {
+			return resp.MakeMultiBulkReply(nil)
+		}
+	}
+
+	db.inMulti = true
+	replies := make([]resp.Reply, 0, len(cmds))
+	for _, cmd := range cmds {
+		db.evictedKeys = db.evictedKeys[:0]
+		var res resp.Reply
+		if len(cmd) > 0 && strings.EqualFold(string(cmd[0]), "unwatch") {
+			// 监视在 EXEC 结束后由 server 统一释放，这里只需回复 OK
+			res = resp.OkReply
+		} else {
+			res = db.execInternal(cmd)
+		}
+		if br, ok := res.(*blockReply); ok {
+			res = br.onTimeout
+		} else if db.aofHandler != nil && !isError(res) {
+			db.logCommand(cmd, res)
+		}
+		replies = append(replies, res)
+	}
+	db.inMulti = false
+	db.evictedKeys = db.evictedKeys[:0]
+	db.flushMultiAof()
+	return resp.MakeMultiRawReply(replies)
+}
+
+// flushMultiAof 把 inMulti 期间缓存的 AOF 记录以 MULTI/EXEC 包裹写入（只有一条记录时无需包裹）。
+func (db *StandaloneDB) flushMultiAof() {
+	switch len(db.multiAof) {
+	case 0:
+		return
+	case 1:
+		db.aofHandler.AddAof(db.multiAof[0])
+	default:
+		db.aofHandler.AddAof([][]byte{[]byte("MULTI")})
+		for _, record := range db.multiAof {
+			db.aofHandler.AddAof(record)
+		}
+		db.aofHandler.AddAof([][]byte{[]byte("EXEC")})
+	}
+	db.multiAof = db.multiAof[:0]
+}
@@ -0,0 +1,177 @@
+// 事务测试：覆盖 ExecMulti 的批量执行、WATCH 版本校验、事务内阻塞命令与 AOF 的 MULTI/EXEC 包裹。
+// 目标：保证被修改的 WATCH key 会让事务放弃，且崩溃时只写了一半的事务在重放时被整体丢弃。
+// 覆盖：运行期错误不影响其它命令、EXPIRE/DEL/过期触发版本变化、BLPOP 立即超时、截断 AOF 重放。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func txCmds(cmds ...[]string) [][][]byte {
+	out := make([][][]byte, 0, len(cmds))
+	for _, args := range cmds {
+		cmd := make([][]byte, 0, len(args))
+		for _, a := range args {
+			cmd = append(cmd, []byte(a))
+		}
+		out = append(out, cmd)
+	}
+	return out
+}
+
+func TestMulti_ExecBatch(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "s", "str")
+	r := d.ExecMulti(txCmds(
+		[]string{"INCR", "n"},
+		[]string{"LPUSH", "s", "x"},
+		[]string{"INCRBY", "n", "4"},
+		[]string{"BLPOP", "empty", "0"},
+	), nil)
+	mr, ok := r.(*resp.MultiRawReply)
+	if !ok || len(mr.Replies) != 4 {
+		t.Fatalf("EXEC reply = %T %+v", r, r)
+	}
+	if ir, ok := mr.Replies[0].(*resp.IntReply); !ok || ir.Code != 1 {
+		t.Fatalf("INCR = %+v", mr.Replies[0])
+	}
+	// 运行期错误只影响自身，后续命令照常执行
+	if errStatus(mr.Replies[1]) == "" {
+		t.Fatalf("LPUSH on string should fail, got %+v", mr.Replies[1])
+	}
+	if ir, ok := mr.Replies[2].(*resp.IntReply); !ok || ir.Code != 5 {
+		t.Fatalf("INCRBY = %+v", mr.Replies[2])
+	}
+	// 阻塞命令在事务中立即按超时返回
+	if !bytes.Equal(mr.Replies[3].ToBytes(), []byte("*-1\r\n")) {
+		t.Fatalf("BLPOP in MULTI = %q", mr.Replies[3].ToBytes())
+	}
+}
+
+func TestMulti_WatchAbort(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "k", "1")
+	watched := func(keys ...string) map[string]uint64 {
+		m := make(map[string]uint64)
+		for i, v := range d.Watch(keys) {
+			m[keys[i]] = v
+		}
+		return m
+	}
+	run := func(w map[string]uint64) resp.Reply {
+		defer func() {
+			keys := make([]string, 0, len(w))
+			for k := range w {
+				keys = append(keys, k)
+			}
+			d.Unwatch(keys)
+		}()
+		return d.ExecMulti(txCmds([]string{"SET", "k", "tx"}), w)
+	}
+	aborted := func(r resp.Reply) bool {
+		mb, ok := r.(*resp.MultiBulkReply)
+		return ok && mb.Args == nil
+	}
+
+	// 未被修改：正常执行
+	if r := run(watched("k", "other")); aborted(r) {
+		t.Fatalf("untouched WATCH should not abort")
+	}
+	// 读命令不影响版本号
+	w := watched("k")
+	execArgs(d, "GET", "k")
+	if r := run(w); aborted(r) {
+		t.Fatalf("GET should not abort the transaction")
+	}
+
+	for _, modify := range [][]string{
+		{"SET", "k", "2"},
+		{"EXPIRE", "k", "100"},
+		{"PERSIST", "k"},
+		{"DEL", "k"},
+		{"SET", "k", "again"},
+	} {
+		w := watched("k")
+		execArgs(d, modify...)
+		if r := run(w); !aborted(r) {
+			t.Fatalf("%v should abort the transaction, got %+v", modify, r)
+		}
+	}
+	if got := execArgs(d, "GET", "k").(*resp.BulkReply); string(got.Arg) != "again" {
+		t.Fatalf("aborted transaction must not run, k = %q", got.Arg)
+	}
+
+	// WATCH 后 key 过期，同样视为被修改
+	execArgs(d, "PEXPIRE", "k", "20")
+	w = watched("k")
+	time.Sleep(40 * time.Millisecond)
+	if r := run(w); !aborted(r) {
+		t.Fatalf("expired watched key should abort the transaction")
+	}
+
+	// 释放监视后不再跟踪版本号
+	if n := replyInt(t, d.runTask(func() resp.Reply {
+		return resp.MakeIntReply(int64(len(d.watchedKeys)))
+	})); n != 0 {
+		t.Fatalf("watchedKeys should be empty after unwatch, got %d", n)
+	}
+}
+
+func TestMulti_AofWrappedAndTruncatedReplay(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "multi.aof")
+
+	d1 := NewStandaloneDB(filename)
+	execArgs(d1, "SET", "before", "1")
+	d1.ExecMulti(txCmds(
+		[]string{"SET", "a", "1"},
+		[]string{"GET", "a"},
+		[]string{"INCR", "a"},
+	), nil)
+	if err := d1.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	d1.Close()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof error: %v", err)
+	}
+	multi := []byte("*1\r\n$5\r\nMULTI\r\n")
+	exec := []byte("*1\r\n$4\r\nEXEC\r\n")
+	start, end := bytes.Index(data, multi), bytes.Index(data, exec)
+	if start < 0 || end < start || bytes.Contains(data[start:end], []byte("GET")) {
+		t.Fatalf("expected write commands wrapped in MULTI/EXEC, got %q", data)
+	}
+
+	// 完整文件：事务被重放
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	if got := execArgs(d2, "GET", "a").(*resp.BulkReply); string(got.Arg) != "2" {
+		t.Fatalf("replayed a = %q", got.Arg)
+	}
+	d2.Close()
+
+	// 截掉 EXEC（模拟写了一半时崩溃）：事务整体丢弃，之前的命令仍然生效
+	if err := os.WriteFile(filename, data[:end], 0o600); err != nil {
+		t.Fatalf("write aof error: %v", err)
+	}
+	d3 := NewStandaloneDB(filename)
+	d3.Load()
+	defer d3.Close()
+	if got := execArgs(d3, "GET", "a").(*resp.BulkReply); got.Arg != nil {
+		t.Fatalf("partial transaction must not be replayed, a = %q", got.Arg)
+	}
+	if got := execArgs(d3, "GET", "before").(*resp.BulkReply); string(got.Arg) != "1" {
+		t.Fatalf("before = %q", got.Arg)
+	}
+}
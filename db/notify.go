@@ -0,0 +1,345 @@
+// 键空间通知：notify-keyspace-events，把 key 的修改、删除、过期与淘汰以发布订阅消息的形式发出。
+// 说明：命令执行期间通过 cache.Add/onEvicted/touchWatched 记录被修改的 key，命令结束后按命令表生成事件；过期与淘汰在 onEvicted 中直接发出。
+// 关键点：事件经由 db.hub 发布到 __keyspace@0__:<key> 与 __keyevent@0__:<event>，在 Actor 中执行，顺序与命令执行顺序一致。
+package db
+
+import (
+	"myredis/module"
+	"myredis/resp"
+	"strings"
+)
+
+// 本文件实现键空间通知（与 Redis notify-keyspace-events 的标志一致）：
+// - K：__keyspace@0__:<key> 频道，消息为事件名；E：__keyevent@0__:<event> 频道，消息为 key
+// - g 通用（del/expire/persist/rename_from/rename_to/copy_to）、$ 字符串、l 列表、s 集合、h 哈希、z 有序集合、t 流、d 模块
+// - x 过期（expired，惰性删除与定期删除都会触发）、e 淘汰（evicted，超过 maxBytes 时触发）、n 新建 key（new）
+// - A 为 g$lshzxetd 的别名；m（key miss）可以设置但不会产生事件
+//
+// 事件来源：
+// - 命令修改的 key 由 trackKey 记录（cache.Add、cache.Remove 的回调、只修改 TTL 时的 touchWatched），命令结束后在 execInternal 中统一发出；
+//   同一 key 只发一次命令事件，被删除的 key 额外发出 del（与 Redis 的 LPOP 弹空列表、HDEL 删空哈希一致）
+// - 过期/淘汰不属于任何命令，在 onEvicted 中立即发出
+// - FLUSHDB/FLUSHALL 不产生逐 key 事件（与 Redis 一致）
+//
+// 集群模式下每个节点只发出本节点 key 的事件，订阅者需要连接到 key 所在的节点。
+
+const (
+	notifyKeyspace = 1 << iota // K
+	notifyKeyevent             // E
+	notifyGeneric              // g
+	notifyString               // $
+	notifyList                 // l
+	notifySet                  // s
+	notifyHash                 // h
+	notifyZSet                 // z
+	notifyExpired              // x
+	notifyEvicted              // e
+	notifyStream               // t
+	notifyKeyMiss              // m
+	notifyModule               // d
+	notifyNew                  // n
+
+	// notifyAll 为 A 代表的事件类型（不含 m 与 n，与 Redis 一致）
+	notifyAll = notifyGeneric | notifyString | notifyList | notifySet | notifyHash | notifyZSet |
+		notifyExpired | notifyEvicted | notifyStream | notifyModule
+)
+
+// notifyFlagChars 为事件类型标志与字符的对应关系（顺序即 CONFIG GET 输出的顺序）。
+var notifyFlagChars = []struct {
+	flag int
+	c    byte
+}{
+	{notifyGeneric, 'g'}, {notifyString, '$'}, {notifyList, 'l'}, {notifySet, 's'},
+	{notifyHash, 'h'}, {notifyZSet, 'z'}, {notifyExpired, 'x'}, {notifyEvicted, 'e'},
+	{notifyStream, 't'}, {notifyModule, 'd'},
+	{notifyKeyspace, 'K'}, {notifyKeyevent, 'E'}, {notifyKeyMiss, 'm'}, {notifyNew, 'n'},
+}
+
+// parseNotifyFlags 解析 notify-keyspace-events 字符串；包含未知字符时返回 false。
+func parseNotifyFlags(s string) (int, bool) {
+	flags := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			flags |= notifyAll
+			continue
+		}
+		found := false
+		for _, fc := range notifyFlagChars {
+			if fc.c == s[i] {
+				flags |= fc.flag
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return flags, true
+}
+
+// notifyFlagsString 把标志转换回字符串（CONFIG GET 使用），全部类型都开启时输出 A。
+func notifyFlagsString(flags int) string {
+	var b strings.Builder
+	all := flags&notifyAll == notifyAll
+	if all {
+		b.WriteByte('A')
+	}
+	for _, fc := range notifyFlagChars {
+		if all && fc.flag&notifyAll != 0 {
+			continue
+		}
+		if flags&fc.flag != 0 {
+			b.WriteByte(fc.c)
+		}
+	}
+	return b.String()
+}
+
+// ValidNotifyKeyspaceEvents 判断 s 是否为合法的 notify-keyspace-events 配置（CLI 参数校验使用）。
+func ValidNotifyKeyspaceEvents(s string) bool {
+	_, ok := parseNotifyFlags(s)
+	return ok
+}
+
+// notifyEnabled 判断是否需要发出通知（至少开启 K 或 E 之一，且开启了某种事件类型）。
+func (db *StandaloneDB) notifyEnabled() bool {
+	return db.notifyFlags&(notifyKeyspace|notifyKeyevent) != 0 && db.notifyFlags&^(notifyKeyspace|notifyKeyevent) != 0
+}
+
+// notify 发出一条键空间事件（class 未开启时忽略）。
+func (db *StandaloneDB) notify(class int, event, key string) {
+	if db.notifyFlags&class == 0 {
+		return
+	}
+	if db.notifyFlags&notifyKeyspace != 0 {
+		db.hub.Publish("__keyspace@0__:"+key, []byte(event))
+	}
+	if db.notifyFlags&notifyKeyevent != 0 {
+		db.hub.Publish("__keyevent@0__:"+event, []byte(key))
+	}
+}
+
+// keyState 为一条命令执行期间 key 的变化。
+type keyState uint8
+
+const (
+	keyModified keyState = iota
+	keyCreated           // 命令执行前不存在、被写入
+	keyDeleted           // 被命令删除
+	keyDropped           // 过期或淘汰（事件已在 onEvicted 中发出，不再产生命令事件）
+)
+
+type keyChange struct {
+	key   string
+	state keyState
+}
+
+// trackKey 记录 key 在当前命令中的变化（只在开启通知时记录）。
+func (db *StandaloneDB) trackKey(key string, state keyState) {
+	if !db.notifyEnabled() {
+		return
+	}
+	db.keyChanges = append(db.keyChanges, keyChange{key: key, state: state})
+}
+
+// execInternal 执行一条命令；开启通知时在命令结束后发出它产生的键空间事件。
+// MULTI/EXEC 与脚本中的命令会递归经过这里，因此每条内部命令各自发出事件。
+func (db *StandaloneDB) execInternal(cmd [][]byte) resp.Reply {
+	if !db.notifyEnabled() {
+		return db.execCommand(cmd)
+	}
+	outer := db.keyChanges
+	db.keyChanges = nil
+	res := db.execCommand(cmd)
+	db.notifyCommand(cmd, db.keyChanges)
+	db.keyChanges = outer
+	return res
+}
+
+// notifyCommand 按命令表为 changes 中的 key 发出事件；cmd 为 nil 表示不属于任何命令（定期删除），只发出 del。
+func (db *StandaloneDB) notifyCommand(cmd [][]byte, changes []keyChange) {
+	if len(changes) == 0 {
+		return
+	}
+	name := ""
+	if len(cmd) > 0 {
+		name = strings.ToLower(string(cmd[0]))
+	}
+	if name == "flushdb" || name == "flushall" {
+		return
+	}
+
+	// 合并同一 key 的多次变化：以最后一次为准，新建后再修改仍视为新建
+	order := make([]string, 0, len(changes))
+	final := make(map[string]keyState, len(changes))
+	for _, c := range changes {
+		prev, seen := final[c.key]
+		if !seen {
+			order = append(order, c.key)
+		}
+		if seen && prev == keyCreated && c.state == keyModified {
+			continue
+		}
+		final[c.key] = c.state
+	}
+
+	for _, key := range order {
+		state := final[key]
+		if state == keyDropped {
+			continue
+		}
+		var events []keyEvent
+		if name != "" {
+			events = commandKeyEvents(name, cmd, key)
+		}
+		if state == keyCreated {
+			db.notify(notifyNew, "new", key)
+		}
+		if state != keyDeleted {
+			for _, ev := range events {
+				db.notify(ev.class, ev.name, key)
+			}
+			if name == "set" && setHasExpire(cmd) {
+				db.notify(notifyGeneric, "expire", key)
+			}
+			continue
+		}
+		// 被删除的 key：先发命令事件（如 lpop），再发 del。
+		// 例外：EXPIRE 设置过去的时间只发 del；RENAME 的源 key 只发 rename_from
+		renamed := false
+		for _, ev := range events {
+			switch ev.name {
+			case "del", "expire":
+			case "rename_from":
+				renamed = true
+				db.notify(ev.class, ev.name, key)
+			default:
+				db.notify(ev.class, ev.name, key)
+			}
+		}
+		if !renamed {
+			db.notify(notifyGeneric, "del", key)
+		}
+	}
+}
+
+// keyEvent 为一条命令对某个 key 产生的事件。
+type keyEvent struct {
+	class int
+	name  string
+}
+
+// commandEvents 为命令与事件的对应关系（与 Redis 各命令调用 notifyKeyspaceEvent 时使用的事件名一致）。
+// 源 key 与目标 key 事件不同的命令（RENAME/COPY/LMOVE/SMOVE 等）在 commandKeyEvents 中单独处理。
+var commandEvents = map[string]keyEvent{
+	"del": {notifyGeneric, "del"}, "getdel": {notifyGeneric, "del"},
+	"expire": {notifyGeneric, "expire"}, "pexpire": {notifyGeneric, "expire"},
+	"expireat": {notifyGeneric, "expire"}, "pexpireat": {notifyGeneric, "expire"},
+	"persist": {notifyGeneric, "persist"},
+
+	"set": {notifyString, "set"}, "setnx": {notifyString, "set"}, "getset": {notifyString, "set"},
+	"mset": {notifyString, "set"}, "msetnx": {notifyString, "set"},
+	"incr": {notifyString, "incrby"}, "decr": {notifyString, "incrby"},
+	"incrby": {notifyString, "incrby"}, "decrby": {notifyString, "incrby"},
+	"incrbyfloat": {notifyString, "incrbyfloat"},
+	"append":      {notifyString, "append"}, "setrange": {notifyString, "setrange"},
+	"setbit": {notifyString, "setbit"}, "bitfield": {notifyString, "setbit"}, "bitop": {notifyString, "set"},
+	"pfadd": {notifyString, "pfadd"}, "pfmerge": {notifyString, "pfadd"},
+
+	"lpush": {notifyList, "lpush"}, "lpushx": {notifyList, "lpush"},
+	"rpush": {notifyList, "rpush"}, "rpushx": {notifyList, "rpush"},
+	"lpop": {notifyList, "lpop"}, "blpop": {notifyList, "lpop"},
+	"rpop": {notifyList, "rpop"}, "brpop": {notifyList, "rpop"},
+	"lset": {notifyList, "lset"}, "linsert": {notifyList, "linsert"},
+	"lrem": {notifyList, "lrem"}, "ltrim": {notifyList, "ltrim"},
+
+	"hset": {notifyHash, "hset"}, "hmset": {notifyHash, "hset"}, "hsetnx": {notifyHash, "hset"},
+	"hdel": {notifyHash, "hdel"}, "hincrby": {notifyHash, "hincrby"}, "hincrbyfloat": {notifyHash, "hincrbyfloat"},
+	"hexpire": {notifyHash, "hexpire"}, "hpexpire": {notifyHash, "hexpire"},
+	"hexpireat": {notifyHash, "hexpire"}, "hpexpireat": {notifyHash, "hexpire"},
+	"hpersist": {notifyHash, "hpersist"},
+
+	"sadd": {notifySet, "sadd"}, "srem": {notifySet, "srem"}, "spop": {notifySet, "spop"},
+	"sinterstore": {notifySet, "sinterstore"}, "sunionstore": {notifySet, "sunionstore"},
+	"sdiffstore": {notifySet, "sdiffstore"},
+
+	"zadd": {notifyZSet, "zadd"}, "zincrby": {notifyZSet, "zincr"}, "zrem": {notifyZSet, "zrem"},
+	"zpopmin": {notifyZSet, "zpopmin"}, "zpopmax": {notifyZSet, "zpopmax"},
+	"zremrangebyscore": {notifyZSet, "zremrangebyscore"}, "zremrangebyrank": {notifyZSet, "zremrangebyrank"},
+	"zremrangebylex": {notifyZSet, "zremrangebylex"},
+	"geoadd":         {notifyZSet, "zadd"}, "geosearchstore": {notifyZSet, "geosearchstore"},
+
+	"xadd": {notifyStream, "xadd"}, "xtrim": {notifyStream, "xtrim"}, "xsetid": {notifyStream, "xsetid"},
+	"xclaim": {notifyStream, "xclaim"}, "xautoclaim": {notifyStream, "xautoclaim"},
+}
+
+// commandKeyEvents 返回命令 name 对 key 产生的事件（不产生事件的命令返回 nil）。
+func commandKeyEvents(name string, cmd [][]byte, key string) []keyEvent {
+	listEnd := func(arg []byte, op string) keyEvent {
+		if strings.EqualFold(string(arg), "left") {
+			return keyEvent{notifyList, "l" + op}
+		}
+		return keyEvent{notifyList, "r" + op}
+	}
+	// roles 为源 key/目标 key 的事件；src 与 dst 相同时两个事件都发出
+	roles := func(src, dst keyEvent) []keyEvent {
+		var out []keyEvent
+		if string(cmd[1]) == key {
+			out = append(out, src)
+		}
+		if string(cmd[2]) == key {
+			out = append(out, dst)
+		}
+		return out
+	}
+
+	switch name {
+	case "rename", "renamenx":
+		return roles(keyEvent{notifyGeneric, "rename_from"}, keyEvent{notifyGeneric, "rename_to"})
+	case "copy":
+		if string(cmd[2]) == key {
+			return []keyEvent{{notifyGeneric, "copy_to"}}
+		}
+		return nil
+	case "lmove", "blmove":
+		return roles(listEnd(cmd[3], "pop"), listEnd(cmd[4], "push"))
+	case "rpoplpush":
+		return roles(keyEvent{notifyList, "rpop"}, keyEvent{notifyList, "lpush"})
+	case "smove":
+		return roles(keyEvent{notifySet, "srem"}, keyEvent{notifySet, "sadd"})
+	case "zadd":
+		for _, a := range cmd[2:] {
+			if strings.EqualFold(string(a), "incr") {
+				return []keyEvent{{notifyZSet, "zincr"}}
+			}
+		}
+	case "getex":
+		for _, a := range cmd[2:] {
+			if strings.EqualFold(string(a), "persist") {
+				return []keyEvent{{notifyGeneric, "persist"}}
+			}
+		}
+		return []keyEvent{{notifyGeneric, "expire"}}
+	case "xgroup":
+		return []keyEvent{{notifyStream, "xgroup-" + strings.ToLower(string(cmd[1]))}}
+	}
+	if ev, ok := commandEvents[name]; ok {
+		return []keyEvent{ev}
+	}
+	if _, ok := module.Lookup(name); ok {
+		return []keyEvent{{notifyModule, name}}
+	}
+	return nil
+}
+
+// setHasExpire 判断 SET 是否带有过期选项（SET 带 EX/PX/EXAT/PXAT 时额外发出 expire 事件）。
+func setHasExpire(cmd [][]byte) bool {
+	for _, a := range cmd[3:] {
+		switch strings.ToLower(string(a)) {
+		case "ex", "px", "exat", "pxat":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+// 键空间通知测试：通过 Hub 订阅 __keyspace@0__/__keyevent@0__，验证命令事件、过期/淘汰事件与 CONFIG 配置。
+// 覆盖：弹空列表额外发出 del、RENAME 的 rename_from/rename_to、SET EX 的 expire、事务中的命令、new 事件、未开启的类型不发出。
+package db
+
+import (
+	"io"
+	"myredis/resp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscribeEvents 以模式订阅 d 的键空间事件，返回依次读取 "channel message" 的函数。
+func subscribeEvents(t *testing.T, d *StandaloneDB, patterns ...string) func() string {
+	t.Helper()
+	pr, pw := io.Pipe()
+	hub := d.PubSub()
+	sub := hub.NewSubscriber(pw)
+	hub.PSubscribe(sub, patterns...)
+	t.Cleanup(func() {
+		hub.RemoveSubscriber(sub)
+		pw.Close()
+	})
+
+	events := make(chan string, 100)
+	go func() {
+		parser := resp.NewStreamParser(pr)
+		for {
+			r, err := parser.ReadReply()
+			if err != nil {
+				return
+			}
+			mb, ok := r.(*resp.MultiBulkReply)
+			if ok && len(mb.Args) == 4 && string(mb.Args[0]) == "pmessage" {
+				events <- string(mb.Args[2]) + " " + string(mb.Args[3])
+			}
+		}
+	}()
+	return func() string {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for keyspace event")
+			return ""
+		}
+	}
+}
+
+// expectEvents 依次读取事件并与 want 比较。
+func expectEvents(t *testing.T, next func() string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if got := next(); got != w {
+			t.Fatalf("event = %q, want %q", got, w)
+		}
+	}
+}
+
+func TestNotify_CommandEvents(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+	if r := execArgs(d, "CONFIG", "SET", "notify-keyspace-events", "EA"); r != resp.OkReply {
+		t.Fatalf("CONFIG SET = %+v", r)
+	}
+	next := subscribeEvents(t, d, "__keyevent@0__:*")
+
+	execArgs(d, "SET", "k", "v")
+	execArgs(d, "GET", "k") // 只读命令不产生事件
+	execArgs(d, "SET", "t", "v", "EX", "100")
+	expectEvents(t, next, "__keyevent@0__:set k", "__keyevent@0__:set t", "__keyevent@0__:expire t")
+
+	execArgs(d, "RPUSH", "l", "a")
+	execArgs(d, "LPOP", "l")
+	execArgs(d, "DEL", "k", "missing")
+	expectEvents(t, next, "__keyevent@0__:rpush l", "__keyevent@0__:lpop l", "__keyevent@0__:del l", "__keyevent@0__:del k")
+
+	execArgs(d, "RENAME", "t", "u")
+	execArgs(d, "EXPIRE", "u", "0")
+	execArgs(d, "SADD", "s", "a")
+	execArgs(d, "SADD", "s", "a") // 没有新增成员，不产生事件
+	execArgs(d, "HSET", "h", "f", "1")
+	expectEvents(t, next, "__keyevent@0__:rename_from t", "__keyevent@0__:rename_to u", "__keyevent@0__:del u",
+		"__keyevent@0__:sadd s", "__keyevent@0__:hset h")
+
+	d.ExecMulti(txCmds([]string{"INCR", "n"}, []string{"ZADD", "z", "INCR", "1", "m"}), nil)
+	execArgs(d, "EVAL", "redis.call('LPUSH', KEYS[1], 'x') return 1", "1", "sl")
+	expectEvents(t, next, "__keyevent@0__:incrby n", "__keyevent@0__:zincr z", "__keyevent@0__:lpush sl")
+
+	// FLUSHALL 不产生逐 key 事件
+	execArgs(d, "FLUSHALL")
+	execArgs(d, "SET", "after", "1")
+	expectEvents(t, next, "__keyevent@0__:set after")
+}
+
+func TestNotify_ExpiredAndEvicted(t *testing.T) {
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{MaxBytes: 300, Eviction: "lru", NotifyKeyspaceEvents: "Kxe"})
+	defer d.Close()
+	next := subscribeEvents(t, d, "__keyspace@0__:*")
+
+	// 只开启 x/e：EXPIRE 等通用事件不发出；定期删除触发 expired
+	execArgs(d, "SET", "session", "v", "PX", "20")
+	expectEvents(t, next, "__keyspace@0__:session expired")
+
+	// 惰性删除同样触发 expired
+	execArgs(d, "SET", "lazy", "v", "PX", "1")
+	time.Sleep(5 * time.Millisecond)
+	execArgs(d, "GET", "lazy")
+	expectEvents(t, next, "__keyspace@0__:lazy expired")
+
+	// 超过 maxBytes 时淘汰最旧的 key
+	value := strings.Repeat("x", 100)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		execArgs(d, "SET", k, value)
+	}
+	expectEvents(t, next, "__keyspace@0__:a evicted")
+}
+
+func TestNotify_NewAndConfig(t *testing.T) {
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{NotifyKeyspaceEvents: "E$n"})
+	defer d.Close()
+	next := subscribeEvents(t, d, "__keyevent@0__:*")
+
+	execArgs(d, "SET", "k", "1")
+	execArgs(d, "SET", "k", "2")
+	execArgs(d, "LPUSH", "list", "a") // l 未开启
+	execArgs(d, "APPEND", "k", "3")
+	expectEvents(t, next, "__keyevent@0__:new k", "__keyevent@0__:set k", "__keyevent@0__:set k",
+		"__keyevent@0__:new list", "__keyevent@0__:append k")
+
+	if got := replyStrings(t, execArgs(d, "CONFIG", "GET", "notify-*")); strings.Join(got, " ") != "notify-keyspace-events $En" {
+		t.Fatalf("CONFIG GET = %v", got)
+	}
+	execArgs(d, "CONFIG", "SET", "notify-keyspace-events", "KEA")
+	if got := replyStrings(t, execArgs(d, "CONFIG", "GET", "notify-keyspace-events")); got[1] != "AKE" {
+		t.Fatalf("CONFIG GET after set = %v", got)
+	}
+	if got := replyStrings(t, execArgs(d, "CONFIG", "GET", "maxmemory")); len(got) != 0 {
+		t.Fatalf("unknown parameter should return empty array, got %v", got)
+	}
+	if er, ok := execArgs(d, "CONFIG", "SET", "notify-keyspace-events", "KQ").(*resp.ErrorReply); !ok || !strings.HasPrefix(er.Status, "ERR CONFIG SET failed") {
+		t.Fatalf("invalid flags should fail, got %+v", er)
+	}
+	if er, ok := execArgs(d, "CONFIG", "SET", "maxmemory", "1").(*resp.ErrorReply); !ok || !strings.HasPrefix(er.Status, "ERR Unknown option") {
+		t.Fatalf("unknown parameter should fail, got %+v", er)
+	}
+
+	// 关闭后不再发出事件
+	execArgs(d, "CONFIG", "SET", "notify-keyspace-events", "")
+	execArgs(d, "SET", "quiet", "1")
+	execArgs(d, "CONFIG", "SET", "notify-keyspace-events", "E$")
+	execArgs(d, "SET", "loud", "1")
+	expectEvents(t, next, "__keyevent@0__:set loud")
+}
@@ -0,0 +1,71 @@
+// 发布订阅：PUBLISH 与 PUBSUB CHANNELS|NUMSUB|NUMPAT，在 Actor 中访问 StandaloneDB 持有的 pubsub.Hub。
+// 说明：订阅关系属于连接（SUBSCRIBE 等由 server 层处理），DB 只负责发布与查询，因此 PUBLISH 可以在 MULTI 与脚本中使用。
+// 关键点：Hub.Publish 只把消息放入订阅者的发送队列，不会阻塞 Actor；PUBLISH 不写 AOF。
+package db
+
+import (
+	"myredis/pubsub"
+	"myredis/resp"
+	"strings"
+)
+
+// 本文件实现：
+// - PUBLISH channel message：返回接收者数量（频道订阅数 + 匹配的模式订阅数）
+// - PUBSUB CHANNELS [pattern] / PUBSUB NUMSUB [channel ...] / PUBSUB NUMPAT
+//
+// 集群模式下 PUBLISH 由 cluster.Router 额外转发到其它节点；PUBSUB 只统计入口节点（与 Redis Cluster 一致）。
+
+// PubSubDB 为支持发布订阅的 DB（server 通过它取得 Hub 来处理 SUBSCRIBE 等连接级命令）。
+type PubSubDB interface {
+	DB
+	PubSub() *pubsub.Hub
+}
+
+func (db *StandaloneDB) PubSub() *pubsub.Hub {
+	return db.hub
+}
+
+// PUBLISH channel message
+func (db *StandaloneDB) publish(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'publish' command")
+	}
+	return resp.MakeIntReply(int64(db.hub.Publish(string(args[1]), args[2])))
+}
+
+// PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT
+func (db *StandaloneDB) pubsubCmd(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'pubsub' command")
+	}
+	sub := strings.ToLower(string(args[1]))
+	arityErr := resp.MakeErrReply("ERR wrong number of arguments for 'pubsub|" + sub + "' command")
+	switch sub {
+	case "channels":
+		if len(args) > 3 {
+			return arityErr
+		}
+		pattern := "*"
+		if len(args) == 3 {
+			pattern = string(args[2])
+		}
+		channels := db.hub.Channels(pattern)
+		out := make([][]byte, 0, len(channels))
+		for _, ch := range channels {
+			out = append(out, []byte(ch))
+		}
+		return resp.MakeMultiBulkReply(out)
+	case "numsub":
+		replies := make([]resp.Reply, 0, 2*(len(args)-2))
+		for _, ch := range args[2:] {
+			replies = append(replies, resp.MakeBulkReply(ch), resp.MakeIntReply(int64(db.hub.NumSub(string(ch)))))
+		}
+		return resp.MakeMultiRawReply(replies)
+	case "numpat":
+		if len(args) != 2 {
+			return arityErr
+		}
+		return resp.MakeIntReply(int64(db.hub.NumPat()))
+	}
+	return resp.MakeErrReply("ERR unknown subcommand '" + string(args[1]) + "'. Try PUBSUB HELP.")
+}
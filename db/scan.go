@@ -0,0 +1,263 @@
+// 游标遍历命令实现：SCAN / HSCAN / SSCAN / ZSCAN（MATCH / COUNT / TYPE）。
+// 说明：SCAN 依赖缓存内部的游标索引（lru.EvictionCache.Scan），每次只访问少量桶，不会像全量遍历那样阻塞 Actor。
+// 关键点：服务端无状态——游标本身编码了遍历进度；过期 key 在遍历结束后统一删除，避免边遍历边修改索引。
+package db
+
+import (
+	"myredis/pkg/glob"
+	"myredis/pkg/lru"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现游标遍历命令：
+// - SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+// - HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+// - SSCAN key cursor [MATCH pattern] [COUNT count]
+// - ZSCAN key cursor [MATCH pattern] [COUNT count]
+//
+// 回包格式：[next_cursor, [element ...]]，next_cursor 为 "0" 表示遍历结束。
+// HSCAN/SSCAN/ZSCAN：小集合（<= collectionScanFullThreshold）一次返回全部元素（对齐 Redis 紧凑编码的行为）；
+// 大集合使用与 SCAN 相同的 lru.ScanIndex 桶表与反向二进制游标，每次调用只访问 COUNT 个左右的桶。
+
+// collectionScanFullThreshold 以内的集合一次性返回全部元素。
+const collectionScanFullThreshold = 512
+
+// memberIndex 为 Hash/Set/ZSet 持有的游标索引：首次遍历超过阈值的集合时建立（O(n) 一次），
+// 之后随成员增删同步维护；集合缩小到阈值以内时释放（此时遍历一次返回全部元素，不再需要索引）。
+// 以指针保存在集合值中，方法均允许 nil 接收者。
+type memberIndex struct {
+	idx *lru.ScanIndex
+}
+
+// added 记录新成员（调用方保证成员此前不存在）。
+func (m *memberIndex) added(member string) {
+	if m != nil && m.idx != nil {
+		m.idx.Add(member)
+	}
+}
+
+// removed 删除成员。
+func (m *memberIndex) removed(member string) {
+	if m == nil || m.idx == nil {
+		return
+	}
+	m.idx.Remove(member)
+	if m.idx.Len() <= collectionScanFullThreshold {
+		m.idx = nil
+	}
+}
+
+// scanOptions 为 SCAN 系列命令的公共参数。
+type scanOptions struct {
+	cursor   uint64
+	count    int
+	pattern  string
+	match    bool
+	typ      string
+	noValues bool
+}
+
+// parseScanArgs 解析 cursor 及其后的可选参数；allowType/allowNoValues 控制命令特有选项。
+func parseScanArgs(args [][]byte, allowType, allowNoValues bool) (scanOptions, resp.Reply) {
+	opts := scanOptions{count: 10}
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		return opts, resp.MakeErrReply("ERR invalid cursor")
+	}
+	opts.cursor = cursor
+
+	for i := 1; i < len(args); i++ {
+		switch opt := strings.ToLower(string(args[i])); {
+		case opt == "match" && i+1 < len(args):
+			opts.pattern, opts.match = string(args[i+1]), true
+			i++
+		case opt == "count" && i+1 < len(args):
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return opts, resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n < 1 {
+				return opts, resp.MakeErrReply("ERR syntax error")
+			}
+			if n > 1<<30 {
+				n = 1 << 30
+			}
+			opts.count = int(n)
+			i++
+		case opt == "type" && allowType && i+1 < len(args):
+			opts.typ = strings.ToLower(string(args[i+1]))
+			i++
+		case opt == "novalues" && allowNoValues:
+			opts.noValues = true
+		default:
+			return opts, resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	return opts, nil
+}
+
+func (o scanOptions) matches(s string) bool {
+	return !o.match || glob.Match(o.pattern, s)
+}
+
+func makeScanReply(cursor uint64, elems [][]byte) resp.Reply {
+	return resp.MakeMultiRawReply([]resp.Reply{
+		resp.MakeBulkReply([]byte(strconv.FormatUint(cursor, 10))),
+		resp.MakeMultiBulkReply(elems),
+	})
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func (db *StandaloneDB) scan(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'scan' command")
+	}
+	opts, errReply := parseScanArgs(args[1:], true, false)
+	if errReply != nil {
+		return errReply
+	}
+
+	now := time.Now()
+	keys := make([][]byte, 0)
+	var expired []string
+	next := db.cache.Scan(opts.cursor, opts.count, func(key string, value lru.Value) {
+		if expireAt, ok := db.ttlMap[key]; ok && now.After(expireAt) {
+			expired = append(expired, key)
+			return
+		}
+		if opts.typ != "" {
+			entity, _ := value.(DataEntity)
+			if typeName(entity) != opts.typ {
+				return
+			}
+		}
+		if opts.matches(key) {
+			keys = append(keys, []byte(key))
+		}
+	})
+	// 遍历期间不能修改缓存，过期 key 统一在结束后删除
+	for _, k := range expired {
+		db.cache.Remove(k)
+	}
+	return makeScanReply(next, keys)
+}
+
+// scanKeyArgs 为 HSCAN/SSCAN/ZSCAN 做参数校验并读取 key（会刷新 LRU/LFU 统计并执行惰性过期）。
+func (db *StandaloneDB) scanKeyArgs(args [][]byte, name string, allowNoValues bool) (DataEntity, scanOptions, resp.Reply) {
+	if len(args) < 3 {
+		return nil, scanOptions{}, resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	opts, errReply := parseScanArgs(args[2:], false, allowNoValues)
+	if errReply != nil {
+		return nil, opts, errReply
+	}
+	entity, _ := db.getEntity(string(args[1]))
+	return entity, opts, nil
+}
+
+// HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func (db *StandaloneDB) hscan(args [][]byte) resp.Reply {
+	entity, opts, errReply := db.scanKeyArgs(args, "hscan", true)
+	if errReply != nil {
+		return errReply
+	}
+	elems := make([][]byte, 0)
+	if entity == nil {
+		return makeScanReply(0, elems)
+	}
+	h, ok := entity.(HashData)
+	if !ok {
+		return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	if !db.expireHashFields(string(args[1]), h) {
+		return makeScanReply(0, elems)
+	}
+	next := scanCollection(h.scan, len(h.fields), func(fn func(string)) {
+		for field := range h.fields {
+			fn(field)
+		}
+	}, opts.cursor, opts.count, func(field string) {
+		if !opts.matches(field) {
+			return
+		}
+		elems = append(elems, []byte(field))
+		if !opts.noValues {
+			elems = append(elems, h.fields[field])
+		}
+	})
+	return makeScanReply(next, elems)
+}
+
+// SSCAN key cursor [MATCH pattern] [COUNT count]
+func (db *StandaloneDB) sscan(args [][]byte) resp.Reply {
+	entity, opts, errReply := db.scanKeyArgs(args, "sscan", false)
+	if errReply != nil {
+		return errReply
+	}
+	elems := make([][]byte, 0)
+	if entity == nil {
+		return makeScanReply(0, elems)
+	}
+	s, ok := entity.(SetData)
+	if !ok {
+		return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	next := scanCollection(s.scan, s.Card(), func(fn func(string)) {
+		for member := range s.members {
+			fn(member)
+		}
+	}, opts.cursor, opts.count, func(member string) {
+		if opts.matches(member) {
+			elems = append(elems, []byte(member))
+		}
+	})
+	return makeScanReply(next, elems)
+}
+
+// ZSCAN key cursor [MATCH pattern] [COUNT count]
+func (db *StandaloneDB) zscan(args [][]byte) resp.Reply {
+	entity, opts, errReply := db.scanKeyArgs(args, "zscan", false)
+	if errReply != nil {
+		return errReply
+	}
+	elems := make([][]byte, 0)
+	if entity == nil {
+		return makeScanReply(0, elems)
+	}
+	zs, ok := entity.(ZSetData)
+	if !ok {
+		return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	next := scanCollection(zs.scan, len(zs.dict), func(fn func(string)) {
+		for member := range zs.dict {
+			fn(member)
+		}
+	}, opts.cursor, opts.count, func(member string) {
+		if opts.matches(member) {
+			elems = append(elems, []byte(member), []byte(formatScore(zs.dict[member])))
+		}
+	})
+	return makeScanReply(next, elems)
+}
+
+// scanCollection 在集合元素上做游标遍历：
+// - n <= collectionScanFullThreshold：一次返回全部元素，游标置 0
+// - 否则使用 mi 中的索引（首次遍历时用 each 建立），从 cursor 开始访问桶，每次调用的代价为 O(count)
+// 索引扩容/缩容后反向二进制游标仍能保证不漏扫；emit 期间不能修改集合。
+func scanCollection(mi *memberIndex, n int, each func(fn func(member string)), cursor uint64, count int, emit func(member string)) uint64 {
+	if n <= collectionScanFullThreshold {
+		each(emit)
+		return 0
+	}
+	if mi == nil {
+		mi = &memberIndex{}
+	}
+	if mi.idx == nil {
+		mi.idx = lru.NewScanIndex()
+		each(mi.idx.Add)
+	}
+	return mi.idx.Scan(cursor, count, emit)
+}
@@ -0,0 +1,204 @@
+// SCAN 系列测试：覆盖游标完整遍历、MATCH/COUNT/TYPE 过滤、遍历期间增删 key 的稳定性与 HSCAN/SSCAN/ZSCAN。
+// 目标：保证一次完整遍历期间始终存在的 key 至少返回一次，且大集合的游标索引同样不漏扫。
+// 覆盖：SCAN 0..0 全覆盖、遍历中途写入导致扩容、非法游标、小集合一次返回、大集合分批返回、集合索引随增删维护。
+package db
+
+import (
+	"fmt"
+	"myredis/resp"
+	"sort"
+	"testing"
+)
+
+// scanAll 循环调用 SCAN 系列命令直到游标回到 0，返回去重后的元素集合与调用次数。
+func scanAll(t *testing.T, d *StandaloneDB, prefix []string, suffix []string, between func(round int)) (map[string]int, int) {
+	t.Helper()
+	seen := make(map[string]int)
+	cursor := "0"
+	rounds := 0
+	for {
+		args := append(append(append([]string{}, prefix...), cursor), suffix...)
+		raw, ok := execArgs(d, args...).(*resp.MultiRawReply)
+		if !ok || len(raw.Replies) != 2 {
+			t.Fatalf("unexpected scan reply for %v", args)
+		}
+		cursor = string(raw.Replies[0].(*resp.BulkReply).Arg)
+		for _, e := range raw.Replies[1].(*resp.MultiBulkReply).Args {
+			seen[string(e)]++
+		}
+		rounds++
+		if between != nil {
+			between(rounds)
+		}
+		if cursor == "0" {
+			return seen, rounds
+		}
+		if rounds > 100000 {
+			t.Fatalf("scan did not terminate")
+		}
+	}
+}
+
+func TestScan_FullIterationWithConcurrentWrites(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		execArgs(d, "SET", fmt.Sprintf("key:%d", i), "v")
+	}
+
+	// 遍历期间持续写入新 key（触发索引扩容），原有 key 必须全部被返回
+	extra := 0
+	seen, rounds := scanAll(t, d, []string{"SCAN"}, []string{"COUNT", "20"}, func(int) {
+		for j := 0; j < 20; j++ {
+			execArgs(d, "SET", fmt.Sprintf("new:%d", extra), "v")
+			extra++
+		}
+	})
+	if rounds < 2 {
+		t.Fatalf("expected incremental iteration, got %d rounds", rounds)
+	}
+	for i := 0; i < n; i++ {
+		if seen[fmt.Sprintf("key:%d", i)] == 0 {
+			t.Fatalf("key:%d missing from SCAN", i)
+		}
+	}
+}
+
+func TestScan_MatchTypeAndErrors(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "user:1", "a")
+	execArgs(d, "SET", "user:2", "b")
+	execArgs(d, "SADD", "user:set", "x")
+	execArgs(d, "SET", "other", "c")
+
+	seen, _ := scanAll(t, d, []string{"SCAN"}, []string{"MATCH", "user:*", "TYPE", "string"}, nil)
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[user:1 user:2]" {
+		t.Fatalf("SCAN MATCH TYPE = %v", keys)
+	}
+
+	if er, ok := execArgs(d, "SCAN", "abc").(*resp.ErrorReply); !ok || er.Status != "ERR invalid cursor" {
+		t.Fatalf("expected invalid cursor error, got %+v", er)
+	}
+	if _, ok := execArgs(d, "SCAN", "0", "COUNT", "0").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected syntax error for COUNT 0")
+	}
+	if _, ok := execArgs(d, "SSCAN", "user:1", "0").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected WRONGTYPE for SSCAN on string")
+	}
+}
+
+func TestScan_CollectionScans(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	// 小集合：一次返回全部元素
+	execArgs(d, "HSET", "h", "f1", "v1", "f2", "v2")
+	raw := execArgs(d, "HSCAN", "h", "0").(*resp.MultiRawReply)
+	if c := string(raw.Replies[0].(*resp.BulkReply).Arg); c != "0" {
+		t.Fatalf("small HSCAN cursor = %s", c)
+	}
+	if got := len(raw.Replies[1].(*resp.MultiBulkReply).Args); got != 4 {
+		t.Fatalf("small HSCAN returned %d elements", got)
+	}
+	raw = execArgs(d, "HSCAN", "h", "0", "NOVALUES").(*resp.MultiRawReply)
+	if got := len(raw.Replies[1].(*resp.MultiBulkReply).Args); got != 2 {
+		t.Fatalf("HSCAN NOVALUES returned %d elements", got)
+	}
+
+	// 大集合：分批返回，且完整遍历覆盖所有成员
+	const n = 2000
+	for i := 0; i < n; i++ {
+		execArgs(d, "SADD", "s", fmt.Sprintf("m%d", i))
+		execArgs(d, "ZADD", "z", fmt.Sprint(i), fmt.Sprintf("m%d", i))
+	}
+	seen, rounds := scanAll(t, d, []string{"SSCAN", "s"}, []string{"COUNT", "100"}, nil)
+	if rounds < 2 || len(seen) != n {
+		t.Fatalf("SSCAN rounds=%d members=%d", rounds, len(seen))
+	}
+	seen, _ = scanAll(t, d, []string{"ZSCAN", "z"}, []string{"MATCH", "m1*"}, nil)
+	// ZSCAN 返回 member/score 交替：m1 与 m10..m19 与 m100..m199 与 m1000..m1999
+	members := 0
+	for k := range seen {
+		if k[0] == 'm' {
+			members++
+		}
+	}
+	if members != 1+10+100+1000 {
+		t.Fatalf("ZSCAN MATCH m1* members = %d", members)
+	}
+
+	if raw, ok := execArgs(d, "SSCAN", "missing", "0").(*resp.MultiRawReply); !ok ||
+		len(raw.Replies[1].(*resp.MultiBulkReply).Args) != 0 {
+		t.Fatalf("SSCAN on missing key should return empty result")
+	}
+}
+
+func TestScan_CollectionIndexMaintained(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		execArgs(d, "HSET", "h", fmt.Sprintf("f%d", i), "v")
+	}
+	index := func() *memberIndex {
+		v, _ := d.cache.Peek("h")
+		return v.(HashData).scan
+	}
+
+	// 遍历期间新增（HSET/HINCRBY）与删除字段：未删除的原有字段全部返回，索引与字段同步增删
+	deleted := make(map[string]bool)
+	seen, _ := scanAll(t, d, []string{"HSCAN", "h"}, []string{"COUNT", "50", "NOVALUES"}, func(round int) {
+		execArgs(d, "HSET", "h", fmt.Sprintf("new%d", round), "v")
+		execArgs(d, "HINCRBY", "h", fmt.Sprintf("cnt%d", round), "1")
+		f := fmt.Sprintf("f%d", n-round)
+		execArgs(d, "HDEL", "h", f)
+		deleted[f] = true
+	})
+	for i := 0; i < n; i++ {
+		f := fmt.Sprintf("f%d", i)
+		if !deleted[f] && seen[f] == 0 {
+			t.Fatalf("%s missing from HSCAN", f)
+		}
+	}
+	mi := index()
+	if mi.idx == nil {
+		t.Fatalf("large hash should keep its scan index")
+	}
+	if got, want := mi.idx.Len(), int(execArgs(d, "HLEN", "h").(*resp.IntReply).Code); got != want {
+		t.Fatalf("scan index has %d fields, hash has %d", got, want)
+	}
+
+	raw := execArgs(d, "HSCAN", "h", "0", "COUNT", "10").(*resp.MultiRawReply)
+	if got := len(raw.Replies[1].(*resp.MultiBulkReply).Args); got == 0 || got > 4*10*2 {
+		t.Fatalf("HSCAN COUNT 10 returned %d elements", got)
+	}
+	if index() != mi {
+		t.Fatalf("scan index should be reused across calls")
+	}
+
+	// 缩小到阈值以内后释放索引，遍历一次返回全部字段
+	fields := execArgs(d, "HKEYS", "h").(*resp.MultiBulkReply).Args
+	for _, f := range fields[collectionScanFullThreshold:] {
+		execArgs(d, "HDEL", "h", string(f))
+	}
+	if mi.idx != nil {
+		t.Fatalf("scan index should be released below the threshold")
+	}
+	raw = execArgs(d, "HSCAN", "h", "0", "NOVALUES").(*resp.MultiRawReply)
+	if c := string(raw.Replies[0].(*resp.BulkReply).Arg); c != "0" {
+		t.Fatalf("small HSCAN cursor = %s", c)
+	}
+	if got := len(raw.Replies[1].(*resp.MultiBulkReply).Args); got != collectionScanFullThreshold {
+		t.Fatalf("small HSCAN returned %d fields", got)
+	}
+}
@@ -0,0 +1,411 @@
+// Lua 脚本：EVAL / EVALSHA / SCRIPT LOAD|EXISTS|FLUSH，脚本在 Actor 内执行，天然原子。
+// 说明：解释器为仓库内的 pkg/lua（纯 Go 实现的 Lua 5.1 子集）；redis.call 直接调用 execInternal，不经过 channel。
+// 关键点：AOF 记录脚本产生的写命令（效果复制）而不是 EVAL 本身，多条写命令以 MULTI/EXEC 包裹，重放不依赖脚本缓存与执行时间。
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"myredis/module"
+	"myredis/pkg/lua"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现脚本命令：
+// - EVAL script numkeys [key ...] [arg ...]：编译（按 SHA1 缓存）并执行，KEYS/ARGV 为全局表
+// - EVALSHA sha1 numkeys ...：执行已缓存的脚本，不存在时返回 NOSCRIPT
+// - SCRIPT LOAD script / SCRIPT EXISTS sha1 [sha1 ...] / SCRIPT FLUSH [ASYNC|SYNC]
+//
+// 脚本内可用：redis.call / redis.pcall / redis.error_reply / redis.status_reply / redis.sha1hex / redis.log，
+// 以及 pkg/lua 提供的 base/string/table/math 子集。全局表被冻结：读取未定义的全局变量或创建全局变量都会报错。
+//
+// 类型转换与 Redis 一致：
+// - Redis -> Lua：整数 -> number，bulk -> string，nil -> false，数组 -> table，状态 -> {ok=...}，错误 -> {err=...}
+// - Lua -> Redis：number -> 整数（截断小数），string -> bulk，true -> 1，false/nil -> nil，
+//   {err=...} -> 错误，{ok=...} -> 状态，其它 table -> 数组（到第一个 nil 为止）
+//
+// 限制：
+// - 脚本缓存不持久化（与 Redis 一致，重启后需要重新 SCRIPT LOAD）
+// - 执行超过 scriptTimeLimit 的脚本会被终止（已执行的写命令不回滚），避免死循环永久占用 Actor
+// - 阻塞命令在脚本中按“立即超时”处理
+
+// scriptTimeLimit 需要小于 Exec 的 5s 安全超时，保证客户端收到的是脚本被终止的错误而不是超时。
+const scriptTimeLimit = 3 * time.Second
+
+var errScriptTimeout = errors.New("ERR Script killed: execution time exceeded the limit")
+
+// notAllowedInScript 为脚本中禁止调用的命令。
+var notAllowedInScript = map[string]struct{}{
+	"eval": {}, "evalsha": {}, "script": {},
+	"multi": {}, "exec": {}, "discard": {}, "watch": {}, "unwatch": {},
+	"config": {},
+}
+
+func scriptSHA(body string) string {
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadScript 编译并缓存脚本，返回 SHA1。
+func (db *StandaloneDB) loadScript(body string) (string, *lua.Proto, resp.Reply) {
+	sha := scriptSHA(body)
+	if p, ok := db.scripts[sha]; ok {
+		return sha, p, nil
+	}
+	p, err := lua.Compile(body, "user_script")
+	if err != nil {
+		return "", nil, resp.MakeErrReply("ERR Error compiling script (new function): " + err.Error())
+	}
+	db.scripts[sha] = p
+	return sha, p, nil
+}
+
+// EVAL script numkeys [key ...] [arg ...]
+func (db *StandaloneDB) eval(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'eval' command")
+	}
+	sha, p, errReply := db.loadScript(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	return db.runScript(sha, p, args[2:])
+}
+
+// EVALSHA sha1 numkeys [key ...] [arg ...]
+func (db *StandaloneDB) evalsha(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'evalsha' command")
+	}
+	sha := strings.ToLower(string(args[1]))
+	p, ok := db.scripts[sha]
+	if !ok {
+		return resp.MakeErrReply("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	return db.runScript(sha, p, args[2:])
+}
+
+// SCRIPT LOAD|EXISTS|FLUSH
+func (db *StandaloneDB) script(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'script' command")
+	}
+	sub := strings.ToLower(string(args[1]))
+	arityErr := resp.MakeErrReply("ERR wrong number of arguments for 'script|" + sub + "' command")
+	switch sub {
+	case "load":
+		if len(args) != 3 {
+			return arityErr
+		}
+		sha, _, errReply := db.loadScript(string(args[2]))
+		if errReply != nil {
+			return errReply
+		}
+		return resp.MakeBulkReply([]byte(sha))
+	case "exists":
+		if len(args) < 3 {
+			return arityErr
+		}
+		replies := make([]resp.Reply, 0, len(args)-2)
+		for _, arg := range args[2:] {
+			_, ok := db.scripts[strings.ToLower(string(arg))]
+			replies = append(replies, resp.MakeIntReply(boolInt(ok)))
+		}
+		return resp.MakeMultiRawReply(replies)
+	case "flush":
+		if len(args) > 3 {
+			return arityErr
+		}
+		if len(args) == 3 {
+			mode := strings.ToLower(string(args[2]))
+			if mode != "async" && mode != "sync" {
+				return resp.MakeErrReply("ERR SCRIPT FLUSH only support SYNC|ASYNC option")
+			}
+		}
+		db.scripts = make(map[string]*lua.Proto)
+		return resp.OkReply
+	}
+	return resp.MakeErrReply("ERR unknown subcommand '" + string(args[1]) + "'. Try SCRIPT HELP.")
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// runScript 执行脚本；rest 为 numkeys [key ...] [arg ...]。
+func (db *StandaloneDB) runScript(sha string, p *lua.Proto, rest [][]byte) resp.Reply {
+	numKeys, err := strconv.ParseInt(string(rest[0]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	if numKeys < 0 {
+		return resp.MakeErrReply("ERR Number of keys can't be negative")
+	}
+	if numKeys > int64(len(rest)-1) {
+		return resp.MakeErrReply("ERR Number of keys can't be greater than number of args")
+	}
+	keys := lua.NewTable(int(numKeys))
+	for _, k := range rest[1 : 1+numKeys] {
+		keys.Append(string(k))
+	}
+	argv := lua.NewTable(len(rest) - 1 - int(numKeys))
+	for _, a := range rest[1+numKeys:] {
+		argv.Append(string(a))
+	}
+
+	L := lua.NewState()
+	L.SetGlobal("KEYS", keys)
+	L.SetGlobal("ARGV", argv)
+	db.openRedisLib(L)
+	L.Freeze()
+	deadline := time.Now().Add(scriptTimeLimit)
+	L.Interrupt = func() error {
+		if time.Now().After(deadline) {
+			return errScriptTimeout
+		}
+		return nil
+	}
+
+	// 脚本内的写命令先缓存，结束后统一写 AOF（脚本在 MULTI 中执行时由外层事务统一包裹）
+	nested := db.inMulti
+	db.inMulti = true
+	rets, err := L.Call(L.Load(p))
+	// 淘汰记录已随各条写命令写入，避免外层 finish/execMulti 重复记录
+	db.evictedKeys = db.evictedKeys[:0]
+	if !nested {
+		db.inMulti = false
+		db.flushMultiAof()
+	}
+
+	if err != nil {
+		return scriptErrorReply(sha, L, err)
+	}
+	if len(rets) == 0 {
+		return resp.NullBulkReply
+	}
+	return luaToReply(rets[0])
+}
+
+// scriptErrorReply 把脚本执行错误转换为错误回复：redis.call 抛出的错误表原样返回，其它错误附带脚本位置。
+func scriptErrorReply(sha string, L *lua.State, err error) resp.Reply {
+	le, ok := err.(*lua.Error)
+	if !ok {
+		return resp.MakeErrReply(err.Error())
+	}
+	if t, ok := le.Value.(*lua.Table); ok {
+		if msg, ok := t.Get("err").(string); ok {
+			return resp.MakeErrReply(msg)
+		}
+	}
+	return resp.MakeErrReply("ERR " + le.Error() + " script: " + sha + ", on @user_script:" + strconv.Itoa(L.Line()) + ".")
+}
+
+// openRedisLib 注册脚本可用的 redis.* 函数。
+func (db *StandaloneDB) openRedisLib(L *lua.State) {
+	L.Register("redis", "call", func(L *lua.State, args []lua.Value) []lua.Value {
+		res := db.scriptCall(L, args)
+		if er, ok := res.(*resp.ErrorReply); ok {
+			L.Raise(errorTable(er.Status))
+		}
+		return []lua.Value{replyToLua(res)}
+	})
+	L.Register("redis", "pcall", func(L *lua.State, args []lua.Value) []lua.Value {
+		return []lua.Value{replyToLua(db.scriptCall(L, args))}
+	})
+	L.Register("redis", "error_reply", func(L *lua.State, args []lua.Value) []lua.Value {
+		msg, ok := scriptArg(args, 0)
+		if !ok {
+			L.Errorf("wrong number or type of arguments")
+		}
+		return []lua.Value{errorTable(msg)}
+	})
+	L.Register("redis", "status_reply", func(L *lua.State, args []lua.Value) []lua.Value {
+		msg, ok := scriptArg(args, 0)
+		if !ok {
+			L.Errorf("wrong number or type of arguments")
+		}
+		t := lua.NewTable(0)
+		t.Set("ok", msg)
+		return []lua.Value{t}
+	})
+	L.Register("redis", "sha1hex", func(L *lua.State, args []lua.Value) []lua.Value {
+		s, ok := scriptArg(args, 0)
+		if !ok || len(args) != 1 {
+			L.Errorf("wrong number of arguments")
+		}
+		return []lua.Value{scriptSHA(s)}
+	})
+	L.Register("redis", "log", func(L *lua.State, args []lua.Value) []lua.Value {
+		if len(args) < 2 {
+			L.Errorf("redis.log() requires two arguments or more.")
+		}
+		parts := make([]string, 0, len(args)-1)
+		for i := range args[1:] {
+			s, ok := scriptArg(args, i+1)
+			if !ok {
+				L.Errorf("Lua redis lib command arguments must be strings or integers")
+			}
+			parts = append(parts, s)
+		}
+		log.Printf("script log: %s", strings.Join(parts, " "))
+		return nil
+	})
+	lib := L.Globals.Get("redis").(*lua.Table)
+	for i, name := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		lib.Set(name, float64(i))
+	}
+}
+
+// scriptCall 执行 redis.call/redis.pcall 的命令，错误以 ErrorReply 返回（由调用方决定抛出还是返回）。
+func (db *StandaloneDB) scriptCall(L *lua.State, args []lua.Value) resp.Reply {
+	if len(args) == 0 {
+		return resp.MakeErrReply("ERR Please specify at least one argument for this redis lib call")
+	}
+	cmd := make([][]byte, 0, len(args))
+	for i := range args {
+		s, ok := scriptArg(args, i)
+		if !ok {
+			return resp.MakeErrReply("ERR Lua redis lib command arguments must be strings or integers")
+		}
+		cmd = append(cmd, []byte(s))
+	}
+	name := strings.ToLower(string(cmd[0]))
+	if _, ok := notAllowedInScript[name]; ok {
+		return resp.MakeErrReply("ERR This Redis command is not allowed from script")
+	}
+	if mc, ok := module.Lookup(name); ok && mc.Flags&module.FlagDenyScript != 0 {
+		return resp.MakeErrReply("ERR This Redis command is not allowed from script")
+	}
+
+	db.evictedKeys = db.evictedKeys[:0]
+	res := db.execInternal(cmd)
+	if br, ok := res.(*blockReply); ok {
+		res = br.onTimeout
+	} else if db.aofHandler != nil && !isError(res) {
+		db.logCommand(cmd, res)
+	}
+	return res
+}
+
+// scriptArg 把 redis.call 的参数转换为字符串（只接受 string 与 number）。
+func scriptArg(args []lua.Value, i int) (string, bool) {
+	if i >= len(args) {
+		return "", false
+	}
+	switch v := args[i].(type) {
+	case string:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e17 {
+			return strconv.FormatInt(int64(v), 10), true
+		}
+		return strconv.FormatFloat(v, 'g', 17, 64), true
+	}
+	return "", false
+}
+
+func errorTable(msg string) *lua.Table {
+	t := lua.NewTable(0)
+	t.Set("err", msg)
+	return t
+}
+
+// replyToLua 把命令回复转换为 Lua 值。
+func replyToLua(r resp.Reply) lua.Value {
+	switch r := r.(type) {
+	case *resp.IntReply:
+		return float64(r.Code)
+	case *resp.BulkReply:
+		if r.Arg == nil {
+			return false
+		}
+		return string(r.Arg)
+	case *resp.StatusReply:
+		t := lua.NewTable(0)
+		t.Set("ok", r.Status)
+		return t
+	case *resp.ErrorReply:
+		return errorTable(r.Status)
+	case *resp.MultiBulkReply:
+		if r.Args == nil {
+			return false
+		}
+		t := lua.NewTable(len(r.Args))
+		for i, a := range r.Args {
+			if a == nil {
+				t.Set(float64(i+1), false)
+			} else {
+				t.Set(float64(i+1), string(a))
+			}
+		}
+		return t
+	case *resp.MultiRawReply:
+		t := lua.NewTable(len(r.Replies))
+		for i, sub := range r.Replies {
+			t.Set(float64(i+1), replyToLua(sub))
+		}
+		return t
+	// RESP3 类型按 RESP2 下的形态转换，脚本看到的结果与协议版本无关
+	case *resp.MapReply:
+		return replyToLua(resp.MakeMultiRawReply(r.Pairs))
+	case *resp.SetReply:
+		return replyToLua(resp.MakeMultiBulkReply(r.Args))
+	case *resp.DoubleReply:
+		return resp.FormatDouble(r.Value)
+	case *resp.BoolReply:
+		if r.Value {
+			return float64(1)
+		}
+		return float64(0)
+	case *resp.BigNumberReply:
+		return r.Value
+	case *resp.VerbatimReply:
+		return string(r.Text)
+	case *resp.PushReply:
+		return replyToLua(resp.MakeMultiRawReply(r.Replies))
+	case *resp.AttributeReply:
+		return replyToLua(r.Reply)
+	}
+	return false
+}
+
+// luaToReply 把脚本返回值转换为命令回复。
+func luaToReply(v lua.Value) resp.Reply {
+	switch v := v.(type) {
+	case string:
+		return resp.MakeBulkReply([]byte(v))
+	case float64:
+		return resp.MakeIntReply(int64(v))
+	case bool:
+		if v {
+			return resp.MakeIntReply(1)
+		}
+		return resp.NullBulkReply
+	case *lua.Table:
+		if msg, ok := v.Get("err").(string); ok {
+			return resp.MakeErrReply(msg)
+		}
+		if msg, ok := v.Get("ok").(string); ok {
+			return resp.MakeStatusReply(msg)
+		}
+		replies := make([]resp.Reply, 0, v.Len())
+		for i := 1; ; i++ {
+			item := v.Get(float64(i))
+			if item == nil {
+				break
+			}
+			replies = append(replies, luaToReply(item))
+		}
+		return resp.MakeMultiRawReply(replies)
+	}
+	return resp.NullBulkReply
+}
@@ -0,0 +1,171 @@
+// 脚本测试：覆盖 EVAL/EVALSHA/SCRIPT 的缓存、redis.call 桥接、类型转换与错误传播。
+// 目标：保证 check-and-set 脚本在 Actor 内原子执行，且 AOF 只记录脚本产生的写命令（以 MULTI/EXEC 包裹）。
+// 覆盖：KEYS/ARGV、NOSCRIPT、call 与 pcall 的错误差异、禁止的命令、编译/运行期错误格式、AOF 重放。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const releaseLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+func TestScript_EvalAndCache(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "lock", "owner-a")
+	if r := execArgs(d, "EVAL", releaseLockScript, "1", "lock", "owner-b"); r.(*resp.IntReply).Code != 0 {
+		t.Fatalf("release by other owner = %+v", r)
+	}
+	if r := execArgs(d, "EVAL", releaseLockScript, "1", "lock", "owner-a"); r.(*resp.IntReply).Code != 1 {
+		t.Fatalf("release by owner = %+v", r)
+	}
+
+	sha := scriptSHA(releaseLockScript)
+	if r := execArgs(d, "SCRIPT", "EXISTS", sha, strings.Repeat("0", 40)); string(r.ToBytes()) != "*2\r\n:1\r\n:0\r\n" {
+		t.Fatalf("SCRIPT EXISTS = %q", r.ToBytes())
+	}
+	execArgs(d, "SET", "lock", "x")
+	if r := execArgs(d, "EVALSHA", strings.ToUpper(sha), "1", "lock", "x"); r.(*resp.IntReply).Code != 1 {
+		t.Fatalf("EVALSHA = %+v", r)
+	}
+
+	execArgs(d, "SCRIPT", "FLUSH")
+	if r := execArgs(d, "EVALSHA", sha, "0"); !strings.HasPrefix(string(r.ToBytes()), "-NOSCRIPT") {
+		t.Fatalf("EVALSHA after flush = %q", r.ToBytes())
+	}
+	r := execArgs(d, "SCRIPT", "LOAD", "return 'loaded'")
+	if got := execArgs(d, "EVALSHA", string(r.(*resp.BulkReply).Arg), "0"); string(got.ToBytes()) != "$6\r\nloaded\r\n" {
+		t.Fatalf("EVALSHA loaded = %q", got.ToBytes())
+	}
+
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"EVAL", "return 1", "-1"}, "-ERR Number of keys can't be negative\r\n"},
+		{[]string{"EVAL", "return 1", "2", "k"}, "-ERR Number of keys can't be greater than number of args\r\n"},
+		{[]string{"EVAL", "return 1", "x"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SCRIPT", "FLUSH", "LATER"}, "-ERR SCRIPT FLUSH only support SYNC|ASYNC option\r\n"},
+	} {
+		if got := string(execArgs(d, c.args...).ToBytes()); got != c.want {
+			t.Fatalf("%v = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestScript_Conversions(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "RPUSH", "l", "a", "b")
+	cases := []struct {
+		script string
+		want   string
+	}{
+		{"return {KEYS[1], ARGV[1], ARGV[2]}", "*3\r\n$1\r\nk\r\n$2\r\nv1\r\n$2\r\nv2\r\n"},
+		{"return 3.9", ":3\r\n"},
+		{"return true", ":1\r\n"},
+		{"return false", "$-1\r\n"},
+		{"return nil", "$-1\r\n"},
+		{"return {1, 2, nil, 4}", "*2\r\n:1\r\n:2\r\n"},
+		{"return redis.call('LRANGE', 'l', 0, -1)", "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{"return redis.call('SET', 'x', 1)", "+OK\r\n"},
+		{"return type(redis.call('GET', 'missing'))", "$7\r\nboolean\r\n"},
+		{"return redis.call('INCR', 'x') + 1", ":3\r\n"},
+		{"return redis.status_reply('PONG')", "+PONG\r\n"},
+		{"return redis.error_reply('MY custom')", "-MY custom\r\n"},
+		{"return redis.pcall('LPUSH', 'x', 'y')['err']", "$65\r\nWRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"return redis.sha1hex('')", "$40\r\nda39a3ee5e6b4b0d3255bfef95601890afd80709\r\n"},
+	}
+	for _, c := range cases {
+		if got := string(execArgs(d, "EVAL", c.script, "1", "k", "v1", "v2").ToBytes()); got != c.want {
+			t.Fatalf("%q = %q, want %q", c.script, got, c.want)
+		}
+	}
+}
+
+func TestScript_Errors(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "s", "str")
+	cases := []struct {
+		script string
+		want   string
+	}{
+		// redis.call 的错误原样返回给客户端
+		{"return redis.call('LPUSH', 's', 'x')", "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"},
+		{"return redis.call('MULTI')", "-ERR This Redis command is not allowed from script\r\n"},
+		{"return redis.call('GET', {})", "-ERR Lua redis lib command arguments must be strings or integers\r\n"},
+		{"return redis.call()", "-ERR Please specify at least one argument for this redis lib call\r\n"},
+		// 其它运行期错误附带脚本位置
+		{"local t = nil\nreturn t.x", "-ERR user_script:2: attempt to index local 't' (a nil value) script: "},
+		{"x = 1", "-ERR user_script:1: Attempt to modify a readonly table script: "},
+		{"return undefined_var", "-ERR user_script:1: Script attempted to access nonexistent global variable 'undefined_var' script: "},
+		{"return 1 +", "-ERR Error compiling script (new function): user_script:1: "},
+	}
+	for _, c := range cases {
+		if got := string(execArgs(d, "EVAL", c.script, "0").ToBytes()); !strings.HasPrefix(got, c.want) {
+			t.Fatalf("%q = %q, want prefix %q", c.script, got, c.want)
+		}
+	}
+
+	// pcall 捕获 redis.call 抛出的错误表，脚本可以继续执行
+	r := execArgs(d, "EVAL", "local ok, e = pcall(redis.call, 'INCR', 's') return {tostring(ok), e.err}", "0")
+	if got := string(r.ToBytes()); got != "*2\r\n$5\r\nfalse\r\n$43\r\nERR value is not an integer or out of range\r\n" {
+		t.Fatalf("pcall(redis.call) = %q", got)
+	}
+	// 出错前已执行的写命令不回滚
+	execArgs(d, "EVAL", "redis.call('SET', 'partial', '1') error('stop')", "0")
+	if got := execArgs(d, "GET", "partial").(*resp.BulkReply); string(got.Arg) != "1" {
+		t.Fatalf("partial = %q", got.Arg)
+	}
+}
+
+func TestScript_AofEffects(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "script.aof")
+
+	d1 := NewStandaloneDB(filename)
+	execArgs(d1, "EVAL", "return redis.call('SET', KEYS[1], ARGV[1])", "1", "single", "1")
+	execArgs(d1, "EVAL", "redis.call('INCRBY', KEYS[1], 5) redis.call('GET', KEYS[1]) return redis.call('RPUSH', KEYS[2], 'a', 'b')", "2", "n", "l")
+	execArgs(d1, "EVAL", "return redis.call('GET', 'single')", "0")
+	if err := d1.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	d1.Close()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof error: %v", err)
+	}
+	if bytes.Contains(bytes.ToUpper(data), []byte("EVAL")) || bytes.Contains(data, []byte("GET")) {
+		t.Fatalf("aof must contain only the script effects, got %q", data)
+	}
+	if bytes.Count(data, []byte("MULTI")) != 1 || bytes.Count(data, []byte("EXEC")) != 1 {
+		t.Fatalf("expected only the multi-write script wrapped in MULTI/EXEC, got %q", data)
+	}
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if got := execArgs(d2, "GET", "n").(*resp.BulkReply); string(got.Arg) != "5" {
+		t.Fatalf("replayed n = %q", got.Arg)
+	}
+	if got := execArgs(d2, "LLEN", "l").(*resp.IntReply); got.Code != 2 {
+		t.Fatalf("replayed LLEN = %d", got.Code)
+	}
+	if got := execArgs(d2, "GET", "single").(*resp.BulkReply); string(got.Arg) != "1" {
+		t.Fatalf("replayed single = %q", got.Arg)
+	}
+}
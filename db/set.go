@@ -1,33 +1,151 @@
-// Set 命令实现：SADD/SREM/SCARD/SMEMBERS 等。
+// Set 命令实现：SADD/SREM/SCARD/SMEMBERS、成员查询、集合运算（交/并/差）、SPOP/SRANDMEMBER/SMOVE。
 // 说明：该模块与 basic/list/hash 一样，依赖 Actor 串行执行保证并发安全。
-// 关键点：当集合元素删空导致 key 被移除时，要同步清理 TTL 并记录 AOF。
+// 关键点：当集合元素删空导致 key 被移除时，要同步清理 TTL；SPOP 的随机结果以 SREM 写入 AOF，保证重放确定。
 package db
 
 import (
+	"math"
+	"math/rand"
 	"myredis/resp"
-	"time"
+	"strconv"
+	"strings"
 )
 
-// 本文件实现 Set 相关命令：SADD / SREM / SCARD / SMEMBERS
+// 本文件实现 Set 相关命令：
+// - 写：SADD / SREM / SPOP / SMOVE / SINTERSTORE / SUNIONSTORE / SDIFFSTORE
+// - 读：SCARD / SMEMBERS / SISMEMBER / SMISMEMBER / SINTER / SUNION / SDIFF / SINTERCARD / SRANDMEMBER
 // 说明：
-// - SetData 使用 map[string]struct{}
+// - SetData 由 members（map[string]struct{}）+ SSCAN 游标索引组成，成员增删统一经过 add/remove
 // - TTL 由 db.ttlMap 管理；过期只做内存删除，不额外写 AOF（AOF 用 PEXPIREAT 保证重启一致性）
+// - 集合运算中不存在的 key 视为空集；任一 key 类型不符返回 WRONGTYPE
+// - *STORE 覆盖目标 key（无论原类型），结果为空时删除目标 key
 
-func (db *StandaloneDB) getSet(key string) (SetData, bool) {
-	val, ok := db.cache.Get(key)
+// --- SetData 基础操作 ---
+
+// has 返回 member 是否在集合中。
+func (d SetData) has(member string) bool {
+	_, ok := d.members[member]
+	return ok
+}
+
+// add 加入 member，返回是否为新增。
+func (d SetData) add(member string) bool {
+	if _, ok := d.members[member]; ok {
+		return false
+	}
+	d.members[member] = struct{}{}
+	d.scan.added(member)
+	return true
+}
+
+// remove 删除 member，返回是否存在。
+func (d SetData) remove(member string) bool {
+	if _, ok := d.members[member]; !ok {
+		return false
+	}
+	delete(d.members, member)
+	d.scan.removed(member)
+	return true
+}
+
+// Card 返回成员数。
+func (d SetData) Card() int {
+	return len(d.members)
+}
+
+// Members 返回全部成员（顺序不定）。
+func (d SetData) Members() [][]byte {
+	res := make([][]byte, 0, len(d.members))
+	for m := range d.members {
+		res = append(res, []byte(m))
+	}
+	return res
+}
+
+// SetOp 为集合运算类型（集群 Router 跨节点聚合时也会用到）。
+type SetOp int
+
+const (
+	SetInter SetOp = iota
+	SetUnion
+	SetDiff
+)
+
+// CombineSets 计算多个集合的交/并/差集，返回新集合（不修改输入）。
+func CombineSets(op SetOp, sets []SetData) SetData {
+	out := newSetData(0)
+	if len(sets) == 0 {
+		return out
+	}
+	switch op {
+	case SetInter:
+		// 从最小的集合出发逐个检查，任一集合为空时结果为空
+		smallest := 0
+		for i, s := range sets {
+			if s.Card() < sets[smallest].Card() {
+				smallest = i
+			}
+		}
+		for m := range sets[smallest].members {
+			inAll := true
+			for i, s := range sets {
+				if i == smallest {
+					continue
+				}
+				if !s.has(m) {
+					inAll = false
+					break
+				}
+			}
+			if inAll {
+				out.add(m)
+			}
+		}
+	case SetUnion:
+		for _, s := range sets {
+			for m := range s.members {
+				out.add(m)
+			}
+		}
+	case SetDiff:
+		for m := range sets[0].members {
+			found := false
+			for _, s := range sets[1:] {
+				if s.has(m) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				out.add(m)
+			}
+		}
+	}
+	return out
+}
+
+// getSet 读取 Set（含惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+func (db *StandaloneDB) getSet(key string) (SetData, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return SetData{}, false, nil
+	}
+	s, ok := entity.(SetData)
 	if !ok {
-		return nil, false
+		return SetData{}, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
 	}
+	return s, true, nil
+}
 
-	if expireTime, ok := db.ttlMap[key]; ok {
-		if time.Now().After(expireTime) {
+// storeSet 写回 Set：为空时删除 key（同时清理 TTL），否则刷新缓存中的大小统计。
+func (db *StandaloneDB) storeSet(key string, s SetData) {
+	if s.Card() == 0 {
+		if _, ok := db.cache.Peek(key); ok {
 			db.cache.Remove(key)
-			return nil, false
 		}
+		return
 	}
-
-	s, ok := val.(SetData)
-	return s, ok
+	db.cache.Add(key, s, 0)
 }
 
 // SADD key member [member ...]
@@ -37,124 +155,338 @@ func (db *StandaloneDB) sadd(args [][]byte) resp.Reply {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'sadd' command")
 	}
 	key := string(args[1])
-	members := args[2:]
 
-	s, ok := db.getSet(key)
-	if !ok {
-		// Check WRONGTYPE or expired
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(SetData); !ok {
-				// Check expiry here too?
-				if expireTime, ok := db.ttlMap[key]; ok {
-					if time.Now().After(expireTime) {
-						db.cache.Remove(key)
-						goto CreateNewSet
-					}
-				}
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
+	s, exists, errReply := db.getSet(key)
+	if errReply != nil {
+		return errReply
 	}
-
-CreateNewSet:
-	if s == nil {
-		s = make(SetData)
+	if !exists {
+		s = newSetData(len(args) - 2)
 	}
 
 	added := 0
-	for _, member := range members {
-		memberStr := string(member)
-		if _, exists := s[memberStr]; !exists {
-			s[memberStr] = struct{}{}
+	for _, member := range args[2:] {
+		if s.add(string(member)) {
 			added++
 		}
 	}
-
-	if added > 0 || len(s) == 0 { // Update cache even if no new added (to refresh LRU)?
-		// cache.Get already refreshes LRU.
-		// But s modified (size changed), so update size in cache.
+	if added > 0 {
+		// 集合大小变化，需要刷新缓存中的大小统计
 		db.cache.Add(key, s, 0)
 	}
-
 	return resp.MakeIntReply(int64(added))
 }
 
+// SREM key member [member ...]
 func (db *StandaloneDB) srem(args [][]byte) resp.Reply {
 	if len(args) < 3 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'srem' command")
 	}
 	key := string(args[1])
 
-	s, ok := db.getSet(key)
-	if !ok {
-		// Check WRONGTYPE
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(SetData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
+	s, exists, errReply := db.getSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
 		return resp.MakeIntReply(0)
 	}
 
 	removed := 0
-	members := args[2:]
-	for _, member := range members {
-		memberStr := string(member)
-		if _, exists := s[memberStr]; exists {
-			delete(s, memberStr)
+	for _, member := range args[2:] {
+		if s.remove(string(member)) {
 			removed++
 		}
 	}
-
-	if len(s) == 0 {
-		db.cache.Remove(key)
-	} else {
-		db.cache.Add(key, s, 0)
-	}
-
+	db.storeSet(key, s)
 	return resp.MakeIntReply(int64(removed))
 }
 
+// SCARD key
 func (db *StandaloneDB) scard(args [][]byte) resp.Reply {
 	if len(args) != 2 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'scard' command")
 	}
-	key := string(args[1])
-
-	s, ok := db.getSet(key)
-	if !ok {
-		// Check WRONGTYPE
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(SetData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
-		}
-		return resp.MakeIntReply(0)
+	s, _, errReply := db.getSet(string(args[1]))
+	if errReply != nil {
+		return errReply
 	}
-
-	return resp.MakeIntReply(int64(len(s)))
+	return resp.MakeIntReply(int64(s.Card()))
 }
 
+// SMEMBERS key
 func (db *StandaloneDB) smembers(args [][]byte) resp.Reply {
 	if len(args) != 2 {
 		return resp.MakeErrReply("ERR wrong number of arguments for 'smembers' command")
 	}
+	s, exists, errReply := db.getSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeSetBulkReply(nil)
+	}
+	return resp.MakeSetBulkReply(s.Members())
+}
+
+// SISMEMBER key member
+func (db *StandaloneDB) sismember(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'sismember' command")
+	}
+	s, _, errReply := db.getSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if s.has(string(args[2])) {
+		return resp.MakeIntReply(1)
+	}
+	return resp.MakeIntReply(0)
+}
+
+// SMISMEMBER key member [member ...]
+func (db *StandaloneDB) smismember(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'smismember' command")
+	}
+	s, _, errReply := db.getSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	replies := make([]resp.Reply, 0, len(args)-2)
+	for _, m := range args[2:] {
+		if s.has(string(m)) {
+			replies = append(replies, resp.MakeIntReply(1))
+		} else {
+			replies = append(replies, resp.MakeIntReply(0))
+		}
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// setOperands 读取集合运算的全部操作数：不存在的 key 视为空集。
+func (db *StandaloneDB) setOperands(keys [][]byte) ([]SetData, resp.Reply) {
+	sets := make([]SetData, 0, len(keys))
+	for _, k := range keys {
+		s, _, errReply := db.getSet(string(k))
+		if errReply != nil {
+			return nil, errReply
+		}
+		sets = append(sets, s)
+	}
+	return sets, nil
+}
+
+// SINTER / SUNION / SDIFF key [key ...]
+func (db *StandaloneDB) setAlgebra(args [][]byte, op SetOp) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + strings.ToLower(string(args[0])) + "' command")
+	}
+	sets, errReply := db.setOperands(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+	return resp.MakeSetBulkReply(CombineSets(op, sets).Members())
+}
+
+// SINTERSTORE / SUNIONSTORE / SDIFFSTORE destination key [key ...]
+func (db *StandaloneDB) setAlgebraStore(args [][]byte, op SetOp) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + strings.ToLower(string(args[0])) + "' command")
+	}
+	sets, errReply := db.setOperands(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	result := CombineSets(op, sets)
+
+	// 目标 key 被整体覆盖（包括类型与 TTL）
+	dst := string(args[1])
+	if _, ok := db.cache.Peek(dst); ok {
+		db.cache.Remove(dst)
+	}
+	if result.Card() > 0 {
+		db.cache.Add(dst, result, 0)
+	}
+	return resp.MakeIntReply(int64(result.Card()))
+}
+
+// SINTERCARD numkeys key [key ...] [LIMIT limit]
+func (db *StandaloneDB) sintercard(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'sintercard' command")
+	}
+	numKeys, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || numKeys <= 0 {
+		return resp.MakeErrReply("ERR numkeys should be greater than 0")
+	}
+	if numKeys > int64(len(args)-2) {
+		return resp.MakeErrReply("ERR Number of keys can't be greater than number of args")
+	}
+	keys := args[2 : 2+numKeys]
+	rest := args[2+numKeys:]
+	limit := int64(0)
+	if len(rest) > 0 {
+		if len(rest) != 2 || !strings.EqualFold(string(rest[0]), "limit") {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		limit, err = strconv.ParseInt(string(rest[1]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return resp.MakeErrReply("ERR LIMIT can't be negative")
+		}
+	}
+
+	sets, errReply := db.setOperands(keys)
+	if errReply != nil {
+		return errReply
+	}
+	n := int64(CombineSets(SetInter, sets).Card())
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	return resp.MakeIntReply(n)
+}
+
+// randomMembers 返回打乱顺序后的全部成员。
+func randomMembers(s SetData) []string {
+	members := make([]string, 0, s.Card())
+	for m := range s.members {
+		members = append(members, m)
+	}
+	rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+	return members
+}
+
+// SPOP key [count]
+func (db *StandaloneDB) spop(args [][]byte) resp.Reply {
+	if len(args) != 2 && len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'spop' command")
+	}
+	withCount := len(args) == 3
+	count := int64(1)
+	if withCount {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil || n < 0 {
+			return resp.MakeErrReply("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
 	key := string(args[1])
+	s, exists, errReply := db.getSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		if withCount {
+			return resp.MakeMultiBulkReply([][]byte{})
+		}
+		return resp.NullBulkReply
+	}
 
-	s, ok := db.getSet(key)
-	if !ok {
-		// Check WRONGTYPE
-		if val, exists := db.cache.Get(key); exists {
-			if _, ok := val.(SetData); !ok {
-				return resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
-			}
+	popped := make([][]byte, 0)
+	for _, m := range randomMembers(s) {
+		if int64(len(popped)) >= count {
+			break
+		}
+		s.remove(m)
+		popped = append(popped, []byte(m))
+	}
+	db.storeSet(key, s)
+
+	if withCount {
+		return resp.MakeMultiBulkReply(popped)
+	}
+	return resp.MakeBulkReply(popped[0])
+}
+
+// SRANDMEMBER key [count]
+// - 不带 count：返回一个随机成员（key 不存在返回 nil）
+// - count > 0：返回最多 count 个互不相同的成员
+// - count < 0：返回 |count| 个成员，允许重复
+func (db *StandaloneDB) srandmember(args [][]byte) resp.Reply {
+	if len(args) != 2 && len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'srandmember' command")
+	}
+	withCount := len(args) == 3
+	var count int64
+	if withCount {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		if n == math.MinInt64 {
+			return resp.MakeErrReply("ERR value is out of range")
+		}
+		count = n
+	}
+	s, exists, errReply := db.getSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !withCount {
+		if !exists {
+			return resp.NullBulkReply
 		}
-		return resp.MakeMultiBulkReply(nil)
+		for m := range s.members {
+			return resp.MakeBulkReply([]byte(m))
+		}
+	}
+	res := make([][]byte, 0)
+	if !exists || count == 0 {
+		return resp.MakeMultiBulkReply(res)
 	}
 
-	res := make([][]byte, 0, len(s))
-	for k := range s {
-		res = append(res, []byte(k))
+	members := randomMembers(s)
+	if count < 0 {
+		for i := int64(0); i < -count; i++ {
+			res = append(res, []byte(members[rand.Intn(len(members))]))
+		}
+		return resp.MakeMultiBulkReply(res)
+	}
+	if count > int64(len(members)) {
+		count = int64(len(members))
+	}
+	for _, m := range members[:count] {
+		res = append(res, []byte(m))
 	}
 	return resp.MakeMultiBulkReply(res)
 }
+
+// SMOVE source destination member
+func (db *StandaloneDB) smove(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'smove' command")
+	}
+	src, dst, member := string(args[1]), string(args[2]), string(args[3])
+
+	srcSet, srcExists, errReply := db.getSet(src)
+	if errReply != nil {
+		return errReply
+	}
+	// 先检查目标类型，避免从 src 删除后才发现无法写入
+	dstSet, dstExists, errReply := db.getSet(dst)
+	if errReply != nil {
+		return errReply
+	}
+	if !srcExists {
+		return resp.MakeIntReply(0)
+	}
+	if !srcSet.has(member) {
+		return resp.MakeIntReply(0)
+	}
+	if src == dst {
+		return resp.MakeIntReply(1)
+	}
+
+	srcSet.remove(member)
+	db.storeSet(src, srcSet)
+	if !dstExists {
+		dstSet = newSetData(1)
+	}
+	dstSet.add(member)
+	db.cache.Add(dst, dstSet, 0)
+	return resp.MakeIntReply(1)
+}
@@ -0,0 +1,130 @@
+// Set 扩展命令测试：覆盖成员查询、交/并/差集及 STORE 变体、SINTERCARD 的 LIMIT、SPOP/SRANDMEMBER/SMOVE。
+// 目标：保证不存在的 key 视为空集、类型不符返回 WRONGTYPE，且 STORE 结果为空时删除目标 key。
+// 覆盖：SPOP 随机结果以 SREM 写入 AOF，重放后与内存状态一致。
+package db
+
+import (
+	"myredis/resp"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sortedMembers(t *testing.T, r resp.Reply) string {
+	t.Helper()
+	members := replyStrings(t, r)
+	sort.Strings(members)
+	return strings.Join(members, ",")
+}
+
+func TestSet_MembershipAndAlgebra(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SADD", "s1", "a", "b", "c", "d")
+	execArgs(d, "SADD", "s2", "c", "d", "e")
+	execArgs(d, "SADD", "s3", "d", "f")
+
+	if n := replyInt(t, execArgs(d, "SISMEMBER", "s1", "a")); n != 1 {
+		t.Fatalf("SISMEMBER = %d", n)
+	}
+	raw := execArgs(d, "SMISMEMBER", "s1", "a", "x").(*resp.MultiRawReply)
+	if len(raw.Replies) != 2 || raw.Replies[0].(*resp.IntReply).Code != 1 || raw.Replies[1].(*resp.IntReply).Code != 0 {
+		t.Fatalf("SMISMEMBER = %+v", raw.Replies)
+	}
+
+	if got := sortedMembers(t, execArgs(d, "SINTER", "s1", "s2", "s3")); got != "d" {
+		t.Fatalf("SINTER = %s", got)
+	}
+	if got := sortedMembers(t, execArgs(d, "SUNION", "s2", "s3", "missing")); got != "c,d,e,f" {
+		t.Fatalf("SUNION = %s", got)
+	}
+	if got := sortedMembers(t, execArgs(d, "SDIFF", "s1", "s2")); got != "a,b" {
+		t.Fatalf("SDIFF = %s", got)
+	}
+	if got := sortedMembers(t, execArgs(d, "SINTER", "s1", "missing")); got != "" {
+		t.Fatalf("SINTER with missing key = %s", got)
+	}
+
+	if n := replyInt(t, execArgs(d, "SINTERCARD", "2", "s1", "s2")); n != 2 {
+		t.Fatalf("SINTERCARD = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SINTERCARD", "2", "s1", "s2", "LIMIT", "1")); n != 1 {
+		t.Fatalf("SINTERCARD LIMIT = %d", n)
+	}
+	if er, ok := execArgs(d, "SINTERCARD", "3", "s1", "s2").(*resp.ErrorReply); !ok || er.Status != "ERR Number of keys can't be greater than number of args" {
+		t.Fatalf("SINTERCARD numkeys = %+v", er)
+	}
+
+	// STORE 覆盖目标 key（包括其它类型），结果为空时删除
+	execArgs(d, "SET", "dst", "string")
+	if n := replyInt(t, execArgs(d, "SUNIONSTORE", "dst", "s1", "s3")); n != 5 {
+		t.Fatalf("SUNIONSTORE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SCARD", "dst")); n != 5 {
+		t.Fatalf("SCARD dst = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SDIFFSTORE", "dst", "s3", "s1")); n != 1 {
+		t.Fatalf("SDIFFSTORE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SINTERSTORE", "dst", "s1", "missing")); n != 0 {
+		t.Fatalf("SINTERSTORE empty = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "dst")); n != 0 {
+		t.Fatalf("empty SINTERSTORE should delete dst")
+	}
+
+	execArgs(d, "SET", "str", "v")
+	if _, ok := execArgs(d, "SINTER", "s1", "str").(*resp.ErrorReply); !ok {
+		t.Fatalf("SINTER with string operand should be WRONGTYPE")
+	}
+}
+
+func TestSet_PopMoveAndReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	execArgs(d, "SADD", "s", "a", "b", "c", "d", "e")
+	popped := replyStrings(t, execArgs(d, "SPOP", "s", "2"))
+	if len(popped) != 2 {
+		t.Fatalf("SPOP count = %v", popped)
+	}
+	if br := execArgs(d, "SPOP", "s").(*resp.BulkReply); br.Arg == nil {
+		t.Fatalf("SPOP should return a member")
+	}
+	if got := replyStrings(t, execArgs(d, "SPOP", "missing", "3")); len(got) != 0 {
+		t.Fatalf("SPOP missing key = %v", got)
+	}
+
+	if got := replyStrings(t, execArgs(d, "SRANDMEMBER", "s", "10")); len(got) != 2 {
+		t.Fatalf("SRANDMEMBER 10 = %v", got)
+	}
+	if got := replyStrings(t, execArgs(d, "SRANDMEMBER", "s", "-4")); len(got) != 4 {
+		t.Fatalf("SRANDMEMBER -4 = %v", got)
+	}
+
+	remaining := replyStrings(t, execArgs(d, "SMEMBERS", "s"))
+	if n := replyInt(t, execArgs(d, "SMOVE", "s", "other", remaining[0])); n != 1 {
+		t.Fatalf("SMOVE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "SMOVE", "s", "other", "nope")); n != 0 {
+		t.Fatalf("SMOVE missing member = %d", n)
+	}
+	execArgs(d, "SET", "str", "v")
+	if _, ok := execArgs(d, "SMOVE", "s", "str", remaining[1]).(*resp.ErrorReply); !ok {
+		t.Fatalf("SMOVE to string should be WRONGTYPE")
+	}
+	want := sortedMembers(t, execArgs(d, "SMEMBERS", "s"))
+	d.Close()
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if got := sortedMembers(t, execArgs(d2, "SMEMBERS", "s")); got != want {
+		t.Fatalf("s after replay = %s, want %s", got, want)
+	}
+	if got := sortedMembers(t, execArgs(d2, "SMEMBERS", "other")); got != remaining[0] {
+		t.Fatalf("other after replay = %s", got)
+	}
+}
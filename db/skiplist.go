@@ -0,0 +1,332 @@
+// 跳表实现：作为有序集合（ZSET）的排序索引。
+// 关键点：按 (score, member) 排序，层级 span 记录跨度，支持 O(logN) 的按排名定位与排名查询。
+// 说明：结构参考 Redis 的 zskiplist，但只保留本项目命令所需的操作，不做并发保护（由 Actor 串行调用）。
+package db
+
+import (
+	"math/rand"
+)
+
+// 本文件实现 ZSET 使用的跳表：
+// - insert / remove / updateScore：维护 (score, member) 有序性
+// - getRank / getByRank：基于 span 的排名计算（rank 从 1 开始）
+// - firstInScoreRange / lastInScoreRange / firstInLexRange / lastInLexRange：区间查询的起点定位
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int64 // 到 forward 节点跨越的节点数，用于计算排名
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int64
+	level  int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// lessThan 判断节点是否排在 (score, member) 之前：先比 score，相同时按 member 字典序。
+func (n *skiplistNode) lessThan(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert 插入新节点。调用方需保证 member 不存在（由 ZSetData.dict 保证）。
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int64
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i == sl.level-1 {
+			rank[i] = 0
+		} else {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.lessThan(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].level[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = (rank[0] - rank[i]) + 1
+	}
+	// 未触及的高层跨度 +1
+	for i := level; i < sl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+	return x
+}
+
+func (sl *skiplist) removeNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < sl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+	for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// remove 删除 (score, member) 对应的节点，返回是否找到。
+func (sl *skiplist) remove(score float64, member string) bool {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.lessThan(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x != nil && x.score == score && x.member == member {
+		sl.removeNode(x, update)
+		return true
+	}
+	return false
+}
+
+// updateScore 更新 member 的分值。位置不变时原地修改，否则删除后重新插入。
+func (sl *skiplist) updateScore(curScore float64, member string, newScore float64) {
+	update := make([]*skiplistNode, skiplistMaxLevel)
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.lessThan(curScore, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.score != curScore || x.member != member {
+		return
+	}
+
+	prevOK := x.backward == nil || x.backward.lessThan(newScore, member)
+	nextOK := x.level[0].forward == nil || !x.level[0].forward.lessThan(newScore, member)
+	if prevOK && nextOK {
+		x.score = newScore
+		return
+	}
+	sl.removeNode(x, update)
+	sl.insert(newScore, member)
+}
+
+// getRank 返回 (score, member) 的排名（从 1 开始），不存在返回 0。
+func (sl *skiplist) getRank(score float64, member string) int64 {
+	var rank int64
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.lessThan(score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != sl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// getByRank 返回指定排名（从 1 开始）的节点。
+func (sl *skiplist) getByRank(rank int64) *skiplistNode {
+	if rank <= 0 || rank > sl.length {
+		return nil
+	}
+	var traversed int64
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// firstInScoreRange 返回落在区间内的第一个节点。
+func (sl *skiplist) firstInScoreRange(r *scoreRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+// lastInScoreRange 返回落在区间内的最后一个节点。
+func (sl *skiplist) lastInScoreRange(r *scoreRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	if x == sl.header || !r.aboveMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+// firstInLexRange 返回落在字典序区间内的第一个节点（要求所有 score 相同）。
+func (sl *skiplist) firstInLexRange(r *lexRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.member) {
+		return nil
+	}
+	return x
+}
+
+// lastInLexRange 返回落在字典序区间内的最后一个节点。
+func (sl *skiplist) lastInLexRange(r *lexRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.belowMax(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	if x == sl.header || !r.aboveMin(x.member) {
+		return nil
+	}
+	return x
+}
+
+// --- 区间定义 ---
+
+// scoreRange 表示分值区间，minEx/maxEx 表示开区间端点（"(" 前缀）。
+type scoreRange struct {
+	min, max     float64
+	minEx, maxEx bool
+}
+
+func (r *scoreRange) aboveMin(v float64) bool {
+	if r.minEx {
+		return v > r.min
+	}
+	return v >= r.min
+}
+
+func (r *scoreRange) belowMax(v float64) bool {
+	if r.maxEx {
+		return v < r.max
+	}
+	return v <= r.max
+}
+
+func (r *scoreRange) empty() bool {
+	return r.min > r.max || (r.min == r.max && (r.minEx || r.maxEx))
+}
+
+// lexRange 表示字典序区间；minInf/maxInf 对应 "-" / "+"。
+type lexRange struct {
+	min, max       string
+	minEx, maxEx   bool
+	minInf, maxInf bool // minInf: 负无穷；maxInf: 正无穷
+	none           bool // 形如 min="+" 或 max="-" 的区间恒为空
+}
+
+func (r *lexRange) aboveMin(v string) bool {
+	if r.minInf {
+		return true
+	}
+	if r.minEx {
+		return v > r.min
+	}
+	return v >= r.min
+}
+
+func (r *lexRange) belowMax(v string) bool {
+	if r.maxInf {
+		return true
+	}
+	if r.maxEx {
+		return v < r.max
+	}
+	return v <= r.max
+}
+
+func (r *lexRange) empty() bool {
+	if r.none {
+		return true
+	}
+	if r.minInf || r.maxInf {
+		return false
+	}
+	return r.min > r.max || (r.min == r.max && (r.minEx || r.maxEx))
+}
@@ -8,6 +8,8 @@ package db
 import (
 	"container/list"
 	"errors"
+	"log"
+	"myredis/module"
 	"myredis/pkg/lru"
 	"myredis/rdb"
 	"sort"
@@ -65,19 +67,27 @@ func (db *StandaloneDB) snapshotEntries() ([]rdb.Entry, error) {
 				List:           out,
 			})
 		case HashData:
-			h := make(map[string][]byte, len(v))
-			for fk, fv := range v {
+			h := make(map[string][]byte, len(v.fields))
+			for fk, fv := range v.fields {
 				h[fk] = append([]byte(nil), fv...)
 			}
+			var hexp map[string]int64
+			if len(v.expires) > 0 {
+				hexp = make(map[string]int64, len(v.expires))
+				for fk, at := range v.expires {
+					hexp[fk] = at
+				}
+			}
 			entries = append(entries, rdb.Entry{
 				Key:            key,
 				Type:           rdb.TypeHash,
 				ExpireAtUnixMs: expireAtMs,
 				Hash:           h,
+				HashExpire:     hexp,
 			})
 		case SetData:
-			members := make([]string, 0, len(v))
-			for m := range v {
+			members := make([]string, 0, v.Card())
+			for m := range v.members {
 				members = append(members, m)
 			}
 			sort.Strings(members)
@@ -87,6 +97,38 @@ func (db *StandaloneDB) snapshotEntries() ([]rdb.Entry, error) {
 				ExpireAtUnixMs: expireAtMs,
 				Set:            members,
 			})
+		case ZSetData:
+			// 跳表按 (score, member) 有序遍历，输出天然稳定。
+			members := make([]rdb.ZSetMember, 0, v.length())
+			for x := v.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+				members = append(members, rdb.ZSetMember{Member: x.member, Score: x.score})
+			}
+			entries = append(entries, rdb.Entry{
+				Key:            key,
+				Type:           rdb.TypeZSet,
+				ExpireAtUnixMs: expireAtMs,
+				ZSet:           members,
+			})
+		case *StreamData:
+			entries = append(entries, rdb.Entry{
+				Key:            key,
+				Type:           rdb.TypeStream,
+				ExpireAtUnixMs: expireAtMs,
+				Stream:         streamToRDB(v),
+			})
+		case ModuleData:
+			data, err := v.typ.Encode(v.v)
+			if err != nil {
+				snapErr = errors.New("encode module type '" + v.typ.Name + "': " + err.Error())
+				return false
+			}
+			entries = append(entries, rdb.Entry{
+				Key:            key,
+				Type:           rdb.TypeModule,
+				ExpireAtUnixMs: expireAtMs,
+				ModuleType:     v.typ.Name,
+				Module:         data,
+			})
 		default:
 			// 未知类型：为了可定位，直接中止快照。
 			snapErr = errors.New("unknown value type in snapshot")
@@ -121,6 +163,7 @@ func (db *StandaloneDB) applySnapshot(entries []rdb.Entry) {
 
 	// 重新初始化 ttlMap（避免残留）。
 	db.ttlMap = make(map[string]time.Time, len(entries))
+	db.hashTTLKeys = make(map[string]struct{})
 
 	for _, e := range entries {
 		// 跳过已过期条目
@@ -138,17 +181,55 @@ func (db *StandaloneDB) applySnapshot(entries []rdb.Entry) {
 			}
 			db.cache.Add(e.Key, ListData{L: l}, 0)
 		case rdb.TypeHash:
-			h := make(HashData, len(e.Hash))
+			// 跳过已过期字段；字段全部过期时整个 key 都不恢复
+			h := newHashData()
 			for fk, fv := range e.Hash {
-				h[fk] = append([]byte(nil), fv...)
+				at, hasTTL := e.HashExpire[fk]
+				if hasTTL && at <= nowMs {
+					continue
+				}
+				h.fields[fk] = append([]byte(nil), fv...)
+				if hasTTL {
+					h.expires[fk] = at
+				}
+			}
+			if len(h.fields) == 0 {
+				continue
 			}
 			db.cache.Add(e.Key, h, 0)
+			if len(h.expires) > 0 {
+				db.hashTTLKeys[e.Key] = struct{}{}
+			}
 		case rdb.TypeSet:
-			s := make(SetData, len(e.Set))
+			s := newSetData(len(e.Set))
 			for _, m := range e.Set {
-				s[m] = struct{}{}
+				s.add(m)
 			}
 			db.cache.Add(e.Key, s, 0)
+		case rdb.TypeZSet:
+			zs := newZSetData()
+			for _, zm := range e.ZSet {
+				zs.add(zm.Member, zm.Score)
+			}
+			db.cache.Add(e.Key, zs, 0)
+		case rdb.TypeStream:
+			if e.Stream == nil {
+				continue
+			}
+			db.cache.Add(e.Key, streamFromRDB(e.Stream), 0)
+		case rdb.TypeModule:
+			// 模块需要在加载前注册；找不到类型或解码失败时跳过该 key 并记录日志
+			t, ok := module.LookupType(e.ModuleType)
+			if !ok {
+				log.Printf("rdb: skip key %q: module type %q is not registered", e.Key, e.ModuleType)
+				continue
+			}
+			v, err := t.Decode(e.Module)
+			if err != nil {
+				log.Printf("rdb: skip key %q: decode module type %q: %v", e.Key, e.ModuleType, err)
+				continue
+			}
+			db.cache.Add(e.Key, ModuleData{typ: t, v: v}, 0)
 		default:
 			// 未知类型跳过（防御），避免启动直接崩溃。
 			continue
@@ -168,3 +249,68 @@ func (db *StandaloneDB) purgeExpiredAll(now time.Time) {
 		}
 	}
 }
+
+// streamToRDB 把 Stream 转为快照结构（条目的 field/value 写入后不会被修改，可以直接共享）。
+func streamToRDB(s *StreamData) *rdb.Stream {
+	out := &rdb.Stream{
+		Entries:      make([]rdb.StreamEntry, 0, len(s.entries)),
+		LastID:       rdb.StreamID{Ms: s.lastID.ms, Seq: s.lastID.seq},
+		MaxDeletedID: rdb.StreamID{Ms: s.maxDeletedID.ms, Seq: s.maxDeletedID.seq},
+		EntriesAdded: s.entriesAdded,
+		Groups:       make([]rdb.StreamGroup, 0, len(s.groups)),
+	}
+	for _, e := range s.entries {
+		out.Entries = append(out.Entries, rdb.StreamEntry{ID: rdb.StreamID{Ms: e.id.ms, Seq: e.id.seq}, Fields: e.fields})
+	}
+	for name, g := range s.groups {
+		rg := rdb.StreamGroup{
+			Name:      name,
+			LastID:    rdb.StreamID{Ms: g.lastID.ms, Seq: g.lastID.seq},
+			Pending:   make([]rdb.StreamPending, 0, len(g.pel)),
+			Consumers: make([]rdb.StreamConsumer, 0, len(g.consumers)),
+		}
+		for _, nack := range g.pel {
+			rg.Pending = append(rg.Pending, rdb.StreamPending{
+				ID:            rdb.StreamID{Ms: nack.id.ms, Seq: nack.id.seq},
+				Consumer:      nack.consumer,
+				DeliveryTime:  nack.deliveryTime,
+				DeliveryCount: nack.deliveryCount,
+			})
+		}
+		for cname, c := range g.consumers {
+			rg.Consumers = append(rg.Consumers, rdb.StreamConsumer{Name: cname, SeenTime: c.seenTime})
+		}
+		sort.Slice(rg.Consumers, func(i, j int) bool { return rg.Consumers[i].Name < rg.Consumers[j].Name })
+		out.Groups = append(out.Groups, rg)
+	}
+	sort.Slice(out.Groups, func(i, j int) bool { return out.Groups[i].Name < out.Groups[j].Name })
+	return out
+}
+
+// streamFromRDB 从快照结构恢复 Stream。
+func streamFromRDB(rs *rdb.Stream) *StreamData {
+	s := newStreamData()
+	s.lastID = streamID{rs.LastID.Ms, rs.LastID.Seq}
+	s.maxDeletedID = streamID{rs.MaxDeletedID.Ms, rs.MaxDeletedID.Seq}
+	s.entriesAdded = rs.EntriesAdded
+	s.entries = make([]streamEntry, 0, len(rs.Entries))
+	for _, e := range rs.Entries {
+		s.entries = append(s.entries, streamEntry{id: streamID{e.ID.Ms, e.ID.Seq}, fields: e.Fields})
+	}
+	for _, rg := range rs.Groups {
+		g := newStreamGroup(streamID{rg.LastID.Ms, rg.LastID.Seq})
+		for _, p := range rg.Pending {
+			g.addNACK(&streamNACK{
+				id:            streamID{p.ID.Ms, p.ID.Seq},
+				consumer:      p.Consumer,
+				deliveryTime:  p.DeliveryTime,
+				deliveryCount: p.DeliveryCount,
+			})
+		}
+		for _, c := range rg.Consumers {
+			g.consumers[c.Name] = &streamConsumer{seenTime: c.SeenTime}
+		}
+		s.groups[rg.Name] = g
+	}
+	return s
+}
@@ -0,0 +1,737 @@
+// Stream 命令实现：XADD / XRANGE / XREVRANGE / XLEN / XTRIM / XSETID / XREAD。
+// 说明：条目按 ID（毫秒时间戳-序号）严格递增保存在切片中，追加 O(1)，按 ID 定位与范围查询二分查找；消费者组见 stream_group.go。
+// 关键点：自动生成的 ID 以 AOF 记录实际值（重放不依赖时钟）；XREAD BLOCK 复用阻塞队列，挂起时把 $ 固定为当时的最后 ID。
+package db
+
+import (
+	"math"
+	"myredis/resp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现 Stream 基础命令：
+// - XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]
+// - XRANGE key start end [COUNT count] / XREVRANGE key end start [COUNT count]（支持 - + 与 "(" 开区间）
+// - XLEN key / XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
+// - XSETID key last-id [ENTRIESADDED n] [MAXDELETEDID id]（主要供 AOF 重写恢复 last-id）
+// - XREAD [COUNT count] [BLOCK ms] STREAMS key [key ...] id [id ...]
+//
+// 说明：
+// - 删空的 Stream 不会被删除（与 Redis 一致），last-id 保留，保证后续 ID 单调递增
+// - "~" 近似裁剪按精确裁剪处理（结果满足“至少保留 threshold”的约定），LIMIT 限制单次裁剪的条目数
+
+// streamID 为 Stream 条目 ID：<毫秒时间戳>-<序号>。
+type streamID struct {
+	ms, seq uint64
+}
+
+var maxStreamID = streamID{ms: math.MaxUint64, seq: math.MaxUint64}
+
+func (a streamID) less(b streamID) bool {
+	return a.ms < b.ms || (a.ms == b.ms && a.seq < b.seq)
+}
+
+func (a streamID) isZero() bool {
+	return a.ms == 0 && a.seq == 0
+}
+
+func (a streamID) String() string {
+	return strconv.FormatUint(a.ms, 10) + "-" + strconv.FormatUint(a.seq, 10)
+}
+
+// incr 返回下一个 ID；已是最大 ID 时 ok=false。
+func (a streamID) incr() (streamID, bool) {
+	if a.seq < math.MaxUint64 {
+		return streamID{a.ms, a.seq + 1}, true
+	}
+	if a.ms < math.MaxUint64 {
+		return streamID{a.ms + 1, 0}, true
+	}
+	return a, false
+}
+
+// decr 返回上一个 ID；已是 0-0 时 ok=false。
+func (a streamID) decr() (streamID, bool) {
+	if a.seq > 0 {
+		return streamID{a.ms, a.seq - 1}, true
+	}
+	if a.ms > 0 {
+		return streamID{a.ms - 1, math.MaxUint64}, true
+	}
+	return a, false
+}
+
+// streamEntry 为一条消息：fields 为扁平的 field/value 序列。
+type streamEntry struct {
+	id     streamID
+	fields [][]byte
+}
+
+var errInvalidStreamID = resp.MakeErrReply("ERR Invalid stream ID specified as stream command argument")
+
+// parseStreamID 解析 "ms-seq" 或 "ms"（省略序号时取 missingSeq）。
+func parseStreamID(b []byte, missingSeq uint64) (streamID, bool) {
+	s := string(b)
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	if !hasSeq {
+		return streamID{ms, missingSeq}, true
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	return streamID{ms, seq}, true
+}
+
+// parseRangeID 解析区间端点：- / + / "(" 开区间 / 完整或省略序号的 ID。
+func parseRangeID(b []byte, isStart bool) (streamID, resp.Reply) {
+	switch string(b) {
+	case "-":
+		return streamID{}, nil
+	case "+":
+		return maxStreamID, nil
+	}
+	missingSeq := uint64(0)
+	if !isStart {
+		missingSeq = math.MaxUint64
+	}
+	exclusive := len(b) > 0 && b[0] == '('
+	if exclusive {
+		b = b[1:]
+	}
+	id, ok := parseStreamID(b, missingSeq)
+	if !ok {
+		return streamID{}, errInvalidStreamID
+	}
+	if !exclusive {
+		return id, nil
+	}
+	if isStart {
+		if id, ok = id.incr(); !ok {
+			return streamID{}, resp.MakeErrReply("ERR invalid start ID for the interval")
+		}
+		return id, nil
+	}
+	if id, ok = id.decr(); !ok {
+		return streamID{}, resp.MakeErrReply("ERR invalid end ID for the interval")
+	}
+	return id, nil
+}
+
+// --- StreamData 基础操作 ---
+
+// search 返回第一个 ID >= id 的条目下标。
+func (s *StreamData) search(id streamID) int {
+	return sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].id.less(id) })
+}
+
+// lookup 按 ID 查找条目。
+func (s *StreamData) lookup(id streamID) (streamEntry, bool) {
+	i := s.search(id)
+	if i < len(s.entries) && s.entries[i].id == id {
+		return s.entries[i], true
+	}
+	return streamEntry{}, false
+}
+
+// rangeEntries 返回 [start, end] 内的条目；count <= 0 表示不限制，rev 为倒序。
+func (s *StreamData) rangeEntries(start, end streamID, count int64, rev bool) []streamEntry {
+	if end.less(start) {
+		return nil
+	}
+	lo := s.search(start)
+	hi := sort.Search(len(s.entries), func(i int) bool { return end.less(s.entries[i].id) })
+	n := int64(hi - lo)
+	if n <= 0 {
+		return nil
+	}
+	if count > 0 && n > count {
+		n = count
+	}
+	out := make([]streamEntry, 0, n)
+	if rev {
+		for i := hi - 1; int64(len(out)) < n; i-- {
+			out = append(out, s.entries[i])
+		}
+		return out
+	}
+	return append(out, s.entries[lo:lo+int(n)]...)
+}
+
+// after 返回 ID 严格大于 id 的前 count 个条目（count <= 0 表示不限制）。
+func (s *StreamData) after(id streamID, count int64) []streamEntry {
+	next, ok := id.incr()
+	if !ok {
+		return nil
+	}
+	return s.rangeEntries(next, maxStreamID, count, false)
+}
+
+// topID 返回最后一个条目的 ID（Stream 为空时返回 0-0）。
+func (s *StreamData) topID() streamID {
+	if len(s.entries) == 0 {
+		return streamID{}
+	}
+	return s.entries[len(s.entries)-1].id
+}
+
+// streamTrimSpec 为 MAXLEN/MINID 裁剪参数。
+type streamTrimSpec struct {
+	maxLen   int64
+	minID    streamID
+	hasLen   bool
+	hasMinID bool
+	approx   bool
+	limit    int64 // 0 表示不限制（只能与 ~ 一起使用）
+}
+
+// trim 按 spec 从头部删除条目，返回删除数量。
+func (s *StreamData) trim(spec *streamTrimSpec) int64 {
+	var n int
+	switch {
+	case spec.hasLen:
+		n = len(s.entries) - int(spec.maxLen)
+	case spec.hasMinID:
+		n = s.search(spec.minID)
+	}
+	if spec.limit > 0 && n > int(spec.limit) {
+		n = int(spec.limit)
+	}
+	if n <= 0 {
+		return 0
+	}
+	if last := s.entries[n-1].id; s.maxDeletedID.less(last) {
+		s.maxDeletedID = last
+	}
+	for i := 0; i < n; i++ {
+		s.entries[i] = streamEntry{} // 释放引用
+	}
+	s.entries = s.entries[n:]
+	return int64(n)
+}
+
+// clone 深拷贝 Stream（COPY 使用）。条目写入后不会被修改，field/value 可以共享。
+func (s *StreamData) clone() *StreamData {
+	c := &StreamData{
+		entries:      append([]streamEntry(nil), s.entries...),
+		lastID:       s.lastID,
+		maxDeletedID: s.maxDeletedID,
+		entriesAdded: s.entriesAdded,
+		groups:       make(map[string]*streamGroup, len(s.groups)),
+	}
+	for name, g := range s.groups {
+		cg := newStreamGroup(g.lastID)
+		for _, nack := range g.pel {
+			n := *nack
+			cg.addNACK(&n)
+		}
+		for cname, consumer := range g.consumers {
+			cc := *consumer
+			cg.consumers[cname] = &cc
+		}
+		c.groups[name] = cg
+	}
+	return c
+}
+
+// getStream 读取 Stream（含惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+func (db *StandaloneDB) getStream(key string) (*StreamData, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return nil, false, nil
+	}
+	s, ok := entity.(*StreamData)
+	if !ok {
+		return nil, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	return s, true, nil
+}
+
+// streamEntryReply 把条目编码为 [id, [field, value, ...]]。
+func streamEntryReply(e streamEntry) resp.Reply {
+	return resp.MakeMultiRawReply([]resp.Reply{
+		resp.MakeBulkReply([]byte(e.id.String())),
+		resp.MakeMultiBulkReply(e.fields),
+	})
+}
+
+// streamEntriesReply 把条目列表编码为 [[id, [field, value, ...]], ...]。
+func streamEntriesReply(entries []streamEntry) resp.Reply {
+	replies := make([]resp.Reply, 0, len(entries))
+	for _, e := range entries {
+		replies = append(replies, streamEntryReply(e))
+	}
+	return resp.MakeMultiRawReply(replies)
+}
+
+// --- 参数解析 ---
+
+// parseStreamTrimArgs 解析 XADD/XTRIM 的选项（从 args[2] 开始）。
+// xadd=true 时遇到第一个非选项参数即停止，返回其下标（即 ID 的位置）。
+func parseStreamTrimArgs(args [][]byte, xadd bool) (spec streamTrimSpec, noMkStream bool, idIdx int, errReply resp.Reply) {
+	hasLimit := false
+	i := 2
+loop:
+	for ; i < len(args); i++ {
+		more := len(args) - 1 - i
+		opt := strings.ToLower(string(args[i]))
+		switch {
+		case xadd && opt == "*":
+			break loop
+		case (opt == "maxlen" || opt == "minid") && more >= 1:
+			if next := string(args[i+1]); next == "~" || next == "=" {
+				spec.approx = next == "~"
+				i++
+				if i+1 >= len(args) {
+					return spec, false, 0, resp.MakeErrReply("ERR syntax error")
+				}
+			}
+			if opt == "maxlen" {
+				if spec.hasMinID {
+					return spec, false, 0, resp.MakeErrReply("ERR syntax error, MAXLEN and MINID options at the same time are not compatible")
+				}
+				n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+				if err != nil {
+					return spec, false, 0, resp.MakeErrReply("ERR value is not an integer or out of range")
+				}
+				if n < 0 {
+					return spec, false, 0, resp.MakeErrReply("ERR The MAXLEN argument must be >= 0.")
+				}
+				spec.hasLen, spec.maxLen = true, n
+			} else {
+				if spec.hasLen {
+					return spec, false, 0, resp.MakeErrReply("ERR syntax error, MAXLEN and MINID options at the same time are not compatible")
+				}
+				id, ok := parseStreamID(args[i+1], 0)
+				if !ok {
+					return spec, false, 0, errInvalidStreamID
+				}
+				spec.hasMinID, spec.minID = true, id
+			}
+			i++
+		case opt == "limit" && more >= 1:
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return spec, false, 0, resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n < 0 {
+				return spec, false, 0, resp.MakeErrReply("ERR The LIMIT argument must be >= 0.")
+			}
+			hasLimit, spec.limit = true, n
+			i++
+		case xadd && opt == "nomkstream":
+			noMkStream = true
+		case xadd:
+			break loop
+		default:
+			return spec, false, 0, resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	if hasLimit && !spec.approx {
+		return spec, false, 0, resp.MakeErrReply("ERR syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	return spec, noMkStream, i, nil
+}
+
+// nextStreamID 根据 XADD 的 ID 参数计算新条目 ID（*、ms-* 或显式 ID）。
+func (s *StreamData) nextStreamID(arg []byte) (streamID, resp.Reply) {
+	errSmaller := resp.MakeErrReply("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	last := s.lastID
+	if string(arg) == "*" {
+		if now := uint64(time.Now().UnixMilli()); now > last.ms {
+			return streamID{now, 0}, nil
+		}
+		id, ok := last.incr()
+		if !ok {
+			return streamID{}, resp.MakeErrReply("ERR The stream has exhausted the last possible ID, unable to add more items")
+		}
+		return id, nil
+	}
+	if msPart, ok := strings.CutSuffix(string(arg), "-*"); ok {
+		ms, err := strconv.ParseUint(msPart, 10, 64)
+		if err != nil {
+			return streamID{}, errInvalidStreamID
+		}
+		switch {
+		case ms > last.ms:
+			return streamID{ms, 0}, nil
+		case ms == last.ms && last.seq < math.MaxUint64:
+			return streamID{ms, last.seq + 1}, nil
+		}
+		return streamID{}, errSmaller
+	}
+	id, ok := parseStreamID(arg, 0)
+	if !ok {
+		return streamID{}, errInvalidStreamID
+	}
+	if id.isZero() {
+		return streamID{}, resp.MakeErrReply("ERR The ID specified in XADD must be greater than 0-0")
+	}
+	if !last.less(id) {
+		return streamID{}, errSmaller
+	}
+	return id, nil
+}
+
+// --- 命令 ---
+
+// XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]
+func (db *StandaloneDB) xadd(args [][]byte) resp.Reply {
+	if len(args) < 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xadd' command")
+	}
+	spec, noMkStream, idIdx, errReply := parseStreamTrimArgs(args, true)
+	if errReply != nil {
+		return errReply
+	}
+	fields := args[idIdx+1:]
+	if len(fields) < 2 || len(fields)%2 != 0 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xadd' command")
+	}
+
+	key := string(args[1])
+	s, exists, errReply := db.getStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		if noMkStream {
+			return resp.NullBulkReply
+		}
+		s = newStreamData()
+	}
+	id, errReply := s.nextStreamID(args[idIdx])
+	if errReply != nil {
+		return errReply
+	}
+
+	copied := make([][]byte, len(fields))
+	for i, f := range fields {
+		copied[i] = append([]byte(nil), f...)
+	}
+	s.entries = append(s.entries, streamEntry{id: id, fields: copied})
+	s.lastID = id
+	s.entriesAdded++
+	if spec.hasLen || spec.hasMinID {
+		s.trim(&spec)
+	}
+	db.cache.Add(key, s, 0)
+	db.signalKeyAsReady(key)
+	return resp.MakeBulkReply([]byte(id.String()))
+}
+
+// appendXAddAof 把 XADD 的 ID 参数改写为实际生成的 ID（* / ms-* 依赖时钟与当时的 last-id）。
+func (db *StandaloneDB) appendXAddAof(cmd [][]byte, res resp.Reply) {
+	br, ok := res.(*resp.BulkReply)
+	if !ok || br.Arg == nil {
+		return
+	}
+	_, _, idIdx, errReply := parseStreamTrimArgs(cmd, true)
+	if errReply != nil {
+		return
+	}
+	record := make([][]byte, len(cmd))
+	copy(record, cmd)
+	record[idIdx] = br.Arg
+	db.addAof(record)
+}
+
+// XRANGE key start end [COUNT count] / XREVRANGE key end start [COUNT count]
+func (db *StandaloneDB) xrange(args [][]byte, rev bool) resp.Reply {
+	name := "xrange"
+	if rev {
+		name = "xrevrange"
+	}
+	if len(args) != 4 && len(args) != 6 {
+		if len(args) < 4 {
+			return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+		}
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	startArg, endArg := args[2], args[3]
+	if rev {
+		startArg, endArg = endArg, startArg
+	}
+	start, errReply := parseRangeID(startArg, true)
+	if errReply != nil {
+		return errReply
+	}
+	end, errReply := parseRangeID(endArg, false)
+	if errReply != nil {
+		return errReply
+	}
+	count := int64(-1)
+	if len(args) == 6 {
+		if !strings.EqualFold(string(args[4]), "count") {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		n, err := strconv.ParseInt(string(args[5]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			n = 0
+		}
+		count = n
+	}
+
+	s, exists, errReply := db.getStream(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists || count == 0 {
+		return resp.MakeMultiRawReply([]resp.Reply{})
+	}
+	return streamEntriesReply(s.rangeEntries(start, end, count, rev))
+}
+
+// XLEN key
+func (db *StandaloneDB) xlen(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xlen' command")
+	}
+	s, exists, errReply := db.getStream(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+	return resp.MakeIntReply(int64(len(s.entries)))
+}
+
+// XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
+func (db *StandaloneDB) xtrim(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xtrim' command")
+	}
+	spec, _, _, errReply := parseStreamTrimArgs(args, false)
+	if errReply != nil {
+		return errReply
+	}
+	if !spec.hasLen && !spec.hasMinID {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	key := string(args[1])
+	s, exists, errReply := db.getStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+	removed := s.trim(&spec)
+	if removed > 0 {
+		db.cache.Add(key, s, 0)
+	}
+	return resp.MakeIntReply(removed)
+}
+
+// XSETID key last-id [ENTRIESADDED entries-added] [MAXDELETEDID max-deleted-id]
+func (db *StandaloneDB) xsetid(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xsetid' command")
+	}
+	id, ok := parseStreamID(args[2], 0)
+	if !ok {
+		return errInvalidStreamID
+	}
+	entriesAdded := int64(-1)
+	var maxDeleted streamID
+	hasMaxDeleted := false
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		switch strings.ToLower(string(args[i])) {
+		case "entriesadded":
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n < 0 {
+				return resp.MakeErrReply("ERR entries_added must be positive")
+			}
+			entriesAdded = n
+		case "maxdeletedid":
+			if maxDeleted, ok = parseStreamID(args[i+1], 0); !ok {
+				return errInvalidStreamID
+			}
+			if id.less(maxDeleted) {
+				return resp.MakeErrReply("ERR The ID specified in XSETID is smaller than the provided max_deleted_entry_id")
+			}
+			hasMaxDeleted = true
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	key := string(args[1])
+	s, exists, errReply := db.getStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeErrReply("ERR no such key")
+	}
+	if len(s.entries) > 0 && id.less(s.topID()) {
+		return resp.MakeErrReply("ERR The ID specified in XSETID is smaller than the target stream top item")
+	}
+	if entriesAdded >= 0 && entriesAdded < int64(len(s.entries)) {
+		return resp.MakeErrReply("ERR The entries_added specified in XSETID is smaller than the target stream length")
+	}
+	s.lastID = id
+	if entriesAdded >= 0 {
+		s.entriesAdded = uint64(entriesAdded)
+	}
+	if hasMaxDeleted {
+		s.maxDeletedID = maxDeleted
+	}
+	return resp.OkReply
+}
+
+// xreadSpec 为 XREAD / XREADGROUP 解析后的参数。
+type xreadSpec struct {
+	group, consumer []byte
+	count           int64 // 0 表示不限制
+	block           bool
+	timeout         time.Duration
+	noAck           bool
+	keys            [][]byte
+	ids             [][]byte
+	idIdx           int // ids 在 args 中的起始下标
+}
+
+// parseXReadArgs 解析 XREAD / XREADGROUP 的参数（group=true 为 XREADGROUP）。
+func parseXReadArgs(args [][]byte, group bool) (*xreadSpec, resp.Reply) {
+	name, idHint := "xread", "$"
+	if group {
+		name, idHint = "xreadgroup", ">"
+	}
+	spec := &xreadSpec{}
+	streamsIdx := 0
+	for i := 1; i < len(args) && streamsIdx == 0; i++ {
+		more := len(args) - 1 - i
+		switch opt := strings.ToLower(string(args[i])); {
+		case opt == "count" && more >= 1:
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n < 0 {
+				n = 0
+			}
+			spec.count = n
+			i++
+		case opt == "block" && more >= 1:
+			ms, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, resp.MakeErrReply("ERR timeout is not an integer or out of range")
+			}
+			if ms < 0 {
+				return nil, resp.MakeErrReply("ERR timeout is negative")
+			}
+			spec.block, spec.timeout = true, time.Duration(ms)*time.Millisecond
+			i++
+		case opt == "streams" && more >= 1:
+			streamsIdx = i + 1
+		case opt == "group" && more >= 2:
+			if !group {
+				return nil, resp.MakeErrReply("ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+			}
+			spec.group, spec.consumer = args[i+1], args[i+2]
+			i += 2
+		case opt == "noack" && group:
+			spec.noAck = true
+		default:
+			return nil, resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	if streamsIdx == 0 {
+		return nil, resp.MakeErrReply("ERR syntax error")
+	}
+	rest := args[streamsIdx:]
+	if len(rest)%2 != 0 {
+		return nil, resp.MakeErrReply("ERR Unbalanced '" + name + "' list of streams: for each stream key an ID or '" + idHint + "' must be specified.")
+	}
+	if group && spec.group == nil {
+		return nil, resp.MakeErrReply("ERR Missing GROUP option for XREADGROUP")
+	}
+	half := len(rest) / 2
+	spec.keys, spec.ids, spec.idIdx = rest[:half], rest[half:], streamsIdx+half
+	return spec, nil
+}
+
+// XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+func (db *StandaloneDB) xread(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xread' command")
+	}
+	spec, errReply := parseXReadArgs(args, false)
+	if errReply != nil {
+		return errReply
+	}
+
+	// 先解析全部 ID（$ 取当前 last-id），再读取，避免部分回复
+	streams := make([]*StreamData, len(spec.keys))
+	starts := make([]streamID, len(spec.keys))
+	for i, k := range spec.keys {
+		s, exists, errReply := db.getStream(string(k))
+		if errReply != nil {
+			return errReply
+		}
+		switch string(spec.ids[i]) {
+		case "$":
+			if exists {
+				starts[i] = s.lastID
+			}
+		case ">":
+			return resp.MakeErrReply("ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.")
+		default:
+			id, ok := parseStreamID(spec.ids[i], 0)
+			if !ok {
+				return errInvalidStreamID
+			}
+			starts[i] = id
+		}
+		if exists {
+			streams[i] = s
+		}
+	}
+
+	var results []resp.Reply
+	for i, s := range streams {
+		if s == nil {
+			continue
+		}
+		if entries := s.after(starts[i], spec.count); len(entries) > 0 {
+			results = append(results, resp.MakeMultiRawReply([]resp.Reply{
+				resp.MakeBulkReply(spec.keys[i]),
+				streamEntriesReply(entries),
+			}))
+		}
+	}
+	if len(results) > 0 {
+		return resp.MakeMultiRawReply(results)
+	}
+	if !spec.block {
+		return resp.MakeMultiBulkReply(nil)
+	}
+
+	// 阻塞：把 $ 固定为当前的 last-id，唤醒后重新执行时只返回阻塞之后写入的条目
+	cmd := make([][]byte, len(args))
+	copy(cmd, args)
+	keys := make([]string, len(spec.keys))
+	for i, k := range spec.keys {
+		keys[i] = string(k)
+		cmd[spec.idIdx+i] = []byte(starts[i].String())
+	}
+	return &blockReply{keys: keys, timeout: spec.timeout, onTimeout: resp.MakeMultiBulkReply(nil), cmd: cmd}
+}
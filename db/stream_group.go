@@ -0,0 +1,824 @@
+// Stream 消费者组实现：XGROUP / XREADGROUP / XACK / XPENDING / XCLAIM / XAUTOCLAIM。
+// 说明：每个组维护 last-delivered-id、按 ID 排序的 PEL（已投递未确认条目）以及消费者表；消费者的 PEL 由组 PEL 按 consumer 过滤得到。
+// 关键点：投递时间/次数依赖时钟，AOF 统一记录为 XCLAIM ... TIME t RETRYCOUNT n FORCE JUSTID LASTID id，重放结果与执行时一致。
+package db
+
+import (
+	"math"
+	"myredis/resp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 本文件实现消费者组命令：
+// - XGROUP CREATE key group id|$ [MKSTREAM] [ENTRIESREAD n] / SETID key group id|$ / DESTROY key group
+// - XGROUP CREATECONSUMER key group consumer / DELCONSUMER key group consumer
+// - XREADGROUP GROUP group consumer [COUNT count] [BLOCK ms] [NOACK] STREAMS key [key ...] id [id ...]
+// - XACK key group id [id ...]
+// - XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+// - XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME ms] [RETRYCOUNT n] [FORCE] [JUSTID] [LASTID id]
+// - XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+//
+// 说明：
+// - XREADGROUP 使用 ">" 读取组内尚未投递的新条目（并记入 PEL，NOACK 除外）；其它 ID 读取该消费者自己的 PEL 历史
+// - 只有全部 ID 都是 ">" 时 BLOCK 才会阻塞；XGROUP DESTROY 会唤醒等待者，使其收到 NOGROUP 错误
+// - ENTRIESREAD 只做语法兼容（不维护 lag 统计）
+
+// streamNACK 为 PEL 中的一条记录。
+type streamNACK struct {
+	id            streamID
+	consumer      string
+	deliveryTime  int64 // UnixMilli
+	deliveryCount int64
+}
+
+type streamConsumer struct {
+	seenTime int64 // UnixMilli
+}
+
+type streamGroup struct {
+	lastID    streamID
+	pel       []*streamNACK // 按 ID 升序
+	pelIndex  map[streamID]*streamNACK
+	consumers map[string]*streamConsumer
+}
+
+func newStreamGroup(lastID streamID) *streamGroup {
+	return &streamGroup{
+		lastID:    lastID,
+		pelIndex:  make(map[streamID]*streamNACK),
+		consumers: make(map[string]*streamConsumer),
+	}
+}
+
+// pelSearch 返回第一个 ID >= id 的 PEL 下标。
+func (g *streamGroup) pelSearch(id streamID) int {
+	return sort.Search(len(g.pel), func(i int) bool { return !g.pel[i].id.less(id) })
+}
+
+func (g *streamGroup) addNACK(n *streamNACK) {
+	g.pelIndex[n.id] = n
+	if len(g.pel) == 0 || g.pel[len(g.pel)-1].id.less(n.id) {
+		g.pel = append(g.pel, n)
+		return
+	}
+	i := g.pelSearch(n.id)
+	g.pel = append(g.pel, nil)
+	copy(g.pel[i+1:], g.pel[i:])
+	g.pel[i] = n
+}
+
+func (g *streamGroup) removeNACK(id streamID) bool {
+	if _, ok := g.pelIndex[id]; !ok {
+		return false
+	}
+	delete(g.pelIndex, id)
+	i := g.pelSearch(id)
+	copy(g.pel[i:], g.pel[i+1:])
+	g.pel[len(g.pel)-1] = nil
+	g.pel = g.pel[:len(g.pel)-1]
+	return true
+}
+
+// consumer 查找（不存在则创建）消费者并刷新 seen-time。
+func (g *streamGroup) consumer(name string, now int64) *streamConsumer {
+	c, ok := g.consumers[name]
+	if !ok {
+		c = &streamConsumer{}
+		g.consumers[name] = c
+	}
+	c.seenTime = now
+	return c
+}
+
+func errNoGroup(key, group []byte) resp.Reply {
+	return resp.MakeErrReply("NOGROUP No such key '" + string(key) + "' or consumer group '" + string(group) + "'")
+}
+
+// getStreamGroup 读取 key 上的消费者组；key 或组不存在返回 NOGROUP 错误。
+func (db *StandaloneDB) getStreamGroup(key, group []byte) (*StreamData, *streamGroup, resp.Reply) {
+	s, exists, errReply := db.getStream(string(key))
+	if errReply != nil {
+		return nil, nil, errReply
+	}
+	if !exists || s.groups[string(group)] == nil {
+		return nil, nil, errNoGroup(key, group)
+	}
+	return s, s.groups[string(group)], nil
+}
+
+// parseGroupStartID 解析 XGROUP CREATE/SETID 的 ID 参数（$ 表示当前 last-id）。
+func parseGroupStartID(arg []byte, s *StreamData) (streamID, resp.Reply) {
+	if string(arg) == "$" {
+		if s == nil {
+			return streamID{}, nil
+		}
+		return s.lastID, nil
+	}
+	id, ok := parseStreamID(arg, 0)
+	if !ok {
+		return streamID{}, errInvalidStreamID
+	}
+	return id, nil
+}
+
+// XGROUP CREATE|SETID|DESTROY|CREATECONSUMER|DELCONSUMER ...
+func (db *StandaloneDB) xgroup(args [][]byte) resp.Reply {
+	if len(args) < 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xgroup' command")
+	}
+	sub := strings.ToLower(string(args[1]))
+	arityErr := resp.MakeErrReply("ERR wrong number of arguments for 'xgroup|" + sub + "' command")
+	switch sub {
+	case "create":
+		if len(args) < 5 {
+			return arityErr
+		}
+	case "setid":
+		if len(args) != 5 && len(args) != 7 {
+			return arityErr
+		}
+	case "destroy":
+		if len(args) != 4 {
+			return arityErr
+		}
+	case "createconsumer", "delconsumer":
+		if len(args) != 5 {
+			return arityErr
+		}
+	default:
+		return resp.MakeErrReply("ERR unknown subcommand '" + string(args[1]) + "'. Try XGROUP HELP.")
+	}
+
+	mkStream := false
+	for i := 5; i < len(args); i++ {
+		switch opt := strings.ToLower(string(args[i])); {
+		case sub == "create" && opt == "mkstream":
+			mkStream = true
+		case opt == "entriesread" && i+1 < len(args):
+			if _, err := strconv.ParseInt(string(args[i+1]), 10, 64); err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			i++
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	key, groupName := string(args[2]), string(args[3])
+	s, exists, errReply := db.getStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		if !mkStream {
+			return resp.MakeErrReply("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+		}
+		s = nil
+	}
+	g := (*streamGroup)(nil)
+	if s != nil {
+		g = s.groups[groupName]
+	}
+	if g == nil && sub != "create" && sub != "destroy" {
+		return resp.MakeErrReply("NOGROUP No such consumer group '" + groupName + "' for key name '" + key + "'")
+	}
+
+	switch sub {
+	case "create":
+		id, errReply := parseGroupStartID(args[4], s)
+		if errReply != nil {
+			return errReply
+		}
+		if g != nil {
+			return resp.MakeErrReply("BUSYGROUP Consumer Group name already exists")
+		}
+		if s == nil {
+			s = newStreamData()
+		}
+		s.groups[groupName] = newStreamGroup(id)
+		db.cache.Add(key, s, 0)
+		return resp.OkReply
+	case "setid":
+		id, errReply := parseGroupStartID(args[4], s)
+		if errReply != nil {
+			return errReply
+		}
+		g.lastID = id
+		return resp.OkReply
+	case "destroy":
+		if g == nil {
+			return resp.MakeIntReply(0)
+		}
+		delete(s.groups, groupName)
+		db.cache.Add(key, s, 0)
+		db.signalKeyAsReady(key) // 等待该组的 XREADGROUP 重新执行后收到 NOGROUP
+		return resp.MakeIntReply(1)
+	case "createconsumer":
+		name := string(args[4])
+		if _, ok := g.consumers[name]; ok {
+			return resp.MakeIntReply(0)
+		}
+		g.consumer(name, time.Now().UnixMilli())
+		db.cache.Add(key, s, 0)
+		return resp.MakeIntReply(1)
+	default: // delconsumer
+		name := string(args[4])
+		if _, ok := g.consumers[name]; !ok {
+			return resp.MakeIntReply(0)
+		}
+		var pending []streamID
+		for _, nack := range g.pel {
+			if nack.consumer == name {
+				pending = append(pending, nack.id)
+			}
+		}
+		for _, id := range pending {
+			g.removeNACK(id)
+		}
+		delete(g.consumers, name)
+		db.cache.Add(key, s, 0)
+		return resp.MakeIntReply(int64(len(pending)))
+	}
+}
+
+// appendXGroupAof 记录 XGROUP：CREATE/SETID 的 $ 改写为执行时解析出的 ID。
+func (db *StandaloneDB) appendXGroupAof(cmd [][]byte, res resp.Reply) {
+	sub := strings.ToLower(string(cmd[1]))
+	if sub != "create" && sub != "setid" {
+		db.addAof(cmd)
+		return
+	}
+	_, g, errReply := db.getStreamGroup(cmd[2], cmd[3])
+	if errReply != nil {
+		return
+	}
+	record := make([][]byte, len(cmd))
+	copy(record, cmd)
+	record[4] = []byte(g.lastID.String())
+	db.addAof(record)
+}
+
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+func (db *StandaloneDB) xreadgroup(args [][]byte) resp.Reply {
+	if len(args) < 7 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xreadgroup' command")
+	}
+	spec, errReply := parseXReadArgs(args, true)
+	if errReply != nil {
+		return errReply
+	}
+
+	type target struct {
+		s       *StreamData
+		g       *streamGroup
+		newOnly bool
+		after   streamID
+	}
+	targets := make([]target, len(spec.keys))
+	for i, k := range spec.keys {
+		s, exists, errReply := db.getStream(string(k))
+		if errReply != nil {
+			return errReply
+		}
+		if !exists || s.groups[string(spec.group)] == nil {
+			return resp.MakeErrReply("NOGROUP No such key '" + string(k) + "' or consumer group '" + string(spec.group) + "' in XREADGROUP with GROUP option")
+		}
+		t := target{s: s, g: s.groups[string(spec.group)]}
+		switch string(spec.ids[i]) {
+		case ">":
+			t.newOnly = true
+		case "$":
+			return resp.MakeErrReply("ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+		default:
+			id, ok := parseStreamID(spec.ids[i], 0)
+			if !ok {
+				return errInvalidStreamID
+			}
+			t.after = id
+		}
+		targets[i] = t
+	}
+
+	now := time.Now().UnixMilli()
+	consumer := string(spec.consumer)
+	allNew := true
+	var results []resp.Reply
+	for i, t := range targets {
+		t.g.consumer(consumer, now)
+		key := string(spec.keys[i])
+		if t.newOnly {
+			entries := t.s.after(t.g.lastID, spec.count)
+			if len(entries) == 0 {
+				continue
+			}
+			t.g.lastID = entries[len(entries)-1].id
+			if !spec.noAck {
+				for _, e := range entries {
+					if nack, ok := t.g.pelIndex[e.id]; ok {
+						// 组的 last-id 被 SETID 回拨后重新投递：转给当前消费者并重置计数
+						nack.consumer, nack.deliveryTime, nack.deliveryCount = consumer, now, 1
+						continue
+					}
+					t.g.addNACK(&streamNACK{id: e.id, consumer: consumer, deliveryTime: now, deliveryCount: 1})
+				}
+			}
+			db.cache.Add(key, t.s, 0)
+			results = append(results, resp.MakeMultiRawReply([]resp.Reply{resp.MakeBulkReply(spec.keys[i]), streamEntriesReply(entries)}))
+			continue
+		}
+
+		// 历史：该消费者 PEL 中 ID 大于给定值的条目；已被删除的条目返回 [id, nil]
+		allNew = false
+		items := []resp.Reply{}
+		start, ok := t.after.incr()
+		for j := t.g.pelSearch(start); ok && j < len(t.g.pel); j++ {
+			if spec.count > 0 && int64(len(items)) >= spec.count {
+				break
+			}
+			nack := t.g.pel[j]
+			if nack.consumer != consumer {
+				continue
+			}
+			e, found := t.s.lookup(nack.id)
+			if !found {
+				items = append(items, resp.MakeMultiRawReply([]resp.Reply{resp.MakeBulkReply([]byte(nack.id.String())), resp.MakeMultiBulkReply(nil)}))
+				continue
+			}
+			nack.deliveryTime = now
+			nack.deliveryCount++
+			items = append(items, streamEntryReply(e))
+		}
+		db.cache.Add(key, t.s, 0)
+		results = append(results, resp.MakeMultiRawReply([]resp.Reply{resp.MakeBulkReply(spec.keys[i]), resp.MakeMultiRawReply(items)}))
+	}
+	if len(results) > 0 || !allNew {
+		return resp.MakeMultiRawReply(results)
+	}
+	if !spec.block {
+		return resp.MakeMultiBulkReply(nil)
+	}
+	keys := make([]string, len(spec.keys))
+	for i, k := range spec.keys {
+		keys[i] = string(k)
+	}
+	return &blockReply{keys: keys, timeout: spec.timeout, onTimeout: resp.MakeMultiBulkReply(nil)}
+}
+
+// XACK key group id [id ...]
+func (db *StandaloneDB) xack(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xack' command")
+	}
+	ids := make([]streamID, 0, len(args)-3)
+	for _, a := range args[3:] {
+		id, ok := parseStreamID(a, 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		ids = append(ids, id)
+	}
+	s, exists, errReply := db.getStream(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists || s.groups[string(args[2])] == nil {
+		return resp.MakeIntReply(0)
+	}
+	g := s.groups[string(args[2])]
+	var acked int64
+	for _, id := range ids {
+		if g.removeNACK(id) {
+			acked++
+		}
+	}
+	if acked > 0 {
+		db.cache.Add(string(args[1]), s, 0)
+	}
+	return resp.MakeIntReply(acked)
+}
+
+// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+func (db *StandaloneDB) xpending(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xpending' command")
+	}
+	if len(args) != 3 && (len(args) < 6 || len(args) > 9) {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	var (
+		minIdle    int64
+		start, end streamID
+		count      int64
+		consumer   []byte
+	)
+	if len(args) > 3 {
+		i := 3
+		if strings.EqualFold(string(args[3]), "idle") {
+			n, err := strconv.ParseInt(string(args[4]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			minIdle = n
+			i = 5
+		}
+		if rest := len(args) - i; rest != 3 && rest != 4 {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		var errReply resp.Reply
+		if start, errReply = parseRangeID(args[i], true); errReply != nil {
+			return errReply
+		}
+		if end, errReply = parseRangeID(args[i+1], false); errReply != nil {
+			return errReply
+		}
+		n, err := strconv.ParseInt(string(args[i+2]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		count = n
+		if i+3 < len(args) {
+			consumer = args[i+3]
+		}
+	}
+
+	_, g, errReply := db.getStreamGroup(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+
+	if len(args) == 3 {
+		if len(g.pel) == 0 {
+			return resp.MakeMultiRawReply([]resp.Reply{
+				resp.MakeIntReply(0), resp.NullBulkReply, resp.NullBulkReply, resp.MakeMultiBulkReply(nil),
+			})
+		}
+		perConsumer := make(map[string]int64)
+		for _, nack := range g.pel {
+			perConsumer[nack.consumer]++
+		}
+		names := make([]string, 0, len(perConsumer))
+		for name := range perConsumer {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		consumers := make([]resp.Reply, 0, len(names))
+		for _, name := range names {
+			consumers = append(consumers, resp.MakeMultiBulkReply([][]byte{
+				[]byte(name), []byte(strconv.FormatInt(perConsumer[name], 10)),
+			}))
+		}
+		return resp.MakeMultiRawReply([]resp.Reply{
+			resp.MakeIntReply(int64(len(g.pel))),
+			resp.MakeBulkReply([]byte(g.pel[0].id.String())),
+			resp.MakeBulkReply([]byte(g.pel[len(g.pel)-1].id.String())),
+			resp.MakeMultiRawReply(consumers),
+		})
+	}
+
+	now := time.Now().UnixMilli()
+	items := []resp.Reply{}
+	for j := g.pelSearch(start); j < len(g.pel) && int64(len(items)) < count; j++ {
+		nack := g.pel[j]
+		if end.less(nack.id) {
+			break
+		}
+		if consumer != nil && nack.consumer != string(consumer) {
+			continue
+		}
+		idle := now - nack.deliveryTime
+		if idle < minIdle {
+			continue
+		}
+		items = append(items, resp.MakeMultiRawReply([]resp.Reply{
+			resp.MakeBulkReply([]byte(nack.id.String())),
+			resp.MakeBulkReply([]byte(nack.consumer)),
+			resp.MakeIntReply(idle),
+			resp.MakeIntReply(nack.deliveryCount),
+		}))
+	}
+	return resp.MakeMultiRawReply(items)
+}
+
+// claimNACK 把 PEL 记录转给 consumer（XCLAIM / XAUTOCLAIM 共用）。
+func (g *streamGroup) claimNACK(nack *streamNACK, consumer string, deliveryTime, retryCount int64, justID bool, now int64) {
+	nack.consumer = consumer
+	nack.deliveryTime = deliveryTime
+	if retryCount >= 0 {
+		nack.deliveryCount = retryCount
+	} else if !justID {
+		nack.deliveryCount++
+	}
+	g.consumer(consumer, now)
+}
+
+// claimedReply 把认领的条目编码为 JUSTID 的 ID 列表或完整条目列表。
+func claimedReply(entries []streamEntry, justID bool) resp.Reply {
+	if !justID {
+		return streamEntriesReply(entries)
+	}
+	ids := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, []byte(e.id.String()))
+	}
+	return resp.MakeMultiBulkReply(ids)
+}
+
+// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME unix-time-milliseconds] [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID lastid]
+func (db *StandaloneDB) xclaim(args [][]byte) resp.Reply {
+	if len(args) < 6 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xclaim' command")
+	}
+	minIdle, err := strconv.ParseInt(string(args[4]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+	// ID 列表到第一个无法解析为 ID 的参数为止，其后为选项
+	j := 5
+	var ids []streamID
+	for ; j < len(args); j++ {
+		id, ok := parseStreamID(args[j], 0)
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	now := time.Now().UnixMilli()
+	deliveryTime := now
+	retryCount := int64(-1)
+	force, justID := false, false
+	var lastID streamID
+	for ; j < len(args); j++ {
+		more := len(args) - 1 - j
+		switch opt := strings.ToLower(string(args[j])); {
+		case opt == "force":
+			force = true
+		case opt == "justid":
+			justID = true
+		case opt == "idle" && more >= 1:
+			n, err := strconv.ParseInt(string(args[j+1]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR Invalid IDLE option argument for XCLAIM")
+			}
+			deliveryTime = now - n
+			j++
+		case opt == "time" && more >= 1:
+			n, err := strconv.ParseInt(string(args[j+1]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR Invalid TIME option argument for XCLAIM")
+			}
+			deliveryTime = n
+			j++
+		case opt == "retrycount" && more >= 1:
+			n, err := strconv.ParseInt(string(args[j+1]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+			}
+			retryCount = n
+			j++
+		case opt == "lastid" && more >= 1:
+			id, ok := parseStreamID(args[j+1], 0)
+			if !ok {
+				return errInvalidStreamID
+			}
+			lastID = id
+			j++
+		default:
+			return resp.MakeErrReply("ERR Unrecognized XCLAIM option '" + string(args[j]) + "'")
+		}
+	}
+	if deliveryTime < 0 || deliveryTime > now {
+		// 未来时间或负数没有意义，按当前时间处理
+		deliveryTime = now
+	}
+
+	s, g, errReply := db.getStreamGroup(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	if g.lastID.less(lastID) {
+		g.lastID = lastID
+	}
+	consumer := string(args[3])
+	var claimed []streamEntry
+	for _, id := range ids {
+		nack, pending := g.pelIndex[id]
+		e, found := s.lookup(id)
+		if !found {
+			// 条目已被删除：顺带清理 PEL
+			if pending {
+				g.removeNACK(id)
+			}
+			continue
+		}
+		if !pending {
+			if !force {
+				continue
+			}
+			nack = &streamNACK{id: id, deliveryCount: 1}
+			g.addNACK(nack)
+		} else if minIdle > 0 && now-nack.deliveryTime < minIdle {
+			continue
+		}
+		g.claimNACK(nack, consumer, deliveryTime, retryCount, justID, now)
+		claimed = append(claimed, e)
+	}
+	db.cache.Add(string(args[1]), s, 0)
+	return claimedReply(claimed, justID)
+}
+
+// XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+func (db *StandaloneDB) xautoclaim(args [][]byte) resp.Reply {
+	if len(args) < 6 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'xautoclaim' command")
+	}
+	minIdle, err := strconv.ParseInt(string(args[4]), 10, 64)
+	if err != nil {
+		return resp.MakeErrReply("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+	start, errReply := parseRangeID(args[5], true)
+	if errReply != nil {
+		return errReply
+	}
+	count, justID := int64(100), false
+	for j := 6; j < len(args); j++ {
+		switch opt := strings.ToLower(string(args[j])); {
+		case opt == "count" && j+1 < len(args):
+			n, err := strconv.ParseInt(string(args[j+1]), 10, 64)
+			if err != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			if n < 1 || n > math.MaxInt64/10 {
+				return resp.MakeErrReply("ERR COUNT must be > 0")
+			}
+			count = n
+			j++
+		case opt == "justid":
+			justID = true
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+
+	s, g, errReply := db.getStreamGroup(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	now := time.Now().UnixMilli()
+	consumer := string(args[3])
+	claimed := []streamEntry{}
+	deleted := [][]byte{}
+	// 每次最多检查 count*10 条 PEL 记录，避免单次调用扫描过长
+	attempts := count * 10
+	i := g.pelSearch(start)
+	for ; i < len(g.pel) && attempts > 0 && int64(len(claimed)) < count; attempts-- {
+		nack := g.pel[i]
+		e, found := s.lookup(nack.id)
+		if !found {
+			deleted = append(deleted, []byte(nack.id.String()))
+			g.removeNACK(nack.id)
+			continue
+		}
+		i++
+		if minIdle > 0 && now-nack.deliveryTime < minIdle {
+			continue
+		}
+		g.claimNACK(nack, consumer, now, -1, justID, now)
+		claimed = append(claimed, e)
+	}
+	cursor := streamID{}
+	if i < len(g.pel) {
+		cursor = g.pel[i].id
+	}
+	db.cache.Add(string(args[1]), s, 0)
+	return resp.MakeMultiRawReply([]resp.Reply{
+		resp.MakeBulkReply([]byte(cursor.String())),
+		claimedReply(claimed, justID),
+		resp.MakeMultiBulkReply(deleted),
+	})
+}
+
+// streamReplyIDs 从条目回复（完整条目或 JUSTID 列表）中取出 ID。
+func streamReplyIDs(r resp.Reply) []streamID {
+	var ids []streamID
+	switch r := r.(type) {
+	case *resp.MultiBulkReply:
+		for _, a := range r.Args {
+			if id, ok := parseStreamID(a, 0); ok {
+				ids = append(ids, id)
+			}
+		}
+	case *resp.MultiRawReply:
+		for _, item := range r.Replies {
+			pair, ok := item.(*resp.MultiRawReply)
+			if !ok || len(pair.Replies) != 2 {
+				continue
+			}
+			// 已删除的历史条目（[id, nil]）不产生新的投递
+			if mb, ok := pair.Replies[1].(*resp.MultiBulkReply); !ok || mb.Args == nil {
+				continue
+			}
+			if br, ok := pair.Replies[0].(*resp.BulkReply); ok {
+				if id, ok := parseStreamID(br.Arg, 0); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// appendClaimRecords 为每个已投递/认领的 ID 记录一条等价的 XCLAIM（带确定的投递时间与次数）。
+func (db *StandaloneDB) appendClaimRecords(key, group []byte, g *streamGroup, ids []streamID) {
+	for _, id := range ids {
+		nack, ok := g.pelIndex[id]
+		if !ok {
+			continue
+		}
+		db.addAof([][]byte{
+			[]byte("XCLAIM"), key, group, []byte(nack.consumer), []byte("0"), []byte(id.String()),
+			[]byte("TIME"), []byte(strconv.FormatInt(nack.deliveryTime, 10)),
+			[]byte("RETRYCOUNT"), []byte(strconv.FormatInt(nack.deliveryCount, 10)),
+			[]byte("FORCE"), []byte("JUSTID"), []byte("LASTID"), []byte(g.lastID.String()),
+		})
+	}
+}
+
+// appendStreamClaimAof 把 XREADGROUP / XCLAIM / XAUTOCLAIM 改写为确定性的 XCLAIM / XACK / XGROUP SETID 记录。
+func (db *StandaloneDB) appendStreamClaimAof(name string, cmd [][]byte, res resp.Reply) {
+	switch name {
+	case "xreadgroup":
+		spec, errReply := parseXReadArgs(cmd, true)
+		outer, ok := res.(*resp.MultiRawReply)
+		if errReply != nil || !ok {
+			return
+		}
+		for _, item := range outer.Replies {
+			pair, ok := item.(*resp.MultiRawReply)
+			if !ok || len(pair.Replies) != 2 {
+				continue
+			}
+			key := pair.Replies[0].(*resp.BulkReply).Arg
+			_, g, errReply := db.getStreamGroup(key, spec.group)
+			if errReply != nil {
+				continue
+			}
+			ids := streamReplyIDs(pair.Replies[1])
+			db.appendClaimRecords(key, spec.group, g, ids)
+			if spec.noAck && len(ids) > 0 {
+				// NOACK 的新条目不进 PEL，只推进组的 last-id
+				db.addAof([][]byte{[]byte("XGROUP"), []byte("SETID"), key, spec.group, []byte(g.lastID.String())})
+			}
+		}
+	case "xclaim":
+		s, g, errReply := db.getStreamGroup(cmd[1], cmd[2])
+		if errReply != nil {
+			return
+		}
+		claimed := streamReplyIDs(res)
+		db.appendClaimRecords(cmd[1], cmd[2], g, claimed)
+		// 条目已删除而被移出 PEL 的 ID 记为 XACK；LASTID 单独记录（没有认领任何条目时也要生效）
+		acks := [][]byte{[]byte("XACK"), cmd[1], cmd[2]}
+		j := 5
+		for ; j < len(cmd); j++ {
+			id, ok := parseStreamID(cmd[j], 0)
+			if !ok {
+				break
+			}
+			if _, pending := g.pelIndex[id]; !pending {
+				if _, found := s.lookup(id); !found {
+					acks = append(acks, cmd[j])
+				}
+			}
+		}
+		hasLastID := false
+		for ; j < len(cmd); j++ {
+			hasLastID = hasLastID || strings.EqualFold(string(cmd[j]), "lastid")
+		}
+		if len(acks) > 3 {
+			db.addAof(acks)
+		}
+		if hasLastID {
+			db.addAof([][]byte{[]byte("XGROUP"), []byte("SETID"), cmd[1], cmd[2], []byte(g.lastID.String())})
+		}
+	case "xautoclaim":
+		_, g, errReply := db.getStreamGroup(cmd[1], cmd[2])
+		outer, ok := res.(*resp.MultiRawReply)
+		if errReply != nil || !ok || len(outer.Replies) != 3 {
+			return
+		}
+		db.appendClaimRecords(cmd[1], cmd[2], g, streamReplyIDs(outer.Replies[1]))
+		if deleted, ok := outer.Replies[2].(*resp.MultiBulkReply); ok && len(deleted.Args) > 0 {
+			db.addAof(append([][]byte{[]byte("XACK"), cmd[1], cmd[2]}, deleted.Args...))
+		}
+	}
+}
@@ -0,0 +1,452 @@
+// Stream 命令测试：覆盖 ID 生成与校验、XRANGE 区间、裁剪、XREAD 阻塞以及消费者组的投递/确认/认领。
+// 目标：行为（含错误信息）与 Redis 一致；阻塞 XREAD 不卡住 Actor，且只返回阻塞之后写入的条目。
+// 覆盖：AOF 重放、RDB 加载与 AOF 重写后 Stream 的条目、last-id、消费者组与 PEL 保持一致；原地修改后的内存统计与淘汰。
+package db
+
+import (
+	"myredis/resp"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamIDs 取出条目回复（[[id, fields], ...]）中的 ID。
+func streamIDs(t *testing.T, r resp.Reply) []string {
+	t.Helper()
+	mr, ok := r.(*resp.MultiRawReply)
+	if !ok {
+		t.Fatalf("expected entries, got %T (%s)", r, r.ToBytes())
+	}
+	ids := make([]string, 0, len(mr.Replies))
+	for _, item := range mr.Replies {
+		ids = append(ids, string(item.(*resp.MultiRawReply).Replies[0].(*resp.BulkReply).Arg))
+	}
+	return ids
+}
+
+// readIDs 取出 XREAD/XREADGROUP 回复中指定 key 的条目 ID。
+func readIDs(t *testing.T, r resp.Reply, key string) []string {
+	t.Helper()
+	mr, ok := r.(*resp.MultiRawReply)
+	if !ok {
+		t.Fatalf("expected read reply, got %T (%s)", r, r.ToBytes())
+	}
+	for _, item := range mr.Replies {
+		pair := item.(*resp.MultiRawReply)
+		if string(pair.Replies[0].(*resp.BulkReply).Arg) == key {
+			return streamIDs(t, pair.Replies[1])
+		}
+	}
+	return nil
+}
+
+func errStatus(r resp.Reply) string {
+	if er, ok := r.(*resp.ErrorReply); ok {
+		return er.Status
+	}
+	return ""
+}
+
+func TestStream_AddRangeTrim(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	for _, id := range []string{"1-1", "1-2", "2-0", "3-5", "5-0"} {
+		if br := execArgs(d, "XADD", "s", id, "f", "v"+id).(*resp.BulkReply); string(br.Arg) != id {
+			t.Fatalf("XADD %s = %q", id, br.Arg)
+		}
+	}
+	if br := execArgs(d, "XADD", "s", "5-*", "f", "v").(*resp.BulkReply); string(br.Arg) != "5-1" {
+		t.Fatalf("XADD 5-* = %q", br.Arg)
+	}
+	if got := errStatus(execArgs(d, "XADD", "s", "5-1", "f", "v")); got != "ERR The ID specified in XADD is equal or smaller than the target stream top item" {
+		t.Fatalf("XADD smaller ID = %q", got)
+	}
+	if got := errStatus(execArgs(d, "XADD", "n", "0-0", "f", "v")); got != "ERR The ID specified in XADD must be greater than 0-0" {
+		t.Fatalf("XADD 0-0 = %q", got)
+	}
+	if got := errStatus(execArgs(d, "XADD", "n", "abc", "f", "v")); got != "ERR Invalid stream ID specified as stream command argument" {
+		t.Fatalf("XADD invalid ID = %q", got)
+	}
+	if got := errStatus(execArgs(d, "XADD", "n", "*", "f")); got != "ERR wrong number of arguments for 'xadd' command" {
+		t.Fatalf("XADD odd fields = %q", got)
+	}
+	if br := execArgs(d, "XADD", "n", "NOMKSTREAM", "*", "f", "v").(*resp.BulkReply); br.Arg != nil {
+		t.Fatalf("XADD NOMKSTREAM on missing key should return nil")
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "n")); n != 0 {
+		t.Fatalf("failed XADD must not create the key")
+	}
+	if st := execArgs(d, "TYPE", "s").(*resp.StatusReply); st.Status != "stream" {
+		t.Fatalf("TYPE = %s", st.Status)
+	}
+	// 自动 ID：毫秒时间戳，大于当前 last-id
+	auto := string(execArgs(d, "XADD", "s", "*", "f", "v").(*resp.BulkReply).Arg)
+	if ms, _, _ := strings.Cut(auto, "-"); len(ms) < 13 {
+		t.Fatalf("auto ID = %s", auto)
+	}
+	if n := replyInt(t, execArgs(d, "XLEN", "s")); n != 7 {
+		t.Fatalf("XLEN = %d", n)
+	}
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"XRANGE", "s", "-", "2"}, "1-1,1-2,2-0"},
+		{[]string{"XRANGE", "s", "-", "3"}, "1-1,1-2,2-0,3-5"},
+		{[]string{"XRANGE", "s", "1", "3-5"}, "1-1,1-2,2-0,3-5"},
+		{[]string{"XRANGE", "s", "(1-1", "(3-5"}, "1-2,2-0"},
+		{[]string{"XRANGE", "s", "2", "+", "COUNT", "2"}, "2-0,3-5"},
+		{[]string{"XRANGE", "s", "5", "1"}, ""},
+		{[]string{"XRANGE", "missing", "-", "+"}, ""},
+		{[]string{"XREVRANGE", "s", "5-0", "-", "COUNT", "3"}, "5-0,3-5,2-0"},
+		{[]string{"XREVRANGE", "s", "(5-0", "(1-1"}, "3-5,2-0,1-2"},
+	}
+	for _, c := range cases {
+		if got := strings.Join(streamIDs(t, execArgs(d, c.args...)), ","); got != c.want {
+			t.Fatalf("%v = %s, want %s", c.args, got, c.want)
+		}
+	}
+	entry := execArgs(d, "XRANGE", "s", "3-5", "3-5").(*resp.MultiRawReply).Replies[0].(*resp.MultiRawReply)
+	if fields := entry.Replies[1].(*resp.MultiBulkReply).Args; string(fields[0]) != "f" || string(fields[1]) != "v3-5" {
+		t.Fatalf("entry fields = %q", fields)
+	}
+	if got := errStatus(execArgs(d, "XRANGE", "s", "(18446744073709551615-18446744073709551615", "+")); got != "ERR invalid start ID for the interval" {
+		t.Fatalf("exclusive start overflow = %q", got)
+	}
+
+	// 裁剪
+	if got := errStatus(execArgs(d, "XTRIM", "s", "MAXLEN", "2", "LIMIT", "1")); got != "ERR syntax error, LIMIT cannot be used without the special ~ option" {
+		t.Fatalf("LIMIT without ~ = %q", got)
+	}
+	if got := errStatus(execArgs(d, "XADD", "s", "MAXLEN", "1", "MINID", "1", "*", "f", "v")); got != "ERR syntax error, MAXLEN and MINID options at the same time are not compatible" {
+		t.Fatalf("MAXLEN+MINID = %q", got)
+	}
+	if n := replyInt(t, execArgs(d, "XTRIM", "s", "MAXLEN", "~", "2", "LIMIT", "1")); n != 1 {
+		t.Fatalf("XTRIM with LIMIT = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "XTRIM", "s", "MINID", "3")); n != 2 {
+		t.Fatalf("XTRIM MINID = %d", n)
+	}
+	execArgs(d, "XADD", "s", "MAXLEN", "=", "2", "*", "f", "v")
+	if n := replyInt(t, execArgs(d, "XLEN", "s")); n != 2 {
+		t.Fatalf("XLEN after XADD MAXLEN = %d", n)
+	}
+	// 删空的 Stream 仍然存在，last-id 保留
+	execArgs(d, "XTRIM", "s", "MAXLEN", "0")
+	if n := replyInt(t, execArgs(d, "EXISTS", "s")); n != 1 {
+		t.Fatalf("empty stream should still exist")
+	}
+	if got := errStatus(execArgs(d, "XADD", "s", "5-2", "f", "v")); !strings.HasPrefix(got, "ERR The ID specified in XADD is equal or smaller") {
+		t.Fatalf("last-id should survive trimming, got %q", got)
+	}
+
+	if got := errStatus(execArgs(d, "XSETID", "s", "1-0")); got != "" {
+		t.Fatalf("XSETID on empty stream = %q", got)
+	}
+	if br := execArgs(d, "XADD", "s", "1-*", "f", "v").(*resp.BulkReply); string(br.Arg) != "1-1" {
+		t.Fatalf("XADD after XSETID = %q", br.Arg)
+	}
+	if got := errStatus(execArgs(d, "XSETID", "s", "0-5")); got != "ERR The ID specified in XSETID is smaller than the target stream top item" {
+		t.Fatalf("XSETID smaller = %q", got)
+	}
+
+	execArgs(d, "SET", "str", "x")
+	if got := errStatus(execArgs(d, "XADD", "str", "*", "f", "v")); !strings.HasPrefix(got, "WRONGTYPE") {
+		t.Fatalf("XADD on string = %q", got)
+	}
+}
+
+func TestStream_XReadBlocking(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "XADD", "a", "1-0", "f", "v")
+	execArgs(d, "XADD", "a", "2-0", "f", "v")
+	execArgs(d, "XADD", "b", "1-0", "f", "v")
+
+	r := execArgs(d, "XREAD", "COUNT", "1", "STREAMS", "a", "b", "0", "1-0")
+	if got := strings.Join(readIDs(t, r, "a"), ","); got != "1-0" {
+		t.Fatalf("XREAD a = %s", got)
+	}
+	if len(r.(*resp.MultiRawReply).Replies) != 1 {
+		t.Fatalf("streams without new entries should be omitted")
+	}
+	if mb, ok := execArgs(d, "XREAD", "STREAMS", "a", "$").(*resp.MultiBulkReply); !ok || mb.Args != nil {
+		t.Fatalf("XREAD $ without BLOCK should return null")
+	}
+	if got := errStatus(execArgs(d, "XREAD", "STREAMS", "a", "b", "0")); got != "ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified." {
+		t.Fatalf("unbalanced = %q", got)
+	}
+	if got := errStatus(execArgs(d, "XREAD", "BLOCK", "-1", "STREAMS", "a", "0")); got != "ERR timeout is negative" {
+		t.Fatalf("negative BLOCK = %q", got)
+	}
+
+	start := time.Now()
+	if mb, ok := execArgs(d, "XREAD", "BLOCK", "100", "STREAMS", "a", "$").(*resp.MultiBulkReply); !ok || mb.Args != nil {
+		t.Fatalf("XREAD BLOCK timeout should return null")
+	}
+	if time.Since(start) < 80*time.Millisecond {
+		t.Fatalf("XREAD BLOCK returned too early")
+	}
+
+	// $ 在挂起时被固定：唤醒后只返回阻塞之后写入的条目
+	waiter := execAsync(d, "XREAD", "BLOCK", "0", "STREAMS", "missing", "a", "$", "$")
+	waitBlocked(t, d, "a", 1)
+	execArgs(d, "XADD", "a", "7-0", "f", "v")
+	r = waitReply(t, waiter)
+	if got := strings.Join(readIDs(t, r, "a"), ","); got != "7-0" {
+		t.Fatalf("woken XREAD a = %s", got)
+	}
+	if len(r.(*resp.MultiRawReply).Replies) != 1 {
+		t.Fatalf("woken XREAD should only contain a: %s", r.ToBytes())
+	}
+
+	// 唤醒顺序：等待更大 ID 的请求不会挡住后面的等待者
+	far := execAsync(d, "XREAD", "BLOCK", "0", "STREAMS", "a", "100-0")
+	waitBlocked(t, d, "a", 1)
+	near := execAsync(d, "XREAD", "BLOCK", "0", "STREAMS", "a", "$")
+	waitBlocked(t, d, "a", 2)
+	execArgs(d, "XADD", "a", "8-0", "f", "v")
+	if got := strings.Join(readIDs(t, waitReply(t, near), "a"), ","); got != "8-0" {
+		t.Fatalf("near waiter = %s", got)
+	}
+	execArgs(d, "XADD", "a", "101-0", "f", "v")
+	if got := strings.Join(readIDs(t, waitReply(t, far), "a"), ","); got != "101-0" {
+		t.Fatalf("far waiter = %s", got)
+	}
+}
+
+func TestStream_ConsumerGroups(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if got := errStatus(execArgs(d, "XGROUP", "CREATE", "s", "g", "$")); !strings.HasPrefix(got, "ERR The XGROUP subcommand requires the key to exist") {
+		t.Fatalf("XGROUP CREATE without MKSTREAM = %q", got)
+	}
+	if _, ok := execArgs(d, "XGROUP", "CREATE", "s", "g", "$", "MKSTREAM").(*resp.StatusReply); !ok {
+		t.Fatalf("XGROUP CREATE MKSTREAM failed")
+	}
+	if got := errStatus(execArgs(d, "XGROUP", "CREATE", "s", "g", "0")); got != "BUSYGROUP Consumer Group name already exists" {
+		t.Fatalf("duplicate group = %q", got)
+	}
+	for _, id := range []string{"1-0", "2-0", "3-0", "4-0"} {
+		execArgs(d, "XADD", "s", id, "f", id)
+	}
+
+	r := execArgs(d, "XREADGROUP", "GROUP", "g", "alice", "COUNT", "2", "STREAMS", "s", ">")
+	if got := strings.Join(readIDs(t, r, "s"), ","); got != "1-0,2-0" {
+		t.Fatalf("alice = %s", got)
+	}
+	r = execArgs(d, "XREADGROUP", "GROUP", "g", "bob", "STREAMS", "s", ">")
+	if got := strings.Join(readIDs(t, r, "s"), ","); got != "3-0,4-0" {
+		t.Fatalf("bob = %s", got)
+	}
+	if mb, ok := execArgs(d, "XREADGROUP", "GROUP", "g", "bob", "STREAMS", "s", ">").(*resp.MultiBulkReply); !ok || mb.Args != nil {
+		t.Fatalf("no new entries should return null")
+	}
+	if got := errStatus(execArgs(d, "XREADGROUP", "GROUP", "nope", "c", "STREAMS", "s", ">")); got != "NOGROUP No such key 's' or consumer group 'nope' in XREADGROUP with GROUP option" {
+		t.Fatalf("missing group = %q", got)
+	}
+
+	// XPENDING 概要与明细
+	summary := execArgs(d, "XPENDING", "s", "g").(*resp.MultiRawReply).Replies
+	if n := summary[0].(*resp.IntReply).Code; n != 4 {
+		t.Fatalf("pending = %d", n)
+	}
+	if lo, hi := string(summary[1].(*resp.BulkReply).Arg), string(summary[2].(*resp.BulkReply).Arg); lo != "1-0" || hi != "4-0" {
+		t.Fatalf("pending range = %s..%s", lo, hi)
+	}
+	if consumers := string(summary[3].ToBytes()); consumers != "*2\r\n*2\r\n$5\r\nalice\r\n$1\r\n2\r\n*2\r\n$3\r\nbob\r\n$1\r\n2\r\n" {
+		t.Fatalf("pending consumers = %q", consumers)
+	}
+	detail := execArgs(d, "XPENDING", "s", "g", "-", "+", "10", "bob").(*resp.MultiRawReply).Replies
+	if len(detail) != 2 {
+		t.Fatalf("bob pending = %d", len(detail))
+	}
+	if first := detail[0].(*resp.MultiRawReply).Replies; string(first[0].(*resp.BulkReply).Arg) != "3-0" || first[3].(*resp.IntReply).Code != 1 {
+		t.Fatalf("bob first pending = %s", detail[0].ToBytes())
+	}
+	if n := len(execArgs(d, "XPENDING", "s", "g", "IDLE", "60000", "-", "+", "10").(*resp.MultiRawReply).Replies); n != 0 {
+		t.Fatalf("XPENDING IDLE filter = %d", n)
+	}
+
+	// 历史读取会增加投递次数
+	r = execArgs(d, "XREADGROUP", "GROUP", "g", "alice", "STREAMS", "s", "0")
+	if got := strings.Join(readIDs(t, r, "s"), ","); got != "1-0,2-0" {
+		t.Fatalf("alice history = %s", got)
+	}
+	if n := replyInt(t, execArgs(d, "XACK", "s", "g", "1-0", "9-0")); n != 1 {
+		t.Fatalf("XACK = %d", n)
+	}
+
+	// XCLAIM：满足 min-idle 才能认领；JUSTID 不增加投递次数
+	if n := len(streamIDs(t, execArgs(d, "XCLAIM", "s", "g", "carol", "60000", "2-0"))); n != 0 {
+		t.Fatalf("XCLAIM should respect min-idle, claimed %d", n)
+	}
+	if got := strings.Join(streamIDs(t, execArgs(d, "XCLAIM", "s", "g", "carol", "0", "2-0", "3-0")), ","); got != "2-0,3-0" {
+		t.Fatalf("XCLAIM = %s", got)
+	}
+	if got := string(execArgs(d, "XCLAIM", "s", "g", "carol", "0", "4-0", "JUSTID").ToBytes()); got != "*1\r\n$3\r\n4-0\r\n" {
+		t.Fatalf("XCLAIM JUSTID = %q", got)
+	}
+	detail = execArgs(d, "XPENDING", "s", "g", "-", "+", "10").(*resp.MultiRawReply).Replies
+	counts := make([]string, 0, len(detail))
+	for _, item := range detail {
+		fields := item.(*resp.MultiRawReply).Replies
+		counts = append(counts, string(fields[0].(*resp.BulkReply).Arg)+":"+string(fields[1].(*resp.BulkReply).Arg)+":"+string(fields[3].ToBytes()[1:2]))
+	}
+	if got := strings.Join(counts, ","); got != "2-0:carol:3,3-0:carol:2,4-0:carol:1" {
+		t.Fatalf("pending after XCLAIM = %s", got)
+	}
+
+	// XAUTOCLAIM：被裁剪掉的条目从 PEL 移除并在第三个元素中返回
+	execArgs(d, "XTRIM", "s", "MINID", "3")
+	auto := execArgs(d, "XAUTOCLAIM", "s", "g", "dave", "0", "-", "COUNT", "1").(*resp.MultiRawReply).Replies
+	if cursor := string(auto[0].(*resp.BulkReply).Arg); cursor != "4-0" {
+		t.Fatalf("XAUTOCLAIM cursor = %s", cursor)
+	}
+	if got := strings.Join(streamIDs(t, auto[1]), ","); got != "3-0" {
+		t.Fatalf("XAUTOCLAIM claimed = %s", got)
+	}
+	if got := string(auto[2].ToBytes()); got != "*1\r\n$3\r\n2-0\r\n" {
+		t.Fatalf("XAUTOCLAIM deleted = %q", got)
+	}
+	auto = execArgs(d, "XAUTOCLAIM", "s", "g", "dave", "0", string(auto[0].(*resp.BulkReply).Arg), "JUSTID").(*resp.MultiRawReply).Replies
+	if cursor := string(auto[0].(*resp.BulkReply).Arg); cursor != "0-0" {
+		t.Fatalf("XAUTOCLAIM final cursor = %s", cursor)
+	}
+
+	// 消费者管理
+	if n := replyInt(t, execArgs(d, "XGROUP", "CREATECONSUMER", "s", "g", "erin")); n != 1 {
+		t.Fatalf("CREATECONSUMER = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "XGROUP", "DELCONSUMER", "s", "g", "dave")); n != 2 {
+		t.Fatalf("DELCONSUMER = %d", n)
+	}
+	if got := errStatus(execArgs(d, "XGROUP", "SETID", "s", "nope", "0")); got != "NOGROUP No such consumer group 'nope' for key name 's'" {
+		t.Fatalf("SETID missing group = %q", got)
+	}
+
+	// BLOCK：新条目唤醒等待者；DESTROY 让等待者收到 NOGROUP
+	waiter := execAsync(d, "XREADGROUP", "GROUP", "g", "erin", "BLOCK", "0", "STREAMS", "s", ">")
+	waitBlocked(t, d, "s", 1)
+	execArgs(d, "XADD", "s", "9-0", "f", "v")
+	if got := strings.Join(readIDs(t, waitReply(t, waiter), "s"), ","); got != "9-0" {
+		t.Fatalf("woken XREADGROUP = %s", got)
+	}
+	waiter = execAsync(d, "XREADGROUP", "GROUP", "g", "erin", "BLOCK", "0", "STREAMS", "s", ">")
+	waitBlocked(t, d, "s", 1)
+	if n := replyInt(t, execArgs(d, "XGROUP", "DESTROY", "s", "g")); n != 1 {
+		t.Fatalf("DESTROY = %d", n)
+	}
+	if got := errStatus(waitReply(t, waiter)); !strings.HasPrefix(got, "NOGROUP") {
+		t.Fatalf("waiter after DESTROY = %q", got)
+	}
+}
+
+func TestStream_EvictionAccounting(t *testing.T) {
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{MaxBytes: 2000, Eviction: "lru"})
+	defer d.Close()
+
+	// 每个条目计 97 字节：20 个条目后 s 与 other 合计超过 2000，最久未访问的 other 被淘汰
+	execArgs(d, "SET", "other", strings.Repeat("v", 100))
+	for i := 0; i < 20; i++ {
+		execArgs(d, "XADD", "s", "*", "f", strings.Repeat("x", 40))
+	}
+	if got := replyInt(t, execArgs(d, "EXISTS", "other")); got != 0 {
+		t.Fatalf("other should be evicted after the stream grew past max-bytes")
+	}
+	// 裁剪后大小统计随之减少，再写入其它 key 不会淘汰 s
+	execArgs(d, "XTRIM", "s", "MAXLEN", "1")
+	execArgs(d, "SET", "other", strings.Repeat("v", 100))
+	if got := replyInt(t, execArgs(d, "XLEN", "s")); got != 1 {
+		t.Fatalf("XLEN s = %d, want 1 (stream evicted by stale size accounting?)", got)
+	}
+}
+
+// streamState 汇总 Stream 的可观察状态：条目、last-id、消费者组 last-id 与 PEL（不含 idle）。
+func streamState(t *testing.T, d *StandaloneDB, key, group string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString(strings.Join(streamIDs(t, execArgs(d, "XRANGE", key, "-", "+")), ","))
+	// last-id 探测：写入更小的 ID 必须失败（不会修改数据）
+	b.WriteString("|" + errStatus(execArgs(d, "XADD", key, "NOMKSTREAM", "5-0", "f", "v")))
+	for _, item := range execArgs(d, "XPENDING", key, group, "-", "+", "100").(*resp.MultiRawReply).Replies {
+		fields := item.(*resp.MultiRawReply).Replies
+		b.WriteString("|" + string(fields[0].(*resp.BulkReply).Arg) + " " + string(fields[1].(*resp.BulkReply).Arg) + " " + string(fields[3].ToBytes()))
+	}
+	return b.String()
+}
+
+func TestStream_PersistenceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "node.aof")
+	rdbFile := filepath.Join(dir, "node.rdb")
+
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	for i := 0; i < 5; i++ {
+		execArgs(d, "XADD", "s", "*", "n", "v")
+	}
+	execArgs(d, "XADD", "s", "MAXLEN", "4", "*", "n", "v")
+	execArgs(d, "XGROUP", "CREATE", "s", "g", "0")
+	execArgs(d, "XREADGROUP", "GROUP", "g", "c1", "COUNT", "2", "STREAMS", "s", ">")
+	execArgs(d, "XREADGROUP", "GROUP", "g", "c2", "NOACK", "COUNT", "1", "STREAMS", "s", ">")
+	execArgs(d, "XREADGROUP", "GROUP", "g", "c1", "STREAMS", "s", "0")
+	first := streamIDs(t, execArgs(d, "XRANGE", "s", "-", "+", "COUNT", "1"))[0]
+	execArgs(d, "XACK", "s", "g", first)
+	execArgs(d, "XCLAIM", "s", "g", "c3", "0", streamIDs(t, execArgs(d, "XRANGE", "s", "-", "+"))[1])
+	execArgs(d, "XADD", "empty", "5-0", "f", "v")
+	execArgs(d, "XTRIM", "empty", "MAXLEN", "0")
+	execArgs(d, "XGROUP", "CREATE", "empty", "g", "$")
+	want := streamState(t, d, "s", "g")
+	wantEmpty := streamState(t, d, "empty", "g")
+	if _, ok := execArgs(d, "SAVE").(*resp.StatusReply); !ok {
+		t.Fatalf("SAVE failed")
+	}
+	if err := d.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	d.Close()
+
+	check := func(name string, db *StandaloneDB) {
+		t.Helper()
+		if got := streamState(t, db, "s", "g"); got != want {
+			t.Fatalf("%s: state = %s, want %s", name, got, want)
+		}
+		if got := streamState(t, db, "empty", "g"); got != wantEmpty {
+			t.Fatalf("%s: empty stream state = %s, want %s", name, got, wantEmpty)
+		}
+		// 组的 last-delivered-id 一致：只剩最后一条未投递
+		r := execArgs(db, "XREADGROUP", "GROUP", "g", "c9", "STREAMS", "s", ">")
+		if got := len(readIDs(t, r, "s")); got != 1 {
+			t.Fatalf("%s: undelivered entries = %d", name, got)
+		}
+	}
+
+	fromAOF := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, MaxBytes: DefaultMaxBytes})
+	fromAOF.Load()
+	check("aof", fromAOF)
+	// 重写后再重放一次
+	wantRewrite := streamState(t, fromAOF, "s", "g")
+	if _, ok := execArgs(fromAOF, "REWRITEAOF").(*resp.StatusReply); !ok {
+		t.Fatalf("REWRITEAOF failed")
+	}
+	fromAOF.Close()
+	rewritten := NewStandaloneDB(aofFile)
+	rewritten.Load()
+	if got := streamState(t, rewritten, "s", "g"); got != wantRewrite {
+		t.Fatalf("rewrite: state = %s, want %s", got, wantRewrite)
+	}
+	if got := streamState(t, rewritten, "empty", "g"); got != wantEmpty {
+		t.Fatalf("rewrite: empty stream state = %s, want %s", got, wantEmpty)
+	}
+	rewritten.Close()
+
+	fromRDB := NewStandaloneDBWithConfig(StandaloneDBConfig{RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	fromRDB.Load()
+	defer fromRDB.Close()
+	check("rdb", fromRDB)
+}
@@ -4,79 +4,140 @@
 package db
 
 import (
+	"math"
 	"myredis/resp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // 本文件实现 TTL 相关命令：
-// - EXPIRE：相对秒（对外命令）
-// - TTL：查询剩余秒数（不应影响 LRU/LFU 统计，因此用 cache.Peek）
+// - EXPIRE / PEXPIRE：相对秒/毫秒；EXPIREAT / PEXPIREAT：绝对秒/毫秒时间戳
+//   均支持 Redis 7 的 NX/XX/GT/LT 条件，AOF 统一改写为 PEXPIREAT（避免重启续命）
+// - TTL / PTTL：查询剩余秒数/毫秒数；EXPIRETIME / PEXPIRETIME：查询绝对过期时间
+//   （不应影响 LRU/LFU 统计，因此用 cache.Peek）
 // - PERSIST：取消过期
-// - PEXPIREAT：绝对毫秒时间戳（主要用于 AOF 重放，避免重启续命）
 
 // --- TTL Commands ---
 
-// EXPIRE key seconds
-func (db *StandaloneDB) expire(args [][]byte) resp.Reply {
-	if len(args) != 3 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'expire' command")
+// expireFlags 为 EXPIRE 系列的 Redis 7 条件选项（可组合，例如 XX GT）。
+type expireFlags struct {
+	nx, xx, gt, lt bool
+}
+
+// parseExpireFlags 解析 EXPIRE 系列的 NX/XX/GT/LT 选项，并校验互斥关系。
+func parseExpireFlags(args [][]byte) (expireFlags, resp.Reply) {
+	var f expireFlags
+	for _, a := range args {
+		switch strings.ToLower(string(a)) {
+		case "nx":
+			f.nx = true
+		case "xx":
+			f.xx = true
+		case "gt":
+			f.gt = true
+		case "lt":
+			f.lt = true
+		default:
+			return f, resp.MakeErrReply("ERR Unsupported option " + string(a))
+		}
 	}
-	key := string(args[1])
-	seconds, err := strconv.ParseInt(string(args[2]), 10, 64)
-	if err != nil {
-		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	if f.nx && (f.xx || f.gt || f.lt) {
+		return f, resp.MakeErrReply("ERR NX and XX, GT or LT options at the same time are not compatible")
 	}
-
-	// Check if key exists
-	if _, ok := db.cache.Peek(key); !ok {
-		return resp.MakeIntReply(0)
+	if f.gt && f.lt {
+		return f, resp.MakeErrReply("ERR GT and LT options at the same time are not compatible")
 	}
+	return f, nil
+}
 
-	// Redis: seconds <= 0 相当于立即过期（删除 key）
-	if seconds <= 0 {
-		db.cache.Remove(key)
-		return resp.MakeIntReply(1)
-	}
+// allow 判断在当前 TTL 状态下是否允许设置新的过期时间（没有 TTL 视为无限长）。
+func (f expireFlags) allow(cur time.Time, hasTTL bool, at time.Time) bool {
+	switch {
+	case f.nx && hasTTL:
+		return false
+	case f.xx && !hasTTL:
+		return false
+	case f.gt && (!hasTTL || !at.After(cur)):
+		return false
+	case f.lt && hasTTL && !at.Before(cur):
+		return false
+	}
+	return true
+}
 
-	// Set TTL
-	db.ttlMap[key] = time.Now().Add(time.Duration(seconds) * time.Second)
+// expireAtMillis 把过期参数 t 换算为 Unix 毫秒时间戳：unit 为时间单位（秒/毫秒），absolute 表示 t 为 Unix 时间戳，
+// 否则相对当前时间。换算溢出 int64 时返回 false（调用方回复 ERR invalid expire time）。
+// EXPIRE 系列、SET 与 GETEX 共用，不经过 time.Duration（纳秒精度的 Duration 只能表示约 292 年）。
+func expireAtMillis(t int64, unit time.Duration, absolute bool) (int64, bool) {
+	unitMs := int64(unit / time.Millisecond)
+	base := int64(0)
+	if !absolute {
+		base = time.Now().UnixMilli()
+	}
+	if (t > 0 && t > (math.MaxInt64-base)/unitMs) || (t < 0 && t < (math.MinInt64+base)/unitMs) {
+		return 0, false
+	}
+	return base + t*unitMs, true
+}
 
-	return resp.MakeIntReply(1)
+// expireOption 返回 SET/GETEX 过期选项（EX/PX/EXAT/PXAT，大写）的时间单位与是否为绝对时间戳。
+func expireOption(opt string) (unit time.Duration, absolute bool) {
+	unit = time.Millisecond
+	if opt == "EX" || opt == "EXAT" {
+		unit = time.Second
+	}
+	return unit, strings.HasSuffix(opt, "AT")
 }
 
-// PEXPIREAT key unix_ms
-// 说明：对齐 Redis AOF 的绝对过期时间语义，用于重放时保持一致性。
-func (db *StandaloneDB) pexpireat(args [][]byte) resp.Reply {
-	if len(args) != 3 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'pexpireat' command")
+// EXPIRE / PEXPIRE / EXPIREAT / PEXPIREAT key time [NX|XX|GT|LT]
+// - unit 为时间单位（秒/毫秒），absolute 表示 time 为 Unix 时间戳
+// - 条件不满足或 key 不存在返回 0；设置成功返回 1
+// - 过期时间不晚于当前时间时直接删除 key（同样返回 1）
+func (db *StandaloneDB) expireGeneric(args [][]byte, name string, unit time.Duration, absolute bool) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
 	}
 	key := string(args[1])
-	ms, err := strconv.ParseInt(string(args[2]), 10, 64)
+	t, err := strconv.ParseInt(string(args[2]), 10, 64)
 	if err != nil {
 		return resp.MakeErrReply("ERR value is not an integer or out of range")
 	}
+	flags, errReply := parseExpireFlags(args[3:])
+	if errReply != nil {
+		return errReply
+	}
 
-	// key 不存在则返回 0（与 Redis 行为一致）
-	if _, ok := db.cache.Peek(key); !ok {
+	ms, ok := expireAtMillis(t, unit, absolute)
+	if !ok {
+		return resp.MakeErrReply("ERR invalid expire time in '" + name + "' command")
+	}
+	expireAt := time.UnixMilli(ms)
+
+	// key 不存在则返回 0（与 Redis 行为一致）；使用 peekEntity 不影响 LRU/LFU 统计
+	if _, ok := db.peekEntity(key); !ok {
+		return resp.MakeIntReply(0)
+	}
+	cur, hasTTL := db.ttlMap[key]
+	if !flags.allow(cur, hasTTL, expireAt) {
 		return resp.MakeIntReply(0)
 	}
 
-	expireAt := time.UnixMilli(ms)
 	if !expireAt.After(time.Now()) {
 		// 已经过期，直接删除
 		db.cache.Remove(key)
 		return resp.MakeIntReply(1)
 	}
-
 	db.ttlMap[key] = expireAt
+	db.touchWatched(key)
 	return resp.MakeIntReply(1)
 }
 
-// TTL key
-func (db *StandaloneDB) ttl(args [][]byte) resp.Reply {
+// TTL / PTTL / EXPIRETIME / PEXPIRETIME key
+// 返回 -2 表示 key 不存在，-1 表示没有 TTL；否则为剩余时间（TTL/PTTL）或绝对时间戳（EXPIRETIME/PEXPIRETIME）。
+func (db *StandaloneDB) ttlGeneric(args [][]byte, name string, unit time.Duration, absolute bool) resp.Reply {
 	if len(args) != 2 {
-		return resp.MakeErrReply("ERR wrong number of arguments for 'ttl' command")
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
 	}
 	key := string(args[1])
 
@@ -91,14 +152,18 @@ func (db *StandaloneDB) ttl(args [][]byte) resp.Reply {
 		return resp.MakeIntReply(-1) // No expire
 	}
 
-	ttl := time.Until(expireTime)
-	if ttl <= 0 {
+	// 按 Unix 毫秒相减：time.Until 返回的 Duration 在约 292 年处饱和，远期过期时间（如 EXPIRE 1e10）会算错
+	ttlMs := expireTime.UnixMilli() - time.Now().UnixMilli()
+	if ttlMs <= 0 {
 		// 已过期但尚未被访问触发惰性删除：这里直接删除并返回 -2
 		db.cache.Remove(key)
 		return resp.MakeIntReply(-2)
 	}
 
-	return resp.MakeIntReply(int64(ttl.Seconds()))
+	if absolute {
+		return resp.MakeIntReply(expireTime.UnixMilli() / int64(unit/time.Millisecond))
+	}
+	return resp.MakeIntReply(ttlMs / int64(unit/time.Millisecond))
 }
 
 // PERSIST key
@@ -118,6 +183,7 @@ func (db *StandaloneDB) persist(args [][]byte) resp.Reply {
 	}
 
 	delete(db.ttlMap, key)
+	db.touchWatched(key)
 	return resp.MakeIntReply(1)
 }
 
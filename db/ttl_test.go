@@ -0,0 +1,149 @@
+// TTL 命令族测试：覆盖 PEXPIRE/EXPIREAT/PEXPIREAT、PTTL/EXPIRETIME/PEXPIRETIME 与 NX/XX/GT/LT 条件。
+// 目标：保证“只延长、不缩短”（GT）等刷新逻辑可以原子表达，且选项互斥关系与 Redis 一致。
+// 覆盖：所有变体在 AOF 中统一记录为 PEXPIREAT（不带条件选项），重放后过期时间不变；远期 TTL 不因 Duration 饱和而算错。
+package db
+
+import (
+	"bytes"
+	"myredis/resp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTTL_VariantsAndConditions(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	execArgs(d, "SET", "k", "v")
+	if n := replyInt(t, execArgs(d, "PEXPIRE", "k", "5000")); n != 1 {
+		t.Fatalf("PEXPIRE = %d", n)
+	}
+	if ms := replyInt(t, execArgs(d, "PTTL", "k")); ms <= 4000 || ms > 5000 {
+		t.Fatalf("PTTL = %d", ms)
+	}
+
+	// GT：只延长不缩短；LT：只缩短不延长
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "2", "GT")); n != 0 {
+		t.Fatalf("EXPIRE GT shorter = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "100", "GT")); n != 1 {
+		t.Fatalf("EXPIRE GT longer = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "200", "LT")); n != 0 {
+		t.Fatalf("EXPIRE LT longer = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "50", "XX", "LT")); n != 1 {
+		t.Fatalf("EXPIRE XX LT = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "10", "NX")); n != 0 {
+		t.Fatalf("EXPIRE NX with TTL = %d", n)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "k")); ttl < 49 || ttl > 50 {
+		t.Fatalf("TTL = %d", ttl)
+	}
+
+	// 没有 TTL 的 key：GT 视为无限长（失败），LT 成功
+	execArgs(d, "SET", "p", "v")
+	if n := replyInt(t, execArgs(d, "EXPIRE", "p", "10", "GT")); n != 0 {
+		t.Fatalf("EXPIRE GT on persistent key = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "p", "10", "XX")); n != 0 {
+		t.Fatalf("EXPIRE XX on persistent key = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXPIRE", "p", "10", "LT")); n != 1 {
+		t.Fatalf("EXPIRE LT on persistent key = %d", n)
+	}
+
+	at := time.Now().Add(time.Hour).Unix()
+	execArgs(d, "EXPIREAT", "p", strconv.FormatInt(at, 10))
+	if got := replyInt(t, execArgs(d, "EXPIRETIME", "p")); got != at {
+		t.Fatalf("EXPIRETIME = %d, want %d", got, at)
+	}
+	if got := replyInt(t, execArgs(d, "PEXPIRETIME", "p")); got != at*1000 {
+		t.Fatalf("PEXPIRETIME = %d", got)
+	}
+	execArgs(d, "SET", "noexp", "v")
+	if got := replyInt(t, execArgs(d, "PEXPIRETIME", "noexp")); got != -1 {
+		t.Fatalf("PEXPIRETIME without TTL = %d", got)
+	}
+	if got := replyInt(t, execArgs(d, "PTTL", "missing")); got != -2 {
+		t.Fatalf("PTTL missing = %d", got)
+	}
+
+	// 过去的绝对时间直接删除 key
+	if n := replyInt(t, execArgs(d, "PEXPIREAT", "noexp", "1")); n != 1 {
+		t.Fatalf("PEXPIREAT past = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "EXISTS", "noexp")); n != 0 {
+		t.Fatalf("key should be deleted by past PEXPIREAT")
+	}
+
+	if er, ok := execArgs(d, "EXPIRE", "k", "10", "NX", "XX").(*resp.ErrorReply); !ok || er.Status != "ERR NX and XX, GT or LT options at the same time are not compatible" {
+		t.Fatalf("NX XX = %+v", er)
+	}
+	if er, ok := execArgs(d, "EXPIRE", "k", "10", "GT", "LT").(*resp.ErrorReply); !ok || er.Status != "ERR GT and LT options at the same time are not compatible" {
+		t.Fatalf("GT LT = %+v", er)
+	}
+	if er, ok := execArgs(d, "EXPIRE", "k", "10", "FOO").(*resp.ErrorReply); !ok || er.Status != "ERR Unsupported option FOO" {
+		t.Fatalf("unknown option = %+v", er)
+	}
+	if er, ok := execArgs(d, "EXPIRE", "k", "9223372036854775807").(*resp.ErrorReply); !ok || er.Status != "ERR invalid expire time in 'expire' command" {
+		t.Fatalf("overflow = %+v", er)
+	}
+
+	// 超过 time.Duration 上限（约 292 年）的 TTL 不能饱和
+	if n := replyInt(t, execArgs(d, "EXPIRE", "k", "10000000000")); n != 1 {
+		t.Fatalf("EXPIRE far future = %d", n)
+	}
+	if ttl := replyInt(t, execArgs(d, "TTL", "k")); ttl < 9999999999 || ttl > 10000000000 {
+		t.Fatalf("TTL far future = %d", ttl)
+	}
+	if ms := replyInt(t, execArgs(d, "PTTL", "k")); ms <= 9999999999000 || ms > 10000000000000 {
+		t.Fatalf("PTTL far future = %d", ms)
+	}
+}
+
+func TestTTL_AOFNormalizesToPExpireAt(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "node.aof")
+	d := NewStandaloneDB(filename)
+
+	execArgs(d, "SET", "a", "1")
+	execArgs(d, "SET", "b", "2")
+	execArgs(d, "SET", "c", "3")
+	execArgs(d, "PEXPIRE", "a", "100000", "NX")
+	execArgs(d, "EXPIRE", "a", "1", "GT") // 条件不满足，不应记录
+	execArgs(d, "EXPIREAT", "b", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	execArgs(d, "PEXPIRE", "c", "0")
+	wantA := replyInt(t, execArgs(d, "PEXPIRETIME", "a"))
+	wantB := replyInt(t, execArgs(d, "PEXPIRETIME", "b"))
+
+	if err := d.aofHandler.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("read aof: %v", err)
+	}
+	for _, bad := range []string{"$7\r\nPEXPIRE\r", "$8\r\nEXPIREAT\r", "$6\r\nEXPIRE\r", "$2\r\nNX\r", "$2\r\nGT\r"} {
+		if bytes.Contains(data, []byte(bad)) {
+			t.Fatalf("AOF should only contain PEXPIREAT, found %q in %q", bad, data)
+		}
+	}
+	d.Close()
+
+	d2 := NewStandaloneDB(filename)
+	d2.Load()
+	defer d2.Close()
+	if got := replyInt(t, execArgs(d2, "PEXPIRETIME", "a")); got != wantA {
+		t.Fatalf("a PEXPIRETIME after replay = %d, want %d", got, wantA)
+	}
+	if got := replyInt(t, execArgs(d2, "PEXPIRETIME", "b")); got != wantB {
+		t.Fatalf("b PEXPIRETIME after replay = %d, want %d", got, wantB)
+	}
+	if n := replyInt(t, execArgs(d2, "EXISTS", "c")); n != 0 {
+		t.Fatalf("c should stay deleted after replay")
+	}
+}
@@ -41,25 +41,102 @@ func (d ListData) Len() int {
 	return size
 }
 
-// Hash
-type HashData map[string][]byte
+// Hash：fields 保存字段值；expires 保存设置了字段级过期时间的字段（field -> 绝对过期时间 UnixMilli）；
+// scan 为 HSCAN 的游标索引（见 scan.go）。三者在构造时分配，存入缓存的值拷贝共享同一份底层数据。
+type HashData struct {
+	fields  map[string][]byte
+	expires map[string]int64
+	scan    *memberIndex
+}
+
+func newHashData() HashData {
+	return HashData{fields: make(map[string][]byte), expires: make(map[string]int64), scan: &memberIndex{}}
+}
 
 func (d HashData) Len() int {
 	// 粗略估算
 	size := 0
-	for k, v := range d {
+	for k, v := range d.fields {
 		size += len(k) + len(v) + 16 // 16 overhead
 	}
-	return size
+	return size + len(d.expires)*8
+}
+
+// Set：members 保存成员；scan 为 SSCAN 的游标索引（见 scan.go）。存入缓存的值拷贝共享同一份底层数据。
+type SetData struct {
+	members map[string]struct{}
+	scan    *memberIndex
+}
+
+func newSetData(n int) SetData {
+	return SetData{members: make(map[string]struct{}, n), scan: &memberIndex{}}
 }
 
-// Set
-type SetData map[string]struct{}
+// NewSetData 由成员列表构造集合（集群 Router 汇总各节点的成员时使用）。
+func NewSetData(members [][]byte) SetData {
+	s := newSetData(len(members))
+	for _, m := range members {
+		s.add(string(m))
+	}
+	return s
+}
 
 func (d SetData) Len() int {
 	size := 0
-	for k := range d {
+	for k := range d.members {
 		size += len(k) + 16
 	}
 	return size
 }
+
+// ZSet 有序集合：dict 提供 O(1) 的 member -> score 查询，zsl 提供按 (score, member) 排序的跳表索引，
+// scan 为 ZSCAN 的游标索引（见 scan.go）。
+type ZSetData struct {
+	dict map[string]float64
+	zsl  *skiplist
+	scan *memberIndex
+}
+
+func newZSetData() ZSetData {
+	return ZSetData{dict: make(map[string]float64), zsl: newSkiplist(), scan: &memberIndex{}}
+}
+
+func (d ZSetData) Len() int {
+	size := 0
+	for m := range d.dict {
+		size += len(m) + 8 + 32 // score(8) + dict/跳表节点 overhead
+	}
+	return size
+}
+
+// Stream：entries 按 ID 严格递增排列（XADD 只会在尾部追加），按 ID 查找/范围查询使用二分；
+// groups 为消费者组。Stream 以指针存入缓存，命令原地修改后再 Add 同一个指针：缓存按条目记录的旧大小计算差值，
+// 超出 max-bytes 时触发淘汰（只读命令不需要 Add）。
+type StreamData struct {
+	entries      []streamEntry
+	lastID       streamID // 曾经分配过的最大 ID（条目被裁剪/删空后仍保留）
+	maxDeletedID streamID
+	entriesAdded uint64 // 历史上 XADD 的总条目数
+	groups       map[string]*streamGroup
+}
+
+func newStreamData() *StreamData {
+	return &StreamData{groups: make(map[string]*streamGroup)}
+}
+
+func (d *StreamData) Len() int {
+	size := 0
+	for _, e := range d.entries {
+		size += 16 + 24 // ID + 切片头
+		for _, f := range e.fields {
+			size += len(f) + 8
+		}
+	}
+	for name, g := range d.groups {
+		size += len(name) + 16 + len(g.pel)*48
+		for c := range g.consumers {
+			size += len(c) + 16
+		}
+	}
+	return size
+}
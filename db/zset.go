@@ -0,0 +1,786 @@
+// ZSet 命令实现：ZADD/ZREM/ZSCORE/ZINCRBY/ZCARD/ZRANK/ZRANGE/ZCOUNT/ZPOP*/ZREMRANGEBY* 等。
+// 说明：ZSetData 由 dict（member -> score）+ 跳表（按 score/member 排序）组成，两者必须同步维护。
+// 关键点：元素删空时移除 key（同步清理 TTL）；写命令原样落 AOF（语义确定，可直接重放）。
+package db
+
+import (
+	"math"
+	"myredis/resp"
+	"strconv"
+	"strings"
+)
+
+// 本文件实现 Sorted Set 相关命令：
+// - 写：ZADD（NX/XX/GT/LT/CH/INCR）/ ZREM / ZINCRBY / ZPOPMIN / ZPOPMAX / ZREMRANGEBYSCORE|RANK|LEX
+// - 读：ZSCORE / ZCARD / ZRANK / ZREVRANK / ZCOUNT / ZRANGE（BYSCORE/BYLEX/REV/LIMIT/WITHSCORES）
+//   以及兼容旧版本的 ZREVRANGE / ZRANGEBYSCORE / ZREVRANGEBYSCORE / ZRANGEBYLEX / ZREVRANGEBYLEX
+
+// --- ZSetData 基础操作 ---
+
+// score 返回 member 的分值。
+func (d ZSetData) score(member string) (float64, bool) {
+	s, ok := d.dict[member]
+	return s, ok
+}
+
+// add 写入/更新 member 的分值，返回是否为新增。
+func (d ZSetData) add(member string, score float64) bool {
+	if cur, ok := d.dict[member]; ok {
+		if cur != score {
+			d.zsl.updateScore(cur, member, score)
+			d.dict[member] = score
+		}
+		return false
+	}
+	d.dict[member] = score
+	d.zsl.insert(score, member)
+	d.scan.added(member)
+	return true
+}
+
+// remove 删除 member，返回是否存在。
+func (d ZSetData) remove(member string) bool {
+	score, ok := d.dict[member]
+	if !ok {
+		return false
+	}
+	delete(d.dict, member)
+	d.zsl.remove(score, member)
+	d.scan.removed(member)
+	return true
+}
+
+func (d ZSetData) length() int64 {
+	return d.zsl.length
+}
+
+// getZSet 读取 ZSET（含惰性过期）。key 不存在返回 exists=false；类型不符返回 WRONGTYPE。
+func (db *StandaloneDB) getZSet(key string) (ZSetData, bool, resp.Reply) {
+	entity, ok := db.getEntity(key)
+	if !ok {
+		return ZSetData{}, false, nil
+	}
+	zs, ok := entity.(ZSetData)
+	if !ok {
+		return ZSetData{}, false, resp.MakeErrReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	}
+	return zs, true, nil
+}
+
+// storeZSet 写回 ZSET：为空时删除 key（同时清理 TTL），否则重新 Add。
+// dict/zsl 已被原地修改，缓存按条目记录的旧大小计算差值，超出 max-bytes 时触发淘汰。
+func (db *StandaloneDB) storeZSet(key string, zs ZSetData) {
+	if zs.length() == 0 {
+		if _, ok := db.cache.Peek(key); ok {
+			db.cache.Remove(key)
+		}
+		return
+	}
+	db.cache.Add(key, zs, 0)
+}
+
+// --- 参数解析 ---
+
+// formatScore 格式化分值（与 ZSCORE 在 RESP2 下的输出一致）。
+func formatScore(f float64) string {
+	return resp.FormatDouble(f)
+}
+
+func parseScore(b []byte) (float64, bool) {
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+func parseScoreRange(minArg, maxArg []byte) (*scoreRange, bool) {
+	r := &scoreRange{}
+	var ok bool
+	min, max := minArg, maxArg
+	if len(min) > 0 && min[0] == '(' {
+		r.minEx = true
+		min = min[1:]
+	}
+	if len(max) > 0 && max[0] == '(' {
+		r.maxEx = true
+		max = max[1:]
+	}
+	if r.min, ok = parseScore(min); !ok {
+		return nil, false
+	}
+	if r.max, ok = parseScore(max); !ok {
+		return nil, false
+	}
+	return r, true
+}
+
+func parseLexRange(minArg, maxArg []byte) (*lexRange, bool) {
+	r := &lexRange{}
+	min, max := string(minArg), string(maxArg)
+
+	switch {
+	case min == "-":
+		r.minInf = true
+	case min == "+":
+		r.none = true
+	case strings.HasPrefix(min, "["):
+		r.min = min[1:]
+	case strings.HasPrefix(min, "("):
+		r.min = min[1:]
+		r.minEx = true
+	default:
+		return nil, false
+	}
+
+	switch {
+	case max == "+":
+		r.maxInf = true
+	case max == "-":
+		r.none = true
+	case strings.HasPrefix(max, "["):
+		r.max = max[1:]
+	case strings.HasPrefix(max, "("):
+		r.max = max[1:]
+		r.maxEx = true
+	default:
+		return nil, false
+	}
+	return r, true
+}
+
+// normalizeRankRange 将可能为负数的 [start, stop] 转换为合法的 0-based 下标区间。
+// 返回 ok=false 表示区间为空。
+func normalizeRankRange(start, stop, size int64) (int64, int64, bool) {
+	if start < 0 {
+		start = size + start
+	}
+	if stop < 0 {
+		stop = size + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+	if start > stop || start >= size {
+		return 0, 0, false
+	}
+	return start, stop, true
+}
+
+// --- 写命令 ---
+
+// ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]
+func (db *StandaloneDB) zadd(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zadd' command")
+	}
+	key := string(args[1])
+
+	var nx, xx, gt, lt, ch, incr bool
+	i := 2
+	for ; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		case "ch":
+			ch = true
+		case "incr":
+			incr = true
+		default:
+			goto parsePairs
+		}
+	}
+
+parsePairs:
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	if nx && xx {
+		return resp.MakeErrReply("ERR XX and NX options at the same time are not compatible")
+	}
+	if (gt && lt) || (gt && nx) || (lt && nx) {
+		return resp.MakeErrReply("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if incr && len(pairs) > 2 {
+		return resp.MakeErrReply("ERR INCR option supports a single increment-element pair")
+	}
+
+	// 先校验全部分值，避免部分写入
+	scores := make([]float64, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, ok := parseScore(pairs[j])
+		if !ok {
+			return resp.MakeErrReply("ERR value is not a valid float")
+		}
+		scores = append(scores, score)
+	}
+
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		zs = newZSetData()
+	}
+
+	added, updated := 0, 0
+	var incrResult float64
+	incrApplied := false
+	for j := 0; j < len(pairs); j += 2 {
+		member := string(pairs[j+1])
+		score := scores[j/2]
+
+		cur, has := zs.score(member)
+		if has {
+			if nx {
+				continue
+			}
+			newScore := score
+			if incr {
+				newScore = cur + score
+				if math.IsNaN(newScore) {
+					return resp.MakeErrReply("ERR resulting score is not a number (NaN)")
+				}
+			}
+			if (gt && newScore <= cur) || (lt && newScore >= cur) {
+				continue
+			}
+			if newScore != cur {
+				zs.add(member, newScore)
+				updated++
+			}
+			incrResult, incrApplied = newScore, true
+		} else {
+			if xx {
+				continue
+			}
+			zs.add(member, score)
+			added++
+			incrResult, incrApplied = score, true
+		}
+	}
+
+	if added+updated > 0 {
+		db.storeZSet(key, zs)
+	}
+
+	if incr {
+		if !incrApplied {
+			return resp.NullBulkReply
+		}
+		return resp.MakeDoubleBulkReply(incrResult)
+	}
+	if ch {
+		return resp.MakeIntReply(int64(added + updated))
+	}
+	return resp.MakeIntReply(int64(added))
+}
+
+// ZREM key member [member ...]
+func (db *StandaloneDB) zrem(args [][]byte) resp.Reply {
+	if len(args) < 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zrem' command")
+	}
+	key := string(args[1])
+
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+
+	removed := 0
+	for _, m := range args[2:] {
+		if zs.remove(string(m)) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		db.storeZSet(key, zs)
+	}
+	return resp.MakeIntReply(int64(removed))
+}
+
+// ZINCRBY key increment member
+func (db *StandaloneDB) zincrby(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zincrby' command")
+	}
+	key := string(args[1])
+	incr, ok := parseScore(args[2])
+	if !ok {
+		return resp.MakeErrReply("ERR value is not a valid float")
+	}
+	member := string(args[3])
+
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		zs = newZSetData()
+	}
+
+	cur, _ := zs.score(member)
+	newScore := cur + incr
+	if math.IsNaN(newScore) {
+		return resp.MakeErrReply("ERR resulting score is not a number (NaN)")
+	}
+	zs.add(member, newScore)
+	db.storeZSet(key, zs)
+	return resp.MakeDoubleBulkReply(newScore)
+}
+
+// ZPOPMIN key [count] / ZPOPMAX key [count]
+func (db *StandaloneDB) zpop(args [][]byte, max bool) resp.Reply {
+	name := "zpopmin"
+	if max {
+		name = "zpopmax"
+	}
+	if len(args) != 2 && len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	key := string(args[1])
+	count := int64(1)
+	if len(args) == 3 {
+		n, err := strconv.ParseInt(string(args[2]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+		if n < 0 {
+			return resp.MakeErrReply("ERR value is out of range, must be positive")
+		}
+		count = n
+	}
+
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeMultiBulkReply([][]byte{})
+	}
+
+	res := make([][]byte, 0)
+	for i := int64(0); i < count && zs.length() > 0; i++ {
+		var node *skiplistNode
+		if max {
+			node = zs.zsl.tail
+		} else {
+			node = zs.zsl.header.level[0].forward
+		}
+		member, score := node.member, node.score
+		zs.remove(member)
+		res = append(res, []byte(member), []byte(formatScore(score)))
+	}
+	db.storeZSet(key, zs)
+	return resp.MakeMultiBulkReply(res)
+}
+
+// ZREMRANGEBYSCORE key min max
+func (db *StandaloneDB) zremrangebyscore(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zremrangebyscore' command")
+	}
+	r, ok := parseScoreRange(args[2], args[3])
+	if !ok {
+		return resp.MakeErrReply("ERR min or max is not a float")
+	}
+	return db.zremRange(string(args[1]), func(zs ZSetData) []string {
+		var members []string
+		if r.empty() {
+			return members
+		}
+		for x := zs.zsl.firstInScoreRange(r); x != nil && r.belowMax(x.score); x = x.level[0].forward {
+			members = append(members, x.member)
+		}
+		return members
+	})
+}
+
+// ZREMRANGEBYRANK key start stop
+func (db *StandaloneDB) zremrangebyrank(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zremrangebyrank' command")
+	}
+	start, err1 := strconv.ParseInt(string(args[2]), 10, 64)
+	stop, err2 := strconv.ParseInt(string(args[3]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return resp.MakeErrReply("ERR value is not an integer or out of range")
+	}
+	return db.zremRange(string(args[1]), func(zs ZSetData) []string {
+		var members []string
+		start, stop, ok := normalizeRankRange(start, stop, zs.length())
+		if !ok {
+			return members
+		}
+		x := zs.zsl.getByRank(start + 1)
+		for i := start; i <= stop && x != nil; i++ {
+			members = append(members, x.member)
+			x = x.level[0].forward
+		}
+		return members
+	})
+}
+
+// ZREMRANGEBYLEX key min max
+func (db *StandaloneDB) zremrangebylex(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zremrangebylex' command")
+	}
+	r, ok := parseLexRange(args[2], args[3])
+	if !ok {
+		return resp.MakeErrReply("ERR min or max not valid string range item")
+	}
+	return db.zremRange(string(args[1]), func(zs ZSetData) []string {
+		var members []string
+		if r.empty() {
+			return members
+		}
+		for x := zs.zsl.firstInLexRange(r); x != nil && r.belowMax(x.member); x = x.level[0].forward {
+			members = append(members, x.member)
+		}
+		return members
+	})
+}
+
+// zremRange 为 ZREMRANGEBY* 的公共流程：先收集待删除成员再统一删除，避免边遍历边修改跳表。
+func (db *StandaloneDB) zremRange(key string, collect func(zs ZSetData) []string) resp.Reply {
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+
+	members := collect(zs)
+	for _, m := range members {
+		zs.remove(m)
+	}
+	if len(members) > 0 {
+		db.storeZSet(key, zs)
+	}
+	return resp.MakeIntReply(int64(len(members)))
+}
+
+// --- 读命令 ---
+
+// ZSCORE key member
+func (db *StandaloneDB) zscore(args [][]byte) resp.Reply {
+	if len(args) != 3 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zscore' command")
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	score, ok := zs.score(string(args[2]))
+	if !ok {
+		return resp.NullBulkReply
+	}
+	return resp.MakeDoubleBulkReply(score)
+}
+
+// ZCARD key
+func (db *StandaloneDB) zcard(args [][]byte) resp.Reply {
+	if len(args) != 2 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zcard' command")
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.MakeIntReply(0)
+	}
+	return resp.MakeIntReply(zs.length())
+}
+
+// ZRANK key member / ZREVRANK key member
+func (db *StandaloneDB) zrank(args [][]byte, rev bool) resp.Reply {
+	if len(args) != 3 {
+		name := "zrank"
+		if rev {
+			name = "zrevrank"
+		}
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists {
+		return resp.NullBulkReply
+	}
+	member := string(args[2])
+	score, ok := zs.score(member)
+	if !ok {
+		return resp.NullBulkReply
+	}
+	rank := zs.zsl.getRank(score, member)
+	if rev {
+		return resp.MakeIntReply(zs.length() - rank)
+	}
+	return resp.MakeIntReply(rank - 1)
+}
+
+// ZCOUNT key min max
+func (db *StandaloneDB) zcount(args [][]byte) resp.Reply {
+	if len(args) != 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zcount' command")
+	}
+	r, ok := parseScoreRange(args[2], args[3])
+	if !ok {
+		return resp.MakeErrReply("ERR min or max is not a float")
+	}
+	zs, exists, errReply := db.getZSet(string(args[1]))
+	if errReply != nil {
+		return errReply
+	}
+	if !exists || r.empty() {
+		return resp.MakeIntReply(0)
+	}
+
+	first := zs.zsl.firstInScoreRange(r)
+	if first == nil {
+		return resp.MakeIntReply(0)
+	}
+	last := zs.zsl.lastInScoreRange(r)
+	// 利用排名相减得到区间长度（O(logN)）
+	count := zs.zsl.getRank(last.score, last.member) - zs.zsl.getRank(first.score, first.member) + 1
+	return resp.MakeIntReply(count)
+}
+
+// zrangeSpec 描述一次范围查询（ZRANGE 及其旧版本变体共用）。
+type zrangeSpec struct {
+	byScore    bool
+	byLex      bool
+	rev        bool
+	withScores bool
+	hasLimit   bool
+	offset     int64
+	count      int64 // <0 表示不限制
+}
+
+// ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func (db *StandaloneDB) zrange(args [][]byte) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zrange' command")
+	}
+	spec := zrangeSpec{count: -1}
+	for i := 4; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "byscore":
+			spec.byScore = true
+		case "bylex":
+			spec.byLex = true
+		case "rev":
+			spec.rev = true
+		case "withscores":
+			spec.withScores = true
+		case "limit":
+			if i+2 >= len(args) {
+				return resp.MakeErrReply("ERR syntax error")
+			}
+			offset, err1 := strconv.ParseInt(string(args[i+1]), 10, 64)
+			count, err2 := strconv.ParseInt(string(args[i+2]), 10, 64)
+			if err1 != nil || err2 != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			spec.hasLimit, spec.offset, spec.count = true, offset, count
+			i += 2
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	if spec.byScore && spec.byLex {
+		return resp.MakeErrReply("ERR syntax error")
+	}
+	if spec.hasLimit && !spec.byScore && !spec.byLex {
+		return resp.MakeErrReply("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if spec.withScores && spec.byLex {
+		return resp.MakeErrReply("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	return db.zrangeGeneric(string(args[1]), args[2], args[3], spec)
+}
+
+// ZREVRANGE key start stop [WITHSCORES]
+func (db *StandaloneDB) zrevrange(args [][]byte) resp.Reply {
+	if len(args) != 4 && len(args) != 5 {
+		return resp.MakeErrReply("ERR wrong number of arguments for 'zrevrange' command")
+	}
+	spec := zrangeSpec{rev: true, count: -1}
+	if len(args) == 5 {
+		if !strings.EqualFold(string(args[4]), "withscores") {
+			return resp.MakeErrReply("ERR syntax error")
+		}
+		spec.withScores = true
+	}
+	return db.zrangeGeneric(string(args[1]), args[2], args[3], spec)
+}
+
+// ZRANGEBYSCORE / ZREVRANGEBYSCORE / ZRANGEBYLEX / ZREVRANGEBYLEX key min max [WITHSCORES] [LIMIT offset count]
+// 注意：REV 变体的参数顺序为 max min。
+func (db *StandaloneDB) zrangeByLegacy(args [][]byte, name string, byLex, rev bool) resp.Reply {
+	if len(args) < 4 {
+		return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+	}
+	spec := zrangeSpec{byScore: !byLex, byLex: byLex, rev: rev, count: -1}
+	for i := 4; i < len(args); i++ {
+		switch strings.ToLower(string(args[i])) {
+		case "withscores":
+			if byLex {
+				return resp.MakeErrReply("ERR syntax error")
+			}
+			spec.withScores = true
+		case "limit":
+			if i+2 >= len(args) {
+				return resp.MakeErrReply("ERR syntax error")
+			}
+			offset, err1 := strconv.ParseInt(string(args[i+1]), 10, 64)
+			count, err2 := strconv.ParseInt(string(args[i+2]), 10, 64)
+			if err1 != nil || err2 != nil {
+				return resp.MakeErrReply("ERR value is not an integer or out of range")
+			}
+			spec.hasLimit, spec.offset, spec.count = true, offset, count
+			i += 2
+		default:
+			return resp.MakeErrReply("ERR syntax error")
+		}
+	}
+	return db.zrangeGeneric(string(args[1]), args[2], args[3], spec)
+}
+
+// zrangeGeneric 执行范围查询。BYSCORE/BYLEX 下 REV 的参数语义为 (max, min)。
+func (db *StandaloneDB) zrangeGeneric(key string, startArg, stopArg []byte, spec zrangeSpec) resp.Reply {
+	var (
+		scoreR *scoreRange
+		lexR   *lexRange
+		start  int64
+		stop   int64
+		ok     bool
+	)
+	switch {
+	case spec.byScore:
+		minArg, maxArg := startArg, stopArg
+		if spec.rev {
+			minArg, maxArg = stopArg, startArg
+		}
+		if scoreR, ok = parseScoreRange(minArg, maxArg); !ok {
+			return resp.MakeErrReply("ERR min or max is not a float")
+		}
+	case spec.byLex:
+		minArg, maxArg := startArg, stopArg
+		if spec.rev {
+			minArg, maxArg = stopArg, startArg
+		}
+		if lexR, ok = parseLexRange(minArg, maxArg); !ok {
+			return resp.MakeErrReply("ERR min or max not valid string range item")
+		}
+	default:
+		var err1, err2 error
+		start, err1 = strconv.ParseInt(string(startArg), 10, 64)
+		stop, err2 = strconv.ParseInt(string(stopArg), 10, 64)
+		if err1 != nil || err2 != nil {
+			return resp.MakeErrReply("ERR value is not an integer or out of range")
+		}
+	}
+
+	zs, exists, errReply := db.getZSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	res := make([][]byte, 0)
+	if !exists {
+		return resp.MakeMultiBulkReply(res)
+	}
+
+	emit := func(x *skiplistNode) {
+		res = append(res, []byte(x.member))
+		if spec.withScores {
+			res = append(res, []byte(formatScore(x.score)))
+		}
+	}
+	next := func(x *skiplistNode) *skiplistNode {
+		if spec.rev {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+
+	// 按排名
+	if !spec.byScore && !spec.byLex {
+		size := zs.length()
+		start, stop, ok = normalizeRankRange(start, stop, size)
+		if !ok {
+			return resp.MakeMultiBulkReply(res)
+		}
+		var x *skiplistNode
+		if spec.rev {
+			x = zs.zsl.getByRank(size - start)
+		} else {
+			x = zs.zsl.getByRank(start + 1)
+		}
+		for i := start; i <= stop && x != nil; i++ {
+			emit(x)
+			x = next(x)
+		}
+		return resp.MakeMultiBulkReply(res)
+	}
+
+	// 按分值 / 字典序：先定位起点，再跳过 offset，最后按 count 输出
+	var x *skiplistNode
+	var inRange func(x *skiplistNode) bool
+	if spec.byScore {
+		if scoreR.empty() {
+			return resp.MakeMultiBulkReply(res)
+		}
+		if spec.rev {
+			x = zs.zsl.lastInScoreRange(scoreR)
+			inRange = func(x *skiplistNode) bool { return scoreR.aboveMin(x.score) }
+		} else {
+			x = zs.zsl.firstInScoreRange(scoreR)
+			inRange = func(x *skiplistNode) bool { return scoreR.belowMax(x.score) }
+		}
+	} else {
+		if lexR.empty() {
+			return resp.MakeMultiBulkReply(res)
+		}
+		if spec.rev {
+			x = zs.zsl.lastInLexRange(lexR)
+			inRange = func(x *skiplistNode) bool { return lexR.aboveMin(x.member) }
+		} else {
+			x = zs.zsl.firstInLexRange(lexR)
+			inRange = func(x *skiplistNode) bool { return lexR.belowMax(x.member) }
+		}
+	}
+
+	if spec.offset < 0 {
+		return resp.MakeMultiBulkReply(res)
+	}
+	for offset := spec.offset; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	for count := spec.count; x != nil && count != 0 && inRange(x); count-- {
+		emit(x)
+		x = next(x)
+	}
+	return resp.MakeMultiBulkReply(res)
+}
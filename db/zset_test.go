@@ -0,0 +1,231 @@
+// ZSet 测试：覆盖 ZADD 选项语义、范围查询（按排名/分值/字典序）、排名计算与持久化往返。
+// 目标：保证跳表与 dict 同步维护，且 RDB/AOF rewrite 能完整恢复有序集合。
+// 覆盖：ZADD NX/XX/GT/LT/CH/INCR、ZRANGE BYSCORE/BYLEX/REV/LIMIT、ZPOP*、ZREMRANGEBY*、原地增长触发淘汰、SAVE/REWRITEAOF + 重启。
+package db
+
+import (
+	"fmt"
+	"myredis/resp"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func execArgs(d *StandaloneDB, args ...string) resp.Reply {
+	cmd := make([][]byte, 0, len(args))
+	for _, a := range args {
+		cmd = append(cmd, []byte(a))
+	}
+	return d.Exec(cmd)
+}
+
+func replyStrings(t *testing.T, r resp.Reply) []string {
+	t.Helper()
+	mb, ok := r.(*resp.MultiBulkReply)
+	if !ok {
+		t.Fatalf("expected array, got %T %+v", r, r)
+	}
+	out := make([]string, 0, len(mb.Args))
+	for _, a := range mb.Args {
+		out = append(out, string(a))
+	}
+	return out
+}
+
+func replyInt(t *testing.T, r resp.Reply) int64 {
+	t.Helper()
+	ir, ok := r.(*resp.IntReply)
+	if !ok {
+		t.Fatalf("expected int, got %T %+v", r, r)
+	}
+	return ir.Code
+}
+
+func TestZSet_AddOptionsAndScore(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	if n := replyInt(t, execArgs(d, "ZADD", "z", "1", "a", "2", "b", "3", "c")); n != 3 {
+		t.Fatalf("ZADD added = %d", n)
+	}
+	// NX：已存在的不更新
+	if n := replyInt(t, execArgs(d, "ZADD", "z", "NX", "10", "a", "4", "d")); n != 1 {
+		t.Fatalf("ZADD NX added = %d", n)
+	}
+	// XX + CH：只更新已存在的，返回变更数
+	if n := replyInt(t, execArgs(d, "ZADD", "z", "XX", "CH", "5", "a", "9", "e")); n != 1 {
+		t.Fatalf("ZADD XX CH changed = %d", n)
+	}
+	// GT：新分值更小则不更新
+	execArgs(d, "ZADD", "z", "GT", "1", "a")
+	if br := execArgs(d, "ZSCORE", "z", "a").(*resp.BulkReply); string(br.Arg) != "5" {
+		t.Fatalf("ZSCORE a = %q", br.Arg)
+	}
+	// INCR
+	if br := execArgs(d, "ZADD", "z", "INCR", "1.5", "a").(*resp.BulkReply); string(br.Arg) != "6.5" {
+		t.Fatalf("ZADD INCR = %q", br.Arg)
+	}
+	if br := execArgs(d, "ZINCRBY", "z", "-0.5", "a").(*resp.BulkReply); string(br.Arg) != "6" {
+		t.Fatalf("ZINCRBY = %q", br.Arg)
+	}
+	if _, ok := execArgs(d, "ZADD", "z", "NX", "XX", "1", "a").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected error for NX+XX")
+	}
+	if _, ok := execArgs(d, "ZADD", "z", "abc", "a").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected error for invalid float")
+	}
+
+	if n := replyInt(t, execArgs(d, "ZCARD", "z")); n != 4 {
+		t.Fatalf("ZCARD = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "ZRANK", "z", "a")); n != 3 {
+		t.Fatalf("ZRANK a = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "ZREVRANK", "z", "a")); n != 0 {
+		t.Fatalf("ZREVRANK a = %d", n)
+	}
+
+	execArgs(d, "SET", "str", "v")
+	if _, ok := execArgs(d, "ZADD", "str", "1", "a").(*resp.ErrorReply); !ok {
+		t.Fatalf("expected WRONGTYPE")
+	}
+}
+
+func TestZSet_RangeQueries(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	for i := 0; i < 10; i++ {
+		execArgs(d, "ZADD", "z", fmt.Sprint(i), fmt.Sprintf("m%d", i))
+	}
+
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "z", "0", "2")), ","); got != "m0,m1,m2" {
+		t.Fatalf("ZRANGE 0 2 = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "z", "0", "1", "REV", "WITHSCORES")), ","); got != "m9,9,m8,8" {
+		t.Fatalf("ZRANGE REV WITHSCORES = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "z", "(2", "5", "BYSCORE")), ","); got != "m3,m4,m5" {
+		t.Fatalf("ZRANGE BYSCORE = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "1", "2")), ","); got != "m8,m7" {
+		t.Fatalf("ZRANGE BYSCORE REV LIMIT = %s", got)
+	}
+	if n := replyInt(t, execArgs(d, "ZCOUNT", "z", "3", "(7")); n != 4 {
+		t.Fatalf("ZCOUNT = %d", n)
+	}
+
+	execArgs(d, "ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d")
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "lex", "[b", "(d", "BYLEX")), ","); got != "b,c" {
+		t.Fatalf("ZRANGE BYLEX = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGEBYLEX", "lex", "-", "+", "LIMIT", "1", "2")), ","); got != "b,c" {
+		t.Fatalf("ZRANGEBYLEX LIMIT = %s", got)
+	}
+
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZPOPMIN", "z", "2")), ","); got != "m0,0,m1,1" {
+		t.Fatalf("ZPOPMIN = %s", got)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZPOPMAX", "z")), ","); got != "m9,9" {
+		t.Fatalf("ZPOPMAX = %s", got)
+	}
+	if n := replyInt(t, execArgs(d, "ZREMRANGEBYSCORE", "z", "-inf", "3")); n != 2 {
+		t.Fatalf("ZREMRANGEBYSCORE = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "ZREMRANGEBYRANK", "z", "0", "0")); n != 1 {
+		t.Fatalf("ZREMRANGEBYRANK = %d", n)
+	}
+	if got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "z", "0", "-1")), ","); got != "m5,m6,m7,m8" {
+		t.Fatalf("ZRANGE after removals = %s", got)
+	}
+	if n := replyInt(t, execArgs(d, "ZREMRANGEBYLEX", "lex", "-", "+")); n != 4 {
+		t.Fatalf("ZREMRANGEBYLEX = %d", n)
+	}
+	if n := replyInt(t, execArgs(d, "ZCARD", "lex")); n != 0 {
+		t.Fatalf("ZCARD lex after remove = %d", n)
+	}
+}
+
+func TestZSet_SkiplistRankConsistency(t *testing.T) {
+	d := NewStandaloneDB("")
+	defer d.Close()
+
+	// 大量插入/更新/删除后，排名必须与 ZRANGE 顺序一致
+	const n = 500
+	for i := 0; i < n; i++ {
+		execArgs(d, "ZADD", "z", fmt.Sprint((i*37)%n), fmt.Sprintf("m%03d", i))
+	}
+	for i := 0; i < n; i += 3 {
+		execArgs(d, "ZINCRBY", "z", "1000", fmt.Sprintf("m%03d", i))
+	}
+	for i := 1; i < n; i += 5 {
+		execArgs(d, "ZREM", "z", fmt.Sprintf("m%03d", i))
+	}
+
+	members := replyStrings(t, execArgs(d, "ZRANGE", "z", "0", "-1"))
+	for rank, m := range members {
+		if got := replyInt(t, execArgs(d, "ZRANK", "z", m)); got != int64(rank) {
+			t.Fatalf("ZRANK %s = %d, want %d", m, got, rank)
+		}
+	}
+}
+
+func TestZSet_EvictionAfterGrowth(t *testing.T) {
+	d := NewStandaloneDBWithConfig(StandaloneDBConfig{MaxBytes: 2000, Eviction: "lru"})
+	defer d.Close()
+
+	// other 约 105 字节；z 每个 80 字节的 member 计 120 字节，16 个后合计超过 2000
+	execArgs(d, "SET", "other", strings.Repeat("v", 100))
+	for i := 0; i < 16; i++ {
+		execArgs(d, "ZADD", "z", fmt.Sprint(i), fmt.Sprintf("%080d", i))
+	}
+	// 原地增长的 ZSET 也要计入内存：最久未访问的 other 被淘汰
+	if got := replyInt(t, execArgs(d, "EXISTS", "other")); got != 0 {
+		t.Fatalf("other should be evicted after z grew past max-bytes")
+	}
+	if got := replyInt(t, execArgs(d, "ZCARD", "z")); got != 16 {
+		t.Fatalf("ZCARD z = %d, want 16", got)
+	}
+}
+
+func TestZSet_PersistenceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	aofFile := filepath.Join(dir, "appendonly.aof")
+	rdbFile := filepath.Join(dir, "dump.rdb")
+
+	db1 := NewStandaloneDBWithConfig(StandaloneDBConfig{
+		AofFilename: aofFile,
+		RdbFilename: rdbFile,
+		MaxBytes:    DefaultMaxBytes,
+		Eviction:    "lru",
+	})
+	execArgs(db1, "ZADD", "board", "100", "alice", "-2.5", "bob", "+inf", "carol")
+	execArgs(db1, "ZINCRBY", "board", "1", "alice")
+	if _, ok := execArgs(db1, "SAVE").(*resp.StatusReply); !ok {
+		t.Fatalf("SAVE failed")
+	}
+	if _, ok := execArgs(db1, "REWRITEAOF").(*resp.StatusReply); !ok {
+		t.Fatalf("REWRITEAOF failed")
+	}
+	db1.Close()
+
+	check := func(d *StandaloneDB) {
+		t.Helper()
+		got := strings.Join(replyStrings(t, execArgs(d, "ZRANGE", "board", "0", "-1", "WITHSCORES")), ",")
+		if got != "bob,-2.5,alice,101,carol,inf" {
+			t.Fatalf("ZRANGE after restart = %s", got)
+		}
+	}
+
+	// 仅 RDB
+	db2 := NewStandaloneDBWithConfig(StandaloneDBConfig{RdbFilename: rdbFile, MaxBytes: DefaultMaxBytes})
+	db2.Load()
+	check(db2)
+	db2.Close()
+
+	// 仅 AOF（重写后的文件）
+	db3 := NewStandaloneDBWithConfig(StandaloneDBConfig{AofFilename: aofFile, MaxBytes: DefaultMaxBytes})
+	db3.Load()
+	check(db3)
+	db3.Close()
+}
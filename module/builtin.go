@@ -0,0 +1,47 @@
+// 内置命令名表：模块命令不能与它们同名。
+// 说明：内置命令由 db.execCommand 的 switch 以及 server/cluster 的连接级命令分发，模块包不能反向依赖这些包，因此在这里维护名称表。
+// 关键点：db 的单元测试解析 execCommand 的 case 列表并与本表比对，新增内置命令时漏改本表会导致测试失败。
+package module
+
+// builtinCommands 为内置命令名（小写）。
+var builtinCommands = map[string]struct{}{
+	// db.execCommand
+	"append": {}, "bgrewriteaof": {}, "bgsave": {}, "bitcount": {}, "bitfield": {}, "bitfield_ro": {},
+	"bitop": {}, "bitpos": {}, "blmove": {}, "blpop": {}, "brpop": {}, "config": {}, "copy": {},
+	"dbsize": {}, "decr": {}, "decrby": {}, "del": {}, "eval": {}, "evalsha": {}, "exists": {},
+	"expire": {}, "expireat": {}, "expiretime": {}, "flushall": {}, "flushdb": {}, "geoadd": {},
+	"geodist": {}, "geopos": {}, "geosearch": {}, "geosearchstore": {}, "get": {}, "getbit": {},
+	"getdel": {}, "getex": {}, "getrange": {}, "getset": {}, "hdel": {}, "hexists": {}, "hexpire": {},
+	"hexpireat": {}, "hexpiretime": {}, "hget": {}, "hgetall": {}, "hincrby": {}, "hincrbyfloat": {},
+	"hkeys": {}, "hlen": {}, "hmget": {}, "hmset": {}, "hpersist": {}, "hpexpire": {},
+	"hpexpireat": {}, "hpexpiretime": {}, "hpttl": {}, "hrandfield": {}, "hscan": {}, "hset": {},
+	"hsetnx": {}, "hstrlen": {}, "httl": {}, "hvals": {}, "incr": {}, "incrby": {}, "incrbyfloat": {},
+	"lindex": {}, "linsert": {}, "llen": {}, "lmove": {}, "lpop": {}, "lpos": {}, "lpush": {},
+	"lpushx": {}, "lrange": {}, "lrem": {}, "lset": {}, "ltrim": {}, "mget": {}, "mset": {},
+	"msetnx": {}, "persist": {}, "pexpire": {}, "pexpireat": {}, "pexpiretime": {}, "pfadd": {},
+	"pfcount": {}, "pfmerge": {}, "ping": {}, "pttl": {}, "publish": {}, "pubsub": {}, "randomkey": {},
+	"rename": {}, "renamenx": {}, "rewriteaof": {}, "rpop": {}, "rpoplpush": {}, "rpush": {},
+	"rpushx": {}, "sadd": {}, "save": {}, "scan": {}, "scard": {}, "script": {}, "sdiff": {},
+	"sdiffstore": {}, "set": {}, "setbit": {}, "setnx": {}, "setrange": {}, "sinter": {},
+	"sintercard": {}, "sinterstore": {}, "sismember": {}, "smembers": {}, "smismember": {},
+	"smove": {}, "spop": {}, "srandmember": {}, "srem": {}, "sscan": {}, "strlen": {}, "sunion": {},
+	"sunionstore": {}, "touch": {}, "ttl": {}, "type": {}, "xack": {}, "xadd": {}, "xautoclaim": {},
+	"xclaim": {}, "xgroup": {}, "xlen": {}, "xpending": {}, "xrange": {}, "xread": {},
+	"xreadgroup": {}, "xrevrange": {}, "xsetid": {}, "xtrim": {}, "zadd": {}, "zcard": {},
+	"zcount": {}, "zincrby": {}, "zpopmax": {}, "zpopmin": {}, "zrange": {}, "zrangebylex": {},
+	"zrangebyscore": {}, "zrank": {}, "zrem": {}, "zremrangebylex": {}, "zremrangebyrank": {},
+	"zremrangebyscore": {}, "zrevrange": {}, "zrevrangebylex": {}, "zrevrangebyscore": {},
+	"zrevrank": {}, "zscan": {}, "zscore": {},
+	// server：连接级命令（事务、订阅、HELLO/RESET/QUIT、SHUTDOWN）
+	"multi": {}, "exec": {}, "discard": {}, "watch": {}, "unwatch": {},
+	"subscribe": {}, "unsubscribe": {}, "psubscribe": {}, "punsubscribe": {},
+	"hello": {}, "reset": {}, "quit": {}, "shutdown": {},
+	// cluster：节点间内部命令
+	"peerhandshake": {}, "localscan": {}, "localexec": {},
+}
+
+// IsBuiltin 判断 name（小写）是否为内置命令名。
+func IsBuiltin(name string) bool {
+	_, ok := builtinCommands[name]
+	return ok
+}
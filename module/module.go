@@ -0,0 +1,185 @@
+// 模块 API：在不修改 db.execInternal 命令分发的前提下注册自定义命令与自定义数据类型。
+// 说明：模块通常在自己包的 init() 中调用 Register/RegisterType，由 cmd/main.go 空导入（import _）后生效，形式上类似 Redis 的 MODULE LOAD。
+// 关键点：命令声明参数个数、标志位与 key 位置，StandaloneDB 据此做参数检查与 AOF 记录，cluster.Router 据此路由；自定义类型通过 Encode/Decode 进入 RDB。
+package module
+
+import (
+	"errors"
+	"myredis/resp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 本包实现模块注册表：
+// - Register(cmd)：注册命令。与内置命令（见 builtin.go）同名或重复注册时返回错误
+// - RegisterType(t)：注册自定义类型，TYPE 命令返回 t.Name，RDB 中以 t.Name 标识
+// - Lookup / LookupType：供 db 与 cluster 查询
+//
+// 约定：
+// - 注册应在服务启动（以及 AOF/RDB 加载）之前完成，否则加载时遇到模块命令/类型会失败
+// - Handler 在 DB 的 Actor 线程中执行，整个命令是原子的；Handler 内不能阻塞，也不能再调用 DB.Exec
+// - 标记为 FlagWrite 的命令执行成功后原样写入 AOF，因此命令本身必须是确定性的（不要依赖随机数、当前时间的相对值等）
+
+// Flag 为命令标志位。
+type Flag uint32
+
+const (
+	// FlagWrite 表示写命令：执行成功（返回非错误）后写入 AOF。
+	FlagWrite Flag = 1 << iota
+	// FlagDenyScript 表示不允许在 Lua 脚本中通过 redis.call 调用。
+	FlagDenyScript
+)
+
+// Value 为自定义类型的值；Len 用于 LRU/LFU 的内存估算。
+// 值以指针形式存入 DB 并原地修改时，修改后应以同一个值再调用一次 Keyspace.Put：DB 按上次 Put 时记录的 Len
+// 计算差值并在超出 max-bytes 时淘汰；不调用 Put 时大小统计停留在上次 Put 的结果。
+type Value interface {
+	Len() int
+}
+
+// Type 描述一个自定义数据类型。
+type Type struct {
+	// Name 为 TYPE 命令的返回值，同时是 RDB 中的类型标识，注册后不能修改。
+	Name string
+	// Encode/Decode 为 RDB 序列化钩子（必填）。Encode 在 Actor 线程中调用，返回的字节必须是独立的拷贝。
+	Encode func(v Value) ([]byte, error)
+	Decode func(data []byte) (Value, error)
+	// AofRewrite 返回重建该值的命令（不含 TTL，TTL 由重写流程统一追加）；为 nil 时 BGREWRITEAOF 会失败。
+	AofRewrite func(key []byte, v Value) [][][]byte
+	// Copy 为 COPY 命令使用的深拷贝；为 nil 时通过 Encode/Decode 完成。
+	Copy func(v Value) Value
+}
+
+// ErrWrongType 为 Keyspace 在 key 类型不匹配时返回的错误（与 Redis 的 WRONGTYPE 错误一致）。
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// Keyspace 为命令 Handler 访问数据库的接口（由 db 实现），所有方法都执行惰性过期。
+type Keyspace interface {
+	// GetString 读取字符串值；key 不存在返回 ok=false，类型不是字符串返回 ErrWrongType。
+	GetString(key string) (val []byte, ok bool, err error)
+	// SetString 写入字符串值并清除 TTL（等同于不带选项的 SET）。
+	SetString(key string, val []byte)
+	// Get 读取类型为 t 的值；key 不存在返回 ok=false，类型不是 t 返回 ErrWrongType。
+	Get(key string, t *Type) (v Value, ok bool, err error)
+	// Put 写入类型为 t 的值（覆盖已有值；key 已存在时保留 TTL），并按 v.Len() 更新内存统计（见 Value）。
+	Put(key string, t *Type, v Value)
+	// Delete 删除 key，返回 key 是否存在。
+	Delete(key string) bool
+	// Exists 判断 key 是否存在。
+	Exists(key string) bool
+	// TTL 返回剩余生存时间；key 不存在或没有 TTL 时 ok=false。
+	TTL(key string) (ttl time.Duration, ok bool)
+}
+
+// Command 描述一个模块命令。
+type Command struct {
+	// Name 为命令名（大小写不敏感）。
+	Name string
+	// Arity 与 Redis 的 arity 语义一致（包含命令名本身）：正数表示参数个数必须相等，负数表示至少 -Arity 个。
+	Arity int
+	Flags Flag
+	// FirstKey/LastKey/KeyStep 与 Redis COMMAND 的 key 位置语义一致：
+	// FirstKey 为 0 表示命令没有 key；LastKey 为负数表示从末尾倒数（-1 为最后一个参数）；KeyStep 为 0 时按 1 处理。
+	FirstKey int
+	LastKey  int
+	KeyStep  int
+	// Handler 执行命令；args 包含命令名，且已通过 Arity 检查。
+	Handler func(ks Keyspace, args [][]byte) resp.Reply
+}
+
+// IsWrite 判断命令是否为写命令。
+func (c *Command) IsWrite() bool {
+	return c.Flags&FlagWrite != 0
+}
+
+// CheckArity 判断参数个数（包含命令名）是否满足 Arity。
+func (c *Command) CheckArity(n int) bool {
+	if c.Arity >= 0 {
+		return n == c.Arity
+	}
+	return n >= -c.Arity
+}
+
+// Keys 按 key 位置声明从 args（包含命令名）中取出 key。
+func (c *Command) Keys(args [][]byte) [][]byte {
+	if c.FirstKey <= 0 || c.FirstKey >= len(args) {
+		return nil
+	}
+	last := c.LastKey
+	if last < 0 {
+		last += len(args)
+	}
+	if last >= len(args) {
+		last = len(args) - 1
+	}
+	step := c.KeyStep
+	if step <= 0 {
+		step = 1
+	}
+	var keys [][]byte
+	for i := c.FirstKey; i <= last; i += step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+var (
+	mu       sync.RWMutex
+	commands = make(map[string]*Command)
+	types    = make(map[string]*Type)
+)
+
+// Register 注册模块命令；命令名与内置命令相同、重复注册或声明不完整时返回错误。
+func Register(cmd *Command) error {
+	if cmd == nil || cmd.Name == "" || cmd.Handler == nil {
+		return errors.New("module: command name and handler are required")
+	}
+	if cmd.Arity == 0 {
+		return errors.New("module: command arity must not be 0")
+	}
+	if cmd.FirstKey < 0 || (cmd.FirstKey > 0 && cmd.LastKey > 0 && cmd.LastKey < cmd.FirstKey) {
+		return errors.New("module: invalid key positions for command '" + cmd.Name + "'")
+	}
+	name := strings.ToLower(cmd.Name)
+	if IsBuiltin(name) {
+		return errors.New("module: command '" + name + "' conflicts with a built-in command")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := commands[name]; ok {
+		return errors.New("module: command '" + name + "' already registered")
+	}
+	commands[name] = cmd
+	return nil
+}
+
+// RegisterType 注册自定义类型；类型名重复或缺少 RDB 钩子时返回错误。
+func RegisterType(t *Type) error {
+	if t == nil || t.Name == "" || t.Encode == nil || t.Decode == nil {
+		return errors.New("module: type name and Encode/Decode are required")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := types[t.Name]; ok {
+		return errors.New("module: type '" + t.Name + "' already registered")
+	}
+	types[t.Name] = t
+	return nil
+}
+
+// Lookup 按命令名（小写）查找模块命令。
+func Lookup(name string) (*Command, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	cmd, ok := commands[name]
+	return cmd, ok
+}
+
+// LookupType 按类型名查找自定义类型。
+func LookupType(name string) (*Type, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, ok := types[name]
+	return t, ok
+}
@@ -0,0 +1,94 @@
+// 模块注册表测试：key 位置、参数个数与注册校验。
+// 覆盖：FirstKey/LastKey/KeyStep 的各种组合、负数 arity、重复注册、与内置命令同名与不完整声明。
+package module
+
+import (
+	"myredis/resp"
+	"strings"
+	"testing"
+)
+
+func splitArgs(s string) [][]byte {
+	var args [][]byte
+	for _, f := range strings.Fields(s) {
+		args = append(args, []byte(f))
+	}
+	return args
+}
+
+func TestCommandKeys(t *testing.T) {
+	cases := []struct {
+		first, last, step int
+		args              string
+		want              string
+	}{
+		{1, 1, 1, "get k", "k"},
+		{1, -1, 1, "mget a b c", "a b c"},
+		{1, -1, 2, "mset a 1 b 2", "a b"},
+		{2, 3, 1, "op dst a b c", "a b"},
+		{0, 0, 0, "ping", ""},
+		{1, 1, 0, "cmd", ""},
+		{1, 5, 1, "cmd a b", "a b"},
+	}
+	for _, c := range cases {
+		cmd := &Command{FirstKey: c.first, LastKey: c.last, KeyStep: c.step}
+		var keys []string
+		for _, k := range cmd.Keys(splitArgs(c.args)) {
+			keys = append(keys, string(k))
+		}
+		if got := strings.Join(keys, " "); got != c.want {
+			t.Fatalf("Keys(%d,%d,%d, %q) = %q, want %q", c.first, c.last, c.step, c.args, got, c.want)
+		}
+	}
+}
+
+func TestCheckArity(t *testing.T) {
+	exact := &Command{Arity: 2}
+	atLeast := &Command{Arity: -2}
+	if !exact.CheckArity(2) || exact.CheckArity(3) || exact.CheckArity(1) {
+		t.Fatal("exact arity check failed")
+	}
+	if !atLeast.CheckArity(2) || !atLeast.CheckArity(5) || atLeast.CheckArity(1) {
+		t.Fatal("minimum arity check failed")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	handler := func(ks Keyspace, args [][]byte) resp.Reply { return resp.OkReply }
+	if err := Register(&Command{Name: "Test.Reg", Arity: 1, Handler: handler}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Lookup("test.reg"); !ok {
+		t.Fatal("registered command should be found by its lower-case name")
+	}
+	for _, bad := range []*Command{
+		{Name: "test.reg", Arity: 1, Handler: handler},
+		{Name: "GET", Arity: 2, Handler: handler},
+		{Name: "subscribe", Arity: -2, Handler: handler},
+		{Name: "test.nohandler", Arity: 1},
+		{Name: "test.noarity", Handler: handler},
+		{Name: "test.badkeys", Arity: -1, FirstKey: 3, LastKey: 2, Handler: handler},
+	} {
+		if err := Register(bad); err == nil {
+			t.Fatalf("Register(%q) should fail", bad.Name)
+		}
+	}
+
+	typ := &Type{
+		Name:   "test.type",
+		Encode: func(v Value) ([]byte, error) { return nil, nil },
+		Decode: func(data []byte) (Value, error) { return nil, nil },
+	}
+	if err := RegisterType(typ); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := LookupType("test.type"); !ok || got != typ {
+		t.Fatal("registered type not found")
+	}
+	if err := RegisterType(typ); err == nil {
+		t.Fatal("duplicate type should fail")
+	}
+	if err := RegisterType(&Type{Name: "test.nohooks"}); err == nil {
+		t.Fatal("type without Encode/Decode should fail")
+	}
+}
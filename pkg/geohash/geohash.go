@@ -0,0 +1,246 @@
+// geohash 包：经纬度与 52 位 geohash 整数之间的编解码，以及 GEO 搜索所需的距离/范围计算。
+// 说明：编码方式与 Redis 相同（纬度占偶数位、经度占奇数位，各 26 位），因此 GEOADD 写入的 ZSET 分值与 Redis 一致。
+// 关键点：纬度范围限制在 Web Mercator 的 ±85.05112878；距离使用 Haversine 公式，地球半径 6372797.560856 米。
+package geohash
+
+import "math"
+
+// 本文件实现 GEO 所需的几何工具：
+// - Encode / Decode / DecodeCenter：经纬度 <-> geohash（step 位精度，最大 26）
+// - Neighbors：九宫格（自身 + 8 个相邻格子），用于把范围搜索转化为若干个分值区间
+// - EstimateSteps / BoundingBox：根据搜索半径选择合适的格子精度
+// - Distance / InRadius / InBox：精确过滤候选点
+//
+// 搜索流程：先按半径估算精度，计算中心点所在格子及其 8 个邻居，每个格子对应 ZSET 中一段连续的分值区间，
+// 扫描这些区间得到候选点，再用精确距离过滤。
+
+const (
+	MaxStep = 26 // 每个维度的最大位数，52 位整数可以被 float64 精确表示
+
+	LonMin = -180.0
+	LonMax = 180.0
+	LatMin = -85.05112878
+	LatMax = 85.05112878
+
+	earthRadius = 6372797.560856 // 米，与 Redis 相同
+	mercatorMax = 20037726.37
+)
+
+// Hash 为某一精度下的 geohash 格子。
+type Hash struct {
+	Bits uint64
+	Step uint
+}
+
+// Area 为 geohash 格子覆盖的经纬度范围。
+type Area struct {
+	LonMin, LonMax float64
+	LatMin, LatMax float64
+}
+
+// Valid 校验经纬度是否在可编码范围内。
+func Valid(lon, lat float64) bool {
+	return lon >= LonMin && lon <= LonMax && lat >= LatMin && lat <= LatMax
+}
+
+// Encode 把经纬度编码为 step 精度的 geohash。调用方需先用 Valid 校验。
+func Encode(lon, lat float64, step uint) Hash {
+	latOffset := (lat - LatMin) / (LatMax - LatMin)
+	lonOffset := (lon - LonMin) / (LonMax - LonMin)
+	scale := float64(uint64(1) << step)
+	latIdx := uint32(latOffset * scale)
+	lonIdx := uint32(lonOffset * scale)
+	// 边界值（LatMax/LonMax）会落到 1<<step，需要收回到最后一个格子
+	if max := uint32(uint64(1)<<step - 1); latIdx > max {
+		latIdx = max
+	}
+	if max := uint32(uint64(1)<<step - 1); lonIdx > max {
+		lonIdx = max
+	}
+	return Hash{Bits: interleave(latIdx, lonIdx), Step: step}
+}
+
+// Decode 返回 geohash 格子覆盖的范围。
+func Decode(h Hash) Area {
+	latIdx, lonIdx := deinterleave(h.Bits)
+	scale := float64(uint64(1) << h.Step)
+	latScale := LatMax - LatMin
+	lonScale := LonMax - LonMin
+	return Area{
+		LatMin: LatMin + float64(latIdx)/scale*latScale,
+		LatMax: LatMin + float64(latIdx+1)/scale*latScale,
+		LonMin: LonMin + float64(lonIdx)/scale*lonScale,
+		LonMax: LonMin + float64(lonIdx+1)/scale*lonScale,
+	}
+}
+
+// DecodeCenter 把 52 位 geohash 解码为格子中心点的经纬度（GEOPOS 的返回值）。
+func DecodeCenter(bits uint64) (lon, lat float64) {
+	a := Decode(Hash{Bits: bits, Step: MaxStep})
+	lon = math.Max(LonMin, math.Min(LonMax, (a.LonMin+a.LonMax)/2))
+	lat = math.Max(LatMin, math.Min(LatMax, (a.LatMin+a.LatMax)/2))
+	return lon, lat
+}
+
+// Align52 把 step 精度的格子转换为 52 位分值区间 [min, max)。
+func (h Hash) Align52() (min, max uint64) {
+	shift := 52 - 2*h.Step
+	return h.Bits << shift, (h.Bits + 1) << shift
+}
+
+// Neighbors 返回格子自身及 8 个相邻格子（经度方向回绕，纬度越界的格子被丢弃，结果去重）。
+func Neighbors(h Hash) []Hash {
+	latIdx, lonIdx := deinterleave(h.Bits)
+	size := int64(1) << h.Step
+	out := make([]Hash, 0, 9)
+	seen := make(map[uint64]struct{}, 9)
+	for dLat := int64(-1); dLat <= 1; dLat++ {
+		lat := int64(latIdx) + dLat
+		if lat < 0 || lat >= size {
+			continue
+		}
+		for dLon := int64(-1); dLon <= 1; dLon++ {
+			lon := (int64(lonIdx) + dLon + size) % size
+			bits := interleave(uint32(lat), uint32(lon))
+			if _, ok := seen[bits]; ok {
+				continue
+			}
+			seen[bits] = struct{}{}
+			out = append(out, Hash{Bits: bits, Step: h.Step})
+		}
+	}
+	return out
+}
+
+// EstimateSteps 估算能让九宫格覆盖 radius 米的最大精度（与 Redis geohashEstimateStepsByRadius 相同）。
+func EstimateSteps(radius, lat float64) uint {
+	if radius == 0 {
+		return MaxStep
+	}
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	step -= 2
+	// 高纬度地区经度方向的格子更窄
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > MaxStep {
+		step = MaxStep
+	}
+	return uint(step)
+}
+
+// BoundingBox 返回以 (lon, lat) 为中心、半宽 halfWidth、半高 halfHeight（米）的外接经纬度矩形。
+func BoundingBox(lon, lat, halfWidth, halfHeight float64) Area {
+	latDelta := radToDeg(halfHeight / earthRadius)
+	lonDeltaTop := radToDeg(halfWidth / earthRadius / math.Cos(degToRad(lat+latDelta)))
+	lonDeltaBottom := radToDeg(halfWidth / earthRadius / math.Cos(degToRad(lat-latDelta)))
+	lonDelta := lonDeltaTop
+	if lat < 0 {
+		lonDelta = lonDeltaBottom
+	}
+	return Area{
+		LonMin: lon - lonDelta,
+		LonMax: lon + lonDelta,
+		LatMin: lat - latDelta,
+		LatMax: lat + latDelta,
+	}
+}
+
+// SearchAreas 返回覆盖搜索范围所需的格子（中心格子 + 邻居）。
+// halfWidth/halfHeight 为搜索范围的外接矩形半宽/半高（米），radius 为用于估算精度的半径。
+func SearchAreas(lon, lat, halfWidth, halfHeight, radius float64) []Hash {
+	step := EstimateSteps(radius, lat)
+	box := BoundingBox(lon, lat, halfWidth, halfHeight)
+	center := Encode(lon, lat, step)
+
+	// 搜索范围贴近中心格子边缘时，相邻格子可能仍不足以覆盖，此时降低一级精度
+	if step > 1 {
+		c := Decode(center)
+		cellH := c.LatMax - c.LatMin
+		cellW := c.LonMax - c.LonMin
+		if c.LatMax+cellH < box.LatMax || c.LatMin-cellH > box.LatMin ||
+			c.LonMax+cellW < box.LonMax || c.LonMin-cellW > box.LonMin {
+			step--
+			center = Encode(lon, lat, step)
+		}
+	}
+	return Neighbors(center)
+}
+
+// Distance 计算两点间的球面距离（米）。
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	lon1r, lon2r := degToRad(lon1), degToRad(lon2)
+	v := math.Sin((lon2r - lon1r) / 2)
+	if v == 0 {
+		return latDistance(lat1, lat2)
+	}
+	lat1r, lat2r := degToRad(lat1), degToRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// InRadius 判断点是否在以 (lon, lat) 为圆心、radius 米为半径的圆内，并返回距离。
+func InRadius(lon, lat, radius, pLon, pLat float64) (float64, bool) {
+	d := Distance(lon, lat, pLon, pLat)
+	return d, d <= radius
+}
+
+// InBox 判断点是否在以 (lon, lat) 为中心、宽 width、高 height（米）的矩形内，并返回到中心的距离。
+func InBox(lon, lat, width, height, pLon, pLat float64) (float64, bool) {
+	// 纬度方向距离计算更便宜，先判断
+	if latDistance(pLat, lat) > height/2 {
+		return 0, false
+	}
+	if Distance(pLon, pLat, lon, pLat) > width/2 {
+		return 0, false
+	}
+	return Distance(lon, lat, pLon, pLat), true
+}
+
+func latDistance(lat1, lat2 float64) float64 {
+	return earthRadius * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+func degToRad(d float64) float64 { return d * math.Pi / 180 }
+func radToDeg(r float64) float64 { return r / (math.Pi / 180) }
+
+// interleave 交错两个 32 位整数：x 占偶数位，y 占奇数位。
+func interleave(x, y uint32) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave 为 interleave 的逆操作。
+func deinterleave(bits uint64) (x, y uint32) {
+	return squash(bits), squash(bits >> 1)
+}
+
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func squash(v uint64) uint32 {
+	x := v & 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
@@ -0,0 +1,89 @@
+// geohash 单元测试：验证编码结果与 Redis 一致、解码误差、九宫格与精度估算。
+// 目标：GEOADD 写入的分值能与 Redis 互通；范围搜索的候选格子能完整覆盖搜索半径。
+package geohash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodeMatchesRedis(t *testing.T) {
+	// 取自 Redis 文档：GEOADD Sicily 13.361389 38.115556 "Palermo" 后 ZSCORE 的结果
+	cases := []struct {
+		lon, lat float64
+		want     uint64
+	}{
+		{13.361389, 38.115556, 3479099956230698},
+		{15.087269, 37.502669, 3479447370796909},
+	}
+	for _, c := range cases {
+		if got := Encode(c.lon, c.lat, MaxStep).Bits; got != c.want {
+			t.Fatalf("Encode(%v, %v) = %d, want %d", c.lon, c.lat, got, c.want)
+		}
+	}
+}
+
+func TestDecodeCenterPrecision(t *testing.T) {
+	for _, p := range [][2]float64{{13.361389, 38.115556}, {-122.27652, 37.805186}, {179.99, -85}, {-180, 85.05112878}} {
+		lon, lat := DecodeCenter(Encode(p[0], p[1], MaxStep).Bits)
+		if Distance(lon, lat, p[0], p[1]) > 1 {
+			t.Fatalf("decode(%v) = %v,%v too far from the original point", p, lon, lat)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// Palermo -> Catania，Redis 文档给出的 GEODIST 结果为 166274.1516 米
+	d := Distance(13.361389, 38.115556, 15.087269, 37.502669)
+	if math.Abs(d-166274.15) > 1 {
+		t.Fatalf("distance = %v", d)
+	}
+	if Distance(10, 20, 10, 20) != 0 {
+		t.Fatalf("distance to self should be 0")
+	}
+}
+
+func TestNeighborsAndAreasCoverRadius(t *testing.T) {
+	h := Encode(0, 0, 4)
+	if n := len(Neighbors(h)); n != 9 {
+		t.Fatalf("neighbors = %d", n)
+	}
+	// 靠近极点时纬度方向越界的格子被丢弃
+	if n := len(Neighbors(Encode(0, LatMax, 4))); n != 6 {
+		t.Fatalf("neighbors at the pole = %d", n)
+	}
+
+	// 随机抽查：半径内的点必须落在某个候选格子的分值区间内
+	lon, lat, radius := 2.3522, 48.8566, 5000.0
+	areas := SearchAreas(lon, lat, radius, radius, radius)
+	for i := 0; i < 360; i += 5 {
+		a := float64(i) * math.Pi / 180
+		// 沿各个方向取接近边界的点
+		pLat := lat + radToDeg(radius*0.99*math.Sin(a)/earthRadius)
+		pLon := lon + radToDeg(radius*0.99*math.Cos(a)/earthRadius/math.Cos(degToRad(lat)))
+		bits := Encode(pLon, pLat, MaxStep).Bits
+		covered := false
+		for _, area := range areas {
+			min, max := area.Align52()
+			if bits >= min && bits < max {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			t.Fatalf("point at %d degrees (%v,%v) is not covered", i, pLon, pLat)
+		}
+	}
+}
+
+func TestEstimateSteps(t *testing.T) {
+	if s := EstimateSteps(0, 0); s != MaxStep {
+		t.Fatalf("radius 0 steps = %d", s)
+	}
+	if a, b := EstimateSteps(1000, 0), EstimateSteps(100000, 0); a <= b {
+		t.Fatalf("smaller radius should use more steps: %d vs %d", a, b)
+	}
+	if EstimateSteps(1000, 81) >= EstimateSteps(1000, 0) {
+		t.Fatalf("high latitude should reduce steps")
+	}
+}
@@ -0,0 +1,98 @@
+// glob 包：Redis 风格的通配符匹配（SCAN MATCH / KEYS / PSUBSCRIBE 等共用）。
+// 说明：语义对齐 Redis stringmatchlen，按字节匹配，不做 Unicode 处理。
+// 关键点：支持 * ? [abc] [^a] [a-z] 以及 \ 转义；非法模式（如未闭合的 [）按字面量尽力匹配而不是报错。
+package glob
+
+// 本文件实现通配符匹配：
+// - *      匹配任意长度（含空）
+// - ?      匹配任意单个字节
+// - [abc]  匹配集合中的任一字节；[^abc] 取反；[a-z] 区间
+// - \x     将 x 按字面量匹配
+
+// Match 判断 str 是否匹配 pattern。
+func Match(pattern, str string) bool {
+	p, s := 0, 0
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			// 合并连续的 *
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for i := s; i <= len(str); i++ {
+				if Match(pattern[p+1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s >= len(str) {
+				return false
+			}
+			s++
+		case '[':
+			if s >= len(str) {
+				return false
+			}
+			var matched bool
+			p, matched = matchClass(pattern, p+1, str[s])
+			if !matched {
+				return false
+			}
+			s++
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+		default:
+			if s >= len(str) || pattern[p] != str[s] {
+				return false
+			}
+			s++
+		}
+		p++
+	}
+	return s == len(str)
+}
+
+// matchClass 匹配 [...] 字符集，p 指向 '[' 之后的位置；返回 ']' 所在位置（或模式末尾）以及是否匹配。
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	not := false
+	if p < len(pattern) && pattern[p] == '^' {
+		not = true
+		p++
+	}
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			if pattern[p] == c {
+				matched = true
+			}
+		case p+2 < len(pattern) && pattern[p+1] == '-':
+			lo, hi := pattern[p], pattern[p+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			p += 2
+		default:
+			if pattern[p] == c {
+				matched = true
+			}
+		}
+		p++
+	}
+	if p >= len(pattern) {
+		// 未闭合的 [：与 Redis 一致，停在模式末尾
+		p = len(pattern) - 1
+	}
+	return p, matched != not
+}
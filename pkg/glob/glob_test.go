@@ -0,0 +1,38 @@
+// 通配符匹配测试：对照 Redis stringmatchlen 的典型用例。
+// 覆盖：* ? [] [^] [a-z] 转义、空模式与空串。
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, str string
+		want         bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"", "", true},
+		{"", "a", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hello world", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"user:*:name", "user:42:name", true},
+		{"user:*:name", "user:42:age", false},
+		{"a**b", "ab", true},
+		{"[abc", "a", true},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.str); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.str, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// hll 包：HyperLogLog 基数估计，字节格式与 Redis 兼容（"HYLL" 头 + sparse/dense 两种编码）。
+// 说明：值本身就是一个普通字符串，因此可以直接存放在 StringData / rdb.Entry.String 中，也能与 Redis 互相 GET/SET 比较估计值。
+// 关键点：P=14（16384 个 6 位寄存器），MurmurHash64A 哈希，估计算法与 Redis 5+ 相同（Ertl 改进估计，无需偏差修正表）。
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// 本文件实现 HyperLogLog 的编解码与估计：
+// - 头部 16 字节："HYLL" | encoding(1) | unused(3) | 基数缓存(8，小端，最高字节的最高位为 1 表示缓存失效)
+// - dense：16384 个 6 位寄存器紧凑排列（低位在前），共 12288 字节
+// - sparse：游程编码，适合寄存器大多为 0 的小集合
+//     ZERO  00xxxxxx           连续 xxxxxx+1 个 0（最多 64）
+//     XZERO 01xxxxxx yyyyyyyy  连续 14 位长度+1 个 0（最多 16384）
+//     VAL   1vvvvvxx           连续 xx+1 个值为 vvvvv+1 的寄存器（值 1..32，最多 4 个）
+//
+// 写入时把寄存器解码到 Registers 上修改，再重新编码：sparse 超过 SparseMaxBytes 或出现 >32 的值时转为 dense，dense 不会退回 sparse。
+
+const (
+	P         = 14
+	Registers = 1 << P // 寄存器个数
+	q         = 64 - P // 参与计算前导零的位数
+	bitsPer   = 6
+	regMax    = 1<<bitsPer - 1
+
+	HeaderSize = 16
+	DenseSize  = HeaderSize + Registers*bitsPer/8
+
+	encodingDense  = 0
+	encodingSparse = 1
+
+	// SparseMaxBytes 对齐 Redis hll-sparse-max-bytes 默认值。
+	SparseMaxBytes = 3000
+
+	sparseValMax   = 32
+	sparseZeroMax  = 64
+	sparseXZeroMax = 16384
+
+	alphaInf = 0.721347520444481703680 // 0.5/ln(2)
+)
+
+var (
+	// ErrInvalid 表示字节串不是 HyperLogLog（头部不匹配或长度不对）。
+	ErrInvalid = errors.New("hll: not a valid HyperLogLog string value")
+	// ErrCorrupted 表示 sparse 编码内容损坏。
+	ErrCorrupted = errors.New("hll: corrupted HLL object")
+)
+
+// Regs 为解码后的寄存器数组。
+type Regs [Registers]uint8
+
+// New 返回一个空的 HyperLogLog（sparse 编码，基数缓存为 0 且有效）。
+func New() []byte {
+	b := make([]byte, HeaderSize, HeaderSize+2)
+	copy(b, "HYLL")
+	b[4] = encodingSparse
+	// 一个 XZERO 覆盖全部寄存器
+	n := sparseXZeroMax - 1
+	b = append(b, 0x40|byte(n>>8), byte(n))
+	return b
+}
+
+// IsValid 只做头部与长度的快速校验（sparse 的内容在解码时校验）。
+func IsValid(b []byte) bool {
+	if len(b) < HeaderSize || string(b[:4]) != "HYLL" {
+		return false
+	}
+	switch b[4] {
+	case encodingDense:
+		return len(b) == DenseSize
+	case encodingSparse:
+		return true
+	}
+	return false
+}
+
+// IsDense 判断是否为 dense 编码。
+func IsDense(b []byte) bool {
+	return len(b) > 4 && b[4] == encodingDense
+}
+
+// Decode 把 HyperLogLog 字节串解码为寄存器数组。
+func Decode(b []byte) (*Regs, error) {
+	if !IsValid(b) {
+		return nil, ErrInvalid
+	}
+	r := new(Regs)
+	if b[4] == encodingDense {
+		for i := 0; i < Registers; i++ {
+			r[i] = denseGet(b[HeaderSize:], i)
+		}
+		return r, nil
+	}
+
+	idx := 0
+	p := b[HeaderSize:]
+	for i := 0; i < len(p); {
+		op := p[i]
+		switch {
+		case op&0xc0 == 0x00: // ZERO
+			idx += int(op&0x3f) + 1
+			i++
+		case op&0xc0 == 0x40: // XZERO
+			if i+1 >= len(p) {
+				return nil, ErrCorrupted
+			}
+			idx += (int(op&0x3f)<<8 | int(p[i+1])) + 1
+			i += 2
+		default: // VAL
+			val := (op>>2)&0x1f + 1
+			run := int(op&0x03) + 1
+			if idx+run > Registers {
+				return nil, ErrCorrupted
+			}
+			for j := 0; j < run; j++ {
+				r[idx+j] = val
+			}
+			idx += run
+			i++
+		}
+		if idx > Registers {
+			return nil, ErrCorrupted
+		}
+	}
+	if idx != Registers {
+		return nil, ErrCorrupted
+	}
+	return r, nil
+}
+
+func denseGet(p []byte, reg int) uint8 {
+	byteIdx := reg * bitsPer / 8
+	fb := uint(reg * bitsPer & 7)
+	b0 := uint(p[byteIdx])
+	var b1 uint
+	if byteIdx+1 < len(p) {
+		b1 = uint(p[byteIdx+1])
+	}
+	return uint8((b0>>fb | b1<<(8-fb)) & regMax)
+}
+
+func denseSet(p []byte, reg int, val uint8) {
+	byteIdx := reg * bitsPer / 8
+	fb := uint(reg * bitsPer & 7)
+	v := uint(val)
+	p[byteIdx] &^= byte(regMax << fb)
+	p[byteIdx] |= byte(v << fb)
+	if byteIdx+1 < len(p) {
+		p[byteIdx+1] &^= byte(regMax >> (8 - fb))
+		p[byteIdx+1] |= byte(v >> (8 - fb))
+	}
+}
+
+// EncodeDense 把寄存器编码为 dense 格式（基数缓存置为失效）。
+func EncodeDense(r *Regs) []byte {
+	b := make([]byte, DenseSize)
+	copy(b, "HYLL")
+	b[4] = encodingDense
+	for i, v := range r {
+		if v != 0 {
+			denseSet(b[HeaderSize:], i, v)
+		}
+	}
+	InvalidateCache(b)
+	return b
+}
+
+// encodeSparse 把寄存器编码为 sparse 格式；无法表示（值 > 32 或超过 SparseMaxBytes）时返回 ok=false。
+func encodeSparse(r *Regs) ([]byte, bool) {
+	b := make([]byte, HeaderSize, HeaderSize+64)
+	copy(b, "HYLL")
+	b[4] = encodingSparse
+	for i := 0; i < Registers; {
+		v := r[i]
+		run := 1
+		for i+run < Registers && r[i+run] == v {
+			run++
+		}
+		if v == 0 {
+			for n := run; n > 0; {
+				if n > sparseZeroMax {
+					l := n
+					if l > sparseXZeroMax {
+						l = sparseXZeroMax
+					}
+					b = append(b, 0x40|byte((l-1)>>8), byte(l-1))
+					n -= l
+				} else {
+					b = append(b, byte(n-1))
+					n = 0
+				}
+			}
+		} else {
+			if v > sparseValMax {
+				return nil, false
+			}
+			for n := run; n > 0; {
+				l := n
+				if l > 4 {
+					l = 4
+				}
+				b = append(b, 0x80|(v-1)<<2|byte(l-1))
+				n -= l
+			}
+		}
+		if len(b)-HeaderSize > SparseMaxBytes {
+			return nil, false
+		}
+		i += run
+	}
+	InvalidateCache(b)
+	return b, true
+}
+
+// Encode 按当前编码写回寄存器：原本为 dense 时保持 dense，否则优先 sparse，放不下时转 dense。
+func Encode(r *Regs, dense bool) []byte {
+	if !dense {
+		if b, ok := encodeSparse(r); ok {
+			return b
+		}
+	}
+	return EncodeDense(r)
+}
+
+// InvalidateCache 使头部的基数缓存失效。
+func InvalidateCache(b []byte) {
+	b[15] |= 0x80
+}
+
+// CachedCount 返回头部缓存的基数（缓存有效时）。
+func CachedCount(b []byte) (uint64, bool) {
+	if b[15]&0x80 != 0 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(b[8:16]), true
+}
+
+// SetCachedCount 写入头部的基数缓存。
+func SetCachedCount(b []byte, card uint64) {
+	binary.LittleEndian.PutUint64(b[8:16], card)
+}
+
+// hashElement 返回元素对应的寄存器下标与游程长度（前导零个数 + 1）。
+func hashElement(elem []byte) (int, uint8) {
+	h := murmurHash64A(elem, 0xadc83b19)
+	idx := int(h & (Registers - 1))
+	h >>= P
+	h |= 1 << q // 保证循环终止：count 最大为 q+1
+	count := uint8(1)
+	for bit := uint64(1); h&bit == 0; bit <<= 1 {
+		count++
+	}
+	return idx, count
+}
+
+// Add 把元素加入寄存器，返回是否有寄存器被更新。
+func (r *Regs) Add(elem []byte) bool {
+	idx, count := hashElement(elem)
+	if count > r[idx] {
+		r[idx] = count
+		return true
+	}
+	return false
+}
+
+// Merge 把 o 合并到 r（逐个寄存器取最大值）。
+func (r *Regs) Merge(o *Regs) {
+	for i, v := range o {
+		if v > r[i] {
+			r[i] = v
+		}
+	}
+}
+
+// Count 估计基数（Ertl 改进估计，与 Redis hllCount 相同）。
+func (r *Regs) Count() uint64 {
+	var histo [q + 2]int
+	for _, v := range r {
+		histo[v]++
+	}
+	m := float64(Registers)
+	z := m * tau((m-float64(histo[q+1]))/m)
+	for j := q; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * sigma(float64(histo[0])/m)
+	return uint64(math.Round(alphaInf * m * m / z))
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// murmurHash64A 为 Redis 使用的 64 位 MurmurHash2（小端读取）。
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ uint64(len(data))*m
+
+	n := len(data) / 8
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint64(data[i*8:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+
+	tail := data[n*8:]
+	switch len(tail) {
+	case 7:
+		h ^= uint64(tail[6]) << 48
+		fallthrough
+	case 6:
+		h ^= uint64(tail[5]) << 40
+		fallthrough
+	case 5:
+		h ^= uint64(tail[4]) << 32
+		fallthrough
+	case 4:
+		h ^= uint64(tail[3]) << 24
+		fallthrough
+	case 3:
+		h ^= uint64(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint64(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint64(tail[0])
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
@@ -0,0 +1,114 @@
+// HyperLogLog 单元测试：验证 sparse/dense 编解码往返、sparse 升级为 dense 的条件与估计误差。
+// 目标：保证字节格式可被稳定解析（损坏数据返回错误而不是 panic），估计误差在 P=14 的理论范围内。
+// 覆盖：空 HLL、寄存器合并、非法头部与截断数据。
+package hll
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestNewIsEmptySparse(t *testing.T) {
+	b := New()
+	if !IsValid(b) || IsDense(b) {
+		t.Fatalf("New should be a valid sparse HLL: %q", b)
+	}
+	if card, ok := CachedCount(b); !ok || card != 0 {
+		t.Fatalf("empty HLL cache = %d, %v", card, ok)
+	}
+	r, err := Decode(b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n := r.Count(); n != 0 {
+		t.Fatalf("empty count = %d", n)
+	}
+}
+
+func TestSparseDenseRoundTrip(t *testing.T) {
+	r := new(Regs)
+	for i := 0; i < 200; i++ {
+		r.Add([]byte("elem-" + strconv.Itoa(i)))
+	}
+	sparse := Encode(r, false)
+	if IsDense(sparse) {
+		t.Fatalf("200 elements should still fit sparse encoding (%d bytes)", len(sparse))
+	}
+	dense := EncodeDense(r)
+	if len(dense) != DenseSize {
+		t.Fatalf("dense size = %d", len(dense))
+	}
+	fromSparse, err := Decode(sparse)
+	if err != nil {
+		t.Fatalf("decode sparse: %v", err)
+	}
+	fromDense, err := Decode(dense)
+	if err != nil {
+		t.Fatalf("decode dense: %v", err)
+	}
+	if *fromSparse != *r || *fromDense != *r {
+		t.Fatalf("registers changed after round trip")
+	}
+	// dense 保持 dense
+	if !IsDense(Encode(fromDense, true)) {
+		t.Fatalf("dense encoding should never go back to sparse")
+	}
+}
+
+func TestPromoteToDense(t *testing.T) {
+	r := new(Regs)
+	for i := 0; i < 5000; i++ {
+		r.Add([]byte(strconv.Itoa(i)))
+	}
+	if !IsDense(Encode(r, false)) {
+		t.Fatalf("large HLL should be promoted to dense")
+	}
+	// 值 > 32 无法用 sparse 表示
+	small := new(Regs)
+	small[10] = 40
+	if !IsDense(Encode(small, false)) {
+		t.Fatalf("register value > 32 should force dense")
+	}
+}
+
+func TestCountAccuracy(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		r := new(Regs)
+		for i := 0; i < n; i++ {
+			r.Add([]byte("user:" + strconv.Itoa(i)))
+		}
+		got := float64(r.Count())
+		if relErr := math.Abs(got-float64(n)) / float64(n); relErr > 0.03 {
+			t.Fatalf("n=%d estimate=%v relative error %.4f", n, got, relErr)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := new(Regs), new(Regs)
+	for i := 0; i < 1000; i++ {
+		a.Add([]byte(strconv.Itoa(i)))
+		b.Add([]byte(strconv.Itoa(i + 500)))
+	}
+	a.Merge(b)
+	if got := float64(a.Count()); math.Abs(got-1500)/1500 > 0.03 {
+		t.Fatalf("merged estimate = %v", got)
+	}
+}
+
+func TestDecodeRejectsInvalid(t *testing.T) {
+	if _, err := Decode([]byte("hello")); err != ErrInvalid {
+		t.Fatalf("plain string err = %v", err)
+	}
+	b := New()
+	b = append(b, 0x00) // 额外的 ZERO 让寄存器总数超过 16384
+	if _, err := Decode(b); err != ErrCorrupted {
+		t.Fatalf("corrupted sparse err = %v", err)
+	}
+	short := bytes.Clone(EncodeDense(new(Regs))[:100])
+	if _, err := Decode(short); err != ErrInvalid {
+		t.Fatalf("truncated dense err = %v", err)
+	}
+}
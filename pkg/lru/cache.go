@@ -38,6 +38,9 @@ type EvictionCache interface {
 	// ForEach 遍历缓存中的所有 key/value（不改变 LRU/LFU 统计）。
 	// 返回值：回调返回 false 时中止遍历。
 	ForEach(fn func(key string, value Value) bool)
+	// Scan 基于反向二进制游标做增量遍历（SCAN 命令使用），返回下一个游标；0 表示一轮遍历结束。
+	// count 为期望返回的数量提示（实际可能略多或略少）；fn 内不能修改缓存。
+	Scan(cursor uint64, count int, fn func(key string, value Value)) uint64
 	Len() int
 	Close()
 }
@@ -21,6 +21,7 @@ type lfuEntry struct {
 	value     Value
 	freq      int
 	expiresAt int64 // 秒级时间戳；0 表示永不过期
+	size      int64 // 计入 nbytes 的 value 大小（Add 时的 value.Len()）
 	element   *list.Element
 }
 
@@ -30,6 +31,7 @@ type LFUCache struct {
 	nbytes   int64
 
 	items   map[string]*lfuEntry
+	index   *ScanIndex         // SCAN 游标索引（与 items 同步维护）
 	buckets map[int]*list.List // freq -> *list.List，元素为 *lfuEntry
 	minFreq int
 
@@ -41,6 +43,7 @@ func NewLFU(maxBytes int64, onRemove OnRemoveFunc) *LFUCache {
 	return &LFUCache{
 		maxBytes: maxBytes,
 		items:    make(map[string]*lfuEntry),
+		index:    NewScanIndex(),
 		buckets:  make(map[int]*list.List),
 		onRemove: onRemove,
 	}
@@ -63,6 +66,7 @@ func (c *LFUCache) ForEach(fn func(key string, value Value) bool) {
 
 // Add 新增/更新条目。
 // 说明：写入也视为一次“访问”，会增加频次（便于更贴近真实热度）。
+// value 原地修改后再次 Add 同一个 value 即可刷新大小统计（差值按条目记录的 size 计算）。
 func (c *LFUCache) Add(key string, value Value, ttl int64) {
 	var expiresAt int64
 	if ttl > 0 {
@@ -71,8 +75,10 @@ func (c *LFUCache) Add(key string, value Value, ttl int64) {
 
 	if ent, ok := c.items[key]; ok {
 		// 更新 value 大小
-		c.nbytes += int64(value.Len()) - int64(ent.value.Len())
+		size := int64(value.Len())
+		c.nbytes += size - ent.size
 		ent.value = value
+		ent.size = size
 		ent.expiresAt = expiresAt
 		c.increment(ent)
 	} else {
@@ -81,11 +87,13 @@ func (c *LFUCache) Add(key string, value Value, ttl int64) {
 			value:     value,
 			freq:      1,
 			expiresAt: expiresAt,
+			size:      int64(value.Len()),
 		}
 		l := c.getOrCreateBucket(1)
 		ent.element = l.PushFront(ent)
 		c.items[key] = ent
-		c.nbytes += int64(len(key)) + int64(value.Len())
+		c.index.Add(key)
+		c.nbytes += int64(len(key)) + ent.size
 		c.minFreq = 1
 	}
 
@@ -95,6 +103,14 @@ func (c *LFUCache) Add(key string, value Value, ttl int64) {
 	}
 }
 
+// Scan 从 cursor 开始按游标索引遍历一批 key（不改变 LFU 频次/顺序），返回下一个游标（0 表示结束）。
+// 注意：fn 内不能修改缓存。
+func (c *LFUCache) Scan(cursor uint64, count int, fn func(key string, value Value)) uint64 {
+	return c.index.Scan(cursor, count, func(key string) {
+		fn(key, c.items[key].value)
+	})
+}
+
 // Get 获取条目并更新访问统计（freq++，同频按 LRU 调整）。
 func (c *LFUCache) Get(key string) (value Value, ok bool) {
 	ent, ok := c.items[key]
@@ -223,7 +239,8 @@ func (c *LFUCache) removeEntry(ent *lfuEntry, reason RemoveReason) {
 	}
 
 	delete(c.items, ent.key)
-	c.nbytes -= int64(len(ent.key)) + int64(ent.value.Len())
+	c.index.Remove(ent.key)
+	c.nbytes -= int64(len(ent.key)) + ent.size
 
 	if c.onRemove != nil {
 		c.onRemove(ent.key, ent.value, reason)
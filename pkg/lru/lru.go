@@ -25,6 +25,7 @@ type Cache struct {
 	nbytes   int64                    // 当前缓存的字节数
 	ll       *list.List               // 双向链表，用于实现 LRU
 	cache    map[string]*list.Element // 键到链表元素的映射
+	index    *ScanIndex               // SCAN 游标索引（与 cache 同步维护）
 	// 优先级队列（最小堆），用于过期管理
 	heap    []*pool.HeapItem // 最小堆数组
 	heapMap map[string]int   // 键到堆索引的映射
@@ -44,6 +45,7 @@ type entry struct {
 	key       string // 键
 	value     Value  // 值
 	expiresAt int64  // 过期时间戳，0 表示永不过期
+	size      int64  // 计入 nbytes 的 value 大小（Add 时的 value.Len()）
 }
 
 // Value 接口用于计算值占用的字节数
@@ -59,6 +61,7 @@ func New(maxBytes int64, onEvicted OnRemoveFunc) *Cache {
 		maxBytes:     maxBytes,
 		ll:           list.New(),
 		cache:        make(map[string]*list.Element),
+		index:        NewScanIndex(),
 		heap:         make([]*pool.HeapItem, 0),
 		heapMap:      make(map[string]int),
 		OnEvicted:    onEvicted,
@@ -80,6 +83,9 @@ func (c *Cache) Close() {
 // key 是缓存的键
 // value 是缓存的值
 // ttl 是生存时间（秒），0 表示永不过期
+//
+// value 原地修改后（map、链表、指针类型）再次 Add 同一个 value 即可刷新大小统计：
+// 差值按条目记录的 size 计算，而不是按旧 value 当前的 Len()（它已经等于新值）。
 func (c *Cache) Add(key string, value Value, ttl int64) {
 	var expiresAt int64
 	if ttl > 0 {
@@ -90,8 +96,10 @@ func (c *Cache) Add(key string, value Value, ttl int64) {
 		// 更新现有条目
 		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*entry)
-		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		size := int64(value.Len())
+		c.nbytes += size - kv.size
 		kv.value = value
+		kv.size = size
 
 		// 更新过期时间
 		oldExpiresAt := kv.expiresAt
@@ -112,9 +120,11 @@ func (c *Cache) Add(key string, value Value, ttl int64) {
 		}
 	} else {
 		// 添加新条目
-		ele := c.ll.PushFront(&entry{key, value, expiresAt})
+		size := int64(value.Len())
+		ele := c.ll.PushFront(&entry{key, value, expiresAt, size})
 		c.cache[key] = ele
-		c.nbytes += int64(len(key)) + int64(value.Len())
+		c.index.Add(key)
+		c.nbytes += int64(len(key)) + size
 
 		// 如果有过期时间，添加到堆中
 		if expiresAt > 0 {
@@ -180,7 +190,8 @@ func (c *Cache) removeEntry(ele *list.Element, reason RemoveReason) {
 	// 从链表中删除
 	c.ll.Remove(ele)
 	delete(c.cache, key)
-	c.nbytes -= int64(len(key)) + int64(kv.value.Len())
+	c.index.Remove(key)
+	c.nbytes -= int64(len(key)) + kv.size
 
 	// 如果有过期时间，从堆中删除
 	if kv.expiresAt > 0 {
@@ -334,6 +345,14 @@ func (c *Cache) ForEach(fn func(key string, value Value) bool) {
 	}
 }
 
+// Scan 从 cursor 开始按游标索引遍历一批 key（不改变 LRU 访问顺序），返回下一个游标（0 表示结束）。
+// 注意：fn 内不能修改缓存。
+func (c *Cache) Scan(cursor uint64, count int, fn func(key string, value Value)) uint64 {
+	return c.index.Scan(cursor, count, func(key string) {
+		fn(key, c.cache[key].Value.(*entry).value)
+	})
+}
+
 // Remove 删除指定键的条目
 func (c *Cache) Remove(key string) {
 	if ele, ok := c.cache[key]; ok {
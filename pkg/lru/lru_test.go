@@ -74,3 +74,36 @@ func TestAdd(t *testing.T) {
 		t.Fatal("expected 6 but got", lru.nbytes)
 	}
 }
+
+// growing 为原地修改的 value（类似 DB 中的 map / 链表 / 指针类型）。
+type growing struct{ data []byte }
+
+func (g *growing) Len() int { return len(g.data) }
+
+func TestAddInPlaceMutation(t *testing.T) {
+	for name, c := range map[string]EvictionCache{"lru": New(int64(20), nil), "lfu": NewLFU(int64(20), nil)} {
+		g := &growing{data: []byte("12")}
+		c.Add("other", String("1"), 0)
+		c.Add("key", g, 0)
+		// 原地增长后再次 Add 同一个指针：按记录的旧大小计算差值，超出上限后淘汰
+		g.data = append(g.data, "0123456789"...)
+		c.Add("key", g, 0)
+		if _, ok := c.Peek("other"); ok {
+			t.Fatalf("%s: growing a value in place did not trigger eviction", name)
+		}
+		g.data = append(g.data, "0123456789"...)
+		c.Add("key", g, 0)
+		if c.Len() != 0 {
+			t.Fatalf("%s: value larger than maxBytes should be evicted, len=%d", name, c.Len())
+		}
+	}
+	lru := New(int64(0), nil)
+	g := &growing{data: []byte("12")}
+	lru.Add("key", g, 0)
+	g.data = append(g.data, "345"...)
+	lru.Add("key", g, 0)
+	lru.Remove("key")
+	if lru.nbytes != 0 {
+		t.Fatalf("nbytes after remove = %d, want 0", lru.nbytes)
+	}
+}
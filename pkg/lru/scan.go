@@ -0,0 +1,134 @@
+// 游标遍历索引：为 SCAN 提供“无状态、结构变化下仍稳定”的 key 迭代能力。
+// 说明：Go map 的遍历顺序不可控且无法断点续扫，因此缓存内部额外维护一张 2 的幂大小的桶表。
+// 关键点：游标按“反向二进制递增”推进（同 Redis dictScan），扩容/缩容后已访问过的桶不会被漏扫。
+package lru
+
+import "math/bits"
+
+// 本文件实现 SCAN 所需的游标索引：
+// - ScanIndex：key -> 桶（hash & mask）的桶表，随元素数量翻倍扩容/折半缩容；缓存与大集合（HSCAN/SSCAN/ZSCAN）共用
+// - NextScanCursor：反向二进制游标递增（高位先进位），保证表大小变化时仍能覆盖全部旧元素
+// - KeyHash：FNV-1a 64 位哈希
+//
+// 保证（与 Redis 一致）：一次完整遍历（从游标 0 开始直到返回 0）期间始终存在的 key 至少返回一次；
+// 期间新增/删除的 key 可能返回也可能不返回；同一个 key 可能返回多次。
+
+const minScanBuckets = 4
+
+// KeyHash 计算 key 的 FNV-1a 64 位哈希。
+func KeyHash(key string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	return h
+}
+
+// NextScanCursor 返回 mask 对应桶表下的下一个游标；返回 0 表示遍历结束。
+func NextScanCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// ScanIndex 为游标索引（不要求并发安全，由上层 Actor 串行调用）。
+type ScanIndex struct {
+	buckets [][]string
+	size    int
+}
+
+func NewScanIndex() *ScanIndex {
+	return &ScanIndex{buckets: make([][]string, minScanBuckets)}
+}
+
+func (s *ScanIndex) mask() uint64 {
+	return uint64(len(s.buckets) - 1)
+}
+
+// Add 记录新 key（调用方保证 key 尚未存在）。
+func (s *ScanIndex) Add(key string) {
+	i := KeyHash(key) & s.mask()
+	s.buckets[i] = append(s.buckets[i], key)
+	s.size++
+	// 负载因子 > 1 时扩容
+	if s.size > len(s.buckets) {
+		s.resize(len(s.buckets) * 2)
+	}
+}
+
+// Remove 删除 key（不存在时忽略）。
+func (s *ScanIndex) Remove(key string) {
+	i := KeyHash(key) & s.mask()
+	b := s.buckets[i]
+	for j, k := range b {
+		if k != key {
+			continue
+		}
+		last := len(b) - 1
+		b[j] = b[last]
+		b[last] = ""
+		s.buckets[i] = b[:last]
+		s.size--
+		// 负载因子 < 1/8 时缩容
+		if len(s.buckets) > minScanBuckets && s.size < len(s.buckets)/8 {
+			s.resize(len(s.buckets) / 2)
+		}
+		return
+	}
+}
+
+func (s *ScanIndex) resize(n int) {
+	if n < minScanBuckets {
+		n = minScanBuckets
+	}
+	buckets := make([][]string, n)
+	mask := uint64(n - 1)
+	for _, b := range s.buckets {
+		for _, k := range b {
+			i := KeyHash(k) & mask
+			buckets[i] = append(buckets[i], k)
+		}
+	}
+	s.buckets = buckets
+}
+
+// Len 返回索引中的 key 数量。
+func (s *ScanIndex) Len() int {
+	return s.size
+}
+
+// Clear 清空索引。
+func (s *ScanIndex) Clear() {
+	s.buckets = make([][]string, minScanBuckets)
+	s.size = 0
+}
+
+// Scan 从 cursor 开始访问桶，直到已返回 count 个 key、访问了 count*10 个桶或遍历结束。
+// fn 在遍历期间不能修改被索引的容器（例如删除 key），需要删除时应先收集、遍历结束后再处理。
+func (s *ScanIndex) Scan(cursor uint64, count int, fn func(key string)) uint64 {
+	if count <= 0 {
+		count = 10
+	}
+	if s.size == 0 {
+		return 0
+	}
+	mask := s.mask()
+	emitted, visited := 0, 0
+	for {
+		for _, k := range s.buckets[cursor&mask] {
+			fn(k)
+			emitted++
+		}
+		cursor = NextScanCursor(cursor, mask)
+		visited++
+		if cursor == 0 || emitted >= count || visited >= count*10 {
+			return cursor
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// 游标索引单元测试：验证 LRU/LFU 的 Scan 在扩容、缩容、删除后仍能覆盖所有存活 key。
+// 目标：保证 SCAN 的“完整遍历期间始终存在的 key 至少返回一次”语义。
+// 覆盖：反向二进制游标、遍历中途扩容/缩容、淘汰删除后索引同步。
+package lru
+
+import (
+	"fmt"
+	"testing"
+)
+
+func scanUntilDone(c EvictionCache, count int, between func()) map[string]int {
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		cursor = c.Scan(cursor, count, func(key string, _ Value) {
+			seen[key]++
+		})
+		if between != nil {
+			between()
+		}
+		if cursor == 0 {
+			return seen
+		}
+	}
+}
+
+func TestScan_ResizeDuringIteration(t *testing.T) {
+	for name, c := range map[string]EvictionCache{"lru": New(0, nil), "lfu": NewLFU(0, nil)} {
+		for i := 0; i < 500; i++ {
+			c.Add(fmt.Sprintf("stable%d", i), String("v"), 0)
+		}
+		// 前半段持续新增（扩容），后半段删除新增的 key（缩容）
+		added := 0
+		seen := scanUntilDone(c, 10, func() {
+			if added < 2000 {
+				for j := 0; j < 50; j++ {
+					c.Add(fmt.Sprintf("tmp%d", added), String("v"), 0)
+					added++
+				}
+				return
+			}
+			for j := 0; j < 200 && added > 0; j++ {
+				added--
+				c.Remove(fmt.Sprintf("tmp%d", added))
+			}
+		})
+		for i := 0; i < 500; i++ {
+			if seen[fmt.Sprintf("stable%d", i)] == 0 {
+				t.Fatalf("%s: stable%d missing", name, i)
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestScan_IndexFollowsEviction(t *testing.T) {
+	c := New(int64(10*len("k0v")), nil)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("k%d", i%10), String("v"), 0)
+		c.Add(fmt.Sprintf("x%d", i), String("v"), 0)
+	}
+	seen := scanUntilDone(c, 1000, nil)
+	if len(seen) != c.Len() {
+		t.Fatalf("scan returned %d keys, cache has %d", len(seen), c.Len())
+	}
+	for k := range seen {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("scan returned evicted key %s", k)
+		}
+	}
+}
+
+func TestNextScanCursor_VisitsEveryBucketOnce(t *testing.T) {
+	const size = 64
+	visited := make(map[uint64]bool)
+	var cursor uint64
+	for {
+		if visited[cursor] {
+			t.Fatalf("bucket %d visited twice", cursor)
+		}
+		visited[cursor] = true
+		cursor = NextScanCursor(cursor, size-1)
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(visited) != size {
+		t.Fatalf("visited %d buckets, want %d", len(visited), size)
+	}
+}
@@ -0,0 +1,141 @@
+// Lua 语法树：编译器（parser.go）的输出、解释器（interp.go）的输入。
+// 说明：变量在编译期解析为局部槽位/上值下标/全局名，运行时无需按名字逐层查找作用域。
+// 关键点：每个局部变量声明独占一个槽位（同一函数内不复用），循环体每次迭代重新创建 cell，闭包捕获语义与 Lua 一致。
+package lua
+
+// 本文件定义语法树节点。
+// 变量引用在编译期就解析为三类之一：
+// - localExpr：当前函数栈帧中的槽位（每次声明都会在运行时创建新的 cell，闭包捕获的是 cell）
+// - upvalExpr：外层函数的局部变量（闭包创建时从外层栈帧/上值中取出 cell）
+// - globalExpr：全局表中的名字
+
+type expr interface{}
+
+type stmt interface{}
+
+type (
+	constExpr struct {
+		v Value
+	}
+	varargExpr struct{}
+	localExpr  struct {
+		slot int
+		name string
+	}
+	upvalExpr struct {
+		idx  int
+		name string
+	}
+	globalExpr   struct{ name string }
+	indexExpr    struct{ obj, key expr }
+	functionExpr struct{ proto *funcProto }
+	// parenExpr 只用于把多返回值表达式截断为一个值：(f())
+	parenExpr struct{ e expr }
+
+	callExpr struct {
+		fn   expr
+		args []expr
+		line int
+	}
+	methodCallExpr struct {
+		obj  expr
+		name string
+		args []expr
+		line int
+	}
+
+	binExpr struct {
+		op   string
+		l, r expr
+	}
+	andExpr struct{ l, r expr }
+	orExpr  struct{ l, r expr }
+	unExpr  struct {
+		op string
+		e  expr
+	}
+
+	tableField struct {
+		key expr // nil 表示按顺序的数组项
+		val expr
+	}
+	tableExpr struct{ fields []tableField }
+)
+
+type block struct {
+	stmts []stmt
+}
+
+type (
+	localStmt struct {
+		slots []int
+		exprs []expr
+		line  int
+	}
+	localFuncStmt struct {
+		slot int
+		fn   *functionExpr
+		line int
+	}
+	assignStmt struct {
+		targets []expr
+		exprs   []expr
+		line    int
+	}
+	callStmt struct {
+		call expr
+		line int
+	}
+	doStmt    struct{ body *block }
+	whileStmt struct {
+		cond expr
+		body *block
+		line int
+	}
+	repeatStmt struct {
+		body *block
+		cond expr
+		line int
+	}
+	ifStmt struct {
+		conds  []expr
+		blocks []*block
+		orElse *block
+		line   int
+	}
+	numForStmt struct {
+		slot               int
+		start, limit, step expr
+		body               *block
+		line               int
+	}
+	genForStmt struct {
+		slots []int
+		exprs []expr
+		body  *block
+		line  int
+	}
+	returnStmt struct {
+		exprs []expr
+		line  int
+	}
+	breakStmt struct{}
+)
+
+// upvalDesc 描述闭包创建时上值的来源：外层函数的局部变量槽位，或外层函数自己的上值。
+type upvalDesc struct {
+	fromParentLocal bool
+	index           int
+}
+
+// funcProto 为编译后的函数原型。
+type funcProto struct {
+	chunk    string
+	name     string
+	line     int
+	params   []int
+	isVararg bool
+	numSlots int
+	upvals   []upvalDesc
+	body     *block
+}
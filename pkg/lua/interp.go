@@ -0,0 +1,667 @@
+// Lua 解释器：按语法树执行脚本，负责作用域/闭包、多返回值、控制流与运行时错误。
+// 说明：Lua 错误（error()、运行时类型错误）以 panic(*Error) 传播，由 pcall 与 State.Call 统一恢复；中断（超时）不能被 pcall 捕获。
+// 关键点：限制调用深度避免 Go 栈溢出；每执行一定数量的语句调用 Interrupt 回调，让宿主可以终止死循环脚本。
+package lua
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+)
+
+// 本文件实现执行引擎：
+// - State：全局表、当前执行位置（用于错误信息）、调用深度、中断检查
+// - exec/eval：语句执行与表达式求值；多返回值只在参数列表/返回列表/赋值列表的最后一项展开
+// - 运行时错误信息对齐 Lua 5.1（"attempt to index local 't' (a nil value)" 等）
+
+const (
+	maxCallDepth   = 200
+	interruptEvery = 1024
+)
+
+// Error 为 Lua 错误；Value 为 error() 抛出的值（运行时错误为带位置前缀的字符串）。
+type Error struct {
+	Value Value
+}
+
+func (e *Error) Error() string {
+	if s, ok := e.Value.(string); ok {
+		return s
+	}
+	if n, ok := e.Value.(float64); ok {
+		return FormatNumber(n)
+	}
+	return "(error object is a " + TypeName(e.Value) + " value)"
+}
+
+// interrupt 为宿主中断执行（不可被 pcall 捕获）。
+type interrupt struct {
+	err error
+}
+
+// State 为一个解释器实例（不是并发安全的）。
+type State struct {
+	Globals *Table
+	// Interrupt 非空时每执行若干语句调用一次，返回非 nil 错误则终止脚本（State.Call 返回该错误）。
+	Interrupt func() error
+
+	stringLib *Table
+	libs      []*Table // Register 创建的库表，Freeze 时一并设为只读
+	frozen    bool
+	chunk     string
+	line      int
+	depth     int
+	steps     int
+}
+
+// NewState 创建解释器并加载基础库（base/string/table/math）。
+func NewState() *State {
+	s := &State{Globals: NewTable(0)}
+	openLibs(s)
+	return s
+}
+
+// SetGlobal 设置全局变量（不受 Freeze 限制，供宿主注入 KEYS/ARGV 等）。
+func (s *State) SetGlobal(name string, v Value) {
+	s.Globals.Set(name, v)
+}
+
+// Freeze 冻结全局表与库表：之后脚本读取不存在的全局变量、给全局变量赋值或修改库函数都会报错（对齐 Redis 的脚本沙箱）。
+func (s *State) Freeze() {
+	s.frozen = true
+	for _, t := range s.libs {
+		t.readonly = true
+	}
+}
+
+// Register 注册 Go 函数到全局表（lib 为空）或库表 lib 中。
+func (s *State) Register(lib, name string, fn func(s *State, args []Value) []Value) {
+	f := &GoFunction{Name: name, Fn: fn}
+	if lib == "" {
+		s.Globals.Set(name, f)
+		return
+	}
+	t, ok := s.Globals.Get(lib).(*Table)
+	if !ok {
+		t = NewTable(0)
+		s.Globals.Set(lib, t)
+		s.libs = append(s.libs, t)
+	}
+	t.Set(name, f)
+}
+
+// Load 把编译好的脚本实例化为可调用的函数。
+func (s *State) Load(p *Proto) *Closure {
+	return &Closure{proto: p.main}
+}
+
+// Call 调用 fn 并恢复执行中的 Lua 错误：返回 *Error（脚本错误）或 Interrupt 返回的错误。
+func (s *State) Call(fn Value, args ...Value) (rets []Value, err error) {
+	depth := s.depth
+	defer func() {
+		if r := recover(); r != nil {
+			s.depth = depth
+			switch e := r.(type) {
+			case *Error:
+				err = e
+			case *interrupt:
+				err = e.err
+			case runtime.Error:
+				// 解释器自身的缺陷不应让宿主进程崩溃
+				err = &Error{Value: s.where() + e.Error()}
+			default:
+				panic(r)
+			}
+		}
+	}()
+	return s.call(fn, args, ""), nil
+}
+
+// Errorf 抛出带当前位置前缀的 Lua 错误（供 Go 函数使用）。
+func (s *State) Errorf(format string, args ...interface{}) {
+	panic(&Error{Value: s.where() + fmt.Sprintf(format, args...)})
+}
+
+// Raise 抛出任意值作为 Lua 错误（不加位置前缀）。
+func (s *State) Raise(v Value) {
+	panic(&Error{Value: v})
+}
+
+// Line 返回当前执行到的行号。
+func (s *State) Line() int {
+	return s.line
+}
+
+func (s *State) where() string {
+	if s.chunk == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d: ", s.chunk, s.line)
+}
+
+func (s *State) tick() {
+	s.steps++
+	if s.steps%interruptEvery == 0 && s.Interrupt != nil {
+		if err := s.Interrupt(); err != nil {
+			panic(&interrupt{err: err})
+		}
+	}
+}
+
+type frame struct {
+	cl      *Closure
+	slots   []*cell
+	varargs []Value
+}
+
+// call 调用函数值；desc 用于“调用了非函数值”时的错误描述。
+func (s *State) call(fn Value, args []Value, desc string) []Value {
+	switch f := fn.(type) {
+	case *Closure:
+		return s.callClosure(f, args)
+	case *GoFunction:
+		chunk, line := s.chunk, s.line
+		rets := f.Fn(s, args)
+		s.chunk, s.line = chunk, line
+		return rets
+	}
+	s.opError("call", desc, fn)
+	return nil
+}
+
+// opError 抛出类型错误：desc 为空时使用 "attempt to <op> a nil value" 的形式。
+func (s *State) opError(op, desc string, v Value) {
+	if desc == "" {
+		s.Errorf("attempt to %s a %s value", op, TypeName(v))
+	}
+	s.Errorf("attempt to %s %s (a %s value)", op, desc, TypeName(v))
+}
+
+func (s *State) callClosure(cl *Closure, args []Value) []Value {
+	if s.depth >= maxCallDepth {
+		s.Errorf("stack overflow")
+	}
+	s.depth++
+	chunk, line := s.chunk, s.line
+	p := cl.proto
+	s.chunk = p.chunk
+
+	f := &frame{cl: cl, slots: make([]*cell, p.numSlots)}
+	for i, slot := range p.params {
+		var v Value
+		if i < len(args) {
+			v = args[i]
+		}
+		f.slots[slot] = &cell{v: v}
+	}
+	if p.isVararg && len(args) > len(p.params) {
+		f.varargs = args[len(p.params):]
+	}
+	flow, rets := s.execBlock(f, p.body)
+
+	s.chunk, s.line = chunk, line
+	s.depth--
+	if flow == flowReturn {
+		return rets
+	}
+	return nil
+}
+
+const (
+	flowNormal = iota
+	flowBreak
+	flowReturn
+)
+
+func (s *State) execBlock(f *frame, b *block) (int, []Value) {
+	for _, st := range b.stmts {
+		if flow, rets := s.exec(f, st); flow != flowNormal {
+			return flow, rets
+		}
+	}
+	return flowNormal, nil
+}
+
+func (s *State) exec(f *frame, st stmt) (int, []Value) {
+	s.tick()
+	switch st := st.(type) {
+	case *localStmt:
+		s.line = st.line
+		vals := s.evalList(f, st.exprs, len(st.slots))
+		for i, slot := range st.slots {
+			f.slots[slot] = &cell{v: vals[i]}
+		}
+	case *localFuncStmt:
+		s.line = st.line
+		c := &cell{}
+		f.slots[st.slot] = c
+		c.v = s.makeClosure(f, st.fn.proto)
+	case *assignStmt:
+		s.line = st.line
+		s.assign(f, st)
+	case *callStmt:
+		s.line = st.line
+		s.evalMulti(f, st.call)
+	case *doStmt:
+		return s.execBlock(f, st.body)
+	case *whileStmt:
+		for {
+			s.tick()
+			s.line = st.line
+			if !Truthy(s.eval(f, st.cond)) {
+				break
+			}
+			flow, rets := s.execBlock(f, st.body)
+			if flow == flowBreak {
+				break
+			}
+			if flow == flowReturn {
+				return flow, rets
+			}
+		}
+	case *repeatStmt:
+		for {
+			s.tick()
+			flow, rets := s.execBlock(f, st.body)
+			if flow == flowBreak {
+				break
+			}
+			if flow == flowReturn {
+				return flow, rets
+			}
+			s.line = st.line
+			if Truthy(s.eval(f, st.cond)) {
+				break
+			}
+		}
+	case *ifStmt:
+		s.line = st.line
+		for i, cond := range st.conds {
+			if Truthy(s.eval(f, cond)) {
+				return s.execBlock(f, st.blocks[i])
+			}
+		}
+		if st.orElse != nil {
+			return s.execBlock(f, st.orElse)
+		}
+	case *numForStmt:
+		return s.execNumFor(f, st)
+	case *genForStmt:
+		return s.execGenFor(f, st)
+	case *returnStmt:
+		s.line = st.line
+		// 尾部的单个调用直接返回其全部结果
+		return flowReturn, s.evalList(f, st.exprs, -1)
+	case *breakStmt:
+		return flowBreak, nil
+	}
+	return flowNormal, nil
+}
+
+func (s *State) execNumFor(f *frame, st *numForStmt) (int, []Value) {
+	s.line = st.line
+	start, ok1 := ToNumber(s.eval(f, st.start))
+	limit, ok2 := ToNumber(s.eval(f, st.limit))
+	step := 1.0
+	ok3 := true
+	if st.step != nil {
+		step, ok3 = ToNumber(s.eval(f, st.step))
+	}
+	switch {
+	case !ok1:
+		s.Errorf("'for' initial value must be a number")
+	case !ok2:
+		s.Errorf("'for' limit must be a number")
+	case !ok3:
+		s.Errorf("'for' step must be a number")
+	}
+	for i := start; (step > 0 && i <= limit) || (step <= 0 && i >= limit); i += step {
+		f.slots[st.slot] = &cell{v: i}
+		flow, rets := s.execBlock(f, st.body)
+		if flow == flowBreak {
+			break
+		}
+		if flow == flowReturn {
+			return flow, rets
+		}
+		s.tick()
+	}
+	return flowNormal, nil
+}
+
+func (s *State) execGenFor(f *frame, st *genForStmt) (int, []Value) {
+	s.line = st.line
+	init := s.evalList(f, st.exprs, 3)
+	iter, state, control := init[0], init[1], init[2]
+	for {
+		s.line = st.line
+		rets := s.call(iter, []Value{state, control}, "")
+		var first Value
+		if len(rets) > 0 {
+			first = rets[0]
+		}
+		if first == nil {
+			break
+		}
+		control = first
+		for i, slot := range st.slots {
+			var v Value
+			if i < len(rets) {
+				v = rets[i]
+			}
+			f.slots[slot] = &cell{v: v}
+		}
+		flow, rets := s.execBlock(f, st.body)
+		if flow == flowBreak {
+			break
+		}
+		if flow == flowReturn {
+			return flow, rets
+		}
+		s.tick()
+	}
+	return flowNormal, nil
+}
+
+func (s *State) assign(f *frame, st *assignStmt) {
+	// 先求出所有目标的表与键，再求右侧的值，最后依次赋值
+	type target struct {
+		t   Value
+		key Value
+	}
+	targets := make([]target, len(st.targets))
+	for i, t := range st.targets {
+		if ie, ok := t.(*indexExpr); ok {
+			targets[i] = target{t: s.eval(f, ie.obj), key: s.eval(f, ie.key)}
+		}
+	}
+	vals := s.evalList(f, st.exprs, len(st.targets))
+	for i, t := range st.targets {
+		switch t := t.(type) {
+		case *localExpr:
+			f.slots[t.slot].v = vals[i]
+		case *upvalExpr:
+			f.cl.upvals[t.idx].v = vals[i]
+		case *globalExpr:
+			if s.frozen {
+				s.Errorf("Attempt to modify a readonly table")
+			}
+			s.Globals.Set(t.name, vals[i])
+		case *indexExpr:
+			s.setIndex(targets[i].t, targets[i].key, vals[i], t.obj)
+		}
+	}
+}
+
+func (s *State) setIndex(obj, key, v Value, objExpr expr) {
+	t, ok := obj.(*Table)
+	if !ok {
+		s.opError("index", describe(objExpr), obj)
+	}
+	switch k := key.(type) {
+	case nil:
+		s.Errorf("table index is nil")
+	case float64:
+		if math.IsNaN(k) {
+			s.Errorf("table index is NaN")
+		}
+	}
+	if t.readonly {
+		s.Errorf("Attempt to modify a readonly table")
+	}
+	t.Set(key, v)
+}
+
+func (s *State) makeClosure(f *frame, p *funcProto) *Closure {
+	cl := &Closure{proto: p, upvals: make([]*cell, len(p.upvals))}
+	for i, u := range p.upvals {
+		if u.fromParentLocal {
+			c := f.slots[u.index]
+			if c == nil {
+				// 理论上不会出现（变量声明后才可见），兜底创建空 cell
+				c = &cell{}
+				f.slots[u.index] = c
+			}
+			cl.upvals[i] = c
+		} else {
+			cl.upvals[i] = f.cl.upvals[u.index]
+		}
+	}
+	return cl
+}
+
+// evalList 求值表达式列表：最后一项展开多返回值；want>=0 时补齐/截断为 want 个值。
+func (s *State) evalList(f *frame, exprs []expr, want int) []Value {
+	var vals []Value
+	for i, e := range exprs {
+		if i == len(exprs)-1 {
+			vals = append(vals, s.evalMulti(f, e)...)
+		} else {
+			vals = append(vals, s.eval(f, e))
+		}
+	}
+	if want < 0 {
+		return vals
+	}
+	for len(vals) < want {
+		vals = append(vals, nil)
+	}
+	return vals[:want]
+}
+
+// evalMulti 求值可能返回多个值的表达式（函数调用与 ...）。
+func (s *State) evalMulti(f *frame, e expr) []Value {
+	switch e := e.(type) {
+	case *callExpr:
+		fn := s.eval(f, e.fn)
+		args := s.evalList(f, e.args, -1)
+		s.line = e.line
+		return s.call(fn, args, describe(e.fn))
+	case *methodCallExpr:
+		obj := s.eval(f, e.obj)
+		s.line = e.line
+		fn := s.index(obj, e.name, e.obj)
+		args := append([]Value{obj}, s.evalList(f, e.args, -1)...)
+		s.line = e.line
+		return s.call(fn, args, "method '"+e.name+"'")
+	case *varargExpr:
+		return append([]Value(nil), f.varargs...)
+	}
+	return []Value{s.eval(f, e)}
+}
+
+func (s *State) eval(f *frame, e expr) Value {
+	switch e := e.(type) {
+	case *constExpr:
+		return e.v
+	case *localExpr:
+		return f.slots[e.slot].v
+	case *upvalExpr:
+		return f.cl.upvals[e.idx].v
+	case *globalExpr:
+		v := s.Globals.Get(e.name)
+		if v == nil && s.frozen {
+			s.Errorf("Script attempted to access nonexistent global variable '%s'", e.name)
+		}
+		return v
+	case *indexExpr:
+		obj := s.eval(f, e.obj)
+		return s.index(obj, s.eval(f, e.key), e.obj)
+	case *callExpr, *methodCallExpr, *varargExpr:
+		if vals := s.evalMulti(f, e); len(vals) > 0 {
+			return vals[0]
+		}
+		return nil
+	case *parenExpr:
+		return s.eval(f, e.e)
+	case *functionExpr:
+		return s.makeClosure(f, e.proto)
+	case *andExpr:
+		if l := s.eval(f, e.l); !Truthy(l) {
+			return l
+		}
+		return s.eval(f, e.r)
+	case *orExpr:
+		if l := s.eval(f, e.l); Truthy(l) {
+			return l
+		}
+		return s.eval(f, e.r)
+	case *unExpr:
+		v := s.eval(f, e.e)
+		switch e.op {
+		case "not":
+			return !Truthy(v)
+		case "-":
+			n, ok := ToNumber(v)
+			if !ok {
+				s.opError("perform arithmetic on", describe(e.e), v)
+			}
+			return -n
+		default: // #
+			switch x := v.(type) {
+			case string:
+				return float64(len(x))
+			case *Table:
+				return float64(x.Len())
+			}
+			s.opError("get length of", describe(e.e), v)
+		}
+	case *binExpr:
+		return s.binary(e.op, s.eval(f, e.l), s.eval(f, e.r), e)
+	case *tableExpr:
+		return s.makeTable(f, e)
+	}
+	return nil
+}
+
+func (s *State) index(obj, key Value, objExpr expr) Value {
+	switch o := obj.(type) {
+	case *Table:
+		return o.Get(key)
+	case string:
+		// 字符串可以用 s:upper() 的形式调用 string 库函数
+		if s.stringLib != nil {
+			return s.stringLib.Get(key)
+		}
+	}
+	s.opError("index", describe(objExpr), obj)
+	return nil
+}
+
+func (s *State) makeTable(f *frame, e *tableExpr) *Table {
+	t := NewTable(len(e.fields))
+	n := 0
+	for i, field := range e.fields {
+		if field.key != nil {
+			k := s.eval(f, field.key)
+			s.setIndex(t, k, s.eval(f, field.val), nil)
+			continue
+		}
+		if i == len(e.fields)-1 {
+			for _, v := range s.evalMulti(f, field.val) {
+				n++
+				t.Set(float64(n), v)
+			}
+			continue
+		}
+		n++
+		t.Set(float64(n), s.eval(f, field.val))
+	}
+	return t
+}
+
+func (s *State) binary(op string, a, b Value, e *binExpr) Value {
+	switch op {
+	case "==":
+		return a == b
+	case "~=":
+		return a != b
+	case "<", "<=", ">", ">=":
+		if op == ">" || op == ">=" {
+			a, b = b, a
+			op = map[string]string{">": "<", ">=": "<="}[op]
+		}
+		switch x := a.(type) {
+		case float64:
+			if y, ok := b.(float64); ok {
+				if op == "<" {
+					return x < y
+				}
+				return x <= y
+			}
+		case string:
+			if y, ok := b.(string); ok {
+				if op == "<" {
+					return x < y
+				}
+				return x <= y
+			}
+		}
+		if TypeName(a) == TypeName(b) {
+			s.Errorf("attempt to compare two %s values", TypeName(a))
+		}
+		s.Errorf("attempt to compare %s with %s", TypeName(a), TypeName(b))
+	case "..":
+		as, ok1 := concatString(a)
+		bs, ok2 := concatString(b)
+		if !ok1 {
+			s.opError("concatenate", describe(e.l), a)
+		}
+		if !ok2 {
+			s.opError("concatenate", describe(e.r), b)
+		}
+		return as + bs
+	}
+
+	x, ok1 := ToNumber(a)
+	y, ok2 := ToNumber(b)
+	if !ok1 {
+		s.opError("perform arithmetic on", describe(e.l), a)
+	}
+	if !ok2 {
+		s.opError("perform arithmetic on", describe(e.r), b)
+	}
+	switch op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		return x / y
+	case "%":
+		return x - math.Floor(x/y)*y
+	default: // ^
+		return math.Pow(x, y)
+	}
+}
+
+func concatString(v Value) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return FormatNumber(x), true
+	}
+	return "", false
+}
+
+// describe 返回错误信息中对表达式的描述（"local 'x'"、"global 'x'"、"field 'x'"）。
+func describe(e expr) string {
+	switch e := e.(type) {
+	case *globalExpr:
+		return "global '" + e.name + "'"
+	case *indexExpr:
+		if c, ok := e.key.(*constExpr); ok {
+			if k, ok := c.v.(string); ok {
+				return "field '" + k + "'"
+			}
+		}
+	case *upvalExpr:
+		return "upvalue '" + e.name + "'"
+	case *localExpr:
+		return "local '" + e.name + "'"
+	}
+	return ""
+}
@@ -0,0 +1,306 @@
+// lua 包：纯 Go 实现的 Lua 5.1 子集解释器，供 EVAL/EVALSHA 在 DB Actor 内原子执行脚本。
+// 说明：源码先编译为带变量解析结果的语法树（局部变量/上值/全局变量在编译期确定），再由树遍历解释器执行。
+// 关键点：不依赖外部模块；错误信息格式对齐 Lua（"chunk:line: message"），便于与 Redis 的脚本报错保持一致。
+package lua
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 本文件实现词法分析：
+// - 名字/关键字、数字（十进制、0x 十六进制、指数）、字符串（'' / "" 与转义、[[长字符串]]）
+// - 注释（-- 行注释与 --[[ 块注释 ]]）
+// - 运算符与分隔符（含 .. / ... / == / ~= / <= / >=）
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokName
+	tokKeyword
+	tokString
+	tokNumber
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	s    string // 名字/关键字/符号/字符串内容（数字为原始文本）
+	n    float64
+	line int
+}
+
+var keywords = map[string]struct{}{
+	"and": {}, "break": {}, "do": {}, "else": {}, "elseif": {}, "end": {}, "false": {}, "for": {},
+	"function": {}, "if": {}, "in": {}, "local": {}, "nil": {}, "not": {}, "or": {}, "repeat": {},
+	"return": {}, "then": {}, "true": {}, "until": {}, "while": {},
+}
+
+// syntaxError 为编译期错误，由 Compile 统一恢复为 error。
+type syntaxError struct {
+	msg string
+}
+
+type lexer struct {
+	src   string
+	pos   int
+	line  int
+	chunk string
+}
+
+func (l *lexer) errorf(near string, format string, args ...interface{}) {
+	msg := fmt.Sprintf("%s:%d: %s", l.chunk, l.line, fmt.Sprintf(format, args...))
+	if near != "" {
+		msg += " near '" + near + "'"
+	}
+	panic(&syntaxError{msg: msg})
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool { return isNameStart(c) || isDigit(c) }
+
+// next 读取下一个 token。
+func (l *lexer) next() token {
+	l.skipSpaceAndComments()
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, s: "<eof>", line: l.line}
+	}
+	c := l.src[l.pos]
+	line := l.line
+	switch {
+	case isNameStart(c):
+		start := l.pos
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		word := l.src[start:l.pos]
+		if _, ok := keywords[word]; ok {
+			return token{kind: tokKeyword, s: word, line: line}
+		}
+		return token{kind: tokName, s: word, line: line}
+	case isDigit(c) || (c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		return l.readNumber()
+	case c == '"' || c == '\'':
+		return token{kind: tokString, s: l.readString(c), line: line}
+	case c == '[':
+		if level, ok := l.longBracketLevel(); ok {
+			return token{kind: tokString, s: l.readLongString(level), line: line}
+		}
+	}
+
+	for _, sym := range []string{"...", "..", "==", "~=", "<=", ">="} {
+		if strings.HasPrefix(l.src[l.pos:], sym) {
+			l.pos += len(sym)
+			return token{kind: tokSymbol, s: sym, line: line}
+		}
+	}
+	if strings.IndexByte("+-*/%^#<>=(){}[];:,.", c) >= 0 {
+		l.pos++
+		return token{kind: tokSymbol, s: string(c), line: line}
+	}
+	l.errorf(string(c), "unexpected symbol")
+	return token{}
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "--"):
+			l.pos += 2
+			if l.pos < len(l.src) && l.src[l.pos] == '[' {
+				if level, ok := l.longBracketLevel(); ok {
+					l.readLongString(level)
+					continue
+				}
+			}
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// longBracketLevel 判断当前位置是否为长括号开头 [[ / [=[ ...，返回等号个数。
+func (l *lexer) longBracketLevel() (int, bool) {
+	i := l.pos + 1
+	for i < len(l.src) && l.src[i] == '=' {
+		i++
+	}
+	if i < len(l.src) && l.src[i] == '[' {
+		return i - l.pos - 1, true
+	}
+	return 0, false
+}
+
+func (l *lexer) readLongString(level int) string {
+	l.pos += level + 2
+	// 紧跟开括号的第一个换行不计入内容
+	if strings.HasPrefix(l.src[l.pos:], "\r\n") {
+		l.pos += 2
+		l.line++
+	} else if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+		l.pos++
+		l.line++
+	}
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(l.src[l.pos:], closing)
+	if end < 0 {
+		l.errorf("<eof>", "unfinished long string")
+	}
+	s := l.src[l.pos : l.pos+end]
+	l.line += strings.Count(s, "\n")
+	l.pos += end + len(closing)
+	return s
+}
+
+func (l *lexer) readString(quote byte) string {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			l.errorf(l.src[start:l.pos], "unfinished string")
+		}
+		c := l.src[l.pos]
+		switch c {
+		case quote:
+			l.pos++
+			return b.String()
+		case '\n':
+			l.errorf(l.src[start:l.pos], "unfinished string")
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				l.errorf(l.src[start:l.pos], "unfinished string")
+			}
+			e := l.src[l.pos]
+			switch e {
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'v':
+				b.WriteByte('\v')
+			case '\\', '"', '\'':
+				b.WriteByte(e)
+			case '\n':
+				b.WriteByte('\n')
+				l.line++
+			case 'x':
+				if l.pos+2 >= len(l.src) {
+					l.errorf(l.src[start:l.pos], "hexadecimal digit expected")
+				}
+				v, err := strconv.ParseUint(l.src[l.pos+1:l.pos+3], 16, 8)
+				if err != nil {
+					l.errorf(l.src[start:l.pos], "hexadecimal digit expected")
+				}
+				b.WriteByte(byte(v))
+				l.pos += 2
+			default:
+				if !isDigit(e) {
+					l.errorf(l.src[start:l.pos+1], "invalid escape sequence")
+				}
+				v := 0
+				for i := 0; i < 3 && l.pos < len(l.src) && isDigit(l.src[l.pos]); i++ {
+					v = v*10 + int(l.src[l.pos]-'0')
+					l.pos++
+				}
+				if v > 255 {
+					l.errorf(l.src[start:l.pos], "escape sequence too large")
+				}
+				b.WriteByte(byte(v))
+				continue
+			}
+			l.pos++
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+func (l *lexer) readNumber() token {
+	start := l.pos
+	line := l.line
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isNameChar(c) || c == '.' {
+			l.pos++
+			continue
+		}
+		// 指数部分的符号
+		if (c == '+' || c == '-') && l.pos > start && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') &&
+			!strings.HasPrefix(strings.ToLower(l.src[start:l.pos]), "0x") {
+			l.pos++
+			continue
+		}
+		break
+	}
+	text := l.src[start:l.pos]
+	n, ok := parseNumber(text)
+	if !ok {
+		l.errorf(text, "malformed number")
+	}
+	return token{kind: tokNumber, s: text, n: n, line: line}
+}
+
+// parseNumber 按 Lua 规则把字符串转换为数字（允许首尾空白、0x 十六进制）。
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	neg := false
+	body := s
+	if body[0] == '-' || body[0] == '+' {
+		neg = body[0] == '-'
+		body = body[1:]
+	}
+	if len(body) > 2 && (body[:2] == "0x" || body[:2] == "0X") {
+		v, err := strconv.ParseUint(body[2:], 16, 64)
+		if err != nil {
+			return 0, false
+		}
+		if neg {
+			return -float64(v), true
+		}
+		return float64(v), true
+	}
+	// strconv 接受 "inf"/"nan"/下划线等 Lua 不接受的写法，这里先排除
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if !isDigit(c) && c != '.' && c != 'e' && c != 'E' && c != '+' && c != '-' {
+			return 0, false
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); !ok || ne.Err != strconv.ErrRange {
+			return 0, false
+		}
+	}
+	return v, true
+}
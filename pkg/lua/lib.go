@@ -0,0 +1,604 @@
+// Lua 标准库子集：base / string / table / math 中脚本常用的函数。
+// 说明：参数校验与错误信息对齐 Lua 5.1（"bad argument #1 to 'insert' (table expected, got nil)"）。
+// 关键点：不提供 io/os/load 等访问外部环境的函数；string 的模式匹配（Lua pattern）未实现，find 只支持纯文本查找。
+package lua
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 本文件实现标准库：
+// - base：assert error pcall type tostring tonumber ipairs pairs next select unpack rawget rawset rawequal
+// - string：len sub upper lower rep reverse byte char format find（字符串值可用 s:len() 形式调用）
+// - table：insert remove concat getn sort
+// - math：abs ceil floor sqrt max min fmod modf pow exp log log10 huge pi
+
+func openLibs(s *State) {
+	for name, fn := range map[string]func(*State, []Value) []Value{
+		"assert": baseAssert, "error": baseError, "pcall": basePcall,
+		"type": baseType, "tostring": baseTostring, "tonumber": baseTonumber,
+		"ipairs": baseIpairs, "pairs": basePairs, "next": baseNext,
+		"select": baseSelect, "unpack": baseUnpack,
+		"rawget": baseRawget, "rawset": baseRawset, "rawequal": baseRawequal,
+	} {
+		s.Register("", name, fn)
+	}
+	for name, fn := range map[string]func(*State, []Value) []Value{
+		"len": strLen, "sub": strSub, "upper": strUpper, "lower": strLower,
+		"rep": strRep, "reverse": strReverse, "byte": strByte, "char": strChar,
+		"format": strFormat, "find": strFind,
+	} {
+		s.Register("string", name, fn)
+	}
+	s.stringLib = s.Globals.Get("string").(*Table)
+	for name, fn := range map[string]func(*State, []Value) []Value{
+		"insert": tabInsert, "remove": tabRemove, "concat": tabConcat,
+		"getn": tabGetn, "sort": tabSort,
+	} {
+		s.Register("table", name, fn)
+	}
+	for name, fn := range map[string]func(float64) float64{
+		"abs": math.Abs, "ceil": math.Ceil, "floor": math.Floor, "sqrt": math.Sqrt,
+		"exp": math.Exp, "log10": math.Log10,
+	} {
+		fn := fn
+		fname := name
+		s.Register("math", name, func(s *State, args []Value) []Value {
+			return []Value{fn(checkNumber(s, args, 0, fname))}
+		})
+	}
+	for name, fn := range map[string]func(*State, []Value) []Value{
+		"max": mathMax, "min": mathMin, "fmod": mathFmod, "modf": mathModf,
+		"pow": mathPow, "log": mathLog,
+	} {
+		s.Register("math", name, fn)
+	}
+	mathLib := s.Globals.Get("math").(*Table)
+	mathLib.Set("huge", math.Inf(1))
+	mathLib.Set("pi", math.Pi)
+}
+
+// --- 参数检查 ---
+
+func argError(s *State, i int, fname, msg string) {
+	s.Errorf("bad argument #%d to '%s' (%s)", i+1, fname, msg)
+}
+
+func typeError(s *State, args []Value, i int, fname, want string) {
+	got := "no value"
+	if i < len(args) {
+		got = TypeName(args[i])
+	}
+	argError(s, i, fname, want+" expected, got "+got)
+}
+
+func arg(args []Value, i int) Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func checkAny(s *State, args []Value, i int, fname string) Value {
+	if i >= len(args) {
+		argError(s, i, fname, "value expected")
+	}
+	return args[i]
+}
+
+func checkTable(s *State, args []Value, i int, fname string) *Table {
+	t, ok := arg(args, i).(*Table)
+	if !ok {
+		typeError(s, args, i, fname, "table")
+	}
+	return t
+}
+
+func checkNumber(s *State, args []Value, i int, fname string) float64 {
+	n, ok := ToNumber(arg(args, i))
+	if !ok {
+		typeError(s, args, i, fname, "number")
+	}
+	return n
+}
+
+func checkInt(s *State, args []Value, i int, fname string) int {
+	n := checkNumber(s, args, i, fname)
+	if n >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if n <= math.MinInt32 {
+		return math.MinInt32
+	}
+	return int(n)
+}
+
+func optInt(s *State, args []Value, i int, fname string, def int) int {
+	if arg(args, i) == nil {
+		return def
+	}
+	return checkInt(s, args, i, fname)
+}
+
+func checkString(s *State, args []Value, i int, fname string) string {
+	switch v := arg(args, i).(type) {
+	case string:
+		return v
+	case float64:
+		return FormatNumber(v)
+	}
+	typeError(s, args, i, fname, "string")
+	return ""
+}
+
+// --- base ---
+
+func baseAssert(s *State, args []Value) []Value {
+	if !Truthy(checkAny(s, args, 0, "assert")) {
+		if len(args) > 1 {
+			s.Raise(args[1])
+		}
+		s.Errorf("assertion failed!")
+	}
+	return args
+}
+
+func baseError(s *State, args []Value) []Value {
+	v := arg(args, 0)
+	level := optInt(s, args, 1, "error", 1)
+	if msg, ok := v.(string); ok && level > 0 {
+		v = s.where() + msg
+	}
+	s.Raise(v)
+	return nil
+}
+
+func basePcall(s *State, args []Value) (rets []Value) {
+	fn := checkAny(s, args, 0, "pcall")
+	depth, chunk, line := s.depth, s.chunk, s.line
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			s.depth, s.chunk, s.line = depth, chunk, line
+			rets = []Value{false, e.Value}
+		}
+	}()
+	return append([]Value{true}, s.call(fn, args[1:], "")...)
+}
+
+func baseType(s *State, args []Value) []Value {
+	return []Value{TypeName(checkAny(s, args, 0, "type"))}
+}
+
+func baseTostring(s *State, args []Value) []Value {
+	return []Value{ToString(checkAny(s, args, 0, "tostring"))}
+}
+
+func baseTonumber(s *State, args []Value) []Value {
+	v := checkAny(s, args, 0, "tonumber")
+	base := optInt(s, args, 1, "tonumber", 10)
+	if base == 10 {
+		if n, ok := ToNumber(v); ok {
+			return []Value{n}
+		}
+		return []Value{nil}
+	}
+	if base < 2 || base > 36 {
+		argError(s, 1, "tonumber", "base out of range")
+	}
+	str := strings.ToLower(strings.TrimSpace(checkString(s, args, 0, "tonumber")))
+	n, err := strconv.ParseInt(str, base, 64)
+	if err != nil {
+		return []Value{nil}
+	}
+	return []Value{float64(n)}
+}
+
+func ipairsAux(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "ipairs")
+	i := checkNumber(s, args, 1, "ipairs") + 1
+	v := t.Get(i)
+	if v == nil {
+		return []Value{nil}
+	}
+	return []Value{i, v}
+}
+
+var ipairsIter = &GoFunction{Name: "ipairs_aux", Fn: ipairsAux}
+
+func baseIpairs(s *State, args []Value) []Value {
+	return []Value{ipairsIter, checkTable(s, args, 0, "ipairs"), 0.0}
+}
+
+var nextFn = &GoFunction{Name: "next", Fn: baseNext}
+
+func basePairs(s *State, args []Value) []Value {
+	return []Value{nextFn, checkTable(s, args, 0, "pairs"), nil}
+}
+
+func baseNext(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "next")
+	k, v, ok := t.Next(arg(args, 1))
+	if !ok {
+		s.Errorf("invalid key to 'next'")
+	}
+	if k == nil {
+		return []Value{nil}
+	}
+	return []Value{k, v}
+}
+
+func baseSelect(s *State, args []Value) []Value {
+	if str, ok := arg(args, 0).(string); ok && str == "#" {
+		return []Value{float64(len(args) - 1)}
+	}
+	n := checkInt(s, args, 0, "select")
+	if n < 0 {
+		n = len(args) + n
+	} else if n > len(args)-1 {
+		return nil
+	}
+	if n < 1 {
+		argError(s, 0, "select", "index out of range")
+	}
+	return args[n:]
+}
+
+func baseUnpack(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "unpack")
+	i := optInt(s, args, 1, "unpack", 1)
+	j := optInt(s, args, 2, "unpack", t.Len())
+	if i > j {
+		return nil
+	}
+	if j-i >= 1<<20 {
+		s.Errorf("too many results to unpack")
+	}
+	out := make([]Value, 0, j-i+1)
+	for k := i; k <= j; k++ {
+		out = append(out, t.Get(float64(k)))
+	}
+	return out
+}
+
+func baseRawget(s *State, args []Value) []Value {
+	return []Value{checkTable(s, args, 0, "rawget").Get(checkAny(s, args, 1, "rawget"))}
+}
+
+func baseRawset(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "rawset")
+	s.setIndex(t, checkAny(s, args, 1, "rawset"), checkAny(s, args, 2, "rawset"), nil)
+	return []Value{t}
+}
+
+func baseRawequal(s *State, args []Value) []Value {
+	return []Value{checkAny(s, args, 0, "rawequal") == checkAny(s, args, 1, "rawequal")}
+}
+
+// --- string ---
+
+// strRange 把 Lua 的 [i, j]（1 起始、负数从末尾计）转换为 Go 切片下标，i>j 时返回空区间。
+func strRange(n, i, j int) (int, int) {
+	if i < 0 {
+		i = n + i + 1
+	}
+	if j < 0 {
+		j = n + j + 1
+	}
+	if i < 1 {
+		i = 1
+	}
+	if j > n {
+		j = n
+	}
+	if i > j {
+		return 0, 0
+	}
+	return i - 1, j
+}
+
+func strLen(s *State, args []Value) []Value {
+	return []Value{float64(len(checkString(s, args, 0, "len")))}
+}
+
+func strSub(s *State, args []Value) []Value {
+	str := checkString(s, args, 0, "sub")
+	from, to := strRange(len(str), optInt(s, args, 1, "sub", 1), optInt(s, args, 2, "sub", -1))
+	return []Value{str[from:to]}
+}
+
+func strUpper(s *State, args []Value) []Value {
+	return []Value{strings.ToUpper(checkString(s, args, 0, "upper"))}
+}
+
+func strLower(s *State, args []Value) []Value {
+	return []Value{strings.ToLower(checkString(s, args, 0, "lower"))}
+}
+
+func strRep(s *State, args []Value) []Value {
+	str := checkString(s, args, 0, "rep")
+	n := checkInt(s, args, 1, "rep")
+	if n <= 0 || str == "" {
+		return []Value{""}
+	}
+	if len(str)*n > 512*1024*1024 {
+		s.Errorf("resulting string too large")
+	}
+	return []Value{strings.Repeat(str, n)}
+}
+
+func strReverse(s *State, args []Value) []Value {
+	b := []byte(checkString(s, args, 0, "reverse"))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return []Value{string(b)}
+}
+
+func strByte(s *State, args []Value) []Value {
+	str := checkString(s, args, 0, "byte")
+	i := optInt(s, args, 1, "byte", 1)
+	from, to := strRange(len(str), i, optInt(s, args, 2, "byte", i))
+	out := make([]Value, 0, to-from)
+	for k := from; k < to; k++ {
+		out = append(out, float64(str[k]))
+	}
+	return out
+}
+
+func strChar(s *State, args []Value) []Value {
+	b := make([]byte, len(args))
+	for i := range args {
+		c := checkInt(s, args, i, "char")
+		if c < 0 || c > 255 {
+			argError(s, i, "char", "invalid value")
+		}
+		b[i] = byte(c)
+	}
+	return []Value{string(b)}
+}
+
+// strFind 只支持纯文本查找（plain=true，或模式中不含特殊字符）。
+func strFind(s *State, args []Value) []Value {
+	str := checkString(s, args, 0, "find")
+	pat := checkString(s, args, 1, "find")
+	init := optInt(s, args, 2, "find", 1)
+	if init < 0 {
+		init = len(str) + init + 1
+		if init < 1 {
+			init = 1
+		}
+	} else if init == 0 {
+		init = 1
+	}
+	if init > len(str)+1 {
+		return []Value{nil}
+	}
+	if !Truthy(arg(args, 3)) && strings.ContainsAny(pat, "^$*+?.([%-") {
+		s.Errorf("pattern matching is not supported, use string.find(s, pattern, init, true)")
+	}
+	idx := strings.Index(str[init-1:], pat)
+	if idx < 0 {
+		return []Value{nil}
+	}
+	start := init + idx
+	return []Value{float64(start), float64(start + len(pat) - 1)}
+}
+
+func strFormat(s *State, args []Value) []Value {
+	f := checkString(s, args, 0, "format")
+	var b strings.Builder
+	n := 1
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			b.WriteByte(f[i])
+			continue
+		}
+		i++
+		if i < len(f) && f[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		start := i
+		for i < len(f) && strings.IndexByte("-+ #0", f[i]) >= 0 {
+			i++
+		}
+		for i < len(f) && isDigit(f[i]) {
+			i++
+		}
+		hasPrec := false
+		if i < len(f) && f[i] == '.' {
+			hasPrec = true
+			i++
+			for i < len(f) && isDigit(f[i]) {
+				i++
+			}
+		}
+		if i >= len(f) {
+			s.Errorf("invalid option '%%' to 'format'")
+		}
+		spec := "%" + f[start:i]
+		verb := f[i]
+		switch verb {
+		case 'd', 'i':
+			b.WriteString(fmt.Sprintf(spec+"d", int64(checkNumber(s, args, n, "format"))))
+		case 'u':
+			b.WriteString(fmt.Sprintf(spec+"d", uint64(int64(checkNumber(s, args, n, "format")))))
+		case 'x', 'X', 'o':
+			b.WriteString(fmt.Sprintf(spec+string(verb), uint64(int64(checkNumber(s, args, n, "format")))))
+		case 'c':
+			b.WriteByte(byte(checkNumber(s, args, n, "format")))
+		case 'e', 'E', 'f', 'g', 'G':
+			if !hasPrec && (verb == 'g' || verb == 'G') {
+				spec += ".6" // C 的 %g 默认精度为 6，Go 默认为最短表示
+			}
+			b.WriteString(fmt.Sprintf(spec+string(verb), checkNumber(s, args, n, "format")))
+		case 's':
+			b.WriteString(fmt.Sprintf(spec+"s", ToString(checkAny(s, args, n, "format"))))
+		case 'q':
+			b.WriteString(quoteString(checkString(s, args, n, "format")))
+		default:
+			s.Errorf("invalid option '%%%c' to 'format'", verb)
+		}
+		n++
+	}
+	return []Value{b.String()}
+}
+
+// quoteString 实现 %q：输出可以被 Lua 重新读入的字符串字面量。
+func quoteString(str string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+			b.WriteString("\\r")
+		case 0:
+			b.WriteString("\\000")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// --- table ---
+
+func tabInsert(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "insert")
+	n := t.Len()
+	switch len(args) {
+	case 2:
+		t.Set(float64(n+1), args[1])
+	case 3:
+		pos := checkInt(s, args, 1, "insert")
+		for i := n; i >= pos; i-- {
+			t.Set(float64(i+1), t.Get(float64(i)))
+		}
+		s.setIndex(t, float64(pos), args[2], nil)
+	default:
+		s.Errorf("wrong number of arguments to 'insert'")
+	}
+	return nil
+}
+
+func tabRemove(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "remove")
+	n := t.Len()
+	pos := optInt(s, args, 1, "remove", n)
+	if n == 0 {
+		return nil
+	}
+	v := t.Get(float64(pos))
+	for i := pos; i < n; i++ {
+		t.Set(float64(i), t.Get(float64(i+1)))
+	}
+	t.Set(float64(n), nil)
+	return []Value{v}
+}
+
+func tabConcat(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "concat")
+	sep := ""
+	if arg(args, 1) != nil {
+		sep = checkString(s, args, 1, "concat")
+	}
+	i := optInt(s, args, 2, "concat", 1)
+	j := optInt(s, args, 3, "concat", t.Len())
+	var b strings.Builder
+	for k := i; k <= j; k++ {
+		str, ok := concatString(t.Get(float64(k)))
+		if !ok {
+			s.Errorf("invalid value (at index %d) in table for 'concat'", k)
+		}
+		b.WriteString(str)
+		if k < j {
+			b.WriteString(sep)
+		}
+	}
+	return []Value{b.String()}
+}
+
+func tabGetn(s *State, args []Value) []Value {
+	return []Value{float64(checkTable(s, args, 0, "getn").Len())}
+}
+
+func tabSort(s *State, args []Value) []Value {
+	t := checkTable(s, args, 0, "sort")
+	comp := arg(args, 1)
+	if comp != nil {
+		switch comp.(type) {
+		case *Closure, *GoFunction:
+		default:
+			typeError(s, args, 1, "sort", "function")
+		}
+	}
+	n := t.Len()
+	items := make([]Value, n)
+	for i := range items {
+		items[i] = t.Get(float64(i + 1))
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if comp != nil {
+			rets := s.call(comp, []Value{items[i], items[j]}, "")
+			return len(rets) > 0 && Truthy(rets[0])
+		}
+		return Truthy(s.binary("<", items[i], items[j], nil))
+	})
+	for i, v := range items {
+		t.Set(float64(i+1), v)
+	}
+	return nil
+}
+
+// --- math ---
+
+func mathMax(s *State, args []Value) []Value {
+	m := checkNumber(s, args, 0, "max")
+	for i := 1; i < len(args); i++ {
+		m = math.Max(m, checkNumber(s, args, i, "max"))
+	}
+	return []Value{m}
+}
+
+func mathMin(s *State, args []Value) []Value {
+	m := checkNumber(s, args, 0, "min")
+	for i := 1; i < len(args); i++ {
+		m = math.Min(m, checkNumber(s, args, i, "min"))
+	}
+	return []Value{m}
+}
+
+func mathFmod(s *State, args []Value) []Value {
+	return []Value{math.Mod(checkNumber(s, args, 0, "fmod"), checkNumber(s, args, 1, "fmod"))}
+}
+
+func mathModf(s *State, args []Value) []Value {
+	i, f := math.Modf(checkNumber(s, args, 0, "modf"))
+	return []Value{i, f}
+}
+
+func mathPow(s *State, args []Value) []Value {
+	return []Value{math.Pow(checkNumber(s, args, 0, "pow"), checkNumber(s, args, 1, "pow"))}
+}
+
+func mathLog(s *State, args []Value) []Value {
+	x := checkNumber(s, args, 0, "log")
+	if arg(args, 1) == nil {
+		return []Value{math.Log(x)}
+	}
+	return []Value{math.Log(x) / math.Log(checkNumber(s, args, 1, "log"))}
+}
@@ -0,0 +1,211 @@
+// Lua 解释器测试：对照 Lua 5.1 的求值结果与错误信息。
+// 覆盖：算术/字符串/比较、闭包与循环变量、表的遍历顺序、标准库子集、pcall/error、冻结的全局表、编译错误与中断。
+package lua
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, src string) ([]Value, error) {
+	t.Helper()
+	p, err := Compile(src, "test")
+	if err != nil {
+		t.Fatalf("compile %q: %v", src, err)
+	}
+	s := NewState()
+	return s.Call(s.Load(p))
+}
+
+func TestEval(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"return 1 + 2 * 3", "7"},
+		{"return 2 ^ 10", "1024"},
+		{"return 7 % 3, -7 % 3", "1 2"},
+		{"return 1 / 2", "0.5"},
+		{"return 10 / 3", "3.3333333333333"},
+		{"return '10' + 1", "11"},
+		{"return 'a' .. 1 .. 'b'", "a1b"},
+		{"return #'hello', #{1, 2, 3}", "5 3"},
+		{"return 1 < 2, 'a' < 'b', 1 == '1'", "true true false"},
+		{"return nil and 1, false or 'x', 1 and 2", "nil x 2"},
+		{"return not nil, not 0", "true false"},
+		{"return 0x10, 1e2", "16 100"},
+		{"return [[long\nstring]]", "long\nstring"},
+		{"return 'a\\tb\\65'", "a\tbA"},
+		{"local a, b = 1; return a, b", "1 nil"},
+		{"local function f(...) return select('#', ...) end return f(1, nil, 3)", "3"},
+		{"local function f() return 1, 2 end return f(), f()", "1 1 2"},
+		{"local t = {f = function(self, x) return self.v + x end, v = 1} return t:f(2)", "3"},
+		{"local s = 0 for i = 10, 1, -3 do s = s + i end return s", "22"},
+		{"local s = 0 while true do s = s + 1 if s > 4 then break end end return s", "5"},
+		{"local n = 0 repeat local m = n n = n + 1 until m >= 3 return n", "4"},
+		{"local x = 5 if x < 3 then return 'a' elseif x < 6 then return 'b' else return 'c' end", "b"},
+		{"local function fib(n) if n < 2 then return n end return fib(n-1) + fib(n-2) end return fib(20)", "6765"},
+	}
+	for _, c := range cases {
+		rets, err := run(t, c.src)
+		if err != nil {
+			t.Fatalf("%q: %v", c.src, err)
+		}
+		parts := make([]string, len(rets))
+		for i, v := range rets {
+			parts[i] = ToString(v)
+		}
+		if got := strings.Join(parts, " "); got != c.want {
+			t.Fatalf("%q = %q, want %q", c.src, got, c.want)
+		}
+	}
+}
+
+func TestClosureAndTable(t *testing.T) {
+	rets, err := run(t, `
+		local fs = {}
+		for i = 1, 3 do fs[i] = function() return i end end
+		local counter = (function() local n = 0 return function() n = n + 1 return n end end)()
+		counter() counter()
+		local t = {1, 2, 3, x = 'a', y = 'b'}
+		t[4] = 4
+		t.x = nil
+		local keys = {}
+		for k, v in pairs(t) do keys[#keys + 1] = tostring(k) end
+		return fs[1]() + fs[2]() + fs[3](), counter(), table.concat(keys, ",")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != 6.0 || rets[1] != 3.0 || rets[2] != "1,2,3,4,y" {
+		t.Fatalf("got %v", rets)
+	}
+}
+
+func TestLibs(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"return string.format('%s=%d %5.2f %q', 'k', 42, 3.14159, 'a\"b')", `k=42  3.14 "a\"b"`},
+		{"return ('abc'):upper(), string.sub('hello', 2, -2), string.rep('ab', 3)", "ABC ell ababab"},
+		{"return string.byte('A'), string.char(72, 105)", "65 Hi"},
+		{"return string.find('hello world', 'o w', 1, true)", "5 7"},
+		{"local t = {3, 1, 2} table.sort(t) return table.concat(t, '-')", "1-2-3"},
+		{"local t = {3, 1, 2} table.sort(t, function(a, b) return a > b end) return table.concat(t, '-')", "3-2-1"},
+		{"local t = {} table.insert(t, 'a') table.insert(t, 1, 'b') return table.remove(t), #t", "a 1"},
+		{"return unpack({1, 2, 3})", "1 2 3"},
+		{"return tonumber('0x1f'), tonumber('z', 36), tonumber('x')", "31 35 nil"},
+		{"return math.floor(3.7), math.max(1, 5, 3), math.fmod(7, 3)", "3 5 1"},
+		{"return type(nil), type({}), type(print or type)", "nil table function"},
+	}
+	for _, c := range cases {
+		rets, err := run(t, c.src)
+		if err != nil {
+			t.Fatalf("%q: %v", c.src, err)
+		}
+		parts := make([]string, len(rets))
+		for i, v := range rets {
+			parts[i] = ToString(v)
+		}
+		if got := strings.Join(parts, " "); got != c.want {
+			t.Fatalf("%q = %q, want %q", c.src, got, c.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"local x error('boom')", "test:1: boom"},
+		{"error({code = 1})", "(error object is a table value)"},
+		{"error('plain', 0)", "plain"},
+		{"local t = nil\nreturn t.x", "test:2: attempt to index local 't' (a nil value)"},
+		{"return undefinedfn()", "test:1: attempt to call global 'undefinedfn' (a nil value)"},
+		{"return {} + 1", "test:1: attempt to perform arithmetic on a table value"},
+		{"return 1 < 'x'", "test:1: attempt to compare number with string"},
+		{"return ('x'):bad()", "test:1: attempt to call method 'bad' (a nil value)"},
+		{"return string.rep()", "test:1: bad argument #1 to 'rep' (string expected, got no value)"},
+		{"local function f() return f() + 1 end return f()", "stack overflow"},
+	}
+	for _, c := range cases {
+		_, err := run(t, c.src)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%q: err = %v, want %q", c.src, err, c.want)
+		}
+	}
+
+	rets, err := run(t, "return pcall(function() error('x', 0) end), pcall(error, {1})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != false || rets[1] != false {
+		t.Fatalf("pcall = %v", rets)
+	}
+	if _, ok := rets[2].(*Table); !ok {
+		t.Fatalf("pcall error value = %v", rets[2])
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"return 1 +", "'<eof>'"},
+		{"x = = 1", "unexpected symbol near '='"},
+		{"break", "no loop to break"},
+		{"local s = 'abc", "unfinished string"},
+		{"for i = 1 do end", "',' expected"},
+		{"return 1\nreturn 2", "'<eof>' expected"},
+	}
+	for _, c := range cases {
+		_, err := Compile(c.src, "test")
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%q: err = %v, want %q", c.src, err, c.want)
+		}
+	}
+}
+
+func TestFreezeAndInterrupt(t *testing.T) {
+	p, err := Compile("return y", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewState()
+	s.SetGlobal("x", 1.0)
+	s.Freeze()
+	if _, err := s.Call(s.Load(p)); err == nil || !strings.Contains(err.Error(), "Script attempted to access nonexistent global variable 'y'") {
+		t.Fatalf("read undefined global: %v", err)
+	}
+	p, _ = Compile("z = 1", "test")
+	if _, err := s.Call(s.Load(p)); err == nil || !strings.Contains(err.Error(), "Attempt to modify a readonly table") {
+		t.Fatalf("create global: %v", err)
+	}
+	p, _ = Compile("string.len = nil", "test")
+	if _, err := s.Call(s.Load(p)); err == nil || !strings.Contains(err.Error(), "Attempt to modify a readonly table") {
+		t.Fatalf("modify library: %v", err)
+	}
+
+	stop := errors.New("stopped")
+	p, _ = Compile("while true do end", "test")
+	s = NewState()
+	n := 0
+	s.Interrupt = func() error {
+		if n++; n > 3 {
+			return stop
+		}
+		return nil
+	}
+	if _, err := s.Call(s.Load(p)); err != stop {
+		t.Fatalf("interrupt: %v", err)
+	}
+	// 中断不能被 pcall 捕获
+	p, _ = Compile("pcall(function() while true do end end) return 1", "test")
+	n = 0
+	if _, err := s.Call(s.Load(p)); err != stop {
+		t.Fatalf("interrupt inside pcall: %v", err)
+	}
+}
@@ -0,0 +1,624 @@
+// Lua 语法分析：递归下降解析 Lua 5.1 语法，并在解析过程中完成变量解析（局部/上值/全局）。
+// 说明：运算符优先级与结合性对齐 Lua 5.1（.. 与 ^ 右结合，一元运算符优先级高于除 ^ 外的二元运算符）。
+// 关键点：错误信息沿用 Lua 的措辞（"'=' expected near 'x'"、"'end' expected (to close 'if' at line 1)"），方便用户定位。
+package lua
+
+import (
+	"fmt"
+)
+
+// 本文件实现编译入口 Compile 与语法分析器。
+// 不支持的语法：goto/标签（Lua 5.2+）、整除 // 与位运算（Lua 5.3+）。
+
+// Proto 为编译后的脚本（主函数原型），可被多个 State 重复执行。
+type Proto struct {
+	main *funcProto
+}
+
+// Compile 编译 Lua 源码；chunk 为错误信息中使用的代码块名（如 "user_script"）。
+func Compile(src, chunk string) (proto *Proto, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			proto, err = nil, fmt.Errorf("%s", se.msg)
+		}
+	}()
+	p := &parser{lex: &lexer{src: src, line: 1, chunk: chunk}}
+	p.advance()
+	fs := &funcState{proto: &funcProto{chunk: chunk, name: "main chunk", line: 0, isVararg: true}}
+	p.fs = fs
+	fs.openScope()
+	fs.proto.body = p.block()
+	if p.tok.kind != tokEOF {
+		p.errorExpected("<eof>")
+	}
+	return &Proto{main: fs.proto}, nil
+}
+
+// funcState 为正在解析的函数的作用域信息。
+type funcState struct {
+	parent    *funcState
+	proto     *funcProto
+	scopes    []map[string]int // 块作用域栈：名字 -> 槽位
+	upvalIdx  map[string]int
+	loopDepth int
+}
+
+func (fs *funcState) openScope()  { fs.scopes = append(fs.scopes, make(map[string]int)) }
+func (fs *funcState) closeScope() { fs.scopes = fs.scopes[:len(fs.scopes)-1] }
+
+// declare 为局部变量分配新槽位（声明后才可见，由调用方决定时机）。
+func (fs *funcState) declare(name string) int {
+	slot := fs.proto.numSlots
+	fs.proto.numSlots++
+	fs.scopes[len(fs.scopes)-1][name] = slot
+	return slot
+}
+
+func (fs *funcState) findLocal(name string) (int, bool) {
+	for i := len(fs.scopes) - 1; i >= 0; i-- {
+		if slot, ok := fs.scopes[i][name]; ok {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+func (fs *funcState) findUpval(name string) (int, bool) {
+	if idx, ok := fs.upvalIdx[name]; ok {
+		return idx, true
+	}
+	if fs.parent == nil {
+		return 0, false
+	}
+	var desc upvalDesc
+	if slot, ok := fs.parent.findLocal(name); ok {
+		desc = upvalDesc{fromParentLocal: true, index: slot}
+	} else if idx, ok := fs.parent.findUpval(name); ok {
+		desc = upvalDesc{index: idx}
+	} else {
+		return 0, false
+	}
+	if fs.upvalIdx == nil {
+		fs.upvalIdx = make(map[string]int)
+	}
+	idx := len(fs.proto.upvals)
+	fs.proto.upvals = append(fs.proto.upvals, desc)
+	fs.upvalIdx[name] = idx
+	return idx, true
+}
+
+func (fs *funcState) resolve(name string) expr {
+	if slot, ok := fs.findLocal(name); ok {
+		return &localExpr{slot: slot, name: name}
+	}
+	if idx, ok := fs.findUpval(name); ok {
+		return &upvalExpr{idx: idx, name: name}
+	}
+	return &globalExpr{name: name}
+}
+
+type parser struct {
+	lex  *lexer
+	tok  token
+	peek *token
+	fs   *funcState
+}
+
+func (p *parser) advance() {
+	if p.peek != nil {
+		p.tok = *p.peek
+		p.peek = nil
+		return
+	}
+	p.tok = p.lex.next()
+}
+
+func (p *parser) lookahead() token {
+	if p.peek == nil {
+		t := p.lex.next()
+		p.peek = &t
+	}
+	return *p.peek
+}
+
+// is 判断当前 token 是否为指定的关键字或符号。
+func (p *parser) is(s string) bool {
+	return (p.tok.kind == tokKeyword || p.tok.kind == tokSymbol) && p.tok.s == s
+}
+
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	p.lex.line = p.tok.line
+	p.lex.errorf(p.tok.s, format, args...)
+}
+
+func (p *parser) errorExpected(what string) {
+	p.errorf("'%s' expected", what)
+}
+
+func (p *parser) expect(s string) {
+	if !p.accept(s) {
+		p.errorExpected(s)
+	}
+}
+
+// expectMatch 期待闭合符号 what（与 line 行的 who 配对）。
+func (p *parser) expectMatch(what, who string, line int) {
+	if p.accept(what) {
+		return
+	}
+	if line == p.tok.line {
+		p.errorExpected(what)
+	}
+	p.errorf("'%s' expected (to close '%s' at line %d)", what, who, line)
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.errorExpected("<name>")
+	}
+	s := p.tok.s
+	p.advance()
+	return s
+}
+
+// blockEnd 判断是否到达块结束。
+func (p *parser) blockEnd() bool {
+	if p.tok.kind == tokEOF {
+		return true
+	}
+	if p.tok.kind != tokKeyword {
+		return false
+	}
+	switch p.tok.s {
+	case "end", "else", "elseif", "until":
+		return true
+	}
+	return false
+}
+
+// block 解析语句序列（不负责打开作用域）。
+func (p *parser) block() *block {
+	b := &block{}
+	for !p.blockEnd() {
+		if p.is("return") {
+			b.stmts = append(b.stmts, p.returnStat())
+			break
+		}
+		if s := p.statement(); s != nil {
+			b.stmts = append(b.stmts, s)
+		}
+	}
+	return b
+}
+
+// scopedBlock 在新作用域中解析块。
+func (p *parser) scopedBlock() *block {
+	p.fs.openScope()
+	b := p.block()
+	p.fs.closeScope()
+	return b
+}
+
+func (p *parser) returnStat() stmt {
+	line := p.tok.line
+	p.advance()
+	s := &returnStmt{line: line}
+	if !p.blockEnd() && !p.is(";") {
+		s.exprs = p.exprList()
+	}
+	p.accept(";")
+	if !p.blockEnd() {
+		p.errorExpected("<eof>")
+	}
+	return s
+}
+
+func (p *parser) statement() stmt {
+	line := p.tok.line
+	switch {
+	case p.accept(";"):
+		return nil
+	case p.is("if"):
+		return p.ifStat(line)
+	case p.accept("while"):
+		cond := p.expr()
+		p.expect("do")
+		p.fs.loopDepth++
+		body := p.scopedBlock()
+		p.fs.loopDepth--
+		p.expectMatch("end", "while", line)
+		return &whileStmt{cond: cond, body: body, line: line}
+	case p.accept("do"):
+		body := p.scopedBlock()
+		p.expectMatch("end", "do", line)
+		return &doStmt{body: body}
+	case p.accept("for"):
+		return p.forStat(line)
+	case p.accept("repeat"):
+		// until 条件可以引用循环体内声明的局部变量
+		p.fs.loopDepth++
+		p.fs.openScope()
+		body := p.block()
+		p.expectMatch("until", "repeat", line)
+		cond := p.expr()
+		p.fs.closeScope()
+		p.fs.loopDepth--
+		return &repeatStmt{body: body, cond: cond, line: line}
+	case p.accept("function"):
+		return p.funcStat(line)
+	case p.accept("local"):
+		if p.accept("function") {
+			name := p.name()
+			slot := p.fs.declare(name) // 先声明，函数体内可以递归引用自身
+			fn := p.funcBody(name, line, false)
+			return &localFuncStmt{slot: slot, fn: fn, line: line}
+		}
+		names := []string{p.name()}
+		for p.accept(",") {
+			names = append(names, p.name())
+		}
+		s := &localStmt{line: line}
+		if p.accept("=") {
+			s.exprs = p.exprList()
+		}
+		for _, n := range names {
+			s.slots = append(s.slots, p.fs.declare(n))
+		}
+		return s
+	case p.is("break"):
+		if p.fs.loopDepth == 0 {
+			p.errorf("no loop to break")
+		}
+		p.advance()
+		return &breakStmt{}
+	}
+	return p.exprStat(line)
+}
+
+func (p *parser) ifStat(line int) stmt {
+	s := &ifStmt{line: line}
+	p.advance()
+	s.conds = append(s.conds, p.expr())
+	p.expect("then")
+	s.blocks = append(s.blocks, p.scopedBlock())
+	for {
+		switch {
+		case p.accept("elseif"):
+			s.conds = append(s.conds, p.expr())
+			p.expect("then")
+			s.blocks = append(s.blocks, p.scopedBlock())
+			continue
+		case p.accept("else"):
+			s.orElse = p.scopedBlock()
+		}
+		break
+	}
+	p.expectMatch("end", "if", line)
+	return s
+}
+
+func (p *parser) forStat(line int) stmt {
+	first := p.name()
+	if p.accept("=") {
+		s := &numForStmt{line: line}
+		s.start = p.expr()
+		p.expect(",")
+		s.limit = p.expr()
+		if p.accept(",") {
+			s.step = p.expr()
+		}
+		p.expect("do")
+		p.fs.openScope()
+		s.slot = p.fs.declare(first)
+		p.fs.loopDepth++
+		s.body = p.scopedBlock()
+		p.fs.loopDepth--
+		p.fs.closeScope()
+		p.expectMatch("end", "for", line)
+		return s
+	}
+
+	names := []string{first}
+	for p.accept(",") {
+		names = append(names, p.name())
+	}
+	if !p.accept("in") {
+		p.errorExpected("=' or 'in")
+	}
+	s := &genForStmt{line: line}
+	s.exprs = p.exprList()
+	p.expect("do")
+	p.fs.openScope()
+	for _, n := range names {
+		s.slots = append(s.slots, p.fs.declare(n))
+	}
+	p.fs.loopDepth++
+	s.body = p.scopedBlock()
+	p.fs.loopDepth--
+	p.fs.closeScope()
+	p.expectMatch("end", "for", line)
+	return s
+}
+
+// funcStat 解析 function a.b.c:m() ... end，等价于对目标赋值一个函数。
+func (p *parser) funcStat(line int) stmt {
+	name := p.name()
+	target := p.fs.resolve(name)
+	fullName := name
+	isMethod := false
+	for p.is(".") || p.is(":") {
+		isMethod = p.is(":")
+		p.advance()
+		key := p.name()
+		fullName += "." + key
+		target = &indexExpr{obj: target, key: &constExpr{v: key}}
+		if isMethod {
+			break
+		}
+	}
+	fn := p.funcBody(fullName, line, isMethod)
+	return &assignStmt{targets: []expr{target}, exprs: []expr{fn}, line: line}
+}
+
+// funcBody 解析参数列表与函数体（当前 token 为 "("）。
+func (p *parser) funcBody(name string, line int, isMethod bool) *functionExpr {
+	parent := p.fs
+	fs := &funcState{parent: parent, proto: &funcProto{chunk: parent.proto.chunk, name: name, line: line}}
+	p.fs = fs
+	fs.openScope()
+	if isMethod {
+		fs.proto.params = append(fs.proto.params, fs.declare("self"))
+	}
+	p.expect("(")
+	if !p.is(")") {
+		for {
+			if p.accept("...") {
+				fs.proto.isVararg = true
+				break
+			}
+			fs.proto.params = append(fs.proto.params, fs.declare(p.name()))
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	p.expect(")")
+	fs.proto.body = p.block()
+	p.expectMatch("end", "function", line)
+	p.fs = parent
+	return &functionExpr{proto: fs.proto}
+}
+
+func (p *parser) exprStat(line int) stmt {
+	e := p.suffixedExpr()
+	if p.is("=") || p.is(",") {
+		targets := []expr{e}
+		for p.accept(",") {
+			targets = append(targets, p.suffixedExpr())
+		}
+		p.expect("=")
+		for _, t := range targets {
+			switch t.(type) {
+			case *localExpr, *upvalExpr, *globalExpr, *indexExpr:
+			default:
+				p.errorf("syntax error")
+			}
+		}
+		return &assignStmt{targets: targets, exprs: p.exprList(), line: line}
+	}
+	switch e.(type) {
+	case *callExpr, *methodCallExpr:
+		return &callStmt{call: e, line: line}
+	}
+	p.errorf("syntax error")
+	return nil
+}
+
+func (p *parser) exprList() []expr {
+	list := []expr{p.expr()}
+	for p.accept(",") {
+		list = append(list, p.expr())
+	}
+	return list
+}
+
+func (p *parser) primaryExpr() expr {
+	switch {
+	case p.tok.kind == tokName:
+		return p.fs.resolve(p.name())
+	case p.is("("):
+		line := p.tok.line
+		p.advance()
+		e := p.expr()
+		p.expectMatch(")", "(", line)
+		switch e.(type) {
+		case *callExpr, *methodCallExpr, *varargExpr:
+			return &parenExpr{e: e}
+		}
+		return e
+	}
+	p.errorf("unexpected symbol")
+	return nil
+}
+
+func (p *parser) suffixedExpr() expr {
+	e := p.primaryExpr()
+	for {
+		switch {
+		case p.is("."):
+			p.advance()
+			e = &indexExpr{obj: e, key: &constExpr{v: p.name()}}
+		case p.is("["):
+			p.advance()
+			key := p.expr()
+			p.expect("]")
+			e = &indexExpr{obj: e, key: key}
+		case p.is(":"):
+			p.advance()
+			name := p.name()
+			line := p.tok.line
+			e = &methodCallExpr{obj: e, name: name, args: p.callArgs(), line: line}
+		case p.is("(") || p.is("{") || p.tok.kind == tokString:
+			line := p.tok.line
+			e = &callExpr{fn: e, args: p.callArgs(), line: line}
+		default:
+			return e
+		}
+	}
+}
+
+func (p *parser) callArgs() []expr {
+	switch {
+	case p.tok.kind == tokString:
+		s := p.tok.s
+		p.advance()
+		return []expr{&constExpr{v: s}}
+	case p.is("{"):
+		return []expr{p.tableConstructor()}
+	case p.is("("):
+		line := p.tok.line
+		p.advance()
+		if p.accept(")") {
+			return nil
+		}
+		args := p.exprList()
+		p.expectMatch(")", "(", line)
+		return args
+	}
+	p.errorf("function arguments expected")
+	return nil
+}
+
+func (p *parser) tableConstructor() expr {
+	line := p.tok.line
+	p.expect("{")
+	t := &tableExpr{}
+	for !p.is("}") {
+		switch {
+		case p.is("["):
+			p.advance()
+			key := p.expr()
+			p.expect("]")
+			p.expect("=")
+			t.fields = append(t.fields, tableField{key: key, val: p.expr()})
+		case p.tok.kind == tokName && p.lookahead().kind == tokSymbol && p.lookahead().s == "=":
+			key := p.name()
+			p.advance() // =
+			t.fields = append(t.fields, tableField{key: &constExpr{v: key}, val: p.expr()})
+		default:
+			t.fields = append(t.fields, tableField{val: p.expr()})
+		}
+		if !p.accept(",") && !p.accept(";") {
+			break
+		}
+	}
+	p.expectMatch("}", "{", line)
+	return t
+}
+
+func (p *parser) simpleExpr() expr {
+	switch {
+	case p.tok.kind == tokNumber:
+		n := p.tok.n
+		p.advance()
+		return &constExpr{v: n}
+	case p.tok.kind == tokString:
+		s := p.tok.s
+		p.advance()
+		return &constExpr{v: s}
+	case p.accept("nil"):
+		return &constExpr{v: nil}
+	case p.accept("true"):
+		return &constExpr{v: true}
+	case p.accept("false"):
+		return &constExpr{v: false}
+	case p.is("..."):
+		if !p.fs.proto.isVararg {
+			p.errorf("cannot use '...' outside a vararg function")
+		}
+		p.advance()
+		return &varargExpr{}
+	case p.is("{"):
+		return p.tableConstructor()
+	case p.is("function"):
+		line := p.tok.line
+		p.advance()
+		return p.funcBody("anonymous", line, false)
+	}
+	return p.suffixedExpr()
+}
+
+// 二元运算符优先级（左, 右）：右优先级低于左优先级表示右结合。
+var binaryPriority = map[string][2]int{
+	"or": {1, 1}, "and": {2, 2},
+	"<": {3, 3}, ">": {3, 3}, "<=": {3, 3}, ">=": {3, 3}, "~=": {3, 3}, "==": {3, 3},
+	"..": {5, 4},
+	"+":  {6, 6}, "-": {6, 6},
+	"*": {7, 7}, "/": {7, 7}, "%": {7, 7},
+	"^": {10, 9},
+}
+
+const unaryPriority = 8
+
+func (p *parser) binaryOp() (string, bool) {
+	if p.tok.kind != tokSymbol && p.tok.kind != tokKeyword {
+		return "", false
+	}
+	if _, ok := binaryPriority[p.tok.s]; ok {
+		return p.tok.s, true
+	}
+	return "", false
+}
+
+func (p *parser) expr() expr {
+	return p.subExpr(0)
+}
+
+// subExpr 解析优先级高于 limit 的表达式（优先级爬升）。
+func (p *parser) subExpr(limit int) expr {
+	var e expr
+	if p.is("not") || p.is("-") || p.is("#") {
+		op := p.tok.s
+		p.advance()
+		operand := p.subExpr(unaryPriority)
+		// 常量折叠：让 -1 这样的字面量保持为常量
+		if c, ok := operand.(*constExpr); ok && op == "-" {
+			if n, ok := c.v.(float64); ok {
+				e = &constExpr{v: -n}
+			}
+		}
+		if e == nil {
+			e = &unExpr{op: op, e: operand}
+		}
+	} else {
+		e = p.simpleExpr()
+	}
+	for {
+		op, ok := p.binaryOp()
+		if !ok || binaryPriority[op][0] <= limit {
+			return e
+		}
+		p.advance()
+		r := p.subExpr(binaryPriority[op][1])
+		switch op {
+		case "and":
+			e = &andExpr{l: e, r: r}
+		case "or":
+			e = &orExpr{l: e, r: r}
+		default:
+			e = &binExpr{op: op, l: e, r: r}
+		}
+	}
+}
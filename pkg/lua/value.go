@@ -0,0 +1,288 @@
+// Lua 值与表：nil/boolean/number/string/table/function 在 Go 中的表示。
+// 说明：Value 直接使用 Go 的 nil、bool、float64、string、*Table、*Closure、*GoFunction，可以用 == 做 Lua 的原始相等比较。
+// 关键点：Table 分数组部分与哈希部分；哈希部分保持插入顺序，保证 next/pairs 的遍历顺序稳定、遍历中删除元素安全。
+package lua
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// 本文件实现值类型与 Table：
+// - 数组部分保存 1..n 的连续整数键（n 之后的键在追加时从哈希部分迁移过来）
+// - 哈希部分删除只置空值（墓碑），插入新键时按需压缩，因此遍历过程中给已有键赋 nil 不影响 next
+// - 不支持元表（setmetatable 等）
+
+// Value 为任意 Lua 值。
+type Value interface{}
+
+// GoFunction 为用 Go 实现的 Lua 函数。
+type GoFunction struct {
+	Name string
+	Fn   func(s *State, args []Value) []Value
+}
+
+// Closure 为 Lua 函数（函数原型 + 捕获的上值）。
+type Closure struct {
+	proto  *funcProto
+	upvals []*cell
+}
+
+type cell struct {
+	v Value
+}
+
+// Table 为 Lua 表。
+type Table struct {
+	arr   []Value
+	keys  []Value
+	vals  []Value
+	index map[Value]int
+	dead  int // 哈希部分中值为 nil 的墓碑数
+
+	readonly bool // 被冻结的库表（只限制脚本内赋值，宿主仍可直接 Set）
+}
+
+// NewTable 创建预分配了 narr 个数组项的表。
+func NewTable(narr int) *Table {
+	return &Table{arr: make([]Value, 0, narr)}
+}
+
+// arrayIndex 判断 k 是否为正整数键，返回其值。
+func arrayIndex(k Value) (int, bool) {
+	n, ok := k.(float64)
+	if !ok || n < 1 || n > math.MaxInt32 {
+		return 0, false
+	}
+	i := int(n)
+	return i, float64(i) == n
+}
+
+// Get 读取 t[k]（不存在返回 nil）。
+func (t *Table) Get(k Value) Value {
+	if i, ok := arrayIndex(k); ok && i <= len(t.arr) {
+		return t.arr[i-1]
+	}
+	if t.index == nil {
+		return nil
+	}
+	if pos, ok := t.index[k]; ok {
+		return t.vals[pos]
+	}
+	return nil
+}
+
+// Set 写入 t[k] = v；k 不能为 nil 或 NaN（由调用方检查）。
+func (t *Table) Set(k, v Value) {
+	if i, ok := arrayIndex(k); ok {
+		if i <= len(t.arr) {
+			t.arr[i-1] = v
+			if v == nil && i == len(t.arr) {
+				t.trimArray()
+			}
+			return
+		}
+		if i == len(t.arr)+1 && v != nil {
+			t.setHash(k, nil)
+			t.arr = append(t.arr, v)
+			t.migrate()
+			return
+		}
+	}
+	t.setHash(k, v)
+}
+
+// Append 在数组部分末尾追加 v（等价于 t[#t+1] = v）。
+func (t *Table) Append(v Value) {
+	t.Set(float64(len(t.arr)+1), v)
+}
+
+// Len 返回 #t（数组部分长度，末尾的 nil 会被裁掉，因此总是一个合法的边界）。
+func (t *Table) Len() int {
+	return len(t.arr)
+}
+
+func (t *Table) trimArray() {
+	n := len(t.arr)
+	for n > 0 && t.arr[n-1] == nil {
+		n--
+	}
+	t.arr = t.arr[:n]
+}
+
+// migrate 把哈希部分中紧接数组末尾的整数键搬到数组部分。
+func (t *Table) migrate() {
+	for t.index != nil {
+		k := float64(len(t.arr) + 1)
+		pos, ok := t.index[k]
+		if !ok || t.vals[pos] == nil {
+			return
+		}
+		t.arr = append(t.arr, t.vals[pos])
+		t.vals[pos] = nil
+		t.dead++
+	}
+}
+
+func (t *Table) setHash(k, v Value) {
+	if t.index == nil {
+		if v == nil {
+			return
+		}
+		t.index = make(map[Value]int)
+	}
+	if pos, ok := t.index[k]; ok {
+		switch {
+		case t.vals[pos] == nil && v != nil:
+			t.dead--
+		case t.vals[pos] != nil && v == nil:
+			t.dead++
+		}
+		t.vals[pos] = v
+		return
+	}
+	if v == nil {
+		return
+	}
+	if t.dead > 8 && t.dead > len(t.keys)/2 {
+		t.compact()
+	}
+	t.index[k] = len(t.keys)
+	t.keys = append(t.keys, k)
+	t.vals = append(t.vals, v)
+}
+
+// compact 清除哈希部分的墓碑（只在插入新键时调用；Lua 规定遍历期间插入新键的行为未定义）。
+func (t *Table) compact() {
+	keys := t.keys[:0]
+	vals := t.vals[:0]
+	for i, k := range t.keys {
+		if t.vals[i] == nil {
+			delete(t.index, k)
+			continue
+		}
+		t.index[k] = len(keys)
+		keys = append(keys, k)
+		vals = append(vals, t.vals[i])
+	}
+	for i := len(keys); i < len(t.keys); i++ {
+		t.keys[i], t.vals[i] = nil, nil
+	}
+	t.keys, t.vals, t.dead = keys, vals, 0
+}
+
+// Next 返回 k 之后的下一个键值对（k 为 nil 表示从头开始）；ok=false 表示 k 不是表中的键。
+func (t *Table) Next(k Value) (Value, Value, bool) {
+	start := 0 // 哈希部分的起始位置
+	if k != nil {
+		i, isIdx := arrayIndex(k)
+		pos, inHash := t.index[k]
+		switch {
+		case isIdx && i <= len(t.arr):
+			for j := i; j < len(t.arr); j++ {
+				if t.arr[j] != nil {
+					return float64(j + 1), t.arr[j], true
+				}
+			}
+		case inHash:
+			start = pos + 1
+		case isIdx:
+			// 遍历中把数组末尾置 nil 会收缩数组部分：视为数组部分已遍历完
+		default:
+			return nil, nil, false
+		}
+	} else {
+		for j, v := range t.arr {
+			if v != nil {
+				return float64(j + 1), v, true
+			}
+		}
+	}
+	for j := start; j < len(t.keys); j++ {
+		if t.vals[j] != nil {
+			return t.keys[j], t.vals[j], true
+		}
+	}
+	return nil, nil, true
+}
+
+// TypeName 返回 type(v) 的结果。
+func TypeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *Table:
+		return "table"
+	case *Closure, *GoFunction:
+		return "function"
+	}
+	return "userdata"
+}
+
+// Truthy 按 Lua 规则判断真假（只有 nil 与 false 为假）。
+func Truthy(v Value) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	}
+	return true
+}
+
+// FormatNumber 按 Lua 5.1 的 "%.14g" 规则格式化数字。
+func FormatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	case n == math.Trunc(n) && math.Abs(n) < 1e15:
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'g', 14, 64)
+}
+
+// ToString 返回 tostring(v) 的结果。
+func ToString(v Value) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return FormatNumber(x)
+	case string:
+		return x
+	case *Table:
+		return fmt.Sprintf("table: %p", x)
+	case *Closure:
+		return fmt.Sprintf("function: %p", x)
+	case *GoFunction:
+		return fmt.Sprintf("function: builtin: %p", x)
+	}
+	return fmt.Sprintf("userdata: %v", v)
+}
+
+// ToNumber 把数字或数字字符串转换为数字（算术运算的隐式转换规则）。
+func ToNumber(v Value) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		return parseNumber(x)
+	}
+	return 0, false
+}
@@ -0,0 +1,381 @@
+// pubsub 包：发布订阅中心（频道订阅 + glob 模式订阅）与订阅者的异步推送。
+// 说明：每个订阅者有独立的发送队列与写协程，PUBLISH 只负责把消息放入队列，不会被慢速客户端阻塞。
+// 关键点：队列积压超过输出缓冲上限（硬上限，或持续超过软上限一段时间）时直接断开该订阅者，与 Redis client-output-buffer-limit pubsub 一致。
+package pubsub
+
+import (
+	"io"
+	"myredis/pkg/glob"
+	"myredis/resp"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 本包实现：
+// - Hub：channel -> 订阅者集合、pattern -> 订阅者集合；Publish 返回接收者数量（频道订阅数 + 匹配的模式订阅数）
+// - Subscriber：一个连接在订阅模式下的发送端。连接进入订阅模式后，命令回复也经由 Subscriber.Write 发送，保证与推送消息的顺序
+// - 确认与消息按订阅者的协议版本编码：RESP2 为数组，RESP3（HELLO 3）为 push 类型
+//
+// 并发：Hub 的订阅关系由 Hub.mu 保护；Subscriber 的发送队列由 Subscriber.mu 保护，写协程批量取出后在锁外写连接。
+
+// Limits 为订阅者的输出缓冲上限（按待发送字节数计算）。
+type Limits struct {
+	// HardBytes 为硬上限：待发送字节超过该值立即断开；0 表示不限制。
+	HardBytes int
+	// SoftBytes/SoftDuration 为软上限：待发送字节持续超过 SoftBytes 达到 SoftDuration 后断开；SoftBytes 为 0 表示不限制。
+	SoftBytes    int
+	SoftDuration time.Duration
+}
+
+// DefaultLimits 与 Redis 默认的 client-output-buffer-limit pubsub 32mb 8mb 60 一致。
+var DefaultLimits = Limits{HardBytes: 32 << 20, SoftBytes: 8 << 20, SoftDuration: 60 * time.Second}
+
+// Hub 为发布订阅中心。
+type Hub struct {
+	limits Limits
+
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+}
+
+// NewHub 创建发布订阅中心。
+func NewHub(limits Limits) *Hub {
+	return &Hub{
+		limits:   limits,
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscriber 为一个订阅连接的发送端。
+type Subscriber struct {
+	hub  *Hub
+	conn io.WriteCloser
+
+	// channels/patterns 由 hub.mu 保护
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	// protocol 为连接协商的 RESP 版本，决定确认与消息的编码
+	protocol atomic.Int32
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     [][]byte
+	pending   int       // 队列中待发送的字节数
+	softSince time.Time // 开始超过软上限的时间（未超过时为零值）
+	closed    bool
+	done      chan struct{}
+}
+
+// NewSubscriber 为连接创建发送端并启动写协程；conn 在输出缓冲超限时会被关闭。
+func (h *Hub) NewSubscriber(conn io.WriteCloser) *Subscriber {
+	s := &Subscriber{
+		hub:      h,
+		conn:     conn,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+	s.protocol.Store(resp.RESP2)
+	s.cond = sync.NewCond(&s.mu)
+	go s.writeLoop()
+	return s
+}
+
+// SetProtocol 设置订阅者的 RESP 版本（连接执行 HELLO 时调用），影响之后入队的确认与消息。
+func (s *Subscriber) SetProtocol(protocol int) {
+	s.protocol.Store(int32(protocol))
+}
+
+// Protocol 返回订阅者的 RESP 版本。
+func (s *Subscriber) Protocol() int {
+	return int(s.protocol.Load())
+}
+
+// Write 把 b 放入发送队列；返回 false 表示订阅者已关闭（或因超限被断开）。
+func (s *Subscriber) Write(b []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.queue = append(s.queue, b)
+	s.pending += len(b)
+	if s.overLimit(time.Now()) {
+		s.dropLocked()
+		return false
+	}
+	s.cond.Signal()
+	return true
+}
+
+// overLimit 判断输出缓冲是否超限（调用方持有 s.mu）。
+func (s *Subscriber) overLimit(now time.Time) bool {
+	l := s.hub.limits
+	if l.HardBytes > 0 && s.pending > l.HardBytes {
+		return true
+	}
+	if l.SoftBytes <= 0 || s.pending <= l.SoftBytes {
+		s.softSince = time.Time{}
+		return false
+	}
+	if s.softSince.IsZero() {
+		s.softSince = now
+		return false
+	}
+	return now.Sub(s.softSince) >= l.SoftDuration
+}
+
+// dropLocked 断开慢速订阅者：丢弃队列并关闭连接，连接的读协程随之退出并清理订阅（调用方持有 s.mu）。
+func (s *Subscriber) dropLocked() {
+	s.closed = true
+	s.queue = nil
+	s.pending = 0
+	_ = s.conn.Close()
+	s.cond.Signal()
+}
+
+// closeWriteTimeout 为 Close 发送剩余队列的时限：客户端不再读取时写连接会一直阻塞，超时后放弃剩余数据。
+var closeWriteTimeout = 5 * time.Second
+
+// Close 停止接收新数据，等待写协程发送完已排队的数据后退出（不关闭连接）。
+// 连接支持写超时（net.Conn）时最多等待 closeWriteTimeout，之后连接不应再写入。
+func (s *Subscriber) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Signal()
+	s.mu.Unlock()
+	if dc, ok := s.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		_ = dc.SetWriteDeadline(time.Now().Add(closeWriteTimeout))
+	}
+	<-s.done
+}
+
+func (s *Subscriber) writeLoop() {
+	defer close(s.done)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		batch := net.Buffers(s.queue)
+		n := s.pending
+		s.queue = nil
+		s.mu.Unlock()
+
+		// 批量写出（net.Conn 上为 writev）；写失败说明连接已断开，读协程会负责清理
+		if _, err := batch.WriteTo(s.conn); err != nil {
+			s.mu.Lock()
+			s.dropLocked()
+			s.mu.Unlock()
+			return
+		}
+
+		s.mu.Lock()
+		s.pending -= n
+		if s.pending < 0 {
+			s.pending = 0 // 写出期间被 dropLocked 清零
+		}
+		s.overLimit(time.Now())
+		s.mu.Unlock()
+	}
+}
+
+// Count 返回订阅者当前的订阅数（频道 + 模式）。
+func (h *Hub) Count(s *Subscriber) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(s.channels) + len(s.patterns)
+}
+
+// Subscribe 订阅频道，并为每个频道向订阅者发送 subscribe 确认。
+// 确认在持有 h.mu 时入队，保证它先于该频道的任何消息到达客户端。
+func (h *Hub) Subscribe(s *Subscriber, channels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range channels {
+		add(h.channels, s.channels, ch, s)
+		s.Write(confirm(s, "subscribe", []byte(ch)))
+	}
+}
+
+// Unsubscribe 退订频道（channels 为空表示退订全部频道），并为每个频道发送 unsubscribe 确认。
+func (h *Hub) Unsubscribe(s *Subscriber, channels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(s, h.channels, s.channels, "unsubscribe", channels)
+}
+
+// PSubscribe 订阅模式，并为每个模式发送 psubscribe 确认。
+func (h *Hub) PSubscribe(s *Subscriber, patterns ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, p := range patterns {
+		add(h.patterns, s.patterns, p, s)
+		s.Write(confirm(s, "psubscribe", []byte(p)))
+	}
+}
+
+// PUnsubscribe 退订模式（patterns 为空表示退订全部模式），并为每个模式发送 punsubscribe 确认。
+func (h *Hub) PUnsubscribe(s *Subscriber, patterns ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unsubscribe(s, h.patterns, s.patterns, "punsubscribe", patterns)
+}
+
+// unsubscribe 为 Unsubscribe/PUnsubscribe 的公共实现（调用方持有 h.mu）。
+// 没有任何订阅时退订全部，与 Redis 一致回复一条名称为 nil、计数为 0 的确认。
+func (h *Hub) unsubscribe(s *Subscriber, index map[string]map[*Subscriber]struct{}, own map[string]struct{}, kind string, names []string) {
+	if len(names) == 0 {
+		names = sortedKeys(own)
+		if len(names) == 0 {
+			s.Write(confirm(s, kind, nil))
+			return
+		}
+	}
+	for _, name := range names {
+		remove(index, own, name, s)
+		s.Write(confirm(s, kind, []byte(name)))
+	}
+}
+
+// confirm 生成订阅/退订确认：[kind, name, count]，count 为 s 当前的订阅数（调用方持有 h.mu）。
+// name 为 nil 时编码为 nil bulk（RESP3 下为 null）。
+func confirm(s *Subscriber, kind string, name []byte) []byte {
+	return resp.Encode(resp.MakePushReply([]resp.Reply{
+		resp.MakeBulkReply([]byte(kind)),
+		resp.MakeBulkReply(name),
+		resp.MakeIntReply(int64(len(s.channels) + len(s.patterns))),
+	}), s.Protocol())
+}
+
+// pushMessage 为一条待推送的消息，按协议版本缓存编码结果，同一条消息对每种协议只编码一次。
+type pushMessage struct {
+	reply   resp.Reply
+	encoded map[int][]byte
+}
+
+func newPushMessage(args ...[]byte) *pushMessage {
+	replies := make([]resp.Reply, 0, len(args))
+	for _, a := range args {
+		replies = append(replies, resp.MakeBulkReply(a))
+	}
+	return &pushMessage{reply: resp.MakePushReply(replies), encoded: make(map[int][]byte, 1)}
+}
+
+func (m *pushMessage) bytes(protocol int) []byte {
+	b, ok := m.encoded[protocol]
+	if !ok {
+		b = resp.Encode(m.reply, protocol)
+		m.encoded[protocol] = b
+	}
+	return b
+}
+
+// RemoveSubscriber 移除订阅者的全部订阅（连接断开或 RESET 时调用，不发送退订确认）。
+func (h *Hub) RemoveSubscriber(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range s.channels {
+		remove(h.channels, s.channels, ch, s)
+	}
+	for p := range s.patterns {
+		remove(h.patterns, s.patterns, p, s)
+	}
+}
+
+func add(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string, s *Subscriber) {
+	own[name] = struct{}{}
+	subs, ok := index[name]
+	if !ok {
+		subs = make(map[*Subscriber]struct{})
+		index[name] = subs
+	}
+	subs[s] = struct{}{}
+}
+
+func remove(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string, s *Subscriber) {
+	delete(own, name)
+	subs, ok := index[name]
+	if !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(index, name)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Publish 向频道发布消息，返回接收者数量（被断开的慢速订阅者不计入）。
+func (h *Hub) Publish(channel string, message []byte) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	receivers := 0
+	if subs := h.channels[channel]; len(subs) > 0 {
+		msg := newPushMessage([]byte("message"), []byte(channel), message)
+		for s := range subs {
+			if s.Write(msg.bytes(s.Protocol())) {
+				receivers++
+			}
+		}
+	}
+	for pattern, subs := range h.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		msg := newPushMessage([]byte("pmessage"), []byte(pattern), []byte(channel), message)
+		for s := range subs {
+			if s.Write(msg.bytes(s.Protocol())) {
+				receivers++
+			}
+		}
+	}
+	return receivers
+}
+
+// Channels 返回至少有一个订阅者、且匹配 pattern 的频道。
+func (h *Hub) Channels(pattern string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]string, 0, len(h.channels))
+	for ch := range h.channels {
+		if glob.Match(pattern, ch) {
+			out = append(out, ch)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NumSub 返回频道的订阅者数量（不含模式订阅）。
+func (h *Hub) NumSub(channel string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.channels[channel])
+}
+
+// NumPat 返回被订阅的模式数量（与 Redis 一致，按不同模式计数）。
+func (h *Hub) NumPat() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.patterns)
+}
@@ -0,0 +1,209 @@
+// 发布订阅中心测试：订阅确认、消息投递与计数、查询接口以及慢速订阅者断开。
+// 覆盖：频道与模式同时匹配、无订阅时的退订确认、硬上限与软上限、客户端不读取时 Close 不会一直阻塞。
+package pubsub
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bufConn 记录写入的数据。
+type bufConn struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (c *bufConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(b)
+}
+
+func (c *bufConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *bufConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// stuckConn 模拟从不读取的客户端：Write 阻塞直到连接被关闭。
+type stuckConn struct {
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newStuckConn() *stuckConn { return &stuckConn{closed: make(chan struct{})} }
+
+func (c *stuckConn) Write(b []byte) (int, error) {
+	<-c.closed
+	return 0, bytes.ErrTooLarge
+}
+
+func (c *stuckConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *stuckConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestHub_SubscribePublish(t *testing.T) {
+	h := NewHub(DefaultLimits)
+	conn := &bufConn{}
+	s := h.NewSubscriber(conn)
+
+	h.Subscribe(s, "news.1")
+	h.PSubscribe(s, "news.*")
+	if got := h.Count(s); got != 2 {
+		t.Fatalf("Count = %d, want 2", got)
+	}
+	if got := h.Publish("news.1", []byte("hi")); got != 2 {
+		t.Fatalf("Publish(news.1) = %d, want 2", got)
+	}
+	if got := h.Publish("news.2", []byte("yo")); got != 1 {
+		t.Fatalf("Publish(news.2) = %d, want 1", got)
+	}
+	if got := h.Publish("other", []byte("x")); got != 0 {
+		t.Fatalf("Publish(other) = %d, want 0", got)
+	}
+	h.Unsubscribe(s)
+	h.PUnsubscribe(s)
+	h.Unsubscribe(s)
+	s.Close()
+
+	want := "*3\r\n$9\r\nsubscribe\r\n$6\r\nnews.1\r\n:1\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:2\r\n" +
+		"*3\r\n$7\r\nmessage\r\n$6\r\nnews.1\r\n$2\r\nhi\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$6\r\nnews.1\r\n$2\r\nhi\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$6\r\nnews.2\r\n$2\r\nyo\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$6\r\nnews.1\r\n:1\r\n" +
+		"*3\r\n$12\r\npunsubscribe\r\n$6\r\nnews.*\r\n:0\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n"
+	if got := conn.buf.String(); got != want {
+		t.Fatalf("output mismatch:\ngot  %q\nwant %q", got, want)
+	}
+	if conn.isClosed() {
+		t.Fatal("Close should not close the connection")
+	}
+	if s.Write([]byte("late")) {
+		t.Fatal("Write after Close should fail")
+	}
+}
+
+func TestHub_Introspection(t *testing.T) {
+	h := NewHub(DefaultLimits)
+	a, b := h.NewSubscriber(&bufConn{}), h.NewSubscriber(&bufConn{})
+	defer a.Close()
+	defer b.Close()
+
+	h.Subscribe(a, "c1", "c2")
+	h.Subscribe(b, "c2", "d1")
+	h.PSubscribe(a, "c*")
+	h.PSubscribe(b, "c*", "d*")
+
+	if got := h.Channels("*"); !reflect.DeepEqual(got, []string{"c1", "c2", "d1"}) {
+		t.Fatalf("Channels(*) = %v", got)
+	}
+	if got := h.Channels("c?"); !reflect.DeepEqual(got, []string{"c1", "c2"}) {
+		t.Fatalf("Channels(c?) = %v", got)
+	}
+	if h.NumSub("c2") != 2 || h.NumSub("c1") != 1 || h.NumSub("none") != 0 {
+		t.Fatal("NumSub mismatch")
+	}
+	if got := h.NumPat(); got != 2 {
+		t.Fatalf("NumPat = %d, want 2", got)
+	}
+
+	h.RemoveSubscriber(b)
+	if got := h.Channels("*"); !reflect.DeepEqual(got, []string{"c1", "c2"}) {
+		t.Fatalf("Channels after remove = %v", got)
+	}
+	if h.NumSub("c2") != 1 || h.NumPat() != 1 || h.Count(b) != 0 {
+		t.Fatal("RemoveSubscriber should drop all subscriptions of b")
+	}
+}
+
+func TestSubscriber_HardLimit(t *testing.T) {
+	h := NewHub(Limits{HardBytes: 1024})
+	conn := newStuckConn()
+	s := h.NewSubscriber(conn)
+	h.Subscribe(s, "ch")
+
+	msg := bytes.Repeat([]byte("x"), 100)
+	delivered := 0
+	for i := 0; i < 50; i++ {
+		delivered += h.Publish("ch", msg)
+	}
+	if delivered == 0 || delivered == 50 {
+		t.Fatalf("delivered = %d, expected the subscriber to be dropped part way", delivered)
+	}
+	if !conn.isClosed() {
+		t.Fatal("connection should be closed after exceeding the hard limit")
+	}
+	if h.Publish("ch", msg) != 0 {
+		t.Fatal("dropped subscriber should not be counted as a receiver")
+	}
+	s.Close()
+}
+
+func TestSubscriber_SoftLimit(t *testing.T) {
+	h := NewHub(Limits{SoftBytes: 100, SoftDuration: 50 * time.Millisecond})
+	conn := newStuckConn()
+	s := h.NewSubscriber(conn)
+	h.Subscribe(s, "ch")
+
+	msg := bytes.Repeat([]byte("x"), 200)
+	if h.Publish("ch", msg) != 1 || h.Publish("ch", msg) != 1 {
+		t.Fatal("soft limit should tolerate a short burst")
+	}
+	if conn.isClosed() {
+		t.Fatal("connection closed before the soft limit duration elapsed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if h.Publish("ch", msg) != 0 {
+		t.Fatal("subscriber over the soft limit for too long should be dropped")
+	}
+	if !conn.isClosed() {
+		t.Fatal("connection should be closed after exceeding the soft limit")
+	}
+	s.Close()
+}
+
+func TestSubscriber_CloseWithStuckReader(t *testing.T) {
+	defer func(d time.Duration) { closeWriteTimeout = d }(closeWriteTimeout)
+	closeWriteTimeout = 50 * time.Millisecond
+
+	// net.Pipe 没有缓冲：对端不读取时写入一直阻塞，只有写超时能让 Close 返回
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	s := NewHub(DefaultLimits).NewSubscriber(conn)
+	s.Write([]byte("+queued\r\n"))
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close should give up draining the queue after the write deadline")
+	}
+}
@@ -6,7 +6,8 @@
 //
 // 注意：
 // - 这里不追求 100% 兼容 Redis 官方 RDB 格式（那会非常复杂且需要大量兼容测试）。
-// - 只覆盖当前项目支持的数据类型：String/List/Hash/Set，并携带绝对过期时间（UnixMilli）。
+// - 只覆盖当前项目支持的数据类型：String/List/Hash/Set/ZSet/Stream，并携带绝对过期时间（UnixMilli）。
+// - 模块自定义类型（TypeModule）以“类型名 + 模块自行编码的字节”保存，本包不解析其内容。
 package rdb
 
 import (
@@ -14,6 +15,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -33,8 +35,22 @@ const (
 	TypeList   EntryType = 2
 	TypeHash   EntryType = 3
 	TypeSet    EntryType = 4
+	TypeZSet   EntryType = 5
+
+	// typeHashTTL 只出现在文件中：带字段级过期时间的 Hash，每个字段额外写入绝对过期时间（0 表示不过期）。
+	// 加载后还原为 TypeHash + HashExpire，旧文件（不含字段 TTL）的格式不变。
+	typeHashTTL EntryType = 6
+
+	TypeStream EntryType = 7
+	TypeModule EntryType = 8
 )
 
+// ZSetMember 表示有序集合中的一个成员及其分值。
+type ZSetMember struct {
+	Member string
+	Score  float64
+}
+
 // Entry 表示快照中的一个键值条目。
 //
 // ExpireAtUnixMs：
@@ -49,7 +65,14 @@ type Entry struct {
 	String []byte
 	List   [][]byte
 	Hash   map[string][]byte
-	Set    []string
+	// HashExpire 为 Hash 字段的绝对过期时间（UnixMilli），只包含设置了 TTL 的字段。
+	HashExpire map[string]int64
+	Set        []string
+	ZSet       []ZSetMember // 按 (score, member) 升序
+	Stream     *Stream
+	// ModuleType/Module 为模块自定义类型的类型名与编码后的值（见 myredis/module）。
+	ModuleType string
+	Module     []byte
 }
 
 // Save 将 entries 写入 filename（使用 tmp 文件 + 原子替换）。
@@ -120,7 +143,11 @@ func SaveToWriter(w io.Writer, entries []Entry) error {
 	}
 
 	for _, e := range entries {
-		if err := writeUint8(w, uint8(e.Type)); err != nil {
+		typ := e.Type
+		if typ == TypeHash && len(e.HashExpire) > 0 {
+			typ = typeHashTTL
+		}
+		if err := writeUint8(w, uint8(typ)); err != nil {
 			return err
 		}
 		if err := writeString(w, e.Key); err != nil {
@@ -166,6 +193,11 @@ func SaveToWriter(w io.Writer, entries []Entry) error {
 				if err := writeBytes(w, e.Hash[field]); err != nil {
 					return err
 				}
+				if typ == typeHashTTL {
+					if err := writeInt64(w, e.HashExpire[field]); err != nil {
+						return err
+					}
+				}
 			}
 		case TypeSet:
 			members := append([]string(nil), e.Set...)
@@ -178,6 +210,29 @@ func SaveToWriter(w io.Writer, entries []Entry) error {
 					return err
 				}
 			}
+		case TypeZSet:
+			if err := writeUint32(w, uint32(len(e.ZSet))); err != nil {
+				return err
+			}
+			for _, zm := range e.ZSet {
+				if err := writeString(w, zm.Member); err != nil {
+					return err
+				}
+				if err := writeInt64(w, int64(math.Float64bits(zm.Score))); err != nil {
+					return err
+				}
+			}
+		case TypeStream:
+			if err := writeStream(w, e.Stream); err != nil {
+				return err
+			}
+		case TypeModule:
+			if err := writeString(w, e.ModuleType); err != nil {
+				return err
+			}
+			if err := writeBytes(w, e.Module); err != nil {
+				return err
+			}
 		default:
 			return errors.New("unknown entry type")
 		}
@@ -243,7 +298,9 @@ func LoadFromReader(r io.Reader) ([]Entry, error) {
 				}
 				e.List = append(e.List, b)
 			}
-		case TypeHash:
+		case TypeHash, typeHashTTL:
+			withTTL := e.Type == typeHashTTL
+			e.Type = TypeHash
 			cnt, err := readUint32(r)
 			if err != nil {
 				return nil, err
@@ -259,6 +316,19 @@ func LoadFromReader(r io.Reader) ([]Entry, error) {
 					return nil, err
 				}
 				e.Hash[field] = val
+				if !withTTL {
+					continue
+				}
+				at, err := readInt64(r)
+				if err != nil {
+					return nil, err
+				}
+				if at > 0 {
+					if e.HashExpire == nil {
+						e.HashExpire = make(map[string]int64)
+					}
+					e.HashExpire[field] = at
+				}
 			}
 		case TypeSet:
 			cnt, err := readUint32(r)
@@ -273,6 +343,34 @@ func LoadFromReader(r io.Reader) ([]Entry, error) {
 				}
 				e.Set = append(e.Set, m)
 			}
+		case TypeZSet:
+			cnt, err := readUint32(r)
+			if err != nil {
+				return nil, err
+			}
+			e.ZSet = make([]ZSetMember, 0, cnt)
+			for j := uint32(0); j < cnt; j++ {
+				m, err := readString(r)
+				if err != nil {
+					return nil, err
+				}
+				bits, err := readInt64(r)
+				if err != nil {
+					return nil, err
+				}
+				e.ZSet = append(e.ZSet, ZSetMember{Member: m, Score: math.Float64frombits(uint64(bits))})
+			}
+		case TypeStream:
+			if e.Stream, err = readStream(r); err != nil {
+				return nil, err
+			}
+		case TypeModule:
+			if e.ModuleType, err = readString(r); err != nil {
+				return nil, err
+			}
+			if e.Module, err = readBytes(r); err != nil {
+				return nil, err
+			}
 		default:
 			return nil, errors.New("unknown entry type")
 		}
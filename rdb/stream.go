@@ -0,0 +1,238 @@
+// Stream 的快照编码：条目、last-id 与消费者组（含 PEL 和消费者）。
+// 说明：ID 以两个 64 位整数保存；条目按 ID 升序写入，PEL 按 ID 升序写入，加载后无需重新排序。
+// 关键点：消费者组的投递时间/次数原样保存，重启后 XPENDING 的 idle 与 delivery count 保持连续。
+package rdb
+
+import (
+	"io"
+	"sort"
+)
+
+// StreamID 为 Stream 条目 ID（<Ms>-<Seq>）。
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// StreamEntry 为一条消息，Fields 为扁平的 field/value 序列。
+type StreamEntry struct {
+	ID     StreamID
+	Fields [][]byte
+}
+
+// StreamPending 为消费者组 PEL 中的一条记录。
+type StreamPending struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  int64 // UnixMilli
+	DeliveryCount int64
+}
+
+// StreamConsumer 为消费者组中的一个消费者。
+type StreamConsumer struct {
+	Name     string
+	SeenTime int64 // UnixMilli
+}
+
+// StreamGroup 为一个消费者组。
+type StreamGroup struct {
+	Name      string
+	LastID    StreamID
+	Pending   []StreamPending // 按 ID 升序
+	Consumers []StreamConsumer
+}
+
+// Stream 为 Stream 类型的完整快照。
+type Stream struct {
+	Entries      []StreamEntry // 按 ID 升序
+	LastID       StreamID
+	MaxDeletedID StreamID
+	EntriesAdded uint64
+	Groups       []StreamGroup
+}
+
+func writeStreamID(w io.Writer, id StreamID) error {
+	if err := writeInt64(w, int64(id.Ms)); err != nil {
+		return err
+	}
+	return writeInt64(w, int64(id.Seq))
+}
+
+func readStreamID(r io.Reader) (StreamID, error) {
+	ms, err := readInt64(r)
+	if err != nil {
+		return StreamID{}, err
+	}
+	seq, err := readInt64(r)
+	if err != nil {
+		return StreamID{}, err
+	}
+	return StreamID{Ms: uint64(ms), Seq: uint64(seq)}, nil
+}
+
+func writeStream(w io.Writer, s *Stream) error {
+	if s == nil {
+		s = &Stream{}
+	}
+	for _, id := range []StreamID{s.LastID, s.MaxDeletedID} {
+		if err := writeStreamID(w, id); err != nil {
+			return err
+		}
+	}
+	if err := writeInt64(w, int64(s.EntriesAdded)); err != nil {
+		return err
+	}
+
+	if err := writeUint32(w, uint32(len(s.Entries))); err != nil {
+		return err
+	}
+	for _, e := range s.Entries {
+		if err := writeStreamID(w, e.ID); err != nil {
+			return err
+		}
+		if err := writeUint32(w, uint32(len(e.Fields))); err != nil {
+			return err
+		}
+		for _, f := range e.Fields {
+			if err := writeBytes(w, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	groups := append([]StreamGroup(nil), s.Groups...)
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	if err := writeUint32(w, uint32(len(groups))); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := writeString(w, g.Name); err != nil {
+			return err
+		}
+		if err := writeStreamID(w, g.LastID); err != nil {
+			return err
+		}
+		if err := writeUint32(w, uint32(len(g.Pending))); err != nil {
+			return err
+		}
+		for _, p := range g.Pending {
+			if err := writeStreamID(w, p.ID); err != nil {
+				return err
+			}
+			if err := writeString(w, p.Consumer); err != nil {
+				return err
+			}
+			if err := writeInt64(w, p.DeliveryTime); err != nil {
+				return err
+			}
+			if err := writeInt64(w, p.DeliveryCount); err != nil {
+				return err
+			}
+		}
+		if err := writeUint32(w, uint32(len(g.Consumers))); err != nil {
+			return err
+		}
+		for _, c := range g.Consumers {
+			if err := writeString(w, c.Name); err != nil {
+				return err
+			}
+			if err := writeInt64(w, c.SeenTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readStream(r io.Reader) (*Stream, error) {
+	s := &Stream{}
+	var err error
+	if s.LastID, err = readStreamID(r); err != nil {
+		return nil, err
+	}
+	if s.MaxDeletedID, err = readStreamID(r); err != nil {
+		return nil, err
+	}
+	added, err := readInt64(r)
+	if err != nil {
+		return nil, err
+	}
+	s.EntriesAdded = uint64(added)
+
+	cnt, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	s.Entries = make([]StreamEntry, 0, cnt)
+	for i := uint32(0); i < cnt; i++ {
+		id, err := readStreamID(r)
+		if err != nil {
+			return nil, err
+		}
+		nf, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		fields := make([][]byte, 0, nf)
+		for j := uint32(0); j < nf; j++ {
+			b, err := readBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, b)
+		}
+		s.Entries = append(s.Entries, StreamEntry{ID: id, Fields: fields})
+	}
+
+	ng, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	s.Groups = make([]StreamGroup, 0, ng)
+	for i := uint32(0); i < ng; i++ {
+		var g StreamGroup
+		if g.Name, err = readString(r); err != nil {
+			return nil, err
+		}
+		if g.LastID, err = readStreamID(r); err != nil {
+			return nil, err
+		}
+		np, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		g.Pending = make([]StreamPending, 0, np)
+		for j := uint32(0); j < np; j++ {
+			var p StreamPending
+			if p.ID, err = readStreamID(r); err != nil {
+				return nil, err
+			}
+			if p.Consumer, err = readString(r); err != nil {
+				return nil, err
+			}
+			if p.DeliveryTime, err = readInt64(r); err != nil {
+				return nil, err
+			}
+			if p.DeliveryCount, err = readInt64(r); err != nil {
+				return nil, err
+			}
+			g.Pending = append(g.Pending, p)
+		}
+		nc, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		g.Consumers = make([]StreamConsumer, 0, nc)
+		for j := uint32(0); j < nc; j++ {
+			var c StreamConsumer
+			if c.Name, err = readString(r); err != nil {
+				return nil, err
+			}
+			if c.SeenTime, err = readInt64(r); err != nil {
+				return nil, err
+			}
+			g.Consumers = append(g.Consumers, c)
+		}
+		s.Groups = append(s.Groups, g)
+	}
+	return s, nil
+}
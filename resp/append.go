@@ -0,0 +1,81 @@
+// AppendReply：把 Reply 直接编码追加到调用方的缓冲区。
+// 说明：服务端配合 bufio.Writer.AvailableBuffer 使用，常用回复类型直接写入输出缓冲，不再为每个回复单独拼接 []byte。
+// 关键点：输出与 Encode 完全一致；未特化的类型回退到 Encode。
+package resp
+
+import (
+	"strconv"
+)
+
+// AppendReply 把 r 按协议版本编码后追加到 dst 并返回扩展后的切片。
+func AppendReply(dst []byte, r Reply, protocol int) []byte {
+	switch r := r.(type) {
+	case *StatusReply:
+		dst = append(dst, '+')
+		dst = append(dst, r.Status...)
+		return append(dst, CRLF...)
+	case *ErrorReply:
+		dst = append(dst, '-')
+		dst = append(dst, r.Status...)
+		return append(dst, CRLF...)
+	case *IntReply:
+		dst = append(dst, ':')
+		dst = strconv.AppendInt(dst, r.Code, 10)
+		return append(dst, CRLF...)
+	case *BulkReply:
+		if r.Double && r.Arg != nil && protocol == RESP3 {
+			dst = append(dst, ',')
+			dst = append(dst, r.Arg...)
+			return append(dst, CRLF...)
+		}
+		return appendBulk(dst, r.Arg, protocol)
+	case *MultiBulkReply:
+		if protocol == RESP3 && r.Kind != KindArray {
+			prefix, n := r.aggregateHeader()
+			dst = append(dst, prefix)
+			dst = strconv.AppendInt(dst, int64(n), 10)
+			dst = append(dst, CRLF...)
+			for _, arg := range r.Args {
+				dst = appendBulk(dst, arg, protocol)
+			}
+			return dst
+		}
+		if r.Args == nil {
+			if protocol == RESP3 {
+				return append(dst, nullBytes...)
+			}
+			return append(dst, "*-1"+CRLF...)
+		}
+		dst = append(dst, '*')
+		dst = strconv.AppendInt(dst, int64(len(r.Args)), 10)
+		dst = append(dst, CRLF...)
+		for _, arg := range r.Args {
+			dst = appendBulk(dst, arg, protocol)
+		}
+		return dst
+	case *MultiRawReply:
+		dst = append(dst, '*')
+		dst = strconv.AppendInt(dst, int64(len(r.Replies)), 10)
+		dst = append(dst, CRLF...)
+		for _, sub := range r.Replies {
+			dst = AppendReply(dst, sub, protocol)
+		}
+		return dst
+	}
+	return append(dst, Encode(r, protocol)...)
+}
+
+// appendBulk 追加一个 bulk string；nil 在 RESP2 下为 $-1，RESP3 下为 null。
+func appendBulk(dst []byte, arg []byte, protocol int) []byte {
+	if arg == nil {
+		if protocol == RESP3 {
+			return append(dst, nullBytes...)
+		}
+		return append(dst, "$-1"+CRLF...)
+	}
+	dst = append(dst, '$')
+	dst = strconv.AppendInt(dst, int64(len(arg)), 10)
+	dst = append(dst, CRLF...)
+	dst = append(dst, arg...)
+	return append(dst, CRLF...)
+}
@@ -0,0 +1,113 @@
+// 解析器模糊测试：任意输入都不能导致 panic、无界递归或超出限制的内存分配。
+// 运行：go test ./resp -run '^$' -fuzz FuzzParseStream（普通 go test 只执行种子用例）。
+// 覆盖：ParseStream 与 RequestReader（请求）、StreamParser（回复，含 RESP3）；成功解析的请求重新编码后应得到相同参数。
+package resp
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fuzzSeeds 为两个模糊测试共用的种子：正常请求、内联命令、RESP3 回复与各类畸形输入。
+var fuzzSeeds = []string{
+	"*1\r\n$4\r\nPING\r\n",
+	"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n",
+	"PING\r\n",
+	"SET k \"a\\x41 b\" 'c\\'d'\n",
+	"\r\n\n*0\r\n*-1\r\n",
+	"*2\r\n*1\r\n:1\r\n+OK\r\n",
+	"%1\r\n$1\r\nk\r\n,1.5\r\n~1\r\n$1\r\na\r\n>1\r\n_\r\n|1\r\n+a\r\n#t\r\n:1\r\n",
+	"=7\r\ntxt:abc\r\n(123\r\n",
+	"$5\r\nab\r\n",
+	"*1\r\n$-7\r\n",
+	"*99999999999\r\n",
+	"$4294967296\r\n",
+	"*1\r\n*1\r\n*1\r\n*1\r\n",
+	"\"unbalanced\n",
+	":abc\r\n",
+}
+
+// fuzzLimits 使用很小的限制，让模糊输入更容易触发各个边界。
+var fuzzLimits = Limits{MaxBulkLen: 1024, MaxMultiBulkLen: 64, MaxQueryBuffer: 4096}
+
+func FuzzParseStream(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for p := range ParseStreamWithLimits(bytes.NewReader(data), fuzzLimits) {
+			if p.Err != nil {
+				return
+			}
+			mb, ok := p.Data.(*MultiBulkReply)
+			if !ok || mb.Args == nil {
+				continue
+			}
+			if len(mb.Args) > int(fuzzLimits.MaxMultiBulkLen) {
+				t.Fatalf("array of %d elements exceeds limit", len(mb.Args))
+			}
+			// 请求重新编码为 RESP 后应解析出相同的参数
+			encoded := mb.ToBytes()
+			again, err := NewStreamParser(bytes.NewReader(encoded)).ReadReply()
+			if err != nil {
+				t.Fatalf("re-parse %q: %v", encoded, err)
+			}
+			if !bytes.Equal(again.ToBytes(), encoded) {
+				t.Fatalf("round trip mismatch: %q -> %q", encoded, again.ToBytes())
+			}
+		}
+	})
+}
+
+func FuzzRequestReader(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rr := NewRequestReader(bytes.NewReader(data), fuzzLimits)
+		for {
+			args, err := rr.ReadCommand()
+			if err == ErrExpectedArray {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			if len(args) > int(fuzzLimits.MaxMultiBulkLen) {
+				t.Fatalf("command of %d arguments exceeds limit", len(args))
+			}
+			// 重新编码后用新的读取器读出相同参数
+			encoded := MakeMultiBulkReply(CloneArgs(args)).ToBytes()
+			again, err := NewRequestReader(bytes.NewReader(encoded), Limits{}).ReadCommand()
+			if err != nil {
+				t.Fatalf("re-read %q: %v", encoded, err)
+			}
+			if len(again) != len(args) {
+				t.Fatalf("round trip of %q read %d arguments", encoded, len(again))
+			}
+			for i := range again {
+				if !bytes.Equal(again[i], args[i]) {
+					t.Fatalf("round trip mismatch at %d: %q -> %q", i, args[i], again[i])
+				}
+			}
+		}
+	})
+}
+
+func FuzzStreamParser(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p := NewStreamParser(bytes.NewReader(data))
+		for i := 0; i < 16; i++ {
+			r, err := p.ReadReply()
+			if err != nil {
+				return
+			}
+			// 解析出的回复在两种协议下都能编码
+			_ = Encode(r, RESP2)
+			_ = Encode(r, RESP3)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// 内联命令：telnet、`echo PING | nc` 与负载均衡健康检查发送的纯文本命令行。
+// 说明：首字节不是 RESP 类型前缀的顶层行按内联命令解析，按空白切分参数并支持引号，结果与等价的 RESP 数组请求相同。
+// 关键点：切分规则与 Redis 的 sdssplitargs 一致；行长度超过 MaxInlineSize 时报错，避免无换行的输入无限占用内存。
+package resp
+
+import (
+	"errors"
+)
+
+// 本文件实现内联命令的解析：
+// - 行尾可以是 CRLF 或单独的 LF；空行被忽略（见 parser.go）
+// - 参数以空白分隔；"..." 内支持 \n \r \t \b \a \\ \" \xHH 转义，'...' 内只支持 \' 转义
+// - 引号未闭合、或闭合引号后紧跟非空白字符时返回 unbalanced quotes 错误
+//
+// 限制：以 RESP 类型前缀（如 + - :）开头的行仍按 RESP 解析，不会被当作内联命令。
+
+// MaxInlineSize 为内联命令行的最大长度（与 Redis PROTO_INLINE_MAX_SIZE 一致）。
+const MaxInlineSize = 64 * 1024
+
+var (
+	errInlineTooBig    = errors.New("protocol error: too big inline request")
+	errUnbalancedQuote = errors.New("protocol error: unbalanced quotes in request")
+)
+
+// isTypePrefix 判断 b 是否为 RESP2/RESP3 的类型前缀。
+func isTypePrefix(b byte) bool {
+	switch b {
+	case '*', '$', '+', '-', ':', '%', '~', '>', '|', ',', '#', '_', '(', '=':
+		return true
+	}
+	return false
+}
+
+// inlineTooBig 判断已读取的内联命令行（含行尾）是否超过 MaxInlineSize。
+func inlineTooBig(line []byte) bool {
+	return len(line) > MaxInlineSize+2 && !isTypePrefix(line[0])
+}
+
+// parseInline 把内联命令行解析为 MultiBulkReply；只有空白的行返回空数组（由调用方忽略）。
+func parseInline(line []byte) (Reply, error) {
+	args, err := splitInlineArgs(line)
+	if err != nil {
+		return nil, err
+	}
+	return MakeMultiBulkReply(args), nil
+}
+
+// splitInlineArgs 按 Redis sdssplitargs 的规则切分参数。
+func splitInlineArgs(line []byte) ([][]byte, error) {
+	args := make([][]byte, 0, 4)
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var (
+			arg      = make([]byte, 0, 16)
+			inDouble bool
+			inSingle bool
+		)
+		for done := false; !done; {
+			switch {
+			case inDouble:
+				if i >= len(line) {
+					return nil, errUnbalancedQuote
+				}
+				c := line[i]
+				switch {
+				case c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					arg = append(arg, hexVal(line[i+2])<<4|hexVal(line[i+3]))
+					i += 3
+				case c == '\\' && i+1 < len(line):
+					i++
+					arg = append(arg, unescape(line[i]))
+				case c == '"':
+					// 闭合引号之后必须是空白或行尾
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuote
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			case inSingle:
+				if i >= len(line) {
+					return nil, errUnbalancedQuote
+				}
+				c := line[i]
+				switch {
+				case c == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					arg = append(arg, '\'')
+				case c == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuote
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			default:
+				if i >= len(line) {
+					done = true
+					break
+				}
+				switch c := line[i]; {
+				case isSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					arg = append(arg, c)
+				}
+			}
+			if i < len(line) {
+				i++
+			}
+		}
+		args = append(args, arg)
+	}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexVal(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// unescape 返回双引号内 \c 转义对应的字节；未知转义保留字符本身。
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	default:
+		return c
+	}
+}
@@ -0,0 +1,101 @@
+// 内联命令测试：验证参数切分（引号/转义）、LF 行尾、空行忽略与长度上限。
+// 覆盖：ParseStream 与 StreamParser 两种入口，未闭合引号与超长行返回协议错误。
+package resp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitInlineArgs(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{"PING", []string{"PING"}},
+		{"  SET  k\tv  ", []string{"SET", "k", "v"}},
+		{`SET k "hello world"`, []string{"SET", "k", "hello world"}},
+		{`SET k "a\"b\n\x41"`, []string{"SET", "k", "a\"b\nA"}},
+		{`SET k 'it\'s \n'`, []string{"SET", "k", `it's \n`}},
+		{`SET k ""`, []string{"SET", "k", ""}},
+		{`SET k pre"fix"`, []string{"SET", "k", "prefix"}},
+		{"   ", []string{}},
+	}
+	for _, c := range cases {
+		args, err := splitInlineArgs([]byte(c.line))
+		if err != nil {
+			t.Fatalf("%q: %v", c.line, err)
+		}
+		got := make([]string, 0, len(args))
+		for _, a := range args {
+			got = append(got, string(a))
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") || len(got) != len(c.want) {
+			t.Fatalf("%q: got %q, want %q", c.line, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{`SET k "abc`, `SET k 'abc`, `SET k "a"b`, `SET k 'a'b`} {
+		if _, err := splitInlineArgs([]byte(bad)); err != errUnbalancedQuote {
+			t.Fatalf("%q: expected unbalanced quotes, got %v", bad, err)
+		}
+	}
+}
+
+func TestParseStream_Inline(t *testing.T) {
+	input := "PING\n\r\n\nSET k \"v 1\"\r\n*1\r\n$4\r\nPING\r\n"
+	var got []string
+	for p := range ParseStream(&chunkReader{data: []byte(input), chunkSize: 3}) {
+		if p.Err != nil {
+			t.Fatalf("parse error: %v", p.Err)
+		}
+		mb, ok := p.Data.(*MultiBulkReply)
+		if !ok {
+			t.Fatalf("expected MultiBulkReply, got %T", p.Data)
+		}
+		parts := make([]string, 0, len(mb.Args))
+		for _, a := range mb.Args {
+			parts = append(parts, string(a))
+		}
+		got = append(got, strings.Join(parts, ","))
+	}
+	if want := []string{"PING", "SET,k,v 1", "PING"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// StreamParser 同样支持内联命令
+	r, err := NewStreamParser(bytes.NewBufferString("\r\nECHO 'x y'\n")).ReadReply()
+	if err != nil {
+		t.Fatalf("StreamParser inline: %v", err)
+	}
+	if mb, ok := r.(*MultiBulkReply); !ok || len(mb.Args) != 2 || string(mb.Args[1]) != "x y" {
+		t.Fatalf("StreamParser inline = %#v", r)
+	}
+}
+
+func TestParseStream_InlineLimits(t *testing.T) {
+	// 超过 MaxInlineSize 且没有换行：读到上限即报错，而不是一直缓冲
+	long := strings.Repeat("a", MaxInlineSize+10)
+	var lastErr error
+	for p := range ParseStream(strings.NewReader("SET k " + long)) {
+		lastErr = p.Err
+	}
+	if lastErr != errInlineTooBig {
+		t.Fatalf("expected too big inline request, got %v", lastErr)
+	}
+
+	// 恰好 MaxInlineSize 的行仍然合法
+	line := "ECHO " + strings.Repeat("b", MaxInlineSize-5) + "\r\n"
+	if _, err := NewStreamParser(strings.NewReader(line)).ReadReply(); err != nil {
+		t.Fatalf("line of MaxInlineSize: %v", err)
+	}
+
+	// RESP 行仍要求 CRLF；未闭合引号为协议错误
+	if _, err := NewStreamParser(strings.NewReader(":1\n")).ReadReply(); err == nil {
+		t.Fatalf("RESP line without CRLF should fail")
+	}
+	if _, err := NewStreamParser(strings.NewReader("SET k \"v\n")).ReadReply(); err != errUnbalancedQuote {
+		t.Fatalf("expected unbalanced quotes, got %v", err)
+	}
+}
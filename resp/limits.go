@@ -0,0 +1,66 @@
+// 协议限制：bulk 长度、数组元素个数与单条请求（查询缓冲）大小的上限。
+// 说明：长度头由客户端提供，解析器在分配内存之前先按 Limits 校验；超限时返回协议错误，服务端回复错误后断开连接。
+// 关键点：即使不设上限，内存也只随实际到达的数据增长（大 bulk 分段读取、数组预分配有上限），单个恶意长度头无法直接分配巨大内存。
+package resp
+
+import (
+	"errors"
+)
+
+// 本文件定义解析器的限制（与 Redis 配置项对应）：
+// - MaxBulkLen：proto-max-bulk-len，单个 bulk string 的最大长度
+// - MaxMultiBulkLen：数组（以及 RESP3 聚合类型）的最大元素个数
+// - MaxQueryBuffer：client-query-buffer-limit，单条请求（顶层消息）累计读取的最大字节数
+//
+// 各字段为 0 表示不限制；ParseStream 使用 DefaultLimits，StreamParser（读取对端节点的回复）不限制。
+
+// Limits 为解析器的协议限制。
+type Limits struct {
+	MaxBulkLen      int64
+	MaxMultiBulkLen int64
+	MaxQueryBuffer  int64
+}
+
+// DefaultLimits 与 Redis 默认值一致：proto-max-bulk-len 512mb、client-query-buffer-limit 1gb；
+// 数组元素个数上限取 Redis 对 multibulk 长度的硬上限 INT_MAX。
+var DefaultLimits = Limits{
+	MaxBulkLen:      512 << 20,
+	MaxMultiBulkLen: 1<<31 - 1,
+	MaxQueryBuffer:  1 << 30,
+}
+
+const (
+	// maxCountLine 为 * / $ 长度头行的最大长度（与 Redis 对 multibulk/bulk 长度头的 64KB 限制一致）
+	maxCountLine = 64 * 1024
+	// maxPrealloc 为数组按长度头预分配的最大元素个数，超过部分随元素到达增长
+	maxPrealloc = 1024
+	// maxNesting 为聚合类型的最大嵌套深度，防止 *1\r\n*1\r\n... 造成无界递归
+	maxNesting = 128
+)
+
+var (
+	errInvalidBulkLen      = errors.New("protocol error: invalid bulk length")
+	errInvalidMultiBulkLen = errors.New("protocol error: invalid multibulk length")
+	errQueryBufferLimit    = errors.New("protocol error: query buffer limit exceeded")
+	errCountLineTooBig     = errors.New("protocol error: too big count string")
+	errTooDeep             = errors.New("protocol error: too deep nesting")
+)
+
+// checkBulkLen 校验 bulk 长度头（-1 表示 nil）。
+func (l Limits) checkBulkLen(n int64) error {
+	if n < -1 || (l.MaxBulkLen > 0 && n > l.MaxBulkLen) || n > maxAllocLen {
+		return errInvalidBulkLen
+	}
+	return nil
+}
+
+// checkMultiBulkLen 校验数组/聚合类型的元素个数（-1 表示 nil 数组）。
+func (l Limits) checkMultiBulkLen(n int64) error {
+	if n < -1 || (l.MaxMultiBulkLen > 0 && n > l.MaxMultiBulkLen) || n > maxAllocLen {
+		return errInvalidMultiBulkLen
+	}
+	return nil
+}
+
+// maxAllocLen 保证不设上限时长度计算（n+2 等）不会溢出 int。
+const maxAllocLen = 1<<62 - 1
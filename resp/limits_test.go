@@ -0,0 +1,91 @@
+// 协议限制测试：验证超限的长度头在分配内存之前被拒绝，以及不设上限时内存随实际数据增长。
+// 覆盖：bulk 长度、数组元素个数、查询缓冲、长度头行长度、嵌套深度、负数长度。
+package resp
+
+import (
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// firstErr 返回解析 input 时产生的第一个错误（没有错误时为 nil）。
+func firstErr(input string, limits Limits) error {
+	for p := range ParseStreamWithLimits(strings.NewReader(input), limits) {
+		if p.Err != nil {
+			return p.Err
+		}
+	}
+	return nil
+}
+
+func TestParseStream_Limits(t *testing.T) {
+	limits := Limits{MaxBulkLen: 16, MaxMultiBulkLen: 4, MaxQueryBuffer: 64}
+	cases := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"bulk at limit", "*1\r\n$16\r\n" + strings.Repeat("x", 16) + "\r\n", nil},
+		{"bulk too long", "*1\r\n$17\r\n", errInvalidBulkLen},
+		{"huge bulk header", "*1\r\n$9223372036854775807\r\n", errInvalidBulkLen},
+		{"negative bulk", "*1\r\n$-2\r\n", errInvalidBulkLen},
+		{"array at limit", "*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n", nil},
+		{"array too long", "*5\r\n", errInvalidMultiBulkLen},
+		{"negative array", "*-5\r\n", errInvalidMultiBulkLen},
+		{"resp3 map too long", "%3\r\n", errInvalidMultiBulkLen},
+		{"query buffer", "*4\r\n$16\r\n" + strings.Repeat("x", 16) + "\r\n$16\r\n" + strings.Repeat("y", 16) + "\r\n$16\r\n", errQueryBufferLimit},
+	}
+	for _, c := range cases {
+		if got := firstErr(c.input, limits); got != c.want {
+			t.Errorf("%s: err = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// 查询缓冲按单条请求计算：pipeline 中多条小请求合计超过上限也没有问题
+	pipeline := strings.Repeat("*2\r\n$4\r\nECHO\r\n$8\r\nabcdefgh\r\n", 10)
+	if err := firstErr(pipeline, limits); err != nil {
+		t.Fatalf("pipeline of small requests: %v", err)
+	}
+
+	// 长度头行与嵌套深度有上限（无论是否设置 Limits）
+	if err := firstErr("*"+strings.Repeat("1", maxCountLine+2), Limits{}); err != errCountLineTooBig {
+		t.Fatalf("count line too long: err = %v, want %v", err, errCountLineTooBig)
+	}
+	deep := strings.Repeat("*1\r\n", maxNesting+2) + ":1\r\n"
+	if err := firstErr(deep, Limits{}); err != errTooDeep {
+		t.Fatalf("deep nesting: err = %v, want %v", err, errTooDeep)
+	}
+	ok := strings.Repeat("*1\r\n", maxNesting) + ":1\r\n"
+	if err := firstErr(ok, Limits{}); err != nil {
+		t.Fatalf("nesting at limit: %v", err)
+	}
+}
+
+func TestParseStream_UntrustedLengthDoesNotPreallocate(t *testing.T) {
+	// 不设上限时，声明 1GB 的 bulk 与 1 亿个元素的数组只发送少量数据：解析器按实际数据报错，而不是先分配
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for _, input := range []string{"*1\r\n$1073741824\r\nabc", "*100000000\r\n$1\r\na\r\n"} {
+		for p := range ParseStreamWithLimits(strings.NewReader(input), Limits{}) {
+			if p.Err != nil && p.Err != io.ErrUnexpectedEOF {
+				t.Fatalf("%q: unexpected error: %v", input, p.Err)
+			}
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8<<20 {
+		t.Fatalf("allocated %d bytes for untrusted length headers", allocated)
+	}
+
+	// 大 bulk 分段读取后内容完整
+	big := strings.Repeat("z", 3*bulkChunk+7)
+	r, err := NewStreamParser(strings.NewReader("$" + strconv.Itoa(len(big)) + "\r\n" + big + "\r\n")).ReadReply()
+	if err != nil {
+		t.Fatalf("big bulk: %v", err)
+	}
+	if br, ok := r.(*BulkReply); !ok || string(br.Arg) != big {
+		t.Fatalf("big bulk mismatch")
+	}
+}
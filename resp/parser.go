@@ -5,15 +5,19 @@ package resp
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"io"
 	"strconv"
 )
 
 // 本文件实现 RESP 协议解析器（Redis Serialization Protocol）：
-// - 使用状态机/分支解析不同前缀：*（数组）、$（Bulk）、+（状态）、-（错误）、:（整数）
-// - 依赖 bufio.Reader 的 ReadBytes/ReadFull 来天然处理 TCP 粘包/拆包
+// - 使用状态机/分支解析不同前缀：*（数组，支持嵌套）、$（Bulk）、+（状态）、-（错误）、:（整数）
+// - RESP3 类型（% ~ > | , # _ ( =）解析为 resp3.go 中对应的 Reply，供 HELLO 3 之后的客户端/测试读取回复
+// - 顶层行首字节不是类型前缀时按内联命令解析（见 inline.go），空行忽略
+// - 依赖 bufio.Reader 的 ReadSlice/ReadFull 来天然处理 TCP 粘包/拆包
 // - ParseStream 支持 Pipeline：一个连接连续发送多条命令，会逐条产出 Payload
+// - 长度头在分配内存之前按 Limits 校验（见 limits.go）
 
 // Pipeline/Payload
 type Payload struct {
@@ -21,31 +25,45 @@ type Payload struct {
 	Err  error
 }
 
+// protoReader 为解析器的输入：带缓冲的读取器、协议限制，以及当前顶层消息的累计字节数与嵌套深度。
+type protoReader struct {
+	buf      *bufio.Reader
+	limits   Limits
+	queryLen int64
+	depth    int
+}
+
+// consume 记录当前消息读取的字节数，超过查询缓冲上限时返回错误。
+func (r *protoReader) consume(n int64) error {
+	r.queryLen += n
+	if r.limits.MaxQueryBuffer > 0 && r.queryLen > r.limits.MaxQueryBuffer {
+		return errQueryBufferLimit
+	}
+	return nil
+}
+
 // ParseStream continuously reads from reader and sends Payloads to channel
 func ParseStream(reader io.Reader) <-chan *Payload {
+	return ParseStreamWithLimits(reader, DefaultLimits)
+}
+
+// ParseStreamWithLimits 与 ParseStream 相同，但使用指定的协议限制。
+func ParseStreamWithLimits(reader io.Reader, limits Limits) <-chan *Payload {
 	ch := make(chan *Payload)
-	go parse0(reader, ch)
+	go parse0(&protoReader{buf: bufio.NewReader(reader), limits: limits}, ch)
 	return ch
 }
 
-func parse0(reader io.Reader, ch chan<- *Payload) {
+func parse0(reader *protoReader, ch chan<- *Payload) {
 	defer close(ch)
-	bufReader := bufio.NewReader(reader)
 
 	for {
-		// Read first line
-		line, err := readLine(bufReader)
-		if err != nil {
-			if err == io.EOF {
-				return
-			}
-			ch <- &Payload{Err: err}
-			return
-		}
-
 		// Parse based on prefix
 		payload := &Payload{}
-		payload.Data, payload.Err = parseLine(line, bufReader)
+		payload.Data, payload.Err = readTop(reader)
+		if payload.Err == io.EOF {
+			return
+		}
 
 		ch <- payload
 		if payload.Err != nil {
@@ -54,7 +72,28 @@ func parse0(reader io.Reader, ch chan<- *Payload) {
 	}
 }
 
-func parseLine(line []byte, reader *bufio.Reader) (Reply, error) {
+// readTop 读取一条顶层消息：跳过空行（telnet 中直接回车，与 Redis 一致忽略），
+// 以类型前缀开头的按 RESP 解析，否则按内联命令解析。
+func readTop(reader *protoReader) (Reply, error) {
+	for {
+		reader.queryLen = 0
+		reader.depth = 0
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if !isTypePrefix(line[0]) {
+			return parseInline(line)
+		}
+		return parseLine(line, reader)
+	}
+}
+
+// parseLine 按类型前缀解析一个 RESP 值（聚合类型的元素也经由这里，元素不允许是内联命令）。
+func parseLine(line []byte, reader *protoReader) (Reply, error) {
 	if len(line) == 0 {
 		return nil, errors.New("empty line")
 	}
@@ -74,63 +113,125 @@ func parseLine(line []byte, reader *bufio.Reader) (Reply, error) {
 			return nil, err
 		}
 		return MakeIntReply(val), nil
+	case '%', '~', '>', '|', ',', '#', '_', '(', '=':
+		return parseResp3(line, reader)
 	default:
-		// Text protocol (inline commands, e.g. "PING\r\n") support?
-		// For strict RESP, this is an error, but telnet sends inline commands.
-		// Let's implement strict RESP first.
 		return nil, errors.New("protocol error: " + string(line))
 	}
 }
 
-func parseArray(header []byte, reader *bufio.Reader) (*MultiBulkReply, error) {
+// readElement 读取并解析聚合类型的一个元素。
+func readElement(reader *protoReader) (Reply, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	return parseElement(line, reader)
+}
+
+// parseElement 解析聚合类型的一个元素（嵌套深度受 maxNesting 限制）。
+func parseElement(line []byte, reader *protoReader) (Reply, error) {
+	if len(line) == 0 {
+		return nil, errors.New("protocol error: empty line in aggregate")
+	}
+	if reader.depth >= maxNesting {
+		return nil, errTooDeep
+	}
+	reader.depth++
+	defer func() { reader.depth-- }()
+	return parseLine(line, reader)
+}
+
+// parseArray 解析数组：元素全部为 bulk 时返回 MultiBulkReply（命令请求的常见形态），
+// 否则递归解析每个元素并返回 MultiRawReply（例如 SCAN 的嵌套回包）。
+func parseArray(header []byte, reader *protoReader) (Reply, error) {
 	// *3\r\n -> 3
-	n, err := strconv.Atoi(string(header[1:]))
+	n, err := strconv.ParseInt(string(header[1:]), 10, 64)
 	if err != nil {
 		return nil, err
 	}
+	if err := reader.limits.checkMultiBulkLen(n); err != nil {
+		return nil, err
+	}
 	if n == -1 {
-		return nil, nil // Null array
+		return MakeMultiBulkReply(nil), nil // Null array
 	}
 
-	lines := make([][]byte, 0, n)
-	for i := 0; i < n; i++ {
+	// 长度头不可信：预分配有上限，其余随元素到达增长
+	lines := make([][]byte, 0, min(n, maxPrealloc))
+	var replies []Reply // 出现非 bulk 元素后切换为通用解析
+	for i := int64(0); i < n; i++ {
 		line, err := readLine(reader)
 		if err != nil {
 			return nil, err
 		}
+		if len(line) == 0 {
+			return nil, errors.New("protocol error: empty line in array")
+		}
 
-		// Typically elements are BulkStrings ($...)
-		// but formally can be any type. Let's handle BulkString optimization
-		if line[0] == '$' {
+		if line[0] == '$' && replies == nil {
 			bulk, err := parseBulk(line, reader)
 			if err != nil {
 				return nil, err
 			}
 			lines = append(lines, bulk.Arg)
-		} else {
-			// Recursive logic for mixed arrays is complex, skip for now.
-			// Redis clients strictly send arrays of bulk strings for commands.
-			return nil, errors.New("protocol error: expected bulk string in array")
+			continue
+		}
+
+		if replies == nil {
+			replies = make([]Reply, 0, min(n, maxPrealloc))
+			for _, l := range lines {
+				replies = append(replies, MakeBulkReply(l))
+			}
+		}
+		elem, err := parseElement(line, reader)
+		if err != nil {
+			return nil, err
 		}
+		replies = append(replies, elem)
+	}
+	if replies != nil {
+		return MakeMultiRawReply(replies), nil
 	}
 	return MakeMultiBulkReply(lines), nil
 }
 
-func parseBulk(header []byte, reader *bufio.Reader) (*BulkReply, error) {
+// bulkChunk 以内的 bulk 一次性分配；更大的 bulk 随数据到达增长，声明的长度只作为上限。
+const bulkChunk = 64 * 1024
+
+func parseBulk(header []byte, reader *protoReader) (*BulkReply, error) {
 	// $4\r\n -> 4
-	n, err := strconv.Atoi(string(header[1:]))
+	n, err := strconv.ParseInt(string(header[1:]), 10, 64)
 	if err != nil {
 		return nil, err
 	}
+	if err := reader.limits.checkBulkLen(n); err != nil {
+		return nil, err
+	}
 	if n == -1 {
 		return MakeBulkReply(nil), nil // Null Bulk String
 	}
+	if err := reader.consume(n + 2); err != nil {
+		return nil, err
+	}
 
 	// Read N bytes + \r\n
-	body := make([]byte, n+2)
-	_, err = io.ReadFull(reader, body)
-	if err != nil {
-		return nil, err
+	var body []byte
+	if n <= bulkChunk {
+		body = make([]byte, n+2)
+		if _, err := io.ReadFull(reader.buf, body); err != nil {
+			return nil, err
+		}
+	} else {
+		var buf bytes.Buffer
+		buf.Grow(bulkChunk)
+		if _, err := io.CopyN(&buf, reader.buf, n+2); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		body = buf.Bytes()
 	}
 
 	// Verify CRLF
@@ -141,15 +242,119 @@ func parseBulk(header []byte, reader *bufio.Reader) (*BulkReply, error) {
 	return MakeBulkReply(body[:n]), nil
 }
 
-func readLine(bufReader *bufio.Reader) ([]byte, error) {
-	// Read until \n
-	line, err := bufReader.ReadBytes('\n')
-	if err != nil {
-		return nil, err
+// readLine 读取一行并去掉行尾。RESP 行必须以 CRLF 结尾；内联命令行与 Redis 一致也可以只以 LF 结尾，
+// 且长度不能超过 MaxInlineSize。* / $ 长度头行不能超过 maxCountLine，其它行计入查询缓冲。
+// 整行已在缓冲中时（常见情况）返回的切片直接引用 bufio 的缓冲，只在下一次读取之前有效。
+func readLine(reader *protoReader) ([]byte, error) {
+	// Read until \n（分段读取，以便在读完整行之前检查长度）
+	var line []byte
+	for {
+		chunk, err := reader.buf.ReadSlice('\n')
+		if line == nil && err == nil {
+			line = chunk
+		} else {
+			line = append(line, chunk...)
+		}
+		if inlineTooBig(line) {
+			return nil, errInlineTooBig
+		}
+		if len(line) > maxCountLine+2 && (line[0] == '*' || line[0] == '$') {
+			return nil, errCountLineTooBig
+		}
+		if cerr := reader.consume(int64(len(chunk))); cerr != nil {
+			return nil, cerr
+		}
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return nil, err
+		}
 	}
+	n := len(line)
 	// Trim \r\n
-	if len(line) > 1 && line[len(line)-2] == '\r' {
-		return line[:len(line)-2], nil
+	if n > 1 && line[n-2] == '\r' {
+		return line[:n-2], nil
+	}
+	if n == 1 || !isTypePrefix(line[0]) {
+		return line[:n-1], nil
 	}
 	return nil, errors.New("protocol error: no CRLF")
 }
+
+// parseResp3 解析 RESP3 类型。
+func parseResp3(line []byte, reader *protoReader) (Reply, error) {
+	body := string(line[1:])
+	switch line[0] {
+	case '_':
+		return &NullReply{}, nil
+	case '#':
+		switch body {
+		case "t":
+			return MakeBoolReply(true), nil
+		case "f":
+			return MakeBoolReply(false), nil
+		}
+		return nil, errors.New("protocol error: bad boolean " + body)
+	case ',':
+		f, err := strconv.ParseFloat(body, 64)
+		if err != nil {
+			return nil, errors.New("protocol error: bad double " + body)
+		}
+		return MakeDoubleReply(f), nil
+	case '(':
+		return MakeBigNumberReply(body), nil
+	case '=':
+		bulk, err := parseBulk(line, reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(bulk.Arg) < 4 || bulk.Arg[3] != ':' {
+			return nil, errors.New("protocol error: bad verbatim string format")
+		}
+		return MakeVerbatimReply(string(bulk.Arg[:3]), bulk.Arg[4:]), nil
+	}
+
+	n, err := strconv.ParseInt(body, 10, 64)
+	if err != nil || n < 0 {
+		return nil, errors.New("protocol error: bad aggregate length " + body)
+	}
+	if line[0] == '%' || line[0] == '|' {
+		n *= 2
+	}
+	if err := reader.limits.checkMultiBulkLen(n); err != nil {
+		return nil, err
+	}
+	elems := make([]Reply, 0, min(n, maxPrealloc))
+	for i := int64(0); i < n; i++ {
+		elem, err := readElement(reader)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+
+	switch line[0] {
+	case '%':
+		return MakeMapReply(elems), nil
+	case '>':
+		return MakePushReply(elems), nil
+	case '|':
+		// 属性之后紧跟被修饰的回复
+		r, err := readElement(reader)
+		if err != nil {
+			return nil, err
+		}
+		return MakeAttributeReply(elems, r), nil
+	default: // '~'
+		members := make([][]byte, 0, len(elems))
+		for _, e := range elems {
+			b, ok := e.(*BulkReply)
+			if !ok {
+				return nil, errors.New("protocol error: set members must be bulk strings")
+			}
+			members = append(members, b.Arg)
+		}
+		return MakeSetReply(members), nil
+	}
+}
@@ -94,3 +94,40 @@ func TestParseStream_FragmentedInput(t *testing.T) {
 		t.Fatalf("expected no more payloads, got %+v", p2)
 	}
 }
+
+func TestStreamParser_NestedArray(t *testing.T) {
+	// SCAN 回包：[cursor, [keys...]]，peer 转发时需要完整解析嵌套数组
+	nested := MakeMultiRawReply([]Reply{
+		MakeBulkReply([]byte("17")),
+		MakeMultiBulkReply([][]byte{[]byte("a"), []byte("b")}),
+		MakeIntReply(3),
+	}).ToBytes()
+	nullArr := MakeMultiBulkReply(nil).ToBytes()
+
+	p := NewStreamParser(&chunkReader{data: append(nested, nullArr...), chunkSize: 3})
+	r, err := p.ReadReply()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	raw, ok := r.(*MultiRawReply)
+	if !ok || len(raw.Replies) != 3 {
+		t.Fatalf("expected MultiRawReply with 3 elements, got %T %+v", r, r)
+	}
+	if string(raw.Replies[0].(*BulkReply).Arg) != "17" {
+		t.Fatalf("unexpected cursor: %+v", raw.Replies[0])
+	}
+	if mb := raw.Replies[1].(*MultiBulkReply); len(mb.Args) != 2 || string(mb.Args[1]) != "b" {
+		t.Fatalf("unexpected inner array: %+v", mb)
+	}
+	if string(raw.ToBytes()) != string(nested) {
+		t.Fatalf("round trip mismatch: %q", raw.ToBytes())
+	}
+
+	r, err = p.ReadReply()
+	if err != nil {
+		t.Fatalf("parse null array error: %v", err)
+	}
+	if mb, ok := r.(*MultiBulkReply); !ok || mb.Args != nil {
+		t.Fatalf("expected null MultiBulkReply, got %T %+v", r, r)
+	}
+}
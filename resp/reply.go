@@ -76,6 +76,8 @@ func (r *IntReply) ToBytes() []byte {
 
 type BulkReply struct {
 	Arg []byte
+	// Double 标记 Arg 为格式化后的浮点数（ZSCORE 等），RESP3 下编码为 double，见 MakeDoubleBulkReply
+	Double bool
 }
 
 func MakeBulkReply(arg []byte) *BulkReply {
@@ -102,6 +104,8 @@ func (r *BulkReply) ToBytes() []byte {
 
 type MultiBulkReply struct {
 	Args [][]byte
+	// Kind 为 RESP3 下的聚合类型（map/set，见 MakeMapBulkReply/MakeSetBulkReply），RESP2 下一律编码为数组
+	Kind AggregateKind
 }
 
 func MakeMultiBulkReply(args [][]byte) *MultiBulkReply {
@@ -130,6 +134,28 @@ func (r *MultiBulkReply) ToBytes() []byte {
 	return buf.Bytes()
 }
 
+// -----------------------------------
+// Nested Array: *2\r\n$1\r\n0\r\n*1\r\n$3\r\nfoo\r\n
+// 元素可以是任意 Reply（SCAN 的 [cursor, [keys...]] 等嵌套结构）
+// -----------------------------------
+
+type MultiRawReply struct {
+	Replies []Reply
+}
+
+func MakeMultiRawReply(replies []Reply) *MultiRawReply {
+	return &MultiRawReply{Replies: replies}
+}
+
+func (r *MultiRawReply) ToBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("*" + strconv.Itoa(len(r.Replies)) + CRLF)
+	for _, rep := range r.Replies {
+		buf.Write(rep.ToBytes())
+	}
+	return buf.Bytes()
+}
+
 var (
 	OkReply       = MakeStatusReply("OK")
 	PongReply     = MakeStatusReply("PONG")
@@ -0,0 +1,220 @@
+// RequestReader：服务端热路径上的同步请求读取器。
+// 说明：在连接的 goroutine 中直接读取命令，不经过 ParseStream 的协程与 channel；参数切片与数据缓冲在命令之间复用，稳定状态下解析不分配内存。
+// 关键点：ReadCommand 返回的参数只在下一次调用之前有效，需要持有参数的调用方（DB、AOF、事务队列）先用 CloneArgs 复制。
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+)
+
+// 本文件实现请求（命令）的同步读取：
+// - *N 数组：元素必须是 bulk string，数据读入复用的 arena，参数切片同样复用
+// - 内联命令：按 inline.go 的规则切分（面向 telnet/探针，不追求零分配）
+// - 顶层的其它 RESP 值被完整读取后返回 ErrExpectedArray，连接可以继续使用
+// - WaitClosed 在阻塞命令等待期间观察连接是否断开，读到的数据留在缓冲中
+//
+// 协议限制与 ParseStream 相同（见 limits.go）；单条命令用过的超大缓冲在下一条命令开始时释放。
+
+// ErrExpectedArray 表示顶层收到的不是命令数组（该值已被读取，连接可以继续使用）。
+var ErrExpectedArray = errors.New("protocol error: expected array")
+
+const (
+	// 超过这些容量的复用缓冲在下一条命令开始时释放，避免一条大命令长期占用连接内存
+	maxRetainedArena = 1 << 20
+	maxRetainedArgs  = 1024
+)
+
+// RequestReader 从连接同步读取命令。
+type RequestReader struct {
+	r     *protoReader
+	args  [][]byte
+	ends  []int // 各参数在 arena 中的结束位置（arena 扩容后再统一切出 args）
+	arena []byte
+}
+
+// NewRequestReader 创建请求读取器，limits 为协议限制。
+func NewRequestReader(rd io.Reader, limits Limits) *RequestReader {
+	return &RequestReader{r: &protoReader{buf: bufio.NewReaderSize(rd, 16*1024), limits: limits}}
+}
+
+// WaitClosed 在不消费数据的前提下继续读取连接，直到读取出错（对端关闭时为 io.EOF）；读到的后续请求留在缓冲中，
+// 之后的 ReadCommand 照常解析。输入缓冲已满时返回 bufio.ErrBufferFull（无法再观察连接）。
+// 用于阻塞命令等待期间发现客户端断开；调用方设置读超时让它返回，超时错误不会影响之后的读取。
+// 不能与 ReadCommand 并发调用。
+func (rr *RequestReader) WaitClosed() error {
+	for {
+		if _, err := rr.r.buf.Peek(rr.r.buf.Buffered() + 1); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadCommand 读取下一条命令。空数组（*0 / *-1）返回长度为 0 的参数；io.EOF 表示连接正常关闭。
+// 除 ErrExpectedArray 外，返回错误后连接不可继续使用。
+func (rr *RequestReader) ReadCommand() ([][]byte, error) {
+	if cap(rr.arena) > maxRetainedArena {
+		rr.arena = nil
+	}
+	if cap(rr.args) > maxRetainedArgs {
+		rr.args, rr.ends = nil, nil
+	}
+	rr.r.queryLen = 0
+	rr.r.depth = 0
+
+	for {
+		line, err := readLine(rr.r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		switch {
+		case line[0] == '*':
+			return rr.readArray(line)
+		case !isTypePrefix(line[0]):
+			return splitInlineArgs(line)
+		default:
+			if _, err := parseLine(line, rr.r); err != nil {
+				return nil, err
+			}
+			return nil, ErrExpectedArray
+		}
+	}
+}
+
+// readArray 读取 *N 命令数组的 N 个 bulk 参数。
+func (rr *RequestReader) readArray(header []byte) ([][]byte, error) {
+	n, ok := parseLen(header[1:])
+	if !ok {
+		return nil, errInvalidMultiBulkLen
+	}
+	if err := rr.r.limits.checkMultiBulkLen(n); err != nil {
+		return nil, err
+	}
+	rr.arena = rr.arena[:0]
+	rr.ends = rr.ends[:0]
+	for i := int64(0); i < n; i++ {
+		line, err := readLine(rr.r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			got := "empty line"
+			if len(line) > 0 {
+				got = "'" + string(line[0]) + "'"
+			}
+			return nil, errors.New("protocol error: expected '$', got " + got)
+		}
+		size, ok := parseLen(line[1:])
+		if !ok || size < 0 {
+			return nil, errInvalidBulkLen
+		}
+		if err := rr.r.limits.checkBulkLen(size); err != nil {
+			return nil, err
+		}
+		if err := rr.r.consume(size + 2); err != nil {
+			return nil, err
+		}
+		if err := rr.readBulk(int(size)); err != nil {
+			return nil, err
+		}
+		rr.ends = append(rr.ends, len(rr.arena))
+	}
+
+	rr.args = rr.args[:0]
+	start := 0
+	for _, end := range rr.ends {
+		rr.args = append(rr.args, rr.arena[start:end:end])
+		start = end
+	}
+	return rr.args, nil
+}
+
+// readBulk 把 size 字节的数据与结尾的 CRLF 读入 arena（CRLF 不保留）。
+// 按 bulkChunk 分段扩容，声明的长度只作为上限，内存随实际到达的数据增长。
+func (rr *RequestReader) readBulk(size int) error {
+	remaining := size + 2
+	for remaining > 0 {
+		chunk := min(remaining, bulkChunk)
+		rr.arena = slices.Grow(rr.arena, chunk)
+		l := len(rr.arena)
+		rr.arena = rr.arena[:l+chunk]
+		if _, err := io.ReadFull(rr.r.buf, rr.arena[l:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		remaining -= chunk
+	}
+	l := len(rr.arena)
+	if rr.arena[l-2] != '\r' || rr.arena[l-1] != '\n' {
+		return errors.New("protocol error: bad bulk string format")
+	}
+	rr.arena = rr.arena[:l-2]
+	return nil
+}
+
+// parseLen 解析长度头中的十进制整数（允许负数），不分配内存；最多 18 位数字，不会溢出。
+func parseLen(b []byte) (int64, bool) {
+	if len(b) == 0 || len(b) > 18 {
+		return 0, false
+	}
+	neg := b[0] == '-'
+	if neg {
+		b = b[1:]
+		if len(b) == 0 {
+			return 0, false
+		}
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+// smallArgs 让短命令（GET/SET/HSET 等）的参数切片头与数据共用一次分配，大小恰为 256 字节的分配规格。
+// 参数互相引用同一个对象，只要还持有任一参数，整个对象都不会被回收。
+type smallArgs struct {
+	hdr  [smallArgsMax][]byte
+	data [smallArgsData]byte
+}
+
+const (
+	smallArgsMax  = 6
+	smallArgsData = 112
+)
+
+// CloneArgs 把参数复制到新分配的内存中，返回的参数可以被长期持有。
+// 短命令只分配一次（smallArgs），超出时切片头与数据各分配一次。
+func CloneArgs(args [][]byte) [][]byte {
+	total := 0
+	for _, a := range args {
+		total += len(a)
+	}
+	var out [][]byte
+	var data []byte
+	if len(args) <= smallArgsMax && total <= smallArgsData {
+		s := new(smallArgs)
+		out, data = s.hdr[:len(args):len(args)], s.data[:total:total]
+	} else {
+		out, data = make([][]byte, len(args)), make([]byte, total)
+	}
+	for i, a := range args {
+		n := copy(data, a)
+		out[i] = data[:n:n]
+		data = data[n:]
+	}
+	return out
+}
@@ -0,0 +1,247 @@
+// RequestReader 测试：验证同步读取命令的正确性、缓冲复用语义与稳定状态下的零分配，并提供与 ParseStream 对比的基准测试。
+// 覆盖：pipeline、拆包、内联命令、非数组请求、协议限制、CloneArgs、WaitClosed、AppendReply 与 Encode 输出一致。
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll 读取全部命令（参数以空格连接），遇到 io.EOF 结束。
+func readAll(t *testing.T, rr *RequestReader) []string {
+	t.Helper()
+	var out []string
+	for {
+		args, err := rr.ReadCommand()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("ReadCommand: %v", err)
+		}
+		parts := make([]string, 0, len(args))
+		for _, a := range args {
+			parts = append(parts, string(a))
+		}
+		out = append(out, strings.Join(parts, " "))
+	}
+}
+
+func TestRequestReader_Commands(t *testing.T) {
+	input := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$0\r\n\r\n" +
+		"PING\n" +
+		"*0\r\n" +
+		"\r\n*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n"
+	want := []string{"SET k ", "PING", "", "ECHO a\r\nb"}
+
+	for _, chunk := range []int{1, 3, len(input)} {
+		rr := NewRequestReader(&chunkReader{data: []byte(input), chunkSize: chunk}, DefaultLimits)
+		if got := readAll(t, rr); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("chunk=%d: got %q, want %q", chunk, got, want)
+		}
+	}
+}
+
+func TestRequestReader_Errors(t *testing.T) {
+	// 非数组请求被完整读取，之后的命令仍可读取
+	rr := NewRequestReader(strings.NewReader("$3\r\nfoo\r\n*1\r\n$4\r\nPING\r\n"), DefaultLimits)
+	if _, err := rr.ReadCommand(); err != ErrExpectedArray {
+		t.Fatalf("expected ErrExpectedArray, got %v", err)
+	}
+	if args, err := rr.ReadCommand(); err != nil || string(args[0]) != "PING" {
+		t.Fatalf("command after non-array: %q %v", args, err)
+	}
+
+	limits := Limits{MaxBulkLen: 4, MaxMultiBulkLen: 2, MaxQueryBuffer: 64}
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"*3\r\n", errInvalidMultiBulkLen.Error()},
+		{"*x\r\n", errInvalidMultiBulkLen.Error()},
+		{"*1\r\n$5\r\n", errInvalidBulkLen.Error()},
+		{"*1\r\n$-1\r\n", errInvalidBulkLen.Error()},
+		{"*1\r\n:1\r\n", "protocol error: expected '$', got ':'"},
+		{"*1\r\n$3\r\nabcd\r\n", "protocol error: bad bulk string format"},
+		{"*2\r\n$4\r\nabcd\r\n$4\r\nab", io.ErrUnexpectedEOF.Error()},
+	}
+	for _, c := range cases {
+		_, err := NewRequestReader(strings.NewReader(c.input), limits).ReadCommand()
+		if err == nil || err.Error() != c.want {
+			t.Errorf("%q: err = %v, want %s", c.input, err, c.want)
+		}
+	}
+}
+
+func TestRequestReader_ReuseAndClone(t *testing.T) {
+	rr := NewRequestReader(strings.NewReader("*2\r\n$3\r\nGET\r\n$1\r\na\r\n*2\r\n$3\r\nGET\r\n$1\r\nb\r\n"), DefaultLimits)
+	first, _ := rr.ReadCommand()
+	kept := CloneArgs(first)
+	if _, err := rr.ReadCommand(); err != nil {
+		t.Fatalf("second command: %v", err)
+	}
+	// 参数缓冲被复用：未复制的参数已被第二条命令覆盖，复制后的参数不受影响
+	if string(first[1]) != "b" || string(kept[1]) != "a" {
+		t.Fatalf("first=%q kept=%q", first, kept)
+	}
+	// 复制出的参数互不重叠：追加不会覆盖后一个参数
+	kept[0] = append(kept[0], 'X')
+	if string(kept[1]) != "a" {
+		t.Fatalf("append to cloned arg overwrote its neighbour: %q", kept)
+	}
+}
+
+func TestCloneArgs_Allocs(t *testing.T) {
+	small := [][]byte{[]byte("SET"), []byte("key"), []byte("value")}
+	large := [][]byte{[]byte("HSET"), []byte("h"), []byte("f1"), []byte("v1"), []byte("f2"), []byte("v2"), []byte("f3"), []byte("v3")}
+	for _, c := range []struct {
+		args   [][]byte
+		allocs float64
+	}{{small, 1}, {large, 2}, {[][]byte{bytes.Repeat([]byte("x"), 200)}, 2}} {
+		if got := CloneArgs(c.args); !slices.EqualFunc(got, c.args, bytes.Equal) {
+			t.Fatalf("CloneArgs = %q, want %q", got, c.args)
+		}
+		if n := testing.AllocsPerRun(100, func() { _ = CloneArgs(c.args) }); n != c.allocs {
+			t.Fatalf("CloneArgs of %d args: %v allocs, want %v", len(c.args), n, c.allocs)
+		}
+	}
+}
+
+func TestRequestReader_ZeroAlloc(t *testing.T) {
+	cmd := MakeMultiBulkReply([][]byte{[]byte("SET"), []byte("key:000001"), []byte(strings.Repeat("v", 64))}).ToBytes()
+	rr := NewRequestReader(&loopReader{data: cmd}, DefaultLimits)
+	rr.ReadCommand() // 预热复用的缓冲
+	if allocs := testing.AllocsPerRun(1000, func() {
+		if _, err := rr.ReadCommand(); err != nil {
+			t.Fatal(err)
+		}
+	}); allocs != 0 {
+		t.Fatalf("ReadCommand allocates %.1f times per command", allocs)
+	}
+}
+
+func TestRequestReader_WaitClosed(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	rr := NewRequestReader(server, DefaultLimits)
+
+	// 读超时结束观察：期间收到的请求留在缓冲中，超时不影响之后的读取
+	result := make(chan error, 1)
+	go func() { result <- rr.WaitClosed() }()
+	_, _ = client.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	_ = server.SetReadDeadline(time.Now())
+	if err := <-result; !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("WaitClosed after deadline = %v", err)
+	}
+	_ = server.SetReadDeadline(time.Time{})
+	if args, err := rr.ReadCommand(); err != nil || len(args) != 1 || string(args[0]) != "PING" {
+		t.Fatalf("ReadCommand after WaitClosed = %q, %v", args, err)
+	}
+
+	// 对端关闭
+	go func() { result <- rr.WaitClosed() }()
+	client.Close()
+	if err := <-result; err != io.EOF {
+		t.Fatalf("WaitClosed after close = %v", err)
+	}
+}
+
+func TestAppendReply_MatchesEncode(t *testing.T) {
+	replies := []Reply{
+		OkReply, MakeErrReply("ERR bad"), MakeIntReply(-42), MakeBulkReply([]byte("v")), NullBulkReply,
+		MakeBulkReply([]byte{}), MakeMultiBulkReply(nil), MakeMultiBulkReply([][]byte{}),
+		MakeMultiBulkReply([][]byte{[]byte("a"), nil}),
+		MakeMultiRawReply([]Reply{MakeIntReply(1), MakeMapReply([]Reply{MakeBulkReply([]byte("k")), MakeDoubleReply(math.Inf(1))})}),
+		MakeSetReply([][]byte{[]byte("m")}), MakeBoolReply(true), &NullReply{},
+		MakeMapBulkReply([][]byte{[]byte("k"), []byte("v")}), MakeMapBulkReply(nil), MakeSetBulkReply([][]byte{[]byte("m")}),
+		MakeDoubleBulkReply(-2.5),
+	}
+	for _, protocol := range []int{RESP2, RESP3} {
+		for _, r := range replies {
+			if got, want := AppendReply([]byte("prefix"), r, protocol), append([]byte("prefix"), Encode(r, protocol)...); !bytes.Equal(got, want) {
+				t.Errorf("RESP%d %T: AppendReply = %q, Encode = %q", protocol, r, got, want)
+			}
+		}
+	}
+}
+
+// loopReader 无限循环输出 data（用于基准测试与分配测试）。
+type loopReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *loopReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.data[r.pos:])
+		n += c
+		r.pos = (r.pos + c) % len(r.data)
+	}
+	return n, nil
+}
+
+// pipelineInput 生成 n 条 SET 命令组成的 pipeline 输入。
+func pipelineInput(n int) []byte {
+	var buf bytes.Buffer
+	value := []byte(strings.Repeat("v", 64))
+	for i := 0; i < n; i++ {
+		buf.Write(MakeMultiBulkReply([][]byte{[]byte("SET"), []byte("key:000001"), value}).ToBytes())
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkRequestReader_Pipeline(b *testing.B) {
+	data := pipelineInput(b.N)
+	b.SetBytes(int64(len(data) / max(b.N, 1)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	rr := NewRequestReader(bytes.NewReader(data), DefaultLimits)
+	for i := 0; i < b.N; i++ {
+		if _, err := rr.ReadCommand(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseStream_Pipeline(b *testing.B) {
+	data := pipelineInput(b.N)
+	b.SetBytes(int64(len(data) / max(b.N, 1)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	n := 0
+	for p := range ParseStream(bytes.NewReader(data)) {
+		if p.Err != nil {
+			b.Fatal(p.Err)
+		}
+		n++
+	}
+	if n != b.N {
+		b.Fatalf("parsed %d of %d commands", n, b.N)
+	}
+}
+
+func BenchmarkAppendReply(b *testing.B) {
+	r := MakeBulkReply([]byte(strings.Repeat("v", 64)))
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendReply(buf[:0], r, RESP2)
+	}
+}
+
+func BenchmarkToBytes(b *testing.B) {
+	r := MakeBulkReply([]byte(strings.Repeat("v", 64)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = r.ToBytes()
+	}
+}
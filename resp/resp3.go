@@ -0,0 +1,360 @@
+// RESP3 Reply：map/set/double/boolean/null/big number/verbatim/push/attribute 类型与按协议版本编码。
+// 说明：所有 Reply 的 ToBytes 始终输出 RESP2 编码；RESP3 下编码不同的类型额外实现 ToBytes3，由 Encode 按连接协议选择。
+// 命令实现返回 RESP2 类型（BulkReply/MultiBulkReply），需要 RESP3 形态时只在其上标记 Kind/Double，编码时再转换。
+// 关键点：RESP3 类型的 RESP2 编码与引入它们之前命令的输出完全一致（map 为 key/value 交替的数组，double 为 bulk string 等），RESP2 客户端不受影响。
+package resp
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+)
+
+// 本文件实现 RESP3（HELLO 3 协商后使用）：
+// - 聚合类型：%（map）、~（set）、>（push，发布订阅消息）、|（attribute，附加在下一个回复之前）
+// - 简单类型：,（double）、#（boolean）、_（null）、((big number)、=（verbatim string）
+// - 已有类型的 RESP3 编码：nil bulk / nil 数组统一为 _；数组元素递归按 RESP3 编码
+
+const (
+	// RESP2/RESP3 为 HELLO 协商的协议版本（连接默认 RESP2）。
+	RESP2 = 2
+	RESP3 = 3
+)
+
+// Resp3Reply 为 RESP3 下编码与 RESP2 不同的 Reply。
+type Resp3Reply interface {
+	Reply
+	ToBytes3() []byte
+}
+
+// Encode 按协议版本编码 r。
+func Encode(r Reply, protocol int) []byte {
+	if protocol == RESP3 {
+		if r3, ok := r.(Resp3Reply); ok {
+			return r3.ToBytes3()
+		}
+	}
+	return r.ToBytes()
+}
+
+// writeAggregate 写出聚合类型：类型前缀 + 元素个数 + 按协议编码的各元素。
+func writeAggregate(buf *bytes.Buffer, prefix byte, n int, replies []Reply, protocol int) {
+	buf.WriteByte(prefix)
+	buf.WriteString(strconv.Itoa(n))
+	buf.WriteString(CRLF)
+	for _, r := range replies {
+		buf.Write(Encode(r, protocol))
+	}
+}
+
+// -----------------------------------
+// 已有类型的 RESP3 编码
+// -----------------------------------
+
+// AggregateKind 为 MultiBulkReply 在 RESP3 下的聚合类型。
+type AggregateKind byte
+
+const (
+	KindArray AggregateKind = iota // *
+	KindMap                        // %，Args 为 key/value 交替
+	KindSet                        // ~
+)
+
+// MakeMapBulkReply 返回 key/value 交替的数组，RESP3 下编码为 map（HGETALL、CONFIG GET）。
+func MakeMapBulkReply(args [][]byte) *MultiBulkReply {
+	return &MultiBulkReply{Args: args, Kind: KindMap}
+}
+
+// MakeSetBulkReply 返回成员数组，RESP3 下编码为 set（SMEMBERS、SUNION 等）。
+func MakeSetBulkReply(args [][]byte) *MultiBulkReply {
+	return &MultiBulkReply{Args: args, Kind: KindSet}
+}
+
+// MakeDoubleBulkReply 返回按 FormatDouble 格式化的 bulk string，RESP3 下编码为 double（ZSCORE 等）。
+func MakeDoubleBulkReply(f float64) *BulkReply {
+	return &BulkReply{Arg: []byte(FormatDouble(f)), Double: true}
+}
+
+var nullBytes = []byte("_" + CRLF)
+
+func (r *BulkReply) ToBytes3() []byte {
+	if r.Arg == nil {
+		return nullBytes
+	}
+	if r.Double {
+		return []byte("," + string(r.Arg) + CRLF)
+	}
+	return r.ToBytes()
+}
+
+// aggregateHeader 返回 RESP3 聚合类型头：前缀 + 元素个数（map 为 key/value 对数）。
+func (r *MultiBulkReply) aggregateHeader() (byte, int) {
+	switch r.Kind {
+	case KindMap:
+		return '%', len(r.Args) / 2
+	case KindSet:
+		return '~', len(r.Args)
+	}
+	return '*', len(r.Args)
+}
+
+func (r *MultiBulkReply) ToBytes3() []byte {
+	// nil 数组为 null；nil map/set 为空聚合（与 MapReply/SetReply 一致）
+	if r.Args == nil && r.Kind == KindArray {
+		return nullBytes
+	}
+	prefix, n := r.aggregateHeader()
+	var buf bytes.Buffer
+	buf.WriteByte(prefix)
+	buf.WriteString(strconv.Itoa(n) + CRLF)
+	for _, arg := range r.Args {
+		if arg == nil {
+			buf.Write(nullBytes)
+			continue
+		}
+		buf.Write(MakeBulkReply(arg).ToBytes())
+	}
+	return buf.Bytes()
+}
+
+func (r *MultiRawReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	writeAggregate(&buf, '*', len(r.Replies), r.Replies, RESP3)
+	return buf.Bytes()
+}
+
+// -----------------------------------
+// Map: %2\r\n+first\r\n:1\r\n+second\r\n:2\r\n
+// RESP2: key/value 交替的数组（HGETALL 等命令原有的输出）
+// -----------------------------------
+
+type MapReply struct {
+	// Pairs 为 key/value 交替的元素；为 nil 时 RESP2 下与 MultiBulkReply 一致编码为 nil 数组，RESP3 下为空 map
+	Pairs []Reply
+}
+
+func MakeMapReply(pairs []Reply) *MapReply {
+	return &MapReply{Pairs: pairs}
+}
+
+func (r *MapReply) ToBytes() []byte {
+	if r.Pairs == nil {
+		return MakeMultiBulkReply(nil).ToBytes()
+	}
+	return MakeMultiRawReply(r.Pairs).ToBytes()
+}
+
+func (r *MapReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	writeAggregate(&buf, '%', len(r.Pairs)/2, r.Pairs, RESP3)
+	return buf.Bytes()
+}
+
+// -----------------------------------
+// Set: ~2\r\n$1\r\na\r\n$1\r\nb\r\n
+// RESP2: 数组（SMEMBERS 等命令原有的输出）
+// -----------------------------------
+
+type SetReply struct {
+	// Args 为集合成员；为 nil 时 RESP2 下编码为 nil 数组，RESP3 下为空集合
+	Args [][]byte
+}
+
+func MakeSetReply(args [][]byte) *SetReply {
+	return &SetReply{Args: args}
+}
+
+func (r *SetReply) ToBytes() []byte {
+	return MakeMultiBulkReply(r.Args).ToBytes()
+}
+
+func (r *SetReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("~" + strconv.Itoa(len(r.Args)) + CRLF)
+	for _, arg := range r.Args {
+		buf.Write(MakeBulkReply(arg).ToBytes3())
+	}
+	return buf.Bytes()
+}
+
+// -----------------------------------
+// Double: ,3.14\r\n  ,inf\r\n  ,-inf\r\n  ,nan\r\n
+// RESP2: bulk string（ZSCORE 等命令原有的输出）
+// -----------------------------------
+
+type DoubleReply struct {
+	Value float64
+}
+
+func MakeDoubleReply(value float64) *DoubleReply {
+	return &DoubleReply{Value: value}
+}
+
+// FormatDouble 格式化浮点数：inf/-inf，可精确表示的整数不使用指数形式，其余使用最短表示。
+func FormatDouble(f float64) string {
+	if math.IsInf(f, 1) {
+		return "inf"
+	}
+	if math.IsInf(f, -1) {
+		return "-inf"
+	}
+	// 可精确表示的整数不使用指数形式（GEO 的 52 位 geohash 分值依赖这一点）
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (r *DoubleReply) ToBytes() []byte {
+	return MakeBulkReply([]byte(FormatDouble(r.Value))).ToBytes()
+}
+
+func (r *DoubleReply) ToBytes3() []byte {
+	s := FormatDouble(r.Value)
+	if math.IsNaN(r.Value) {
+		s = "nan"
+	}
+	return []byte("," + s + CRLF)
+}
+
+// -----------------------------------
+// Boolean: #t\r\n  #f\r\n
+// RESP2: 整数 1/0
+// -----------------------------------
+
+type BoolReply struct {
+	Value bool
+}
+
+func MakeBoolReply(value bool) *BoolReply {
+	return &BoolReply{Value: value}
+}
+
+func (r *BoolReply) ToBytes() []byte {
+	if r.Value {
+		return []byte(":1" + CRLF)
+	}
+	return []byte(":0" + CRLF)
+}
+
+func (r *BoolReply) ToBytes3() []byte {
+	if r.Value {
+		return []byte("#t" + CRLF)
+	}
+	return []byte("#f" + CRLF)
+}
+
+// -----------------------------------
+// Null: _\r\n
+// RESP2: nil bulk string
+// -----------------------------------
+
+type NullReply struct{}
+
+func (r *NullReply) ToBytes() []byte {
+	return []byte("$-1" + CRLF)
+}
+
+func (r *NullReply) ToBytes3() []byte {
+	return nullBytes
+}
+
+// -----------------------------------
+// Big Number: (3492890328409238509324850943850943825024385\r\n
+// RESP2: bulk string
+// -----------------------------------
+
+type BigNumberReply struct {
+	// Value 为十进制整数字符串（可带负号）
+	Value string
+}
+
+func MakeBigNumberReply(value string) *BigNumberReply {
+	return &BigNumberReply{Value: value}
+}
+
+func (r *BigNumberReply) ToBytes() []byte {
+	return MakeBulkReply([]byte(r.Value)).ToBytes()
+}
+
+func (r *BigNumberReply) ToBytes3() []byte {
+	return []byte("(" + r.Value + CRLF)
+}
+
+// -----------------------------------
+// Verbatim String: =15\r\ntxt:Some string\r\n
+// RESP2: bulk string（不含格式前缀）
+// -----------------------------------
+
+type VerbatimReply struct {
+	// Format 为 3 个字符的格式（txt / mkd）
+	Format string
+	Text   []byte
+}
+
+func MakeVerbatimReply(format string, text []byte) *VerbatimReply {
+	return &VerbatimReply{Format: format, Text: text}
+}
+
+func (r *VerbatimReply) ToBytes() []byte {
+	return MakeBulkReply(r.Text).ToBytes()
+}
+
+func (r *VerbatimReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("=" + strconv.Itoa(len(r.Format)+1+len(r.Text)) + CRLF)
+	buf.WriteString(r.Format)
+	buf.WriteByte(':')
+	buf.Write(r.Text)
+	buf.WriteString(CRLF)
+	return buf.Bytes()
+}
+
+// -----------------------------------
+// Push: >3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$3\r\nmsg\r\n
+// 带外推送（发布订阅消息）；RESP2: 数组
+// -----------------------------------
+
+type PushReply struct {
+	Replies []Reply
+}
+
+func MakePushReply(replies []Reply) *PushReply {
+	return &PushReply{Replies: replies}
+}
+
+func (r *PushReply) ToBytes() []byte {
+	return MakeMultiRawReply(r.Replies).ToBytes()
+}
+
+func (r *PushReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	writeAggregate(&buf, '>', len(r.Replies), r.Replies, RESP3)
+	return buf.Bytes()
+}
+
+// -----------------------------------
+// Attribute: |1\r\n+key\r\n+value\r\n<reply>
+// 附加在 Reply 之前的辅助信息；RESP2 下丢弃属性，只输出 Reply
+// -----------------------------------
+
+type AttributeReply struct {
+	// Attrs 为 key/value 交替的属性
+	Attrs []Reply
+	Reply Reply
+}
+
+func MakeAttributeReply(attrs []Reply, reply Reply) *AttributeReply {
+	return &AttributeReply{Attrs: attrs, Reply: reply}
+}
+
+func (r *AttributeReply) ToBytes() []byte {
+	return r.Reply.ToBytes()
+}
+
+func (r *AttributeReply) ToBytes3() []byte {
+	var buf bytes.Buffer
+	writeAggregate(&buf, '|', len(r.Attrs)/2, r.Attrs, RESP3)
+	buf.Write(Encode(r.Reply, RESP3))
+	return buf.Bytes()
+}
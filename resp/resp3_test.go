@@ -0,0 +1,82 @@
+// RESP3 测试：验证各类型在 RESP2/RESP3 下的编码，以及解析器能读回 RESP3 回复。
+// 目标：RESP2 输出与引入 RESP3 之前完全一致；RESP3 输出符合规范。
+// 覆盖：map/set/double/boolean/null/big number/verbatim/push/attribute，nil bulk/数组在 RESP3 下为 null。
+package resp
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestResp3_Encode(t *testing.T) {
+	pairs := []Reply{MakeBulkReply([]byte("f")), MakeBulkReply([]byte("v"))}
+	cases := []struct {
+		name         string
+		reply        Reply
+		resp2, resp3 string
+	}{
+		{"map", MakeMapReply(pairs), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{"nil map", MakeMapReply(nil), "*-1\r\n", "%0\r\n"},
+		{"set", MakeSetReply([][]byte{[]byte("a")}), "*1\r\n$1\r\na\r\n", "~1\r\n$1\r\na\r\n"},
+		{"double", MakeDoubleReply(1.5), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{"integral double", MakeDoubleReply(3479099956230698), "$16\r\n3479099956230698\r\n", ",3479099956230698\r\n"},
+		{"inf", MakeDoubleReply(math.Inf(-1)), "$4\r\n-inf\r\n", ",-inf\r\n"},
+		{"map bulk", MakeMapBulkReply([][]byte{[]byte("f"), []byte("v")}), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{"nil map bulk", MakeMapBulkReply(nil), "*-1\r\n", "%0\r\n"},
+		{"set bulk", MakeSetBulkReply([][]byte{[]byte("a")}), "*1\r\n$1\r\na\r\n", "~1\r\n$1\r\na\r\n"},
+		{"double bulk", MakeDoubleBulkReply(1.5), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{"bool", MakeBoolReply(true), ":1\r\n", "#t\r\n"},
+		{"null", &NullReply{}, "$-1\r\n", "_\r\n"},
+		{"nil bulk", MakeBulkReply(nil), "$-1\r\n", "_\r\n"},
+		{"nil array", MakeMultiBulkReply(nil), "*-1\r\n", "_\r\n"},
+		{"array with nil", MakeMultiBulkReply([][]byte{nil, []byte("x")}), "*2\r\n$-1\r\n$1\r\nx\r\n", "*2\r\n_\r\n$1\r\nx\r\n"},
+		{"big number", MakeBigNumberReply("-12345678901234567890"), "$21\r\n-12345678901234567890\r\n", "(-12345678901234567890\r\n"},
+		{"verbatim", MakeVerbatimReply("txt", []byte("hi")), "$2\r\nhi\r\n", "=6\r\ntxt:hi\r\n"},
+		{"push", MakePushReply(pairs), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n", ">2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{"attribute", MakeAttributeReply(pairs, MakeIntReply(1)), ":1\r\n", "|1\r\n$1\r\nf\r\n$1\r\nv\r\n:1\r\n"},
+		{"nested", MakeMultiRawReply([]Reply{MakeMapReply(pairs), MakeBulkReply(nil)}),
+			"*2\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n$-1\r\n", "*2\r\n%1\r\n$1\r\nf\r\n$1\r\nv\r\n_\r\n"},
+	}
+	for _, c := range cases {
+		if got := string(Encode(c.reply, RESP2)); got != c.resp2 {
+			t.Errorf("%s RESP2 = %q, want %q", c.name, got, c.resp2)
+		}
+		if got := string(Encode(c.reply, RESP3)); got != c.resp3 {
+			t.Errorf("%s RESP3 = %q, want %q", c.name, got, c.resp3)
+		}
+	}
+}
+
+func TestResp3_Parse(t *testing.T) {
+	inputs := []Reply{
+		MakeMapReply([]Reply{MakeBulkReply([]byte("k")), MakeDoubleReply(2.5)}),
+		MakeSetReply([][]byte{[]byte("a"), []byte("b")}),
+		MakeBoolReply(false),
+		&NullReply{},
+		MakeBigNumberReply("123"),
+		MakeVerbatimReply("mkd", []byte("# title")),
+		MakePushReply([]Reply{MakeBulkReply([]byte("message")), MakeIntReply(1)}),
+		MakeAttributeReply([]Reply{MakeBulkReply([]byte("ttl")), MakeIntReply(3)}, MakeBulkReply([]byte("v"))),
+	}
+	var stream bytes.Buffer
+	for _, r := range inputs {
+		stream.Write(Encode(r, RESP3))
+	}
+	p := NewStreamParser(&stream)
+	for i, want := range inputs {
+		got, err := p.ReadReply()
+		if err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+		if g, w := string(Encode(got, RESP3)), string(Encode(want, RESP3)); g != w {
+			t.Fatalf("reply %d: got %q (%T), want %q", i, g, got, w)
+		}
+	}
+
+	for _, bad := range []string{"#x\r\n", ",abc\r\n", "%-1\r\n", "=3\r\nabc\r\n", "~1\r\n:1\r\n"} {
+		if _, err := NewStreamParser(bytes.NewBufferString(bad)).ReadReply(); err == nil {
+			t.Errorf("%q: expected protocol error", bad)
+		}
+	}
+}
@@ -13,19 +13,16 @@ import (
 // 而不是 ParseStream 那种“持续解析直到 EOF”的异步模型。
 
 // StreamParser 是一个面向流的 RESP 解析器（同步读取）。
+// 对端节点的回复是可信的，不设协议限制（长度头仍不会直接决定内存分配，见 limits.go）。
 type StreamParser struct {
-	reader *bufio.Reader
+	reader *protoReader
 }
 
 func NewStreamParser(r io.Reader) *StreamParser {
-	return &StreamParser{reader: bufio.NewReader(r)}
+	return &StreamParser{reader: &protoReader{buf: bufio.NewReader(r)}}
 }
 
-// ReadReply 从流中读取一个完整的 RESP Reply。
+// ReadReply 从流中读取一个完整的 RESP Reply（与 ParseStream 一致：跳过空行，支持内联命令）。
 func (p *StreamParser) ReadReply() (Reply, error) {
-	line, err := readLine(p.reader)
-	if err != nil {
-		return nil, err
-	}
-	return parseLine(line, p.reader)
+	return readTop(p.reader)
 }
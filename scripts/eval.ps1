@@ -25,6 +25,10 @@ param(
   [switch]$SkipBenchmark
 )
 $ErrorActionPreference = "Stop"
+# 节点进程从环境变量读取集群密钥（未设置时每次运行随机生成）
+if (-not $env:MYREDIS_CLUSTER_SECRET) {
+  $env:MYREDIS_CLUSTER_SECRET = [guid]::NewGuid().ToString("N")
+}
 
 
 function Ensure-Dir([string]$Path) {
@@ -9,12 +9,15 @@ set -euo pipefail
 #
 # 可选环境变量：
 #   BASE_PORT=6399 NODE_COUNT=3 VNODES=160 MAX_BYTES=104857600 SKIP_BENCHMARK=1
+#   MYREDIS_CLUSTER_SECRET=...（节点间共享密钥，未设置时每次运行随机生成）
 
 BASE_PORT="${BASE_PORT:-6399}"
 NODE_COUNT="${NODE_COUNT:-3}"
 VNODES="${VNODES:-160}"
 MAX_BYTES="${MAX_BYTES:-104857600}"
 SKIP_BENCHMARK="${SKIP_BENCHMARK:-1}"
+# 节点进程从环境变量读取集群密钥
+export MYREDIS_CLUSTER_SECRET="${MYREDIS_CLUSTER_SECRET:-$(od -An -tx1 -N16 /dev/urandom | tr -d ' \n')}"
 
 ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
 TS="$(date +%Y%m%d-%H%M%S)"
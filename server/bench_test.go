@@ -0,0 +1,77 @@
+// 服务端 pipeline 基准测试：通过 TCP 批量发送 SET/GET，测量端到端吞吐。
+// 运行：go test ./server -run '^$' -bench Pipeline（每个操作为一条命令，MB/s 按请求字节计算）。
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"myredis/db"
+	"myredis/resp"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pipelineDepth 为每批发送的命令数。
+const pipelineDepth = 64
+
+func BenchmarkServerPipeline(b *testing.B) {
+	addr := "localhost:16405"
+	srv := NewServer(addr, db.NewStandaloneDB(""))
+	go func() { _ = srv.Start() }()
+	b.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	if err := waitForListen(addr, 2*time.Second); err != nil {
+		b.Fatalf("server not ready: %v", err)
+	}
+
+	value := strings.Repeat("v", 64)
+	batch := func(cmd string) []byte {
+		var buf bytes.Buffer
+		for i := 0; i < pipelineDepth; i++ {
+			args := []string{cmd, fmt.Sprintf("key:%06d", i)}
+			if cmd == "SET" {
+				args = append(args, value)
+			}
+			buf.Write(encodeCommand(args...))
+		}
+		return buf.Bytes()
+	}
+
+	for _, cmd := range []string{"SET", "GET"} {
+		data := batch(cmd)
+		b.Run(cmd, func(b *testing.B) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				b.Fatalf("dial error: %v", err)
+			}
+			defer conn.Close()
+			parser := resp.NewStreamParser(conn)
+
+			b.SetBytes(int64(len(data) / pipelineDepth))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for sent := 0; sent < b.N; sent += pipelineDepth {
+				n := min(pipelineDepth, b.N-sent)
+				// 各条命令长度相同，最后一批不足 pipelineDepth 条时只发送前 n 条
+				if _, err := conn.Write(data[:len(data)/pipelineDepth*n]); err != nil {
+					b.Fatalf("write error: %v", err)
+				}
+				for i := 0; i < n; i++ {
+					r, err := parser.ReadReply()
+					if err != nil {
+						b.Fatalf("read error: %v", err)
+					}
+					if _, ok := r.(*resp.ErrorReply); ok {
+						b.Fatalf("%s failed: %s", cmd, r.ToBytes())
+					}
+				}
+			}
+		})
+	}
+}
@@ -18,6 +18,13 @@ import (
 // - 启动 3 个节点（本机不同端口）
 // - 连接任意一个节点即可对所有 key 做 SET/GET（自动转发）
 // - DEL 多 key 能跨节点聚合返回值
+// - 转发回来的 HGETALL/SMEMBERS/ZSCORE 等回复在 HELLO 3 下保留 map/set/double 形态
+// - MGET/MSET 能跨节点分组执行，MGET 按请求顺序返回
+// - SCAN 能从任意入口节点遍历全部节点；内部命令 LOCALSCAN/LOCALEXEC 不接受普通客户端（密钥错误的 PEERHANDSHAKE 也不能解锁）
+// - SINTER/SUNION/SINTERCARD 跨节点聚合，SINTERSTORE 跨节点返回 CROSSSLOT
+// - 多 key PFCOUNT 跨节点合并寄存器，PFMERGE 跨节点返回 CROSSSLOT
+// - 转发的阻塞命令在客户端断开后撤销，之后写入的元素不丢失
+// - PUBLISH 转发到所有节点，订阅在其它节点的客户端也能收到，返回值为各节点接收者之和
 
 func TestDistributed_3Nodes_TransparentForward(t *testing.T) {
 	addrs := []string{freeAddr(t), freeAddr(t), freeAddr(t)}
@@ -30,8 +37,9 @@ func TestDistributed_3Nodes_TransparentForward(t *testing.T) {
 			MaxBytes:    db.DefaultMaxBytes,
 			Eviction:    "lru",
 		})
-		router := cluster.NewRouter(addr, localDB, addrs, 160)
+		router := cluster.NewRouter(addr, localDB, addrs, 160, testClusterSecret)
 		srv := NewServer(addr, router)
+		srv.ClusterSecret = testClusterSecret
 		servers = append(servers, srv)
 
 		go func(s *Server) {
@@ -100,8 +108,201 @@ func TestDistributed_3Nodes_TransparentForward(t *testing.T) {
 	if !ok || ir.Code != 3 {
 		t.Fatalf("DEL expected 3, got %T %+v", rDel, rDel)
 	}
+
+	// MGET/MSET 跨节点 scatter-gather，MGET 结果保持请求顺序
+	if r, ok := do("MSET", k1, "v1", k2, "v2", k3, "v3").(*resp.StatusReply); !ok || r.Status != "OK" {
+		t.Fatalf("MSET expected OK, got %+v", r)
+	}
+	mg, ok := do("MGET", k3, "missing-key", k1, k2).(*resp.MultiBulkReply)
+	if !ok || len(mg.Args) != 4 || string(mg.Args[0]) != "v3" || mg.Args[1] != nil ||
+		string(mg.Args[2]) != "v1" || string(mg.Args[3]) != "v2" {
+		t.Fatalf("MGET unexpected: %+v", mg)
+	}
+	if _, ok := do("MSETNX", k1, "x", k2, "y").(*resp.ErrorReply); !ok {
+		t.Fatalf("MSETNX across nodes should be rejected")
+	}
+	do("DEL", k1, k2, k3)
+
+	// ZSET 单 key 命令同样透明转发（回包为数组/bulk，需经 peer 解析后原样返回）
+	for _, key := range keysByNode {
+		if r, ok := do("ZADD", key, "2", "b", "1", "a").(*resp.IntReply); !ok || r.Code != 2 {
+			t.Fatalf("ZADD %s expected 2, got %+v", key, r)
+		}
+		mb, ok := do("ZRANGE", key, "0", "-1", "WITHSCORES").(*resp.MultiBulkReply)
+		if !ok || len(mb.Args) != 4 || string(mb.Args[0]) != "a" || string(mb.Args[3]) != "2" {
+			t.Fatalf("ZRANGE %s unexpected: %+v", key, mb)
+		}
+	}
+
+	// 转发回来的回复在 RESP3 下保留 map/set/double 形态（与本地 key 一致）
+	remote := ""
+	for i := 0; remote == ""; i++ {
+		if k := fmt.Sprintf("resp3:%d", i); ring.NodeForKey(k) != addrs[0] {
+			remote = k
+		}
+	}
+	c3 := dialRESP(t, addrs[0])
+	c3.proto = resp.RESP3
+	c3.do("HELLO", "3")
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"HSET", remote, "f", "v"}, ":1\r\n"},
+		{[]string{"HGETALL", remote}, "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"DEL", remote}, ":1\r\n"},
+		{[]string{"SADD", remote, "m"}, ":1\r\n"},
+		{[]string{"SMEMBERS", remote}, "~1\r\n$1\r\nm\r\n"},
+		{[]string{"SUNION", remote}, "~1\r\n$1\r\nm\r\n"},
+		{[]string{"DEL", remote}, ":1\r\n"},
+		{[]string{"ZADD", remote, "1.5", "m"}, ":1\r\n"},
+		{[]string{"ZSCORE", remote, "m"}, ",1.5\r\n"},
+		{[]string{"ZINCRBY", remote, "1", "m"}, ",2.5\r\n"},
+		{[]string{"ZADD", remote, "INCR", "1", "m"}, ",3.5\r\n"},
+		{[]string{"ZSCORE", remote, "missing"}, "_\r\n"},
+		{[]string{"DEL", remote}, ":1\r\n"},
+	} {
+		if got := c3.do(tc.args...); got != tc.want {
+			t.Fatalf("RESP3 %v on remote key = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	// 集群范围 SCAN：游标编码节点下标，从入口节点即可遍历全部节点的 key
+	seen := make(map[string]bool)
+	cursor := "0"
+	for rounds := 0; ; rounds++ {
+		raw, ok := do("SCAN", cursor, "COUNT", "100").(*resp.MultiRawReply)
+		if !ok || len(raw.Replies) != 2 {
+			t.Fatalf("SCAN unexpected reply: %+v", raw)
+		}
+		cursor = string(raw.Replies[0].(*resp.BulkReply).Arg)
+		for _, k := range raw.Replies[1].(*resp.MultiBulkReply).Args {
+			seen[string(k)] = true
+		}
+		if cursor == "0" {
+			break
+		}
+		if rounds > 1000 {
+			t.Fatalf("cluster SCAN did not terminate")
+		}
+	}
+	for _, key := range keysByNode {
+		if !seen[key] {
+			t.Fatalf("cluster SCAN missing %s, got %v", key, seen)
+		}
+	}
+	// 节点间内部命令只接受密钥校验通过的 peer 连接；普通客户端自行发送 PEERHANDSHAKE 也不能解锁
+	for _, hs := range [][]string{{"PEERHANDSHAKE"}, {"PEERHANDSHAKE", ""}, {"PEERHANDSHAKE", "guess"}} {
+		if r, ok := do(hs...).(*resp.ErrorReply); !ok || r.Status != "ERR invalid cluster secret" {
+			t.Fatalf("%v should be rejected, got %+v", hs, r)
+		}
+	}
+	if r, ok := do("LOCALSCAN", "0").(*resp.ErrorReply); !ok || r.Status != "ERR unknown command 'localscan'" {
+		t.Fatalf("LOCALSCAN from a client should be rejected, got %+v", r)
+	}
+	// LOCALEXEC 不能绕过路由把 key 写到非归属节点（上面的 PEERHANDSHAKE 失败后同样拒绝）
+	do("SET", k2, "v2")
+	if r, ok := do("LOCALEXEC", "SET", k2, "misplaced").(*resp.ErrorReply); !ok || r.Status != "ERR unknown command 'localexec'" {
+		t.Fatalf("LOCALEXEC from a client should be rejected, got %+v", r)
+	}
+	if r, ok := do("GET", k2).(*resp.BulkReply); !ok || string(r.Arg) != "v2" {
+		t.Fatalf("GET %s after rejected LOCALEXEC: %+v", k2, r)
+	}
+	// 用正确密钥握手的连接才是 peer：入口节点本地没有 k2（未被写到非归属节点）
+	peerConn, err := net.Dial("tcp", addrs[0])
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	defer peerConn.Close()
+	peerParser := resp.NewStreamParser(peerConn)
+	peerDo := func(args ...string) resp.Reply {
+		_, _ = peerConn.Write(encodeCommand(args...))
+		r, e := peerParser.ReadReply()
+		if e != nil {
+			t.Fatalf("read reply error: %v", e)
+		}
+		return r
+	}
+	if r, ok := peerDo("PEERHANDSHAKE", testClusterSecret).(*resp.StatusReply); !ok || r.Status != "OK" {
+		t.Fatalf("PEERHANDSHAKE with the cluster secret: %+v", r)
+	}
+	if r, ok := peerDo("LOCALEXEC", "EXISTS", k2).(*resp.IntReply); !ok || r.Code != 0 {
+		t.Fatalf("%s leaked into a non-owner node: %+v", k2, r)
+	}
+	// SCRIPT LOAD 经 peer 连接（LOCALEXEC）广播：EVALSHA 转发到其它节点也能找到脚本
+	sha, ok := do("SCRIPT", "LOAD", "return redis.call('GET', KEYS[1])").(*resp.BulkReply)
+	if !ok {
+		t.Fatalf("SCRIPT LOAD failed: %+v", sha)
+	}
+	do("SET", k3, "v3")
+	if r := do("EVALSHA", string(sha.Arg), "1", k3); string(r.ToBytes()) != "$2\r\nv3\r\n" {
+		t.Fatalf("EVALSHA on another node: %q", r.ToBytes())
+	}
+
+	// 集合运算：跨节点的读命令在入口节点聚合，写命令返回 CROSSSLOT
+	do("DEL", k1, k2, k3)
+	do("SADD", k1, "a", "b", "c")
+	do("SADD", k2, "b", "c", "d")
+	do("SADD", k3, "c", "e")
+	inter, ok := do("SINTER", k1, k2, k3).(*resp.MultiBulkReply)
+	if !ok || len(inter.Args) != 1 || string(inter.Args[0]) != "c" {
+		t.Fatalf("cross-node SINTER unexpected: %+v", inter)
+	}
+	if r, ok := do("SUNION", k1, k2, k3).(*resp.MultiBulkReply); !ok || len(r.Args) != 5 {
+		t.Fatalf("cross-node SUNION unexpected: %+v", r)
+	}
+	if r, ok := do("SINTERCARD", "2", k1, k2, "LIMIT", "1").(*resp.IntReply); !ok || r.Code != 1 {
+		t.Fatalf("cross-node SINTERCARD unexpected: %+v", r)
+	}
+	if er, ok := do("SINTERSTORE", k1, k2, k3).(*resp.ErrorReply); !ok || er.Status != "CROSSSLOT Keys in request don't hash to the same slot" {
+		t.Fatalf("cross-node SINTERSTORE should be rejected, got %+v", er)
+	}
+
+	// HyperLogLog：跨节点 PFCOUNT 在入口节点合并
+	do("DEL", k1, k2, k3)
+	do("PFADD", k1, "a", "b", "c")
+	do("PFADD", k2, "c", "d")
+	do("PFADD", k3, "d", "e", "f")
+	if r, ok := do("PFCOUNT", k1, k2, k3).(*resp.IntReply); !ok || r.Code != 6 {
+		t.Fatalf("cross-node PFCOUNT unexpected: %+v", r)
+	}
+	if er, ok := do("PFMERGE", k1, k2, k3).(*resp.ErrorReply); !ok || er.Status != "CROSSSLOT Keys in request don't hash to the same slot" {
+		t.Fatalf("cross-node PFMERGE should be rejected, got %+v", er)
+	}
+
+	// 阻塞命令转发到其它节点后客户端断开：入口节点关闭 peer 连接，目标节点撤销等待者，之后 push 的元素不丢失
+	do("DEL", k2)
+	blocked := dialRESP(t, addrs[0])
+	blocked.send("BLPOP", k2, "0")
+	time.Sleep(100 * time.Millisecond)
+	blocked.conn.Close()
+	time.Sleep(200 * time.Millisecond)
+	do("RPUSH", k2, "x")
+	if r, ok := do("LLEN", k2).(*resp.IntReply); !ok || r.Code != 1 {
+		t.Fatalf("element pushed after a forwarded BLPOP disconnected was lost: %+v", r)
+	}
+
+	// Pub/Sub：订阅者分别连在第二、第三个节点，从入口节点发布
+	sub1, sub2 := dialRESP(t, addrs[1]), dialRESP(t, addrs[2])
+	sub1.do("SUBSCRIBE", "cluster-ch")
+	sub2.do("PSUBSCRIBE", "cluster-*")
+	if r, ok := do("PUBLISH", "cluster-ch", "hello").(*resp.IntReply); !ok || r.Code != 2 {
+		t.Fatalf("cluster PUBLISH expected 2 receivers, got %+v", r)
+	}
+	if got, want := sub1.read(), "*3\r\n$7\r\nmessage\r\n$10\r\ncluster-ch\r\n$5\r\nhello\r\n"; got != want {
+		t.Fatalf("subscriber on node 2 got %q, want %q", got, want)
+	}
+	if got, want := sub2.read(), "*4\r\n$8\r\npmessage\r\n$9\r\ncluster-*\r\n$10\r\ncluster-ch\r\n$5\r\nhello\r\n"; got != want {
+		t.Fatalf("subscriber on node 3 got %q, want %q", got, want)
+	}
+	if got := string(do("PUBSUB", "NUMSUB", "cluster-ch").ToBytes()); got != "*2\r\n$10\r\ncluster-ch\r\n:0\r\n" {
+		t.Fatalf("PUBSUB NUMSUB should only count the entry node, got %q", got)
+	}
 }
 
+// testClusterSecret 为集成测试中各节点共用的集群密钥。
+const testClusterSecret = "test-cluster-secret"
+
 func freeAddr(t *testing.T) string {
 	t.Helper()
 	l, err := net.Listen("tcp", "127.0.0.1:0")
@@ -0,0 +1,126 @@
+// HELLO 协议协商：连接级 RESP 版本与客户端信息。
+// 说明：连接默认使用 RESP2；HELLO 3 之后该连接的回复按 RESP3 编码（map/set/double/null 等原生类型），HELLO 2 切回 RESP2。
+// 关键点：协议版本只影响本连接的编码；cluster 模式下节点间经 RESP2 转发，入口节点按命令重新标记 map/set/double（见 cluster.replyShapes）。
+package server
+
+import (
+	"myredis/db"
+	"myredis/resp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// 本文件实现：
+// - HELLO [protover [AUTH username password] [SETNAME clientname]]：回复服务端信息（server/version/proto/id/mode/role/modules）
+// - 不带 protover 时只返回信息，不切换协议
+// - RESP3 下订阅模式不再限制命令：推送消息为 push 类型，客户端可与普通回复区分（见 pubsub.go）
+// - RESET：把连接恢复到刚建立时的状态（事务、WATCH、订阅、协议版本、客户端名），回复 +RESET
+//
+// 限制：服务端没有 ACL/密码，AUTH 只接受 default 用户（任意密码，与 Redis 默认的 nopass 用户一致）；HELLO 不能在 MULTI 中使用。
+
+// serverVersion 为 HELLO 报告的兼容 Redis 版本。
+const serverVersion = "7.2.0"
+
+// nextClientID 为连接分配递增的客户端 ID。
+var nextClientID atomic.Int64
+
+// connClient 为一个连接的客户端信息（只在该连接的 goroutine 中访问）。
+type connClient struct {
+	id    int64
+	proto int
+	name  string
+	peer  bool // 连接已完成 cluster.PeerHandshakeCommand 握手且密钥校验通过（来自其它节点的 PeerClient）
+}
+
+func newConnClient() *connClient {
+	return &connClient{id: nextClientID.Add(1), proto: resp.RESP2}
+}
+
+// handleHello 执行 HELLO；成功时切换连接（以及已创建的订阅发送端）的协议版本，并按新协议回复服务端信息。
+func (s *Server) handleHello(cl *connClient, ps *connPubSub, tx *connTx, args [][]byte) resp.Reply {
+	if tx.inMulti {
+		return resp.MakeErrReply("ERR Command not allowed inside a transaction")
+	}
+	proto := cl.proto
+	opts := args[1:]
+	if len(opts) > 0 {
+		v, err := strconv.ParseInt(string(opts[0]), 10, 64)
+		if err != nil {
+			return resp.MakeErrReply("ERR Protocol version is not an integer or out of range")
+		}
+		if v < resp.RESP2 || v > resp.RESP3 {
+			return resp.MakeErrReply("NOPROTO unsupported protocol version")
+		}
+		proto = int(v)
+		opts = opts[1:]
+	}
+
+	name := cl.name
+	for i := 0; i < len(opts); i++ {
+		opt := strings.ToLower(string(opts[i]))
+		switch {
+		case opt == "auth" && i+2 < len(opts):
+			if string(opts[i+1]) != "default" {
+				return resp.MakeErrReply("WRONGPASS invalid username-password pair or user is disabled.")
+			}
+			i += 2
+		case opt == "setname" && i+1 < len(opts):
+			if !validClientName(opts[i+1]) {
+				return resp.MakeErrReply("ERR Client names cannot contain spaces, newlines or special characters.")
+			}
+			name = string(opts[i+1])
+			i++
+		default:
+			return resp.MakeErrReply("ERR Syntax error in HELLO option '" + string(opts[i]) + "'")
+		}
+	}
+
+	cl.proto = proto
+	cl.name = name
+	if ps.sub != nil {
+		ps.sub.SetProtocol(proto)
+	}
+
+	mode := "standalone"
+	if _, ok := s.Db.(*db.StandaloneDB); !ok {
+		mode = "cluster"
+	}
+	return resp.MakeMapReply([]resp.Reply{
+		resp.MakeBulkReply([]byte("server")), resp.MakeBulkReply([]byte("redis")),
+		resp.MakeBulkReply([]byte("version")), resp.MakeBulkReply([]byte(serverVersion)),
+		resp.MakeBulkReply([]byte("proto")), resp.MakeIntReply(int64(proto)),
+		resp.MakeBulkReply([]byte("id")), resp.MakeIntReply(cl.id),
+		resp.MakeBulkReply([]byte("mode")), resp.MakeBulkReply([]byte(mode)),
+		resp.MakeBulkReply([]byte("role")), resp.MakeBulkReply([]byte("master")),
+		resp.MakeBulkReply([]byte("modules")), resp.MakeMultiBulkReply([][]byte{}),
+	})
+}
+
+// handleReset 执行 RESET：丢弃 MULTI 并释放 WATCH、退订全部频道与模式（不发送退订确认）、协议切回 RESP2、清除客户端名。
+// 节点间连接的标记保留（RESET 不改变连接来源）。
+func (s *Server) handleReset(cl *connClient, ps *connPubSub, tx *connTx, args [][]byte) resp.Reply {
+	if len(args) != 1 {
+		return wrongArgs("reset")
+	}
+	tx.inMulti = false
+	tx.queue = nil
+	s.unwatchAll(tx)
+	if ps.sub != nil {
+		ps.hub.RemoveSubscriber(ps.sub)
+		ps.sub.SetProtocol(resp.RESP2)
+	}
+	cl.proto = resp.RESP2
+	cl.name = ""
+	return resp.MakeStatusReply("RESET")
+}
+
+// validClientName 与 Redis 一致：客户端名只能包含 '!' 到 '~' 之间的字符。
+func validClientName(name []byte) bool {
+	for _, c := range name {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
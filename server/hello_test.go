@@ -0,0 +1,97 @@
+// HELLO 集成测试：通过 TCP 验证协议协商、RESP3 下的原生回复类型与 push 消息。
+// 覆盖：HELLO 参数校验、HGETALL/SMEMBERS/ZSCORE/GET 在两种协议下的编码、RESP3 订阅模式下可执行普通命令、HELLO 2 与 RESET 切回。
+package server
+
+import (
+	"context"
+	"myredis/db"
+	"myredis/resp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerHello(t *testing.T) {
+	addr := "localhost:16403"
+	srv := NewServer(addr, db.NewStandaloneDB(""))
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server stopped: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	if err := waitForListen(addr, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+
+	c, pub := dialRESP(t, addr), dialRESP(t, addr)
+	expect := func(got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	expect(c.do("HELLO", "4"), "-NOPROTO unsupported protocol version\r\n")
+	expect(c.do("HELLO", "x"), "-ERR Protocol version is not an integer or out of range\r\n")
+	expect(c.do("HELLO", "3", "AUTH", "alice", "pw"), "-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+	expect(c.do("HELLO", "3", "SETNAME"), "-ERR Syntax error in HELLO option 'SETNAME'\r\n")
+
+	// 协商前为 RESP2
+	expect(c.do("HSET", "h", "f", "v"), ":1\r\n")
+	expect(c.do("HGETALL", "h"), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n")
+	expect(c.do("GET", "missing"), "$-1\r\n")
+
+	c.proto = resp.RESP3
+	hello := c.do("HELLO", "3", "AUTH", "default", "any", "SETNAME", "conn1")
+	if !strings.HasPrefix(hello, "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n") || !strings.Contains(hello, "$5\r\nproto\r\n:3\r\n") ||
+		!strings.Contains(hello, "$4\r\nmode\r\n$10\r\nstandalone\r\n") {
+		t.Fatalf("HELLO 3 = %q", hello)
+	}
+
+	expect(c.do("HGETALL", "h"), "%1\r\n$1\r\nf\r\n$1\r\nv\r\n")
+	expect(c.do("HGETALL", "missing"), "%0\r\n")
+	expect(c.do("GET", "missing"), "_\r\n")
+	expect(c.do("SADD", "s", "m"), ":1\r\n")
+	expect(c.do("SMEMBERS", "s"), "~1\r\n$1\r\nm\r\n")
+	expect(c.do("ZADD", "z", "1.5", "m"), ":1\r\n")
+	expect(c.do("ZSCORE", "z", "m"), ",1.5\r\n")
+	expect(c.do("ZINCRBY", "z", "1", "m"), ",2.5\r\n")
+	expect(c.do("CONFIG", "GET", "notify-keyspace-events"), "%1\r\n$22\r\nnotify-keyspace-events\r\n$0\r\n\r\n")
+
+	// 事务中的回复同样按 RESP3 编码；HELLO 不能在 MULTI 中使用
+	expect(c.do("MULTI"), "+OK\r\n")
+	expect(c.do("HELLO", "2"), "-ERR Command not allowed inside a transaction\r\n")
+	expect(c.do("ZSCORE", "z", "m"), "+QUEUED\r\n")
+	expect(c.do("EXEC"), "*1\r\n,2.5\r\n")
+
+	// RESP3 订阅模式：确认与消息为 push 类型，仍可执行普通命令
+	expect(c.do("SUBSCRIBE", "ch"), ">3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n")
+	expect(c.do("GET", "missing"), "_\r\n")
+	expect(c.do("PING"), "+PONG\r\n")
+	expect(pub.do("PUBLISH", "ch", "hi"), ":1\r\n")
+	expect(c.read(), ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n")
+
+	// 切回 RESP2 后订阅模式恢复限制，消息恢复为数组
+	c.send("HELLO", "2")
+	c.proto = resp.RESP2
+	if got := c.read(); !strings.HasPrefix(got, "*14\r\n") {
+		t.Fatalf("HELLO 2 = %q", got)
+	}
+	expect(c.do("GET", "missing"), "-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n")
+	expect(pub.do("PUBLISH", "ch", "again"), ":1\r\n")
+	expect(c.read(), "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$5\r\nagain\r\n")
+	expect(c.do("UNSUBSCRIBE"), "*3\r\n$11\r\nunsubscribe\r\n$2\r\nch\r\n:0\r\n")
+	expect(c.do("HGETALL", "h"), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n")
+
+	// RESET 把协议切回 RESP2（c.proto 保持 3：map 回复会重新编码为 %，数组说明服务端按 RESP2 回复）
+	c.send("HELLO", "3", "SETNAME", "before-reset")
+	c.proto = resp.RESP3
+	c.read()
+	expect(c.do("RESET"), "+RESET\r\n")
+	expect(c.do("HGETALL", "h"), "*2\r\n$1\r\nf\r\n$1\r\nv\r\n")
+}
@@ -0,0 +1,130 @@
+// 连接级事务状态：MULTI/EXEC/DISCARD 的命令排队与 WATCH 的版本记录。
+// 说明：命令在连接内排队，EXEC 时整批交给 db.TxDB.ExecMulti，在 Actor 中一次性原子执行。
+// 关键点：WATCH 记录 key 在监视时的版本号，EXEC 时由 DB 校验；连接断开/EXEC/DISCARD/UNWATCH 都要释放监视。
+package server
+
+import (
+	"myredis/db"
+	"myredis/resp"
+	"strings"
+)
+
+// 本文件实现事务相关命令（不经过 db.Exec，而是在连接层处理）：
+// - MULTI：进入事务，之后的命令回复 +QUEUED 并排队
+// - EXEC：原子执行队列中的命令；WATCH 的 key 被修改过时返回 nil 数组
+// - DISCARD：丢弃队列
+// - WATCH key [key ...] / UNWATCH：乐观锁
+//
+// cluster 模式下 Db 不实现 db.TxDB（跨节点无法原子执行），事务命令直接返回错误。
+
+// connTx 为一个连接的事务状态（只在该连接的 goroutine 中访问）。
+type connTx struct {
+	inMulti bool
+	queue   [][][]byte
+	watched map[string]uint64 // key -> WATCH 时的版本号
+}
+
+// handleTxCommand 处理事务相关命令；返回 false 表示 args 不是事务命令，应按普通命令执行。
+func (s *Server) handleTxCommand(tx *connTx, args [][]byte) (resp.Reply, bool) {
+	name := strings.ToLower(string(args[0]))
+	switch name {
+	case "multi", "exec", "discard", "watch", "unwatch":
+	default:
+		if !tx.inMulti {
+			return nil, false
+		}
+		tx.queue = append(tx.queue, args)
+		return resp.MakeStatusReply("QUEUED"), true
+	}
+
+	txDB, ok := s.Db.(db.TxDB)
+	if !ok {
+		return resp.MakeErrReply("ERR MULTI/EXEC is not supported in cluster mode"), true
+	}
+
+	switch name {
+	case "multi":
+		if len(args) != 1 {
+			return wrongArgs(name), true
+		}
+		if tx.inMulti {
+			return resp.MakeErrReply("ERR MULTI calls can not be nested"), true
+		}
+		tx.inMulti = true
+		tx.queue = nil
+		return resp.OkReply, true
+	case "exec":
+		if len(args) != 1 {
+			return wrongArgs(name), true
+		}
+		if !tx.inMulti {
+			return resp.MakeErrReply("ERR EXEC without MULTI"), true
+		}
+		reply := txDB.ExecMulti(tx.queue, tx.watched)
+		tx.inMulti = false
+		tx.queue = nil
+		s.unwatchAll(tx)
+		return reply, true
+	case "discard":
+		if len(args) != 1 {
+			return wrongArgs(name), true
+		}
+		if !tx.inMulti {
+			return resp.MakeErrReply("ERR DISCARD without MULTI"), true
+		}
+		tx.inMulti = false
+		tx.queue = nil
+		s.unwatchAll(tx)
+		return resp.OkReply, true
+	case "watch":
+		if len(args) < 2 {
+			return wrongArgs(name), true
+		}
+		if tx.inMulti {
+			return resp.MakeErrReply("ERR WATCH inside MULTI is not allowed"), true
+		}
+		keys := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			key := string(arg)
+			if _, dup := tx.watched[key]; dup {
+				continue
+			}
+			if tx.watched == nil {
+				tx.watched = make(map[string]uint64)
+			}
+			tx.watched[key] = 0 // 占位，去重同一命令中的重复 key
+			keys = append(keys, key)
+		}
+		for i, version := range txDB.Watch(keys) {
+			tx.watched[keys[i]] = version
+		}
+		return resp.OkReply, true
+	default: // unwatch
+		if tx.inMulti {
+			// 事务中的 UNWATCH 与其它命令一样排队（EXEC 后监视会统一释放）
+			tx.queue = append(tx.queue, args)
+			return resp.MakeStatusReply("QUEUED"), true
+		}
+		s.unwatchAll(tx)
+		return resp.OkReply, true
+	}
+}
+
+// unwatchAll 释放连接上的全部监视。
+func (s *Server) unwatchAll(tx *connTx) {
+	if len(tx.watched) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(tx.watched))
+	for key := range tx.watched {
+		keys = append(keys, key)
+	}
+	if txDB, ok := s.Db.(db.TxDB); ok {
+		txDB.Unwatch(keys)
+	}
+	tx.watched = nil
+}
+
+func wrongArgs(name string) resp.Reply {
+	return resp.MakeErrReply("ERR wrong number of arguments for '" + name + "' command")
+}
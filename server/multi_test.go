@@ -0,0 +1,121 @@
+// 事务集成测试：通过 TCP 验证 MULTI/QUEUED/EXEC/DISCARD 与 WATCH 在多连接下的行为。
+// 覆盖：事务命令的错误提示、另一连接修改 WATCH 的 key 导致 EXEC 返回 nil 数组、断开连接释放监视。
+package server
+
+import (
+	"bufio"
+	"context"
+	"myredis/db"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// encodeCommand 把命令编码为 RESP 数组。
+func encodeCommand(args ...string) []byte {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+	}
+	return []byte(b.String())
+}
+
+func TestServerTransaction(t *testing.T) {
+	addr := "localhost:16401"
+	srv := NewServer(addr, db.NewStandaloneDB(""))
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server stopped: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func() func(args ...string) []string {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		reader := bufio.NewReader(conn)
+		// 返回回复的原始行（数组回复按行展开），便于直接比较
+		var readReply func() []string
+		readReply = func() []string {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+			line = strings.TrimSpace(line)
+			out := []string{line}
+			switch line[0] {
+			case '$':
+				if n, _ := strconv.Atoi(line[1:]); n >= 0 {
+					data, _ := reader.ReadString('\n')
+					out = append(out, strings.TrimSpace(data))
+				}
+			case '*':
+				n, _ := strconv.Atoi(line[1:])
+				for i := 0; i < n; i++ {
+					out = append(out, readReply()...)
+				}
+			}
+			return out
+		}
+		return func(args ...string) []string {
+			conn.Write(encodeCommand(args...))
+			return readReply()
+		}
+	}
+	c1, c2 := dial(), dial()
+	expect := func(got []string, want ...string) {
+		t.Helper()
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	expect(c1("EXEC"), "-ERR EXEC without MULTI")
+	expect(c1("DISCARD"), "-ERR DISCARD without MULTI")
+
+	expect(c1("MULTI"), "+OK")
+	expect(c1("MULTI"), "-ERR MULTI calls can not be nested")
+	expect(c1("WATCH", "k"), "-ERR WATCH inside MULTI is not allowed")
+	expect(c1("SET", "k", "1"), "+QUEUED")
+	expect(c1("INCR", "k"), "+QUEUED")
+	// 事务执行前其它连接看不到排队的写入
+	expect(c2("GET", "k"), "$-1")
+	expect(c1("EXEC"), "*2", "+OK", ":2")
+
+	expect(c1("MULTI"), "+OK")
+	expect(c1("INCR", "k"), "+QUEUED")
+	expect(c1("DISCARD"), "+OK")
+	expect(c2("GET", "k"), "$1", "2")
+
+	// WATCH：另一连接修改了 key，EXEC 返回 nil 数组
+	expect(c1("WATCH", "k"), "+OK")
+	expect(c2("SET", "k", "changed"), "+OK")
+	expect(c1("MULTI"), "+OK")
+	expect(c1("SET", "k", "tx"), "+QUEUED")
+	expect(c1("EXEC"), "*-1")
+	expect(c2("GET", "k"), "$7", "changed")
+
+	// EXEC 后监视已释放：再次事务不受之前修改影响
+	expect(c1("MULTI"), "+OK")
+	expect(c1("SET", "k", "tx"), "+QUEUED")
+	expect(c1("EXEC"), "*1", "+OK")
+
+	// UNWATCH 后的修改不影响事务
+	expect(c1("WATCH", "k"), "+OK")
+	expect(c1("UNWATCH"), "+OK")
+	expect(c2("SET", "k", "again"), "+OK")
+	expect(c1("MULTI"), "+OK")
+	expect(c1("GET", "k"), "+QUEUED")
+	expect(c1("EXEC"), "*1", "$5", "again")
+}
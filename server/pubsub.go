@@ -0,0 +1,99 @@
+// 连接级发布订阅：SUBSCRIBE/UNSUBSCRIBE/PSUBSCRIBE/PUNSUBSCRIBE 与订阅模式。
+// 说明：订阅关系登记在 DB 持有的 pubsub.Hub 中；连接第一次订阅时创建 Subscriber，此后该连接的所有输出都经由 Subscriber 的发送队列（见 writer.go）。
+// 关键点：RESP2 订阅模式下只允许订阅类命令与 PING；订阅/退订确认由 Hub 在持锁时入队，保证先于对应频道的消息到达。
+package server
+
+import (
+	"myredis/db"
+	"myredis/pubsub"
+	"myredis/resp"
+	"strings"
+)
+
+// 本文件实现连接的订阅状态（PUBLISH/PUBSUB 不依赖连接状态，由 DB 在 Actor 中执行，见 db/pubsub.go）：
+// - SUBSCRIBE channel [channel ...] / PSUBSCRIBE pattern [pattern ...]
+// - UNSUBSCRIBE [channel ...] / PUNSUBSCRIBE [pattern ...]（不带参数表示退订全部）
+// - RESP2 订阅模式（至少有一个订阅）下：PING 回复 [pong, message]，QUIT/RESET 照常执行（见 server.execute），其它命令返回错误
+// - RESP3 下消息为 push 类型，订阅模式不限制命令
+//
+// 限制：订阅命令不能在 MULTI 中使用（不会排队）。
+
+// connPubSub 为一个连接的订阅状态（只在该连接的 goroutine 中访问）。
+type connPubSub struct {
+	hub *pubsub.Hub
+	sub *pubsub.Subscriber
+}
+
+// subscribed 判断连接是否处于订阅模式。
+func (ps *connPubSub) subscribed() bool {
+	return ps.sub != nil && ps.hub.Count(ps.sub) > 0
+}
+
+// close 在连接断开时移除全部订阅，并等待已排队的数据发送完毕（客户端不再读取时由写超时兜底，见 Subscriber.Close）。
+func (ps *connPubSub) close() {
+	if ps.sub == nil {
+		return
+	}
+	ps.hub.RemoveSubscriber(ps.sub)
+	ps.sub.Close()
+}
+
+// handlePubSubCommand 处理订阅类命令与订阅模式下的命令限制；返回 handled=false 表示 args 应按普通命令执行。
+// reply 为 nil 表示确认已由 Hub 写入发送队列。
+func (s *Server) handlePubSubCommand(out *connWriter, ps *connPubSub, cl *connClient, tx *connTx, args [][]byte) (resp.Reply, bool) {
+	name := strings.ToLower(string(args[0]))
+	restricted := cl.proto == resp.RESP2 && ps.subscribed()
+	switch name {
+	case "subscribe", "psubscribe", "unsubscribe", "punsubscribe":
+	case "ping":
+		if !restricted {
+			return nil, false
+		}
+		if len(args) > 2 {
+			return wrongArgs(name), true
+		}
+		msg := []byte{}
+		if len(args) == 2 {
+			msg = args[1]
+		}
+		return resp.MakeMultiBulkReply([][]byte{[]byte("pong"), msg}), true
+	default:
+		if !restricted {
+			return nil, false
+		}
+		return resp.MakeErrReply("ERR Can't execute '" + name + "': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context"), true
+	}
+
+	if (name == "subscribe" || name == "psubscribe") && len(args) < 2 {
+		return wrongArgs(name), true
+	}
+	if tx.inMulti {
+		return resp.MakeErrReply("ERR Command not allowed inside a transaction"), true
+	}
+	if ps.sub == nil {
+		psDB, ok := s.Db.(db.PubSubDB)
+		if !ok || psDB.PubSub() == nil {
+			return resp.MakeErrReply("ERR pub/sub is not supported"), true
+		}
+		ps.hub = psDB.PubSub()
+		ps.sub = ps.hub.NewSubscriber(out.conn)
+		ps.sub.SetProtocol(cl.proto)
+		out.attach(ps.sub)
+	}
+
+	names := make([]string, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		names = append(names, string(arg))
+	}
+	switch name {
+	case "subscribe":
+		ps.hub.Subscribe(ps.sub, names...)
+	case "psubscribe":
+		ps.hub.PSubscribe(ps.sub, names...)
+	case "unsubscribe":
+		ps.hub.Unsubscribe(ps.sub, names...)
+	default:
+		ps.hub.PUnsubscribe(ps.sub, names...)
+	}
+	return nil, true
+}
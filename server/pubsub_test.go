@@ -0,0 +1,151 @@
+// 发布订阅集成测试：通过 TCP 验证订阅确认、消息推送、订阅模式限制与 PUBSUB 查询。
+// 覆盖：频道与模式同时匹配时的接收者计数、MULTI 中的 PUBLISH、退订全部后恢复普通命令、断开连接清理订阅、订阅模式下的 RESET/QUIT。
+package server
+
+import (
+	"context"
+	"myredis/db"
+	"myredis/resp"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respConn 为测试用的 RESP 客户端连接。
+type respConn struct {
+	t      *testing.T
+	conn   net.Conn
+	parser *resp.StreamParser
+	proto  int // read 重新编码回复时使用的协议版本
+}
+
+func dialRESP(t *testing.T, addr string) *respConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &respConn{t: t, conn: conn, parser: resp.NewStreamParser(conn), proto: resp.RESP2}
+}
+
+// send 只发送命令，不读取回复。
+func (c *respConn) send(args ...string) {
+	if _, err := c.conn.Write(encodeCommand(args...)); err != nil {
+		c.t.Fatalf("write error: %v", err)
+	}
+}
+
+// read 读取一个回复并返回其 RESP 编码（便于直接比较）。
+func (c *respConn) read() string {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r, err := c.parser.ReadReply()
+	if err != nil {
+		c.t.Fatalf("read reply error: %v", err)
+	}
+	return string(resp.Encode(r, c.proto))
+}
+
+func (c *respConn) do(args ...string) string {
+	c.t.Helper()
+	c.send(args...)
+	return c.read()
+}
+
+func TestServerPubSub(t *testing.T) {
+	addr := "localhost:16402"
+	srv := NewServer(addr, db.NewStandaloneDB(""))
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server stopped: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	if err := waitForListen(addr, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+
+	sub, pub := dialRESP(t, addr), dialRESP(t, addr)
+	expect := func(got, want string) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	sub.send("SUBSCRIBE", "news.1", "alerts")
+	expect(sub.read(), "*3\r\n$9\r\nsubscribe\r\n$6\r\nnews.1\r\n:1\r\n")
+	expect(sub.read(), "*3\r\n$9\r\nsubscribe\r\n$6\r\nalerts\r\n:2\r\n")
+	expect(sub.do("PSUBSCRIBE", "news.*"), "*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:3\r\n")
+
+	// 订阅模式下只允许订阅类命令与 PING
+	expect(sub.do("GET", "k"), "-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n")
+	expect(sub.do("PING"), "*2\r\n$4\r\npong\r\n$0\r\n\r\n")
+
+	expect(pub.do("PUBSUB", "CHANNELS"), "*2\r\n$6\r\nalerts\r\n$6\r\nnews.1\r\n")
+	expect(pub.do("PUBSUB", "CHANNELS", "news*"), "*1\r\n$6\r\nnews.1\r\n")
+	expect(pub.do("PUBSUB", "NUMSUB", "news.1", "none"), "*4\r\n$6\r\nnews.1\r\n:1\r\n$4\r\nnone\r\n:0\r\n")
+	expect(pub.do("PUBSUB", "NUMPAT"), ":1\r\n")
+
+	// 频道与模式同时匹配：接收者计数为 2，订阅者依次收到 message 与 pmessage
+	expect(pub.do("PUBLISH", "news.1", "hello"), ":2\r\n")
+	expect(sub.read(), "*3\r\n$7\r\nmessage\r\n$6\r\nnews.1\r\n$5\r\nhello\r\n")
+	expect(sub.read(), "*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$6\r\nnews.1\r\n$5\r\nhello\r\n")
+	expect(pub.do("PUBLISH", "nobody", "x"), ":0\r\n")
+
+	// MULTI 中的 PUBLISH 在 EXEC 时发布
+	expect(pub.do("MULTI"), "+OK\r\n")
+	expect(pub.do("PUBLISH", "alerts", "tx"), "+QUEUED\r\n")
+	expect(pub.do("EXEC"), "*1\r\n:1\r\n")
+	expect(sub.read(), "*3\r\n$7\r\nmessage\r\n$6\r\nalerts\r\n$2\r\ntx\r\n")
+
+	// 退订全部后恢复普通命令
+	sub.send("UNSUBSCRIBE")
+	expect(sub.read(), "*3\r\n$11\r\nunsubscribe\r\n$6\r\nalerts\r\n:2\r\n")
+	expect(sub.read(), "*3\r\n$11\r\nunsubscribe\r\n$6\r\nnews.1\r\n:1\r\n")
+	expect(sub.do("PUNSUBSCRIBE"), "*3\r\n$12\r\npunsubscribe\r\n$6\r\nnews.*\r\n:0\r\n")
+	expect(sub.do("UNSUBSCRIBE"), "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n")
+	expect(sub.do("PING"), "+PONG\r\n")
+	expect(sub.do("SUBSCRIBE"), "-ERR wrong number of arguments for 'subscribe' command\r\n")
+
+	// 断开连接后订阅被清理
+	other := dialRESP(t, addr)
+	expect(other.do("SUBSCRIBE", "gone"), "*3\r\n$9\r\nsubscribe\r\n$4\r\ngone\r\n:1\r\n")
+	other.conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for pub.do("PUBSUB", "NUMSUB", "gone") != "*2\r\n$4\r\ngone\r\n:0\r\n" {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription should be removed after the connection is closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := pub.do("PUBSUB", "NOPE"); !strings.HasPrefix(got, "-ERR unknown subcommand 'NOPE'") {
+		t.Fatalf("PUBSUB NOPE = %q", got)
+	}
+
+	// RESET：退订全部（不发送确认）、丢弃事务并切回 RESP2，之后恢复普通命令
+	reset := dialRESP(t, addr)
+	expect(reset.do("SUBSCRIBE", "r1"), "*3\r\n$9\r\nsubscribe\r\n$2\r\nr1\r\n:1\r\n")
+	expect(reset.do("RESET", "x"), "-ERR wrong number of arguments for 'reset' command\r\n")
+	expect(reset.do("RESET"), "+RESET\r\n")
+	expect(reset.do("GET", "k"), "$-1\r\n")
+	expect(pub.do("PUBLISH", "r1", "x"), ":0\r\n")
+	expect(reset.do("MULTI"), "+OK\r\n")
+	expect(reset.do("SET", "k", "v"), "+QUEUED\r\n")
+	expect(reset.do("RESET"), "+RESET\r\n")
+	expect(reset.do("EXEC"), "-ERR EXEC without MULTI\r\n")
+
+	// QUIT：订阅模式下同样可用，回复 +OK 后关闭连接
+	quit := dialRESP(t, addr)
+	expect(quit.do("SUBSCRIBE", "q"), "*3\r\n$9\r\nsubscribe\r\n$1\r\nq\r\n:1\r\n")
+	expect(quit.do("QUIT"), "+OK\r\n")
+	if _, err := quit.parser.ReadReply(); err == nil {
+		t.Fatal("connection should be closed after QUIT")
+	}
+}
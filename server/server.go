@@ -4,21 +4,29 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"io"
 	"log"
+	"myredis/cluster"
 	"myredis/db"
 	"myredis/resp"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"time"
 )
 
 // 本文件实现 TCP Server：
-// - 基于 RESP 协议解析请求
-// - 每个连接一个 goroutine 负责读/写
-// - 命令执行交给 db.DB（Actor 串行执行）
+// - 基于 RESP 协议解析请求；bulk 长度、数组元素个数与单条请求大小受 Limits 限制，超限时回复错误并断开连接
+// - 每个连接一个 goroutine 负责读/写：同步读取请求（resp.RequestReader），回复写入输出缓冲，一批 pipeline 请求处理完后统一 flush（见 writer.go）
+// - 命令执行交给 db.DB（Actor 串行执行）；MULTI/EXEC/WATCH 等事务命令由连接层处理（见 multi.go）
+// - 阻塞命令等待期间继续观察连接，客户端断开时经 db.BlockingDB 撤销等待者（见 execBlocking）
+// - SUBSCRIBE 等订阅命令由连接层处理，进入订阅模式后回复与推送消息共用发送队列（见 pubsub.go）
+// - HELLO 协商连接的 RESP 版本，回复按连接协议编码；RESET 恢复连接的初始状态（见 hello.go）；QUIT 回复后关闭连接
+// - 节点间内部命令（cluster.IsPeerCommand）只在完成 cluster.PeerHandshakeCommand 握手的连接上执行
 //
 // 同时提供优雅关闭：
 // - 关闭 listener，停止 accept 新连接
@@ -28,6 +36,10 @@ import (
 type Server struct {
 	Addr string
 	Db   db.DB
+	// Limits 为请求解析的协议限制（proto-max-bulk-len 等），默认 resp.DefaultLimits；需在 Start 之前设置
+	Limits resp.Limits
+	// ClusterSecret 为集群共享密钥，PEERHANDSHAKE 校验通过的连接才能执行节点间内部命令；为空时拒绝所有握手。需在 Start 之前设置
+	ClusterSecret string
 
 	listener net.Listener
 
@@ -43,6 +55,7 @@ func NewServer(addr string, db db.DB) *Server {
 	return &Server{
 		Addr:    addr,
 		Db:      db,
+		Limits:  resp.DefaultLimits,
 		closing: make(chan struct{}),
 		conns:   make(map[net.Conn]struct{}),
 	}
@@ -114,54 +127,131 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 	defer s.untrackConn(conn)
 
-	// Parse requests from connection
-	payloads := resp.ParseStream(conn)
+	// 连接断开时释放该连接的 WATCH 与订阅
+	tx := &connTx{}
+	defer s.unwatchAll(tx)
+	ps := &connPubSub{}
+	defer ps.close()
+	cl := newConnClient()
 
-	for payload := range payloads {
-		if payload == nil {
-			continue // Should not happen, but safe guard
-		}
+	// 同步读取请求：参数缓冲在命令之间复用；回复写入输出缓冲，每次读取连接之前写出
+	out := newConnWriter(conn)
+	reader := resp.NewRequestReader(flushingReader{out}, s.Limits)
 
-		if payload.Err != nil {
-			if payload.Err != io.EOF {
-				log.Printf("Connection error: %v", payload.Err)
-				conn.Write(resp.MakeErrReply(payload.Err.Error()).ToBytes())
+	for {
+		args, err := reader.ReadCommand()
+		switch {
+		case err == resp.ErrExpectedArray:
+			log.Printf("Protocol error: %v", err)
+			out.writeReply(resp.MakeErrReply(err.Error()), cl.proto)
+		case err != nil:
+			if err != io.EOF {
+				log.Printf("Connection error: %v", err)
+				out.writeReply(resp.MakeErrReply(err.Error()), cl.proto)
 			}
+			out.flush()
 			return
+		case len(args) > 0: // 空数组（*0 / *-1）与 Redis 一致：直接忽略
+			// reader 的参数缓冲会被下一条命令复用，而 DB/AOF/事务队列会持有参数，先复制一份
+			if !s.execute(out, reader, ps, cl, tx, resp.CloneArgs(args)) {
+				out.flush()
+				return
+			}
 		}
+	}
+}
 
-		if payload.Data == nil {
-			continue
+// execute 执行一条命令并写出回复；返回 false 表示连接应当关闭（SHUTDOWN/QUIT）。
+// reader 为连接的请求读取器，阻塞命令等待期间用它观察连接是否断开。
+func (s *Server) execute(out *connWriter, reader *resp.RequestReader, ps *connPubSub, cl *connClient, tx *connTx, args [][]byte) bool {
+	name := strings.ToLower(string(args[0]))
+	switch {
+	case name == "shutdown":
+		// SHUTDOWN：用于评估流程/优雅退出（返回 +OK 后触发 Shutdown）
+		out.writeReply(resp.OkReply, cl.proto)
+		go func() {
+			// 给一个默认超时，避免卡死
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = s.Shutdown(ctx)
+		}()
+		return false
+	case name == "quit":
+		// QUIT：回复 +OK 后关闭连接（订阅模式与 MULTI 中同样立即执行）
+		out.writeReply(resp.OkReply, cl.proto)
+		return false
+	case name == "reset":
+		reply := s.handleReset(cl, ps, tx, args)
+		out.writeReply(reply, cl.proto)
+		return true
+	case name == cluster.PeerHandshakeCommand:
+		// 节点间连接的握手：只有携带正确集群密钥的连接才能执行 LOCALSCAN 等内部命令，普通客户端不能绕过 cluster 路由
+		if len(args) != 2 || !cluster.VerifyPeerSecret(s.ClusterSecret, args[1]) {
+			out.writeReply(resp.MakeErrReply("ERR invalid cluster secret"), cl.proto)
+			return true
 		}
+		cl.peer = true
+		out.writeReply(resp.OkReply, cl.proto)
+		return true
+	case cluster.IsPeerCommand(name) && !cl.peer:
+		out.writeReply(resp.MakeErrReply("ERR unknown command '"+name+"'"), cl.proto)
+		return true
+	}
 
-		// Expecting MultiBulkReply (Array of Bulk Strings)
-		multiBulk, ok := payload.Data.(*resp.MultiBulkReply)
-		if !ok {
-			log.Printf("Protocol error: expected MultiBulkReply, got %T", payload.Data)
-			conn.Write(resp.MakeErrReply("protocol error: expected array").ToBytes())
-			continue
+	// 订阅类命令（确认由 Hub 直接写入发送队列）与订阅模式下的命令限制
+	if reply, handled := s.handlePubSubCommand(out, ps, cl, tx, args); handled {
+		if reply != nil {
+			out.writeReply(reply, cl.proto)
 		}
+		return true
+	}
 
-		// SHUTDOWN：用于评估流程/优雅退出（返回 +OK 后触发 Shutdown）
-		if len(multiBulk.Args) > 0 && strings.EqualFold(string(multiBulk.Args[0]), "shutdown") {
-			_, _ = conn.Write(resp.OkReply.ToBytes())
-			go func() {
-				// 给一个默认超时，避免卡死
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				_ = s.Shutdown(ctx)
-			}()
-			return
-		}
+	if name == "hello" {
+		out.writeReply(s.handleHello(cl, ps, tx, args), cl.proto)
+		return true
+	}
 
-		// Execute command
-		reply := s.Db.Exec(multiBulk.Args)
-		if reply != nil {
-			conn.Write(reply.ToBytes())
+	// Execute command
+	reply, handled := s.handleTxCommand(tx, args)
+	if !handled {
+		// 阻塞命令可能很久才返回，先把同一批 pipeline 中前面命令的回复写出
+		if db.IsBlockingCommand(args) {
+			out.flush()
+			reply = s.execBlocking(out.conn, reader, args)
 		} else {
-			conn.Write(resp.MakeErrReply("unknown error").ToBytes())
+			reply = s.Db.Exec(args)
 		}
 	}
+	if reply == nil {
+		reply = resp.MakeErrReply("unknown error")
+	}
+	out.writeReply(reply, cl.proto)
+	return true
+}
+
+// execBlocking 执行阻塞命令（BLPOP 等）：等待期间继续读取连接，客户端断开时撤销等待者，
+// 避免之后写入的元素被弹给已断开的连接而丢失。等待期间收到的后续请求留在输入缓冲中，命令返回后照常处理。
+func (s *Server) execBlocking(conn net.Conn, reader *resp.RequestReader, args [][]byte) resp.Reply {
+	bdb, ok := s.Db.(db.BlockingDB)
+	if !ok {
+		return s.Db.Exec(args)
+	}
+	done := make(chan struct{})
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		err := reader.WaitClosed()
+		// 读超时为下面主动结束观察；缓冲已满时只能停止观察
+		if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, bufio.ErrBufferFull) {
+			close(done)
+		}
+	}()
+	reply := bdb.ExecBlocking(args, done)
+	// 结束观察：读超时让 WaitClosed 返回（已读到的数据留在缓冲中），再恢复为不超时
+	_ = conn.SetReadDeadline(time.Now())
+	<-watching
+	_ = conn.SetReadDeadline(time.Time{})
+	return reply
 }
 
 func (s *Server) trackConn(conn net.Conn) {
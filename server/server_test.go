@@ -1,12 +1,13 @@
 // server 单元测试：覆盖基本命令、Pipeline 交互、优雅关闭等行为。
 // 目标：确保服务端在并发连接与关闭场景下不 panic、无资源泄漏。
-// 覆盖：SET/GET/DEL、Pipeline、SHUTDOWN。
+// 覆盖：SET/GET/DEL、Pipeline（含阻塞命令前的回复、末尾命令只到达一半）、SHUTDOWN、内联命令、协议限制。
 package server
 
 import (
 	"bufio"
 	"context"
 	"myredis/db"
+	"myredis/resp"
 	"net"
 	"strconv"
 	"strings"
@@ -188,7 +189,124 @@ func TestServerIntegration(t *testing.T) {
 			t.Errorf("Expected expired key to return $-1, got %q", line)
 		}
 	})
+
+	t.Run("Inline_Commands", func(t *testing.T) {
+		// telnet / nc 发送的内联命令：LF 行尾、空行忽略、引号参数
+		if res := sendCommand("PING\n"); res != "+PONG" {
+			t.Errorf("inline PING: %q", res)
+		}
+		if res := sendCommand("\r\nSET inline \"hello world\"\r\n"); res != "+OK" {
+			t.Errorf("inline SET: %q", res)
+		}
+		if res := sendCommand("STRLEN 'inline'\r\n"); res != ":11" {
+			t.Errorf("inline STRLEN: %q", res)
+		}
+	})
+
+	t.Run("Pipeline_Before_Blocking", func(t *testing.T) {
+		// 同一批 pipeline 中阻塞命令之前的回复立即写出，不等阻塞命令返回
+		conn.Write([]byte("*1\r\n$4\r\nPING\r\n*3\r\n$5\r\nBLPOP\r\n$9\r\nemptylist\r\n$1\r\n1\r\n"))
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		line, err := reader.ReadString('\n')
+		conn.SetReadDeadline(time.Time{})
+		if err != nil || strings.TrimSpace(line) != "+PONG" {
+			t.Fatalf("PING reply before BLPOP: %q %v", line, err)
+		}
+		// BLPOP 超时返回 nil 数组
+		if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != "*-1" {
+			t.Errorf("BLPOP timeout: %q", line)
+		}
+	})
+
+	t.Run("Pipeline_After_Blocking", func(t *testing.T) {
+		// 阻塞期间读到的后续请求留在输入缓冲中，BLPOP 返回后照常执行
+		conn.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$9\r\nemptylist\r\n$3\r\n0.2\r\n*1\r\n$4\r\nPING\r\n"))
+		if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != "*-1" {
+			t.Errorf("BLPOP timeout: %q", line)
+		}
+		if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != "+PONG" {
+			t.Errorf("PING after BLPOP: %q", line)
+		}
+	})
+
+	t.Run("Pipeline_Partial_Command", func(t *testing.T) {
+		// 末尾的命令只到达一半时，前面已执行完的回复不等它到齐就写出
+		conn.Write([]byte("*1\r\n$4\r\nPING\r\n*2\r\n$6\r\nEXISTS\r\n$2\r\nh"))
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		line, err := reader.ReadString('\n')
+		conn.SetReadDeadline(time.Time{})
+		if err != nil || strings.TrimSpace(line) != "+PONG" {
+			t.Fatalf("PING reply before the partial command arrived: %q %v", line, err)
+		}
+		conn.Write([]byte("i\r\n"))
+		if line, _ := reader.ReadString('\n'); strings.TrimSpace(line) != ":0" {
+			t.Fatalf("EXISTS after the rest arrived: %q", line)
+		}
+	})
+
+	t.Run("Blocking_Disconnect", func(t *testing.T) {
+		// 阻塞中的客户端断开后撤销等待，之后 push 的元素不会被弹给已断开的连接
+		blocked, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial error: %v", err)
+		}
+		blocked.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$8\r\ngonelist\r\n$1\r\n0\r\n"))
+		time.Sleep(100 * time.Millisecond)
+		blocked.Close()
+		time.Sleep(100 * time.Millisecond)
+		if res := sendCommand("*3\r\n$5\r\nRPUSH\r\n$8\r\ngonelist\r\n$1\r\nx\r\n"); res != ":1" {
+			t.Fatalf("RPUSH: %q", res)
+		}
+		if res := sendCommand("*2\r\n$4\r\nLLEN\r\n$8\r\ngonelist\r\n"); res != ":1" {
+			t.Fatalf("element pushed after disconnect was lost, LLEN: %q", res)
+		}
+	})
 }
 
 // TestAOF skipped for now as it duplicates integration logic and was flaky.
 // We rely on manual verification + unit tests above.
+
+func TestServerProtocolLimits(t *testing.T) {
+	addr := "localhost:16404"
+	srv := NewServer(addr, db.NewStandaloneDB(""))
+	srv.Limits = resp.Limits{MaxBulkLen: 8, MaxMultiBulkLen: 4, MaxQueryBuffer: 1024}
+	go func() {
+		if err := srv.Start(); err != nil {
+			t.Logf("Server stopped: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	if err := waitForListen(addr, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+
+	// 超限的长度头：回复协议错误后断开连接，不会等待声明的数据到达
+	for _, c := range []struct{ input, want string }{
+		{"*2\r\n$3\r\nGET\r\n$1000000000\r\n", "-protocol error: invalid bulk length\r\n"},
+		{"*5\r\n", "-protocol error: invalid multibulk length\r\n"},
+	} {
+		c := c
+		t.Run(c.want[1:len(c.want)-2], func(t *testing.T) {
+			conn := dialRESP(t, addr)
+			if _, err := conn.conn.Write([]byte(c.input)); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			if got := conn.read(); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+			if _, err := conn.parser.ReadReply(); err == nil {
+				t.Fatalf("connection should be closed after protocol error")
+			}
+		})
+	}
+
+	// 限制内的请求正常执行
+	conn := dialRESP(t, addr)
+	if got := conn.do("SET", "k", "12345678"); got != "+OK\r\n" {
+		t.Fatalf("SET within limits: %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+// 连接输出：回复写入 bufio.Writer，下一次读取连接之前统一 flush。
+// 说明：回复直接编码进 bufio.Writer 的空闲缓冲（resp.AppendReply），一批请求只产生一次 write 系统调用。
+// 关键点：连接进入订阅模式时先 flush 已缓冲的回复，之后的输出全部经由 pubsub.Subscriber 的发送队列，保证回复与推送消息的顺序。
+package server
+
+import (
+	"bufio"
+	"myredis/pubsub"
+	"myredis/resp"
+	"net"
+)
+
+// 本文件实现 connWriter（只在连接的 goroutine 中访问）：
+// - writeReply：按连接协议编码回复；普通模式写入输出缓冲，订阅模式放入发送队列
+// - flush：RequestReader 需要从连接读取数据之前调用（flushingReader），以及连接关闭、执行阻塞命令之前
+// - attach：连接创建 Subscriber 时切换输出路径
+
+// writeBufferSize 为连接输出缓冲的大小；写满时 bufio.Writer 自动 flush。
+const writeBufferSize = 16 * 1024
+
+type connWriter struct {
+	conn net.Conn
+	buf  *bufio.Writer
+	sub  *pubsub.Subscriber
+}
+
+func newConnWriter(conn net.Conn) *connWriter {
+	return &connWriter{conn: conn, buf: bufio.NewWriterSize(conn, writeBufferSize)}
+}
+
+// flushingReader 为 RequestReader 的数据源：从连接读取之前先写出缓冲的回复。
+// RequestReader 只有在输入缓冲不足以解析出下一条命令时才会读连接，此时已计算好的回复不能等后续数据到齐
+// （例如 pipeline 末尾的命令只到达了一半）。
+type flushingReader struct {
+	w *connWriter
+}
+
+func (r flushingReader) Read(p []byte) (int, error) {
+	r.w.flush()
+	return r.w.conn.Read(p)
+}
+
+// writeReply 按协议版本编码并输出 r。
+func (w *connWriter) writeReply(r resp.Reply, protocol int) {
+	if w.sub != nil {
+		w.sub.Write(resp.Encode(r, protocol))
+		return
+	}
+	// 写入失败时 bufio.Writer 会记住错误，连接随后的读取也会失败并退出
+	_, _ = w.buf.Write(resp.AppendReply(w.buf.AvailableBuffer(), r, protocol))
+}
+
+// flush 把缓冲的回复写到连接。
+func (w *connWriter) flush() {
+	if w.sub == nil {
+		_ = w.buf.Flush()
+	}
+}
+
+// attach 把输出切换到订阅者的发送队列（此前缓冲的回复先写出）。
+func (w *connWriter) attach(sub *pubsub.Subscriber) {
+	_ = w.buf.Flush()
+	w.sub = sub
+}